@@ -0,0 +1,72 @@
+package config
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithAdaptiveTTL 根据配置变化的频率调整缓存时间：
+// 刷新后内容未变化时缓存时间加倍，直到max；内容变化时恢复为min
+//
+// 适用于大量很少变化的配置，降低后端压力。cacheTime <= 0 时不生效，开始监听变化后以兜底的刷新间隔为上限；
+// min <= 0 时忽略该选项，使用固定的cacheTime
+func WithAdaptiveTTL(min, max time.Duration) AsyncOption {
+	return func(cfg *asyncConfig) {
+		if min <= 0 {
+			logger.Warnf("config[%s] adaptive ttl min %v <= 0, ignored", cfg.asyncKey, min)
+			return
+		}
+		if max < min {
+			max = min
+		}
+		cfg.adaptive = &adaptiveTTL{min: min, max: max, cur: int64(min)}
+	}
+}
+
+type adaptiveTTL struct {
+	min, max time.Duration
+	cur      int64 // time.Duration
+}
+
+func (a *adaptiveTTL) get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&a.cur))
+}
+
+// unchanged 内容未变化，缓存时间加倍
+func (a *adaptiveTTL) unchanged() {
+	if a == nil {
+		return
+	}
+	next := a.get() * 2
+	if next > a.max {
+		next = a.max
+	}
+	atomic.StoreInt64(&a.cur, int64(next))
+}
+
+// changed 内容变化，恢复为最短的缓存时间
+func (a *adaptiveTTL) changed() {
+	if a == nil {
+		return
+	}
+	atomic.StoreInt64(&a.cur, int64(a.min))
+}
+
+// EffectiveTTL 当前生效的缓存时间，<= 0 不过期
+func (c *AsyncConfig) EffectiveTTL() time.Duration {
+	return c.Configer.(*asyncConfig).effectiveTTL()
+}
+
+func (cfg *asyncConfig) effectiveTTL() time.Duration {
+	cacheTime := time.Duration(atomic.LoadInt64(&cfg.cacheTime))
+	if cacheTime <= 0 || cfg.adaptive == nil {
+		return cacheTime
+	}
+
+	ttl := cfg.adaptive.get()
+	if cfg.watching() && ttl > cacheTime {
+		// 监听时兜底的刷新间隔
+		ttl = cacheTime
+	}
+	return ttl
+}
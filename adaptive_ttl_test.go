@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveTTL(t *testing.T) {
+	ast := assert.New(t)
+
+	now := time.Now()
+	oldNow := _now
+	_now = func() time.Time { return now }
+	defer func() { _now = oldNow }()
+
+	asyncer := &rawAsyncer{NewMockAsyncer(false)}
+	asyncer.data.Store("adaptive.json", []byte(`{"a": 1}`))
+	cfg := NewAsyncConfig(asyncer, "adaptive.json", time.Minute, false, WithAdaptiveTTL(time.Second, 4*time.Second))
+	ast.Equal(time.Second, cfg.EffectiveTTL())
+
+	refresh := func() {
+		now = now.Add(cfg.EffectiveTTL() + time.Millisecond)
+		cfg.Get("a")
+	}
+
+	refresh()
+	ast.Equal(2*time.Second, cfg.EffectiveTTL())
+	refresh()
+	ast.Equal(4*time.Second, cfg.EffectiveTTL())
+	refresh()
+	ast.Equal(4*time.Second, cfg.EffectiveTTL(), "max")
+
+	// 未过期不刷新
+	asyncer.data.Store("adaptive.json", []byte(`{"a": 2}`))
+	now = now.Add(2 * time.Second)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	refresh()
+	ast.EqualValues(2, cfg.Int("a"))
+	ast.Equal(time.Second, cfg.EffectiveTTL(), "changed")
+
+	// 监听时以兜底间隔为上限
+	watched := NewAsyncConfig(NewMockAsyncer(true), "watched.json", time.Minute, false,
+		WithAdaptiveTTL(time.Hour, 24*time.Hour))
+	defer watched.Close()
+	ast.Equal(5*time.Minute, watched.EffectiveTTL())
+
+	ast.Equal(time.Duration(0), NewAsyncConfig(asyncer, "adaptive.json", 0, false, WithAdaptiveTTL(time.Second, time.Minute)).EffectiveTTL())
+
+	// min <= 0 时忽略，仍按cacheTime刷新
+	ast.Equal(time.Minute, NewAsyncConfig(asyncer, "adaptive.json", time.Minute, false, WithAdaptiveTTL(0, time.Minute)).EffectiveTTL())
+}
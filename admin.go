@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AdminMaskValue 敏感配置在管理接口中显示的值
+const AdminMaskValue = "******"
+
+// NewAdminHandler 查看配置的只读http接口，maskKeyPaths 及其子节点的值会被隐藏
+//
+// 路径（相对挂载的路径）：
+//   - GET /            所有叶子节点的keyPath及值
+//   - GET /?key=db     指定节点的值，不存在时返回404
+//   - GET /keys?prefix=db 叶子节点的keyPath列表
+//   - GET /dump?key=db 树形文本格式，标注类型及来源Layer，见 ConfigHelper.DumpTo
+//   - GET /openapi.json 以上接口的OpenAPI文档，见 AdminOpenAPI
+//
+// 例如：
+//
+//	mux.Handle("/admin/config/", http.StripPrefix("/admin/config", config.NewAdminHandler(cfg, "db.password")))
+//
+// IP白名单及审计见 NewAdminHandlerWithOptions
+func NewAdminHandler(cfg Configer, maskKeyPaths ...string) http.Handler {
+	return &adminHandler{
+		cfg:     cfg,
+		options: AdminOptions{MaskKeyPaths: maskKeyPaths},
+	}
+}
+
+// NewAuthorizedAdminHandler 见 NewAdminHandler，每个请求以 subject(r) 为调用方检查读取的权限，拒绝时返回403
+//
+// 检查的keyPath为 key 或 prefix 参数，未指定时为 RootKey；/openapi.json 不检查
+func NewAuthorizedAdminHandler(cfg Configer, authz Authorizer, subject func(r *http.Request) string, maskKeyPaths ...string) http.Handler {
+	return &adminHandler{
+		cfg: cfg,
+		options: AdminOptions{
+			MaskKeyPaths: maskKeyPaths,
+			Authorizer:   authz,
+			Subject:      subject,
+		},
+	}
+}
+
+type adminHandler struct {
+	cfg       Configer
+	options   AdminOptions
+	allowNets []*net.IPNet // 见 AdminOptions.AllowCIDRs
+}
+
+// serve 处理通过访问控制的请求
+func (h *adminHandler) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/openapi.json") {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(AdminOpenAPI(adminBasePath(r.RequestURI, r.URL.Path)))
+		return
+	}
+
+	// 同一请求内读取一致的配置
+	snap := Snapshot(h.cfg)
+
+	if strings.HasSuffix(r.URL.Path, "/dump") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		err := snap.DumpTo(w, &DumpOptions{
+			KeyPath:      r.FormValue("key"),
+			Types:        true,
+			Sources:      true,
+			MaskKeyPaths: h.options.MaskKeyPaths,
+		})
+		if err != nil {
+			logger.Errorf("admin handler dump err:%v", err)
+		}
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/keys") {
+		prefix := r.FormValue("prefix")
+		keys := make([]string, 0)
+		for _, k := range snap.AllKeys() {
+			if prefix == "" || k == prefix || strings.HasPrefix(k, prefix+".") {
+				keys = append(keys, k)
+			}
+		}
+		h.writeJSON(w, keys)
+		return
+	}
+
+	if key := r.FormValue("key"); key != "" {
+		val, err := snap.Lookup(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.writeJSON(w, h.mask(key, val))
+		return
+	}
+
+	leaves := make(map[string]interface{})
+	snap.Range(func(keyPath string, value interface{}) bool {
+		leaves[keyPath] = h.mask(keyPath, value)
+		return true
+	})
+	h.writeJSON(w, leaves)
+}
+
+// mask 隐藏敏感的值，不修改原值
+func (h *adminHandler) mask(keyPath string, v interface{}) interface{} {
+	return maskValue(h.options.MaskKeyPaths, keyPath, v)
+}
+
+func (h *adminHandler) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logger.Errorf("admin handler encode err:%v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AdminAuditEvent 的结果
+const (
+	AdminOutcomeOK     = "ok"
+	AdminOutcomeDenied = "denied" // 不在IP白名单中或 Authorizer 拒绝
+	AdminOutcomeError  = "error"  // 其他4xx、5xx，如key不存在
+)
+
+// AdminOptions 管理接口的选项，见 NewAdminHandlerWithOptions
+type AdminOptions struct {
+	MaskKeyPaths []string // 隐藏值的节点，见 NewAdminHandler
+
+	// AllowCIDRs 允许访问的客户端地址，如 "10.0.0.0/8"、"127.0.0.1"，为空时不限制
+	AllowCIDRs []string
+	// ClientIP 客户端地址，默认取 RemoteAddr；在反向代理之后时可从可信的请求头中获取
+	ClientIP func(r *http.Request) string
+
+	Authorizer Authorizer                   // 见 NewAuthorizedAdminHandler
+	Subject    func(r *http.Request) string // 调用方，用于 Authorizer 及审计
+
+	// Audit 每个请求处理完后调用，包括被拒绝的请求
+	//
+	//	Audit: func(e config.AdminAuditEvent) {
+	//		auditLogger.Infow("config admin", "subject", e.Subject, "ip", e.ClientIP,
+	//			"endpoint", e.Endpoint, "key", e.KeyPath, "outcome", e.Outcome, "status", e.Status)
+	//	}
+	Audit func(AdminAuditEvent)
+}
+
+// AdminAuditEvent 管理接口的访问记录
+type AdminAuditEvent struct {
+	Time     time.Time
+	Subject  string // 见 AdminOptions.Subject，未设置时为空
+	ClientIP string
+	Method   string
+	Endpoint string // 相对挂载的路径，如 /keys
+	KeyPath  string // key 或 prefix 参数
+	Status   int
+	Outcome  string // AdminOutcomeOK、AdminOutcomeDenied 或 AdminOutcomeError
+	Reason   string // 拒绝或失败的原因
+	Duration time.Duration
+}
+
+// NewAdminHandlerWithOptions 见 NewAdminHandler，增加IP白名单、访问控制及审计，AllowCIDRs 无效时返回错误
+//
+// 依次检查IP白名单及 Authorizer，任一不通过时返回403
+//
+//	handler, err := config.NewAdminHandlerWithOptions(cfg, &config.AdminOptions{
+//		MaskKeyPaths: []string{"db.password"},
+//		AllowCIDRs:   []string{"10.0.0.0/8", "127.0.0.1"},
+//		Audit:        auditAdmin,
+//	})
+func NewAdminHandlerWithOptions(cfg Configer, options *AdminOptions) (http.Handler, error) {
+	h := &adminHandler{cfg: cfg}
+	if options != nil {
+		h.options = *options
+	}
+
+	for _, cidr := range h.options.AllowCIDRs {
+		ipNet, err := parseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		h.allowNets = append(h.allowNets, ipNet)
+	}
+
+	return h, nil
+}
+
+// parseCIDR 单个IP视为只包含该地址的网段
+func parseCIDR(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipNet, err := net.ParseCIDR(s)
+		return ipNet, errors.Wrapf(err, "invalid cidr[%s]", s)
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.Errorf("invalid cidr[%s]", s)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	event := AdminAuditEvent{
+		Time:     _now(),
+		ClientIP: h.clientIP(r),
+		Method:   r.Method,
+		Endpoint: r.URL.Path,
+		KeyPath:  adminKeyPath(r),
+	}
+	if h.options.Subject != nil {
+		event.Subject = h.options.Subject(r)
+	}
+
+	rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	if reason := h.deny(r, &event); reason != "" {
+		logger.Warnf("admin handler %s %s: %s", r.Method, r.URL.Path, reason)
+		event.Outcome, event.Reason = AdminOutcomeDenied, reason
+		http.Error(rw, "forbidden", http.StatusForbidden)
+	} else {
+		h.serve(rw, r)
+		event.Outcome = AdminOutcomeOK
+		if rw.status >= http.StatusBadRequest {
+			event.Outcome, event.Reason = AdminOutcomeError, http.StatusText(rw.status)
+		}
+	}
+
+	if h.options.Audit != nil {
+		event.Status = rw.status
+		event.Duration = _now().Sub(event.Time)
+		h.options.Audit(event)
+	}
+}
+
+// deny 返回拒绝的原因，允许时为空
+func (h *adminHandler) deny(r *http.Request, event *AdminAuditEvent) string {
+	if len(h.allowNets) > 0 && !h.allowed(event.ClientIP) {
+		return "client ip[" + event.ClientIP + "] not allowed"
+	}
+
+	if h.options.Authorizer != nil && !strings.HasSuffix(r.URL.Path, "/openapi.json") {
+		if err := authorize(h.options.Authorizer, event.Subject, ActionRead, event.KeyPath); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+func (h *adminHandler) allowed(clientIP string) bool {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range h.allowNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *adminHandler) clientIP(r *http.Request) string {
+	if h.options.ClientIP != nil {
+		return h.options.ClientIP(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// adminKeyPath 请求访问的节点，未指定时为 RootKey
+func adminKeyPath(r *http.Request) string {
+	if strings.HasSuffix(r.URL.Path, "/keys") {
+		return r.FormValue("prefix")
+	}
+	return r.FormValue("key")
+}
+
+// statusRecorder 记录响应的状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
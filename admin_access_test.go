@@ -0,0 +1,87 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandlerWithOptions(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"app": map[string]interface{}{"name": "demo"},
+		"db":  map[string]interface{}{"host": "example.com"},
+	})
+
+	var events []AdminAuditEvent
+	handler, err := NewAdminHandlerWithOptions(cfg, &AdminOptions{
+		AllowCIDRs: []string{"10.0.0.0/8", "127.0.0.1", "::1"},
+		Authorizer: testPolicy,
+		Subject: func(r *http.Request) string {
+			return r.Header.Get("X-Subject")
+		},
+		Audit: func(e AdminAuditEvent) {
+			events = append(events, e)
+		},
+	})
+	ast.Nil(err)
+
+	get := func(remoteAddr, subject, url string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Subject", subject)
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	ast.Equal(http.StatusOK, get("10.1.2.3:5000", "user", "/?key=app.name"))
+	ast.Equal(http.StatusOK, get("127.0.0.1:5000", "admin", "/keys"))
+	ast.Equal(http.StatusOK, get("[::1]:5000", "admin", "/"))
+	ast.Equal(http.StatusForbidden, get("192.168.1.1:5000", "admin", "/"))
+	ast.Equal(http.StatusForbidden, get("192.168.1.1:5000", "admin", "/openapi.json"))
+	ast.Equal(http.StatusForbidden, get("10.1.2.3:5000", "user", "/?key=db"))
+	ast.Equal(http.StatusNotFound, get("10.1.2.3:5000", "admin", "/?key=not_exist"))
+
+	ast.Len(events, 7)
+	ast.Equal("user", events[0].Subject)
+	ast.Equal("10.1.2.3", events[0].ClientIP)
+	ast.Equal("/", events[0].Endpoint)
+	ast.Equal("app.name", events[0].KeyPath)
+	ast.Equal(AdminOutcomeOK, events[0].Outcome)
+	ast.Equal(http.StatusOK, events[0].Status)
+	ast.False(events[0].Time.IsZero())
+
+	ast.Equal("/keys", events[1].Endpoint)
+	ast.Equal(AdminOutcomeDenied, events[3].Outcome)
+	ast.Equal(http.StatusForbidden, events[3].Status)
+	ast.Contains(events[3].Reason, "192.168.1.1")
+	ast.Equal(AdminOutcomeDenied, events[5].Outcome)
+	ast.Contains(events[5].Reason, "forbidden")
+	ast.Equal(AdminOutcomeError, events[6].Outcome)
+	ast.Equal(http.StatusNotFound, events[6].Status)
+
+	// 反向代理之后
+	handler, err = NewAdminHandlerWithOptions(cfg, &AdminOptions{
+		AllowCIDRs: []string{"10.0.0.0/8"},
+		ClientIP: func(r *http.Request) string {
+			return r.Header.Get("X-Real-IP")
+		},
+	})
+	ast.Nil(err)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	handler.ServeHTTP(rec, req)
+	ast.Equal(http.StatusOK, rec.Code)
+
+	_, err = NewAdminHandlerWithOptions(cfg, &AdminOptions{AllowCIDRs: []string{"10.0.0.0/33"}})
+	ast.NotNil(err)
+	_, err = NewAdminHandlerWithOptions(cfg, &AdminOptions{AllowCIDRs: []string{"localhost"}})
+	ast.NotNil(err)
+	_, err = NewAdminHandlerWithOptions(cfg, nil)
+	ast.Nil(err)
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// AdminOpenAPIVersion NewAdminHandler 接口的版本，接口变化时修改
+const AdminOpenAPIVersion = "1.0.0"
+
+// AdminOpenAPI 返回 NewAdminHandler 各接口的OpenAPI 3.0文档（JSON），basePath为挂载的路径，如 "/admin/config"
+//
+// NewAdminHandler 在 GET /openapi.json 提供该文档，basePath取自请求的路径
+func AdminOpenAPI(basePath string) []byte {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath == "" {
+		basePath = "/"
+	}
+
+	query := func(name, desc string, required bool) map[string]interface{} {
+		return map[string]interface{}{
+			"name":        name,
+			"in":          "query",
+			"description": desc,
+			"required":    required,
+			"schema":      map[string]string{"type": "string"},
+		}
+	}
+	jsonContent := func(schema map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+	}
+	ok := func(desc string, content map[string]interface{}) map[string]interface{} {
+		resp := map[string]interface{}{"description": desc}
+		if content != nil {
+			resp["content"] = content
+		}
+		return resp
+	}
+	get := func(id, summary string, params []interface{}, responses map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"get": map[string]interface{}{
+			"operationId": id,
+			"summary":     summary,
+			"parameters":  params,
+			"responses":   responses,
+		}}
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":       "config admin",
+			"description": "只读的配置查看接口，敏感配置的值显示为 " + AdminMaskValue,
+			"version":     AdminOpenAPIVersion,
+		},
+		"servers": []interface{}{map[string]string{"url": basePath}},
+		"paths": map[string]interface{}{
+			"/": get("getConfig", "所有叶子节点的keyPath及值，指定key时为该节点的值",
+				[]interface{}{query("key", "节点的keyPath，如 db", false)},
+				map[string]interface{}{
+					"200": ok("配置的值", jsonContent(map[string]interface{}{})),
+					"404": ok("key不存在", nil),
+				}),
+			"/keys": get("listKeys", "叶子节点的keyPath列表，按字典序排序",
+				[]interface{}{query("prefix", "只返回该节点及其子节点", false)},
+				map[string]interface{}{
+					"200": ok("keyPath列表", jsonContent(map[string]interface{}{
+						"type":  "array",
+						"items": map[string]string{"type": "string"},
+					})),
+				}),
+			"/dump": get("dumpConfig", "树形文本格式，标注类型及来源Layer",
+				[]interface{}{query("key", "只输出该节点", false)},
+				map[string]interface{}{
+					"200": ok("树形文本", map[string]interface{}{
+						"text/plain": map[string]interface{}{"schema": map[string]string{"type": "string"}},
+					}),
+				}),
+			"/openapi.json": get("getOpenAPI", "本文档", []interface{}{},
+				map[string]interface{}{
+					"200": ok("OpenAPI 3.0文档", jsonContent(map[string]interface{}{"type": "object"})),
+				}),
+		},
+	}
+
+	// 只包含可序列化的值
+	data, _ := json.MarshalIndent(doc, "", "  ")
+	return data
+}
+
+// adminBasePath 挂载的路径，http.StripPrefix 后从RequestURI中获取
+func adminBasePath(requestURI, path string) string {
+	if u, err := url.ParseRequestURI(requestURI); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	return strings.TrimSuffix(path, "/openapi.json")
+}
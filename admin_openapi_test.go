@@ -0,0 +1,54 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminOpenAPI(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"db": map[string]interface{}{"host": "example.com"}})
+	mux := http.NewServeMux()
+	mux.Handle("/admin/config/", http.StripPrefix("/admin/config", NewAdminHandler(cfg)))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/config/openapi.json", nil))
+	ast.Equal(http.StatusOK, rec.Code)
+	ast.Contains(rec.Header().Get("Content-Type"), "application/json")
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct{ Version string }
+		Servers []struct{ URL string }
+		Paths   map[string]map[string]struct {
+			OperationID string                     `json:"operationId"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+		}
+	}
+	ast.Nil(json.Unmarshal(rec.Body.Bytes(), &doc))
+	ast.Equal("3.0.3", doc.OpenAPI)
+	ast.Equal(AdminOpenAPIVersion, doc.Info.Version)
+	ast.Equal("/admin/config", doc.Servers[0].URL)
+
+	// 文档中的接口都可访问
+	ast.Len(doc.Paths, 4)
+	for path, ops := range doc.Paths {
+		ast.NotEmpty(ops["get"].OperationID)
+		ast.Contains(ops["get"].Responses, "200")
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, doc.Servers[0].URL+path, nil))
+		ast.Equal(http.StatusOK, rec.Code, path)
+	}
+
+	// 未经StripPrefix挂载
+	ast.Contains(string(AdminOpenAPI("/")), `"url": "/"`)
+	rec = httptest.NewRecorder()
+	NewAdminHandler(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	ast.Contains(rec.Body.String(), `"url": "/"`)
+}
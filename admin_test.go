@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandler(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host":     "example.com",
+			"password": "secret",
+		},
+		"name": "app",
+	})
+	handler := NewAdminHandler(cfg, "db.password")
+
+	get := func(url string) (int, interface{}) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var v interface{}
+		json.Unmarshal(rec.Body.Bytes(), &v)
+		return rec.Code, v
+	}
+
+	code, v := get("/")
+	ast.Equal(http.StatusOK, code)
+	ast.Equal(map[string]interface{}{
+		"db.host":     "example.com",
+		"db.password": AdminMaskValue,
+		"name":        "app",
+	}, v)
+
+	_, v = get("/?key=db")
+	ast.Equal(map[string]interface{}{"host": "example.com", "password": AdminMaskValue}, v)
+	ast.Equal("secret", cfg.String("db.password"), "not modified")
+
+	_, v = get("/?key=db.password")
+	ast.Equal(AdminMaskValue, v)
+
+	code, _ = get("/?key=not_exist")
+	ast.Equal(http.StatusNotFound, code)
+
+	_, v = get("/keys")
+	ast.Equal([]interface{}{"db.host", "db.password", "name"}, v)
+	_, v = get("/keys?prefix=db")
+	ast.Equal([]interface{}{"db.host", "db.password"}, v)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	ast.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestAdminHandlerMaskList(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a.example.com", "password": "secret-a"},
+			map[string]interface{}{"host": "b.example.com", "password": "secret-b"},
+		},
+	})
+	handler := NewAdminHandler(cfg, "servers.0.password", "servers.1")
+
+	get := func(url string) interface{} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var v interface{}
+		json.Unmarshal(rec.Body.Bytes(), &v)
+		return v
+	}
+
+	// 数组是叶子节点，其中的字段同样隐藏
+	servers := []interface{}{
+		map[string]interface{}{"host": "a.example.com", "password": AdminMaskValue},
+		AdminMaskValue,
+	}
+	ast.Equal(map[string]interface{}{"servers": servers}, get("/"))
+	ast.Equal(servers, get("/?key=servers"))
+	ast.Equal(AdminMaskValue, get("/?key=servers.0.password"))
+	ast.Equal("secret-a", cfg.String("servers.0.password"), "not modified")
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AgentMaxSources 代理最多同时加载的后端key数，超过后拒绝新的key，避免任意客户端让代理创建无限的配置及监听
+var AgentMaxSources = 1024
+
+// AgentIdleTimeout 没有watch且超过该时间未读取的后端key会被关闭，<= 0 不关闭，在创建代理时确定
+var AgentIdleTimeout = 10 * time.Minute
+
+// NewAgentServer 代理模式：每台主机运行一个代理进程，从后端读取并缓存配置，
+// 通过unix socket提供给同主机的其他进程（见 NewAgentAsyncer），
+// 容器密集的主机上后端连接数从每个进程一个减少为每台主机一个
+//
+// 首次请求某个后端key时创建 AsyncConfig，之后所有进程共享其缓存及变化通知；
+// 同时加载的key数不超过 AgentMaxSources，空闲超过 AgentIdleTimeout 的key会被关闭，
+// 需要限制可读取的key时使用 SetAuthorizer
+//
+//	server, err := config.NewAgentServer(asyncer, "/run/config-agent.sock", time.Minute)
+//	go server.Serve()
+func NewAgentServer(asyncer Asyncer, path string, cacheTime time.Duration, opts ...AsyncOption) (*SocketServer, error) {
+	s, err := listenSocket(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.agent = &socketAgent{
+		asyncer:     asyncer,
+		cacheTime:   cacheTime,
+		opts:        opts,
+		notifier:    s.notifier,
+		idleTimeout: AgentIdleTimeout,
+		sources:     make(map[string]*agentSource),
+		quit:        make(chan struct{}),
+	}
+	if s.agent.idleTimeout > 0 {
+		go s.agent.evictLoop()
+	}
+	return s, nil
+}
+
+// NewAgentAsyncer 从 NewAgentServer 读取配置，key为后端key，内容为JSON，只读
+func NewAgentAsyncer(path string) *SocketAsyncer {
+	a := NewSocketAsyncer(path)
+	a.agent = true
+	return a
+}
+
+type socketAgent struct {
+	asyncer     Asyncer
+	cacheTime   time.Duration
+	opts        []AsyncOption
+	notifier    chan struct{}
+	idleTimeout time.Duration
+
+	sync.Mutex
+	sources map[string]*agentSource
+	closed  bool
+
+	quit chan struct{}
+}
+
+// agentSource 一个后端key的配置，由 socketAgent 的锁保护
+type agentSource struct {
+	cfg      *AsyncConfig
+	ready    chan struct{} // 加载完成后关闭
+	watchers int           // watch该key的连接数，大于0时不关闭
+	lastUsed time.Time
+}
+
+func (src *agentSource) loaded() bool {
+	select {
+	case <-src.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// config 返回source的配置，首次请求时在锁外加载，并发请求同一source时等待同一次加载
+func (a *socketAgent) config(source string) (*AsyncConfig, error) {
+	a.Lock()
+	if a.closed {
+		a.Unlock()
+		return nil, ErrClosed
+	}
+	src, ok := a.sources[source]
+	if !ok {
+		if len(a.sources) >= AgentMaxSources {
+			a.Unlock()
+			return nil, errors.Errorf("agent sources exceed %d", AgentMaxSources)
+		}
+		src = &agentSource{ready: make(chan struct{})}
+		a.sources[source] = src
+	}
+	src.lastUsed = _now()
+	a.Unlock()
+
+	if !ok {
+		a.load(source, src)
+	}
+	<-src.ready
+	return src.cfg, nil
+}
+
+func (a *socketAgent) load(source string, src *agentSource) {
+	cfg := NewAsyncConfig(a.asyncer, source, a.cacheTime, true, a.opts...)
+	cfg.Watch(a.notifier)
+	logger.Infof("config agent load[%s]", source)
+
+	a.Lock()
+	src.cfg = cfg
+	close(src.ready)
+	closed := a.closed
+	a.Unlock()
+
+	if closed {
+		cfg.Close()
+	}
+}
+
+// retain 连接watch了source，之后需调用release
+func (a *socketAgent) retain(source string) {
+	a.Lock()
+	defer a.Unlock()
+	if src, ok := a.sources[source]; ok {
+		src.watchers++
+	}
+}
+
+func (a *socketAgent) release(source string) {
+	a.Lock()
+	defer a.Unlock()
+	if src, ok := a.sources[source]; ok && src.watchers > 0 {
+		src.watchers--
+		src.lastUsed = _now()
+	}
+}
+
+func (a *socketAgent) evictLoop() {
+	interval := a.idleTimeout / 2
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			a.evictIdle()
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+// evictIdle 关闭没有watch且空闲超过idleTimeout的配置
+func (a *socketAgent) evictIdle() {
+	var idle []*AsyncConfig
+	a.Lock()
+	for source, src := range a.sources {
+		if src.watchers == 0 && src.loaded() && _now().Sub(src.lastUsed) > a.idleTimeout {
+			delete(a.sources, source)
+			idle = append(idle, src.cfg)
+			logger.Infof("config agent evict idle[%s]", source)
+		}
+	}
+	a.Unlock()
+
+	for _, cfg := range idle {
+		cfg.Close()
+	}
+}
+
+func (a *socketAgent) close() {
+	a.Lock()
+	if a.closed {
+		a.Unlock()
+		return
+	}
+	a.closed = true
+	close(a.quit)
+	var cfgs []*AsyncConfig
+	for _, src := range a.sources {
+		if src.loaded() {
+			cfgs = append(cfgs, src.cfg)
+		}
+	}
+	a.sources = nil
+	a.Unlock()
+
+	for _, cfg := range cfgs {
+		cfg.Close()
+	}
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentServer(t *testing.T) {
+	ast := assert.New(t)
+
+	backend := &slowAsyncer{MockAsyncer: NewMockAsyncer(true)}
+	backend.data.Store("app.yaml", []byte("db:\n  host: example.com\n"))
+
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	server, err := NewAgentServer(backend, path, time.Hour)
+	ast.Nil(err)
+	defer server.Close()
+	go server.Serve()
+
+	// 同主机的多个进程共享代理的缓存
+	cfgs := make([]*AsyncConfig, 5)
+	for i := range cfgs {
+		cfgs[i] = NewAsyncConfig(NewAgentAsyncer(path), "app.yaml", time.Hour, false)
+		defer cfgs[i].Close()
+		ast.Equal("example.com", cfgs[i].String("db.host"))
+	}
+	ast.EqualValues(1, atomic.LoadInt32(&backend.gets))
+	ast.Nil(NewAgentAsyncer(path).Get("not_exist.json"))
+
+	// 后端的变化推送到所有进程
+	ast.Nil(backend.Set("app.yaml", []byte("db:\n  host: db.example.com\n")))
+	for _, cfg := range cfgs {
+		cfg := cfg
+		ast.Eventually(func() bool {
+			return cfg.String("db.host") == "db.example.com"
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	// 代理模式需指定source
+	conn, err := net.Dial("unix", path)
+	ast.Nil(err)
+	defer conn.Close()
+	ast.Nil(writeFrame(conn, SocketRequest{ID: 1, Op: SocketOpGet, Key: "db"}))
+	var resp SocketResponse
+	ast.Nil(readFrame(bufio.NewReader(conn), &resp))
+	ast.Equal("source is required by agent", resp.Error)
+
+	// 非代理模式不支持source
+	plainPath := filepath.Join(t.TempDir(), "config.sock")
+	plain, err := NewSocketServer(NewMapConfig(map[string]interface{}{"a": 1}), plainPath)
+	ast.Nil(err)
+	defer plain.Close()
+	go plain.Serve()
+	ast.Nil(NewAgentAsyncer(plainPath).Get("app.yaml"))
+	ast.JSONEq(`1`, string(NewSocketAsyncer(plainPath).Get("a")))
+}
+
+func TestAgentSourceLoading(t *testing.T) {
+	ast := assert.New(t)
+
+	block := make(chan struct{})
+	backend := funcAsyncer{MockAsyncer: NewMockAsyncer(false), get: func(key string) []byte {
+		if key == "slow.json" {
+			<-block
+		}
+		return []byte(`{"a": 1}`)
+	}}
+
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	server, err := NewAgentServer(backend, path, time.Hour)
+	ast.Nil(err)
+	defer server.Close()
+	go server.Serve()
+
+	slow := make(chan []byte)
+	go func() { slow <- NewAgentAsyncer(path).Get("slow.json") }()
+	ast.Eventually(func() bool {
+		server.agent.Lock()
+		defer server.agent.Unlock()
+		return server.agent.sources["slow.json"] != nil
+	}, time.Second, time.Millisecond)
+
+	// 慢的后端key不阻塞其他key
+	ast.JSONEq(`{"a": 1}`, string(NewAgentAsyncer(path).Get("fast.json")))
+	close(block)
+	ast.JSONEq(`{"a": 1}`, string(<-slow))
+
+	// 超过数量限制时拒绝新的key
+	max := AgentMaxSources
+	AgentMaxSources = 2
+	defer func() { AgentMaxSources = max }()
+	ast.Nil(NewAgentAsyncer(path).Get("other.json"))
+	ast.JSONEq(`{"a": 1}`, string(NewAgentAsyncer(path).Get("fast.json")))
+}
+
+func TestAgentEvictIdle(t *testing.T) {
+	ast := assert.New(t)
+
+	now := time.Now()
+	oldNow := _now
+	_now = func() time.Time { return now }
+	defer func() { _now = oldNow }()
+
+	backend := NewMockAsyncer(true)
+	backend.data.Store("idle.json", []byte(`{"a": 1}`))
+	backend.data.Store("watched.json", []byte(`{"a": 2}`))
+
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	server, err := NewAgentServer(backend, path, time.Hour)
+	ast.Nil(err)
+	defer server.Close()
+	go server.Serve()
+	agent := server.agent
+
+	ast.NotNil(NewAgentAsyncer(path).Get("idle.json"))
+	watcher := NewAgentAsyncer(path)
+	defer watcher.Close()
+	<-watcher.Watch("watched.json")
+
+	sources := func() []string {
+		agent.Lock()
+		defer agent.Unlock()
+		ret := make([]string, 0, len(agent.sources))
+		for source := range agent.sources {
+			ret = append(ret, source)
+		}
+		return ret
+	}
+
+	// 空闲的key被关闭，watch中的保留
+	now = now.Add(agent.idleTimeout + time.Second)
+	agent.evictIdle()
+	ast.Equal([]string{"watched.json"}, sources())
+
+	// 连接断开后空闲时被关闭
+	ast.Nil(watcher.Close())
+	ast.Eventually(func() bool {
+		agent.Lock()
+		defer agent.Unlock()
+		return agent.sources["watched.json"].watchers == 0
+	}, time.Second, 5*time.Millisecond)
+	now = now.Add(agent.idleTimeout + time.Second)
+	agent.evictIdle()
+	ast.Empty(sources())
+}
+
+func TestAgentTinyIdleTimeout(t *testing.T) {
+	ast := assert.New(t)
+
+	origin := AgentIdleTimeout
+	AgentIdleTimeout = time.Nanosecond
+	defer func() { AgentIdleTimeout = origin }()
+
+	server, err := NewAgentServer(NewMockAsyncer(false), filepath.Join(t.TempDir(), "agent.sock"), time.Hour)
+	ast.Nil(err)
+	defer server.Close()
+
+	// evictLoop 的间隔不小于1ms，不会panic
+	time.Sleep(5 * time.Millisecond)
+}
@@ -1,13 +1,12 @@
 package config
 
 import (
-	"crypto/md5"
-	"fmt"
+	"crypto/ed25519"
+	"io"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/kot-w/goutils/object"
 	"github.com/mohae/deepcopy"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/singleflight"
@@ -57,7 +56,8 @@ type AsyncConfig struct {
 // asyncKey: 获取整个异步的Key（和Get方法的Key要区分）
 // cacheTime: 配置缓存的时间，超过该缓存时间会触发重新获取异步数据. <= 0 数据不过期
 // refreshAsync: 缓存过期时，刷新数据是同步还是异步（同步：有查询请求时，会等待数据刷新完成，异步则不会等待）
-func NewAsyncConfig(asyncer Asyncer, asyncKey string, cacheTime time.Duration, refreshAsync bool) *AsyncConfig {
+// opts: 可选配置，见 AsyncOption
+func NewAsyncConfig(asyncer Asyncer, asyncKey string, cacheTime time.Duration, refreshAsync bool, opts ...AsyncOption) *AsyncConfig {
 	contentType := asyncer.ContentType(asyncKey)
 
 	cfg := &asyncConfig{
@@ -65,19 +65,36 @@ func NewAsyncConfig(asyncer Asyncer, asyncKey string, cacheTime time.Duration, r
 		marshaler:    typeMarshalers[contentType],
 		contentType:  contentType,
 		asyncer:      asyncer,
-		cacheTime:    cacheTime,
+		cacheTime:    int64(cacheTime),
 		refreshAsync: refreshAsync,
 		quit:         make(chan struct{}),
 	}
 
-	cfg.refresh()
+	if m := getProtoMarshaler(asyncKey); m != nil {
+		cfg.marshaler = m
+		cfg.contentType = T_PROTOBUF
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	timeouts := DefaultTimeouts
+	if cfg.timeouts != nil {
+		timeouts = *cfg.timeouts
+	}
+	if cfg.frozen {
+		cfg.cacheTime = 0
+	}
+	atomic.AddInt64(&_resources.Configs, 1)
+
+	if timeouts.enabled() {
+		cfg.asyncer = NewTimeoutAsyncer(cfg.asyncer, timeouts)
+	}
 
-	if notify := asyncer.Watch(asyncKey); notify != nil {
-		// 推送更新机制下可以不使用过期策略
-		// 但为了防止更新消息丢失导致的旧值一直得不到更新
-		// 设置一个兜底的过期时间
-		cfg.cacheTime = 5 * time.Minute
-		go cfg.watch(notify)
+	if !cfg.deferStart {
+		cfg.refresh()
+		cfg.startWatch()
 	}
 
 	return &AsyncConfig{
@@ -89,21 +106,74 @@ func NewAsyncConfig(asyncer Asyncer, asyncKey string, cacheTime time.Duration, r
 
 type asyncConfig struct {
 	sync.Mutex
-	asyncKey      string
-	marshaler     Marshaler
-	contentType   ContentType
-	value         atomic.Value
-	rawMessageMd5 string
+	asyncKey         string
+	marshaler        Marshaler
+	contentType      ContentType
+	schema           Schema
+	blobs            []Blob
+	transformers     []Transformer
+	audit            *secretAudit
+	slow             *slowLog
+	ryw              *readYourWrites
+	adaptive         *adaptiveTTL
+	value            atomic.Value
+	rawMessageDigest string
+	scratch          digestScratch // 只在refresh中使用
+	// 配置中的密钥引用 keyPath => 引用
+	secretRefs atomic.Value // map[string]secretRef
+	// 配置中密钥引用最早的过期时间（UnixNano），0 表示不过期
+	secretsExpireAt int64
+	// 见 WithEncryptedFields
+	fieldCrypto     *fieldCrypto
+	encryptedFields atomic.Value // map[string]encryptedField
 
 	sf singleflight.Group
 
-	notifiers []chan struct{}
+	notifiers atomic.Value  // []chan struct{}，Watch时复制后替换，notify不加锁读取
+	subs      subscriptions // 见 OnChange
 
 	asyncer      Asyncer
+	timeouts     *Timeouts
 	refreshAsync bool
 	refreshTime  int64
-	cacheTime    time.Duration
+	cacheTime    int64 // time.Duration，开始监听后会被修改
 	quit         chan struct{}
+	closeOnce    sync.Once
+	bgMu         sync.Mutex
+	bgWg         sync.WaitGroup // 后台goroutine
+
+	deferStart bool
+	frozen     bool
+	startOnce  sync.Once
+	status     atomic.Value // LoadStatus
+
+	signingKeys  map[string]ed25519.PublicKey // 见 WithSignatureVerification
+	lastSignedAt time.Time                    // 已生效的bundle的签名时间，只在refresh中使用
+
+	envelope    bool
+	publishedAt int64 // 最近一次生效的变化的发布时间（UnixNano），见 WithEnvelope
+	appliedAt   int64 // UnixNano
+
+	// 见 Preset
+	retryInterval time.Duration
+	noWatch       bool
+	watchFallback time.Duration
+	watchStarted  int32
+
+	autoDetect bool
+	detected   atomic.Value // detectedMarshaler，见 WithAutoDetect
+
+	lint       bool
+	lintIssues atomic.Value // []LintIssue，见 WithLint
+
+	streaming bool // 见 WithStreaming
+
+	owned io.Closer // 随配置关闭的后端，见 NewAsyncConfigFromURL
+
+	errorReporter func(*InternalError) // 见 WithErrorReporter
+
+	events       <-chan Event // 见 Events
+	eventsCancel func()
 }
 
 func (cfg *asyncConfig) watch(notify chan struct{}) {
@@ -112,119 +182,363 @@ func (cfg *asyncConfig) watch(notify chan struct{}) {
 		case <-notify:
 			cfg.refresh()
 
-		//TODO
 		case <-cfg.quit:
 			return
 		}
 	}
 }
 
+// Close 停止监听及刷新，之后Get返回最后一次获取的配置
+func (c *AsyncConfig) Close() error {
+	return c.Configer.(*asyncConfig).Close()
+}
+
+func (cfg *asyncConfig) Close() error {
+	cfg.closeOnce.Do(func() {
+		cfg.bgMu.Lock()
+		close(cfg.quit)
+		if cfg.eventsCancel != nil {
+			cfg.eventsCancel()
+		}
+		cfg.bgMu.Unlock()
+		atomic.AddInt64(&_resources.Configs, -1)
+		atomic.AddInt64(&_resources.Subscriptions, -int64(cfg.subs.clear()))
+		if cfg.owned != nil {
+			cfg.owned.Close()
+		}
+	})
+	return nil
+}
+
+func (cfg *asyncConfig) closed() bool {
+	select {
+	case <-cfg.quit:
+		return true
+	default:
+		return false
+	}
+}
+
 func (cfg *asyncConfig) Get(keyPath string) interface{} {
+	val, _ := cfg.Lookup(keyPath)
+	return val
+}
+
+func (cfg *asyncConfig) Lookup(keyPath string) (interface{}, bool) {
+	defer cfg.slow.get(cfg.slow.startGet(), cfg.asyncKey, keyPath)
+
 	now := _now().UnixNano()
 	refreshTime := atomic.LoadInt64(&cfg.refreshTime)
-	if cfg.cacheTime > 0 && time.Duration(now-refreshTime)*time.Nanosecond > cfg.cacheTime { // content expired
+	if cfg.expired(now) && !cfg.closed() { // content expired
 		if refreshTime > 0 && cfg.refreshAsync { // if the content initialized and refreshAsync setted
 			logger.Debugf("asyncer[%s] refresh async", cfg.asyncKey)
-			go cfg.refresh()
+			cfg.goBackground(func() { cfg.refresh() })
 		} else { // 同步更新
-			logger.Debugf("asyncer[%s] refresh sync, cacheTime=%d, refreshTime=%d", cfg.asyncKey, cfg.cacheTime, refreshTime)
+			logger.Debugf("asyncer[%s] refresh sync, cacheTime=%d, refreshTime=%d", cfg.asyncKey, atomic.LoadInt64(&cfg.cacheTime), refreshTime)
 			cfg.refresh()
 		}
 	}
 
-	if keyPath == RootKey {
-		return cfg.value.Load()
-	}
-
-	val, ok := object.GetValue(cfg.value.Load(), keyPath)
-	if !ok {
-		return nil
+	if cfg.audit != nil {
+		cfg.audit.record(cfg.asyncKey, keyPath)
 	}
 
-	return val
+	return lookupValue(cfg.value.Load(), keyPath)
 }
 
-func (cfg *asyncConfig) refresh() {
-	cfg.sf.Do("", func() (_ interface{}, _ error) {
-		atomic.StoreInt64(&cfg.refreshTime, _now().UnixNano())
+func (cfg *asyncConfig) refresh() error {
+	_, err, _ := cfg.sf.Do("", func() (_ interface{}, err error) {
+		now := _now()
+		atomic.StoreInt64(&cfg.refreshTime, now.UnixNano())
+		start := cfg.slow.startRefresh()
+		prevDigest := cfg.rawMessageDigest
+		defer func() {
+			cfg.slow.refresh(start, cfg.asyncKey)
+			cfg.setStatus(now, err)
+			switch {
+			case err == nil:
+				cfg.emit(EventRefreshSucceeded, nil, cfg.rawMessageDigest != prevDigest)
+			case errors.Is(err, ErrValidation):
+				cfg.emit(EventValidationRejected, err, false)
+			default:
+				cfg.emit(EventRefreshFailed, err, false)
+			}
+		}()
+
+		version := cfg.ryw.currentVersion()
+		if r, ok := cfg.streamReader(); ok {
+			return nil, cfg.refreshStream(r, version, now)
+		}
 
-		rawMessage := cfg.asyncer.Get(cfg.asyncKey)
+		rawMessage, signedAt, err := cfg.verifySignature(fetch(cfg.asyncer, cfg.asyncKey))
+		if err != nil {
+			logger.Errorf("verify async config[%s] signature error:%v", cfg.asyncKey, err)
+			return nil, err
+		}
+		rawMessage, publishedAt := cfg.openEnvelope(rawMessage)
 		rawMessage = processRawMessage(rawMessage, cfg.contentType)
 
 		if len(rawMessage) == 0 {
 			logger.Warnf("asyncer[%s] get empty content", cfg.asyncKey)
-			return
+			return nil, backendError(cfg.asyncKey, errEmptyContent)
 		}
+		loadMetrics().Observe(MetricPayloadBytes, float64(len(rawMessage)), "key", cfg.asyncKey)
 
-		rawMessageMd5 := fmt.Sprintf("%x", md5.Sum(rawMessage))
+		blobs, err := cfg.fetchBlobs()
+		if err != nil {
+			logger.Errorf("fetch async config[%s] blobs error:%v", cfg.asyncKey, err)
+			return nil, err
+		}
+
+		rawMessageDigest := cfg.digest(rawMessage, blobs)
 
 		// no change
-		if rawMessageMd5 == cfg.rawMessageMd5 {
-			return
+		// 比较时不分配内存，内容变化时才转换为string
+		if string(rawMessageDigest) == cfg.rawMessageDigest && !cfg.secretsExpired() {
+			cfg.signedApplied(signedAt)
+			cfg.adaptive.unchanged()
+			return nil, nil
 		}
 
-		var val interface{}
-		if err := cfg.marshaler.Unmarshal(rawMessage, &val); err != nil {
-			logger.Errorf("unmarshal async config[%s] error:%v", cfg.asyncKey, err)
-			return
+		val, err := cfg.decode(rawMessage, blobs)
+		if err != nil {
+			logger.Errorf("decode async config[%s] error:%v", cfg.asyncKey, err)
+			return nil, err
 		}
-		cfg.rawMessageMd5 = rawMessageMd5
-		cfg.value.Store(val)
+		cfg.signedApplied(signedAt)
+		cfg.refreshed(val, string(rawMessageDigest), version, cfg.ryw.digestOf(rawMessage), rawMessage, publishedAt, now)
 
-		cfg.notify()
+		return nil, nil
+	})
+
+	return err
+}
 
+// refreshed 保存刷新获取的新配置并通知，rawMessage 为nil时（流式读取）不检查内容
+func (cfg *asyncConfig) refreshed(val interface{}, rawMessageDigest string, version uint64, contentDigest string,
+	rawMessage []byte, publishedAt, now time.Time) {
+	if !cfg.storeRefreshed(val, rawMessageDigest, version, contentDigest, now) {
 		return
-	})
+	}
+	cfg.applied(publishedAt, now)
+	cfg.adaptive.changed()
+	observeTree(cfg.asyncKey, val)
+	if rawMessage != nil {
+		cfg.lintContent(rawMessage, val)
+	}
+
+	cfg.notify()
+}
+
+// decode 解析原始配置内容
+func (cfg *asyncConfig) decode(rawMessage []byte, blobs map[string][]byte) (interface{}, error) {
+	var val interface{}
+	marshaler := cfg.detectMarshaler(rawMessage)
+	if err := safeUnmarshal(marshaler, rawMessage, &val); err != nil {
+		var pe *panicError
+		if errors.As(err, &pe) {
+			cfg.reportError(&InternalError{Op: OpDecode, Err: err, Panic: pe.value, Stack: pe.stack})
+		}
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+
+	val, err := cfg.process(val, blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.autoDetect {
+		cfg.detected.Store(detectedMarshaler{marshaler})
+	}
+	return val, nil
+}
+
+// process 处理解析后的配置：转换、blob、加密字段、secret引用及校验
+func (cfg *asyncConfig) process(val interface{}, blobs map[string][]byte) (interface{}, error) {
+	val, err := cfg.transform(val)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err = cfg.resolveBlobs(val, blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	var encryptedFields map[string]encryptedField
+	if cfg.fieldCrypto != nil {
+		if val, encryptedFields, err = cfg.fieldCrypto.decrypt(val); err != nil {
+			return nil, err
+		}
+	}
+
+	val, secretRefs, secretsExpireAt, err := resolveSecrets(val)
+	if err != nil {
+		return nil, err
+	}
+
+	if val, err = cfg.validate(val); err != nil {
+		return nil, err
+	}
+
+	cfg.secretRefs.Store(secretRefs)
+	if cfg.fieldCrypto != nil {
+		cfg.encryptedFields.Store(encryptedFields)
+	}
+	if secretsExpireAt.IsZero() {
+		atomic.StoreInt64(&cfg.secretsExpireAt, 0)
+	} else {
+		atomic.StoreInt64(&cfg.secretsExpireAt, secretsExpireAt.UnixNano())
+	}
+
+	return val, nil
+}
+
+// secretsExpired 配置中的密钥引用是否需要重新解析
+func (cfg *asyncConfig) secretsExpired() bool {
+	expireAt := atomic.LoadInt64(&cfg.secretsExpireAt)
+	return expireAt > 0 && _now().UnixNano() >= expireAt
+}
+
+// validate 使用schema校验配置并填充默认值
+func (cfg *asyncConfig) validate(val interface{}) (interface{}, error) {
+	if cfg.schema == nil {
+		return val, nil
+	}
+
+	ret, err := cfg.schema.Apply(val)
+	if err != nil {
+		return nil, validationError(err)
+	}
+
+	return ret, nil
 }
 
 // Set 设置配置
 //
 // 注意：配置自动刷新会覆盖手动设置的同名配置值
 func (cfg *asyncConfig) Set(keyPath string, value interface{}) error {
+	_, err := cfg.SetWithResult(keyPath, value)
+	return err
+}
+
+func (cfg *asyncConfig) SetWithResult(keyPath string, value interface{}) (WriteResult, error) {
+	if cfg.signingKeys != nil {
+		return WriteResult{}, errors.Wrapf(ErrReadOnly, "Set config[%s]: signed config is published by configctl sign", cfg.asyncKey)
+	}
+
 	cfg.Lock()
 	defer cfg.Unlock()
 
-	var iorigin interface{}
+	var newValue interface{}
 
 	if keyPath == RootKey {
-		cfg.value.Store(value)
+		newValue = value
 	} else {
-		iorigin = cfg.value.Load()
+		iorigin := cfg.value.Load()
 		if iorigin == nil {
 			iorigin = make(map[string]interface{})
 		}
 		origin, ok := iorigin.(map[string]interface{})
 		if !ok {
-			return errors.Errorf("Set config[%s] %s=%v error", cfg.asyncKey, keyPath, value)
+			return WriteResult{}, errors.Wrapf(typeMismatch(RootKey, errors.Errorf("%T is not a map", iorigin)), "Set config[%s] %s=%v error", cfg.asyncKey, keyPath, value)
 		}
-		newValue := deepcopy.Copy(origin).(map[string]interface{})
-		if err := setMapValue(newValue, keyPath, value); err != nil {
-			return err
+		newMap := deepcopy.Copy(origin).(map[string]interface{})
+		if err := setMapValue(newMap, keyPath, value); err != nil {
+			return WriteResult{}, err
 		}
-		cfg.value.Store(newValue)
+		newValue = newMap
 	}
 
-	data, err := cfg.marshaler.Marshal(cfg.value.Load())
+	newValue, err := cfg.validate(newValue)
 	if err != nil {
-		return err
+		return WriteResult{}, errors.Wrapf(err, "Set config[%s] %s=%v error", cfg.asyncKey, keyPath, value)
 	}
+	cfg.value.Store(newValue)
 
-	cfg.notify()
+	data, err := cfg.marshal(newValue)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	cfg.ryw.wrote(processRawMessage(data, cfg.contentType), _now())
+	if cfg.envelope {
+		publishedAt := _now()
+		if data, err = WrapEnvelope(data, publishedAt); err != nil {
+			return WriteResult{}, err
+		}
+		cfg.applied(publishedAt, publishedAt)
+	}
 
-	return cfg.asyncer.Set(cfg.asyncKey, data)
+	ret := WriteResult{Bytes: len(data), Notified: cfg.notify()}
+	ret.Version, err = setVersioned(cfg.asyncer, cfg.asyncKey, data)
+	return ret, backendError(cfg.asyncKey, err)
 }
 
-func (cfg *asyncConfig) notify() {
-	for _, notifier := range cfg.notifiers {
+// marshal 序列化配置用于写入后端
+func (cfg *asyncConfig) marshal(val interface{}) ([]byte, error) {
+	secretRefs, _ := cfg.secretRefs.Load().(map[string]secretRef)
+
+	if len(cfg.blobs) > 0 || len(secretRefs) > 0 || cfg.fieldCrypto != nil {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("blob, secret and encrypted field config requires a map")
+		}
+		m = deepcopy.Copy(m).(map[string]interface{})
+
+		restoreSecretRefs(m, secretRefs)
+
+		if cfg.fieldCrypto != nil {
+			fields, _ := cfg.encryptedFields.Load().(map[string]encryptedField)
+			if _, err := cfg.fieldCrypto.encrypt(m, fields); err != nil {
+				return nil, err
+			}
+		}
+
+		var err error
+		if val, err = cfg.storeBlobs(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg.writeMarshaler().Marshal(val)
+}
+
+// notify 通知配置变化，返回是否有通知发出（监听者未处理上一次通知时不重复发送）
+func (cfg *asyncConfig) notify() bool {
+	notified := false
+	notifiers, _ := cfg.notifiers.Load().([]chan struct{})
+	for _, notifier := range notifiers {
 		select {
 		case notifier <- struct{}{}:
+			notified = true
 		default:
+			cfg.emit(EventNotifyDropped, nil, false)
 		}
 	}
+	cfg.subs.dispatch()
+	return notified
+}
+
+// subscribe 关闭后不再订阅，关闭时清空所有回调
+func (cfg *asyncConfig) subscribe(s *subscription) {
+	cfg.bgMu.Lock()
+	defer cfg.bgMu.Unlock()
+	if cfg.closed() {
+		return
+	}
+	cfg.subs.subscribe(s)
+	atomic.AddInt64(&_resources.Subscriptions, 1)
+}
+
+func (cfg *asyncConfig) unsubscribe(s *subscription) {
+	if cfg.subs.unsubscribe(s) {
+		atomic.AddInt64(&_resources.Subscriptions, -1)
+	}
 }
 
 func (cfg *asyncConfig) Watch(notifier chan struct{}) {
 	cfg.Lock()
 	defer cfg.Unlock()
-	cfg.notifiers = append(cfg.notifiers, notifier)
+	notifiers, _ := cfg.notifiers.Load().([]chan struct{})
+	cfg.notifiers.Store(append(notifiers[:len(notifiers):len(notifiers)], notifier))
 }
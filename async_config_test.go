@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
@@ -25,6 +26,7 @@ func TestAsyncConfig(t *testing.T) {
 	asyncKey := "async_key1"
 	asyncer.Set(asyncKey, []byte(`{"custom":"custom"}`))
 	cfg := NewAsyncConfig(asyncer, asyncKey, 1000*time.Millisecond, false)
+	defer cfg.Close()
 	ast.Equal("custom", cfg.Get("custom"))
 	// get count = 1
 	ast.EqualValues(1, cfg.Get("ct"))
@@ -38,6 +40,7 @@ func TestAsyncConfig(t *testing.T) {
 	// 异步刷新
 	tm = tb
 	cfg2 := NewAsyncConfig(asyncer, asyncKey, 1000*time.Millisecond, true)
+	defer cfg2.Close()
 	// ct++ cause cfg2 initialition
 	ast.EqualValues(3, cfg2.Get("ct"))
 	//time.Sleep(1001 * time.Millisecond)
@@ -63,6 +66,7 @@ b:
   d: [3, 4]
 `))
 	cfg3 := NewAsyncConfig(asyncer, asyncKey, 1000*time.Millisecond, false)
+	defer cfg3.Close()
 	ast.EqualValues(1, cfg3.Get("a"))
 	asyncer.Set(asyncKey, []byte(`
 a: 2
@@ -70,3 +74,16 @@ a: 2
 	time.Sleep(1 * time.Millisecond) // wait for update
 	ast.EqualValues(2, cfg3.Get("a"))
 }
+
+func BenchmarkAsyncConfigRefreshUnchanged(b *testing.B) {
+	asyncer := rawAsyncer{NewMockAsyncer(false)}
+	asyncer.data.Store("bench.json", append([]byte(`{"a": 1}`), bytes.Repeat([]byte(" "), 64<<10)...))
+	cfg := NewAsyncConfig(asyncer, "bench.json", time.Hour, false).Configer.(*asyncConfig)
+	defer cfg.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cfg.refresh()
+	}
+}
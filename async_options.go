@@ -0,0 +1,39 @@
+package config
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+// AsyncOption NewAsyncConfig 的可选配置
+type AsyncOption func(cfg *asyncConfig)
+
+// WithSchema 刷新及Set时使用schema校验配置并填充默认值
+//
+// 刷新的内容校验失败时会保留旧值，Set的值校验失败时返回error
+func WithSchema(schema Schema) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.schema = schema
+	}
+}
+
+// WithMarshaler 使用指定的Marshaler解析及序列化内容，替代Asyncer.ContentType对应的Marshaler
+func WithMarshaler(marshaler Marshaler) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.marshaler = marshaler
+	}
+}
+
+// WithProtoMessage 内容为msg类型的protobuf消息
+func WithProtoMessage(msg proto.Message) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.marshaler = NewProtoMarshaler(msg)
+		cfg.contentType = T_PROTOBUF
+	}
+}
+
+// WithBlobs 指定二进制配置项，见 Blob
+func WithBlobs(blobs ...Blob) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.blobs = append(cfg.blobs, blobs...)
+	}
+}
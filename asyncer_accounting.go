@@ -0,0 +1,235 @@
+package config
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _accountingAsyncers sync.Map // Asyncer => *AccountingAsyncer
+
+// AccountStats 后端的调用统计
+type AccountStats struct {
+	Name     string
+	Gets     int64 // Get的次数
+	GetBytes int64 // Get返回的字节数
+	Misses   int64 // Get返回空内容的次数
+	Sets     int64
+	SetBytes int64
+	SetErrs  int64
+	Watches  int64
+}
+
+// AccountingAsyncer 统计后端的调用次数及传输的字节数，并上报 MetricBackendRequests、MetricBackendBytes，
+// 用于将配置服务的负载归属到使用方
+type AccountingAsyncer struct {
+	asyncer Asyncer
+	name    string
+
+	gets, getBytes, misses  int64
+	sets, setBytes, setErrs int64
+	watches                 int64
+}
+
+// Account 返回asyncer共享的统计包装，name 为指标及 AccountingStats 中的名称，
+// 同一asyncer多次调用返回同一个包装，之后调用的name与已有的不同时只记录警告
+//
+// 包装会一直保留在 AccountingStats 中，asyncer不再使用时调用 RemoveAccount；
+// asyncer不能比较（如包含slice、map的struct）时返回不共享、不在 AccountingStats 中的包装
+//
+//	cfg := config.NewAsyncConfig(config.Account(redis, "billing-redis"), "billing.json", time.Minute, true)
+func Account(asyncer Asyncer, name string) *AccountingAsyncer {
+	if !isComparable(asyncer) {
+		return &AccountingAsyncer{asyncer: asyncer, name: name}
+	}
+
+	v, loaded := _accountingAsyncers.Load(asyncer)
+	if !loaded {
+		v, loaded = _accountingAsyncers.LoadOrStore(asyncer, &AccountingAsyncer{asyncer: asyncer, name: name})
+	}
+	a := v.(*AccountingAsyncer)
+	if loaded && a.name != name {
+		logger.Warnf("asyncer %T already accounted as %s, ignore name %s", asyncer, a.name, name)
+	}
+	return a
+}
+
+// RemoveAccount 移除 Account 共享的包装，之后不再出现在 AccountingStats 中
+func RemoveAccount(asyncer Asyncer) {
+	if isComparable(asyncer) {
+		_accountingAsyncers.Delete(asyncer)
+	}
+}
+
+// AccountingStats 所有通过 Account 包装的后端的统计，按名称排序
+func AccountingStats() []AccountStats {
+	ret := make([]AccountStats, 0)
+	_accountingAsyncers.Range(func(_, v interface{}) bool {
+		ret = append(ret, v.(*AccountingAsyncer).Stats())
+		return true
+	})
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// Stats 返回统计
+func (a *AccountingAsyncer) Stats() AccountStats {
+	return AccountStats{
+		Name:     a.name,
+		Gets:     atomic.LoadInt64(&a.gets),
+		GetBytes: atomic.LoadInt64(&a.getBytes),
+		Misses:   atomic.LoadInt64(&a.misses),
+		Sets:     atomic.LoadInt64(&a.sets),
+		SetBytes: atomic.LoadInt64(&a.setBytes),
+		SetErrs:  atomic.LoadInt64(&a.setErrs),
+		Watches:  atomic.LoadInt64(&a.watches),
+	}
+}
+
+func (a *AccountingAsyncer) ContentType(key string) ContentType {
+	return a.asyncer.ContentType(key)
+}
+
+func (a *AccountingAsyncer) Get(key string) []byte {
+	ret := a.asyncer.Get(key)
+
+	atomic.AddInt64(&a.gets, 1)
+	atomic.AddInt64(&a.getBytes, int64(len(ret)))
+	if len(ret) == 0 {
+		atomic.AddInt64(&a.misses, 1)
+	}
+	m := loadMetrics()
+	m.Counter(MetricBackendRequests, 1, "backend", a.name, "op", "get")
+	m.Counter(MetricBackendBytes, float64(len(ret)), "backend", a.name, "op", "get")
+	return ret
+}
+
+func (a *AccountingAsyncer) Set(key string, value []byte) error {
+	return a.recordSet(value, a.asyncer.Set(key, value))
+}
+
+// SetVersioned 同 Set，asyncer未实现 VersionedSetter 时版本号为空
+func (a *AccountingAsyncer) SetVersioned(key string, value []byte) (string, error) {
+	version, err := setVersioned(a.asyncer, key, value)
+	return version, a.recordSet(value, err)
+}
+
+// CompareAndSet 计为一次Set，asyncer未实现 CASSetter 时返回错误
+func (a *AccountingAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	ok, err := compareAndSet(a.asyncer, key, old, value)
+	return ok, a.recordSet(value, err)
+}
+
+// SetWithTTL 计为一次Set，asyncer未实现 TTLSetter 时返回错误
+func (a *AccountingAsyncer) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return a.recordSet(value, setWithTTL(a.asyncer, key, value, ttl))
+}
+
+// BatchGet 每个key计为一次Get，不存在的key计为miss
+func (a *AccountingAsyncer) BatchGet(keys []string) (map[string][]byte, error) {
+	kvs, err := BatchGet(a.asyncer, keys)
+
+	var size int
+	for _, v := range kvs {
+		size += len(v)
+	}
+	atomic.AddInt64(&a.gets, int64(len(keys)))
+	atomic.AddInt64(&a.getBytes, int64(size))
+	atomic.AddInt64(&a.misses, int64(len(keys)-len(kvs)))
+	m := loadMetrics()
+	m.Counter(MetricBackendRequests, float64(len(keys)), "backend", a.name, "op", "get")
+	m.Counter(MetricBackendBytes, float64(size), "backend", a.name, "op", "get")
+	return kvs, err
+}
+
+func (a *AccountingAsyncer) recordSet(value []byte, err error) error {
+	atomic.AddInt64(&a.sets, 1)
+	atomic.AddInt64(&a.setBytes, int64(len(value)))
+	if err != nil {
+		atomic.AddInt64(&a.setErrs, 1)
+	}
+	m := loadMetrics()
+	m.Counter(MetricBackendRequests, 1, "backend", a.name, "op", "set")
+	m.Counter(MetricBackendBytes, float64(len(value)), "backend", a.name, "op", "set")
+	return err
+}
+
+func (a *AccountingAsyncer) Watch(key string) chan struct{} {
+	atomic.AddInt64(&a.watches, 1)
+	loadMetrics().Counter(MetricBackendRequests, 1, "backend", a.name, "op", "watch")
+	return a.asyncer.Watch(key)
+}
+
+func (a *AccountingAsyncer) ContentTypeHint(key string) (ContentType, bool) {
+	if h, ok := a.asyncer.(ContentTypeHinter); ok {
+		return h.ContentTypeHint(key)
+	}
+	return T_JSON, false
+}
+
+// GetReader 计为一次Get，读取的字节数在Close时计入
+func (a *AccountingAsyncer) GetReader(key string) (io.ReadCloser, error) {
+	rc, err := getReader(a.asyncer, key)
+
+	atomic.AddInt64(&a.gets, 1)
+	loadMetrics().Counter(MetricBackendRequests, 1, "backend", a.name, "op", "get")
+	if rc == nil {
+		atomic.AddInt64(&a.misses, 1)
+		return nil, err
+	}
+	return &accountingReader{ReadCloser: rc, a: a}, err
+}
+
+// List 计为一次list请求
+func (a *AccountingAsyncer) List(prefix string) ([]string, error) {
+	loadMetrics().Counter(MetricBackendRequests, 1, "backend", a.name, "op", "list")
+	return ListKeys(a.asyncer, prefix)
+}
+
+// ListPrefix 计为一次list请求，字节数为所有值的长度
+func (a *AccountingAsyncer) ListPrefix(prefix string) (map[string][]byte, error) {
+	kvs, err := listPrefix(a.asyncer, prefix)
+
+	var size int
+	for _, v := range kvs {
+		size += len(v)
+	}
+	m := loadMetrics()
+	m.Counter(MetricBackendRequests, 1, "backend", a.name, "op", "list")
+	m.Counter(MetricBackendBytes, float64(size), "backend", a.name, "op", "list")
+	return kvs, err
+}
+
+func (a *AccountingAsyncer) Capabilities() Capabilities {
+	return wrapperCapabilities(a.asyncer)
+}
+
+func (a *AccountingAsyncer) unwrap() Asyncer {
+	return a.asyncer
+}
+
+// accountingReader 统计 GetReader 读取的字节数
+type accountingReader struct {
+	io.ReadCloser
+	a    *AccountingAsyncer
+	size int64
+	once sync.Once
+}
+
+func (r *accountingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *accountingReader) Close() error {
+	r.once.Do(func() {
+		atomic.AddInt64(&r.a.getBytes, r.size)
+		loadMetrics().Counter(MetricBackendBytes, float64(r.size), "backend", r.a.name, "op", "get")
+	})
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountingAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	m := &testMetrics{counters: make(map[string]float64)}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	mock := NewMockAsyncer(true)
+	asyncer := Account(mock, "test-backend")
+	ast.Equal(asyncer, Account(mock, "other"), "shared")
+	ast.True(ProbeCapabilities(asyncer).Watch)
+
+	content := []byte(`{"a": 1}`)
+	mock.data.Store("account.json", content)
+	cfg := NewAsyncConfig(asyncer, "account.json", 0, false)
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a"))
+
+	asyncer.Get("missing.json")
+	ast.Nil(cfg.Set("a", 2))
+
+	stats := asyncer.Stats()
+	ast.Equal("test-backend", stats.Name)
+	ast.EqualValues(2, stats.Gets)
+	ast.EqualValues(1, stats.Misses)
+	ast.True(stats.GetBytes > int64(len(content)), "mock adds fields")
+	ast.EqualValues(1, stats.Sets)
+	ast.True(stats.SetBytes > 0)
+	ast.EqualValues(1, stats.Watches)
+	ast.Contains(AccountingStats(), stats)
+
+	ast.EqualValues(2, m.counters[MetricBackendRequests+"{backend,test-backend,op,get}"])
+	ast.EqualValues(1, m.counters[MetricBackendRequests+"{backend,test-backend,op,set}"])
+	ast.EqualValues(stats.GetBytes, m.counters[MetricBackendBytes+"{backend,test-backend,op,get}"])
+
+	failing := Account(&versionedAsyncer{rawAsyncer: rawAsyncer{NewMockAsyncer(false)}, err: errors.New("down")}, "failing")
+	_, err := failing.SetVersioned("k", []byte("v"))
+	ast.NotNil(err)
+	ast.EqualValues(1, failing.Stats().SetErrs)
+
+	RemoveAccount(mock)
+	ast.NotContains(AccountingStats(), asyncer.Stats())
+
+	uncomparable := Account(uncomparableAsyncer{Asyncer: mock}, "uncomparable")
+	ast.False(uncomparable == Account(uncomparableAsyncer{Asyncer: mock}, "uncomparable"))
+	for _, s := range AccountingStats() {
+		ast.NotEqual("uncomparable", s.Name)
+	}
+}
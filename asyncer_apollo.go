@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ApolloLongPollTimeout /notifications/v2 长轮询的超时时间，服务端在60秒无变化时返回304
+	ApolloLongPollTimeout = 90 * time.Second
+	// ApolloRequestTimeout 读取配置的超时时间
+	ApolloRequestTimeout = 10 * time.Second
+	// ApolloRetryInterval 监听失败后重试的间隔
+	ApolloRetryInterval = time.Second
+)
+
+// ApolloOptions apollo配置中心的访问选项
+type ApolloOptions struct {
+	AppID   string
+	Cluster string // 默认 default
+	Secret  string // 开启访问密钥时用于签名
+	IP      string // 用于灰度发布的客户端IP，可为空
+}
+
+// ApolloAsyncer 以apollo配置中心为数据源，key为namespace，Watch使用 /notifications/v2 长轮询
+//
+// properties格式的namespace（如 application）内容为其配置项组成的properties；
+// 其他格式的namespace（如 app.yaml、app.json）内容为原文。
+// 配置需在apollo portal中发布，Set返回 ErrReadOnly
+type ApolloAsyncer struct {
+	addr    string
+	options ApolloOptions
+	client  *http.Client
+
+	releaseKeys sync.Map // key => string，最近一次Get的releaseKey
+	contents    sync.Map // key => []byte，releaseKey对应的内容
+	notifyChans sync.Map // key => chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+// NewApolloAsyncer addr 为apollo config service的地址，如 "http://apollo-config:8080"
+//
+// opts: TLS及代理等连接选项，见 BackendOptions
+func NewApolloAsyncer(addr string, options *ApolloOptions, opts ...BackendOption) *ApolloAsyncer {
+	a := &ApolloAsyncer{
+		addr:    strings.TrimSuffix(addr, "/"),
+		options: *options,
+		client:  backendHTTPClient(opts),
+	}
+	if a.options.Cluster == "" {
+		a.options.Cluster = "default"
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	logger.Infof("NewApolloAsyncer:addr=%s,appId=%s,cluster=%s", a.addr, a.options.AppID, a.options.Cluster)
+
+	return a
+}
+
+// ContentType 以 .xml、.json、.yml、.yaml、.txt 结尾的namespace按后缀判断，其他为properties
+func (a *ApolloAsyncer) ContentType(key string) ContentType {
+	if apolloProperties(key) {
+		return T_PROPERTIES
+	}
+	return ContentTypeByExt(key)
+}
+
+// apolloProperties 是否为properties格式的namespace，其内容为配置项而不是原文
+func apolloProperties(key string) bool {
+	switch path.Ext(key) {
+	case ".xml", ".json", ".yml", ".yaml", ".txt":
+		return false
+	}
+	return true
+}
+
+// do GET请求，返回响应内容及状态码，304、404不视为错误
+func (a *ApolloAsyncer) do(ctx context.Context, pathAndQuery string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.addr+pathAndQuery, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if a.options.Secret != "" {
+		// 签名为 HmacSHA1(timestamp + "\n" + pathWithQuery)
+		timestamp := strconv.FormatInt(_now().UnixNano()/int64(time.Millisecond), 10)
+		mac := hmac.New(sha1.New, []byte(a.options.Secret))
+		mac.Write([]byte(timestamp + "\n" + pathAndQuery))
+		req.Header.Set("Authorization", "Apollo "+a.options.AppID+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		req.Header.Set("Timestamp", timestamp)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotModified, http.StatusNotFound:
+		return data, resp.StatusCode, nil
+	}
+	return nil, resp.StatusCode, errors.Errorf("apollo %s: status %d: %s", pathAndQuery, resp.StatusCode, bytes.TrimSpace(data))
+}
+
+// apolloConfig /configs 的响应
+type apolloConfig struct {
+	ReleaseKey     string            `json:"releaseKey"`
+	Configurations map[string]string `json:"configurations"`
+}
+
+// fetch 读取namespace的内容，releaseKey未变化时使用缓存；不存在时为nil
+func (a *ApolloAsyncer) fetch(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, ApolloRequestTimeout)
+	defer cancel()
+
+	query := url.Values{}
+	if v, ok := a.releaseKeys.Load(key); ok {
+		query.Set("releaseKey", v.(string))
+	}
+	if a.options.IP != "" {
+		query.Set("ip", a.options.IP)
+	}
+	p := "/configs/" + url.PathEscape(a.options.AppID) + "/" + url.PathEscape(a.options.Cluster) + "/" + url.PathEscape(key)
+	if len(query) > 0 {
+		p += "?" + query.Encode()
+	}
+
+	data, status, err := a.do(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusNotModified:
+		v, _ := a.contents.Load(key)
+		content, _ := v.([]byte)
+		return content, nil
+	case http.StatusNotFound:
+		a.releaseKeys.Delete(key)
+		a.contents.Delete(key)
+		return nil, nil
+	}
+
+	var ret apolloConfig
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, errors.Wrapf(err, "apollo namespace[%s]", key)
+	}
+
+	var content []byte
+	if apolloProperties(key) {
+		m := make(map[string]interface{}, len(ret.Configurations))
+		for k, v := range ret.Configurations {
+			m[k] = v
+		}
+		if content, err = (PropertiesMarshaler{}).Marshal(m); err != nil {
+			return nil, errors.Wrapf(err, "apollo namespace[%s]", key)
+		}
+	} else {
+		content = []byte(ret.Configurations["content"])
+	}
+
+	a.releaseKeys.Store(key, ret.ReleaseKey)
+	a.contents.Store(key, content)
+	return content, nil
+}
+
+// Get 不存在或请求失败时返回nil
+func (a *ApolloAsyncer) Get(key string) []byte {
+	content, err := a.fetch(key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from apollo err:%v", key, err)
+		return nil
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	return content
+}
+
+// Set apollo的配置需在portal中编辑并发布
+func (a *ApolloAsyncer) Set(key string, value []byte) error {
+	return errors.Wrapf(ErrReadOnly, "apollo namespace[%s]: publish in the portal", key)
+}
+
+// apolloNotification /notifications/v2 的请求及响应条目
+type apolloNotification struct {
+	NamespaceName  string `json:"namespaceName"`
+	NotificationID int64  `json:"notificationId"`
+}
+
+// Watch 长轮询namespace的发布通知，失败时每隔 ApolloRetryInterval 重试
+func (a *ApolloAsyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	go a.watch(key, ch)
+
+	return ch
+}
+
+func (a *ApolloAsyncer) watch(key string, ch chan struct{}) {
+	// 第一次请求使用-1，立即返回当前的notificationId
+	id := int64(-1)
+	failed := false
+	for {
+		notifications, _ := json.Marshal([]apolloNotification{{NamespaceName: key, NotificationID: id}})
+		query := url.Values{
+			"appId":         {a.options.AppID},
+			"cluster":       {a.options.Cluster},
+			"notifications": {string(notifications)},
+		}
+
+		ctx, cancel := context.WithTimeout(a.ctx, ApolloLongPollTimeout)
+		data, status, err := a.do(ctx, "/notifications/v2?"+query.Encode())
+		cancel()
+
+		if a.ctx.Err() != nil {
+			return
+		}
+		var ret []apolloNotification
+		switch {
+		case err != nil, status == http.StatusNotModified:
+		case status == http.StatusOK:
+			err = errors.Wrapf(json.Unmarshal(data, &ret), "apollo notifications[%s]", key)
+		default:
+			err = errors.Errorf("apollo notifications[%s]: status %d", key, status)
+		}
+		if err != nil {
+			logger.Warnf("watch conf[%s] from apollo err:%v", key, err)
+			failed = true
+			select {
+			case <-a.ctx.Done():
+				return
+			case <-time.After(ApolloRetryInterval):
+			}
+			continue
+		}
+		if failed {
+			failed = false
+			emitBackendEvent(EventWatchReconnected, a, key)
+		}
+
+		for _, n := range ret {
+			if n.NamespaceName != key || n.NotificationID == id {
+				continue
+			}
+			if id == -1 {
+				// Get之后、开始监听之前的发布
+				prev, _ := a.releaseKeys.Load(key)
+				a.fetch(key)
+				if next, _ := a.releaseKeys.Load(key); next == prev {
+					id = n.NotificationID
+					continue
+				}
+			}
+			id = n.NotificationID
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Capabilities 见 CapabilityReporter
+func (a *ApolloAsyncer) Capabilities() Capabilities {
+	return Capabilities{Watch: true}
+}
+
+// Close 停止所有Watch
+func (a *ApolloAsyncer) Close() error {
+	a.cancel()
+	return nil
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeApollo 内存中的apollo config service，支持releaseKey缓存、发布通知及访问密钥
+type fakeApollo struct {
+	sync.Mutex
+	secret     string
+	namespaces map[string]map[string]string // namespace => configurations
+	releases   map[string]int64
+	full       int
+}
+
+func (s *fakeApollo) publish(namespace string, configurations map[string]string) {
+	s.Lock()
+	defer s.Unlock()
+	s.namespaces[namespace] = configurations
+	s.releases[namespace]++
+}
+
+func (s *fakeApollo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.secret != "" {
+		mac := hmac.New(sha1.New, []byte(s.secret))
+		mac.Write([]byte(r.Header.Get("Timestamp") + "\n" + r.URL.RequestURI()))
+		if r.Header.Get("Authorization") != "Apollo billing:"+base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if r.URL.Path == "/notifications/v2" {
+		var ns []apolloNotification
+		json.Unmarshal([]byte(r.FormValue("notifications")), &ns)
+		deadline := time.Now().Add(200 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			var changed []apolloNotification
+			for _, n := range ns {
+				if id := s.releases[n.NamespaceName]; id != n.NotificationID {
+					changed = append(changed, apolloNotification{NamespaceName: n.NamespaceName, NotificationID: id})
+				}
+			}
+			if len(changed) > 0 {
+				json.NewEncoder(w).Encode(changed)
+				return
+			}
+			s.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			s.Lock()
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// /configs/{appId}/{cluster}/{namespace}
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 5 || parts[2] != "billing" || parts[3] != "default" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	configurations, ok := s.namespaces[parts[4]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	releaseKey := fmt.Sprint(s.releases[parts[4]])
+	if r.FormValue("releaseKey") == releaseKey {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.full++
+	json.NewEncoder(w).Encode(apolloConfig{ReleaseKey: releaseKey, Configurations: configurations})
+}
+
+func TestApolloAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	store := &fakeApollo{
+		secret:     "secret",
+		namespaces: make(map[string]map[string]string),
+		releases:   make(map[string]int64),
+	}
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	a := NewApolloAsyncer(server.URL, &ApolloOptions{AppID: "billing", Secret: "secret"})
+	defer a.Close()
+
+	ast.Equal(T_PROPERTIES, a.ContentType("application"))
+	ast.Equal(T_PROPERTIES, a.ContentType("TEST1.public"))
+	ast.Equal(T_YAML, a.ContentType("app.yaml"))
+	ast.True(errors.Is(a.Set("application", nil), ErrReadOnly))
+	ast.Nil(a.Get("application"))
+
+	// properties的namespace
+	store.publish("application", map[string]string{"db.host": "127.0.0.1", "timeout": "3"})
+	cfg := NewAsyncConfig(a, "application", time.Hour, false)
+	ch := make(chan struct{}, 1)
+	cfg.Watch(ch)
+	ast.Equal("127.0.0.1", cfg.String("db.host"))
+	ast.EqualValues(3, cfg.Int("timeout"))
+
+	// releaseKey未变化时使用缓存
+	ast.NotNil(a.Get("application"))
+	store.Lock()
+	ast.Equal(1, store.full)
+	store.Unlock()
+
+	store.publish("application", map[string]string{"db.host": "10.0.0.1"})
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		ast.Fail("not notified")
+	}
+	ast.Equal("10.0.0.1", cfg.String("db.host"))
+
+	// 其他格式的namespace
+	store.publish("app.yaml", map[string]string{"content": "a:\n  b: 1\n"})
+	ast.Equal("a:\n  b: 1\n", string(a.Get("app.yaml")))
+
+	// 签名错误
+	b := NewApolloAsyncer(server.URL, &ApolloOptions{AppID: "billing", Secret: "wrong"})
+	defer b.Close()
+	ast.Nil(b.Get("application"))
+}
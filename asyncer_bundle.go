@@ -0,0 +1,310 @@
+package config
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// BundleMaxSize bundle解压后所有文档的最大总长度
+var BundleMaxSize int64 = 64 << 20
+
+// BundleAsyncer 将后端的一个bundle（tar、tar.gz、zip或多文档YAML）展开为多个独立的文档，
+// key为文档名，各文档的变化单独通知，只读
+//
+// 依赖很多文档的服务只需读取及监听一个后端key：
+//
+//	bundle := config.NewBundleAsyncer(httpAsyncer, "app/bundle.tar.gz")
+//	dbCfg := config.NewAsyncConfig(bundle, "db.yaml", time.Minute, true)
+//	cacheCfg := config.NewAsyncConfig(bundle, "cache.json", time.Minute, true)
+//
+// 格式按内容判断；多文档YAML以 "---" 分隔，每个文档的首行为 "# name: <文档名>"，没有名称的文档被忽略：
+//
+//	# name: db.yaml
+//	host: example.com
+//	---
+//	# name: cache.yaml
+//	ttl: 60
+type BundleAsyncer struct {
+	asyncer Asyncer
+	key     string
+	group   singleflight.Group
+
+	sync.Mutex
+	docs        map[string][]byte // 最近一次展开的文档
+	yaml        bool              // 最近一次为多文档YAML
+	watching    bool              // 已监听后端，docs随变化更新
+	notifyChans map[string]chan struct{}
+}
+
+// NewBundleAsyncer key为bundle在asyncer中的key
+func NewBundleAsyncer(asyncer Asyncer, key string) *BundleAsyncer {
+	return &BundleAsyncer{
+		asyncer:     asyncer,
+		key:         key,
+		notifyChans: make(map[string]chan struct{}),
+	}
+}
+
+// ContentType 按文档名的后缀判断，多文档YAML中没有后缀的文档为YAML
+func (a *BundleAsyncer) ContentType(name string) ContentType {
+	a.Lock()
+	yaml := a.yaml
+	a.Unlock()
+
+	if yaml && path.Ext(name) == "" {
+		return T_YAML
+	}
+	return ContentTypeByExt(name)
+}
+
+// Get 监听后端后使用随变化更新的文档，否则每次读取bundle（并发的读取合并为一次）
+func (a *BundleAsyncer) Get(name string) []byte {
+	a.Lock()
+	docs, watching := a.docs, a.watching
+	a.Unlock()
+
+	if !watching || docs == nil {
+		var err error
+		if docs, err = a.load(); err != nil {
+			logger.Errorf("load bundle[%s] err:%v", a.key, err)
+			return nil
+		}
+	}
+	return docs[name]
+}
+
+func (a *BundleAsyncer) Set(name string, content []byte) error {
+	return errors.Wrapf(ErrReadOnly, "bundle[%s]", a.key)
+}
+
+// Names 最近一次展开的文档名
+func (a *BundleAsyncer) Names() []string {
+	a.Lock()
+	defer a.Unlock()
+
+	names := make([]string, 0, len(a.docs))
+	for name := range a.docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Watch 监听bundle，只在该文档的内容变化（包括新增及删除）时通知；后端不支持通知时返回nil
+func (a *BundleAsyncer) Watch(name string) chan struct{} {
+	a.Lock()
+	defer a.Unlock()
+
+	if ch, ok := a.notifyChans[name]; ok {
+		return ch
+	}
+	if !a.watching {
+		notify := a.asyncer.Watch(a.key)
+		if notify == nil {
+			return nil
+		}
+		a.watching = true
+		go a.watch(notify)
+	}
+
+	ch := make(chan struct{}, 1)
+	a.notifyChans[name] = ch
+	return ch
+}
+
+func (a *BundleAsyncer) watch(notify chan struct{}) {
+	for range notify {
+		if _, err := a.load(); err != nil {
+			logger.Errorf("reload bundle[%s] err:%v", a.key, err)
+		}
+	}
+}
+
+// load 读取并展开bundle，通知内容变化的文档
+func (a *BundleAsyncer) load() (map[string][]byte, error) {
+	v, err, _ := a.group.Do("", func() (interface{}, error) {
+		content := a.asyncer.Get(a.key)
+		if content == nil {
+			return nil, backendError(a.key, errors.New("empty content"))
+		}
+		docs, yaml, err := explodeBundle(content)
+		if err != nil {
+			return nil, err
+		}
+
+		a.Lock()
+		prev := a.docs
+		a.docs, a.yaml = docs, yaml
+		var changed []chan struct{}
+		for name, ch := range a.notifyChans {
+			old, had := prev[name]
+			doc, has := docs[name]
+			if prev == nil || had != has || !bytes.Equal(old, doc) {
+				changed = append(changed, ch)
+			}
+		}
+		a.Unlock()
+
+		for _, ch := range changed {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+		return docs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string][]byte), nil
+}
+
+// explodeBundle 按内容判断格式并展开，yaml表示是否为多文档YAML
+func explodeBundle(content []byte) (docs map[string][]byte, yaml bool, err error) {
+	switch {
+	case bytes.HasPrefix(content, []byte("\x1f\x8b")):
+		r, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, false, errors.Wrap(err, "gzip")
+		}
+		defer r.Close()
+		docs, err = explodeTar(r)
+		return docs, false, err
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")):
+		docs, err = explodeZip(content)
+		return docs, false, err
+	case len(content) > 262 && string(content[257:262]) == "ustar":
+		docs, err = explodeTar(bytes.NewReader(content))
+		return docs, false, err
+	}
+	docs, err = explodeYAML(content)
+	return docs, true, err
+}
+
+// bundleName 归一化的文档名，目录等返回false
+func bundleName(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if name == "." || name == "/" {
+		return "", false
+	}
+	return strings.TrimPrefix(name, "/"), true
+}
+
+// readBundleDoc 读取文档，总长度超过 BundleMaxSize 时返回错误
+func readBundleDoc(r io.Reader, total *int64) ([]byte, error) {
+	content, err := io.ReadAll(io.LimitReader(r, BundleMaxSize-*total+1))
+	if err != nil {
+		return nil, err
+	}
+	if *total += int64(len(content)); *total > BundleMaxSize {
+		return nil, errors.Errorf("bundle too large: > %d", BundleMaxSize)
+	}
+	return content, nil
+}
+
+func explodeTar(r io.Reader) (map[string][]byte, error) {
+	docs := make(map[string][]byte)
+	var total int64
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "tar")
+		}
+		name, ok := bundleName(h.Name)
+		if h.Typeflag != tar.TypeReg || !ok {
+			continue
+		}
+		if docs[name], err = readBundleDoc(tr, &total); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func explodeZip(content []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, errors.Wrap(err, "zip")
+	}
+
+	docs := make(map[string][]byte)
+	var total int64
+	for _, f := range zr.File {
+		name, ok := bundleName(f.Name)
+		if f.FileInfo().IsDir() || !ok {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "zip[%s]", f.Name)
+		}
+		docs[name], err = readBundleDoc(r, &total)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// explodeYAML 以 "---" 分隔的文档，首行为 "# name: <文档名>"
+func explodeYAML(content []byte) (map[string][]byte, error) {
+	docs := make(map[string][]byte)
+
+	var doc bytes.Buffer
+	flush := func() {
+		name, ok := yamlDocName(doc.Bytes())
+		if ok {
+			docs[name] = append([]byte(nil), doc.Bytes()...)
+		} else if len(bytes.TrimSpace(doc.Bytes())) > 0 {
+			logger.Warnf("bundle yaml document without name ignored")
+		}
+		doc.Reset()
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(content))
+	s.Buffer(nil, int(BundleMaxSize))
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line == "---" || strings.HasPrefix(line, "--- ") {
+			flush()
+			continue
+		}
+		doc.Write(s.Bytes())
+		doc.WriteByte('\n')
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "yaml bundle")
+	}
+	flush()
+	return docs, nil
+}
+
+// yamlDocName 文档的第一个非空行为 "# name: <文档名>" 时返回文档名
+func yamlDocName(doc []byte) (string, bool) {
+	for _, line := range strings.Split(string(doc), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		if !strings.HasPrefix(line, "#") || !strings.HasPrefix(name, "name:") {
+			return "", false
+		}
+		return bundleName(strings.TrimSpace(strings.TrimPrefix(name, "name:")))
+	}
+	return "", false
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func tarBundle(t *testing.T, gz bool, docs map[string]string) []byte {
+	var buf bytes.Buffer
+	var tw *tar.Writer
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(zw)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+	assert.Nil(t, tw.WriteHeader(&tar.Header{Name: "conf/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, content := range docs {
+		assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+	if zw != nil {
+		assert.Nil(t, zw.Close())
+	}
+	return buf.Bytes()
+}
+
+func zipBundle(t *testing.T, docs map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	_, err := zw.Create("conf/")
+	assert.Nil(t, err)
+	for name, content := range docs {
+		w, err := zw.Create(name)
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestExplodeBundle(t *testing.T) {
+	ast := assert.New(t)
+
+	docs := map[string]string{
+		"./db.yaml":       "host: example.com\n",
+		"conf/cache.json": `{"ttl": 60}`,
+	}
+	want := map[string][]byte{
+		"db.yaml":         []byte("host: example.com\n"),
+		"conf/cache.json": []byte(`{"ttl": 60}`),
+	}
+
+	for name, content := range map[string][]byte{
+		"tar":    tarBundle(t, false, docs),
+		"tar.gz": tarBundle(t, true, docs),
+		"zip":    zipBundle(t, docs),
+	} {
+		exploded, yaml, err := explodeBundle(content)
+		ast.Nil(err, name)
+		ast.False(yaml, name)
+		ast.Equal(want, exploded, name)
+	}
+
+	exploded, yaml, err := explodeBundle([]byte(strings.Join([]string{
+		"# name: db",
+		"host: example.com",
+		"---",
+		"",
+		"#name: cache.json",
+		`{"ttl": 60}`,
+		"--- # without name",
+		"ignored: true",
+		"---",
+	}, "\n")))
+	ast.Nil(err)
+	ast.True(yaml)
+	ast.Equal(map[string][]byte{
+		"db":         []byte("# name: db\nhost: example.com\n"),
+		"cache.json": []byte("\n#name: cache.json\n{\"ttl\": 60}\n"),
+	}, exploded)
+
+	_, _, err = explodeBundle([]byte("\x1f\x8bbroken"))
+	ast.NotNil(err)
+
+	total := BundleMaxSize - 2
+	_, err = readBundleDoc(strings.NewReader("abcd"), &total)
+	ast.NotNil(err, "too large")
+}
+
+func TestBundleAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	bus := &memoryBus{}
+	backend, err := NewBusAsyncer(bus, nil)
+	ast.Nil(err)
+	publish := func(docs map[string]string) {
+		ast.Nil(bus.Publish(BusEvent{Key: "app/bundle.tgz", Payload: tarBundle(t, true, docs)}))
+	}
+
+	bundle := NewBundleAsyncer(backend, "app/bundle.tgz")
+	ast.Nil(bundle.Get("db.yaml"), "empty bundle")
+	ast.True(errors.Is(bundle.Set("db.yaml", nil), ErrReadOnly))
+
+	publish(map[string]string{
+		"db.yaml":    "host: example.com\n",
+		"cache.json": `{"ttl": 60}`,
+	})
+	ast.Equal([]byte(`{"ttl": 60}`), bundle.Get("cache.json"))
+	ast.Equal([]string{"cache.json", "db.yaml"}, bundle.Names())
+	ast.Equal(T_YAML, bundle.ContentType("db.yaml"))
+	ast.Equal(T_JSON, bundle.ContentType("cache.json"))
+
+	dbCfg := NewAsyncConfig(bundle, "db.yaml", time.Hour, false)
+	defer dbCfg.Close()
+	cacheCfg := NewAsyncConfig(bundle, "cache.json", time.Hour, false)
+	defer cacheCfg.Close()
+	ast.Equal("example.com", dbCfg.String("host"))
+	ast.EqualValues(60, cacheCfg.Int("ttl"))
+
+	publish(map[string]string{
+		"db.yaml":    "host: db.example.com\n",
+		"cache.json": `{"ttl": 60}`,
+	})
+	ast.Eventually(func() bool {
+		return dbCfg.String("host") == "db.example.com"
+	}, time.Second, 5*time.Millisecond)
+
+	// 只通知变化的文档，包括删除的；监听后使用随变化更新的文档
+	other, err := NewBusAsyncer(bus, nil)
+	ast.Nil(err)
+	watched := NewBundleAsyncer(other, "app/bundle.tgz")
+	ast.Nil(watched.Get("db.yaml"), "not received yet")
+	publish(map[string]string{
+		"db.yaml":    "host: db.example.com\n",
+		"cache.json": `{"ttl": 60}`,
+	})
+	ast.NotNil(watched.Get("db.yaml"))
+	dbCh, cacheCh := watched.Watch("db.yaml"), watched.Watch("cache.json")
+	publish(map[string]string{"cache.json": `{"ttl": 60}`})
+	select {
+	case <-dbCh:
+	case <-time.After(time.Second):
+		ast.Fail("removed document not notified")
+	}
+	select {
+	case <-cacheCh:
+		ast.Fail("unchanged document notified")
+	default:
+	}
+	ast.Nil(watched.Get("db.yaml"))
+	ast.Equal([]byte(`{"ttl": 60}`), watched.Get("cache.json"))
+
+	// 后端不支持通知
+	ast.Nil(NewBundleAsyncer(NewMockAsyncer(false), "bundle.yaml").Watch("db"))
+}
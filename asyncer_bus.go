@@ -0,0 +1,114 @@
+package config
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BusEvent 消息总线上的配置变化消息
+//
+// Payload 为变化后的内容；为nil时只通知变化，内容从store获取（没有store时视为删除）
+type BusEvent struct {
+	Key     string
+	Payload []byte
+}
+
+// Bus 消息总线（如NATS subject、Kafka topic），实现见 contrib/natsasyncer、contrib/kafkaasyncer
+type Bus interface {
+	// Subscribe 开始接收消息，handler按消息的顺序串行调用
+	Subscribe(handler func(BusEvent)) error
+	Publish(event BusEvent) error
+	Close() error
+}
+
+// BusAsyncer Watch由消息总线推送的 BusEvent 驱动，不需要轮询
+//
+// 内容可以由消息携带（如compact的Kafka topic），也可以只通知变化、从store（如数据库、对象存储）获取：
+//
+//	asyncer, err := config.NewBusAsyncer(bus, nil)      // 内容在消息中
+//	asyncer, err := config.NewBusAsyncer(bus, s3Store)  // 消息只通知变化
+type BusAsyncer struct {
+	bus   Bus
+	store Asyncer // 可为nil
+
+	payloads    sync.Map // key => []byte，最近一次消息携带的内容
+	notifyChans sync.Map // key => chan struct{}
+	closeOnce   sync.Once
+}
+
+var _ CapabilityReporter = (*BusAsyncer)(nil)
+
+// NewBusAsyncer 订阅bus，store为nil时内容只来自消息
+func NewBusAsyncer(bus Bus, store Asyncer) (*BusAsyncer, error) {
+	a := &BusAsyncer{bus: bus, store: store}
+	if err := bus.Subscribe(a.onEvent); err != nil {
+		return nil, errors.Wrap(err, "subscribe bus")
+	}
+	return a, nil
+}
+
+func (a *BusAsyncer) onEvent(e BusEvent) {
+	if e.Payload != nil {
+		a.payloads.Store(e.Key, e.Payload)
+	} else {
+		a.payloads.Delete(e.Key)
+	}
+
+	if ch, ok := a.notifyChans.Load(e.Key); ok {
+		select {
+		case ch.(chan struct{}) <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (a *BusAsyncer) ContentType(key string) ContentType {
+	if a.store != nil {
+		return a.store.ContentType(key)
+	}
+	return ContentTypeByExt(key)
+}
+
+// Get 优先返回消息携带的内容，否则从store获取
+func (a *BusAsyncer) Get(key string) []byte {
+	if payload, ok := a.payloads.Load(key); ok {
+		return payload.([]byte)
+	}
+	if a.store != nil {
+		return a.store.Get(key)
+	}
+	return nil
+}
+
+// Set 有store时写入store后发布变化通知，否则发布携带内容的消息
+func (a *BusAsyncer) Set(key string, value []byte) error {
+	if a.store == nil {
+		return a.bus.Publish(BusEvent{Key: key, Payload: value})
+	}
+
+	if err := a.store.Set(key, value); err != nil {
+		return err
+	}
+	a.payloads.Delete(key)
+	return errors.Wrapf(a.bus.Publish(BusEvent{Key: key}), "publish bus event[%s]", key)
+}
+
+func (a *BusAsyncer) Watch(key string) chan struct{} {
+	ch, _ := a.notifyChans.LoadOrStore(key, make(chan struct{}, 1))
+	return ch.(chan struct{})
+}
+
+// Capabilities 见 CapabilityReporter
+func (a *BusAsyncer) Capabilities() Capabilities {
+	return Capabilities{Watch: true}
+}
+
+// Close 关闭bus，不关闭store
+func (a *BusAsyncer) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		err = a.bus.Close()
+	})
+	return err
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryBus 进程内的消息总线，所有订阅者都会收到消息
+type memoryBus struct {
+	sync.Mutex
+	handlers []func(BusEvent)
+	closed   bool
+}
+
+func (b *memoryBus) Subscribe(handler func(BusEvent)) error {
+	b.Lock()
+	defer b.Unlock()
+	if b.closed {
+		return errors.New("bus closed")
+	}
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *memoryBus) Publish(e BusEvent) error {
+	b.Lock()
+	defer b.Unlock()
+	if b.closed {
+		return errors.New("bus closed")
+	}
+	for _, h := range b.handlers {
+		h(e)
+	}
+	return nil
+}
+
+func (b *memoryBus) Close() error {
+	b.Lock()
+	defer b.Unlock()
+	b.closed = true
+	return nil
+}
+
+func TestBusAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	bus := &memoryBus{}
+	a, err := NewBusAsyncer(bus, nil)
+	ast.Nil(err)
+	b, err := NewBusAsyncer(bus, nil)
+	ast.Nil(err)
+
+	ast.Nil(a.Get("app.json"))
+	ast.True(ProbeCapabilities(a).Watch)
+	ast.Equal(T_JSON, a.ContentType("app.json"))
+
+	ch := b.Watch("app.json")
+	ast.Equal(ch, b.Watch("app.json"))
+
+	// 其他实例发布的内容
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		ast.Fail("not notified")
+	}
+	ast.Equal(`{"a": 1}`, string(b.Get("app.json")))
+
+	cfg := NewAsyncConfig(b, "app.json", time.Hour, false)
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.Nil(bus.Publish(BusEvent{Key: "app.json", Payload: []byte(`{"a": 2}`)}))
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 2
+	}, time.Second, 5*time.Millisecond)
+
+	// 没有store时nil视为删除
+	ast.Nil(bus.Publish(BusEvent{Key: "app.json"}))
+	ast.Nil(b.Get("app.json"))
+
+	ast.Nil(a.Close())
+	ast.NotNil(a.Set("app.json", []byte(`{}`)))
+	_, err = NewBusAsyncer(bus, nil)
+	ast.NotNil(err)
+}
+
+func TestBusAsyncerWithStore(t *testing.T) {
+	ast := assert.New(t)
+
+	bus := &memoryBus{}
+	store := NewMockAsyncer(false)
+	a, err := NewBusAsyncer(bus, store)
+	ast.Nil(err)
+	b, err := NewBusAsyncer(bus, store)
+	ast.Nil(err)
+
+	cfg := NewAsyncConfig(b, "app.json", time.Hour, false)
+	// 写入store，消息只通知变化
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 1
+	}, time.Second, 5*time.Millisecond)
+	raw, _ := store.data.Load("app.json")
+	ast.Equal(`{"a": 1}`, string(raw.([]byte)))
+
+	// 消息携带的内容优先
+	ast.Nil(bus.Publish(BusEvent{Key: "app.json", Payload: []byte(`{"a": 2}`)}))
+	ast.Equal(`{"a": 2}`, string(b.Get("app.json")))
+	ast.Nil(bus.Publish(BusEvent{Key: "app.json"}))
+	ast.Contains(string(b.Get("app.json")), `"a":1`)
+}
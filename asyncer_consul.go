@@ -1,2 +1,264 @@
-// todo: config from consul
 package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ConsulWaitTime blocking query的最长等待时间
+	ConsulWaitTime = 5 * time.Minute
+	// ConsulRequestTimeout 非blocking请求的超时时间
+	ConsulRequestTimeout = 10 * time.Second
+	// ConsulRetryInterval blocking query失败后重试的间隔
+	ConsulRetryInterval = time.Second
+)
+
+// ConsulOptions consul KV的访问选项
+type ConsulOptions struct {
+	Datacenter string // 为空时使用agent所在的datacenter
+	Token      string // ACL token，通过 X-Consul-Token 头发送
+	Prefix     string // 所有key的前缀，如 "config/"
+}
+
+// ConsulAsyncer 以consul KV为数据源，Watch使用blocking query
+type ConsulAsyncer struct {
+	addr    string
+	options ConsulOptions
+	client  *http.Client
+
+	indexes     sync.Map // key => uint64，最近一次Get的X-Consul-Index
+	notifyChans sync.Map // key => chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+var _ CASSetter = (*ConsulAsyncer)(nil)
+
+// NewConsulAsyncer addr 为consul的http地址，如 "http://127.0.0.1:8500"，options可为nil
+//
+// opts: TLS及代理等连接选项，见 BackendOptions
+func NewConsulAsyncer(addr string, options *ConsulOptions, opts ...BackendOption) *ConsulAsyncer {
+	a := &ConsulAsyncer{
+		addr:   strings.TrimSuffix(addr, "/"),
+		client: backendHTTPClient(opts),
+	}
+	if options != nil {
+		a.options = *options
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	logger.Infof("NewConsulAsyncer:addr=%s,dc=%s", a.addr, a.options.Datacenter)
+
+	return a
+}
+
+func (a *ConsulAsyncer) ContentType(key string) ContentType {
+	return ContentTypeByExt(key)
+}
+
+// consulKV /v1/kv 返回的条目
+type consulKV struct {
+	ModifyIndex uint64
+	Value       []byte // base64解码
+}
+
+// do 请求 /v1/kv/<prefix+key>，返回响应及X-Consul-Index
+func (a *ConsulAsyncer) do(ctx context.Context, method, key string, query url.Values, body []byte) ([]byte, uint64, int, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if a.options.Datacenter != "" {
+		query.Set("dc", a.options.Datacenter)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/kv/%s?%s", a.addr, a.options.Prefix+key, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if a.options.Token != "" {
+		req.Header.Set("X-Consul-Token", a.options.Token)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, resp.StatusCode, err
+	}
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return nil, index, resp.StatusCode, errors.Errorf("consul kv[%s]: status %d: %s", key, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return data, index, resp.StatusCode, nil
+}
+
+// get 返回key的条目，不存在时为nil
+func (a *ConsulAsyncer) get(ctx context.Context, key string, query url.Values) (*consulKV, uint64, error) {
+	data, index, status, err := a.do(ctx, http.MethodGet, key, query, nil)
+	if err != nil || status == http.StatusNotFound {
+		return nil, index, err
+	}
+
+	var kvs []consulKV
+	if err := json.Unmarshal(data, &kvs); err != nil {
+		return nil, index, errors.Wrapf(err, "consul kv[%s]", key)
+	}
+	if len(kvs) == 0 {
+		return nil, index, nil
+	}
+	return &kvs[0], index, nil
+}
+
+func (a *ConsulAsyncer) Get(key string) []byte {
+	ctx, cancel := context.WithTimeout(a.ctx, ConsulRequestTimeout)
+	defer cancel()
+
+	kv, index, err := a.get(ctx, key, nil)
+	if err != nil {
+		logger.Errorf("read conf[%s] from consul err:%v", key, err)
+		return nil
+	}
+	a.indexes.Store(key, index)
+
+	if kv == nil || len(kv.Value) == 0 {
+		return nil
+	}
+	return kv.Value
+}
+
+func (a *ConsulAsyncer) put(key string, value []byte, query url.Values) (bool, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, ConsulRequestTimeout)
+	defer cancel()
+
+	data, _, _, err := a.do(ctx, http.MethodPut, key, query, value)
+	if err != nil {
+		return false, err
+	}
+	return string(bytes.TrimSpace(data)) == "true", nil
+}
+
+func (a *ConsulAsyncer) Set(key string, value []byte) error {
+	ok, err := a.put(key, value, nil)
+	if err == nil && !ok {
+		err = errors.Errorf("consul kv[%s]: put failed", key)
+	}
+	return err
+}
+
+// CompareAndSet 使用check-and-set（?cas=ModifyIndex），old为nil时要求key不存在
+func (a *ConsulAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, ConsulRequestTimeout)
+	defer cancel()
+
+	kv, _, err := a.get(ctx, key, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var index uint64
+	if kv != nil {
+		if old == nil || !bytes.Equal(kv.Value, old) {
+			return false, nil
+		}
+		index = kv.ModifyIndex
+	} else if old != nil {
+		return false, nil
+	}
+
+	return a.put(key, value, url.Values{"cas": {strconv.FormatUint(index, 10)}})
+}
+
+// Watch 使用blocking query监听变化，从最近一次Get的index开始，失败时每隔 ConsulRetryInterval 重试
+func (a *ConsulAsyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	var index uint64
+	if v, ok := a.indexes.Load(key); ok {
+		index = v.(uint64)
+	}
+	go a.watch(key, index, ch)
+
+	return ch
+}
+
+func (a *ConsulAsyncer) watch(key string, index uint64, ch chan struct{}) {
+	wait := strconv.FormatInt(int64(ConsulWaitTime/time.Second), 10) + "s"
+	failed := false
+	for {
+		ctx, cancel := context.WithTimeout(a.ctx, ConsulWaitTime+ConsulRequestTimeout)
+		_, next, err := a.get(ctx, key, url.Values{
+			"index": {strconv.FormatUint(index, 10)},
+			"wait":  {wait},
+		})
+		cancel()
+
+		if a.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warnf("watch conf[%s] from consul err:%v", key, err)
+			failed = true
+			select {
+			case <-a.ctx.Done():
+				return
+			case <-time.After(ConsulRetryInterval):
+			}
+			continue
+		}
+		if failed {
+			failed = false
+			emitBackendEvent(EventWatchReconnected, a, key)
+		}
+
+		switch {
+		case next < index:
+			// index回退（如快照恢复）时重新开始
+			index = 0
+			a.notify(ch)
+		case next > index:
+			if index > 0 {
+				a.notify(ch)
+			}
+			index = next
+		}
+	}
+}
+
+func (a *ConsulAsyncer) notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Capabilities 见 CapabilityReporter
+func (a *ConsulAsyncer) Capabilities() Capabilities {
+	return Capabilities{Watch: true, CAS: true}
+}
+
+// Close 停止所有Watch
+func (a *ConsulAsyncer) Close() error {
+	a.cancel()
+	return nil
+}
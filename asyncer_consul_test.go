@@ -0,0 +1,120 @@
+package config
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConsul 内存中的consul KV，支持blocking query及cas
+type fakeConsul struct {
+	sync.Mutex
+	index   uint64
+	data    map[string]consulKV
+	headers []http.Header
+	queries []string
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{index: 1, data: make(map[string]consulKV)}
+}
+
+func (c *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	c.headers = append(c.headers, r.Header.Clone())
+	c.queries = append(c.queries, r.URL.RawQuery)
+
+	switch r.Method {
+	case http.MethodGet:
+		if idx, _ := strconv.ParseUint(r.FormValue("index"), 10, 64); idx > 0 {
+			deadline := time.Now().Add(time.Second)
+			for c.index <= idx && time.Now().Before(deadline) {
+				c.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				c.Lock()
+			}
+		}
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+		kv, ok := c.data[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]consulKV{kv})
+	case http.MethodPut:
+		if cas := r.FormValue("cas"); cas != "" {
+			if kv := c.data[key]; strconv.FormatUint(kv.ModifyIndex, 10) != cas {
+				io.WriteString(w, "false")
+				return
+			}
+		}
+		body, _ := io.ReadAll(r.Body)
+		c.index++
+		c.data[key] = consulKV{ModifyIndex: c.index, Value: body}
+		io.WriteString(w, "true")
+	}
+}
+
+func TestConsulAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	fake := newFakeConsul()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := NewConsulAsyncer(server.URL, &ConsulOptions{Datacenter: "dc1", Token: "secret", Prefix: "config/"})
+	defer a.Close()
+
+	ast.Nil(a.Get("app.json"))
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	ast.Equal(`{"a": 1}`, string(a.Get("app.json")))
+	ast.Equal(`{"a": 1}`, string(fake.data["config/app.json"].Value))
+
+	fake.Lock()
+	ast.Equal("secret", fake.headers[0].Get("X-Consul-Token"))
+	ast.Contains(fake.queries[0], "dc=dc1")
+	fake.Unlock()
+
+	ok, err := a.CompareAndSet("app.json", []byte(`{"a": 2}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("app.json", []byte(`{"a": 1}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("app.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("new.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.True(ok)
+
+	ast.Equal(Capabilities{Watch: true, CAS: true}, ProbeCapabilities(a))
+
+	// Get之后的变化通过blocking query通知
+	cfg := NewAsyncConfig(a, "app.json", time.Hour, false)
+	ast.EqualValues(3, cfg.Int("a"))
+	ast.Nil(a.Set("app.json", []byte(`{"a": 4}`)))
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 4
+	}, 2*time.Second, 5*time.Millisecond)
+
+	fake.Lock()
+	ast.Contains(strings.Join(fake.queries, "&"), "wait=300s")
+	fake.Unlock()
+
+	// 后端错误
+	bad := NewConsulAsyncer("http://127.0.0.1:1", nil)
+	ast.Nil(bad.Get("app.json"))
+	ast.NotNil(bad.Set("app.json", nil))
+}
@@ -1,2 +1,309 @@
-// todo: config from database
 package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// DBPollInterval Watch轮询版本列的间隔，在创建 DBAsyncer 时确定
+	DBPollInterval = 30 * time.Second
+	// DBQueryTimeout 每次查询的超时时间
+	DBQueryTimeout = 10 * time.Second
+)
+
+// DBOptions 表结构，表及列名直接拼接在SQL中，不能来自外部输入
+//
+//	CREATE TABLE config (
+//		name    VARCHAR(255) PRIMARY KEY,
+//		value   BLOB NOT NULL,
+//		version BIGINT NOT NULL
+//	);
+type DBOptions struct {
+	Table         string // 默认 config
+	KeyColumn     string // 默认 name
+	ValueColumn   string // 默认 value
+	VersionColumn string // 默认 version，每次写入时变化，Watch只查询该列
+	// Timestamp 版本列为时间（如 updated_at），写入时设为当前时间；否则为整数，写入时加1
+	//
+	// 列的精度需小于秒（如MySQL的 DATETIME(6)），否则同一秒内的多次写入版本相同，Watch可能漏掉变化；
+	// 版本相同时 CompareAndSet 同时比较内容
+	Timestamp bool
+	// Placeholder 第n（从1开始）个参数的占位符，默认为 ?（MySQL、SQLite），PostgreSQL使用 DollarPlaceholder
+	Placeholder func(n int) string
+}
+
+// DollarPlaceholder PostgreSQL的占位符 $1, $2...
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// DBAsyncer 以数据库表的一行作为一个key的数据源，通过 database/sql 访问，驱动由调用方引入
+//
+// Watch定期查询版本列，版本变化时通知；连接由调用方管理，Close不关闭db
+type DBAsyncer struct {
+	db       *sql.DB
+	options  DBOptions
+	interval time.Duration
+
+	notifyChans sync.Map // key => chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+var _ CASSetter = (*DBAsyncer)(nil)
+
+// NewDBAsyncer options为nil时使用默认的表结构，见 DBOptions
+func NewDBAsyncer(db *sql.DB, options *DBOptions) *DBAsyncer {
+	a := &DBAsyncer{db: db, interval: DBPollInterval}
+	if options != nil {
+		a.options = *options
+	}
+	if a.options.Table == "" {
+		a.options.Table = "config"
+	}
+	if a.options.KeyColumn == "" {
+		a.options.KeyColumn = "name"
+	}
+	if a.options.ValueColumn == "" {
+		a.options.ValueColumn = "value"
+	}
+	if a.options.VersionColumn == "" {
+		a.options.VersionColumn = "version"
+	}
+	if a.options.Placeholder == nil {
+		a.options.Placeholder = func(int) string { return "?" }
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	logger.Infof("NewDBAsyncer:table=%s", a.options.Table)
+
+	return a
+}
+
+// ContentType 根据key的后缀判断，见 ContentTypeByExt
+func (a *DBAsyncer) ContentType(key string) ContentType {
+	return ContentTypeByExt(key)
+}
+
+// row 查询内容及版本列的原始值，不存在时value为nil
+func (a *DBAsyncer) row(key string) (value []byte, version interface{}, err error) {
+	ctx, cancel := context.WithTimeout(a.ctx, DBQueryTimeout)
+	defer cancel()
+
+	o := &a.options
+	query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s = %s",
+		o.ValueColumn, o.VersionColumn, o.Table, o.KeyColumn, o.Placeholder(1))
+	err = a.db.QueryRowContext(ctx, query, key).Scan(&value, &version)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "query db[%s]", key)
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, version, nil
+}
+
+// version 只查询版本列，不存在时为空
+func (a *DBAsyncer) version(key string) (string, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, DBQueryTimeout)
+	defer cancel()
+
+	o := &a.options
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s",
+		o.VersionColumn, o.Table, o.KeyColumn, o.Placeholder(1))
+	var v interface{}
+	err := a.db.QueryRowContext(ctx, query, key).Scan(&v)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "query db[%s] version", key)
+	}
+	return versionString(v), nil
+}
+
+// versionString 不同驱动返回的版本类型不同（int64、[]byte、time.Time），统一转为字符串比较
+func versionString(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(vv)
+	case time.Time:
+		return vv.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(vv)
+	}
+}
+
+// Get 不存在或查询失败时返回nil
+func (a *DBAsyncer) Get(key string) []byte {
+	value, _, err := a.row(key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from db err:%v", key, err)
+		return nil
+	}
+	if len(value) == 0 {
+		return nil
+	}
+	return value
+}
+
+// setVersion UPDATE中设置版本列的表达式及参数
+func (a *DBAsyncer) setVersion(n int) (string, []interface{}) {
+	o := &a.options
+	if o.Timestamp {
+		return fmt.Sprintf("%s = %s", o.VersionColumn, o.Placeholder(n)), []interface{}{_now().UTC()}
+	}
+	return fmt.Sprintf("%s = %s + 1", o.VersionColumn, o.VersionColumn), nil
+}
+
+func (a *DBAsyncer) exec(query string, args ...interface{}) (int64, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, DBQueryTimeout)
+	defer cancel()
+
+	result, err := a.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// update 更新已有的行，version非nil时要求版本一致（时间版本时同时要求内容为old），返回是否更新
+func (a *DBAsyncer) update(key string, value []byte, version interface{}, old []byte) (bool, error) {
+	o := &a.options
+	set, args := a.setVersion(2)
+	args = append([]interface{}{value}, args...)
+	query := fmt.Sprintf("UPDATE %s SET %s = %s, %s WHERE %s = %s",
+		o.Table, o.ValueColumn, o.Placeholder(1), set, o.KeyColumn, o.Placeholder(len(args)+1))
+	args = append(args, key)
+	if version != nil {
+		query += fmt.Sprintf(" AND %s = %s", o.VersionColumn, o.Placeholder(len(args)+1))
+		args = append(args, version)
+		if o.Timestamp {
+			// 精度内的并发写入版本相同
+			query += fmt.Sprintf(" AND %s = %s", o.ValueColumn, o.Placeholder(len(args)+1))
+			args = append(args, old)
+		}
+	}
+
+	n, err := a.exec(query, args...)
+	if err != nil {
+		return false, errors.Wrapf(err, "update db[%s]", key)
+	}
+	return n > 0, nil
+}
+
+// insert 插入新的行，主键冲突时返回错误
+func (a *DBAsyncer) insert(key string, value []byte) error {
+	o := &a.options
+	var version interface{} = 1
+	if o.Timestamp {
+		version = _now().UTC()
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (%s, %s, %s)",
+		o.Table, o.KeyColumn, o.ValueColumn, o.VersionColumn, o.Placeholder(1), o.Placeholder(2), o.Placeholder(3))
+	_, err := a.exec(query, key, value, version)
+	return errors.Wrapf(err, "insert db[%s]", key)
+}
+
+// Set 行存在时更新，否则插入
+func (a *DBAsyncer) Set(key string, value []byte) error {
+	ok, err := a.update(key, value, nil, nil)
+	if err != nil || ok {
+		return err
+	}
+	return a.insert(key, value)
+}
+
+// CompareAndSet 内容与old一致时按读取到的版本条件更新，old为nil时要求不存在
+func (a *DBAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	current, version, err := a.row(key)
+	if err != nil {
+		return false, err
+	}
+
+	if old == nil {
+		if current != nil {
+			return false, nil
+		}
+		if err := a.insert(key, value); err != nil {
+			// 并发插入时主键冲突
+			if current, _, e := a.row(key); e == nil && current != nil {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	if current == nil || !bytes.Equal(current, old) {
+		return false, nil
+	}
+	return a.update(key, value, version, old)
+}
+
+// Watch 每隔 DBPollInterval 查询版本列，变化时通知
+func (a *DBAsyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	version, err := a.version(key)
+	if err != nil {
+		logger.Warnf("poll conf[%s] from db err:%v", key, err)
+	}
+	go a.poll(key, version, ch)
+
+	return ch
+}
+
+func (a *DBAsyncer) poll(key, version string, ch chan struct{}) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := a.version(key)
+		if err != nil {
+			if a.ctx.Err() == nil {
+				logger.Warnf("poll conf[%s] from db err:%v", key, err)
+			}
+			continue
+		}
+		if current != version {
+			version = current
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Capabilities 见 CapabilityReporter
+func (a *DBAsyncer) Capabilities() Capabilities {
+	return Capabilities{Watch: true, CAS: true}
+}
+
+// Close 停止所有Watch，不关闭db
+func (a *DBAsyncer) Close() error {
+	a.cancel()
+	return nil
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDBDriver 只支持 DBAsyncer 生成的SQL的内存表，记录执行过的语句
+type fakeDBDriver struct {
+	sync.Mutex
+	rows    map[string][2]driver.Value // name => value, version
+	queries []string
+}
+
+var fakeDB = &fakeDBDriver{rows: make(map[string][2]driver.Value)}
+
+func init() {
+	sql.Register("configfakedb", fakeDB)
+}
+
+func (d *fakeDBDriver) reset() {
+	d.Lock()
+	defer d.Unlock()
+	d.rows = make(map[string][2]driver.Value)
+	d.queries = nil
+}
+
+func (d *fakeDBDriver) Open(string) (driver.Conn, error) { return fakeDBConn{d}, nil }
+
+type fakeDBConn struct{ d *fakeDBDriver }
+
+func (c fakeDBConn) Prepare(query string) (driver.Stmt, error) { return fakeDBStmt{c.d, query}, nil }
+func (c fakeDBConn) Close() error                              { return nil }
+func (c fakeDBConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeDBStmt struct {
+	d     *fakeDBDriver
+	query string
+}
+
+func (s fakeDBStmt) Close() error  { return nil }
+func (s fakeDBStmt) NumInput() int { return -1 }
+
+func (s fakeDBStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.Lock()
+	defer s.d.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+
+	if strings.HasPrefix(s.query, "INSERT") {
+		key := args[0].(string)
+		if _, ok := s.d.rows[key]; ok {
+			return nil, errors.New("duplicate key")
+		}
+		s.d.rows[key] = [2]driver.Value{args[1], args[2]}
+		return driver.RowsAffected(1), nil
+	}
+
+	// UPDATE t SET value = ?, version = version + 1|?  WHERE name = ? [AND version = ? [AND value = ?]]
+	value, rest := args[0], args[1:]
+	var version driver.Value
+	if !strings.Contains(s.query, "+ 1") {
+		version, rest = rest[0], rest[1:]
+	}
+	row, ok := s.d.rows[rest[0].(string)]
+	if !ok || len(rest) > 1 && versionString(row[1]) != versionString(rest[1]) ||
+		len(rest) > 2 && !bytes.Equal(row[0].([]byte), rest[2].([]byte)) {
+		return driver.RowsAffected(0), nil
+	}
+	if version == nil {
+		version = row[1].(int64) + 1
+	}
+	s.d.rows[rest[0].(string)] = [2]driver.Value{value, version}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeDBStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.Lock()
+	defer s.d.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+
+	cols := strings.Split(strings.TrimPrefix(strings.SplitN(s.query, " FROM", 2)[0], "SELECT "), ", ")
+	rows := &fakeDBRows{cols: cols}
+	if row, ok := s.d.rows[args[0].(string)]; ok {
+		rows.values = row[2-len(cols):]
+	}
+	return rows, nil
+}
+
+type fakeDBRows struct {
+	cols   []string
+	values []driver.Value
+}
+
+func (r *fakeDBRows) Columns() []string { return r.cols }
+func (r *fakeDBRows) Close() error      { return nil }
+
+func (r *fakeDBRows) Next(dest []driver.Value) error {
+	if r.values == nil {
+		return io.EOF
+	}
+	copy(dest, r.values)
+	r.values = nil
+	return nil
+}
+
+func TestDBAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	DBPollInterval = 10 * time.Millisecond
+	defer func() { DBPollInterval = 30 * time.Second }()
+
+	fakeDB.reset()
+	db, err := sql.Open("configfakedb", "")
+	ast.Nil(err)
+	defer db.Close()
+
+	a := NewDBAsyncer(db, nil)
+	defer a.Close()
+
+	ast.True(ProbeCapabilities(a).Watch)
+	ast.Equal(T_YAML, a.ContentType("app.yaml"))
+	ast.Nil(a.Get("app.json"))
+	ch := a.Watch("app.json")
+	ast.Equal(ch, a.Watch("app.json"))
+
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	ast.Equal(`{"a": 1}`, string(a.Get("app.json")))
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		ast.Fail("not notified")
+	}
+
+	cfg := NewAsyncConfig(a, "app.json", time.Hour, false)
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.Nil(a.Set("app.json", []byte(`{"a": 2}`)))
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 2
+	}, time.Second, 5*time.Millisecond)
+
+	// CAS
+	ok, err := a.CompareAndSet("app.json", []byte(`{"a": 1}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("app.json", []byte(`{"a": 2}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("app.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("other.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.True(ok)
+
+	fakeDB.Lock()
+	ast.EqualValues(3, fakeDB.rows["app.json"][1])
+	ast.Contains(fakeDB.queries, "SELECT version FROM config WHERE name = ?")
+	fakeDB.Unlock()
+}
+
+func TestDBAsyncerOptions(t *testing.T) {
+	ast := assert.New(t)
+
+	fakeDB.reset()
+	db, err := sql.Open("configfakedb", "")
+	ast.Nil(err)
+	defer db.Close()
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	oldNow := _now
+	_now = func() time.Time { return now }
+	defer func() { _now = oldNow }()
+
+	a := NewDBAsyncer(db, &DBOptions{
+		Table:         "settings",
+		KeyColumn:     "id",
+		ValueColumn:   "body",
+		VersionColumn: "updated_at",
+		Timestamp:     true,
+		Placeholder:   DollarPlaceholder,
+	})
+	defer a.Close()
+
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	ast.Nil(a.Set("app.json", []byte(`{"a": 2}`)))
+	ok, err := a.CompareAndSet("app.json", []byte(`{"a": 2}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ast.Equal(`{"a": 3}`, string(a.Get("app.json")))
+
+	// 版本相同但内容已变化
+	fakeDB.Lock()
+	version := fakeDB.rows["app.json"][1]
+	fakeDB.Unlock()
+	ok, err = a.update("app.json", []byte(`{"a": 4}`), version, []byte(`{"a": 2}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ast.Equal(`{"a": 3}`, string(a.Get("app.json")))
+
+	fakeDB.Lock()
+	defer fakeDB.Unlock()
+	ast.Equal(now, fakeDB.rows["app.json"][1])
+	ast.Contains(fakeDB.queries, "INSERT INTO settings (id, body, updated_at) VALUES ($1, $2, $3)")
+	ast.Contains(fakeDB.queries, "UPDATE settings SET body = $1, updated_at = $2 WHERE id = $3 AND updated_at = $4 AND body = $5")
+}
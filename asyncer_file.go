@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -23,11 +25,7 @@ func NewFileAsyncer() *FileAsyncer {
 }
 
 func (a *FileAsyncer) ContentType(file string) ContentType {
-	if strings.HasSuffix(file, ".yml") {
-		return T_YAML
-	}
-
-	return T_JSON
+	return ContentTypeByExt(file)
 }
 
 func (a *FileAsyncer) Get(file string) []byte {
@@ -59,6 +57,18 @@ func (a *FileAsyncer) Get(file string) []byte {
 	return content
 }
 
+// GetReader 打开文件，见 ReaderGetter，文件不存在时返回 (nil, nil)
+func (a *FileAsyncer) GetReader(file string) (io.ReadCloser, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
 func (a *FileAsyncer) Set(file string, content []byte) error {
 
 	return fmt.Errorf("the method is not implement")
@@ -67,3 +77,39 @@ func (a *FileAsyncer) Set(file string, content []byte) error {
 func (a *FileAsyncer) Watch(file string) chan struct{} {
 	return nil
 }
+
+// List 列出目录下所有的文件，prefix为目录路径，忽略隐藏文件
+func (a *FileAsyncer) List(dir string) ([]string, error) {
+	ret := make([]string, 0)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		ret = append(ret, path)
+		return nil
+	})
+
+	return ret, err
+}
+
+// ListPrefix 列出目录下所有的文件及其内容，prefix为目录路径
+func (a *FileAsyncer) ListPrefix(dir string) (map[string][]byte, error) {
+	files, err := a.List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string][]byte, len(files))
+	for _, path := range files {
+		if content := a.Get(path); content != nil {
+			ret[path] = content
+		}
+	}
+
+	return ret, nil
+}
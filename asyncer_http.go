@@ -0,0 +1,255 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// HTTPPollInterval Watch轮询的间隔，在创建 HTTPAsyncer 时确定
+	HTTPPollInterval = 30 * time.Second
+	// HTTPRequestTimeout 请求的超时时间
+	HTTPRequestTimeout = 10 * time.Second
+)
+
+// HTTPAsyncer 以HTTP(S)地址为数据源，Watch通过轮询实现
+//
+// 请求时携带上一次响应的ETag（If-None-Match）及Last-Modified（If-Modified-Since），
+// 304时使用缓存的内容，内容未变化时不会重新下载及解析；轮询只在内容变化时通知
+type HTTPAsyncer struct {
+	baseURL  string
+	client   *http.Client
+	interval time.Duration
+
+	entries     sync.Map // key => *httpEntry
+	notifyChans sync.Map // key => chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+var _ CASSetter = (*HTTPAsyncer)(nil)
+
+// httpEntry 一个地址最近一次响应的缓存
+type httpEntry struct {
+	sync.Mutex
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// NewHTTPAsyncer key拼接在baseURL之后，如 baseURL为 "https://cdn.example.com/config"，
+// key为 "app.json" 时请求 https://cdn.example.com/config/app.json；key为完整的URL时直接使用
+//
+// opts: TLS、代理及token等连接选项，见 BackendOptions
+func NewHTTPAsyncer(baseURL string, opts ...BackendOption) *HTTPAsyncer {
+	a := &HTTPAsyncer{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		client:   backendHTTPClient(opts),
+		interval: HTTPPollInterval,
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	logger.Infof("NewHTTPAsyncer:baseURL=%s", a.baseURL)
+
+	return a
+}
+
+func (a *HTTPAsyncer) url(key string) string {
+	if strings.HasPrefix(key, "http://") || strings.HasPrefix(key, "https://") || a.baseURL == "" {
+		return key
+	}
+	return a.baseURL + "/" + strings.TrimPrefix(key, "/")
+}
+
+// ContentType 根据key的后缀判断，见 ContentTypeByExt
+func (a *HTTPAsyncer) ContentType(key string) ContentType {
+	return ContentTypeByExt(strings.SplitN(key, "?", 2)[0])
+}
+
+func (a *HTTPAsyncer) entry(key string) *httpEntry {
+	e, _ := a.entries.LoadOrStore(key, &httpEntry{})
+	return e.(*httpEntry)
+}
+
+// fetch 条件请求，返回最新的内容及是否变化；不存在时内容为nil
+func (a *HTTPAsyncer) fetch(key string) ([]byte, bool, error) {
+	e := a.entry(key)
+	e.Lock()
+	defer e.Unlock()
+
+	ctx, cancel := context.WithTimeout(a.ctx, HTTPRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url(key), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if e.etag != "" {
+		req.Header.Set("If-None-Match", e.etag)
+	}
+	if e.lastModified != "" {
+		req.Header.Set("If-Modified-Since", e.lastModified)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return e.body, false, nil
+	case http.StatusNotFound:
+		changed := e.body != nil
+		e.etag, e.lastModified, e.body = "", "", nil
+		return nil, changed, nil
+	case http.StatusOK:
+	default:
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, false, errors.Errorf("http[%s]: status %d: %s", a.url(key), resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	// 不支持条件请求的服务端总是返回200
+	changed := !bytes.Equal(body, e.body)
+	e.etag = resp.Header.Get("ETag")
+	e.lastModified = resp.Header.Get("Last-Modified")
+	e.body = body
+	return body, changed, nil
+}
+
+// Get 不存在或请求失败时返回nil
+func (a *HTTPAsyncer) Get(key string) []byte {
+	body, _, err := a.fetch(key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from http err:%v", key, err)
+		return nil
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	return body
+}
+
+// put PUT到地址，condition为条件请求的头（If-Match、If-None-Match），返回是否写入（412时为false）
+func (a *HTTPAsyncer) put(key string, value []byte, condition, etag string) (bool, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, HTTPRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, a.url(key), bytes.NewReader(value))
+	if err != nil {
+		return false, err
+	}
+	if condition != "" {
+		req.Header.Set(condition, etag)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return false, errors.Wrapf(err, "put http[%s]", a.url(key))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return false, nil
+	}
+	if resp.StatusCode >= 300 {
+		return false, errors.Errorf("put http[%s]: status %d", a.url(key), resp.StatusCode)
+	}
+	return true, nil
+}
+
+// Set 使用PUT写入，需服务端支持
+func (a *HTTPAsyncer) Set(key string, value []byte) error {
+	_, err := a.put(key, value, "", "")
+	return err
+}
+
+// CompareAndSet 当前内容与old一致时使用 If-Match 写入，old为nil时要求不存在（If-None-Match: *）
+func (a *HTTPAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	body, _, err := a.fetch(key)
+	if err != nil {
+		return false, err
+	}
+
+	if old == nil {
+		if body != nil {
+			return false, nil
+		}
+		return a.put(key, value, "If-None-Match", "*")
+	}
+	if body == nil || !bytes.Equal(body, old) {
+		return false, nil
+	}
+
+	e := a.entry(key)
+	e.Lock()
+	etag := e.etag
+	e.Unlock()
+	if etag == "" {
+		return false, errors.Errorf("http[%s]: CompareAndSet requires an ETag", a.url(key))
+	}
+	return a.put(key, value, "If-Match", etag)
+}
+
+// Watch 每隔 HTTPPollInterval 发送条件请求，内容变化时通知
+func (a *HTTPAsyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	go a.poll(key, ch)
+
+	return ch
+}
+
+func (a *HTTPAsyncer) poll(key string, ch chan struct{}) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, changed, err := a.fetch(key)
+		if err != nil {
+			if a.ctx.Err() == nil {
+				logger.Warnf("poll conf[%s] from http err:%v", key, err)
+			}
+			continue
+		}
+		if changed {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Capabilities 见 CapabilityReporter
+func (a *HTTPAsyncer) Capabilities() Capabilities {
+	return Capabilities{Watch: true, CAS: true}
+}
+
+// Close 停止所有Watch
+func (a *HTTPAsyncer) Close() error {
+	a.cancel()
+	return nil
+}
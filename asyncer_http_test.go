@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHTTPStore 支持ETag、条件请求及PUT的内存存储
+type fakeHTTPStore struct {
+	sync.Mutex
+	files       map[string][]byte
+	versions    map[string]int
+	full        int // 返回完整内容的次数
+	notModified int
+}
+
+func (s *fakeHTTPStore) etag(path string) string {
+	return fmt.Sprintf(`"%d"`, s.versions[path])
+}
+
+func (s *fakeHTTPStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	data, ok := s.files[r.URL.Path]
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", s.etag(r.URL.Path))
+		if r.Header.Get("If-None-Match") == s.etag(r.URL.Path) {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.full++
+		w.Write(data)
+	case http.MethodPut:
+		if m := r.Header.Get("If-Match"); m != "" && (!ok || m != s.etag(r.URL.Path)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && ok {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.files[r.URL.Path] = body
+		s.versions[r.URL.Path]++
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestHTTPAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	HTTPPollInterval = 10 * time.Millisecond
+	defer func() { HTTPPollInterval = 30 * time.Second }()
+
+	store := &fakeHTTPStore{files: make(map[string][]byte), versions: make(map[string]int)}
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	a := NewHTTPAsyncer(server.URL + "/config/")
+	defer a.Close()
+
+	ast.Nil(a.Get("app.json"))
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	ast.Equal(`{"a": 1}`, string(a.Get("app.json")))
+	ast.Equal(`{"a": 1}`, string(a.Get(server.URL+"/config/app.json")))
+	ast.Equal(T_YAML, a.ContentType("app.yaml?v=1"))
+
+	// 304时使用缓存
+	store.Lock()
+	full := store.full
+	store.Unlock()
+	ast.Equal(`{"a": 1}`, string(a.Get("app.json")))
+	store.Lock()
+	ast.Equal(full, store.full)
+	ast.Equal(1, store.notModified)
+	store.Unlock()
+
+	ok, err := a.CompareAndSet("app.json", []byte(`{"a": 2}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("app.json", []byte(`{"a": 1}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("app.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("new.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.True(ok)
+
+	// 只在内容变化时通知
+	cfg := NewAsyncConfig(a, "app.json", time.Hour, false)
+	ast.EqualValues(3, cfg.Int("a"))
+	ch := make(chan struct{}, 1)
+	cfg.Watch(ch)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-ch:
+		ast.Fail("notified without change")
+	default:
+	}
+
+	ast.Nil(a.Set("app.json", []byte(`{"a": 4}`)))
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 4
+	}, time.Second, 5*time.Millisecond)
+
+	// 服务端错误
+	bad := NewHTTPAsyncer("http://127.0.0.1:1")
+	ast.Nil(bad.Get("app.json"))
+	ast.NotNil(bad.Set("app.json", nil))
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// LazyConnectRetryInterval LazyAsyncer连接失败后重试的最小间隔
+var LazyConnectRetryInterval = time.Second
+
+// LazyAsyncer 首次使用时才连接后端，连接失败时下次使用再重试
+//
+// 未连接时ContentType按key的扩展名判断，见 ContentTypeByExt
+//
+//	asyncer := config.NewLazyAsyncer(func() (config.Asyncer, error) {
+//		return dialBackend()
+//	})
+//	cfg := config.NewAsyncConfig(asyncer, key, cacheTime, false, config.WithDeferredStart())
+//	err := cfg.Start(ctx)
+type LazyAsyncer struct {
+	connect func() (Asyncer, error)
+
+	sync.Mutex
+	asyncer     Asyncer
+	lastAttempt time.Time
+	lastErr     error
+}
+
+func NewLazyAsyncer(connect func() (Asyncer, error)) *LazyAsyncer {
+	return &LazyAsyncer{connect: connect}
+}
+
+// Connected 返回已连接的asyncer，未连接时返回nil
+func (a *LazyAsyncer) Connected() Asyncer {
+	a.Lock()
+	defer a.Unlock()
+	return a.asyncer
+}
+
+func (a *LazyAsyncer) get() (Asyncer, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.asyncer != nil {
+		return a.asyncer, nil
+	}
+
+	if !a.lastAttempt.IsZero() && _now().Sub(a.lastAttempt) < LazyConnectRetryInterval {
+		return nil, a.lastErr
+	}
+
+	a.lastAttempt = _now()
+	asyncer, err := a.connect()
+	if err != nil {
+		a.lastErr = backendError("", err)
+		logger.Errorf("lazy asyncer connect err:%v", err)
+		return nil, a.lastErr
+	}
+
+	a.asyncer = asyncer
+	a.lastErr = nil
+	return asyncer, nil
+}
+
+func (a *LazyAsyncer) ContentType(key string) ContentType {
+	if asyncer := a.Connected(); asyncer != nil {
+		return asyncer.ContentType(key)
+	}
+	return ContentTypeByExt(key)
+}
+
+func (a *LazyAsyncer) Get(key string) []byte {
+	asyncer, err := a.get()
+	if err != nil {
+		return nil
+	}
+	return asyncer.Get(key)
+}
+
+func (a *LazyAsyncer) Set(key string, value []byte) error {
+	asyncer, err := a.get()
+	if err != nil {
+		return err
+	}
+	return asyncer.Set(key, value)
+}
+
+func (a *LazyAsyncer) Watch(key string) chan struct{} {
+	asyncer, err := a.get()
+	if err != nil {
+		return nil
+	}
+	return asyncer.Watch(key)
+}
+
+func (a *LazyAsyncer) Capabilities() Capabilities {
+	asyncer, err := a.get()
+	if err != nil {
+		return Capabilities{}
+	}
+	return ProbeCapabilities(asyncer)
+}
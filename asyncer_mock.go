@@ -1,6 +1,8 @@
 package config
 
 import (
+	"bytes"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -11,6 +13,7 @@ type MockAsyncer struct {
 	data          sync.Map
 	notifyChans   sync.Map
 	notifyEnabled bool
+	casMu         sync.Mutex
 }
 
 func NewMockAsyncer(notifyEnabled bool) *MockAsyncer {
@@ -20,11 +23,7 @@ func NewMockAsyncer(notifyEnabled bool) *MockAsyncer {
 }
 
 func (a *MockAsyncer) ContentType(key string) ContentType {
-	if strings.HasSuffix(key, ".yml") {
-		return T_YAML
-	}
-
-	return T_JSON
+	return ContentTypeByExt(key)
 }
 
 func (a *MockAsyncer) Get(key string) []byte {
@@ -44,9 +43,9 @@ func (a *MockAsyncer) Get(key string) []byte {
 		return nil
 	}
 
-	atomic.AddInt32(&a.ct, 1)
+	ct := atomic.AddInt32(&a.ct, 1)
 	setMapValue(m, "key", key)
-	setMapValue(m, "ct", a.ct)
+	setMapValue(m, "ct", ct)
 	ret, _ := mar.Marshal(m)
 	logger.Infof("get async config[%s]:%s", key, ret)
 	return ret
@@ -82,3 +81,63 @@ func (a *MockAsyncer) Watch(key string) chan struct{} {
 
 	return ch
 }
+
+func (a *MockAsyncer) Capabilities() Capabilities {
+	return Capabilities{
+		Watch:    a.notifyEnabled,
+		CAS:      true,
+		BatchGet: true,
+		List:     true,
+	}
+}
+
+func (a *MockAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	a.casMu.Lock()
+	defer a.casMu.Unlock()
+
+	v, ok := a.data.Load(key)
+	if old == nil {
+		if ok {
+			return false, nil
+		}
+	} else if !ok || !bytes.Equal(v.([]byte), old) {
+		return false, nil
+	}
+
+	return true, a.Set(key, value)
+}
+
+func (a *MockAsyncer) BatchGet(keys []string) (map[string][]byte, error) {
+	ret := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if v, ok := a.data.Load(key); ok {
+			ret[key] = v.([]byte)
+		}
+	}
+	return ret, nil
+}
+
+func (a *MockAsyncer) List(prefix string) ([]string, error) {
+	ret := make([]string, 0)
+	a.data.Range(func(k, v interface{}) bool {
+		if key := k.(string); strings.HasPrefix(key, prefix) {
+			ret = append(ret, key)
+		}
+		return true
+	})
+
+	sort.Strings(ret)
+	return ret, nil
+}
+
+func (a *MockAsyncer) ListPrefix(prefix string) (map[string][]byte, error) {
+	ret := make(map[string][]byte)
+	a.data.Range(func(k, v interface{}) bool {
+		if key := k.(string); strings.HasPrefix(key, prefix) {
+			ret[key] = v.([]byte)
+		}
+		return true
+	})
+
+	return ret, nil
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// NacosLongPollTimeout 监听请求的长轮询时间（Long-Pulling-Timeout）
+	NacosLongPollTimeout = 30 * time.Second
+	// NacosRequestTimeout 非长轮询请求的超时时间
+	NacosRequestTimeout = 10 * time.Second
+	// NacosRetryInterval 监听失败后重试的间隔
+	NacosRetryInterval = time.Second
+)
+
+// NacosOptions nacos配置中心的访问选项
+type NacosOptions struct {
+	Namespace string // 命名空间ID（tenant），为空时为public
+	Group     string // 默认 DEFAULT_GROUP
+	// Username、Password 开启鉴权时登录获取accessToken，过期前重新登录
+	Username string
+	Password string
+}
+
+// NacosAsyncer 以nacos配置中心为数据源，key为dataId，Watch使用 /v1/cs/configs/listener 长轮询
+type NacosAsyncer struct {
+	addr    string
+	options NacosOptions
+	client  *http.Client
+
+	tokenMu     sync.Mutex
+	token       string
+	tokenExpire time.Time
+
+	md5s        sync.Map // key => string，最近一次Get的内容的MD5
+	notifyChans sync.Map // key => chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+// NewNacosAsyncer addr 为nacos的地址，如 "http://127.0.0.1:8848/nacos"，options可为nil
+//
+// opts: TLS及代理等连接选项，见 BackendOptions
+func NewNacosAsyncer(addr string, options *NacosOptions, opts ...BackendOption) *NacosAsyncer {
+	a := &NacosAsyncer{
+		addr:   strings.TrimSuffix(addr, "/"),
+		client: backendHTTPClient(opts),
+	}
+	if options != nil {
+		a.options = *options
+	}
+	if a.options.Group == "" {
+		a.options.Group = "DEFAULT_GROUP"
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	logger.Infof("NewNacosAsyncer:addr=%s,namespace=%s,group=%s", a.addr, a.options.Namespace, a.options.Group)
+
+	return a
+}
+
+// ContentType 根据dataId的后缀判断
+func (a *NacosAsyncer) ContentType(key string) ContentType {
+	return ContentTypeByExt(key)
+}
+
+// accessToken 未配置用户名时为空
+func (a *NacosAsyncer) accessToken(ctx context.Context) (string, error) {
+	if a.options.Username == "" {
+		return "", nil
+	}
+
+	a.tokenMu.Lock()
+	defer a.tokenMu.Unlock()
+	if a.token != "" && _now().Before(a.tokenExpire) {
+		return a.token, nil
+	}
+
+	form := url.Values{"username": {a.options.Username}, "password": {a.options.Password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.addr+"/v1/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "nacos login")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("nacos login: status %d", resp.StatusCode)
+	}
+
+	var ret struct {
+		AccessToken string `json:"accessToken"`
+		TokenTTL    int64  `json:"tokenTtl"` // 秒
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return "", errors.Wrap(err, "nacos login")
+	}
+	// 提前10%重新登录
+	a.token = ret.AccessToken
+	a.tokenExpire = _now().Add(time.Duration(ret.TokenTTL) * time.Second * 9 / 10)
+	return a.token, nil
+}
+
+// do 请求 /v1/cs/<path>，返回响应内容及状态码，404不视为错误
+func (a *NacosAsyncer) do(ctx context.Context, method, path string, form url.Values, header http.Header) ([]byte, int, error) {
+	token, err := a.accessToken(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if token != "" {
+		form.Set("accessToken", token)
+	}
+
+	endpoint := a.addr + "/v1/cs/" + path
+	var body io.Reader
+	if method == http.MethodGet {
+		endpoint += "?" + form.Encode()
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		// token失效时下次重新登录
+		a.tokenMu.Lock()
+		a.token = ""
+		a.tokenMu.Unlock()
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return nil, resp.StatusCode, errors.Errorf("nacos %s: status %d: %s", path, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return data, resp.StatusCode, nil
+}
+
+// configForm dataId、group及tenant参数
+func (a *NacosAsyncer) configForm(key string) url.Values {
+	form := url.Values{"dataId": {key}, "group": {a.options.Group}}
+	if a.options.Namespace != "" {
+		form.Set("tenant", a.options.Namespace)
+	}
+	return form
+}
+
+// get 返回配置内容，不存在时为nil
+func (a *NacosAsyncer) get(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, NacosRequestTimeout)
+	defer cancel()
+
+	data, status, err := a.do(ctx, http.MethodGet, "configs", a.configForm(key), nil)
+	if err != nil || status == http.StatusNotFound {
+		return nil, err
+	}
+	return data, nil
+}
+
+func nacosMD5(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (a *NacosAsyncer) Get(key string) []byte {
+	data, err := a.get(key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from nacos err:%v", key, err)
+		return nil
+	}
+	a.md5s.Store(key, nacosMD5(data))
+
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// Set 发布配置，type根据dataId的后缀确定，便于在控制台中编辑
+func (a *NacosAsyncer) Set(key string, value []byte) error {
+	ctx, cancel := context.WithTimeout(a.ctx, NacosRequestTimeout)
+	defer cancel()
+
+	form := a.configForm(key)
+	form.Set("content", string(value))
+	if typ := nacosType(a.ContentType(key)); typ != "" {
+		form.Set("type", typ)
+	}
+	data, _, err := a.do(ctx, http.MethodPost, "configs", form, nil)
+	if err == nil && string(bytes.TrimSpace(data)) != "true" {
+		err = errors.Errorf("nacos config[%s]: publish failed: %s", key, bytes.TrimSpace(data))
+	}
+	return err
+}
+
+// nacosType nacos控制台支持的配置格式
+func nacosType(t ContentType) string {
+	switch t {
+	case T_JSON:
+		return "json"
+	case T_YAML:
+		return "yaml"
+	case T_PROPERTIES:
+		return "properties"
+	case T_XML:
+		return "xml"
+	case T_TOML:
+		return "toml"
+	}
+	return ""
+}
+
+// Watch 以最近一次Get的内容MD5监听变化，失败时每隔 NacosRetryInterval 重试
+func (a *NacosAsyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	go a.watch(key, ch)
+
+	return ch
+}
+
+func (a *NacosAsyncer) watch(key string, ch chan struct{}) {
+	if _, ok := a.md5s.Load(key); !ok {
+		a.Get(key)
+	}
+
+	header := http.Header{"Long-Pulling-Timeout": {strconv.FormatInt(int64(NacosLongPollTimeout/time.Millisecond), 10)}}
+	failed := false
+	for {
+		var sum string
+		if v, ok := a.md5s.Load(key); ok {
+			sum = v.(string)
+		}
+		// dataId^2group^2md5[^2tenant]^1
+		listening := key + "\x02" + a.options.Group + "\x02" + sum
+		if a.options.Namespace != "" {
+			listening += "\x02" + a.options.Namespace
+		}
+
+		ctx, cancel := context.WithTimeout(a.ctx, NacosLongPollTimeout+NacosRequestTimeout)
+		data, _, err := a.do(ctx, http.MethodPost, "configs/listener", url.Values{"Listening-Configs": {listening + "\x01"}}, header)
+		cancel()
+
+		// 变化时返回url编码的 dataId^2group[^2tenant]^1
+		changed := err == nil && len(bytes.TrimSpace(data)) > 0
+		if changed {
+			data, err = a.get(key)
+		}
+
+		if a.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warnf("watch conf[%s] from nacos err:%v", key, err)
+			failed = true
+			select {
+			case <-a.ctx.Done():
+				return
+			case <-time.After(NacosRetryInterval):
+			}
+			continue
+		}
+		if failed {
+			failed = false
+			emitBackendEvent(EventWatchReconnected, a, key)
+		}
+		if !changed {
+			continue
+		}
+		if next := nacosMD5(data); next != sum {
+			a.md5s.Store(key, next)
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Capabilities 见 CapabilityReporter
+func (a *NacosAsyncer) Capabilities() Capabilities {
+	return Capabilities{Watch: true}
+}
+
+// Close 停止所有Watch
+func (a *NacosAsyncer) Close() error {
+	a.cancel()
+	return nil
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNacos 内存中的nacos配置中心，支持发布、长轮询监听及登录
+type fakeNacos struct {
+	sync.Mutex
+	configs map[string]string // tenant/group/dataId => content
+	logins  int
+	forms   []string
+}
+
+func (n *fakeNacos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	n.Lock()
+	defer n.Unlock()
+	n.forms = append(n.forms, r.Form.Encode())
+
+	if r.URL.Path == "/nacos/v1/auth/login" {
+		if r.Form.Get("username") != "nacos" || r.Form.Get("password") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		n.logins++
+		w.Write([]byte(`{"accessToken": "token", "tokenTtl": 18000}`))
+		return
+	}
+	if r.Form.Get("accessToken") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	id := r.Form.Get("tenant") + "/" + r.Form.Get("group") + "/" + r.Form.Get("dataId")
+	switch r.URL.Path {
+	case "/nacos/v1/cs/configs":
+		if r.Method == http.MethodPost {
+			n.configs[id] = r.Form.Get("content")
+			w.Write([]byte("true"))
+			return
+		}
+		content, ok := n.configs[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(content))
+	case "/nacos/v1/cs/configs/listener":
+		// dataId^2group^2md5^2tenant^1
+		parts := strings.Split(strings.TrimSuffix(r.Form.Get("Listening-Configs"), "\x01"), "\x02")
+		if len(parts) == 3 {
+			parts = append(parts, "")
+		}
+		id := parts[3] + "/" + parts[1] + "/" + parts[0]
+		deadline := time.Now().Add(200 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			if nacosMD5([]byte(n.configs[id])) != parts[2] {
+				w.Write([]byte(strings.Join(parts[:2], "%02") + "%01"))
+				return
+			}
+			n.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			n.Lock()
+		}
+	}
+}
+
+func TestNacosAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	store := &fakeNacos{configs: make(map[string]string)}
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	a := NewNacosAsyncer(server.URL+"/nacos/", &NacosOptions{
+		Namespace: "dev",
+		Username:  "nacos",
+		Password:  "secret",
+	})
+	defer a.Close()
+
+	ast.Equal(T_YAML, a.ContentType("app.yaml"))
+	ast.Nil(a.Get("app.json"))
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	ast.Equal(`{"a": 1}`, string(a.Get("app.json")))
+
+	store.Lock()
+	ast.Equal(`{"a": 1}`, store.configs["dev/DEFAULT_GROUP/app.json"])
+	ast.Contains(store.forms[len(store.forms)-2], "type=json")
+	ast.Equal(1, store.logins)
+	store.Unlock()
+
+	cfg := NewAsyncConfig(a, "app.json", time.Hour, false)
+	ch := make(chan struct{}, 1)
+	cfg.Watch(ch)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	// 控制台发布
+	store.Lock()
+	store.configs["dev/DEFAULT_GROUP/app.json"] = `{"a": 2}`
+	store.Unlock()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		ast.Fail("not notified")
+	}
+	ast.EqualValues(2, cfg.Int("a"))
+
+	// 删除时也通知
+	b := NewNacosAsyncer(server.URL+"/nacos", &NacosOptions{Namespace: "dev", Username: "nacos", Password: "secret"})
+	defer b.Close()
+	ast.NotNil(b.Get("app.json"))
+	deleted := b.Watch("app.json")
+	store.Lock()
+	delete(store.configs, "dev/DEFAULT_GROUP/app.json")
+	store.Unlock()
+	select {
+	case <-deleted:
+	case <-time.After(2 * time.Second):
+		ast.Fail("not notified")
+	}
+	ast.Nil(b.Get("app.json"))
+}
+
+func TestNacosAsyncerLogin(t *testing.T) {
+	ast := assert.New(t)
+
+	store := &fakeNacos{configs: make(map[string]string)}
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	a := NewNacosAsyncer(server.URL+"/nacos", &NacosOptions{Username: "nacos", Password: "wrong"})
+	defer a.Close()
+	ast.NotNil(a.Set("app.json", []byte(`{}`)))
+
+	a = NewNacosAsyncer(server.URL+"/nacos", nil)
+	defer a.Close()
+	ast.NotNil(a.Set("app.json", []byte(`{}`)))
+}
@@ -0,0 +1,393 @@
+package config
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// PrefixLister 列出前缀下所有的key及其内容
+type PrefixLister interface {
+	ListPrefix(prefix string) (map[string][]byte, error)
+}
+
+// Lister 列出前缀下所有的key，按字典序排序
+type Lister interface {
+	List(prefix string) ([]string, error)
+}
+
+// ListKeys 列出asyncer中前缀下所有的key，asyncer需实现 Lister 或 PrefixLister
+func ListKeys(asyncer Asyncer, prefix string) ([]string, error) {
+	if _, ok := backendOf(asyncer).(Lister); ok {
+		return asyncer.(Lister).List(prefix)
+	}
+
+	if _, ok := backendOf(asyncer).(PrefixLister); ok {
+		l := asyncer.(PrefixLister)
+		kvs, err := l.ListPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(kvs))
+		for key := range kvs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys, nil
+	}
+
+	return nil, errors.Errorf("asyncer %T does not implement Lister", backendOf(asyncer))
+}
+
+// listPrefix 列出前缀下所有的key及其内容，asyncer需实现 PrefixLister 或 Lister
+func listPrefix(asyncer Asyncer, prefix string) (map[string][]byte, error) {
+	switch l := asyncer.(type) {
+	case PrefixLister:
+		return l.ListPrefix(prefix)
+	case Lister:
+		return listGetter{Lister: l, asyncer: asyncer}.ListPrefix(prefix)
+	}
+	return nil, errors.Errorf("asyncer %T does not implement PrefixLister or Lister", asyncer)
+}
+
+// listGetter 使用 Lister 列出key后逐个读取
+type listGetter struct {
+	Lister
+	asyncer Asyncer
+}
+
+func (l listGetter) ListPrefix(prefix string) (map[string][]byte, error) {
+	keys, err := l.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if content := l.asyncer.Get(key); content != nil {
+			ret[key] = content
+		}
+	}
+	return ret, nil
+}
+
+// PrefixAsyncer 将前缀下"一个key一个值"的存储方式组装为一个完整的配置
+//
+// consul/etcd 中常见按key路径存储配置：
+//
+//	app/db/host = example.com
+//	app/db/port = 3306
+//	app/features = ["a", "b"]
+//
+// 使用 PrefixAsyncer 后 Get("app") 返回组装后的JSON：
+//
+//	{"db": {"host": "example.com", "port": 3306}, "features": ["a", "b"]}
+//
+// 值为合法JSON时按JSON解析，否则作为字符串；只包含 prefix+separator 下的key，app2/db 不属于 app
+//
+//	asyncer, err := config.NewPrefixAsyncer(consulAsyncer, "/")
+//	defer asyncer.Close()
+//	cfg := config.NewAsyncConfig(asyncer, "app", cacheTime, false)
+type PrefixAsyncer struct {
+	asyncer   Asyncer
+	lister    PrefixLister
+	separator string
+
+	sync.Mutex
+	// prefix => aggregated notify channel
+	notifyChans map[string]chan struct{}
+	// prefix => watched keys
+	watchedKeys map[string]map[string]bool
+	// key => notify channels of the prefixes containing it
+	keyTargets map[string][]chan struct{}
+
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewPrefixAsyncer asyncer 需实现 PrefixLister 或 Lister 接口，separator 为key路径的分隔符
+func NewPrefixAsyncer(asyncer Asyncer, separator string) (*PrefixAsyncer, error) {
+	var lister PrefixLister
+	switch backendOf(asyncer).(type) {
+	case PrefixLister:
+		lister = asyncer.(PrefixLister)
+	case Lister:
+		lister = listGetter{Lister: asyncer.(Lister), asyncer: asyncer}
+	default:
+		return nil, errors.Errorf("asyncer %T does not implement PrefixLister or Lister", asyncer)
+	}
+
+	if separator == "" {
+		separator = "/"
+	}
+
+	return &PrefixAsyncer{
+		asyncer:     asyncer,
+		lister:      lister,
+		separator:   separator,
+		notifyChans: make(map[string]chan struct{}),
+		watchedKeys: make(map[string]map[string]bool),
+		keyTargets:  make(map[string][]chan struct{}),
+		quit:        make(chan struct{}),
+	}, nil
+}
+
+func (a *PrefixAsyncer) ContentType(prefix string) ContentType {
+	return T_JSON
+}
+
+func (a *PrefixAsyncer) Get(prefix string) []byte {
+	kvs, err := a.lister.ListPrefix(prefix)
+	if err != nil {
+		logger.Errorf("list prefix[%s] err:%v", prefix, err)
+		return nil
+	}
+
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	// 按key排序组装，冲突的key每次的结果一致
+	sorted := make([]string, 0, len(kvs))
+	for key := range kvs {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	tree := make(map[string]interface{})
+	for _, key := range sorted {
+		keys := a.relativeKeys(prefix, key)
+		if len(keys) == 0 {
+			continue
+		}
+		if err := setTreeValue(tree, keys, decodeKVValue(kvs[key])); err != nil {
+			logger.Warnf("assemble prefix[%s] key[%s] err:%v", prefix, key, err)
+		}
+	}
+
+	a.syncWatches(prefix, kvs)
+
+	bs, err := json.Marshal(tree)
+	if err != nil {
+		logger.Errorf("marshal prefix[%s] err:%v", prefix, err)
+		return nil
+	}
+
+	return bs
+}
+
+// Set 将配置展开后逐个key写入
+//
+// 注意：不会删除配置中已不存在的key
+func (a *PrefixAsyncer) Set(prefix string, content []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return err
+	}
+
+	leaves := make(map[string]interface{})
+	flattenLeaves("", v, leaves)
+
+	for keyPath, value := range leaves {
+		key := strings.TrimSuffix(prefix, a.separator) + a.separator + strings.ReplaceAll(keyPath, ".", a.separator)
+
+		// 字符串不是合法JSON时原样写入，否则按JSON编码，避免 "123" 读回后变为数字
+		var bs []byte
+		if s, ok := value.(string); ok && !json.Valid([]byte(s)) {
+			bs = []byte(s)
+		} else {
+			var err error
+			if bs, err = json.Marshal(value); err != nil {
+				return err
+			}
+		}
+
+		if err := a.asyncer.Set(key, bs); err != nil {
+			return errors.Wrapf(err, "set key[%s]", key)
+		}
+	}
+
+	return nil
+}
+
+// Watch 聚合前缀下所有key的变化通知
+//
+// 被包装的asyncer不支持通知时返回nil
+// 新增的key会在下次Get时加入监控
+func (a *PrefixAsyncer) Watch(prefix string) chan struct{} {
+	a.Lock()
+	ch, ok := a.notifyChans[prefix]
+	a.Unlock()
+	if ok {
+		return ch
+	}
+
+	kvs, err := a.lister.ListPrefix(prefix)
+	if err != nil {
+		logger.Errorf("list prefix[%s] err:%v", prefix, err)
+		return nil
+	}
+
+	// 以前缀本身探测是否支持通知
+	probe := a.asyncer.Watch(prefix)
+	if probe == nil {
+		return nil
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	if ch, ok = a.notifyChans[prefix]; ok {
+		return ch
+	}
+	ch = make(chan struct{}, 1)
+	a.notifyChans[prefix] = ch
+	a.watchedKeys[prefix] = map[string]bool{prefix: true}
+	a.addTargetLocked(prefix, probe, ch)
+
+	a.watchKeysLocked(prefix, ch, kvs)
+
+	return ch
+}
+
+func (a *PrefixAsyncer) syncWatches(prefix string, kvs map[string][]byte) {
+	a.Lock()
+	defer a.Unlock()
+
+	if ch, ok := a.notifyChans[prefix]; ok {
+		a.watchKeysLocked(prefix, ch, kvs)
+	}
+}
+
+// watchKeysLocked 按前缀记录已监控的key，同一个key在不同前缀下都会收到通知
+func (a *PrefixAsyncer) watchKeysLocked(prefix string, ch chan struct{}, kvs map[string][]byte) {
+	watched := a.watchedKeys[prefix]
+	for key := range kvs {
+		if watched[key] {
+			continue
+		}
+		if _, ok := a.keyTargets[key]; ok {
+			watched[key] = true
+			a.keyTargets[key] = append(a.keyTargets[key], ch)
+			continue
+		}
+		if notify := a.asyncer.Watch(key); notify != nil {
+			watched[key] = true
+			a.addTargetLocked(key, notify, ch)
+		}
+	}
+}
+
+// addTargetLocked 每个key只启动一个转发，asyncer对同一个key返回同一个channel
+func (a *PrefixAsyncer) addTargetLocked(key string, notify chan struct{}, ch chan struct{}) {
+	if _, ok := a.keyTargets[key]; !ok {
+		go a.forward(key, notify)
+	}
+	a.keyTargets[key] = append(a.keyTargets[key], ch)
+}
+
+func (a *PrefixAsyncer) forward(key string, from chan struct{}) {
+	for {
+		select {
+		case _, ok := <-from:
+			if !ok {
+				return
+			}
+			a.Lock()
+			targets := a.keyTargets[key]
+			a.Unlock()
+			for _, to := range targets {
+				select {
+				case to <- struct{}{}:
+				default:
+				}
+			}
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+// Close 停止转发各key的变化通知，不关闭被包装的asyncer
+func (a *PrefixAsyncer) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.quit)
+	})
+	return nil
+}
+
+// relativeKeys 返回key相对prefix的路径，key不在 prefix+separator 下时返回nil
+func (a *PrefixAsyncer) relativeKeys(prefix string, key string) []string {
+	prefix = strings.TrimSuffix(prefix, a.separator)
+	if prefix != "" {
+		if !strings.HasPrefix(key, prefix+a.separator) {
+			return nil
+		}
+		key = key[len(prefix)+len(a.separator):]
+	}
+
+	rel := strings.Trim(key, a.separator)
+	if rel == "" {
+		return nil
+	}
+
+	return strings.Split(rel, a.separator)
+}
+
+// decodeKVValue 合法的JSON按JSON解析，否则作为字符串
+func decodeKVValue(value []byte) interface{} {
+	var v interface{}
+	if err := json.Unmarshal(value, &v); err == nil {
+		return v
+	}
+
+	return string(value)
+}
+
+// setTreeValue 按路径设置值，中间节点不存在时自动创建
+func setTreeValue(tree map[string]interface{}, keys []string, value interface{}) error {
+	node := tree
+	for i, key := range keys[:len(keys)-1] {
+		child, ok := node[key]
+		if !ok {
+			sub := make(map[string]interface{})
+			node[key] = sub
+			node = sub
+			continue
+		}
+		sub, ok := child.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("path[%s] is not a map", strings.Join(keys[:i+1], "."))
+		}
+		node = sub
+	}
+
+	lastKey := keys[len(keys)-1]
+	if exist, ok := node[lastKey].(map[string]interface{}); ok {
+		// 目录节点，合并内容
+		if sub, ok := value.(map[string]interface{}); ok {
+			mergeMap(exist, sub)
+			return nil
+		}
+		return errors.Errorf("path[%s] conflicts with its sub keys", strings.Join(keys, "."))
+	}
+	node[lastKey] = value
+
+	return nil
+}
+
+// flattenLeaves 展开到叶子节点（map之外的值）
+func flattenLeaves(prefix string, v interface{}, leaves map[string]interface{}) {
+	if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+		for k, sub := range m {
+			flattenLeaves(joinKeyPath(prefix, k), sub, leaves)
+		}
+		return
+	}
+
+	if prefix != "" {
+		leaves[prefix] = v
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(true)
+	mock.Set("app/db/host", []byte("example.com"))
+	mock.Set("app/db/port", []byte("3306"))
+	mock.Set("app/features", []byte(`["a", "b"]`))
+	mock.Set("app/", []byte(""))
+	mock.Set("other/key", []byte("other"))
+	// 前缀相同的兄弟key不属于app
+	mock.Set("app2/db", []byte("x"))
+	mock.Set("application/x", []byte("y"))
+
+	_, err := NewPrefixAsyncer(struct{ Asyncer }{mock}, "/")
+	ast.NotNil(err)
+
+	asyncer, err := NewPrefixAsyncer(mock, "/")
+	ast.Nil(err)
+	defer asyncer.Close()
+
+	cfg := NewAsyncConfig(asyncer, "app", time.Minute, false)
+	defer cfg.Close()
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Equal(int64(3306), cfg.Int("db.port"))
+	ast.Equal("b", cfg.String("features.1"))
+	ast.Nil(cfg.Get("key"))
+	ast.Nil(cfg.Get("2"))
+	ast.Nil(cfg.Get("lication"))
+
+	// per-key watches aggregated
+	mock.Set("app/db/host", []byte("example2.com"))
+	for i := 0; i < 100 && cfg.String("db.host") != "example2.com"; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	ast.Equal("example2.com", cfg.String("db.host"))
+
+	// write back
+	ast.Nil(cfg.Set("db.user", "root"))
+	v, _ := mock.data.Load("app/db/user")
+	ast.Equal([]byte("root"), v)
+	v, _ = mock.data.Load("app/db/port")
+	ast.Equal([]byte("3306"), v)
+}
+
+func TestPrefixAsyncerConflict(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(false)
+	mock.Set("app/db", []byte("x"))
+	mock.Set("app/db/host", []byte("example.com"))
+
+	asyncer, err := NewPrefixAsyncer(mock, "/")
+	ast.Nil(err)
+
+	// 按key排序组装，冲突的key结果稳定
+	first := asyncer.Get("app")
+	for i := 0; i < 20; i++ {
+		ast.Equal(first, asyncer.Get("app"))
+	}
+	ast.JSONEq(`{"db": "x"}`, string(first))
+}
+
+func TestFilePrefixAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	ast.Nil(err)
+	defer os.RemoveAll(dir)
+
+	ast.Nil(os.MkdirAll(filepath.Join(dir, "db"), 0700))
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "db", "host"), []byte("example.com"), 0600))
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "timeout"), []byte("3"), 0600))
+
+	asyncer, err := NewPrefixAsyncer(NewFileAsyncer(), string(filepath.Separator))
+	ast.Nil(err)
+
+	cfg := NewAsyncConfig(asyncer, dir, time.Minute, false)
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Equal(int64(3), cfg.Int("timeout"))
+
+	keys, err := ListKeys(NewFileAsyncer(), dir)
+	ast.Nil(err)
+	ast.Equal([]string{filepath.Join(dir, "db", "host"), filepath.Join(dir, "timeout")}, keys)
+}
+
+type listOnlyAsyncer struct {
+	Asyncer
+	Lister
+}
+
+func TestListerPrefixAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(false)
+	mock.Set("svc/b", []byte("2"))
+	mock.Set("svc/a", []byte("1"))
+	mock.Set("other", []byte("x"))
+
+	keys, err := ListKeys(mock, "svc/")
+	ast.Nil(err)
+	ast.Equal([]string{"svc/a", "svc/b"}, keys)
+
+	_, err = ListKeys(struct{ Asyncer }{mock}, "svc/")
+	ast.NotNil(err)
+
+	// 只实现了Lister时逐个key读取
+	listOnly := listOnlyAsyncer{Asyncer: rawAsyncer{mock}, Lister: mock}
+	keys, err = ListKeys(listOnly, "svc/")
+	ast.Nil(err)
+	ast.Equal([]string{"svc/a", "svc/b"}, keys)
+
+	asyncer, err := NewPrefixAsyncer(listOnly, "/")
+	ast.Nil(err)
+	cfg := NewAsyncConfig(asyncer, "svc", time.Minute, false)
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.EqualValues(2, cfg.Int("b"))
+
+	// 包装后以被包装的后端判断
+	timeout := NewTimeoutAsyncer(listOnly, Timeouts{Get: time.Second})
+	keys, err = ListKeys(timeout, "svc/")
+	ast.Nil(err)
+	ast.Equal([]string{"svc/a", "svc/b"}, keys)
+	asyncer, err = NewPrefixAsyncer(timeout, "/")
+	ast.Nil(err)
+	ast.JSONEq(`{"a": 1, "b": 2}`, string(asyncer.Get("svc")))
+	_, err = NewPrefixAsyncer(NewTimeoutAsyncer(struct{ Asyncer }{mock}, Timeouts{Get: time.Second}), "/")
+	ast.NotNil(err)
+	_, err = ListKeys(NewTimeoutAsyncer(struct{ Asyncer }{mock}, Timeouts{}), "svc/")
+	ast.NotNil(err)
+}
+
+func TestPrefixAsyncerStringRoundTrip(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(false)
+	asyncer, err := NewPrefixAsyncer(mock, "/")
+	ast.Nil(err)
+
+	ast.Nil(asyncer.Set("app", []byte(`{"a": "123", "b": "true", "c": "example.com", "d": 1, "e": ""}`)))
+	v, _ := mock.data.Load("app/c")
+	ast.Equal([]byte("example.com"), v)
+	ast.JSONEq(`{"a": "123", "b": "true", "c": "example.com", "d": 1, "e": ""}`, string(asyncer.Get("app")))
+}
+
+func TestPrefixAsyncerOverlappingWatch(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(true)
+	mock.Set("app/db/host", []byte("example.com"))
+
+	asyncer, err := NewPrefixAsyncer(mock, "/")
+	ast.Nil(err)
+	defer asyncer.Close()
+
+	app := asyncer.Watch("app")
+	db := asyncer.Watch("app/db")
+	ast.NotNil(app)
+	ast.NotNil(db)
+
+	// 两个前缀都包含该key，都应收到通知
+	mock.Set("app/db/host", []byte("example2.com"))
+	for _, ch := range []chan struct{}{app, db} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("missing notify")
+		}
+	}
+}
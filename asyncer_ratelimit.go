@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitWait 等待令牌的最长时间，超时后Get返回nil（保留旧值），Set返回 ErrRateLimited
+var RateLimitWait = time.Second
+
+// ErrRateLimited 后端调用被限流
+var ErrRateLimited = errors.New("rate limited")
+
+var _rateLimitedAsyncers sync.Map // Asyncer => *RateLimitedAsyncer
+
+// RateLimitedAsyncer 使用令牌桶限制后端Get/Set的调用频率
+//
+// 同一后端的多个配置共享一个限流器，避免大量缓存同时过期时压垮后端
+type RateLimitedAsyncer struct {
+	asyncer Asyncer
+	limiter *rate.Limiter
+}
+
+// NewRateLimitedAsyncer 每秒最多r次调用，最多突发burst次
+func NewRateLimitedAsyncer(asyncer Asyncer, r float64, burst int) *RateLimitedAsyncer {
+	return &RateLimitedAsyncer{
+		asyncer: asyncer,
+		limiter: rate.NewLimiter(rate.Limit(r), burst),
+	}
+}
+
+// RateLimit 返回asyncer共享的限流包装，同一asyncer多次调用返回同一个包装，
+// 之后调用的r、burst与已有的不同时只记录警告，需要调整时使用 SetLimit
+//
+// 包装会一直保留以便共享，asyncer不再使用时调用 RemoveRateLimit；
+// asyncer不能比较（如包含slice、map的struct）时返回不共享的包装
+func RateLimit(asyncer Asyncer, r float64, burst int) *RateLimitedAsyncer {
+	if !isComparable(asyncer) {
+		return NewRateLimitedAsyncer(asyncer, r, burst)
+	}
+
+	v, loaded := _rateLimitedAsyncers.Load(asyncer)
+	if !loaded {
+		v, loaded = _rateLimitedAsyncers.LoadOrStore(asyncer, NewRateLimitedAsyncer(asyncer, r, burst))
+	}
+	a := v.(*RateLimitedAsyncer)
+	if loaded && (a.limiter.Limit() != rate.Limit(r) || a.limiter.Burst() != burst) {
+		logger.Warnf("asyncer %T already rate limited at %v/%d, ignore %v/%d", asyncer, a.limiter.Limit(), a.limiter.Burst(), r, burst)
+	}
+	return a
+}
+
+// RemoveRateLimit 移除 RateLimit 共享的包装，之后再调用 RateLimit 时创建新的包装
+func RemoveRateLimit(asyncer Asyncer) {
+	if isComparable(asyncer) {
+		_rateLimitedAsyncers.Delete(asyncer)
+	}
+}
+
+// SetLimit 调整限流
+func (a *RateLimitedAsyncer) SetLimit(r float64, burst int) {
+	a.limiter.SetLimit(rate.Limit(r))
+	a.limiter.SetBurst(burst)
+}
+
+func (a *RateLimitedAsyncer) wait() error {
+	ctx, cancel := context.WithTimeout(context.Background(), RateLimitWait)
+	defer cancel()
+
+	if err := a.limiter.Wait(ctx); err != nil {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+func (a *RateLimitedAsyncer) ContentType(key string) ContentType {
+	return a.asyncer.ContentType(key)
+}
+
+func (a *RateLimitedAsyncer) Get(key string) []byte {
+	if err := a.wait(); err != nil {
+		logger.Warnf("get conf[%s] err:%v", key, err)
+		return nil
+	}
+	return a.asyncer.Get(key)
+}
+
+func (a *RateLimitedAsyncer) Set(key string, value []byte) error {
+	if err := a.wait(); err != nil {
+		return backendError(key, err)
+	}
+	return a.asyncer.Set(key, value)
+}
+
+// SetVersioned 同 Set，asyncer未实现 VersionedSetter 时版本号为空
+func (a *RateLimitedAsyncer) SetVersioned(key string, value []byte) (string, error) {
+	if err := a.wait(); err != nil {
+		return "", backendError(key, err)
+	}
+	return setVersioned(a.asyncer, key, value)
+}
+
+// CompareAndSet asyncer未实现 CASSetter 时返回错误
+func (a *RateLimitedAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	if err := a.wait(); err != nil {
+		return false, backendError(key, err)
+	}
+	return compareAndSet(a.asyncer, key, old, value)
+}
+
+// SetWithTTL asyncer未实现 TTLSetter 时返回错误
+func (a *RateLimitedAsyncer) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	if err := a.wait(); err != nil {
+		return backendError(key, err)
+	}
+	return setWithTTL(a.asyncer, key, value, ttl)
+}
+
+// BatchGet 计为一次调用，asyncer未实现 BatchGetter 时逐个读取
+func (a *RateLimitedAsyncer) BatchGet(keys []string) (map[string][]byte, error) {
+	if err := a.wait(); err != nil {
+		return nil, backendError(strings.Join(keys, ","), err)
+	}
+	return BatchGet(a.asyncer, keys)
+}
+
+func (a *RateLimitedAsyncer) ContentTypeHint(key string) (ContentType, bool) {
+	if h, ok := a.asyncer.(ContentTypeHinter); ok {
+		return h.ContentTypeHint(key)
+	}
+	return T_JSON, false
+}
+
+func (a *RateLimitedAsyncer) Watch(key string) chan struct{} {
+	return a.asyncer.Watch(key)
+}
+
+func (a *RateLimitedAsyncer) GetReader(key string) (io.ReadCloser, error) {
+	if err := a.wait(); err != nil {
+		return nil, backendError(key, err)
+	}
+	return getReader(a.asyncer, key)
+}
+
+func (a *RateLimitedAsyncer) List(prefix string) ([]string, error) {
+	if err := a.wait(); err != nil {
+		return nil, backendError(prefix, err)
+	}
+	return ListKeys(a.asyncer, prefix)
+}
+
+func (a *RateLimitedAsyncer) ListPrefix(prefix string) (map[string][]byte, error) {
+	if err := a.wait(); err != nil {
+		return nil, backendError(prefix, err)
+	}
+	return listPrefix(a.asyncer, prefix)
+}
+
+func (a *RateLimitedAsyncer) Capabilities() Capabilities {
+	return wrapperCapabilities(a.asyncer)
+}
+
+func (a *RateLimitedAsyncer) unwrap() Asyncer {
+	return a.asyncer
+}
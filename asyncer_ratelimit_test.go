@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitedAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	wait := RateLimitWait
+	RateLimitWait = 10 * time.Millisecond
+	defer func() { RateLimitWait = wait }()
+
+	mock := NewMockAsyncer(true)
+	mock.Set("rate.json", []byte(`{"a": 1}`))
+
+	a := RateLimit(mock, 1, 2)
+	ast.True(a == RateLimit(mock, 100, 100), "shared by asyncer")
+	ast.Equal(Capabilities{Watch: true, CAS: true, BatchGet: true, List: true}, ProbeCapabilities(a))
+
+	ast.NotNil(a.Get("rate.json"))
+	ast.Nil(a.Set("rate.json", []byte(`{"a": 2}`)))
+
+	// 令牌用完
+	ast.Nil(a.Get("rate.json"))
+	err := a.Set("rate.json", []byte(`{"a": 3}`))
+	ast.True(errors.Is(err, ErrRateLimited))
+	ast.True(errors.Is(err, ErrBackendUnavailable))
+
+	a.SetLimit(1000, 10)
+	time.Sleep(2 * time.Millisecond)
+	ast.NotNil(a.Get("rate.json"))
+	ast.NotNil(a.Watch("rate.json"))
+
+	RemoveRateLimit(mock)
+	ast.False(a == RateLimit(mock, 1, 2), "removed")
+	RemoveRateLimit(mock)
+
+	// 不能比较的asyncer不共享
+	uncomparable := uncomparableAsyncer{Asyncer: mock}
+	ast.False(RateLimit(uncomparable, 1, 1) == RateLimit(uncomparable, 1, 1))
+	RemoveRateLimit(uncomparable)
+}
+
+type uncomparableAsyncer struct {
+	Asyncer
+	tags []string
+}
@@ -2,7 +2,12 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
+	"net"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -17,7 +22,75 @@ type RedisAsyncer struct {
 // NewRedisAsyncer create new RedisAsyncer.
 // subChannel is channel name for subscribing to notify value changed,
 // the notify feature will be disabled when subChannel is not specified.
-func NewRedisAsyncer(options *redis.Options, subChannel string) *RedisAsyncer {
+// opts: TLS及代理等连接选项，见 BackendOptions，指定了 WithTLS 时忽略options.TLSConfig
+func NewRedisAsyncer(options *redis.Options, subChannel string, opts ...BackendOption) *RedisAsyncer {
+	if len(opts) > 0 {
+		o := *options
+		backend := NewBackendOptions(opts...)
+		o.Dialer = redisDialer(backend, o.TLSConfig)
+		o.OnConnect = redisTokenAuth(o.OnConnect, o.Username, backend.TokenSource)
+		options = &o
+	}
+
+	return newRedisAsyncer(options, subChannel)
+}
+
+// NewRedisAsyncerWithResolver 通过resolver解析redis地址，连接失败时重新解析，忽略options.Addr
+func NewRedisAsyncerWithResolver(options *redis.Options, resolver Resolver, subChannel string, opts ...BackendOption) *RedisAsyncer {
+	pool := newEndpointPool(resolver)
+	o := *options
+	if len(opts) > 0 {
+		backend := NewBackendOptions(opts...)
+		pool.dial = redisDialer(backend, o.TLSConfig)
+		o.OnConnect = redisTokenAuth(o.OnConnect, o.Username, backend.TokenSource)
+	}
+	o.Dialer = pool.DialContext
+
+	return newRedisAsyncer(&o, subChannel)
+}
+
+// redisDialer 自定义Dialer时redis不再使用options.TLSConfig，backend未指定 WithTLS 时由dial完成TLS握手
+func redisDialer(backend *BackendOptions, tlsConfig *tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if backend.TLS != nil || tlsConfig == nil {
+		return backend.DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := backend.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return tlsHandshake(ctx, conn, tlsConfig, addr)
+	}
+}
+
+// redisTokenAuth 建立连接时使用token作为密码认证
+func redisTokenAuth(onConnect func(context.Context, *redis.Conn) error, username string, source TokenSource) func(context.Context, *redis.Conn) error {
+	if source == nil {
+		return onConnect
+	}
+
+	return func(ctx context.Context, cn *redis.Conn) error {
+		tok, err := source.Token(ctx)
+		if err != nil {
+			return err
+		}
+		if username != "" {
+			err = cn.AuthACL(ctx, username, tok.Value).Err()
+		} else {
+			err = cn.Auth(ctx, tok.Value).Err()
+		}
+		if err != nil {
+			return err
+		}
+		if onConnect != nil {
+			return onConnect(ctx, cn)
+		}
+		return nil
+	}
+}
+
+func newRedisAsyncer(options *redis.Options, subChannel string) *RedisAsyncer {
 	db := redis.NewClient(options)
 	a := &RedisAsyncer{
 		db:  db,
@@ -109,3 +182,111 @@ func (a *RedisAsyncer) Watch(key string) chan struct{} {
 
 	return ch
 }
+
+// List 使用SCAN列出前缀下所有的key
+func (a *RedisAsyncer) List(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	iter := a.db.Scan(a.ctx, 0, escapeGlob(prefix)+"*", 100).Iterator()
+	for iter.Next(a.ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// escapeGlob 转义SCAN MATCH中的通配符，前缀按字面匹配
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// ListPrefix 使用SCAN列出前缀下所有的key及其内容
+func (a *RedisAsyncer) ListPrefix(prefix string) (map[string][]byte, error) {
+	keys, err := a.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.BatchGet(keys)
+}
+
+var redisCASScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+func (a *RedisAsyncer) Capabilities() Capabilities {
+	return Capabilities{
+		Watch:    a.notifyEnabled,
+		CAS:      true,
+		BatchGet: true,
+		List:     true,
+		TTL:      true,
+	}
+}
+
+// CompareAndSet old为nil时使用SETNX，否则使用lua脚本比较后设置
+func (a *RedisAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	var ok bool
+	if old == nil {
+		var err error
+		if ok, err = a.db.SetNX(a.ctx, key, string(value), 0).Result(); err != nil {
+			return false, err
+		}
+	} else {
+		ret, err := redisCASScript.Run(a.ctx, a.db, []string{key}, string(old), string(value)).Int()
+		if err != nil {
+			return false, err
+		}
+		ok = ret == 1
+	}
+
+	if ok {
+		a.notify(key)
+	}
+	return ok, nil
+}
+
+func (a *RedisAsyncer) BatchGet(keys []string) (map[string][]byte, error) {
+	ret := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return ret, nil
+	}
+
+	vals, err := a.db.MGet(a.ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, val := range vals {
+		if s, ok := val.(string); ok && s != "" {
+			ret[keys[i]] = []byte(s)
+		}
+	}
+
+	return ret, nil
+}
+
+func (a *RedisAsyncer) SetWithTTL(key string, content []byte, ttl time.Duration) error {
+	err := a.db.Set(a.ctx, key, string(content), ttl).Err()
+
+	if err == nil {
+		a.notify(key)
+	}
+
+	return err
+}
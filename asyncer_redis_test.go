@@ -1,7 +1,10 @@
 package config
 
 import (
+	"context"
+	"crypto/tls"
 	//"encoding/json"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -97,6 +100,129 @@ func (s *redisAsyncerTestSuite) TestNotify() {
 	s.EqualValues(2, redisCfg.Int("foo.bar"), "get foo.bar")
 }
 
+func (s *redisAsyncerTestSuite) TestResolver() {
+	resolved := 0
+	asyncer := NewRedisAsyncerWithResolver(&redis.Options{
+		MaxRetries: -1,
+	}, ResolverFunc(func(ctx context.Context) ([]string, error) {
+		resolved++
+		if resolved == 1 {
+			// 第一次解析出不可用的地址
+			return []string{"127.0.0.1:1"}, nil
+		}
+		return []string{s.rds.Addr()}, nil
+	}), "")
+
+	s.EqualValues(s.defaultValue, asyncer.Get(s.defaultKey), "re-resolve on dial failure")
+	s.Equal(2, resolved)
+}
+
+func (s *redisAsyncerTestSuite) TestBackendOptions() {
+	proxy, tunnels := newTestConnectProxy(s.T(), "user", "pass")
+	proxyURL := "http://user:pass@" + proxy.Listener.Addr().String()
+
+	asyncer := NewRedisAsyncer(&redis.Options{
+		Addr: s.rds.Addr(),
+	}, "", WithProxy(proxyURL))
+	s.EqualValues(s.defaultValue, asyncer.Get(s.defaultKey), "via proxy")
+	s.EqualValues(1, atomic.LoadInt32(tunnels))
+
+	asyncer = NewRedisAsyncerWithResolver(&redis.Options{}, StaticResolver(s.rds.Addr()), "", WithProxy(proxyURL))
+	s.EqualValues(s.defaultValue, asyncer.Get(s.defaultKey), "resolver via proxy")
+	s.EqualValues(2, atomic.LoadInt32(tunnels))
+}
+
+func (s *redisAsyncerTestSuite) TestBackendOptionsKeepTLS() {
+	certPEM, keyPEM := genTestCert(s.T(), "redis", time.Now().Add(time.Hour))
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	s.Require().Nil(err)
+	rds, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{cert}})
+	s.Require().Nil(err)
+	defer rds.Close()
+	rds.Set(s.defaultKey, s.defaultValue)
+
+	proxy, tunnels := newTestConnectProxy(s.T(), "user", "pass")
+	proxyURL := "http://user:pass@" + proxy.Listener.Addr().String()
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+
+	asyncer := NewRedisAsyncer(&redis.Options{
+		Addr:      rds.Addr(),
+		TLSConfig: tlsConfig,
+	}, "", WithProxy(proxyURL))
+	s.EqualValues(s.defaultValue, asyncer.Get(s.defaultKey), "tls via proxy")
+	s.EqualValues(1, atomic.LoadInt32(tunnels))
+
+	asyncer = NewRedisAsyncerWithResolver(&redis.Options{TLSConfig: tlsConfig}, StaticResolver(rds.Addr()), "", WithProxy(proxyURL))
+	s.EqualValues(s.defaultValue, asyncer.Get(s.defaultKey), "resolver tls via proxy")
+	s.EqualValues(2, atomic.LoadInt32(tunnels))
+}
+
+func (s *redisAsyncerTestSuite) TestTokenSource() {
+	rds, err := miniredis.Run()
+	s.Nil(err)
+	defer rds.Close()
+	rds.RequireAuth("token")
+	rds.Set(s.defaultKey, s.defaultValue)
+
+	asyncer := NewRedisAsyncer(&redis.Options{Addr: rds.Addr()}, "", WithTokenSource(StaticToken("token")))
+	s.EqualValues(s.defaultValue, asyncer.Get(s.defaultKey))
+
+	asyncer = NewRedisAsyncer(&redis.Options{Addr: rds.Addr(), MaxRetries: -1}, "", WithTokenSource(StaticToken("wrong")))
+	s.Nil(asyncer.Get(s.defaultKey))
+}
+
+func (s *redisAsyncerTestSuite) TestList() {
+	asyncer := NewRedisAsyncer(&redis.Options{
+		Addr: s.rds.Addr(),
+	}, "")
+	s.rds.Set("list/b", "2")
+	s.rds.Set("list/a", "1")
+
+	keys, err := asyncer.List("list/")
+	s.Nil(err)
+	s.Equal([]string{"list/a", "list/b"}, keys)
+
+	kvs, err := asyncer.ListPrefix("list/")
+	s.Nil(err)
+	s.Equal(map[string][]byte{"list/a": []byte("1"), "list/b": []byte("2")}, kvs)
+
+	// 前缀中的通配符按字面匹配
+	s.rds.Set("glob*/a", "1")
+	s.rds.Set("globx/a", "2")
+	keys, err = asyncer.List("glob*/")
+	s.Nil(err)
+	s.Equal([]string{"glob*/a"}, keys)
+	s.Equal(`a\*b\?\[c]\\`, escapeGlob(`a*b?[c]\`))
+}
+
+func (s *redisAsyncerTestSuite) TestCapabilities() {
+	asyncer := NewRedisAsyncer(&redis.Options{
+		Addr: s.rds.Addr(),
+	}, "")
+	s.Equal(Capabilities{CAS: true, BatchGet: true, List: true, TTL: true}, ProbeCapabilities(asyncer))
+
+	ok, err := asyncer.CompareAndSet("cas", nil, []byte("1"))
+	s.Nil(err)
+	s.True(ok)
+	ok, err = asyncer.CompareAndSet("cas", nil, []byte("2"))
+	s.Nil(err)
+	s.False(ok)
+	ok, err = asyncer.CompareAndSet("cas", []byte("0"), []byte("2"))
+	s.Nil(err)
+	s.False(ok)
+	ok, err = asyncer.CompareAndSet("cas", []byte("1"), []byte("2"))
+	s.Nil(err)
+	s.True(ok)
+	s.Equal([]byte("2"), asyncer.Get("cas"))
+
+	kvs, err := asyncer.BatchGet([]string{"cas", "not_exist"})
+	s.Nil(err)
+	s.Equal(map[string][]byte{"cas": []byte("2")}, kvs)
+
+	s.Nil(asyncer.SetWithTTL("ttl", []byte("v"), time.Minute))
+	s.Equal(time.Minute, s.rds.TTL("ttl"))
+}
+
 func TestRedisAsyncerTestSuite(t *testing.T) {
 	suite.Run(t, new(redisAsyncerTestSuite))
 }
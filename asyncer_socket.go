@@ -0,0 +1,349 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SocketReconnectInterval watch连接断开后重连的间隔，在创建 SocketAsyncer 时确定
+var SocketReconnectInterval = time.Second
+
+// SocketPollInterval 服务端不支持watch时轮询的间隔，在创建 SocketAsyncer 时确定
+var SocketPollInterval = 5 * time.Second
+
+// SocketAsyncer 从 SocketServer 读取配置，key为配置的keyPath，只读
+type SocketAsyncer struct {
+	path   string
+	agent  bool // key为后端key，见 NewAgentAsyncer
+	nextID uint64
+
+	version           int32 // 最近一次协商的协议版本，见 ServerVersion
+	pollInterval      time.Duration
+	reconnectInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	sync.Mutex
+	notifyChans map[string]chan struct{}
+	values      map[string]socketValue // 最近一次的值，请求时携带其版本，见 SocketRequest.Hash
+	watchConns  map[net.Conn]struct{}  // watch中的连接，Close时关闭
+}
+
+type socketValue struct {
+	value []byte
+	hash  string
+}
+
+func NewSocketAsyncer(path string) *SocketAsyncer {
+	a := &SocketAsyncer{
+		path:              path,
+		pollInterval:      SocketPollInterval,
+		reconnectInterval: SocketReconnectInterval,
+		notifyChans:       make(map[string]chan struct{}),
+		values:            make(map[string]socketValue),
+		watchConns:        make(map[net.Conn]struct{}),
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	return a
+}
+
+func (a *SocketAsyncer) dial() (net.Conn, error) {
+	d := net.Dialer{Timeout: 3 * time.Second}
+	return d.DialContext(a.ctx, "unix", a.path)
+}
+
+func (a *SocketAsyncer) ContentType(key string) ContentType {
+	return T_JSON
+}
+
+func (a *SocketAsyncer) Get(key string) []byte {
+	conn, err := a.dial()
+	if err != nil {
+		logger.Errorf("dial config socket %s err:%v", a.path, err)
+		return nil
+	}
+	defer conn.Close()
+
+	resp, err := a.request(conn, bufio.NewReader(conn), SocketOpGet, key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from socket err:%v", key, err)
+		return nil
+	}
+
+	if string(resp.Value) == "null" {
+		return nil
+	}
+	return resp.Value
+}
+
+func (a *SocketAsyncer) request(conn net.Conn, r *bufio.Reader, op, key string) (*SocketResponse, error) {
+	req := SocketRequest{
+		ID:        atomic.AddUint64(&a.nextID, 1),
+		Op:        op,
+		Key:       key,
+		Encodings: acceptedEncodings(),
+	}
+	a.Lock()
+	req.Hash = a.values[key].hash
+	a.Unlock()
+	if a.agent {
+		req.Source, req.Key = key, RootKey
+	}
+	if err := writeFrame(conn, req); err != nil {
+		return nil, err
+	}
+
+	var resp SocketResponse
+	if err := readFrame(r, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, socketServerError(resp.Error)
+	}
+	if err := a.resolve(key, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// resolve 解压或使用未变化的值，之后resp.Value为原始的值
+func (a *SocketAsyncer) resolve(key string, resp *SocketResponse) error {
+	a.Lock()
+	defer a.Unlock()
+
+	if resp.NotModified {
+		cached, ok := a.values[key]
+		if !ok || cached.hash != resp.Hash {
+			return errors.Errorf("conf[%s] not modified but not cached", key)
+		}
+		resp.Value = cached.value
+		return nil
+	}
+
+	value, err := decodeValue(resp)
+	if err != nil {
+		return err
+	}
+	resp.Value, resp.Data, resp.Encoding = value, nil, ""
+	// 旧版本的服务端不返回Hash
+	if resp.Hash != "" {
+		a.values[key] = socketValue{value: value, hash: resp.Hash}
+	}
+	return nil
+}
+
+// socketServerError 服务端响应的错误，区别于连接的错误
+type socketServerError string
+
+func (e socketServerError) Error() string {
+	return string(e)
+}
+
+func isUnsupportedOp(err error) bool {
+	e, ok := err.(socketServerError)
+	return ok && strings.HasPrefix(string(e), "unsupported op")
+}
+
+// hello 协商协议版本及服务端支持的操作，服务端返回错误时（版本1）按版本1处理
+func (a *SocketAsyncer) hello(conn net.Conn, r *bufio.Reader) (int, []string, error) {
+	req := SocketRequest{
+		ID:      atomic.AddUint64(&a.nextID, 1),
+		Op:      SocketOpHello,
+		Version: SocketProtocolVersion,
+	}
+	if err := writeFrame(conn, req); err != nil {
+		return 0, nil, err
+	}
+
+	var resp SocketResponse
+	if err := readFrame(r, &resp); err != nil {
+		return 0, nil, err
+	}
+
+	version, ops := resp.Version, resp.Ops
+	if resp.Error != "" || version <= 0 {
+		version, ops = 1, []string{SocketOpGet, SocketOpWatch}
+	}
+	if version > SocketProtocolVersion {
+		version = SocketProtocolVersion
+	}
+	atomic.StoreInt32(&a.version, int32(version))
+	return version, ops, nil
+}
+
+// ServerVersion 最近一次Watch连接时与服务端协商的协议版本，未连接时为0
+func (a *SocketAsyncer) ServerVersion() int {
+	return int(atomic.LoadInt32(&a.version))
+}
+
+func (a *SocketAsyncer) Set(key string, content []byte) error {
+	return errors.New("config socket is read-only")
+}
+
+func (a *SocketAsyncer) Watch(key string) chan struct{} {
+	a.Lock()
+	defer a.Unlock()
+
+	if ch, ok := a.notifyChans[key]; ok {
+		return ch
+	}
+
+	ch := make(chan struct{}, 1)
+	a.notifyChans[key] = ch
+	go a.watch(key, ch)
+
+	return ch
+}
+
+func (a *SocketAsyncer) watch(key string, ch chan struct{}) {
+	for reconnect := false; ; reconnect = true {
+		if err := a.watchOnce(key, ch, reconnect); err != nil && a.ctx.Err() == nil {
+			logger.Warnf("watch conf[%s] from socket err:%v", key, err)
+		}
+		if !a.sleep(a.reconnectInterval) {
+			return
+		}
+	}
+}
+
+// sleep 等待d，Close时返回false
+func (a *SocketAsyncer) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-a.ctx.Done():
+		return false
+	}
+}
+
+// track 记录watch中的连接，已Close时返回false
+func (a *SocketAsyncer) track(conn net.Conn) bool {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.ctx.Err() != nil {
+		return false
+	}
+	a.watchConns[conn] = struct{}{}
+	return true
+}
+
+func (a *SocketAsyncer) untrack(conn net.Conn) {
+	a.Lock()
+	delete(a.watchConns, conn)
+	a.Unlock()
+}
+
+// Close 停止所有的watch并关闭其连接
+func (a *SocketAsyncer) Close() error {
+	a.Lock()
+	defer a.Unlock()
+
+	a.cancel()
+	for conn := range a.watchConns {
+		conn.Close()
+	}
+	return nil
+}
+
+// watchOnce reconnect 是否为断开后的重连；服务端不支持watch时在该连接上轮询，
+// 重连时重新协商，滚动升级后的服务端恢复为推送
+func (a *SocketAsyncer) watchOnce(key string, ch chan struct{}, reconnect bool) error {
+	conn, err := a.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if !a.track(conn) {
+		return nil
+	}
+	defer a.untrack(conn)
+
+	r := bufio.NewReader(conn)
+	_, ops, err := a.hello(conn, r)
+	if err != nil {
+		return err
+	}
+
+	var resp *SocketResponse
+	streaming := containsString(ops, SocketOpWatch)
+	if streaming {
+		resp, err = a.request(conn, r, SocketOpWatch, key)
+		if isUnsupportedOp(err) {
+			streaming = false
+		} else if err != nil {
+			return err
+		}
+	}
+	if !streaming {
+		logger.Warnf("config socket %s does not support watch, poll conf[%s] every %v", a.path, key, a.pollInterval)
+		if resp, err = a.request(conn, r, SocketOpGet, key); err != nil {
+			return err
+		}
+	}
+	// 重连期间可能有变化，服务端确认未变化时不通知
+	if !resp.NotModified {
+		a.notify(ch)
+	}
+	if reconnect {
+		emitBackendEvent(EventWatchReconnected, a, key)
+	}
+
+	if !streaming {
+		return a.poll(conn, r, key, ch, resp.Value)
+	}
+	for {
+		var resp SocketResponse
+		if err := readFrame(r, &resp); err != nil {
+			return err
+		}
+		if err := a.resolve(key, &resp); err != nil {
+			return err
+		}
+		a.notify(ch)
+	}
+}
+
+// poll 每 SocketPollInterval 读取一次，值变化时通知
+func (a *SocketAsyncer) poll(conn net.Conn, r *bufio.Reader, key string, ch chan struct{}, last []byte) error {
+	for a.sleep(a.pollInterval) {
+		resp, err := a.request(conn, r, SocketOpGet, key)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(resp.Value, last) {
+			last = resp.Value
+			a.notify(ch)
+		}
+	}
+	return nil
+}
+
+func (a *SocketAsyncer) notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTimeout 后端调用超时
+var ErrTimeout = errors.New("backend timeout")
+
+// DefaultTimeouts 未指定 WithTimeouts 时异步配置使用的超时时间，零值不限制
+var DefaultTimeouts Timeouts
+
+// Timeouts 后端各操作的超时时间，<= 0 不限制
+type Timeouts struct {
+	Get   time.Duration // 超时后本次刷新失败，保留旧值
+	Set   time.Duration // 超时后返回 ErrTimeout，写入可能仍会完成
+	Watch time.Duration // 建立监听超时后不再监听，按缓存时间刷新
+}
+
+func (t Timeouts) enabled() bool {
+	return t.Get > 0 || t.Set > 0 || t.Watch > 0
+}
+
+// TimeoutAsyncer 限制后端调用的时长，不依赖asyncer自身对超时的处理
+//
+// 超时的调用在后台继续执行直到asyncer返回
+type TimeoutAsyncer struct {
+	asyncer  Asyncer
+	timeouts Timeouts
+}
+
+func NewTimeoutAsyncer(asyncer Asyncer, timeouts Timeouts) *TimeoutAsyncer {
+	return &TimeoutAsyncer{
+		asyncer:  asyncer,
+		timeouts: timeouts,
+	}
+}
+
+// timeoutAsyncerKey 合并读取时的标识，相同后端及超时时间的包装视为同一个后端
+type timeoutAsyncerKey struct {
+	asyncer  Asyncer
+	timeouts Timeouts
+}
+
+func (a *TimeoutAsyncer) coalesceKey() interface{} {
+	return timeoutAsyncerKey{asyncer: a.asyncer, timeouts: a.timeouts}
+}
+
+// WithTimeouts 后端调用的超时时间，替代 DefaultTimeouts
+func WithTimeouts(timeouts Timeouts) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.timeouts = &timeouts
+	}
+}
+
+// withTimeout 在timeout内执行fn，超时返回false，此时fn的结果不可读取
+func withTimeout(timeout time.Duration, fn func()) bool {
+	if timeout <= 0 {
+		fn()
+		return true
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (a *TimeoutAsyncer) ContentType(key string) ContentType {
+	return a.asyncer.ContentType(key)
+}
+
+func (a *TimeoutAsyncer) Get(key string) []byte {
+	var ret []byte
+	if !withTimeout(a.timeouts.Get, func() { ret = a.asyncer.Get(key) }) {
+		logger.Warnf("get conf[%s] timeout after %v", key, a.timeouts.Get)
+		return nil
+	}
+	return ret
+}
+
+func (a *TimeoutAsyncer) Set(key string, value []byte) error {
+	var err error
+	if !withTimeout(a.timeouts.Set, func() { err = a.asyncer.Set(key, value) }) {
+		return backendError(key, ErrTimeout)
+	}
+	return err
+}
+
+// SetVersioned 同 Set，asyncer未实现 VersionedSetter 时版本号为空
+func (a *TimeoutAsyncer) SetVersioned(key string, value []byte) (string, error) {
+	var (
+		version string
+		err     error
+	)
+	if !withTimeout(a.timeouts.Set, func() { version, err = setVersioned(a.asyncer, key, value) }) {
+		return "", backendError(key, ErrTimeout)
+	}
+	return version, err
+}
+
+// CompareAndSet 使用 Timeouts.Set，asyncer未实现 CASSetter 时返回错误
+func (a *TimeoutAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	var (
+		ok  bool
+		err error
+	)
+	if !withTimeout(a.timeouts.Set, func() { ok, err = compareAndSet(a.asyncer, key, old, value) }) {
+		return false, backendError(key, ErrTimeout)
+	}
+	return ok, err
+}
+
+// SetWithTTL 使用 Timeouts.Set，asyncer未实现 TTLSetter 时返回错误
+func (a *TimeoutAsyncer) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	var err error
+	if !withTimeout(a.timeouts.Set, func() { err = setWithTTL(a.asyncer, key, value, ttl) }) {
+		return backendError(key, ErrTimeout)
+	}
+	return err
+}
+
+// BatchGet 同 BatchGet，所有key共用 Timeouts.Get
+func (a *TimeoutAsyncer) BatchGet(keys []string) (map[string][]byte, error) {
+	var (
+		kvs map[string][]byte
+		err error
+	)
+	if !withTimeout(a.timeouts.Get, func() { kvs, err = BatchGet(a.asyncer, keys) }) {
+		return nil, backendError(strings.Join(keys, ","), ErrTimeout)
+	}
+	return kvs, err
+}
+
+func (a *TimeoutAsyncer) ContentTypeHint(key string) (ContentType, bool) {
+	if h, ok := a.asyncer.(ContentTypeHinter); ok {
+		return h.ContentTypeHint(key)
+	}
+	return T_JSON, false
+}
+
+func (a *TimeoutAsyncer) Watch(key string) chan struct{} {
+	var ch chan struct{}
+	if !withTimeout(a.timeouts.Watch, func() { ch = a.asyncer.Watch(key) }) {
+		logger.Warnf("watch conf[%s] timeout after %v", key, a.timeouts.Watch)
+		return nil
+	}
+	return ch
+}
+
+// GetReader 在 Timeouts.Get 内返回reader，之后的读取不限时，超时后返回的reader会被关闭
+func (a *TimeoutAsyncer) GetReader(key string) (io.ReadCloser, error) {
+	if a.timeouts.Get <= 0 {
+		return getReader(a.asyncer, key)
+	}
+
+	type result struct {
+		rc  io.ReadCloser
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		rc, err := getReader(a.asyncer, key)
+		done <- result{rc: rc, err: err}
+	}()
+
+	timer := time.NewTimer(a.timeouts.Get)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.rc, r.err
+	case <-timer.C:
+		go func() {
+			if r := <-done; r.rc != nil {
+				r.rc.Close()
+			}
+		}()
+		return nil, backendError(key, ErrTimeout)
+	}
+}
+
+// List 同 ListKeys，使用 Timeouts.Get
+func (a *TimeoutAsyncer) List(prefix string) ([]string, error) {
+	var (
+		keys []string
+		err  error
+	)
+	if !withTimeout(a.timeouts.Get, func() { keys, err = ListKeys(a.asyncer, prefix) }) {
+		return nil, backendError(prefix, ErrTimeout)
+	}
+	return keys, err
+}
+
+// ListPrefix 使用 Timeouts.Get
+func (a *TimeoutAsyncer) ListPrefix(prefix string) (map[string][]byte, error) {
+	var (
+		kvs map[string][]byte
+		err error
+	)
+	if !withTimeout(a.timeouts.Get, func() { kvs, err = listPrefix(a.asyncer, prefix) }) {
+		return nil, backendError(prefix, ErrTimeout)
+	}
+	return kvs, err
+}
+
+func (a *TimeoutAsyncer) Capabilities() Capabilities {
+	return wrapperCapabilities(a.asyncer)
+}
+
+func (a *TimeoutAsyncer) unwrap() Asyncer {
+	return a.asyncer
+}
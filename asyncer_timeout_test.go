@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type hangingAsyncer struct {
+	*MockAsyncer
+	hang   chan struct{}
+	closed chan struct{}
+}
+
+func (a hangingAsyncer) Get(key string) []byte {
+	<-a.hang
+	return a.MockAsyncer.Get(key)
+}
+
+func (a hangingAsyncer) Set(key string, value []byte) error {
+	<-a.hang
+	return a.MockAsyncer.Set(key, value)
+}
+
+func (a hangingAsyncer) Watch(key string) chan struct{} {
+	<-a.hang
+	return a.MockAsyncer.Watch(key)
+}
+
+// GetReader 在hang关闭后返回reader
+func (a hangingAsyncer) GetReader(key string) (io.ReadCloser, error) {
+	<-a.hang
+	return &closeRecorder{Reader: bytes.NewReader(a.MockAsyncer.Get(key)), closed: a.closed}, nil
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (r *closeRecorder) Close() error {
+	close(r.closed)
+	return nil
+}
+
+func TestTimeoutAsyncerGetReader(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(false)
+	mock.Set("app.json", []byte(`{"a": 1}`))
+	hanging := hangingAsyncer{MockAsyncer: mock, hang: make(chan struct{}), closed: make(chan struct{})}
+	asyncer := NewTimeoutAsyncer(hanging, Timeouts{Get: 10 * time.Millisecond})
+
+	rc, err := asyncer.GetReader("app.json")
+	ast.Nil(rc)
+	ast.True(errors.Is(err, ErrTimeout))
+
+	// 超时后返回的reader被关闭
+	close(hanging.hang)
+	select {
+	case <-hanging.closed:
+	case <-time.After(time.Second):
+		t.Fatal("reader returned after timeout not closed")
+	}
+}
+
+func TestTimeoutAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(true)
+	mock.Set("app.json", []byte(`{"a": 1}`))
+	hanging := hangingAsyncer{MockAsyncer: mock, hang: make(chan struct{})}
+	defer close(hanging.hang)
+
+	timeouts := Timeouts{Get: 10 * time.Millisecond, Set: 10 * time.Millisecond, Watch: 10 * time.Millisecond}
+	asyncer := NewTimeoutAsyncer(hanging, timeouts)
+
+	start := time.Now()
+	ast.Nil(asyncer.Get("app.json"))
+	ast.Nil(asyncer.Watch("app.json"))
+	err := asyncer.Set("app.json", []byte(`{}`))
+	ast.True(errors.Is(err, ErrTimeout))
+	ast.True(errors.Is(err, ErrBackendUnavailable))
+	ast.True(time.Since(start) < time.Second)
+
+	// 未超时
+	asyncer = NewTimeoutAsyncer(mock, timeouts)
+	ast.NotNil(asyncer.Get("app.json"))
+	ast.NotNil(asyncer.Watch("app.json"))
+	ast.Nil(asyncer.Set("other.json", []byte(`{}`)))
+	ast.True(asyncer.Capabilities().Watch)
+
+	// 同步刷新不会被阻塞
+	cfg := NewAsyncConfig(hanging, "app.json", time.Nanosecond, false, WithTimeouts(timeouts))
+	defer cfg.Close()
+	ast.Nil(cfg.Get("a"))
+	ast.False(cfg.Status().Loaded)
+
+	// 相同后端及超时时间合并读取
+	ast.Equal(coalesceKey(NewTimeoutAsyncer(mock, timeouts)), coalesceKey(NewTimeoutAsyncer(mock, timeouts)))
+	ast.NotEqual(coalesceKey(NewTimeoutAsyncer(mock, timeouts)), coalesceKey(NewTimeoutAsyncer(mock, Timeouts{Get: time.Second})))
+
+	origin := DefaultTimeouts
+	defer func() {
+		DefaultTimeouts = origin
+	}()
+	DefaultTimeouts = timeouts
+	cfg = NewAsyncConfig(hanging, "app.json", 0, false)
+	defer cfg.Close()
+	ast.Nil(cfg.Get("a"))
+}
@@ -0,0 +1,344 @@
+package config
+
+import (
+	"crypto/tls"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// AsyncerFactory 根据URL创建后端，返回后端及asyncKey；u的query中已移除公共参数，见 NewAsyncConfigFromURL
+type AsyncerFactory func(u *url.URL) (asyncer Asyncer, asyncKey string, err error)
+
+var _asyncerFactories sync.Map // scheme => AsyncerFactory
+
+func init() {
+	RegisterAsyncerFactory("consul", consulFromURL)
+	RegisterAsyncerFactory("vault", vaultFromURL)
+	RegisterAsyncerFactory("nacos", nacosFromURL)
+	RegisterAsyncerFactory("apollo", apolloFromURL)
+	RegisterAsyncerFactory("http", httpFromURL)
+	RegisterAsyncerFactory("https", httpFromURL)
+	RegisterAsyncerFactory("redis", redisFromURL)
+	RegisterAsyncerFactory("rediss", redisFromURL)
+	RegisterAsyncerFactory("file", fileFromURL)
+}
+
+// RegisterAsyncerFactory 注册scheme对应的后端，"consul+https" 等带 "+" 的scheme使用 "+" 之前部分注册的factory
+func RegisterAsyncerFactory(scheme string, f AsyncerFactory) {
+	_asyncerFactories.Store(strings.ToLower(scheme), f)
+}
+
+// NewAsyncConfigFromURL 根据URL创建 AsyncConfig，后端由部署时的配置决定，如
+//
+//	consul://127.0.0.1:8500/billing/app.json?dc=dc1&token=xxx&cacheTime=30s
+//	consul+https://consul:8501/billing/app.json?tlsCA=/etc/ca.pem
+//	vault+https://vault:8200/billing/db?token=xxx&mount=secret
+//	nacos://127.0.0.1:8848/nacos/app.yaml?namespace=dev&group=billing
+//	apollo://apollo-config:8080/application?appId=billing&cluster=default
+//	https://cdn.example.com/config/app.json
+//	redis://:password@127.0.0.1:6379/app.json?db=0&channel=config.changed
+//	file:///etc/billing/app.json
+//
+// 公共参数，从URL中移除后交给factory：
+//   - cacheTime: 缓存时间，如 30s，默认不过期
+//   - refreshAsync: 缓存过期时异步刷新
+//   - autoDetect: 见 WithAutoDetect
+//   - frozen: 见 WithFrozen
+//
+// 使用 http 的后端支持的连接参数：proxy、dialTimeout、tlsCA、tlsCert、tlsKey、tlsServerName、tlsInsecure，见 BackendOptions
+//
+// 没有host且scheme为 RegisterAsyner 注册的名称时使用注册的后端，如 "redis:app.json"、"redis:///app.json"，
+// 未指定时使用注册的CacheTime及RefreshAsync；factory创建的后端在配置Close时关闭
+//
+// opts 在URL中的选项之后应用
+func NewAsyncConfigFromURL(rawURL string, opts ...AsyncOption) (*AsyncConfig, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse config url")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	query := u.Query()
+	var (
+		cacheTime    time.Duration
+		refreshAsync bool
+		urlOpts      []AsyncOption
+		set          = make(map[string]bool)
+	)
+	for _, name := range []string{"cacheTime", "refreshAsync", "autoDetect", "frozen"} {
+		if _, ok := query[name]; !ok {
+			continue
+		}
+		value := query.Get(name)
+		query.Del(name)
+		set[name] = true
+
+		var err error
+		switch name {
+		case "cacheTime":
+			cacheTime, err = time.ParseDuration(value)
+		case "refreshAsync":
+			refreshAsync, err = parseURLBool(value)
+		case "autoDetect":
+			var enabled bool
+			if enabled, err = parseURLBool(value); enabled {
+				urlOpts = append(urlOpts, WithAutoDetect())
+			}
+		case "frozen":
+			var enabled bool
+			if enabled, err = parseURLBool(value); enabled {
+				urlOpts = append(urlOpts, WithFrozen())
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "config url parameter %s", name)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	scheme := u.Scheme
+	if args := GetAsyncer(scheme); args != nil && u.Host == "" {
+		if !set["cacheTime"] {
+			cacheTime = args.CacheTime
+		}
+		if !set["refreshAsync"] {
+			refreshAsync = args.RefreshAsync
+		}
+		return NewAsyncConfig(args.Ins, registeredKey(u), cacheTime, refreshAsync, append(urlOpts, opts...)...), nil
+	}
+
+	f, ok := _asyncerFactories.Load(scheme)
+	if !ok {
+		f, ok = _asyncerFactories.Load(strings.SplitN(scheme, "+", 2)[0])
+	}
+	if !ok {
+		return nil, errors.Errorf("unsupported config url scheme[%s]", scheme)
+	}
+
+	asyncer, asyncKey, err := f.(AsyncerFactory)(u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "config url scheme[%s]", scheme)
+	}
+	if closer, ok := asyncer.(io.Closer); ok {
+		urlOpts = append(urlOpts, func(cfg *asyncConfig) {
+			cfg.owned = closer
+		})
+	}
+	return NewAsyncConfig(asyncer, asyncKey, cacheTime, refreshAsync, append(urlOpts, opts...)...), nil
+}
+
+// registeredKey "source:key" 及 "source:///key" 中的key
+func registeredKey(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// parseURLBool 参数只有名称（如 "?frozen"）时为true
+func parseURLBool(value string) (bool, error) {
+	if value == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// urlKey path去掉开头的 "/"
+func urlKey(u *url.URL) (string, error) {
+	key := strings.TrimPrefix(u.Path, "/")
+	if key == "" {
+		return "", errors.New("missing config key in path")
+	}
+	return key, nil
+}
+
+// urlHTTPAddr "xxx+https" 使用https，否则使用http
+func urlHTTPAddr(u *url.URL) string {
+	scheme := "http"
+	if strings.HasSuffix(u.Scheme, "+https") {
+		scheme = "https"
+	}
+	return scheme + "://" + u.Host
+}
+
+// urlBackendOptions query中的连接参数，解析后从query中移除
+func urlBackendOptions(query url.Values) ([]BackendOption, error) {
+	defer func() {
+		for _, name := range []string{"proxy", "dialTimeout", "tlsCA", "tlsCert", "tlsKey", "tlsServerName", "tlsInsecure"} {
+			query.Del(name)
+		}
+	}()
+
+	var opts []BackendOption
+	if proxy := query.Get("proxy"); proxy != "" {
+		opts = append(opts, WithProxy(proxy))
+	}
+	if v := query.Get("dialTimeout"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "dialTimeout")
+		}
+		opts = append(opts, WithDialTimeout(timeout))
+	}
+
+	tlsOptions := &TLSOptions{
+		CAFile:     query.Get("tlsCA"),
+		CertFile:   query.Get("tlsCert"),
+		KeyFile:    query.Get("tlsKey"),
+		ServerName: query.Get("tlsServerName"),
+	}
+	if v := query.Get("tlsInsecure"); v != "" {
+		insecure, err := parseURLBool(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "tlsInsecure")
+		}
+		tlsOptions.InsecureSkipVerify = insecure
+	}
+	if *tlsOptions != (TLSOptions{}) {
+		opts = append(opts, WithTLS(tlsOptions))
+	}
+	return opts, nil
+}
+
+// consulFromURL consul[+https]://host:port/<key>?dc=&token=&prefix=
+func consulFromURL(u *url.URL) (Asyncer, string, error) {
+	key, err := urlKey(u)
+	if err != nil {
+		return nil, "", err
+	}
+	query := u.Query()
+	opts, err := urlBackendOptions(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return NewConsulAsyncer(urlHTTPAddr(u), &ConsulOptions{
+		Datacenter: query.Get("dc"),
+		Token:      query.Get("token"),
+		Prefix:     query.Get("prefix"),
+	}, opts...), key, nil
+}
+
+// vaultFromURL vault[+https]://host:port/<path>?token=&namespace=&mount=，动态secret的路径以 "//" 开头
+func vaultFromURL(u *url.URL) (Asyncer, string, error) {
+	key, err := urlKey(u)
+	if err != nil {
+		return nil, "", err
+	}
+	query := u.Query()
+	opts, err := urlBackendOptions(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return NewVaultAsyncer(urlHTTPAddr(u), &VaultOptions{
+		Token:     query.Get("token"),
+		Namespace: query.Get("namespace"),
+		Mount:     query.Get("mount"),
+	}, opts...), key, nil
+}
+
+// nacosFromURL nacos[+https]://host:port[/context]/<dataId>?namespace=&group=&username=&password=
+func nacosFromURL(u *url.URL) (Asyncer, string, error) {
+	dir, dataID := path.Split(u.Path)
+	if dataID == "" {
+		return nil, "", errors.New("missing dataId in path")
+	}
+	query := u.Query()
+	opts, err := urlBackendOptions(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return NewNacosAsyncer(urlHTTPAddr(u)+strings.TrimSuffix(dir, "/"), &NacosOptions{
+		Namespace: query.Get("namespace"),
+		Group:     query.Get("group"),
+		Username:  query.Get("username"),
+		Password:  query.Get("password"),
+	}, opts...), dataID, nil
+}
+
+// apolloFromURL apollo[+https]://host:port/<namespace>?appId=&cluster=&secret=&ip=
+func apolloFromURL(u *url.URL) (Asyncer, string, error) {
+	key, err := urlKey(u)
+	if err != nil {
+		return nil, "", err
+	}
+	query := u.Query()
+	if query.Get("appId") == "" {
+		return nil, "", errors.New("missing appId")
+	}
+	opts, err := urlBackendOptions(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return NewApolloAsyncer(urlHTTPAddr(u), &ApolloOptions{
+		AppID:   query.Get("appId"),
+		Cluster: query.Get("cluster"),
+		Secret:  query.Get("secret"),
+		IP:      query.Get("ip"),
+	}, opts...), key, nil
+}
+
+// httpFromURL 移除公共参数及连接参数后的URL即为key
+func httpFromURL(u *url.URL) (Asyncer, string, error) {
+	query := u.Query()
+	opts, err := urlBackendOptions(query)
+	if err != nil {
+		return nil, "", err
+	}
+	key := *u
+	key.RawQuery = query.Encode()
+	key.ForceQuery = false
+	return NewHTTPAsyncer("", opts...), key.String(), nil
+}
+
+// redisFromURL redis[s]://[user:password@]host:port/<key>?db=&channel=，channel为空时不监听变化
+func redisFromURL(u *url.URL) (Asyncer, string, error) {
+	key, err := urlKey(u)
+	if err != nil {
+		return nil, "", err
+	}
+	query := u.Query()
+
+	options := &redis.Options{Addr: u.Host}
+	if u.User != nil {
+		options.Username = u.User.Username()
+		options.Password, _ = u.User.Password()
+	}
+	if v := query.Get("db"); v != "" {
+		if options.DB, err = strconv.Atoi(v); err != nil {
+			return nil, "", errors.Wrap(err, "db")
+		}
+	}
+	if u.Scheme == "rediss" {
+		options.TLSConfig = &tls.Config{ServerName: u.Hostname()}
+	}
+	opts, err := urlBackendOptions(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return NewRedisAsyncer(options, query.Get("channel"), opts...), key, nil
+}
+
+// fileFromURL file:///abs/path 或 file:relative/path
+func fileFromURL(u *url.URL) (Asyncer, string, error) {
+	name := u.Opaque
+	if name == "" {
+		name = u.Path
+	}
+	if name == "" {
+		return nil, "", errors.New("missing file path")
+	}
+	return NewFileAsyncer(), name, nil
+}
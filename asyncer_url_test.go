@@ -0,0 +1,128 @@
+package config
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// closeAsyncer 记录Close次数
+type closeAsyncer struct {
+	*MockAsyncer
+	closed int32
+}
+
+func (a *closeAsyncer) Close() error {
+	atomic.AddInt32(&a.closed, 1)
+	return nil
+}
+
+func TestNewAsyncConfigFromURL(t *testing.T) {
+	ast := assert.New(t)
+
+	// consul
+	fake := newFakeConsul()
+	fake.data["config/app.json"] = consulKV{ModifyIndex: 1, Value: []byte(`{"a": 1}`)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	cfg, err := NewAsyncConfigFromURL("consul://" + host + "/app.json?dc=dc1&token=secret&prefix=config/&cacheTime=1m&refreshAsync")
+	ast.Nil(err)
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a"))
+	// 开始监听后cacheTime会被修改
+	ast.True(cfg.Configer.(*asyncConfig).refreshAsync)
+	fake.Lock()
+	ast.Equal("secret", fake.headers[0].Get("X-Consul-Token"))
+	ast.Contains(fake.queries[0], "dc=dc1")
+	fake.Unlock()
+
+	// http，公共参数及连接参数不随请求发送
+	store := &fakeHTTPStore{files: map[string][]byte{"/config/app.yaml": []byte("a: 2\n")}, versions: make(map[string]int)}
+	httpServer := httptest.NewServer(store)
+	defer httpServer.Close()
+	cfg, err = NewAsyncConfigFromURL(httpServer.URL + "/config/app.yaml?frozen&dialTimeout=1s")
+	ast.Nil(err)
+	defer cfg.Close()
+	ast.EqualValues(2, cfg.Int("a"))
+	ast.True(cfg.Configer.(*asyncConfig).frozen)
+	ast.Equal(httpServer.URL+"/config/app.yaml", cfg.Configer.(*asyncConfig).asyncKey)
+
+	// file
+	dir, err := ioutil.TempDir("", "config-url")
+	ast.Nil(err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.json")
+	ast.Nil(ioutil.WriteFile(name, []byte(`{"a": 3}`), 0644))
+	cfg, err = NewAsyncConfigFromURL("file://" + name)
+	ast.Nil(err)
+	ast.EqualValues(3, cfg.Int("a"))
+
+	// redis
+	rds, err := miniredis.Run()
+	ast.Nil(err)
+	defer rds.Close()
+	rds.Select(1)
+	rds.Set("app.json", `{"a": 4}`)
+	cfg, err = NewAsyncConfigFromURL("redis://" + rds.Addr() + "/app.json?db=1")
+	ast.Nil(err)
+	defer cfg.Close()
+	ast.EqualValues(4, cfg.Int("a"))
+
+	// 错误
+	for _, rawURL := range []string{
+		"unknown://host/app.json",
+		"consul://" + host + "/app.json?cacheTime=x",
+		"consul://" + host + "/",
+		"apollo://" + host + "/application",
+		"redis://" + rds.Addr() + "/app.json?db=x",
+		"consul://" + host + "/app.json?dialTimeout=x",
+	} {
+		_, err = NewAsyncConfigFromURL(rawURL)
+		ast.NotNil(err, rawURL)
+	}
+}
+
+func TestNewAsyncConfigFromURLRegistry(t *testing.T) {
+	ast := assert.New(t)
+
+	// RegisterAsyner 注册的后端
+	mock := NewMockAsyncer(false)
+	mock.Set("app.json", []byte(`{"a": 1}`))
+	RegisterAsyner("urltest", &AsyncerArgs{Ins: mock, CacheTime: time.Minute})
+	defer _asyncers.Delete("urltest")
+
+	for _, rawURL := range []string{"urltest:app.json", "urltest:///app.json?refreshAsync=true"} {
+		cfg, err := NewAsyncConfigFromURL(rawURL)
+		ast.Nil(err)
+		ast.EqualValues(1, cfg.Int("a"))
+		ast.EqualValues(time.Minute, cfg.Configer.(*asyncConfig).cacheTime)
+		ast.Nil(cfg.Close())
+	}
+
+	// factory创建的后端随配置关闭，"+" 之后的部分由factory处理
+	var created *closeAsyncer
+	RegisterAsyncerFactory("closetest", func(u *url.URL) (Asyncer, string, error) {
+		created = &closeAsyncer{MockAsyncer: NewMockAsyncer(false)}
+		created.Set(u.Path, []byte(`{"b": "`+u.Scheme+`"}`))
+		return created, u.Path, nil
+	})
+	defer _asyncerFactories.Delete("closetest")
+
+	cfg, err := NewAsyncConfigFromURL("CloseTest+tls://host/app.json")
+	ast.Nil(err)
+	ast.Equal("closetest+tls", cfg.String("b"))
+	ast.Nil(cfg.Close())
+	ast.Nil(cfg.Close())
+	ast.EqualValues(1, atomic.LoadInt32(&created.closed))
+}
@@ -0,0 +1,428 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// VaultPollInterval 检查KV v2版本号的间隔
+	VaultPollInterval = 30 * time.Second
+	// VaultRequestTimeout 请求vault的超时时间
+	VaultRequestTimeout = 10 * time.Second
+	// VaultRenewRatio 租约已过去该比例的时间后续期，与 VaultPollInterval 在创建时确定
+	VaultRenewRatio = 2.0 / 3
+)
+
+// VaultOptions vault的访问选项
+type VaultOptions struct {
+	Token     string // 通过 X-Vault-Token 头发送，也可使用 WithTokenSource
+	Namespace string // 企业版的namespace，通过 X-Vault-Namespace 头发送
+	Mount     string // KV v2的挂载路径，默认 "secret"
+}
+
+// VaultAsyncer 以vault为数据源，内容为secret的data组成的JSON对象
+//
+// key为KV v2中的路径（如 "billing/db"）；以 "/" 开头时为完整的API路径，
+// 用于数据库凭证等动态secret（如 "/database/creds/billing"）。
+//
+// Watch每隔 VaultPollInterval 检查KV v2的版本号，版本变化时通知；
+// 带租约的secret在租约过去 VaultRenewRatio 后续期，
+// 不可续期、续期失败或续期后的租约不足（达到max TTL）时通知重新读取以获取新的凭证
+type VaultAsyncer struct {
+	addr    string
+	options VaultOptions
+	client  *http.Client
+
+	pollInterval time.Duration
+	renewRatio   float64
+
+	versions     sync.Map // key => int，最近一次Get的KV v2版本号
+	leases       sync.Map // key => vaultLease，最近一次Get的租约
+	leaseChanged sync.Map // key => chan struct{}，Get获取新租约时通知watch
+	notifyChans  sync.Map // key => chan struct{}
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+var (
+	_ CASSetter       = (*VaultAsyncer)(nil)
+	_ VersionedSetter = (*VaultAsyncer)(nil)
+)
+
+// vaultLease 动态secret的租约
+type vaultLease struct {
+	id        string
+	duration  time.Duration
+	renewable bool
+	start     time.Time // 获取或续期的时间
+}
+
+// renewAt 需要续期的时间
+func (l vaultLease) renewAt(ratio float64) time.Time {
+	return l.start.Add(time.Duration(float64(l.duration) * ratio))
+}
+
+// NewVaultAsyncer addr 为vault的地址，如 "https://vault:8200"，options可为nil
+//
+// opts: TLS及代理等连接选项，见 BackendOptions
+func NewVaultAsyncer(addr string, options *VaultOptions, opts ...BackendOption) *VaultAsyncer {
+	a := &VaultAsyncer{
+		addr:         strings.TrimSuffix(addr, "/"),
+		client:       backendHTTPClient(opts),
+		pollInterval: VaultPollInterval,
+		renewRatio:   VaultRenewRatio,
+	}
+	if options != nil {
+		a.options = *options
+	}
+	if a.options.Mount == "" {
+		a.options.Mount = "secret"
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	logger.Infof("NewVaultAsyncer:addr=%s,mount=%s", a.addr, a.options.Mount)
+
+	return a
+}
+
+// ContentType 总是JSON
+func (a *VaultAsyncer) ContentType(key string) ContentType {
+	return T_JSON
+}
+
+// kv key为KV v2中的路径
+func (a *VaultAsyncer) kv(key string) bool {
+	return !strings.HasPrefix(key, "/")
+}
+
+// path 返回key在KV v2中kind（data、metadata）下的API路径，完整路径时原样返回
+func (a *VaultAsyncer) path(key, kind string) string {
+	if !a.kv(key) {
+		return key
+	}
+	return "/" + a.options.Mount + "/" + kind + "/" + key
+}
+
+// vaultResponse vault API的响应
+type vaultResponse struct {
+	LeaseID       string          `json:"lease_id"`
+	LeaseDuration int             `json:"lease_duration"` // 秒
+	Renewable     bool            `json:"renewable"`
+	Data          json.RawMessage `json:"data"`
+	Errors        []string        `json:"errors"`
+}
+
+// vaultKVData KV v2读取时的data
+type vaultKVData struct {
+	Data     json.RawMessage `json:"data"`
+	Metadata struct {
+		Version int `json:"version"`
+	} `json:"metadata"`
+}
+
+// do 请求 /v1<path>，404时返回nil
+func (a *VaultAsyncer) do(method, path string, body interface{}) (*vaultResponse, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, VaultRequestTimeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/v1%s", a.addr, path), reader)
+	if err != nil {
+		return nil, err
+	}
+	if a.options.Token != "" {
+		req.Header.Set("X-Vault-Token", a.options.Token)
+	}
+	if a.options.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", a.options.Namespace)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	ret := &vaultResponse{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, ret); err != nil {
+			return nil, errors.Wrapf(err, "vault[%s]: status %d", path, resp.StatusCode)
+		}
+	}
+	if resp.StatusCode >= 300 {
+		return nil, errors.Errorf("vault[%s]: status %d: %s", path, resp.StatusCode, strings.Join(ret.Errors, "; "))
+	}
+	return ret, nil
+}
+
+// read 返回secret的data及KV v2版本号，不存在时data为nil
+func (a *VaultAsyncer) read(key string) ([]byte, int, *vaultResponse, error) {
+	resp, err := a.do(http.MethodGet, a.path(key, "data"), nil)
+	if err != nil || resp == nil {
+		return nil, 0, nil, err
+	}
+	if !a.kv(key) {
+		return resp.Data, 0, resp, nil
+	}
+
+	var kv vaultKVData
+	if err := json.Unmarshal(resp.Data, &kv); err != nil {
+		return nil, 0, nil, errors.Wrapf(err, "vault kv[%s]", key)
+	}
+	// 已删除的版本data为null
+	if string(kv.Data) == "null" {
+		kv.Data = nil
+	}
+	return kv.Data, kv.Metadata.Version, resp, nil
+}
+
+func (a *VaultAsyncer) Get(key string) []byte {
+	data, version, resp, err := a.read(key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from vault err:%v", key, err)
+		return nil
+	}
+
+	a.versions.Store(key, version)
+	if resp != nil && resp.LeaseID != "" {
+		a.leases.Store(key, vaultLease{
+			id:        resp.LeaseID,
+			duration:  time.Duration(resp.LeaseDuration) * time.Second,
+			renewable: resp.Renewable,
+			start:     _now(),
+		})
+		if ch, ok := a.leaseChanged.Load(key); ok {
+			a.notify(ch.(chan struct{}))
+		}
+	} else {
+		a.leases.Delete(key)
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+func (a *VaultAsyncer) Set(key string, value []byte) error {
+	_, err := a.SetVersioned(key, value)
+	return err
+}
+
+// write KV v2写入，cas为nil时不检查版本，返回写入后的版本号
+func (a *VaultAsyncer) write(key string, value []byte, cas *int) (int, error) {
+	if !a.kv(key) {
+		_, err := a.do(http.MethodPut, key, json.RawMessage(value))
+		return 0, errors.Wrapf(err, "write vault[%s]", key)
+	}
+
+	body := map[string]interface{}{"data": json.RawMessage(value)}
+	if cas != nil {
+		body["options"] = map[string]int{"cas": *cas}
+	}
+	resp, err := a.do(http.MethodPost, a.path(key, "data"), body)
+	if err != nil {
+		return 0, errors.Wrapf(err, "write vault kv[%s]", key)
+	}
+
+	var meta struct {
+		Version int `json:"version"`
+	}
+	if resp != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, &meta); err != nil {
+			return 0, errors.Wrapf(err, "write vault kv[%s]", key)
+		}
+	}
+	return meta.Version, nil
+}
+
+// SetVersioned value需为JSON对象，返回写入后的KV v2版本号；完整路径时原样写入，版本号为空
+func (a *VaultAsyncer) SetVersioned(key string, value []byte) (string, error) {
+	if !json.Valid(value) {
+		return "", errors.Errorf("vault[%s]: value is not valid JSON", key)
+	}
+	version, err := a.write(key, value, nil)
+	if err != nil || !a.kv(key) {
+		return "", err
+	}
+	return strconv.Itoa(version), nil
+}
+
+// CompareAndSet 使用KV v2的check-and-set（options.cas），old为nil时要求secret不存在
+func (a *VaultAsyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	if !a.kv(key) {
+		return false, errors.Errorf("vault[%s]: CompareAndSet requires a KV v2 path", key)
+	}
+
+	data, version, _, err := a.read(key)
+	if err != nil {
+		return false, err
+	}
+	if data != nil {
+		if old == nil || !bytes.Equal(data, old) {
+			return false, nil
+		}
+	} else if old != nil {
+		return false, nil
+	}
+
+	if _, err := a.write(key, value, &version); err != nil {
+		// 版本不一致时vault返回400
+		if strings.Contains(err.Error(), "check-and-set") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Watch 见 VaultAsyncer
+func (a *VaultAsyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	changed := make(chan struct{}, 1)
+	a.leaseChanged.Store(key, changed)
+	go a.watch(key, ch, changed)
+
+	return ch
+}
+
+func (a *VaultAsyncer) watch(key string, ch, leaseChanged chan struct{}) {
+	for {
+		wait := a.pollInterval
+		lease, hasLease := a.lease(key)
+		if hasLease {
+			if d := lease.renewAt(a.renewRatio).Sub(_now()); d < wait {
+				wait = d
+			}
+		}
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-leaseChanged:
+			continue
+		case <-time.After(wait):
+		}
+
+		if hasLease && !_now().Before(lease.renewAt(a.renewRatio)) {
+			if !a.renew(key, lease) {
+				a.notify(ch)
+			}
+			continue
+		}
+		if a.kv(key) && a.versionChanged(key) {
+			a.notify(ch)
+		}
+	}
+}
+
+func (a *VaultAsyncer) lease(key string) (vaultLease, bool) {
+	v, ok := a.leases.Load(key)
+	if !ok {
+		return vaultLease{}, false
+	}
+	return v.(vaultLease), true
+}
+
+// renew 续期租约，返回续期后的租约是否足够（不需要重新读取）
+func (a *VaultAsyncer) renew(key string, lease vaultLease) bool {
+	// 避免通知后重新读取前重复续期
+	a.leases.Delete(key)
+	if !lease.renewable {
+		return false
+	}
+
+	increment := int(lease.duration / time.Second)
+	resp, err := a.do(http.MethodPut, "/sys/leases/renew", map[string]interface{}{
+		"lease_id":  lease.id,
+		"increment": increment,
+	})
+	if err != nil || resp == nil {
+		logger.Warnf("renew vault lease of conf[%s] err:%v", key, err)
+		return false
+	}
+
+	// 达到max TTL时续期后的租约短于请求的时间
+	if resp.LeaseDuration < increment {
+		logger.Infof("vault lease of conf[%s] reaches max ttl, reload", key)
+		return false
+	}
+	a.leases.Store(key, vaultLease{
+		id:        lease.id,
+		duration:  time.Duration(resp.LeaseDuration) * time.Second,
+		renewable: resp.Renewable,
+		start:     _now(),
+	})
+	return true
+}
+
+// versionChanged KV v2的当前版本与最近一次Get不同
+func (a *VaultAsyncer) versionChanged(key string) bool {
+	resp, err := a.do(http.MethodGet, a.path(key, "metadata"), nil)
+	if err != nil {
+		logger.Warnf("watch conf[%s] from vault err:%v", key, err)
+		return false
+	}
+
+	var meta struct {
+		CurrentVersion int `json:"current_version"`
+	}
+	if resp != nil {
+		if err := json.Unmarshal(resp.Data, &meta); err != nil {
+			logger.Warnf("watch conf[%s] from vault err:%v", key, err)
+			return false
+		}
+	}
+
+	last, loaded := a.versions.LoadOrStore(key, meta.CurrentVersion)
+	return loaded && last.(int) != meta.CurrentVersion
+}
+
+func (a *VaultAsyncer) notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Capabilities 见 CapabilityReporter
+func (a *VaultAsyncer) Capabilities() Capabilities {
+	return Capabilities{Watch: true, CAS: true, Version: true}
+}
+
+// Close 停止所有Watch
+func (a *VaultAsyncer) Close() error {
+	a.cancel()
+	return nil
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVault 内存中的KV v2及一个动态secret
+type fakeVault struct {
+	sync.Mutex
+	kv       map[string][]json.RawMessage // path => 各版本的data
+	headers  []http.Header
+	leaseTTL int // 动态secret的租约秒数
+	renewals int
+	issued   int // 签发的凭证数
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{kv: make(map[string][]json.RawMessage), leaseTTL: 1}
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.Lock()
+	defer v.Unlock()
+	v.headers = append(v.headers, r.Header.Clone())
+
+	reply := func(resp interface{}) {
+		json.NewEncoder(w).Encode(resp)
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	switch {
+	case strings.HasPrefix(path, "/secret/data/"):
+		key := strings.TrimPrefix(path, "/secret/data/")
+		versions := v.kv[key]
+		if r.Method == http.MethodGet {
+			if len(versions) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				reply(map[string]interface{}{"errors": []string{}})
+				return
+			}
+			reply(map[string]interface{}{"data": map[string]interface{}{
+				"data":     versions[len(versions)-1],
+				"metadata": map[string]int{"version": len(versions)},
+			}})
+			return
+		}
+
+		var body struct {
+			Data    json.RawMessage
+			Options struct {
+				CAS *int `json:"cas"`
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Options.CAS != nil && *body.Options.CAS != len(versions) {
+			w.WriteHeader(http.StatusBadRequest)
+			reply(map[string]interface{}{"errors": []string{"check-and-set parameter did not match the current version"}})
+			return
+		}
+		v.kv[key] = append(versions, body.Data)
+		reply(map[string]interface{}{"data": map[string]int{"version": len(v.kv[key])}})
+	case strings.HasPrefix(path, "/secret/metadata/"):
+		key := strings.TrimPrefix(path, "/secret/metadata/")
+		reply(map[string]interface{}{"data": map[string]int{"current_version": len(v.kv[key])}})
+	case path == "/database/creds/app":
+		v.issued++
+		reply(map[string]interface{}{
+			"lease_id":       "database/creds/app/" + string(rune('0'+v.issued)),
+			"lease_duration": v.leaseTTL,
+			"renewable":      true,
+			"data":           map[string]interface{}{"username": "u" + string(rune('0'+v.issued))},
+		})
+	case path == "/sys/leases/renew":
+		// 第一个凭证已达到max TTL，无法延长
+		var body struct {
+			LeaseID string `json:"lease_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		v.renewals++
+		duration := 3600
+		if strings.HasSuffix(body.LeaseID, "/1") {
+			duration = 0
+		}
+		reply(map[string]interface{}{"lease_duration": duration, "renewable": true})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestVaultAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	VaultPollInterval = 20 * time.Millisecond
+	defer func() { VaultPollInterval = 30 * time.Second }()
+
+	fake := newFakeVault()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := NewVaultAsyncer(server.URL, &VaultOptions{Token: "root", Namespace: "team"})
+	defer a.Close()
+
+	ast.Nil(a.Get("billing/db"))
+	version, err := a.SetVersioned("billing/db", []byte(`{"password": "p1"}`))
+	ast.Nil(err)
+	ast.Equal("1", version)
+	ast.JSONEq(`{"password": "p1"}`, string(a.Get("billing/db")))
+	ast.NotNil(a.Set("billing/db", []byte("not json")))
+	ast.Equal(T_JSON, a.ContentType("billing/db"))
+	ast.Equal(Capabilities{Watch: true, CAS: true, Version: true}, ProbeCapabilities(a))
+
+	fake.Lock()
+	ast.Equal("root", fake.headers[0].Get("X-Vault-Token"))
+	ast.Equal("team", fake.headers[0].Get("X-Vault-Namespace"))
+	fake.Unlock()
+
+	ok, err := a.CompareAndSet("billing/db", []byte(`{"password":"p0"}`), []byte(`{"password": "p2"}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("billing/db", a.Get("billing/db"), []byte(`{"password": "p2"}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("billing/new", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("billing/new", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.False(ok)
+
+	// 版本变化时通知
+	cfg := NewAsyncConfig(a, "billing/db", time.Hour, false)
+	ast.Equal("p2", cfg.String("password"))
+	ast.Nil(a.Set("billing/db", []byte(`{"password": "p3"}`)))
+	ast.Eventually(func() bool {
+		return cfg.String("password") == "p3"
+	}, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestVaultLease(t *testing.T) {
+	ast := assert.New(t)
+
+	VaultRenewRatio = 0.05
+	defer func() { VaultRenewRatio = 2.0 / 3 }()
+
+	fake := newFakeVault()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := NewVaultAsyncer(server.URL, nil)
+	defer a.Close()
+
+	// 续期后租约不足时重新读取获取新凭证
+	cfg := NewAsyncConfig(a, "/database/creds/app", time.Hour, false)
+	ast.Equal("u1", cfg.String("username"))
+	ast.Eventually(func() bool {
+		return cfg.String("username") == "u2"
+	}, 2*time.Second, 5*time.Millisecond)
+	fake.Lock()
+	ast.Equal(1, fake.renewals)
+	fake.Unlock()
+
+	// 续期成功时不重新读取
+	ast.Eventually(func() bool {
+		fake.Lock()
+		defer fake.Unlock()
+		return fake.renewals == 2
+	}, 2*time.Second, 5*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	fake.Lock()
+	ast.Equal(2, fake.renewals)
+	ast.Equal(2, fake.issued)
+	fake.Unlock()
+	ast.Equal("u2", cfg.String("username"))
+}
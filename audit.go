@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// AuditEvent 敏感配置的访问记录
+type AuditEvent struct {
+	Source       string    // 配置来源（asyncKey）
+	KeyPath      string    // 读取的路径
+	SensitiveKey string    // 命中的敏感配置路径
+	Caller       string    // 调用方，格式为 "function file:line"
+	Time         time.Time // 访问时间
+}
+
+// secretAudit 敏感配置访问审计
+type secretAudit struct {
+	keyPaths []string
+	handler  func(AuditEvent)
+}
+
+// WithSecretAudit 读取敏感配置时调用handler记录访问
+//
+// 读取敏感配置本身、其子节点或任一父节点（包括RootKey）都会产生访问记录
+//
+//	cfg := config.NewAsyncConfig(asyncer, key, cacheTime, false,
+//		config.WithSecretAudit(func(e config.AuditEvent) {
+//			auditLogger.Infow("secret accessed", "key", e.SensitiveKey, "caller", e.Caller)
+//		}, "db.password", "api.keys"),
+//	)
+func WithSecretAudit(handler func(AuditEvent), keyPaths ...string) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.audit = &secretAudit{
+			keyPaths: keyPaths,
+			handler:  handler,
+		}
+	}
+}
+
+func (a *secretAudit) record(source string, keyPath string) {
+	var caller string
+	for _, sensitive := range a.keyPaths {
+		if !keyPathOverlaps(keyPath, sensitive) {
+			continue
+		}
+		if caller == "" {
+			caller = externalCaller()
+		}
+		a.handler(AuditEvent{
+			Source:       source,
+			KeyPath:      keyPath,
+			SensitiveKey: sensitive,
+			Caller:       caller,
+			Time:         _now(),
+		})
+	}
+}
+
+// keyPathOverlaps a是b本身、父节点或子节点
+func keyPathOverlaps(a string, b string) bool {
+	if a == RootKey || b == RootKey || a == b {
+		return true
+	}
+
+	return strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
+// externalCaller 返回本package之外最近的调用方
+func externalCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	pkgPrefix := packagePath() + "."
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, pkgPrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// packagePath 本package的导入路径
+func packagePath() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name() // github.com/kot-w/config.packagePath
+	return name[:strings.LastIndex(name, ".")]
+}
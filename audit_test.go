@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretAudit(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(false)
+	key := "audit_key"
+	mock.Set(key, []byte(`{"db": {"host": "example.com", "password": "secret"}}`))
+
+	events := make([]AuditEvent, 0)
+	cfg := NewAsyncConfig(mock, key, time.Hour, false, WithSecretAudit(func(e AuditEvent) {
+		events = append(events, e)
+	}, "db.password"))
+
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Len(events, 0)
+
+	ast.Equal("secret", cfg.String("db.password"))
+	ast.Len(events, 1)
+	ast.Equal(key, events[0].Source)
+	ast.Equal("db.password", events[0].KeyPath)
+	ast.Equal("db.password", events[0].SensitiveKey)
+	ast.True(strings.Contains(events[0].Caller, "TestSecretAudit"), events[0].Caller)
+	ast.False(events[0].Time.IsZero())
+
+	// parent nodes
+	cfg.Map("db")
+	cfg.Get(RootKey)
+	ast.Len(events, 3)
+	ast.Equal("db", events[1].KeyPath)
+	ast.Equal(RootKey, events[2].KeyPath)
+
+	ast.True(keyPathOverlaps("db.password.v1", "db.password"))
+	ast.False(keyPathOverlaps("db.pass", "db.password"))
+}
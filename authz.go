@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Authorizer 检查的操作
+const (
+	ActionRead  = "read"
+	ActionWrite = "write"
+)
+
+// Authorizer 访问控制，管理接口（见 NewAuthorizedAdminHandler）、unix socket服务（见 SocketServer.SetAuthorizer）
+// 及写入（见 NewAuthorizedConfig）时调用，返回nil表示允许
+//
+// subject为调用方的标识，由使用方从请求中获取；keyPath为访问的节点，整个配置树为 RootKey
+type Authorizer interface {
+	Authorize(subject, action, keyPath string) error
+}
+
+// AuthorizerFunc 函数形式的 Authorizer
+type AuthorizerFunc func(subject, action, keyPath string) error
+
+func (f AuthorizerFunc) Authorize(subject, action, keyPath string) error {
+	return f(subject, action, keyPath)
+}
+
+// AuthzError Authorizer拒绝访问，可通过 errors.Is(err, ErrForbidden) 判断
+type AuthzError struct {
+	Subject string
+	Action  string
+	KeyPath string
+	Err     error // Authorizer返回的错误
+}
+
+func (e *AuthzError) Error() string {
+	return fmt.Sprintf("%s %s path[%s]: %v: %v", e.Subject, e.Action, e.KeyPath, ErrForbidden, e.Err)
+}
+
+func (e *AuthzError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+func (e *AuthzError) Unwrap() error {
+	return e.Err
+}
+
+// authorize authz为nil时允许所有操作；Authorizer返回任何错误都视为拒绝
+func authorize(authz Authorizer, subject, action, keyPath string) error {
+	if authz == nil {
+		return nil
+	}
+	if err := authz.Authorize(subject, action, keyPath); err != nil {
+		return errors.WithStack(&AuthzError{Subject: subject, Action: action, KeyPath: keyPath, Err: err})
+	}
+	return nil
+}
+
+// AuthorizedConfig Set前检查subject是否有写入keyPath的权限，读取及Watch不检查
+//
+//	cfg := config.NewAuthorizedConfig(appCfg, policy, "deploy-bot")
+//	err := cfg.Set("feature.beta", true) // errors.Is(err, config.ErrForbidden)
+type AuthorizedConfig struct {
+	ConfigHelper
+}
+
+func NewAuthorizedConfig(cfg Configer, authz Authorizer, subject string) *AuthorizedConfig {
+	return &AuthorizedConfig{
+		ConfigHelper: ConfigHelper{
+			Configer: &authorizedConfig{
+				Configer: cfg,
+				authz:    authz,
+				subject:  subject,
+			},
+		},
+	}
+}
+
+type authorizedConfig struct {
+	Configer
+	authz   Authorizer
+	subject string
+}
+
+func (c *authorizedConfig) Set(keyPath string, value interface{}) error {
+	if err := authorize(c.authz, c.subject, ActionWrite, keyPath); err != nil {
+		return err
+	}
+	return c.Configer.Set(keyPath, value)
+}
+
+// configer 快照等按原配置读取
+func (c *authorizedConfig) configer() Configer {
+	return c.Configer
+}
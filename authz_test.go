@@ -0,0 +1,155 @@
+package config
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPolicy admin可访问所有节点，其他调用方只能读取app及其子节点
+var testPolicy = AuthorizerFunc(func(subject, action, keyPath string) error {
+	if subject == "admin" {
+		return nil
+	}
+	if action == ActionRead && (keyPath == "app" || strings.HasPrefix(keyPath, "app.")) {
+		return nil
+	}
+	return errors.New("denied by policy")
+})
+
+func TestAuthorizedConfig(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"app": map[string]interface{}{"name": "demo"}})
+
+	user := NewAuthorizedConfig(cfg, testPolicy, "user")
+	ast.Equal("demo", user.String("app.name"))
+	err := user.Set("app.name", "x")
+	ast.True(errors.Is(err, ErrForbidden))
+	var authzErr *AuthzError
+	ast.True(errors.As(err, &authzErr))
+	ast.Equal(ActionWrite, authzErr.Action)
+	ast.Equal("app.name", authzErr.KeyPath)
+	ast.Equal("denied by policy", errors.Cause(authzErr.Err).Error())
+	ast.Equal("demo", cfg.String("app.name"))
+
+	admin := NewAuthorizedConfig(cfg, testPolicy, "admin")
+	ast.Nil(admin.Set("app.name", "x"))
+	ast.Equal("x", cfg.String("app.name"))
+	ast.Equal("x", Snapshot(admin).String("app.name"))
+
+	// 没有Authorizer时不检查
+	ast.Nil(NewAuthorizedConfig(cfg, nil, "").Set("app.name", "y"))
+}
+
+func TestAuthorizedAdminHandler(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"app": map[string]interface{}{"name": "demo"},
+		"db":  map[string]interface{}{"host": "example.com"},
+	})
+	handler := NewAuthorizedAdminHandler(cfg, testPolicy, func(r *http.Request) string {
+		return r.Header.Get("X-Subject")
+	})
+
+	get := func(subject, url string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("X-Subject", subject)
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	ast.Equal(http.StatusOK, get("user", "/?key=app.name"))
+	ast.Equal(http.StatusOK, get("user", "/keys?prefix=app"))
+	ast.Equal(http.StatusOK, get("user", "/dump?key=app"))
+	ast.Equal(http.StatusForbidden, get("user", "/?key=db"))
+	ast.Equal(http.StatusForbidden, get("user", "/"))
+	ast.Equal(http.StatusForbidden, get("user", "/keys"))
+	ast.Equal(http.StatusOK, get("user", "/openapi.json"))
+	ast.Equal(http.StatusOK, get("admin", "/"))
+}
+
+func TestSocketServerAuthorizer(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"app": map[string]interface{}{"name": "demo"},
+		"db":  map[string]interface{}{"host": "example.com"},
+	})
+
+	path := filepath.Join(t.TempDir(), "config.sock")
+	server, err := NewSocketServer(cfg, path)
+	ast.Nil(err)
+	defer server.Close()
+	server.SetAuthorizer(testPolicy, func(conn net.Conn) string { return "user" })
+	go server.Serve()
+
+	conn, err := net.Dial("unix", path)
+	ast.Nil(err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	var resp SocketResponse
+	ast.Nil(writeFrame(conn, SocketRequest{ID: 1, Op: SocketOpGet, Key: "app.name"}))
+	ast.Nil(readFrame(r, &resp))
+	ast.Empty(resp.Error)
+	ast.JSONEq(`"demo"`, string(resp.Value))
+
+	resp = SocketResponse{}
+	ast.Nil(writeFrame(conn, SocketRequest{ID: 2, Op: SocketOpWatch, Key: "db"}))
+	ast.Nil(readFrame(r, &resp))
+	ast.EqualValues(2, resp.ID)
+	ast.Contains(resp.Error, "forbidden")
+	ast.Nil(resp.Value)
+}
+
+func TestAgentServerAuthorizer(t *testing.T) {
+	ast := assert.New(t)
+
+	backend := NewMockAsyncer(false)
+	backend.data.Store("app.json", []byte(`{"name": "demo"}`))
+	backend.data.Store("secret.json", []byte(`{"password": "x"}`))
+
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	server, err := NewAgentServer(backend, path, time.Hour)
+	ast.Nil(err)
+	defer server.Close()
+
+	// 按后端key限制读取
+	var resources []string
+	var mu sync.Mutex
+	server.SetAuthorizer(AuthorizerFunc(func(subject, action, keyPath string) error {
+		mu.Lock()
+		resources = append(resources, keyPath)
+		mu.Unlock()
+		if strings.HasPrefix(keyPath, "app.json:") {
+			return nil
+		}
+		return errors.New("denied by policy")
+	}), func(conn net.Conn) string { return "user" })
+	go server.Serve()
+
+	ast.Contains(string(NewAgentAsyncer(path).Get("app.json")), `"name":"demo"`)
+	ast.Nil(NewAgentAsyncer(path).Get("secret.json"))
+
+	mu.Lock()
+	ast.Equal([]string{"app.json:", "secret.json:"}, resources)
+	mu.Unlock()
+
+	// 拒绝的后端key不会被加载
+	server.agent.Lock()
+	_, ok := server.agent.sources["secret.json"]
+	server.agent.Unlock()
+	ast.False(ok)
+}
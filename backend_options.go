@@ -0,0 +1,301 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+// DefaultDialTimeout 内置后端建立连接的默认超时时间
+var DefaultDialTimeout = 10 * time.Second
+
+// TLSOptions 内置后端共用的TLS配置
+type TLSOptions struct {
+	CAFile             string        // CA证书文件，为空时使用系统证书
+	CertFile           string        // 客户端证书文件（mTLS）
+	KeyFile            string        // 客户端私钥文件（mTLS）
+	ClientCert         *CertReloader // 热更新的客户端证书，优先于CertFile/KeyFile
+	ServerName         string        // 校验的服务端名称，为空时使用连接地址的主机名
+	InsecureSkipVerify bool          // 不校验服务端证书，仅用于测试
+}
+
+// Config 生成tls.Config
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	c := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.CAFile != "" {
+		pem, err := ioutil.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read ca file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in ca file[%s]", o.CAFile)
+		}
+		c.RootCAs = pool
+	}
+
+	switch {
+	case o.ClientCert != nil:
+		c = o.ClientCert.TLSConfig(c)
+	case o.CertFile != "" || o.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate")
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return c, nil
+}
+
+// BackendOptions 内置后端（redis、consul等）共用的连接选项
+type BackendOptions struct {
+	TLS *TLSOptions // 为nil时不使用TLS
+	// Proxy 代理地址，支持 http://、https://、socks5://，可包含用户名密码
+	Proxy       string
+	DialTimeout time.Duration
+	// TokenSource HTTP后端每次请求时设置Authorization头，redis建立连接时AUTH
+	TokenSource TokenSource
+
+	once      sync.Once
+	tlsConfig *tls.Config
+	dialer    proxy.ContextDialer
+	err       error
+}
+
+type BackendOption func(*BackendOptions)
+
+// WithTLS 使用TLS连接后端
+func WithTLS(tlsOptions *TLSOptions) BackendOption {
+	return func(o *BackendOptions) {
+		o.TLS = tlsOptions
+	}
+}
+
+// WithProxy 通过代理连接后端，见 BackendOptions.Proxy
+func WithProxy(proxyURL string) BackendOption {
+	return func(o *BackendOptions) {
+		o.Proxy = proxyURL
+	}
+}
+
+// WithDialTimeout 建立连接的超时时间，默认 DefaultDialTimeout
+func WithDialTimeout(timeout time.Duration) BackendOption {
+	return func(o *BackendOptions) {
+		o.DialTimeout = timeout
+	}
+}
+
+// WithTokenSource 使用token访问后端，source会被 ReuseTokenSource 缓存
+func WithTokenSource(source TokenSource) BackendOption {
+	return func(o *BackendOptions) {
+		o.TokenSource = ReuseTokenSource(source)
+	}
+}
+
+func NewBackendOptions(opts ...BackendOption) *BackendOptions {
+	o := &BackendOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// init 配置错误（证书、代理地址）在首次连接时返回
+func (o *BackendOptions) init() error {
+	o.once.Do(func() {
+		timeout := o.DialTimeout
+		if timeout <= 0 {
+			timeout = DefaultDialTimeout
+		}
+		direct := &net.Dialer{Timeout: timeout}
+		o.dialer = direct
+
+		if o.TLS != nil {
+			if o.tlsConfig, o.err = o.TLS.Config(); o.err != nil {
+				return
+			}
+		}
+
+		if o.Proxy != "" {
+			o.dialer, o.err = newProxyDialer(o.Proxy, direct)
+		}
+	})
+	return o.err
+}
+
+// TLSConfig 未配置TLS时返回nil
+func (o *BackendOptions) TLSConfig() (*tls.Config, error) {
+	if err := o.init(); err != nil {
+		return nil, err
+	}
+	return o.tlsConfig, nil
+}
+
+// dialRaw 经代理（如有）建立连接，不包含TLS
+func (o *BackendOptions) dialRaw(ctx context.Context, network, addr string) (net.Conn, error) {
+	if err := o.init(); err != nil {
+		return nil, err
+	}
+	return o.dialer.DialContext(ctx, network, addr)
+}
+
+// DialContext 经代理（如有）建立连接，配置了TLS时完成TLS握手
+func (o *BackendOptions) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := o.dialRaw(ctx, network, addr)
+	if err != nil || o.tlsConfig == nil {
+		return conn, err
+	}
+	return tlsHandshake(ctx, conn, o.tlsConfig, addr)
+}
+
+// tlsHandshake 在conn上完成TLS握手，c未指定ServerName时使用addr的主机名，失败时关闭conn
+func tlsHandshake(ctx context.Context, conn net.Conn, c *tls.Config, addr string) (net.Conn, error) {
+	if c.ServerName == "" {
+		c = c.Clone()
+		c.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tlsConn := tls.Client(conn, c)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "tls handshake %s", addr)
+	}
+	return tlsConn, nil
+}
+
+// HTTPClient 使用该选项的http.Client
+func (o *BackendOptions) HTTPClient() *http.Client {
+	// 配置错误时由dialRaw返回
+	tlsConfig, _ := o.TLSConfig()
+	var transport http.RoundTripper = &http.Transport{
+		DialContext:         o.dialRaw,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: DefaultDialTimeout,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if o.TokenSource != nil {
+		transport = &tokenTransport{base: transport, source: o.TokenSource}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// backendHTTPClient 未指定选项时使用http.DefaultClient
+func backendHTTPClient(opts []BackendOption) *http.Client {
+	if len(opts) == 0 {
+		return http.DefaultClient
+	}
+	return NewBackendOptions(opts...).HTTPClient()
+}
+
+func newProxyDialer(proxyURL string, forward *net.Dialer) (proxy.ContextDialer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse proxy url")
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &httpProxyDialer{proxyURL: u, forward: forward}, nil
+	case "socks5", "socks5h":
+		d, err := proxy.FromURL(u, forward)
+		if err != nil {
+			return nil, errors.Wrap(err, "socks5 proxy")
+		}
+		return d.(proxy.ContextDialer), nil
+	default:
+		return nil, errors.Errorf("unsupported proxy scheme[%s]", u.Scheme)
+	}
+}
+
+// httpProxyDialer 通过HTTP CONNECT建立隧道
+type httpProxyDialer struct {
+	proxyURL *url.URL
+	forward  *net.Dialer
+}
+
+func (d *httpProxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyAddr := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		port := "80"
+		if d.proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(d.proxyURL.Hostname(), port)
+	}
+
+	conn, err := d.forward.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "dial proxy")
+	}
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "proxy tls handshake")
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u := d.proxyURL.User; u != nil {
+		password, _ := u.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "proxy connect")
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "proxy connect")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, errors.Errorf("proxy connect %s: %s", addr, resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
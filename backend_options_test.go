@@ -0,0 +1,146 @@
+package config
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestConnectProxy HTTP CONNECT代理，返回代理地址及建立的隧道数
+func newTestConnectProxy(t *testing.T, user, password string) (*httptest.Server, *int32) {
+	var tunnels int32
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "connect only", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != auth {
+			http.Error(w, "auth required", http.StatusProxyAuthRequired)
+			return
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		atomic.AddInt32(&tunnels, 1)
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &tunnels
+}
+
+func TestBackendOptionsTLS(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	ast.Nil(err)
+	defer os.RemoveAll(dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	ast.Nil(ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600))
+	certPEM, keyPEM := genTestCert(t, "client", time.Now().Add(time.Hour))
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ast.Nil(ioutil.WriteFile(certFile, certPEM, 0600))
+	ast.Nil(ioutil.WriteFile(keyFile, keyPEM, 0600))
+
+	get := func(opts ...BackendOption) (string, error) {
+		resp, err := NewBackendOptions(opts...).HTTPClient().Get(srv.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	body, err := get(WithTLS(&TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}))
+	ast.Nil(err)
+	ast.Equal("client", body)
+
+	// 未提供客户端证书
+	_, err = get(WithTLS(&TLSOptions{CAFile: caFile}))
+	ast.NotNil(err)
+
+	// 未信任服务端证书
+	_, err = get(WithTLS(&TLSOptions{CertFile: certFile, KeyFile: keyFile}))
+	ast.NotNil(err)
+
+	body, err = get(WithTLS(&TLSOptions{InsecureSkipVerify: true, CertFile: certFile, KeyFile: keyFile}))
+	ast.Nil(err)
+	ast.Equal("client", body)
+
+	// 服务端名称不匹配
+	_, err = get(WithTLS(&TLSOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "other.com"}))
+	ast.NotNil(err)
+
+	_, err = get(WithTLS(&TLSOptions{CAFile: keyFile}))
+	ast.NotNil(err)
+	_, err = NewBackendOptions(WithTLS(&TLSOptions{CAFile: keyFile})).TLSConfig()
+	ast.NotNil(err)
+}
+
+func TestBackendOptionsProxy(t *testing.T) {
+	ast := assert.New(t)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	proxy, tunnels := newTestConnectProxy(t, "user", "pass")
+
+	client := NewBackendOptions(WithProxy("http://user:pass@" + proxy.Listener.Addr().String())).HTTPClient()
+	resp, err := client.Get(backend.URL)
+	if ast.Nil(err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		ast.Equal("ok", string(body))
+	}
+	ast.EqualValues(1, atomic.LoadInt32(tunnels))
+
+	client = NewBackendOptions(WithProxy("http://user:wrong@" + proxy.Listener.Addr().String())).HTTPClient()
+	_, err = client.Get(backend.URL)
+	ast.NotNil(err)
+
+	client = NewBackendOptions(WithProxy("ftp://" + proxy.Listener.Addr().String())).HTTPClient()
+	_, err = client.Get(backend.URL)
+	ast.NotNil(err)
+}
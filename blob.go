@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+
+	"github.com/kot-w/goutils/object"
+	"github.com/pkg/errors"
+)
+
+// Blob 二进制配置项
+//
+// 二进制内容（证书、模型元数据等）不适合直接以JSON存储，有两种存储方式：
+//   - BackendKey为空：在配置中以base64字符串存储
+//   - BackendKey非空：内容原样存储在后端的BackendKey中，配置中不包含该项
+//
+// 两种方式下，刷新后KeyPath对应的值均为[]byte，使用 GetBytes 获取
+type Blob struct {
+	KeyPath    string
+	BackendKey string
+	MaxSize    int // 内容大小上限（字节），<= 0 不限制
+}
+
+func (b *Blob) checkSize(content []byte) error {
+	if b.MaxSize > 0 && len(content) > b.MaxSize {
+		return errors.Errorf("blob[%s] size %d exceeds limit %d", b.KeyPath, len(content), b.MaxSize)
+	}
+	return nil
+}
+
+// fetchBlobs 获取单独存储的二进制内容
+func (cfg *asyncConfig) fetchBlobs() (map[string][]byte, error) {
+	ret := make(map[string][]byte)
+	for i := range cfg.blobs {
+		blob := &cfg.blobs[i]
+		if blob.BackendKey == "" {
+			continue
+		}
+
+		content := fetch(cfg.asyncer, blob.BackendKey)
+		if err := blob.checkSize(content); err != nil {
+			return nil, err
+		}
+		ret[blob.KeyPath] = content
+	}
+
+	return ret, nil
+}
+
+// digest 配置内容（包括单独存储的二进制内容）的摘要，用于检测内容变化，返回的内容在下次刷新前有效
+func (cfg *asyncConfig) digest(rawMessage []byte, blobs map[string][]byte) []byte {
+	s := &cfg.scratch
+	s.reset()
+	s.write(rawMessage)
+	for _, blob := range cfg.blobs {
+		if content, ok := blobs[blob.KeyPath]; ok {
+			s.write(content)
+		}
+	}
+
+	return s.hexSum()
+}
+
+// resolveBlobs 将配置中的base64字符串及单独存储的内容替换为[]byte
+func (cfg *asyncConfig) resolveBlobs(val interface{}, fetched map[string][]byte) (interface{}, error) {
+	if len(cfg.blobs) == 0 {
+		return val, nil
+	}
+
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("blob config requires a map")
+	}
+
+	for i := range cfg.blobs {
+		blob := &cfg.blobs[i]
+
+		if blob.BackendKey != "" {
+			if content, ok := fetched[blob.KeyPath]; ok && content != nil {
+				if err := setMapValue(m, blob.KeyPath, content); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		v, ok := object.GetValue(m, blob.KeyPath)
+		if !ok || v == nil {
+			continue
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("blob[%s] is not a base64 string", blob.KeyPath)
+		}
+		content, err := decodeBase64(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "blob[%s]", blob.KeyPath)
+		}
+		if err := blob.checkSize(content); err != nil {
+			return nil, err
+		}
+		if err := setMapValue(m, blob.KeyPath, content); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// storeBlobs 将单独存储的内容写入后端，并返回用于序列化的配置（不含单独存储的内容，内联内容转为base64）
+func (cfg *asyncConfig) storeBlobs(val map[string]interface{}) (map[string]interface{}, error) {
+	for i := range cfg.blobs {
+		blob := &cfg.blobs[i]
+
+		v, ok := object.GetValue(val, blob.KeyPath)
+		if !ok || v == nil {
+			continue
+		}
+
+		content, ok := v.([]byte)
+		if !ok {
+			if s, isStr := v.(string); isStr {
+				content = []byte(s)
+			} else {
+				return nil, errors.Errorf("blob[%s] type %T is not []byte", blob.KeyPath, v)
+			}
+		}
+		if err := blob.checkSize(content); err != nil {
+			return nil, err
+		}
+
+		if blob.BackendKey == "" {
+			if err := setMapValue(val, blob.KeyPath, base64.StdEncoding.EncodeToString(content)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := backendError(blob.BackendKey, cfg.asyncer.Set(blob.BackendKey, content)); err != nil {
+			return nil, errors.Wrapf(err, "set blob[%s]", blob.KeyPath)
+		}
+		if err := setMapValue(val, blob.KeyPath, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return val, nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	if content, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return content, nil
+	}
+
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// GetBytes 返回指定节点的二进制内容
+//
+// 值为[]byte时直接返回（见 Blob），为字符串时按base64解码
+func (h *ConfigHelper) GetBytes(keyPath string) ([]byte, error) {
+	val := h.Get(keyPath)
+
+	switch v := val.(type) {
+	case nil:
+		return nil, keyNotFound(keyPath)
+	case []byte:
+		return v, nil
+	case string:
+		bs, err := decodeBase64(v)
+		if err != nil {
+			return nil, typeMismatch(keyPath, err)
+		}
+		return bs, nil
+	default:
+		return nil, typeMismatch(keyPath, errors.Errorf("%T is not bytes", val))
+	}
+}
+
+// BlobReader 以io.Reader的方式读取指定节点的二进制内容，见 GetBytes
+func (h *ConfigHelper) BlobReader(keyPath string) (io.Reader, error) {
+	content, err := h.GetBytes(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(content), nil
+}
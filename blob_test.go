@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlob(t *testing.T) {
+	ast := assert.New(t)
+
+	cert := []byte{0x30, 0x82, 0x01, 0x0a, 0x00, 0xff}
+	model := []byte("model metadata \x00\x01")
+
+	mock := NewMockAsyncer(false)
+	asyncer := rawAsyncer{mock}
+	key := "blob_key"
+	mock.data.Store(key, []byte(`{"name":"svc","tls":{"cert":"`+base64.StdEncoding.EncodeToString(cert)+`"}}`))
+	mock.data.Store("blob_key.model", model)
+
+	cfg := NewAsyncConfig(asyncer, key, time.Millisecond, false, WithBlobs(
+		Blob{KeyPath: "tls.cert", MaxSize: 1024},
+		Blob{KeyPath: "model", BackendKey: "blob_key.model", MaxSize: 1024},
+	))
+
+	ast.Equal("svc", cfg.String("name"))
+	bs, err := cfg.GetBytes("tls.cert")
+	ast.Nil(err)
+	ast.Equal(cert, bs)
+	bs, err = cfg.GetBytes("model")
+	ast.Nil(err)
+	ast.Equal(model, bs)
+
+	r, err := cfg.BlobReader("model")
+	ast.Nil(err)
+	bs, _ = ioutil.ReadAll(r)
+	ast.Equal(model, bs)
+
+	_, err = cfg.GetBytes("not_exist")
+	ast.NotNil(err)
+
+	// change of blob stored in separate key
+	model2 := []byte("model metadata v2")
+	mock.data.Store("blob_key.model", model2)
+	time.Sleep(2 * time.Millisecond)
+	bs, _ = cfg.GetBytes("model")
+	ast.Equal(model2, bs)
+
+	// exceeds limit, keep old value
+	mock.data.Store("blob_key.model", make([]byte, 1025))
+	time.Sleep(2 * time.Millisecond)
+	bs, _ = cfg.GetBytes("model")
+	ast.Equal(model2, bs)
+	mock.data.Store("blob_key.model", model2)
+
+	// write back
+	cfg = NewAsyncConfig(asyncer, key, time.Minute, false, WithBlobs(
+		Blob{KeyPath: "tls.cert"},
+		Blob{KeyPath: "model", BackendKey: "blob_key.model"},
+	))
+	ast.Nil(cfg.Set("model", []byte("model v3")))
+	ast.Nil(cfg.Set("tls.cert", []byte("cert v2")))
+	v, _ := mock.data.Load("blob_key.model")
+	ast.Equal([]byte("model v3"), v)
+	v, _ = mock.data.Load(key)
+	ast.JSONEq(`{"name":"svc","tls":{"cert":"`+base64.StdEncoding.EncodeToString([]byte("cert v2"))+`"}}`, string(v.([]byte)))
+	bs, _ = cfg.GetBytes("model")
+	ast.Equal([]byte("model v3"), bs)
+}
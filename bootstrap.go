@@ -0,0 +1,205 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// BootstrapLayerName 见 AddBootstrapLayer
+	BootstrapLayerName = "bootstrap"
+	// DefaultPodInfoDir downward API 文件默认的挂载目录
+	DefaultPodInfoDir = "/etc/podinfo"
+	// ServiceAccountNamespaceFile ServiceAccount挂载的namespace文件
+	ServiceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// bootstrapEnvs 环境变量 => keyPath，同一keyPath以先出现的为准
+var bootstrapEnvs = [][2]string{
+	{"POD_NAME", "pod.name"},
+	{"HOSTNAME", "pod.name"},
+	{"POD_NAMESPACE", "pod.namespace"},
+	{"NAMESPACE", "pod.namespace"},
+	{"POD_IP", "pod.ip"},
+	{"POD_UID", "pod.uid"},
+	{"POD_SERVICE_ACCOUNT", "pod.service_account"},
+	{"NODE_NAME", "node.name"},
+}
+
+// bootstrapLabels Helm及Kubernetes推荐的标签 => keyPath
+var bootstrapLabels = [][2]string{
+	{"app.kubernetes.io/name", "app.name"},
+	{"app.kubernetes.io/instance", "app.instance"},
+	{"app.kubernetes.io/version", "app.version"},
+	{"app.kubernetes.io/component", "app.component"},
+	{"app.kubernetes.io/part-of", "app.part_of"},
+	{"app.kubernetes.io/managed-by", "app.managed_by"},
+	{"helm.sh/chart", "helm.chart"},
+}
+
+type bootstrapOptions struct {
+	podInfoDir    string
+	namespaceFile string
+	getenv        func(string) string
+}
+
+type BootstrapOption func(*bootstrapOptions)
+
+// WithPodInfoDir downward API 文件的挂载目录，默认 DefaultPodInfoDir
+func WithPodInfoDir(dir string) BootstrapOption {
+	return func(o *bootstrapOptions) {
+		o.podInfoDir = dir
+	}
+}
+
+// WithBootstrapEnv 读取环境变量的方法，默认 os.Getenv
+func WithBootstrapEnv(getenv func(string) string) BootstrapOption {
+	return func(o *bootstrapOptions) {
+		o.getenv = getenv
+	}
+}
+
+// BootstrapConfig 启动时确定的Pod元数据，只读
+type BootstrapConfig struct {
+	ConfigHelper
+}
+
+// NewBootstrapConfig 从downward API文件及环境变量中收集Pod元数据
+//
+// downward API 目录中的 labels、annotations 文件解析为 pod.labels、pod.annotations，
+// 其他文件以文件名为key保存在 pod 下；环境变量仅在文件中未提供时生效：
+//
+//	pod.name pod.namespace pod.ip pod.uid pod.service_account node.name
+//	app.name app.instance app.version app.component app.part_of app.managed_by helm.chart
+//
+// 目录不存在时（非Kubernetes环境）只使用环境变量
+func NewBootstrapConfig(opts ...BootstrapOption) (*BootstrapConfig, error) {
+	o := &bootstrapOptions{
+		podInfoDir:    DefaultPodInfoDir,
+		namespaceFile: ServiceAccountNamespaceFile,
+		getenv:        os.Getenv,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	root := make(map[string]interface{})
+	pod := make(map[string]interface{})
+	root["pod"] = pod
+
+	if err := readPodInfo(o.podInfoDir, pod); err != nil {
+		return nil, err
+	}
+
+	for _, item := range bootstrapEnvs {
+		if v := o.getenv(item[0]); v != "" && lookupString(root, item[1]) == "" {
+			setMapValue(root, item[1], v)
+		}
+	}
+
+	if lookupString(root, "pod.namespace") == "" {
+		if data, err := ioutil.ReadFile(o.namespaceFile); err == nil {
+			pod["namespace"] = strings.TrimSpace(string(data))
+		}
+	}
+
+	labels, _ := pod["labels"].(map[string]interface{})
+	for _, item := range bootstrapLabels {
+		if v, ok := labels[item[0]]; ok {
+			setMapValue(root, item[1], v)
+		}
+	}
+
+	return &BootstrapConfig{
+		ConfigHelper: ConfigHelper{
+			Configer: &snapshotConfig{layers: []snapshotLayer{{source: "bootstrap", root: root}}},
+		},
+	}, nil
+}
+
+// AddBootstrapLayer 将Pod元数据添加为 BootstrapLayerName 层
+func AddBootstrapLayer(opts ...BootstrapOption) error {
+	cfg, err := NewBootstrapConfig(opts...)
+	if err != nil {
+		return err
+	}
+	AddLayer(BootstrapLayerName, cfg)
+	return nil
+}
+
+func lookupString(root map[string]interface{}, keyPath string) string {
+	v, _ := lookupValue(root, keyPath)
+	s, _ := v.(string)
+	return s
+}
+
+func readPodInfo(dir string, pod map[string]interface{}) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "read podinfo dir[%s]", dir)
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		// ..data 等为configmap/downwardAPI卷内部使用的文件
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		// downwardAPI卷中的文件是符号链接
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "read podinfo[%s]", path)
+		}
+
+		switch name {
+		case "labels", "annotations":
+			m, err := parsePodInfoMap(data)
+			if err != nil {
+				return errors.Wrapf(err, "parse podinfo[%s]", path)
+			}
+			pod[name] = m
+		default:
+			pod[name] = strings.TrimSpace(string(data))
+		}
+	}
+
+	return nil
+}
+
+// parsePodInfoMap 解析downward API的labels/annotations文件，每行格式为 key="value"
+func parsePodInfoMap(data []byte) (map[string]interface{}, error) {
+	ret := make(map[string]interface{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, errors.Errorf("invalid line: %s", line)
+		}
+		v, err := strconv.Unquote(line[i+1:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid line: %s", line)
+		}
+		ret[line[:i]] = v
+	}
+	return ret, scanner.Err()
+}
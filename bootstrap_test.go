@@ -0,0 +1,66 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrapConfig(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	ast.Nil(err)
+	defer os.RemoveAll(dir)
+
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "labels"), []byte(
+		"app.kubernetes.io/name=\"billing\"\n"+
+			"app.kubernetes.io/instance=\"billing-prod\"\n"+
+			"helm.sh/chart=\"billing-1.2.0\"\n"+
+			"tier=\"backend\"\n"), 0600))
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "annotations"), []byte("note=\"a \\\"quoted\\\" value\"\n"), 0600))
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "name"), []byte("billing-7d9f\n"), 0600))
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "..data"), []byte("ignored"), 0600))
+
+	env := map[string]string{
+		"POD_NAME":      "from-env",
+		"HOSTNAME":      "host",
+		"NAMESPACE":     "prod",
+		"NODE_NAME":     "node-1",
+		"POD_IP":        "10.0.0.1",
+		"POD_NAMESPACE": "",
+	}
+	getenv := func(k string) string { return env[k] }
+
+	cfg, err := NewBootstrapConfig(WithPodInfoDir(dir), WithBootstrapEnv(getenv))
+	ast.Nil(err)
+	ast.Equal("billing-7d9f", cfg.String("pod.name"), "files take precedence")
+	ast.Equal("prod", cfg.String("pod.namespace"))
+	ast.Equal("10.0.0.1", cfg.String("pod.ip"))
+	ast.Equal("node-1", cfg.String("node.name"))
+	ast.Equal("billing", cfg.String("app.name"))
+	ast.Equal("billing-prod", cfg.String("app.instance"))
+	ast.Equal("billing-1.2.0", cfg.String("helm.chart"))
+	ast.Equal("backend", cfg.Map("pod.labels").Get("tier"))
+	ast.Equal(`a "quoted" value`, cfg.Map("pod.annotations").Get("note"))
+	ast.Nil(cfg.Get("pod.data"))
+	ast.True(errors.Is(cfg.Set("pod.name", "x"), ErrReadOnly))
+
+	// 非Kubernetes环境
+	cfg, err = NewBootstrapConfig(WithPodInfoDir(filepath.Join(dir, "not_exist")), WithBootstrapEnv(getenv))
+	ast.Nil(err)
+	ast.Equal("from-env", cfg.String("pod.name"))
+	ast.Equal("", cfg.String("app.name"))
+
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "labels"), []byte("invalid"), 0600))
+	_, err = NewBootstrapConfig(WithPodInfoDir(dir), WithBootstrapEnv(getenv))
+	ast.NotNil(err)
+
+	ast.Nil(AddBootstrapLayer(WithPodInfoDir(filepath.Join(dir, "not_exist")), WithBootstrapEnv(getenv)))
+	defer RemoveLayer(BootstrapLayerName)
+	ast.Equal("node-1", String("node.name", BootstrapLayerName))
+}
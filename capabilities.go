@@ -0,0 +1,139 @@
+package config
+
+import (
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Capabilities 后端支持的能力
+type Capabilities struct {
+	Watch    bool // 支持变化通知，见 Asyncer.Watch
+	CAS      bool // 见 CASSetter
+	BatchGet bool // 见 BatchGetter
+	List     bool // 见 Lister、PrefixLister
+	TTL      bool // 见 TTLSetter
+	Version  bool // 见 VersionedSetter
+}
+
+// CapabilityReporter 后端主动声明支持的能力
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CASSetter 支持比较并设置的后端，old为nil表示key不存在时才设置，返回是否设置成功
+type CASSetter interface {
+	CompareAndSet(key string, old, value []byte) (bool, error)
+}
+
+// BatchGetter 支持批量读取的后端，不存在的key不在返回结果中
+type BatchGetter interface {
+	BatchGet(keys []string) (map[string][]byte, error)
+}
+
+// TTLSetter 支持设置过期时间的后端
+type TTLSetter interface {
+	SetWithTTL(key string, value []byte, ttl time.Duration) error
+}
+
+// VersionedSetter 写入后返回后端的版本号（如etcd revision、consul ModifyIndex）的后端
+type VersionedSetter interface {
+	SetVersioned(key string, value []byte) (version string, err error)
+}
+
+// ProbeCapabilities 返回asyncer支持的能力
+//
+// 实现了 CapabilityReporter 时以其为准，否则根据实现的接口判断，
+// 此时无法判断是否支持Watch（调用Watch会注册监听），Watch为false
+func ProbeCapabilities(asyncer Asyncer) Capabilities {
+	if r, ok := asyncer.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+
+	var c Capabilities
+	_, c.CAS = asyncer.(CASSetter)
+	_, c.BatchGet = asyncer.(BatchGetter)
+	_, c.TTL = asyncer.(TTLSetter)
+	_, c.Version = asyncer.(VersionedSetter)
+	if _, ok := asyncer.(Lister); ok {
+		c.List = true
+	} else {
+		_, c.List = asyncer.(PrefixLister)
+	}
+
+	return c
+}
+
+// asyncerWrapper 包装其他后端的asyncer（如 TimeoutAsyncer、RateLimitedAsyncer、AccountingAsyncer），
+// 总是实现 ReaderGetter、Lister、PrefixLister、CASSetter、BatchGetter、TTLSetter，
+// 是否支持以被包装的后端为准，见 backendOf
+type asyncerWrapper interface {
+	unwrap() Asyncer
+}
+
+// backendOf 去掉包装后的后端，只用于判断支持的接口，调用仍需经过包装
+func backendOf(asyncer Asyncer) Asyncer {
+	for {
+		w, ok := asyncer.(asyncerWrapper)
+		if !ok {
+			return asyncer
+		}
+		asyncer = w.unwrap()
+	}
+}
+
+// wrapperCapabilities 包装转发所有能力，以被包装的后端为准
+func wrapperCapabilities(asyncer Asyncer) Capabilities {
+	return ProbeCapabilities(asyncer)
+}
+
+// getReader asyncer未实现 ReaderGetter 时返回错误
+func getReader(asyncer Asyncer, key string) (io.ReadCloser, error) {
+	r, ok := asyncer.(ReaderGetter)
+	if !ok {
+		return nil, errors.Errorf("asyncer %T does not implement ReaderGetter", asyncer)
+	}
+	return r.GetReader(key)
+}
+
+// setVersioned 写入并返回版本号，asyncer未实现 VersionedSetter 时版本号为空
+func setVersioned(asyncer Asyncer, key string, value []byte) (string, error) {
+	if s, ok := asyncer.(VersionedSetter); ok {
+		return s.SetVersioned(key, value)
+	}
+	return "", asyncer.Set(key, value)
+}
+
+// compareAndSet asyncer未实现 CASSetter 时返回错误
+func compareAndSet(asyncer Asyncer, key string, old, value []byte) (bool, error) {
+	s, ok := asyncer.(CASSetter)
+	if !ok {
+		return false, errors.Errorf("asyncer %T does not implement CASSetter", asyncer)
+	}
+	return s.CompareAndSet(key, old, value)
+}
+
+// setWithTTL asyncer未实现 TTLSetter 时返回错误
+func setWithTTL(asyncer Asyncer, key string, value []byte, ttl time.Duration) error {
+	s, ok := asyncer.(TTLSetter)
+	if !ok {
+		return errors.Errorf("asyncer %T does not implement TTLSetter", asyncer)
+	}
+	return s.SetWithTTL(key, value, ttl)
+}
+
+// BatchGet 批量读取，asyncer未实现 BatchGetter 时逐个读取
+func BatchGet(asyncer Asyncer, keys []string) (map[string][]byte, error) {
+	if _, ok := backendOf(asyncer).(BatchGetter); ok {
+		return asyncer.(BatchGetter).BatchGet(keys)
+	}
+
+	ret := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if content := asyncer.Get(key); content != nil {
+			ret[key] = content
+		}
+	}
+	return ret, nil
+}
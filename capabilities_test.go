@@ -0,0 +1,74 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal(Capabilities{List: true}, ProbeCapabilities(NewFileAsyncer()))
+	ast.Equal(Capabilities{Watch: true, CAS: true, BatchGet: true, List: true}, ProbeCapabilities(NewMockAsyncer(true)))
+	ast.Equal(Capabilities{}, ProbeCapabilities(struct{ Asyncer }{NewMockAsyncer(true)}))
+
+	mock := NewMockAsyncer(false)
+	ok, err := mock.CompareAndSet("k", nil, []byte("1"))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, _ = mock.CompareAndSet("k", nil, []byte("2"))
+	ast.False(ok, "exists")
+	ok, _ = mock.CompareAndSet("k", []byte("0"), []byte("2"))
+	ast.False(ok, "not match")
+	ok, _ = mock.CompareAndSet("k", []byte("1"), []byte("2"))
+	ast.True(ok)
+
+	mock.Set("k2", []byte("v2"))
+	kvs, err := BatchGet(mock, []string{"k", "k2", "k3"})
+	ast.Nil(err)
+	ast.Equal(map[string][]byte{"k": []byte("2"), "k2": []byte("v2")}, kvs)
+
+	// 逐个读取
+	kvs, err = BatchGet(rawAsyncer{mock}, []string{"k", "k3"})
+	ast.Nil(err)
+	ast.Equal(map[string][]byte{"k": []byte("2")}, kvs)
+}
+
+func TestWrapperCapabilities(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(true)
+	wrappers := []Asyncer{
+		NewTimeoutAsyncer(mock, Timeouts{Get: time.Second, Set: time.Second}),
+		NewRateLimitedAsyncer(mock, 1000, 10),
+		&AccountingAsyncer{asyncer: mock, name: "caps"},
+	}
+	for _, w := range wrappers {
+		ast.Equal(ProbeCapabilities(mock), ProbeCapabilities(w), "%T", w)
+
+		mock.data.Delete("k")
+		ok, err := w.(CASSetter).CompareAndSet("k", nil, []byte("1"))
+		ast.Nil(err)
+		ast.True(ok, "%T", w)
+		kvs, err := BatchGet(w, []string{"k", "k2"})
+		ast.Nil(err)
+		ast.Equal(map[string][]byte{"k": []byte("1")}, kvs)
+	}
+
+	raw := NewTimeoutAsyncer(struct{ Asyncer }{rawAsyncer{mock}}, Timeouts{})
+	ast.Equal(Capabilities{}, ProbeCapabilities(raw))
+	_, err := raw.CompareAndSet("k", nil, []byte("1"))
+	ast.NotNil(err)
+	ast.NotNil(raw.SetWithTTL("k", []byte("1"), time.Second))
+	kvs, err := BatchGet(raw, []string{"k", "k2"})
+	ast.Nil(err)
+	ast.Equal(map[string][]byte{"k": []byte("1")}, kvs)
+
+	acc := &AccountingAsyncer{asyncer: mock, name: "caps_batch"}
+	BatchGet(acc, []string{"k", "k2"})
+	stats := acc.Stats()
+	ast.Equal(int64(2), stats.Gets)
+	ast.Equal(int64(1), stats.Misses)
+}
@@ -0,0 +1,216 @@
+// configctl 发布配置的工具
+//
+//	configctl keygen -out ci                         # 生成 ci.key（私钥）及 ci.pub（公钥）
+//	configctl sign -key ci.key -key-id ci-2024 -target app.json -provenance commit=$GIT_SHA -o app.signed.json app.json
+//	configctl verify -pub ci-2024=ci.pub -target app.json app.signed.json
+//
+// sign 生成的内容由 config.WithSignatureVerification 在运行时校验
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kot-w/config"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `usage: configctl <command> [flags]
+
+commands:
+  keygen  generate an ed25519 key pair
+  sign    sign a config bundle
+  verify  verify a signed config bundle
+`
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "keygen":
+		err = keygen(args[1:], stderr)
+	case "sign":
+		err = sign(args[1:], stdin, stdout, stderr)
+	case "verify":
+		err = verify(args[1:], stdin, stdout, stderr)
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	if err == flag.ErrHelp {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "configctl %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// pairs 可重复的 k=v 参数
+type pairs map[string]string
+
+func (p pairs) String() string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = k + "=" + p[k]
+	}
+	return strings.Join(keys, ",")
+}
+
+func (p pairs) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("%q is not k=v", s)
+	}
+	p[kv[0]] = kv[1]
+	return nil
+}
+
+// readInput 读取文件，"-" 或未指定时读取stdin
+func readInput(fs *flag.FlagSet, stdin io.Reader) ([]byte, error) {
+	if fs.NArg() > 1 {
+		return nil, fmt.Errorf("too many arguments")
+	}
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(fs.Arg(0))
+}
+
+func keygen(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("out", "", "output prefix, writes <out>.key and <out>.pub")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("-out is required")
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(*out+".key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(*out+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644)
+}
+
+func sign(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	provenance := pairs{}
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyFile := fs.String("key", "", "PEM private key, see keygen")
+	keyID := fs.String("key-id", "", "key id recorded in the bundle")
+	target := fs.String("target", "", "backend key the bundle is published to")
+	out := fs.String("o", "", "output file, default stdout")
+	fs.Var(provenance, "provenance", "provenance k=v, such as commit=<sha> (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keyFile == "" || *keyID == "" || *target == "" {
+		return fmt.Errorf("-key, -key-id and -target are required")
+	}
+
+	pemBytes, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	key, err := config.ParsePrivateKeyPEM(pemBytes)
+	if err != nil {
+		return err
+	}
+	content, err := readInput(fs, stdin)
+	if err != nil {
+		return err
+	}
+
+	if len(provenance) == 0 {
+		provenance = nil
+	}
+	bundle, err := config.SignBundle(*target, content, key, *keyID, provenance, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(bundle)
+		return err
+	}
+	return ioutil.WriteFile(*out, bundle, 0644)
+}
+
+func verify(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	pubs := pairs{}
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Var(pubs, "pub", "key_id=<PEM public key file> (repeatable)")
+	target := fs.String("target", "", "backend key the bundle must be signed for, default not checked")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(pubs) == 0 {
+		return fmt.Errorf("-pub is required")
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(pubs))
+	for keyID, file := range pubs {
+		pemBytes, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if keys[keyID], err = config.ParsePublicKeyPEM(pemBytes); err != nil {
+			return err
+		}
+	}
+
+	data, err := readInput(fs, stdin)
+	if err != nil {
+		return err
+	}
+	b, err := config.VerifyBundle(data, keys)
+	if err != nil {
+		return err
+	}
+	if *target != "" && b.Target != *target {
+		return fmt.Errorf("bundle signed for key[%s], want %s", b.Target, *target)
+	}
+
+	fmt.Fprintf(stdout, "ok: key=%s key_id=%s signed_at=%s provenance=%s bytes=%d\n",
+		b.Target, b.KeyID, b.SignedAt.Format(time.RFC3339), pairs(b.Provenance), len(b.Content))
+	return nil
+}
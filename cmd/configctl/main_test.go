@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	ast := assert.New(t)
+
+	dir := t.TempDir()
+	ci := filepath.Join(dir, "ci")
+	other := filepath.Join(dir, "other")
+	var stdout, stderr bytes.Buffer
+
+	ast.Equal(0, run([]string{"keygen", "-out", ci}, nil, &stdout, &stderr), stderr.String())
+	ast.Equal(0, run([]string{"keygen", "-out", other}, nil, &stdout, &stderr), stderr.String())
+	ast.Equal(1, run([]string{"keygen"}, nil, &stdout, &stderr))
+
+	app := filepath.Join(dir, "app.json")
+	ast.Nil(ioutil.WriteFile(app, []byte(`{"a": 1}`), 0644))
+	signed := filepath.Join(dir, "app.signed.json")
+	ast.Equal(0, run([]string{"sign", "-key", ci + ".key", "-key-id", "ci-2024", "-target", "app.json",
+		"-provenance", "commit=9f2c1e0", "-provenance", "pr=42", "-o", signed, app}, nil, &stdout, &stderr), stderr.String())
+
+	stdout.Reset()
+	ast.Equal(0, run([]string{"verify", "-pub", "ci-2024=" + ci + ".pub", signed}, nil, &stdout, &stderr), stderr.String())
+	ast.Contains(stdout.String(), "key=app.json key_id=ci-2024")
+	ast.Equal(0, run([]string{"verify", "-pub", "ci-2024=" + ci + ".pub", "-target", "app.json", signed}, nil, &stdout, &stderr), stderr.String())
+	ast.Equal(1, run([]string{"verify", "-pub", "ci-2024=" + ci + ".pub", "-target", "other.json", signed}, nil, &stdout, &stderr))
+	ast.Contains(stdout.String(), "provenance=commit=9f2c1e0,pr=42")
+
+	// 从stdin读取，输出到stdout
+	stdout.Reset()
+	ast.Equal(0, run([]string{"sign", "-key", ci + ".key", "-key-id", "ci-2024", "-target", "app.json"}, strings.NewReader(`{"b": 2}`), &stdout, &stderr))
+	bundle := stdout.String()
+	stdout.Reset()
+	ast.Equal(0, run([]string{"verify", "-pub", "ci-2024=" + ci + ".pub", "-"}, strings.NewReader(bundle), &stdout, &stderr))
+
+	// 公钥不一致、未知的key_id及未签名的内容
+	stderr.Reset()
+	ast.Equal(1, run([]string{"verify", "-pub", "ci-2024=" + other + ".pub", signed}, nil, &stdout, &stderr))
+	ast.Contains(stderr.String(), "signature mismatch")
+	ast.Equal(1, run([]string{"verify", "-pub", "other=" + other + ".pub", signed}, nil, &stdout, &stderr))
+	ast.Equal(1, run([]string{"verify", "-pub", "ci-2024=" + ci + ".pub", app}, nil, &stdout, &stderr))
+
+	ast.Equal(1, run([]string{"sign", "-key", ci + ".pub", "-key-id", "x", "-target", "app.json", app}, nil, &stdout, &stderr))
+	ast.Equal(1, run([]string{"sign", "-key", ci + ".key", "-key-id", "ci-2024", app}, nil, &stdout, &stderr))
+	ast.Equal(1, run([]string{"sign", "-provenance", "x"}, nil, &stdout, &stderr))
+	ast.Equal(2, run([]string{"unknown"}, nil, &stdout, &stderr))
+	ast.Equal(2, run(nil, nil, &stdout, &stderr))
+}
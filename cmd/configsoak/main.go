@@ -0,0 +1,402 @@
+// configsoak 长时间运行 AsyncConfig 的稳定性测试
+//
+//	make soak SOAK_DURATION=4h
+//	go run -race ./cmd/configsoak -duration 4h -fail-rate 0.1
+//
+// 在会随机失败、延迟、丢失及重复通知的内存后端上并发执行Get、Set、Watch，
+// 并不断创建、关闭短期的配置；定期输出goroutine数量及堆内存，结束时检查：
+//   - 读到的版本回退
+//   - 关闭所有配置后goroutine的增长（泄漏时输出goroutine栈）
+//   - 预热后堆内存的增长
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kot-w/config"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+type options struct {
+	duration           time.Duration
+	report             time.Duration
+	keys               int
+	readers            int
+	writers            int
+	failRate           float64
+	latency            time.Duration
+	cacheTime          time.Duration
+	maxGoroutineGrowth int
+	maxHeapGrowth      int64 // bytes
+	seed               int64
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("configsoak", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var o options
+	var heapMB int64
+	fs.DurationVar(&o.duration, "duration", time.Hour, "how long to run")
+	fs.DurationVar(&o.report, "report", time.Minute, "report interval")
+	fs.IntVar(&o.keys, "keys", 8, "number of config keys")
+	fs.IntVar(&o.readers, "readers", 8, "concurrent readers")
+	fs.IntVar(&o.writers, "writers", 2, "concurrent writers")
+	fs.Float64Var(&o.failRate, "fail-rate", 0.05, "probability of each injected fault")
+	fs.DurationVar(&o.latency, "latency", 2*time.Millisecond, "max injected backend latency")
+	fs.DurationVar(&o.cacheTime, "cache-time", 50*time.Millisecond, "cache time of the configs")
+	fs.IntVar(&o.maxGoroutineGrowth, "max-goroutine-growth", 10, "max goroutines left after all configs are closed")
+	fs.Int64Var(&heapMB, "max-heap-growth", 64, "max heap growth in MB after warm-up")
+	fs.Int64Var(&o.seed, "seed", time.Now().UnixNano(), "random seed")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 2
+		}
+		return 1
+	}
+	o.maxHeapGrowth = heapMB << 20
+	if o.keys <= 0 || o.readers <= 0 || o.writers <= 0 || o.report <= 0 {
+		fmt.Fprintln(stderr, "configsoak: -keys, -readers, -writers and -report must be positive")
+		return 1
+	}
+
+	s := newSoak(o, stdout)
+	failures := s.run()
+	if len(failures) > 0 {
+		for _, f := range failures {
+			fmt.Fprintf(stdout, "FAIL: %s\n", f)
+		}
+		if s.leakedStacks != nil {
+			stderr.Write(s.leakedStacks)
+		}
+		return 1
+	}
+	fmt.Fprintln(stdout, "PASS")
+	return 0
+}
+
+// counters 各类操作及注入故障的次数
+type counters struct {
+	gets, sets, watches, churns    int64
+	getFaults, setFaults           int64
+	dropped, spurious, regressions int64
+	warnings                       int64
+}
+
+type soak struct {
+	options
+	out     io.Writer
+	backend *flakyAsyncer
+	stats   counters
+
+	leakedStacks []byte
+}
+
+func newSoak(o options, out io.Writer) *soak {
+	s := &soak{options: o, out: out}
+	s.backend = newFlakyAsyncer(o.failRate, o.latency, o.seed, &s.stats)
+	return s
+}
+
+func (s *soak) key(i int) string {
+	return fmt.Sprintf("soak/%d.json", i)
+}
+
+// run 返回失败的原因
+func (s *soak) run() []string {
+	config.SetLogger(quietLogger{&s.stats.warnings})
+
+	runtime.GC()
+	baseGoroutines := runtime.NumGoroutine()
+
+	for i := 0; i < s.keys; i++ {
+		s.backend.publish(s.key(i))
+	}
+	cfgs := make([]*config.AsyncConfig, s.keys)
+	for i := range cfgs {
+		// 一半的配置异步刷新
+		cfgs[i] = config.NewAsyncConfig(s.backend, s.key(i), s.cacheTime, i%2 == 0)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var workers int64
+	start := func(f func(r *rand.Rand)) {
+		wg.Add(1)
+		// 每个worker使用不同的随机数种子
+		workers++
+		r := rand.New(rand.NewSource(s.seed + workers))
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				f(r)
+			}
+		}()
+	}
+
+	for i := 0; i < s.readers; i++ {
+		last := make([]int64, s.keys)
+		start(func(r *rand.Rand) {
+			i := r.Intn(s.keys)
+			counter := cfgs[i].Int("counter")
+			atomic.AddInt64(&s.stats.gets, 1)
+			if counter < last[i] {
+				atomic.AddInt64(&s.stats.regressions, 1)
+			}
+			last[i] = counter
+		})
+	}
+	for i := 0; i < s.writers; i++ {
+		start(func(r *rand.Rand) {
+			s.backend.publish(s.key(r.Intn(s.keys)))
+			atomic.AddInt64(&s.stats.sets, 1)
+			sleep(ctx, time.Millisecond)
+		})
+	}
+	for i := range cfgs {
+		cfg := cfgs[i]
+		ch := make(chan struct{}, 1)
+		cfg.Watch(ch)
+		start(func(r *rand.Rand) {
+			select {
+			case <-ch:
+				atomic.AddInt64(&s.stats.watches, 1)
+			case <-ctx.Done():
+			}
+		})
+	}
+	// 通过配置写入及短期配置的创建、关闭
+	start(func(r *rand.Rand) {
+		cfg := config.NewAsyncConfig(s.backend, "soak/churn.json", s.cacheTime, r.Intn(2) == 0)
+		ch := make(chan struct{}, 1)
+		cfg.Watch(ch)
+		cfg.Set("n", r.Int63())
+		cfg.Int("n")
+		cfg.Close()
+		atomic.AddInt64(&s.stats.churns, 1)
+	})
+
+	var baseHeap, heap uint64
+	began := time.Now()
+	ticker := time.NewTicker(s.report)
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-ticker.C:
+		}
+
+		heap = heapAlloc()
+		if baseHeap == 0 {
+			// 第一次输出前为预热
+			baseHeap = heap
+		}
+		s.print(time.Since(began), heap)
+	}
+	ticker.Stop()
+	wg.Wait()
+
+	var failures []string
+	if n := atomic.LoadInt64(&s.stats.regressions); n > 0 {
+		failures = append(failures, fmt.Sprintf("%d reads went back to an older version", n))
+	}
+	if growth := int64(heap) - int64(baseHeap); growth > s.maxHeapGrowth {
+		failures = append(failures, fmt.Sprintf("heap grew %.1fMB after warm-up", float64(growth)/(1<<20)))
+	}
+
+	for _, cfg := range cfgs {
+		cfg.Close()
+	}
+	// 等待goroutine退出
+	var goroutines int
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		runtime.GC()
+		goroutines = runtime.NumGoroutine()
+		if goroutines-baseGoroutines <= s.maxGoroutineGrowth || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	fmt.Fprintf(s.out, "goroutines: before=%d after-close=%d\n", baseGoroutines, goroutines)
+	if growth := goroutines - baseGoroutines; growth > s.maxGoroutineGrowth {
+		failures = append(failures, fmt.Sprintf("%d goroutines leaked after closing all configs", growth))
+		var buf bytes.Buffer
+		pprof.Lookup("goroutine").WriteTo(&buf, 1)
+		s.leakedStacks = buf.Bytes()
+	}
+	return failures
+}
+
+func (s *soak) print(elapsed time.Duration, heap uint64) {
+	c := &s.stats
+	fmt.Fprintf(s.out, "elapsed=%s get=%d set=%d watch=%d churn=%d "+
+		"faults(get=%d set=%d dropped=%d spurious=%d) warnings=%d regressions=%d goroutines=%d heap=%.1fMB\n",
+		elapsed.Truncate(time.Second),
+		atomic.LoadInt64(&c.gets), atomic.LoadInt64(&c.sets), atomic.LoadInt64(&c.watches), atomic.LoadInt64(&c.churns),
+		atomic.LoadInt64(&c.getFaults), atomic.LoadInt64(&c.setFaults), atomic.LoadInt64(&c.dropped), atomic.LoadInt64(&c.spurious),
+		atomic.LoadInt64(&c.warnings), atomic.LoadInt64(&c.regressions),
+		runtime.NumGoroutine(), float64(heap)/(1<<20))
+}
+
+func heapAlloc() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// quietLogger 只统计警告及错误的数量，注入的故障会产生大量日志
+type quietLogger struct {
+	warnings *int64
+}
+
+func (l quietLogger) Debugf(string, ...interface{}) {}
+func (l quietLogger) Infof(string, ...interface{})  {}
+func (l quietLogger) Warnf(string, ...interface{})  { atomic.AddInt64(l.warnings, 1) }
+func (l quietLogger) Errorf(string, ...interface{}) { atomic.AddInt64(l.warnings, 1) }
+func (l quietLogger) Fatalf(msg string, args ...interface{}) {
+	panic(fmt.Sprintf(msg, args...))
+}
+
+// flakyAsyncer 内存后端，按概率注入故障：Get失败、Set失败、丢失通知、重复通知，每次访问有随机延迟
+type flakyAsyncer struct {
+	failRate float64
+	latency  time.Duration
+	stats    *counters
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	data     map[string][]byte
+	counters map[string]int64
+	chans    map[string]chan struct{}
+}
+
+var _ config.Asyncer = (*flakyAsyncer)(nil)
+
+func newFlakyAsyncer(failRate float64, latency time.Duration, seed int64, stats *counters) *flakyAsyncer {
+	return &flakyAsyncer{
+		failRate: failRate,
+		latency:  latency,
+		stats:    stats,
+		rand:     rand.New(rand.NewSource(seed)),
+		data:     make(map[string][]byte),
+		counters: make(map[string]int64),
+		chans:    make(map[string]chan struct{}),
+	}
+}
+
+// fault 是否注入故障，并返回本次的延迟
+func (a *flakyAsyncer) fault() (bool, time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var d time.Duration
+	if a.latency > 0 {
+		d = time.Duration(a.rand.Int63n(int64(a.latency)))
+	}
+	return a.rand.Float64() < a.failRate, d
+}
+
+func (a *flakyAsyncer) ContentType(key string) config.ContentType {
+	return config.ContentTypeByExt(key)
+}
+
+func (a *flakyAsyncer) Get(key string) []byte {
+	failed, d := a.fault()
+	time.Sleep(d)
+	if failed {
+		atomic.AddInt64(&a.stats.getFaults, 1)
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.data[key]
+}
+
+func (a *flakyAsyncer) Set(key string, value []byte) error {
+	failed, d := a.fault()
+	time.Sleep(d)
+	if failed {
+		atomic.AddInt64(&a.stats.setFaults, 1)
+		return fmt.Errorf("injected set failure")
+	}
+
+	a.mu.Lock()
+	a.data[key] = value
+	a.mu.Unlock()
+	a.notify(key)
+	return nil
+}
+
+// publish 写入递增的counter，同一key的版本只增不减
+func (a *flakyAsyncer) publish(key string) {
+	a.mu.Lock()
+	a.counters[key]++
+	a.data[key], _ = json.Marshal(map[string]interface{}{"key": key, "counter": a.counters[key]})
+	a.mu.Unlock()
+	a.notify(key)
+}
+
+func (a *flakyAsyncer) notify(key string) {
+	failed, _ := a.fault()
+	if failed {
+		atomic.AddInt64(&a.stats.dropped, 1)
+		return
+	}
+
+	a.mu.Lock()
+	ch := a.chans[key]
+	spurious := a.rand.Float64() < a.failRate
+	a.mu.Unlock()
+	if ch == nil {
+		return
+	}
+
+	n := 1
+	if spurious {
+		atomic.AddInt64(&a.stats.spurious, 1)
+		n = 2
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (a *flakyAsyncer) Watch(key string) chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ch, ok := a.chans[key]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		a.chans[key] = ch
+	}
+	return ch
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	ast := assert.New(t)
+
+	var stdout, stderr bytes.Buffer
+	ast.Equal(0, run([]string{"-duration", "2s", "-report", "500ms", "-seed", "1"}, &stdout, &stderr), stdout.String()+stderr.String())
+	ast.Contains(stdout.String(), "goroutines: before=")
+	ast.Contains(stdout.String(), "PASS")
+
+	ast.Equal(1, run([]string{"-keys", "0"}, &stdout, &stderr))
+	ast.Equal(1, run([]string{"-unknown"}, &stdout, &stderr))
+}
+
+func TestFlakyAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	var stats counters
+	a := newFlakyAsyncer(0, 0, 1, &stats)
+	ch := a.Watch("a.json")
+	a.publish("a.json")
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		ast.Fail("no notification")
+	}
+	ast.JSONEq(`{"key": "a.json", "counter": 1}`, string(a.Get("a.json")))
+	a.publish("a.json")
+	ast.JSONEq(`{"key": "a.json", "counter": 2}`, string(a.Get("a.json")))
+	ast.Nil(a.Get("b.json"))
+}
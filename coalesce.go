@@ -0,0 +1,67 @@
+package config
+
+import (
+	"sync"
+)
+
+// fetchKey 同一后端同一key的读取
+type fetchKey struct {
+	backend interface{} // 见 coalesceKey
+	key     string
+}
+
+// coalescer 包装每次创建新的实例时（如 TimeoutAsyncer），以此标识相同的后端
+type coalescer interface {
+	coalesceKey() interface{}
+}
+
+// coalesceKey 合并读取时后端的标识
+func coalesceKey(asyncer Asyncer) interface{} {
+	if c, ok := asyncer.(coalescer); ok {
+		return c.coalesceKey()
+	}
+	return asyncer
+}
+
+type fetchCall struct {
+	wg    sync.WaitGroup
+	value []byte
+}
+
+var (
+	// 所有配置共享，只保存进行中的读取，完成后删除，不会一直持有后端
+	_fetchMu    sync.Mutex
+	_fetchCalls = make(map[fetchKey]*fetchCall)
+)
+
+// fetch 读取后端内容，多个配置并发读取同一后端的同一key时只读取一次，返回的内容不可修改
+//
+// 不可比较的后端（如包含slice、map、func的struct）不合并
+func fetch(asyncer Asyncer, key string) []byte {
+	backend := coalesceKey(asyncer)
+	if !isComparable(backend) {
+		return asyncer.Get(key)
+	}
+
+	k := fetchKey{backend: backend, key: key}
+	_fetchMu.Lock()
+	if c, ok := _fetchCalls[k]; ok {
+		_fetchMu.Unlock()
+		c.wg.Wait()
+		return c.value
+	}
+	c := &fetchCall{}
+	c.wg.Add(1)
+	_fetchCalls[k] = c
+	_fetchMu.Unlock()
+
+	defer func() {
+		_fetchMu.Lock()
+		delete(_fetchCalls, k)
+		_fetchMu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.value = asyncer.Get(key)
+	return c.value
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type slowAsyncer struct {
+	*MockAsyncer
+	gets int32
+}
+
+func (a *slowAsyncer) Get(key string) []byte {
+	atomic.AddInt32(&a.gets, 1)
+	time.Sleep(20 * time.Millisecond)
+	v, _ := a.data.Load(key)
+	bs, _ := v.([]byte)
+	return bs
+}
+
+type funcAsyncer struct {
+	*MockAsyncer
+	get func(key string) []byte
+}
+
+func (a funcAsyncer) Get(key string) []byte {
+	return a.get(key)
+}
+
+func TestFetchCoalescing(t *testing.T) {
+	ast := assert.New(t)
+
+	asyncer := &slowAsyncer{MockAsyncer: NewMockAsyncer(false)}
+	asyncer.data.Store("shared.json", []byte(`{"a": 1}`))
+
+	var wg sync.WaitGroup
+	cfgs := make([]*AsyncConfig, 10)
+	for i := range cfgs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cfgs[i] = NewAsyncConfig(asyncer, "shared.json", time.Hour, false)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, cfg := range cfgs {
+		ast.EqualValues(1, cfg.Int("a"))
+	}
+	ast.True(atomic.LoadInt32(&asyncer.gets) < 10, "gets=%d", asyncer.gets)
+
+	// 不同后端不合并
+	other := &slowAsyncer{MockAsyncer: NewMockAsyncer(false)}
+	other.data.Store("shared.json", []byte(`{"a": 2}`))
+	ast.EqualValues(2, NewAsyncConfig(other, "shared.json", time.Hour, false).Int("a"))
+
+	// 不可比较的后端不合并
+	fa := funcAsyncer{MockAsyncer: NewMockAsyncer(false), get: func(key string) []byte {
+		return []byte(`{"a": 3}`)
+	}}
+	ast.False(isComparable(fa))
+	ast.EqualValues(3, NewAsyncConfig(fa, "shared.json", time.Hour, false).Int("a"))
+
+	// 包含不可比较字段的包装也不合并
+	ast.EqualValues(3, NewAsyncConfig(struct{ Asyncer }{fa}, "shared.json", time.Hour, false).Int("a"))
+
+	// 读取完成后不再持有后端
+	_fetchMu.Lock()
+	ast.Empty(_fetchCalls)
+	_fetchMu.Unlock()
+}
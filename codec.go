@@ -0,0 +1,91 @@
+package config
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Codec 配置内容的编解码，即 Marshaler
+type Codec = Marshaler
+
+// 内置的Codec
+var (
+	JSONCodec Codec = JSONMarshaler{}
+	YAMLCodec Codec = YAMLMarshaler{}
+	TOMLCodec Codec = TOMLMarshaler{}
+	HCLCodec  Codec = HCLMarshaler{}
+	INICodec  Codec = INIMarshaler{}
+
+	PropertiesCodec Codec = PropertiesMarshaler{}
+)
+
+// NewAsyncConfigWithCodec 使用codec解析及序列化内容，不依赖asyncer根据key判断的 ContentType，
+// 适用于key没有后缀的后端（如consul）
+//
+//	cfg := config.NewAsyncConfigWithCodec(consul, "service/app", config.YAMLCodec, time.Minute, true)
+func NewAsyncConfigWithCodec(asyncer Asyncer, asyncKey string, codec Codec, cacheTime time.Duration, refreshAsync bool, opts ...AsyncOption) *AsyncConfig {
+	return NewAsyncConfig(asyncer, asyncKey, cacheTime, refreshAsync, append([]AsyncOption{WithMarshaler(codec)}, opts...)...)
+}
+
+var (
+	_codecs sync.Map // name => codecEntry
+
+	_codecMu         sync.Mutex
+	_nextContentType = T_INI + 1
+)
+
+type codecEntry struct {
+	codec       Codec
+	contentType ContentType
+}
+
+func init() {
+	for name, t := range map[string]ContentType{
+		"json":       T_JSON,
+		"yaml":       T_YAML,
+		"properties": T_PROPERTIES,
+		"dotenv":     T_DOTENV,
+		"hcl":        T_HCL,
+		"xml":        T_XML,
+		"toml":       T_TOML,
+		"ini":        T_INI,
+	} {
+		_codecs.Store(name, codecEntry{codec: typeMarshalers[t], contentType: t})
+	}
+}
+
+// RegisterCodec 注册第三方格式（如CUE、Jsonnet），返回分配的 ContentType，
+// exts 为该格式的文件后缀（如 ".cue"），见 ContentTypeByExt
+//
+// 需在创建配置前注册（如在init中），同名的Codec会被替换，沿用原来的 ContentType
+//
+//	func init() {
+//		config.RegisterCodec("cue", cueCodec{}, ".cue")
+//	}
+func RegisterCodec(name string, c Codec, exts ...string) ContentType {
+	_codecMu.Lock()
+	defer _codecMu.Unlock()
+
+	t := _nextContentType
+	if e, ok := _codecs.Load(name); ok {
+		t = e.(codecEntry).contentType
+	} else {
+		_nextContentType++
+	}
+
+	_codecs.Store(name, codecEntry{codec: c, contentType: t})
+	typeMarshalers[t] = c
+	for _, ext := range exts {
+		extContentTypes[strings.ToLower(ext)] = t
+	}
+	return t
+}
+
+// GetCodec 返回已注册的Codec，内置的名称为 json、yaml、toml、hcl、ini、properties、dotenv、xml
+func GetCodec(name string) Codec {
+	if e, ok := _codecs.Load(name); ok {
+		return e.(codecEntry).codec
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAsyncConfigWithCodec(t *testing.T) {
+	ast := assert.New(t)
+
+	asyncer := &rawAsyncer{NewMockAsyncer(false)}
+	asyncer.data.Store("service/app", []byte("db:\n  host: example.com\n  port: 3306\n"))
+
+	cfg := NewAsyncConfigWithCodec(asyncer, "service/app", YAMLCodec, 0, false)
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.EqualValues(3306, cfg.Int("db.port"))
+
+	ast.Nil(cfg.Set("db.port", 3307))
+	v, _ := asyncer.data.Load("service/app")
+	ast.Contains(string(v.([]byte)), "port: 3307", "written as YAML")
+
+	// 后续的选项可以覆盖codec
+	asyncer.data.Store("service/json", []byte(`{"a": 1}`))
+	cfg = NewAsyncConfigWithCodec(asyncer, "service/json", YAMLCodec, 0, false, WithMarshaler(JSONCodec))
+	ast.EqualValues(1, cfg.Int("a"))
+}
+
+type upperCodec struct {
+	JSONMarshaler
+}
+
+func TestRegisterCodec(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal(YAMLCodec, GetCodec("yaml"))
+	ast.Nil(GetCodec("unknown"))
+
+	ct := RegisterCodec("upper-test", upperCodec{}, ".UPPER")
+	ast.True(ct > T_INI)
+	ast.Equal(upperCodec{}, GetCodec("upper-test"))
+	ast.Equal(ct, ContentTypeByExt("conf.upper"))
+	ast.Equal(ct, RegisterCodec("upper-test", upperCodec{}), "same name keeps content type")
+	ast.NotEqual(ct, RegisterCodec("upper-test2", upperCodec{}))
+
+	asyncer := NewMockAsyncer(false)
+	asyncer.data.Store("app.upper", []byte(`{"a": 1}`))
+	ast.EqualValues(1, NewAsyncConfig(asyncer, "app.upper", 0, false).Int("a"))
+}
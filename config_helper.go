@@ -2,8 +2,7 @@ package config
 
 import (
 	"encoding/json"
-
-	"github.com/pkg/errors"
+	"reflect"
 
 	"github.com/kot-w/goutils/itype"
 )
@@ -18,7 +17,7 @@ func (h *ConfigHelper) JSON(keyPath string) ([]byte, error) {
 	val := h.Get(keyPath)
 
 	if val == nil {
-		return nil, errors.Errorf("path[%s] is nil", keyPath)
+		return nil, keyNotFound(keyPath)
 	}
 
 	return json.Marshal(val)
@@ -33,7 +32,10 @@ func (h *ConfigHelper) Remarshal(keyPath string, v interface{}) error {
 		return err
 	}
 
-	return json.Unmarshal(bs, v)
+	if err := json.Unmarshal(bs, v); err != nil {
+		return typeMismatch(keyPath, err)
+	}
+	return nil
 }
 
 // Dump 打印指定节点的配置JSON
@@ -175,3 +177,65 @@ func (h *ConfigHelper) BoolDefault(keyPath string, dft bool) bool {
 
 	return !isFalseStr(itype.String(ivalue))
 }
+
+// getAs 将值转换到out指向的类型，规则同 Unmarshal，不存在、为null或无法转换时返回false
+func (h *ConfigHelper) getAs(keyPath string, out interface{}) bool {
+	val, err := h.lookupNotNull(keyPath)
+	if err != nil {
+		return false
+	}
+	return decodeInto(keyPath, val, reflect.ValueOf(out).Elem()) == nil
+}
+
+// GetString 返回转换为string的配置值（数字、布尔值转为字符串），不存在、为null或无法转换时返回def，未指定时为""
+//
+//	host := cfg.GetString("db.host", "localhost")
+func (h *ConfigHelper) GetString(keyPath string, def ...string) string {
+	var v string
+	if h.getAs(keyPath, &v) {
+		return v
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return v
+}
+
+// GetInt 返回转换为int64的配置值（如 "123"、123.0），有小数或溢出视为无法转换，此时返回def，未指定时为0
+func (h *ConfigHelper) GetInt(keyPath string, def ...int64) int64 {
+	var v int64
+	if h.getAs(keyPath, &v) {
+		return v
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return v
+}
+
+// GetFloat 返回转换为float64的配置值（如 "1.5"），无法转换时返回def，未指定时为0
+func (h *ConfigHelper) GetFloat(keyPath string, def ...float64) float64 {
+	var v float64
+	if h.getAs(keyPath, &v) {
+		return v
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return v
+}
+
+// GetBool 返回转换为bool的配置值，字符串支持 true/false、1/0、yes/no、on/off（不区分大小写），
+// 数字非0为true；无法转换时返回def，未指定时为false
+//
+// 与 Bool 不同，无法识别的字符串不视为true
+func (h *ConfigHelper) GetBool(keyPath string, def ...bool) bool {
+	var v bool
+	if h.getAs(keyPath, &v) {
+		return v
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return v
+}
@@ -90,6 +90,46 @@ func TestConfig(t *testing.T) {
 	ast.NotNil(err)
 }
 
+func TestTypedGetters(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"port":    "3306",
+		"ratio":   "0.5",
+		"debug":   "on",
+		"enabled": "TRUE",
+		"flag":    1.0,
+		"count":   12.0,
+		"half":    1.5,
+		"name":    "app",
+		"null":    nil,
+	})
+
+	ast.Equal("app", cfg.GetString("name"))
+	ast.Equal("12", cfg.GetString("count"))
+	ast.Equal("default", cfg.GetString("not_exist", "default"))
+	ast.Equal("default", cfg.GetString("null", "default"))
+	ast.Equal("", cfg.GetString("not_exist"))
+
+	ast.Equal(int64(3306), cfg.GetInt("port"))
+	ast.Equal(int64(12), cfg.GetInt("count", 1))
+	ast.Equal(int64(1), cfg.GetInt("half", 1), "not an integer")
+	ast.Equal(int64(1), cfg.GetInt("name", 1))
+	ast.Equal(int64(0), cfg.GetInt("not_exist"))
+
+	ast.Equal(0.5, cfg.GetFloat("ratio"))
+	ast.Equal(1.5, cfg.GetFloat("half"))
+	ast.Equal(2.5, cfg.GetFloat("name", 2.5))
+
+	ast.True(cfg.GetBool("debug"))
+	ast.True(cfg.GetBool("enabled"))
+	ast.True(cfg.GetBool("flag"))
+	ast.True(cfg.GetBool("name", true), "unrecognized string")
+	ast.False(cfg.GetBool("name"))
+	ast.True(cfg.Bool("name"))
+	ast.False(cfg.GetBool("not_exist"))
+}
+
 func TestAddDefaultLayerName(t *testing.T) {
 	t.Skip("Skipping test because of ZK dependency")
 	ast := assert.New(t)
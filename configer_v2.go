@@ -0,0 +1,218 @@
+package config
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ChangeEvent 配置变化事件
+type ChangeEvent struct {
+	Time time.Time
+	// 配置的发布时间及发布到生效的延迟，后端内容为 Envelope 时有效
+	PublishedAt time.Time
+	Latency     time.Duration
+}
+
+// ConfigerV2 带context、error及关闭的配置接口
+//
+// 与 Configer 相互转换见 AdaptV1、AdaptV2，便于逐步迁移
+type ConfigerV2 interface {
+	// GetCtx 配置项不存在时返回 ErrKeyNotFound，值为null时返回 (nil, nil)
+	GetCtx(ctx context.Context, keyPath string) (interface{}, error)
+	SetCtx(ctx context.Context, keyPath string, value interface{}) error
+	// WatchEvents 订阅配置变化，ctx结束或Close后channel关闭
+	WatchEvents(ctx context.Context) (<-chan ChangeEvent, error)
+	Close() error
+}
+
+// AdaptV1 将 Configer 包装为 ConfigerV2，Configer实现了 Close() error 时Close会调用
+func AdaptV1(c Configer) ConfigerV2 {
+	if a, ok := c.(*v1Configer); ok {
+		return a.c
+	}
+
+	return &v2Configer{
+		c:    c,
+		subs: make(map[chan ChangeEvent]struct{}),
+		quit: make(chan struct{}),
+	}
+}
+
+// AdaptV2 将 ConfigerV2 包装为 Configer
+func AdaptV2(c ConfigerV2) Configer {
+	if a, ok := c.(*v2Configer); ok {
+		return a.c
+	}
+
+	return &v1Configer{c: c}
+}
+
+type v2Configer struct {
+	c Configer
+
+	sync.Mutex
+	notifier  chan struct{}
+	subs      map[chan ChangeEvent]struct{}
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func (a *v2Configer) GetCtx(ctx context.Context, keyPath string) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	lookup := func() (interface{}, error) {
+		val, found := lookupConfiger(a.c, keyPath)
+		if !found {
+			return nil, keyNotFound(keyPath)
+		}
+		return val, nil
+	}
+
+	if ctx.Done() == nil {
+		return lookup()
+	}
+
+	// Get可能因同步刷新阻塞
+	type result struct {
+		val interface{}
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		val, err := lookup()
+		ch <- result{val, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.val, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (a *v2Configer) SetCtx(ctx context.Context, keyPath string, value interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.c.Set(keyPath, value)
+}
+
+func (a *v2Configer) WatchEvents(ctx context.Context) (<-chan ChangeEvent, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	select {
+	case <-a.quit:
+		return nil, ErrClosed
+	default:
+	}
+
+	if a.notifier == nil {
+		// Configer.Watch 无法取消，只注册一次，分发给所有订阅者
+		a.notifier = make(chan struct{}, 1)
+		a.c.Watch(a.notifier)
+		go a.dispatch()
+	}
+
+	ch := make(chan ChangeEvent, 1)
+	a.subs[ch] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-a.quit:
+		}
+		a.Lock()
+		if _, ok := a.subs[ch]; ok {
+			delete(a.subs, ch)
+			close(ch)
+		}
+		a.Unlock()
+	}()
+
+	return ch, nil
+}
+
+func (a *v2Configer) dispatch() {
+	for {
+		select {
+		case <-a.notifier:
+			event := ChangeEvent{Time: _now()}
+			if p, ok := unwrapConfiger(a.c).(interface {
+				propagation() (time.Time, time.Time)
+			}); ok {
+				if publishedAt, appliedAt := p.propagation(); !publishedAt.IsZero() {
+					event.PublishedAt = publishedAt
+					event.Latency = appliedAt.Sub(publishedAt)
+				}
+			}
+			a.Lock()
+			for ch := range a.subs {
+				select {
+				case ch <- event:
+				default:
+				}
+			}
+			a.Unlock()
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+func (a *v2Configer) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		close(a.quit)
+		if c, ok := a.c.(interface{ Close() error }); ok {
+			err = c.Close()
+		}
+	})
+	return err
+}
+
+type v1Configer struct {
+	c ConfigerV2
+}
+
+func (a *v1Configer) Get(keyPath string) interface{} {
+	val, err := a.c.GetCtx(context.Background(), keyPath)
+	if err != nil {
+		return nil
+	}
+	return val
+}
+
+func (a *v1Configer) Lookup(keyPath string) (interface{}, bool) {
+	val, err := a.c.GetCtx(context.Background(), keyPath)
+	return val, err == nil
+}
+
+func (a *v1Configer) Set(keyPath string, value interface{}) error {
+	return a.c.SetCtx(context.Background(), keyPath, value)
+}
+
+func (a *v1Configer) Watch(notifier chan struct{}) {
+	events, err := a.c.WatchEvents(context.Background())
+	if err != nil {
+		logger.Errorf("watch config err:%v", err)
+		return
+	}
+
+	go func() {
+		for range events {
+			select {
+			case notifier <- struct{}{}:
+			default:
+			}
+		}
+	}()
+}
+
+func (a *v1Configer) Close() error {
+	return a.c.Close()
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigerV2(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"a": 1, "null": nil})
+	v2 := AdaptV1(cfg)
+	defer v2.Close()
+
+	v, err := v2.GetCtx(context.Background(), "a")
+	ast.Nil(err)
+	ast.EqualValues(1, v)
+
+	v, err = v2.GetCtx(context.Background(), "null")
+	ast.Nil(err)
+	ast.Nil(v)
+
+	_, err = v2.GetCtx(context.Background(), "b")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = v2.GetCtx(ctx, "a")
+	ast.Equal(context.Canceled, err)
+	ast.Equal(context.Canceled, v2.SetCtx(ctx, "a", 2))
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	v, err = v2.GetCtx(ctx, "a")
+	cancel()
+	ast.Nil(err)
+	ast.EqualValues(1, v)
+
+	// watch events
+	ctx, cancel = context.WithCancel(context.Background())
+	events, err := v2.WatchEvents(ctx)
+	ast.Nil(err)
+	events2, err := v2.WatchEvents(context.Background())
+	ast.Nil(err)
+
+	ast.Nil(v2.SetCtx(context.Background(), "a", 2))
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+	select {
+	case <-events2:
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	cancel()
+	_, ok := <-events
+	ast.False(ok, "closed after ctx done")
+
+	ast.Nil(v2.Close())
+	_, ok = <-events2
+	ast.False(ok, "closed after Close")
+	_, err = v2.WatchEvents(context.Background())
+	ast.True(errors.Is(err, ErrClosed))
+
+	// v2 => v1
+	v1 := AdaptV2(AdaptV1(cfg))
+	ast.Equal(Configer(cfg), v1, "unwrap adapter")
+
+	wrapped := &ConfigHelper{Configer: AdaptV2(&testConfigerV2{ConfigerV2: AdaptV1(cfg)})}
+	ast.EqualValues(2, wrapped.Get("a"))
+	ast.Nil(wrapped.Get("b"))
+	v, err = wrapped.Lookup("null")
+	ast.Nil(err)
+	ast.Nil(v)
+	ast.Nil(wrapped.Set("a", 3))
+
+	notifier := make(chan struct{}, 1)
+	wrapped.Watch(notifier)
+	ast.Nil(wrapped.Set("a", 4))
+	select {
+	case <-notifier:
+	case <-time.After(time.Second):
+		t.Fatal("notify not received")
+	}
+}
+
+type testConfigerV2 struct {
+	ConfigerV2
+}
+
+func TestAsyncConfigClose(t *testing.T) {
+	ast := assert.New(t)
+
+	asyncer := NewMockAsyncer(true)
+	asyncer.Set("close.json", []byte(`{"a": 1}`))
+	cfg := NewAsyncConfig(asyncer, "close.json", time.Millisecond, false)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	ast.Nil(AdaptV1(cfg).Close())
+	asyncer.data.Store("close.json", []byte(`{"a": 2}`))
+	time.Sleep(2 * time.Millisecond)
+	ast.EqualValues(1, cfg.Int("a"), "no refresh after close")
+}
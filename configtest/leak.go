@@ -0,0 +1,122 @@
+// Package configtest 测试中使用的辅助函数
+package configtest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+)
+
+// LeakTimeout 测试结束后等待后台goroutine退出的时间
+var LeakTimeout = 5 * time.Second
+
+// ignoredFuncs 进程内常驻、不属于某个配置的goroutine
+var ignoredFuncs = []string{
+	"github.com/kot-w/config.(*notifyWorker).run",
+}
+
+// VerifyNoLeaks 在测试结束时（t.Cleanup）检查测试中创建的配置均已关闭，
+// 且配置、后端、回调的后台goroutine均已退出，否则测试失败并输出泄漏的goroutine栈
+//
+//	func TestXxx(t *testing.T) {
+//		configtest.VerifyNoLeaks(t)
+//		cfg := config.NewAsyncConfig(...)
+//		defer cfg.Close()
+//	}
+//
+// 需在测试开始时调用；通过进程内的计数比较，不能用于 t.Parallel 的测试
+func VerifyNoLeaks(t testing.TB) {
+	t.Helper()
+
+	before := config.LiveResources()
+	existing := goroutineIDs(goroutines())
+
+	t.Cleanup(func() {
+		var (
+			after  config.Resources
+			leaked []string
+		)
+		for deadline := time.Now().Add(LeakTimeout); ; {
+			after = config.LiveResources()
+			leaked = leakedGoroutines(existing)
+			if (after == before && len(leaked) == 0) || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if n := after.Configs - before.Configs; n > 0 {
+			t.Errorf("configtest: %d AsyncConfig not closed", n)
+		}
+		if n := after.Subscriptions - before.Subscriptions; n > 0 {
+			t.Errorf("configtest: %d OnChange subscriptions not cancelled", n)
+		}
+		if n := after.Goroutines - before.Goroutines; n > 0 {
+			t.Errorf("configtest: %d config background goroutines still running", n)
+		}
+		if len(leaked) > 0 {
+			t.Errorf("configtest: %d goroutines leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+	})
+}
+
+// goroutines 所有goroutine的栈，每个以 "goroutine <id> [<state>]:" 开头
+func goroutines() []string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	return strings.Split(string(bytes.TrimSpace(buf)), "\n\n")
+}
+
+func goroutineID(stack string) string {
+	var id string
+	fmt.Sscanf(stack, "goroutine %s", &id)
+	return id
+}
+
+func goroutineIDs(stacks []string) map[string]bool {
+	ids := make(map[string]bool, len(stacks))
+	for _, stack := range stacks {
+		ids[goroutineID(stack)] = true
+	}
+	return ids
+}
+
+// leakedGoroutines 新创建的、执行本模块代码的goroutine
+func leakedGoroutines(existing map[string]bool) []string {
+	var leaked []string
+	for _, stack := range goroutines() {
+		if existing[goroutineID(stack)] || !ownedStack(stack) {
+			continue
+		}
+		leaked = append(leaked, stack)
+	}
+	return leaked
+}
+
+// ownedStack 栈中有本模块（含contrib及其测试）的函数，且不是常驻的goroutine
+func ownedStack(stack string) bool {
+	for _, fn := range ignoredFuncs {
+		if strings.Contains(stack, fn+"(") {
+			return false
+		}
+	}
+	for _, line := range strings.Split(stack, "\n") {
+		line = strings.TrimPrefix(line, "created by ")
+		if strings.HasPrefix(line, "github.com/kot-w/config") {
+			return true
+		}
+	}
+	return false
+}
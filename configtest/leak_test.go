@@ -0,0 +1,85 @@
+package configtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder 记录失败及Cleanup，由测试手动执行Cleanup
+type recorder struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recorder) finish() string {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+	return strings.Join(r.errors, "\n")
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+	ast := assert.New(t)
+
+	old := LeakTimeout
+	LeakTimeout = 200 * time.Millisecond
+	defer func() { LeakTimeout = old }()
+
+	// 全部关闭
+	r := &recorder{TB: t}
+	VerifyNoLeaks(r)
+	cfg := config.NewAsyncConfig(config.NewMockAsyncer(true), "a.json", 0, true)
+	cancel := cfg.OnChange(func() {})
+	cfg.Set("a", 1)
+	cancel()
+	cfg.OnChange(func() {})
+	cfg.Close()
+	ast.Empty(r.finish())
+
+	// 未关闭的配置及回调
+	r = &recorder{TB: t}
+	VerifyNoLeaks(r)
+	cfg = config.NewAsyncConfig(config.NewMockAsyncer(true), "a.json", 0, true)
+	cfg.OnChange(func() {})
+	errs := r.finish()
+	ast.Contains(errs, "1 AsyncConfig not closed")
+	ast.Contains(errs, "1 OnChange subscriptions not cancelled")
+	ast.Contains(errs, "1 config background goroutines still running")
+	ast.Contains(errs, "goroutines leaked")
+	cfg.Close()
+
+	// 测试中启动的goroutine
+	r = &recorder{TB: t}
+	VerifyNoLeaks(r)
+	stop := make(chan struct{})
+	go func() { <-stop }()
+	errs = r.finish()
+	ast.Contains(errs, "1 goroutines leaked")
+	ast.Contains(errs, "TestVerifyNoLeaks")
+	close(stop)
+}
+
+func TestOwnedStack(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.True(ownedStack("goroutine 7 [select]:\ngithub.com/kot-w/config.(*asyncConfig).watch(0xc000)\n\t/src/async_config.go:170"))
+	ast.True(ownedStack("goroutine 7 [chan receive]:\nmain.f()\ncreated by github.com/kot-w/config/contrib/etcdasyncer.(*Asyncer).Watch in goroutine 1"))
+	ast.False(ownedStack("goroutine 7 [sync.Cond.Wait]:\ngithub.com/kot-w/config.(*notifyWorker).run(0xc000)"))
+	ast.False(ownedStack("goroutine 7 [IO wait]:\nnet/http.(*persistConn).readLoop()"))
+}
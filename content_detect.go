@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ContentTypeHinter 可以给出最近一次Get的内容格式的后端（如consul的flags、http响应的Content-Type），
+// 见 WithAutoDetect
+type ContentTypeHinter interface {
+	ContentTypeHint(key string) (ContentType, bool)
+}
+
+// WithAutoDetect 刷新时根据内容判断格式（JSON、XML、TOML、YAML），
+// asyncer实现了 ContentTypeHinter 时优先使用其给出的格式，Set时按最近一次获取的格式写入
+//
+// 适用于同一key可能由不同的人以不同格式编辑的后端
+func WithAutoDetect() AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.autoDetect = true
+	}
+}
+
+// DetectContentType 根据内容判断格式，无法判断时返回 T_JSON
+//
+// 依次尝试JSON、XML、TOML、YAML，TOML及YAML需要能解析为map
+func DetectContentType(data []byte) ContentType {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return T_JSON
+	}
+
+	switch trimmed[0] {
+	case '{':
+		if json.Valid(trimmed) {
+			return T_JSON
+		}
+	case '[':
+		// JSON数组或TOML的table
+		if json.Valid(trimmed) {
+			return T_JSON
+		}
+	case '<':
+		return T_XML
+	}
+
+	var m map[string]interface{}
+	if bytes.IndexByte(trimmed, '=') >= 0 && safeUnmarshal(TOMLMarshaler{}, trimmed, &m) == nil {
+		return T_TOML
+	}
+	m = nil
+	if safeUnmarshal(YAMLMarshaler{}, trimmed, &m) == nil && m != nil {
+		return T_YAML
+	}
+
+	return T_JSON
+}
+
+// detectMarshaler 刷新时使用的Marshaler
+func (cfg *asyncConfig) detectMarshaler(rawMessage []byte) Marshaler {
+	if !cfg.autoDetect {
+		return cfg.marshaler
+	}
+
+	t, ok := T_JSON, false
+	if h, isHinter := cfg.asyncer.(ContentTypeHinter); isHinter {
+		t, ok = h.ContentTypeHint(cfg.asyncKey)
+	}
+	if !ok {
+		t = DetectContentType(rawMessage)
+	}
+
+	m, found := typeMarshalers[t]
+	if !found {
+		return cfg.marshaler
+	}
+	return m
+}
+
+// writeMarshaler Set时使用的Marshaler，自动判断格式时为最近一次获取的格式
+func (cfg *asyncConfig) writeMarshaler() Marshaler {
+	if m, ok := cfg.detected.Load().(detectedMarshaler); ok {
+		return m.Marshaler
+	}
+	return cfg.marshaler
+}
+
+// detectedMarshaler atomic.Value 要求保存的值类型一致
+type detectedMarshaler struct {
+	Marshaler
+}
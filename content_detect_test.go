@@ -0,0 +1,68 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hintAsyncer struct {
+	rawAsyncer
+	hint ContentType
+}
+
+func (a *hintAsyncer) ContentTypeHint(key string) (ContentType, bool) {
+	return a.hint, a.hint != T_JSON
+}
+
+func TestDetectContentType(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal(T_JSON, DetectContentType([]byte(` {"a": 1}`)))
+	ast.Equal(T_JSON, DetectContentType([]byte(`[1, 2]`)))
+	ast.Equal(T_JSON, DetectContentType(nil))
+	ast.Equal(T_XML, DetectContentType([]byte(`<config><a>1</a></config>`)))
+	ast.Equal(T_TOML, DetectContentType([]byte("[db]\nhost = \"a\"\n")))
+	ast.Equal(T_TOML, DetectContentType([]byte(`a = 1`)))
+	ast.Equal(T_YAML, DetectContentType([]byte("db:\n  host: a\n")))
+	ast.Equal(T_YAML, DetectContentType([]byte("url: http://a?b=c\n")))
+	ast.Equal(T_JSON, DetectContentType([]byte(`not a config`)))
+}
+
+func TestAutoDetect(t *testing.T) {
+	ast := assert.New(t)
+
+	asyncer := &hintAsyncer{rawAsyncer: rawAsyncer{NewMockAsyncer(false)}}
+	asyncer.data.Store("mixed", []byte(`{"a": 1}`))
+	cfg := NewAsyncConfig(asyncer, "mixed", 0, false, WithAutoDetect())
+	refresh := cfg.Configer.(*asyncConfig).refresh
+	ast.EqualValues(1, cfg.Int("a"))
+
+	asyncer.data.Store("mixed", []byte("a: 2\n"))
+	ast.Nil(refresh())
+	ast.EqualValues(2, cfg.Int("a"))
+
+	// 按最近一次获取的格式写入
+	ast.Nil(cfg.Set("a", 3))
+	v, _ := asyncer.data.Load("mixed")
+	ast.Equal("a: 3\n", string(v.([]byte)))
+
+	asyncer.data.Store("mixed", []byte("a = 4\n"))
+	ast.Nil(refresh())
+	ast.EqualValues(4, cfg.Int("a"))
+
+	// 后端给出的格式优先
+	asyncer.hint = T_PROPERTIES
+	asyncer.data.Store("mixed", []byte("a=5\n"))
+	ast.Nil(refresh())
+	ast.Equal("5", cfg.String("a"))
+
+	// 经过限流包装
+	hinted, ok := NewRateLimitedAsyncer(asyncer, 1000, 10).ContentTypeHint("mixed")
+	ast.True(ok)
+	ast.Equal(T_PROPERTIES, hinted)
+
+	// 未开启时按ContentType解析
+	asyncer.data.Store("plain", []byte("a: 1\n"))
+	ast.Nil(NewAsyncConfig(asyncer, "plain", 0, false).Get("a"))
+}
@@ -0,0 +1,120 @@
+// Package awsasyncer 使用AWS Systems Manager Parameter Store或Secrets Manager作为异步配置的数据源
+//
+// 两者都不支持变化通知，Watch每隔 PollInterval 比较版本号；被限流时按指数退避重试：
+//
+//	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+//	params := awsasyncer.NewParameterStore(ssm.NewFromConfig(awsCfg))
+//	cfg := config.NewAsyncConfig(params, "/billing/", 0, false) // /billing/ 下的所有参数
+//
+//	secrets := awsasyncer.NewSecretsManager(secretsmanager.NewFromConfig(awsCfg))
+//	db := config.NewAsyncConfig(secrets, "prod/billing/db", 0, false)
+package awsasyncer
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/kot-w/config"
+	_logger "github.com/kot-w/logger"
+)
+
+var (
+	// PollInterval Watch检查版本号的间隔，在创建asyncer时确定
+	PollInterval = 30 * time.Second
+	// RequestTimeout 一次请求（包括重试）的超时时间
+	RequestTimeout = 30 * time.Second
+	// MaxAttempts 被限流时的最多尝试次数
+	MaxAttempts = 5
+	// RetryMin 被限流后首次重试的最长等待时间，之后每次翻倍
+	RetryMin = 200 * time.Millisecond
+	// RetryMax 重试等待时间的上限
+	RetryMax = 10 * time.Second
+)
+
+var logger config.Logger = _logger.Named("config.aws")
+
+var throttles = retry.IsErrorThrottles(retry.DefaultThrottles)
+
+// withRetry 执行fn，被限流（ThrottlingException等）时以full jitter指数退避重试，其他错误直接返回
+//
+// SDK客户端自身的重试用尽后仍被限流时由此继续重试，避免大量实例同时启动时读取失败
+func withRetry(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := RetryMin
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= MaxAttempts || throttles.IsErrorThrottle(err) != aws.TrueTernary {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(rand.Int63n(int64(backoff) + 1))):
+		}
+		if backoff *= 2; backoff > RetryMax {
+			backoff = RetryMax
+		}
+	}
+}
+
+// poller 定期调用version，与最近一次Get时的版本不同时通知
+type poller struct {
+	interval    time.Duration
+	versions    sync.Map // key => string，最近一次Get时的版本
+	notifyChans sync.Map // key => chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+func newPoller() *poller {
+	p := &poller{interval: PollInterval}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return p
+}
+
+func (p *poller) watch(key string, version func(ctx context.Context, key string) (string, error)) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := p.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(p.ctx, RequestTimeout)
+			v, err := version(ctx, key)
+			cancel()
+			if err != nil {
+				if p.ctx.Err() == nil {
+					logger.Warnf("poll conf[%s] version err:%v", key, err)
+				}
+				continue
+			}
+
+			if last, loaded := p.versions.LoadOrStore(key, v); loaded && last.(string) != v {
+				p.versions.Store(key, v)
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+func (p *poller) close() {
+	p.cancel()
+}
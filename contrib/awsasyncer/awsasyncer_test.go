@@ -0,0 +1,62 @@
+package awsasyncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRetry(t *testing.T) {
+	ast := assert.New(t)
+
+	RetryMin = time.Millisecond
+	defer func() { RetryMin = 200 * time.Millisecond }()
+
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+
+	// 限流时重试
+	calls := 0
+	err := withRetry(context.Background(), func(ctx context.Context) error {
+		if calls++; calls < 3 {
+			return throttled
+		}
+		return nil
+	})
+	ast.Nil(err)
+	ast.Equal(3, calls)
+
+	// 最多 MaxAttempts 次
+	calls = 0
+	err = withRetry(context.Background(), func(ctx context.Context) error {
+		calls++
+		return throttled
+	})
+	ast.Equal(throttled, err)
+	ast.Equal(MaxAttempts, calls)
+
+	// 其他错误不重试
+	calls = 0
+	other := errors.New("access denied")
+	err = withRetry(context.Background(), func(ctx context.Context) error {
+		calls++
+		return other
+	})
+	ast.Equal(other, err)
+	ast.Equal(1, calls)
+
+	// ctx结束时停止
+	RetryMin = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	err = withRetry(ctx, func(ctx context.Context) error {
+		calls++
+		return throttled
+	})
+	ast.Equal(throttled, err)
+	ast.Equal(1, calls)
+}
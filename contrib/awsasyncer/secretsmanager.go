@@ -0,0 +1,134 @@
+package awsasyncer
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/kot-w/config"
+	"github.com/pkg/errors"
+)
+
+// SecretsManagerAPI *secretsmanager.Client 中用到的方法
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	DescribeSecret(ctx context.Context, params *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+	PutSecretValue(ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error)
+}
+
+var _ SecretsManagerAPI = (*secretsmanager.Client)(nil)
+
+// currentStage 当前版本的staging label
+const currentStage = "AWSCURRENT"
+
+// SecretsManager 以Secrets Manager为数据源的 config.Asyncer
+//
+// key为secret的名称或ARN，内容为SecretString（没有时为SecretBinary），格式根据后缀判断，默认JSON。
+// Watch通过DescribeSecret比较AWSCURRENT的VersionId，不读取secret的值
+type SecretsManager struct {
+	client SecretsManagerAPI
+	poller *poller
+}
+
+var _ config.VersionedSetter = (*SecretsManager)(nil)
+
+func NewSecretsManager(client SecretsManagerAPI) *SecretsManager {
+	return &SecretsManager{client: client, poller: newPoller()}
+}
+
+func (a *SecretsManager) ContentType(key string) config.ContentType {
+	return config.ContentTypeByExt(key)
+}
+
+// Get secret不存在或读取失败时返回nil
+func (a *SecretsManager) Get(key string) []byte {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	var out *secretsmanager.GetSecretValueOutput
+	err := withRetry(ctx, func(ctx context.Context) (err error) {
+		out, err = a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(key)})
+		return err
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		a.poller.versions.Store(key, "")
+		return nil
+	}
+	if err != nil {
+		logger.Errorf("read conf[%s] from secrets manager err:%v", key, err)
+		return nil
+	}
+
+	a.poller.versions.Store(key, aws.ToString(out.VersionId))
+	if out.SecretString != nil {
+		return []byte(*out.SecretString)
+	}
+	return out.SecretBinary
+}
+
+func (a *SecretsManager) Set(key string, value []byte) error {
+	_, err := a.SetVersioned(key, value)
+	return err
+}
+
+// SetVersioned 写入新版本并标记为AWSCURRENT，返回VersionId，secret需已存在
+func (a *SecretsManager) SetVersioned(key string, value []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	var out *secretsmanager.PutSecretValueOutput
+	err := withRetry(ctx, func(ctx context.Context) (err error) {
+		out, err = a.client.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+			SecretId:     aws.String(key),
+			SecretString: aws.String(string(value)),
+		})
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "put secret value[%s]", key)
+	}
+	return aws.ToString(out.VersionId), nil
+}
+
+// version 返回AWSCURRENT的VersionId，secret不存在时为空
+func (a *SecretsManager) version(ctx context.Context, key string) (string, error) {
+	var out *secretsmanager.DescribeSecretOutput
+	err := withRetry(ctx, func(ctx context.Context) (err error) {
+		out, err = a.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(key)})
+		return err
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for id, stages := range out.VersionIdsToStages {
+		for _, stage := range stages {
+			if stage == currentStage {
+				return id, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// Watch 每隔 PollInterval 比较AWSCURRENT的VersionId，轮换或写入新版本时通知
+func (a *SecretsManager) Watch(key string) chan struct{} {
+	return a.poller.watch(key, a.version)
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *SecretsManager) Capabilities() config.Capabilities {
+	return config.Capabilities{Watch: true, Version: true}
+}
+
+// Close 停止所有Watch
+func (a *SecretsManager) Close() error {
+	a.poller.close()
+	return nil
+}
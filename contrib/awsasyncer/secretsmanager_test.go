@@ -0,0 +1,95 @@
+package awsasyncer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSecretsManager 内存中的Secrets Manager，只保留AWSCURRENT版本
+type fakeSecretsManager struct {
+	sync.Mutex
+	values    map[string]string
+	versions  map[string]int
+	describes int
+}
+
+func newFakeSecretsManager() *fakeSecretsManager {
+	return &fakeSecretsManager{values: make(map[string]string), versions: make(map[string]int)}
+}
+
+func (c *fakeSecretsManager) GetSecretValue(ctx context.Context, in *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	id := aws.ToString(in.SecretId)
+	v, ok := c.values[id]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{}
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(v), VersionId: aws.String(strconv.Itoa(c.versions[id]))}, nil
+}
+
+func (c *fakeSecretsManager) DescribeSecret(ctx context.Context, in *secretsmanager.DescribeSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.describes++
+
+	id := aws.ToString(in.SecretId)
+	if _, ok := c.values[id]; !ok {
+		return nil, &types.ResourceNotFoundException{}
+	}
+	return &secretsmanager.DescribeSecretOutput{VersionIdsToStages: map[string][]string{
+		strconv.Itoa(c.versions[id]):     {currentStage},
+		strconv.Itoa(c.versions[id] - 1): {"AWSPREVIOUS"},
+	}}, nil
+}
+
+func (c *fakeSecretsManager) PutSecretValue(ctx context.Context, in *secretsmanager.PutSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	id := aws.ToString(in.SecretId)
+	c.values[id] = aws.ToString(in.SecretString)
+	c.versions[id]++
+	return &secretsmanager.PutSecretValueOutput{VersionId: aws.String(strconv.Itoa(c.versions[id]))}, nil
+}
+
+func TestSecretsManager(t *testing.T) {
+	ast := assert.New(t)
+
+	PollInterval = 10 * time.Millisecond
+	defer func() { PollInterval = 30 * time.Second }()
+
+	client := newFakeSecretsManager()
+	a := NewSecretsManager(client)
+	defer a.Close()
+
+	ast.Nil(a.Get("prod/db"))
+	version, err := a.SetVersioned("prod/db", []byte(`{"password": "p1"}`))
+	ast.Nil(err)
+	ast.Equal("1", version)
+	ast.Equal(`{"password": "p1"}`, string(a.Get("prod/db")))
+	ast.Equal(config.T_JSON, a.ContentType("prod/db"))
+	ast.Equal(config.Capabilities{Watch: true, Version: true}, config.ProbeCapabilities(a))
+
+	// 轮换后通知
+	cfg := config.NewAsyncConfig(a, "prod/db", 0, false)
+	ast.Equal("p1", cfg.String("password"))
+	ast.Nil(a.Set("prod/db", []byte(`{"password": "p2"}`)))
+	ast.Eventually(func() bool {
+		return cfg.String("password") == "p2"
+	}, time.Second, 5*time.Millisecond)
+
+	client.Lock()
+	ast.Greater(client.describes, 0)
+	client.Unlock()
+}
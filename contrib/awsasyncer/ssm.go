@@ -0,0 +1,251 @@
+package awsasyncer
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/kot-w/config"
+	"github.com/pkg/errors"
+)
+
+// SSMAPI *ssm.Client 中用到的方法
+type SSMAPI interface {
+	ssm.GetParametersByPathAPIClient
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+	PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+}
+
+var _ SSMAPI = (*ssm.Client)(nil)
+
+// ParameterStore 以Parameter Store为数据源的 config.Asyncer
+//
+// key以 "/" 结尾时读取该路径下的所有参数（GetParametersByPath，递归、解密、分页），
+// 按路径组成JSON对象，如 /billing/db/host 在key为 /billing/ 时为 {"db": {"host": ...}}；
+// 否则为单个参数，内容为参数的值，格式根据后缀判断
+type ParameterStore struct {
+	client SSMAPI
+	poller *poller
+}
+
+var _ config.VersionedSetter = (*ParameterStore)(nil)
+
+func NewParameterStore(client SSMAPI) *ParameterStore {
+	return &ParameterStore{client: client, poller: newPoller()}
+}
+
+func isPath(key string) bool {
+	return strings.HasSuffix(key, "/")
+}
+
+// ContentType 路径为JSON，单个参数根据后缀判断
+func (a *ParameterStore) ContentType(key string) config.ContentType {
+	if isPath(key) {
+		return config.T_JSON
+	}
+	return config.ContentTypeByExt(key)
+}
+
+// parameters 返回路径下的所有参数
+func (a *ParameterStore) parameters(ctx context.Context, path string) ([]types.Parameter, error) {
+	var params []types.Parameter
+	paginator := ssm.NewGetParametersByPathPaginator(a.client, &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		var page *ssm.GetParametersByPathOutput
+		err := withRetry(ctx, func(ctx context.Context) (err error) {
+			page, err = paginator.NextPage(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "get parameters by path[%s]", path)
+		}
+		params = append(params, page.Parameters...)
+	}
+	return params, nil
+}
+
+// parameter 参数不存在时返回nil
+func (a *ParameterStore) parameter(ctx context.Context, name string) (*types.Parameter, error) {
+	var out *ssm.GetParameterOutput
+	err := withRetry(ctx, func(ctx context.Context) (err error) {
+		out, err = a.client.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(name),
+			WithDecryption: aws.Bool(true),
+		})
+		return err
+	})
+	var notFound *types.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "get parameter[%s]", name)
+	}
+	return out.Parameter, nil
+}
+
+// pathVersion 路径下所有参数的名称及版本号
+func pathVersion(params []types.Parameter) string {
+	versions := make([]string, len(params))
+	for i, p := range params {
+		versions[i] = aws.ToString(p.Name) + "@" + strconv.FormatInt(p.Version, 10)
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ",")
+}
+
+// tree 按相对path的路径组成嵌套的map
+func tree(path string, params []types.Parameter) map[string]interface{} {
+	root := make(map[string]interface{})
+	for _, p := range params {
+		segments := strings.Split(strings.TrimPrefix(aws.ToString(p.Name), path), "/")
+		node := root
+		for _, seg := range segments[:len(segments)-1] {
+			child, ok := node[seg].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[seg] = child
+			}
+			node = child
+		}
+		// 同时存在 /a 与 /a/b 时保留子节点
+		if _, ok := node[segments[len(segments)-1]].(map[string]interface{}); !ok {
+			node[segments[len(segments)-1]] = aws.ToString(p.Value)
+		}
+	}
+	return root
+}
+
+func (a *ParameterStore) read(ctx context.Context, key string) ([]byte, string, error) {
+	if !isPath(key) {
+		p, err := a.parameter(ctx, key)
+		if err != nil || p == nil {
+			return nil, "", err
+		}
+		return []byte(aws.ToString(p.Value)), strconv.FormatInt(p.Version, 10), nil
+	}
+
+	params, err := a.parameters(ctx, key)
+	if err != nil || len(params) == 0 {
+		return nil, "", err
+	}
+	data, err := json.Marshal(tree(key, params))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, pathVersion(params), nil
+}
+
+// Get 参数不存在或读取失败时返回nil
+func (a *ParameterStore) Get(key string) []byte {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	data, version, err := a.read(ctx, key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from parameter store err:%v", key, err)
+		return nil
+	}
+	a.poller.versions.Store(key, version)
+	return data
+}
+
+func (a *ParameterStore) Set(key string, value []byte) error {
+	_, err := a.SetVersioned(key, value)
+	return err
+}
+
+// put 参数不存在时创建为String类型，已存在时保留原类型
+func (a *ParameterStore) put(ctx context.Context, name, value string) (int64, error) {
+	input := &ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(value),
+		Overwrite: aws.Bool(true),
+	}
+	if p, err := a.parameter(ctx, name); err != nil {
+		return 0, err
+	} else if p == nil {
+		input.Type = types.ParameterTypeString
+	}
+
+	var out *ssm.PutParameterOutput
+	err := withRetry(ctx, func(ctx context.Context) (err error) {
+		out, err = a.client.PutParameter(ctx, input)
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "put parameter[%s]", name)
+	}
+	return out.Version, nil
+}
+
+// SetVersioned 单个参数时返回写入后的版本号
+//
+// key为路径时value需为JSON对象，每个叶子节点写入为一个参数，不在value中的参数不会删除，版本号为空
+func (a *ParameterStore) SetVersioned(key string, value []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	if !isPath(key) {
+		version, err := a.put(ctx, key, string(value))
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(version, 10), nil
+	}
+
+	var root map[string]interface{}
+	if err := json.Unmarshal(value, &root); err != nil {
+		return "", errors.Wrapf(err, "parameters[%s] require a JSON object", key)
+	}
+	leaves := make(map[string]string)
+	flatten(strings.TrimSuffix(key, "/"), root, leaves)
+	for name, v := range leaves {
+		if _, err := a.put(ctx, name, v); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func flatten(prefix string, v interface{}, leaves map[string]string) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range vv {
+			flatten(prefix+"/"+k, sub, leaves)
+		}
+	case string:
+		leaves[prefix] = vv
+	default:
+		data, _ := json.Marshal(vv)
+		leaves[prefix] = string(data)
+	}
+}
+
+// Watch 每隔 PollInterval 比较版本号，路径下的参数新增、修改、删除时通知
+func (a *ParameterStore) Watch(key string) chan struct{} {
+	return a.poller.watch(key, func(ctx context.Context, key string) (string, error) {
+		_, version, err := a.read(ctx, key)
+		return version, err
+	})
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *ParameterStore) Capabilities() config.Capabilities {
+	return config.Capabilities{Watch: true, Version: true}
+}
+
+// Close 停止所有Watch
+func (a *ParameterStore) Close() error {
+	a.poller.close()
+	return nil
+}
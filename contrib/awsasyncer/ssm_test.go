@@ -0,0 +1,150 @@
+package awsasyncer
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSSM 内存中的Parameter Store，每页最多2个参数
+type fakeSSM struct {
+	sync.Mutex
+	params    map[string]*types.Parameter
+	pages     int // GetParametersByPath 的调用次数
+	throttled int // 接下来被限流的请求数
+}
+
+func newFakeSSM() *fakeSSM {
+	return &fakeSSM{params: make(map[string]*types.Parameter)}
+}
+
+func (c *fakeSSM) throttle() error {
+	if c.throttled > 0 {
+		c.throttled--
+		return &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	}
+	return nil
+}
+
+func (c *fakeSSM) GetParametersByPath(ctx context.Context, in *ssm.GetParametersByPathInput, _ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.throttle(); err != nil {
+		return nil, err
+	}
+	c.pages++
+
+	var names []string
+	for name := range c.params {
+		if strings.HasPrefix(name, aws.ToString(in.Path)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	start, _ := strconv.Atoi(aws.ToString(in.NextToken))
+	out := &ssm.GetParametersByPathOutput{}
+	for i := start; i < len(names) && i < start+2; i++ {
+		out.Parameters = append(out.Parameters, *c.params[names[i]])
+	}
+	if start+2 < len(names) {
+		out.NextToken = aws.String(strconv.Itoa(start + 2))
+	}
+	return out, nil
+}
+
+func (c *fakeSSM) GetParameter(ctx context.Context, in *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.throttle(); err != nil {
+		return nil, err
+	}
+
+	p, ok := c.params[aws.ToString(in.Name)]
+	if !ok {
+		return nil, &types.ParameterNotFound{}
+	}
+	cp := *p
+	return &ssm.GetParameterOutput{Parameter: &cp}, nil
+}
+
+func (c *fakeSSM) PutParameter(ctx context.Context, in *ssm.PutParameterInput, _ ...func(*ssm.Options)) (*ssm.PutParameterOutput, error) {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.throttle(); err != nil {
+		return nil, err
+	}
+
+	name := aws.ToString(in.Name)
+	p, ok := c.params[name]
+	if !ok {
+		if in.Type == "" {
+			return nil, &smithy.GenericAPIError{Code: "ValidationException", Message: "type required"}
+		}
+		p = &types.Parameter{Name: in.Name, Type: in.Type}
+		c.params[name] = p
+	}
+	p.Value = in.Value
+	p.Version++
+	return &ssm.PutParameterOutput{Version: p.Version}, nil
+}
+
+func TestParameterStore(t *testing.T) {
+	ast := assert.New(t)
+
+	PollInterval = 10 * time.Millisecond
+	defer func() { PollInterval = 30 * time.Second }()
+
+	client := newFakeSSM()
+	a := NewParameterStore(client)
+	defer a.Close()
+
+	ast.Nil(a.Get("/billing/"))
+	ast.Nil(a.Get("/billing/app.yaml"))
+
+	// 路径
+	ast.Nil(a.Set("/billing/", []byte(`{"db": {"host": "h", "port": 3306}, "name": "billing", "debug": true}`)))
+	ast.Equal(types.ParameterTypeString, client.params["/billing/db/host"].Type)
+	ast.Equal("3306", aws.ToString(client.params["/billing/db/port"].Value))
+	client.pages = 0
+	ast.JSONEq(`{"db": {"host": "h", "port": "3306"}, "name": "billing", "debug": "true"}`, string(a.Get("/billing/")))
+	ast.Equal(2, client.pages, "paginated")
+	ast.Equal(config.T_JSON, a.ContentType("/billing/"))
+
+	// 单个参数
+	version, err := a.SetVersioned("/billing/app.yaml", []byte("a: 1"))
+	ast.Nil(err)
+	ast.Equal("1", version)
+	// 已存在时保留类型
+	client.params["/billing/app.yaml"].Type = types.ParameterTypeSecureString
+	version, err = a.SetVersioned("/billing/app.yaml", []byte("a: 2"))
+	ast.Nil(err)
+	ast.Equal("2", version)
+	ast.Equal(types.ParameterTypeSecureString, client.params["/billing/app.yaml"].Type)
+	ast.Equal("a: 2", string(a.Get("/billing/app.yaml")))
+	ast.Equal(config.T_YAML, a.ContentType("/billing/app.yaml"))
+
+	cfg := config.NewAsyncConfig(a, "/billing/", 0, false)
+	ast.Equal("h", cfg.String("db.host"))
+	ast.Nil(a.Set("/billing/db/host", []byte("h2")))
+	ast.Eventually(func() bool {
+		return cfg.String("db.host") == "h2"
+	}, time.Second, 5*time.Millisecond)
+
+	// 被限流时重试
+	client.Lock()
+	client.throttled = 2
+	client.Unlock()
+	ast.Equal("a: 2", string(a.Get("/billing/app.yaml")))
+}
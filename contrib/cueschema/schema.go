@@ -0,0 +1,103 @@
+// Package cueschema 基于CUE的 config.Schema 实现
+//
+// 一份CUE定义同时描述配置的结构、约束及默认值：
+//
+//	#Config: {
+//		db: {
+//			host: string
+//			port: int & >0 & <65536 | *3306
+//		}
+//		timeout: *"3s" | string
+//	}
+//
+//	schema, err := cueschema.New(src, "#Config")
+//	cfg := config.NewAsyncConfig(asyncer, key, cacheTime, false, config.WithSchema(schema))
+package cueschema
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/errors"
+	"github.com/kot-w/config"
+)
+
+type Schema struct {
+	sync.Mutex
+	ctx    *cue.Context
+	schema cue.Value
+	prefix []string // schema定义的路径，从字段路径中去除
+}
+
+// New 编译CUE定义
+//
+// path 指定作为schema的定义（如 "#Config"），未指定时使用整个文档
+func New(source string, path ...string) (*Schema, error) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(source)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	var prefix []string
+	if len(path) > 0 && path[0] != "" {
+		p := cue.ParsePath(path[0])
+		v = v.LookupPath(p)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+		for _, sel := range p.Selectors() {
+			prefix = append(prefix, sel.String())
+		}
+	}
+
+	return &Schema{
+		ctx:    ctx,
+		schema: v,
+		prefix: prefix,
+	}, nil
+}
+
+// Apply 校验配置并填充默认值
+func (s *Schema) Apply(value interface{}) (interface{}, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	data := s.ctx.Encode(value)
+	if err := data.Err(); err != nil {
+		return nil, err
+	}
+
+	unified := s.schema.Unify(data)
+	if err := unified.Validate(cue.Concrete(true), cue.Final()); err != nil {
+		return nil, s.validationError(err)
+	}
+
+	var ret interface{}
+	if err := unified.Decode(&ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// validationError 转换为带字段详情的 config.ValidationError
+func (s *Schema) validationError(err error) error {
+	verr := &config.ValidationError{Err: err}
+	for _, e := range errors.Errors(err) {
+		path := e.Path()
+		if len(path) >= len(s.prefix) && strings.Join(path[:len(s.prefix)], ".") == strings.Join(s.prefix, ".") {
+			path = path[len(s.prefix):]
+		}
+
+		format, args := e.Msg()
+		verr.Fields = append(verr.Fields, config.FieldError{
+			Field:   strings.Join(path, "."),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	return verr
+}
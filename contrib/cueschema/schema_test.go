@@ -0,0 +1,85 @@
+package cueschema
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kot-w/config"
+)
+
+const testSchema = `
+#Config: {
+	db: {
+		host: string
+		port: int & >0 & <65536 | *3306
+	}
+	timeout: *"3s" | string
+	...
+}
+`
+
+func TestSchema(t *testing.T) {
+	ast := assert.New(t)
+
+	schema, err := New(testSchema, "#Config")
+	ast.Nil(err)
+
+	v, err := schema.Apply(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host": "example.com",
+		},
+		"extra": true,
+	})
+	ast.Nil(err)
+	cfg := config.NewMapConfig(v.(map[string]interface{}))
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Equal(int64(3306), cfg.Int("db.port"))
+	ast.Equal("3s", cfg.String("timeout"))
+	ast.True(cfg.Bool("extra"))
+
+	_, err = schema.Apply(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host": "example.com",
+			"port": 70000,
+		},
+	})
+	ast.True(errors.Is(err, config.ErrValidation))
+	var verr *config.ValidationError
+	ast.True(errors.As(err, &verr))
+	if ast.NotEmpty(verr.Fields) {
+		ast.Equal("db.port", verr.Fields[0].Field)
+	}
+
+	// missing required field
+	_, err = schema.Apply(map[string]interface{}{})
+	ast.NotNil(err)
+
+	_, err = New(`a: `)
+	ast.NotNil(err)
+}
+
+func TestAsyncConfigWithSchema(t *testing.T) {
+	ast := assert.New(t)
+
+	schema, err := New(testSchema, "#Config")
+	ast.Nil(err)
+
+	asyncer := config.NewMockAsyncer(false)
+	key := "cue_schema_key"
+	asyncer.Set(key, []byte(`{"db": {"host": "example.com"}}`))
+
+	cfg := config.NewAsyncConfig(asyncer, key, time.Millisecond, false, config.WithSchema(schema))
+	ast.Equal(int64(3306), cfg.Int("db.port"))
+
+	// invalid content keep the old value
+	asyncer.Set(key, []byte(`{"db": {"host": 1}}`))
+	time.Sleep(2 * time.Millisecond)
+	ast.Equal("example.com", cfg.String("db.host"))
+
+	ast.NotNil(cfg.Set("db.port", "not_int"))
+	ast.Nil(cfg.Set("db.port", 3307))
+	ast.Equal(int64(3307), cfg.Int("db.port"))
+}
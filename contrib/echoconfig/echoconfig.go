@@ -0,0 +1,50 @@
+// Package echoconfig 为echo提供配置快照中间件及配置查看接口
+package echoconfig
+
+import (
+	"github.com/kot-w/config"
+	"github.com/labstack/echo/v4"
+)
+
+// ContextKey 快照在echo.Context中的key
+const ContextKey = "config.snapshot"
+
+// Snapshot 为每个请求创建配置快照，同时写入echo.Context及Request.Context
+func Snapshot(cfg config.Configer) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			snap := config.Snapshot(cfg)
+			c.Set(ContextKey, snap)
+			c.SetRequest(c.Request().WithContext(config.ContextWithSnapshot(c.Request().Context(), snap)))
+			return next(c)
+		}
+	}
+}
+
+// FromContext 获取请求的配置快照，未使用 Snapshot 中间件时返回nil
+func FromContext(c echo.Context) *config.SnapshotConfig {
+	if snap, ok := c.Get(ContextKey).(*config.SnapshotConfig); ok {
+		return snap
+	}
+	return config.SnapshotFromContext(c.Request().Context())
+}
+
+// Router echo.Echo 及 echo.Group 共有的路由方法
+type Router interface {
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// Mount 在path下挂载配置查看接口，见 config.NewAdminHandler
+//
+//	GET path        全部配置
+//	GET path?key=   指定配置
+//	GET path/keys   配置key列表
+//	GET path/dump   树形文本格式
+//	GET path/openapi.json 以上接口的OpenAPI文档
+func Mount(r Router, path string, cfg config.Configer, maskKeyPaths ...string) {
+	h := echo.WrapHandler(config.NewAdminHandler(cfg, maskKeyPaths...))
+	r.GET(path, h)
+	r.GET(path+"/keys", h)
+	r.GET(path+"/dump", h)
+	r.GET(path+"/openapi.json", h)
+}
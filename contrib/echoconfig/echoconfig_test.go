@@ -0,0 +1,45 @@
+package echoconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kot-w/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEcho(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := config.NewMapConfig(map[string]interface{}{"name": "app", "password": "secret"})
+
+	e := echo.New()
+	e.Use(Snapshot(cfg))
+	e.GET("/hello", func(c echo.Context) error {
+		ast.Equal(FromContext(c), config.SnapshotFromContext(c.Request().Context()))
+		return c.String(http.StatusOK, FromContext(c).String("name"))
+	})
+	Mount(e.Group("/debug"), "/config", cfg, "password")
+
+	do := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	ast.Equal("app", do("/hello").Body.String())
+
+	var m map[string]interface{}
+	ast.Nil(json.Unmarshal(do("/debug/config").Body.Bytes(), &m))
+	ast.Equal(map[string]interface{}{"name": "app", "password": config.AdminMaskValue}, m)
+
+	ast.Equal("\"app\"\n", do("/debug/config?key=name").Body.String())
+
+	var keys []string
+	ast.Nil(json.Unmarshal(do("/debug/config/keys").Body.Bytes(), &keys))
+	ast.Equal([]string{"name", "password"}, keys)
+	ast.Contains(do("/debug/config/openapi.json").Body.String(), `"url": "/debug/config"`)
+}
@@ -0,0 +1,282 @@
+// Package etcdasyncer 使用etcd v3作为异步配置的数据源
+//
+// 通过watch stream监听key的变化，stream关闭（网络中断、leader切换等）后自动重连，
+// 并从最后收到的revision继续监听，不会丢失期间的变化：
+//
+//	asyncer, err := etcdasyncer.NewForEndpoints([]string{"127.0.0.1:2379"}, "/config/")
+//	cfg := config.NewAsyncConfig(asyncer, "billing.json", 0, false) // key为 /config/billing.json
+package etcdasyncer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kot-w/config"
+	_logger "github.com/kot-w/logger"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	// RequestTimeout 单次读写请求的超时时间
+	RequestTimeout = 5 * time.Second
+	// DialTimeout NewForEndpoints 建立连接的超时时间
+	DialTimeout = 5 * time.Second
+	// ReconnectMin watch stream关闭后首次重连的等待时间，之后每次翻倍
+	ReconnectMin = 100 * time.Millisecond
+	// ReconnectMax 重连等待时间的上限
+	ReconnectMax = 30 * time.Second
+)
+
+var logger config.Logger = _logger.Named("config.etcd")
+
+// Asyncer 以etcd为数据源的 config.Asyncer
+type Asyncer struct {
+	kv      clientv3.KV
+	watcher clientv3.Watcher
+	lease   clientv3.Lease
+	prefix  string
+	client  *clientv3.Client // NewForEndpoints 创建的client，Close时关闭
+
+	revisions   sync.Map // key => int64，最近一次Get时的store revision
+	notifyChans sync.Map // key => chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+var (
+	_ config.Asyncer         = (*Asyncer)(nil)
+	_ config.CASSetter       = (*Asyncer)(nil)
+	_ config.VersionedSetter = (*Asyncer)(nil)
+	_ config.TTLSetter       = (*Asyncer)(nil)
+	_ config.PrefixLister    = (*Asyncer)(nil)
+)
+
+// NewForEndpoints 连接etcd集群，见 New
+func NewForEndpoints(endpoints []string, prefix string) (*Asyncer, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: DialTimeout,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "create etcd client")
+	}
+
+	a := New(client, prefix)
+	a.client = client
+	return a, nil
+}
+
+// New 使用已有的client，prefix为所有key的前缀，Close不关闭client
+func New(client *clientv3.Client, prefix string) *Asyncer {
+	return NewWithKV(client.KV, client.Watcher, client.Lease, prefix)
+}
+
+// NewWithKV 使用指定的KV、Watcher及Lease（如namespace包装后的client），lease为nil时不支持TTL
+func NewWithKV(kv clientv3.KV, watcher clientv3.Watcher, lease clientv3.Lease, prefix string) *Asyncer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Asyncer{
+		kv:      kv,
+		watcher: watcher,
+		lease:   lease,
+		prefix:  prefix,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// ContentType 根据key的后缀判断，见 config.ContentTypeByExt
+func (a *Asyncer) ContentType(key string) config.ContentType {
+	return config.ContentTypeByExt(key)
+}
+
+func (a *Asyncer) timeout() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(a.ctx, RequestTimeout)
+}
+
+// Get key不存在或读取失败时返回nil
+func (a *Asyncer) Get(key string) []byte {
+	ctx, cancel := a.timeout()
+	defer cancel()
+
+	resp, err := a.kv.Get(ctx, a.prefix+key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from etcd err:%v", key, err)
+		return nil
+	}
+	a.revisions.Store(key, resp.Header.Revision)
+
+	if len(resp.Kvs) == 0 {
+		return nil
+	}
+	return resp.Kvs[0].Value
+}
+
+func (a *Asyncer) Set(key string, value []byte) error {
+	_, err := a.SetVersioned(key, value)
+	return err
+}
+
+// SetVersioned 返回写入后的revision，见 config.VersionedSetter
+func (a *Asyncer) SetVersioned(key string, value []byte) (string, error) {
+	ctx, cancel := a.timeout()
+	defer cancel()
+
+	resp, err := a.kv.Put(ctx, a.prefix+key, string(value))
+	if err != nil {
+		return "", errors.Wrapf(err, "put etcd[%s]", key)
+	}
+	return strconv.FormatInt(resp.Header.Revision, 10), nil
+}
+
+// CompareAndSet 见 config.CASSetter，old为nil时要求key不存在
+func (a *Asyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	ctx, cancel := a.timeout()
+	defer cancel()
+
+	fullKey := a.prefix + key
+	cmp := clientv3.Compare(clientv3.Value(fullKey), "=", string(old))
+	if old == nil {
+		cmp = clientv3.Compare(clientv3.CreateRevision(fullKey), "=", 0)
+	}
+
+	resp, err := a.kv.Txn(ctx).If(cmp).Then(clientv3.OpPut(fullKey, string(value))).Commit()
+	if err != nil {
+		return false, errors.Wrapf(err, "cas etcd[%s]", key)
+	}
+	return resp.Succeeded, nil
+}
+
+// SetWithTTL 写入并绑定到新的lease，lease过期后key被删除，见 config.TTLSetter
+func (a *Asyncer) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	if a.lease == nil {
+		return errors.New("etcd lease not supported")
+	}
+
+	ctx, cancel := a.timeout()
+	defer cancel()
+
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	grant, err := a.lease.Grant(ctx, seconds)
+	if err != nil {
+		return errors.Wrapf(err, "grant etcd lease[%s]", key)
+	}
+	if _, err = a.kv.Put(ctx, a.prefix+key, string(value), clientv3.WithLease(grant.ID)); err != nil {
+		return errors.Wrapf(err, "put etcd[%s]", key)
+	}
+	return nil
+}
+
+// ListPrefix 返回前缀下所有的key（不含 New 的prefix）及内容，见 config.PrefixLister
+func (a *Asyncer) ListPrefix(prefix string) (map[string][]byte, error) {
+	ctx, cancel := a.timeout()
+	defer cancel()
+
+	resp, err := a.kv.Get(ctx, a.prefix+prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Wrapf(err, "list etcd[%s]", prefix)
+	}
+
+	result := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		result[string(kv.Key)[len(a.prefix):]] = kv.Value
+	}
+	return result, nil
+}
+
+// Watch key变化（包括删除）时通知，同一key只建立一个watch stream
+//
+// 从最近一次Get的revision之后开始监听，Get与Watch之间的变化也会通知
+func (a *Asyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	var rev int64
+	if v, ok := a.revisions.Load(key); ok {
+		rev = v.(int64)
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.watch(key, rev, ch)
+	}()
+	return ch
+}
+
+// watch 监听直到Close，rev为已读取到的revision，0 表示从当前revision开始
+func (a *Asyncer) watch(key string, rev int64, ch chan struct{}) {
+	backoff := ReconnectMin
+	for {
+		opts := make([]clientv3.OpOption, 0, 1)
+		if rev > 0 {
+			opts = append(opts, clientv3.WithRev(rev+1))
+		}
+
+		wch := a.watcher.Watch(clientv3.WithRequireLeader(a.ctx), a.prefix+key, opts...)
+		for resp := range wch {
+			if resp.CompactRevision > 0 {
+				// 需要的revision已被压缩，无法得知期间的变化，通知重新读取
+				logger.Warnf("etcd watch[%s] compacted at %d", key, resp.CompactRevision)
+				rev = 0
+				notify(ch)
+				break
+			}
+			if err := resp.Err(); err != nil {
+				logger.Warnf("etcd watch[%s] err:%v", key, err)
+				break
+			}
+
+			backoff = ReconnectMin
+			if n := len(resp.Events); n > 0 {
+				rev = resp.Events[n-1].Kv.ModRevision
+				notify(ch)
+			}
+		}
+
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		logger.Infof("etcd watch[%s] reconnect from revision %d", key, rev)
+		if backoff *= 2; backoff > ReconnectMax {
+			backoff = ReconnectMax
+		}
+	}
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *Asyncer) Capabilities() config.Capabilities {
+	return config.Capabilities{
+		Watch:   true,
+		CAS:     true,
+		List:    true,
+		TTL:     a.lease != nil,
+		Version: true,
+	}
+}
+
+// Close 停止所有watch，NewForEndpoints 创建时关闭client
+func (a *Asyncer) Close() error {
+	a.cancel()
+	a.wg.Wait()
+
+	if a.client != nil {
+		return a.client.Close()
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package etcdasyncer
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcd 内存中的etcd，只实现测试用到的KV及Watcher方法
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Watcher
+	clientv3.Lease
+
+	mu        sync.Mutex
+	rev       int64
+	compacted int64
+	data      map[string]*mvccpb.KeyValue
+	history   []*clientv3.Event
+	watches   []*fakeWatch
+	watchRevs []int64 // 每次Watch的起始revision
+	leases    int
+}
+
+type fakeWatch struct {
+	key string
+	ch  chan clientv3.WatchResponse
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{data: make(map[string]*mvccpb.KeyValue)}
+}
+
+func (f *fakeEtcd) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{Revision: f.rev}
+}
+
+func (f *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resp := &clientv3.GetResponse{Header: f.header()}
+	op := clientv3.OpGet(key, opts...)
+	for k, kv := range f.data {
+		if k == key || (len(op.RangeBytes()) > 0 && bytes.HasPrefix([]byte(k), []byte(key))) {
+			resp.Kvs = append(resp.Kvs, kv)
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeEtcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.put(key, val)
+	return &clientv3.PutResponse{Header: f.header()}, nil
+}
+
+func (f *fakeEtcd) put(key, val string) {
+	f.rev++
+	kv := &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val), ModRevision: f.rev, CreateRevision: f.rev}
+	if old, ok := f.data[key]; ok {
+		kv.CreateRevision = old.CreateRevision
+	}
+	f.data[key] = kv
+
+	ev := &clientv3.Event{Type: mvccpb.PUT, Kv: kv}
+	f.history = append(f.history, ev)
+	for _, w := range f.watches {
+		if w.key == key {
+			w.ch <- clientv3.WatchResponse{Events: []*clientv3.Event{ev}}
+		}
+	}
+}
+
+func (f *fakeEtcd) Txn(ctx context.Context) clientv3.Txn {
+	return &fakeTxn{f: f}
+}
+
+func (f *fakeEtcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rev := clientv3.OpGet(key, opts...).Rev()
+	f.watchRevs = append(f.watchRevs, rev)
+
+	ch := make(chan clientv3.WatchResponse, 100)
+	if rev > 0 && rev <= f.compacted {
+		ch <- clientv3.WatchResponse{CompactRevision: f.compacted}
+		close(ch)
+		return ch
+	}
+	for _, ev := range f.history {
+		if rev > 0 && ev.Kv.ModRevision >= rev && string(ev.Kv.Key) == key {
+			ch <- clientv3.WatchResponse{Events: []*clientv3.Event{ev}}
+		}
+	}
+
+	w := &fakeWatch{key: key, ch: ch}
+	f.watches = append(f.watches, w)
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.closeWatch(w)
+	}()
+	return ch
+}
+
+func (f *fakeEtcd) closeWatch(w *fakeWatch) {
+	for i, o := range f.watches {
+		if o == w {
+			f.watches = append(f.watches[:i], f.watches[i+1:]...)
+			close(w.ch)
+			return
+		}
+	}
+}
+
+// disconnect 关闭所有watch stream，模拟连接中断
+func (f *fakeEtcd) disconnect() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.watches) > 0 {
+		f.closeWatch(f.watches[0])
+	}
+}
+
+func (f *fakeEtcd) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.leases++
+	return &clientv3.LeaseGrantResponse{ID: clientv3.LeaseID(f.leases), TTL: ttl}, nil
+}
+
+func (f *fakeEtcd) Close() error {
+	return nil
+}
+
+func (f *fakeEtcd) watchStarts() []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64(nil), f.watchRevs...)
+}
+
+type fakeTxn struct {
+	f    *fakeEtcd
+	cmps []clientv3.Cmp
+	ops  []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmps = cs
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.ops = ops
+	return t
+}
+
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	ok := true
+	for _, c := range t.cmps {
+		kv := t.f.data[string(c.Key)]
+		switch c.Target {
+		case pb.Compare_VALUE:
+			ok = ok && kv != nil && bytes.Equal(kv.Value, c.ValueBytes())
+		case pb.Compare_CREATE:
+			ok = ok && kv == nil
+		}
+	}
+	if ok {
+		for _, op := range t.ops {
+			t.f.put(string(op.KeyBytes()), string(op.ValueBytes()))
+		}
+	}
+	return &clientv3.TxnResponse{Header: t.f.header(), Succeeded: ok}, nil
+}
+
+func waitNotify(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	f := newFakeEtcd()
+	a := NewWithKV(f, f, f, "/config/")
+	defer a.Close()
+
+	ast.Nil(a.Get("app.json"))
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	ast.Equal(`{"a": 1}`, string(a.Get("app.json")))
+	ast.Equal(`{"a": 1}`, string(f.data["/config/app.json"].Value))
+
+	version, err := a.SetVersioned("app.json", []byte(`{"a": 2}`))
+	ast.Nil(err)
+	ast.Equal("2", version)
+
+	ok, err := a.CompareAndSet("app.json", []byte(`{"a": 1}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("app.json", []byte(`{"a": 2}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("new.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("new.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.False(ok)
+
+	ast.Nil(a.SetWithTTL("tmp.json", []byte(`{}`), 1500*time.Millisecond))
+	ast.Equal(1, f.leases)
+
+	list, err := a.ListPrefix("")
+	ast.Nil(err)
+	ast.Len(list, 3)
+	ast.Equal(`{"a": 3}`, string(list["app.json"]))
+
+	ast.Equal(config.T_YAML, a.ContentType("app.yaml"))
+	ast.Equal(config.Capabilities{Watch: true, CAS: true, List: true, TTL: true, Version: true}, config.ProbeCapabilities(a))
+
+	cfg := config.NewAsyncConfig(a, "app.json", 0, false)
+	ast.EqualValues(3, cfg.Int("a"))
+}
+
+func TestWatchReconnect(t *testing.T) {
+	ast := assert.New(t)
+
+	ReconnectMin = 10 * time.Millisecond
+	defer func() { ReconnectMin = 100 * time.Millisecond }()
+
+	f := newFakeEtcd()
+	a := NewWithKV(f, f, nil, "")
+
+	ast.Nil(a.Set("k", []byte("1")))
+	a.Get("k")
+	// Get之后Watch之前的变化
+	ast.Nil(a.Set("k", []byte("2")))
+
+	ch := a.Watch("k")
+	ast.Equal(ch, a.Watch("k"))
+	ast.True(waitNotify(ch))
+	ast.Equal([]int64{2}, f.watchStarts())
+
+	// 断开期间的变化在重连后补发
+	f.disconnect()
+	ast.Nil(a.Set("k", []byte("3")))
+	ast.True(waitNotify(ch))
+	ast.Equal([]int64{2, 3}, f.watchStarts())
+
+	// revision已被压缩时通知重新读取，从当前revision开始监听
+	f.mu.Lock()
+	f.compacted = 10
+	f.mu.Unlock()
+	f.disconnect()
+	ast.True(waitNotify(ch))
+	ast.Eventually(func() bool {
+		revs := f.watchStarts()
+		return len(revs) == 4 && revs[3] == 0
+	}, time.Second, 5*time.Millisecond)
+
+	ast.Nil(a.Set("k", []byte("4")))
+	ast.True(waitNotify(ch))
+
+	ast.Nil(a.Close())
+	ast.False(a.Capabilities().TTL)
+	ast.NotNil(a.SetWithTTL("k", nil, time.Second))
+}
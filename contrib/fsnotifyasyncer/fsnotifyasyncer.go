@@ -0,0 +1,168 @@
+// Package fsnotifyasyncer 使用fsnotify监听本地文件，文件变化时立即通知 config.AsyncConfig 刷新
+//
+//	asyncer, err := fsnotifyasyncer.New()
+//	cfg := config.NewAsyncConfig(asyncer, "conf/app.yaml", time.Minute, false)
+//
+// 监听的是文件所在的目录，编辑器先写临时文件再重命名、kubelet替换 ..data 符号链接等方式更新时同样能收到通知
+package fsnotifyasyncer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kot-w/config"
+	_logger "github.com/kot-w/logger"
+	"github.com/pkg/errors"
+)
+
+var logger config.Logger = _logger.Named("config.fsnotify")
+
+// Asyncer 在 config.FileAsyncer 的基础上通过fsnotify实现Watch
+type Asyncer struct {
+	*config.FileAsyncer
+
+	watcher *fsnotify.Watcher
+
+	mu       sync.Mutex
+	files    map[string]*watchedFile // 绝对路径 => 监听的文件
+	dirs     map[string]bool         // 已监听的目录
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+type watchedFile struct {
+	ch      chan struct{}
+	version string // 最近一次通知时的版本
+}
+
+func New() (*Asyncer, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "new fsnotify watcher")
+	}
+
+	a := &Asyncer{
+		FileAsyncer: config.NewFileAsyncer(),
+		watcher:     watcher,
+		files:       make(map[string]*watchedFile),
+		dirs:        make(map[string]bool),
+		quit:        make(chan struct{}),
+	}
+	go a.loop()
+
+	return a, nil
+}
+
+// Get 每次都读取文件；config.FileAsyncer 以修改时间缓存内容，重命名替换时修改时间可能不变
+func (a *Asyncer) Get(file string) []byte {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		logger.Errorf("read conf file[%s] err:%v", file, err)
+		return nil
+	}
+	return content
+}
+
+// version 文件的实际路径（解析符号链接）、大小及修改时间，文件不存在时为空
+func version(file string) string {
+	real, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(real)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s|%d|%d", real, info.ModTime().UnixNano(), info.Size())
+}
+
+// Watch 监听文件所在的目录，目录不存在等原因无法监听时返回nil
+func (a *Asyncer) Watch(file string) chan struct{} {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		logger.Errorf("watch conf file[%s] err:%v", file, err)
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if w, ok := a.files[path]; ok {
+		return w.ch
+	}
+
+	dir := filepath.Dir(path)
+	if !a.dirs[dir] {
+		if err := a.watcher.Add(dir); err != nil {
+			logger.Errorf("watch conf file[%s] err:%v", file, err)
+			return nil
+		}
+		a.dirs[dir] = true
+	}
+
+	w := &watchedFile{ch: make(chan struct{}, 1), version: version(path)}
+	a.files[path] = w
+	return w.ch
+}
+
+func (a *Asyncer) loop() {
+	for {
+		select {
+		case <-a.quit:
+			return
+		case event, ok := <-a.watcher.Events:
+			if !ok {
+				return
+			}
+			a.check(event.Name)
+		case err, ok := <-a.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warnf("fsnotify err:%v", err)
+		}
+	}
+}
+
+// check name有变化时检查同一目录下监听的文件，事件为该文件或版本变化时通知
+//
+// 文件本身可能是指向其他目录的符号链接（如kubelet替换 ..data），不能只看事件的文件名；
+// 重命名替换时修改时间及大小可能不变，也不能只看版本
+func (a *Asyncer) check(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	name = filepath.Clean(name)
+	dir := filepath.Dir(name)
+	for path, w := range a.files {
+		if filepath.Dir(path) != dir {
+			continue
+		}
+		if v := version(path); path == name || v != w.version {
+			w.version = v
+			select {
+			case w.ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *Asyncer) Capabilities() config.Capabilities {
+	return config.Capabilities{Watch: true, List: true}
+}
+
+// Close 停止监听
+func (a *Asyncer) Close() error {
+	var err error
+	a.stopOnce.Do(func() {
+		close(a.quit)
+		err = a.watcher.Close()
+	})
+	return err
+}
@@ -0,0 +1,90 @@
+package fsnotifyasyncer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func waitNotify(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(2 * time.Second):
+		return false
+	}
+}
+
+func TestAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.json")
+	ast.Nil(ioutil.WriteFile(file, []byte(`{"a": 1}`), 0644))
+
+	a, err := New()
+	ast.Nil(err)
+	defer a.Close()
+
+	ast.True(config.ProbeCapabilities(a).Watch)
+	ast.Equal(config.T_JSON, a.ContentType(file))
+	ast.Nil(a.Watch(filepath.Join(dir, "missing", "app.json")))
+
+	cfg := config.NewAsyncConfig(a, file, time.Hour, false)
+	ast.EqualValues(1, cfg.Int("a"))
+	ch := make(chan struct{}, 1)
+	cfg.Watch(ch)
+
+	// 直接写入
+	ast.Nil(ioutil.WriteFile(file, []byte(`{"a": 2}`), 0644))
+	ast.True(waitNotify(ch))
+	ast.EqualValues(2, cfg.Int("a"))
+
+	// 写临时文件后重命名
+	tmp := filepath.Join(dir, ".app.json.swp")
+	ast.Nil(ioutil.WriteFile(tmp, []byte(`{"a": 3}`), 0644))
+	ast.Nil(os.Rename(tmp, file))
+	ast.True(waitNotify(ch))
+	ast.EqualValues(3, cfg.Int("a"))
+
+	// 同一目录下其他文件的变化不会通知
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "other.json"), []byte(`{}`), 0644))
+	select {
+	case <-ch:
+		ast.Fail("notified by other file")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAsyncerSymlink(t *testing.T) {
+	ast := assert.New(t)
+
+	// 模拟kubelet的挂载方式：app.json -> ..data/app.json，..data -> ..v1
+	dir := t.TempDir()
+	for _, v := range []string{"..v1", "..v2"} {
+		ast.Nil(os.Mkdir(filepath.Join(dir, v), 0755))
+	}
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "..v1", "app.json"), []byte(`{"a": 1}`), 0644))
+	ast.Nil(ioutil.WriteFile(filepath.Join(dir, "..v2", "app.json"), []byte(`{"a": 2}`), 0644))
+	ast.Nil(os.Symlink("..v1", filepath.Join(dir, "..data")))
+	ast.Nil(os.Symlink(filepath.Join("..data", "app.json"), filepath.Join(dir, "app.json")))
+
+	a, err := New()
+	ast.Nil(err)
+	defer a.Close()
+
+	file := filepath.Join(dir, "app.json")
+	ch := a.Watch(file)
+	ast.NotNil(ch)
+	ast.Equal(ch, a.Watch(file))
+
+	ast.Nil(os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	ast.Nil(os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	ast.True(waitNotify(ch))
+	ast.Equal(`{"a": 2}`, string(a.Get(file)))
+}
@@ -0,0 +1,92 @@
+// Package fxconfig 为 uber-go/fx 及 google/wire 提供配置的provider
+//
+// fx：
+//
+//	fx.New(
+//		fxconfig.Module,
+//		fx.Provide(fxconfig.File("conf/app.yaml", 0)),
+//		fx.Invoke(func(cfg *config.AsyncConfig) { ... }),
+//	)
+//
+// wire：
+//
+//	wire.Build(fxconfig.FileSet, ...)
+//
+// 异步配置在应用启动时加载（失败时启动失败），应用停止时停止监听及刷新
+package fxconfig
+
+import (
+	"context"
+	"time"
+
+	"github.com/kot-w/config"
+	"go.uber.org/fx"
+)
+
+// Module 提供全局的分层配置 config.Config
+var Module = fx.Module("config",
+	fx.Provide(Default),
+)
+
+// Default 全局的分层配置，按默认的Layer查询
+func Default() config.Config {
+	return config.Default()
+}
+
+// Layered 按指定的Layer依次查询的配置
+//
+//	fx.Provide(fx.Annotate(fxconfig.Layered("billing", config.DefaultLayerName), fx.ResultTags(`name:"billing"`)))
+func Layered(layerNames ...string) func() config.Config {
+	return func() config.Config {
+		return config.Layer(layerNames...)
+	}
+}
+
+// File 文件配置，cacheTime见 config.NewAsyncConfig
+func File(path string, cacheTime time.Duration, opts ...config.AsyncOption) func(fx.Lifecycle) *config.AsyncConfig {
+	return Async(path, cacheTime, opts...).withAsyncer(config.NewFileAsyncer())
+}
+
+// AsyncProvider 异步配置的provider，Asyncer由容器提供
+type AsyncProvider func(lc fx.Lifecycle, asyncer config.Asyncer) *config.AsyncConfig
+
+// Async 异步配置，需要容器中提供 config.Asyncer
+//
+//	fx.Provide(
+//		func() config.Asyncer { return config.NewRedisAsyncer(opts, "channel") },
+//		fxconfig.Async("app", 3*time.Second),
+//	)
+func Async(key string, cacheTime time.Duration, opts ...config.AsyncOption) AsyncProvider {
+	return func(lc fx.Lifecycle, asyncer config.Asyncer) *config.AsyncConfig {
+		cfg := config.NewAsyncConfig(asyncer, key, cacheTime, false, append(append([]config.AsyncOption{}, opts...), config.WithDeferredStart())...)
+		lc.Append(fx.Hook{
+			OnStart: cfg.Start,
+			OnStop:  cfg.Stop,
+		})
+		return cfg
+	}
+}
+
+func (p AsyncProvider) withAsyncer(asyncer config.Asyncer) func(fx.Lifecycle) *config.AsyncConfig {
+	return func(lc fx.Lifecycle) *config.AsyncConfig {
+		return p(lc, asyncer)
+	}
+}
+
+// startAsync 供wire使用：启动配置并返回cleanup
+func startAsync(cfg *config.AsyncConfig, timeout time.Duration) (*config.AsyncConfig, func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := cfg.Start(ctx); err != nil {
+		cfg.Close()
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		cfg.Stop(ctx)
+	}
+	return cfg, cleanup, nil
+}
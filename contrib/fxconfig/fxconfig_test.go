@@ -0,0 +1,98 @@
+package fxconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/fx"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFx(t *testing.T) {
+	ast := assert.New(t)
+
+	path := writeFile(t, `{"name": "app"}`)
+	config.Set("fx_key", "fx_value")
+
+	var fileCfg *config.AsyncConfig
+	var defaultCfg config.Config
+	app := fx.New(
+		fx.NopLogger,
+		Module,
+		fx.Provide(File(path, 0)),
+		fx.Populate(&fileCfg, &defaultCfg),
+	)
+	ast.Nil(app.Err())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ast.Nil(app.Start(ctx))
+	ast.Equal("app", fileCfg.String("name"))
+	ast.True(fileCfg.Status().Loaded)
+	ast.Equal("fx_value", defaultCfg.String("fx_key"))
+	ast.Nil(app.Stop(ctx))
+	ast.Equal(config.ErrClosed, fileCfg.Start(ctx))
+}
+
+func TestFxStartFailed(t *testing.T) {
+	ast := assert.New(t)
+
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(
+			func() config.Asyncer { return config.NewMockAsyncer(false) },
+			Async("not_exist.json", 0),
+		),
+		fx.Invoke(func(*config.AsyncConfig) {}),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ast.NotNil(app.Start(ctx))
+}
+
+func TestWire(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg, cleanup, err := ProvideFile(FilePath(writeFile(t, `{"name": "wire"}`)))
+	ast.Nil(err)
+	ast.Equal("wire", cfg.String("name"))
+	cleanup()
+
+	timeout := StartTimeout
+	StartTimeout = 10 * time.Millisecond
+	defer func() { StartTimeout = timeout }()
+	_, _, err = ProvideAsync(config.NewMockAsyncer(false), AsyncSettings{Key: "not_exist.json"})
+	ast.NotNil(err)
+
+	ast.NotNil(Layered(config.DefaultLayerName)())
+}
+
+func TestAsyncKeepsOptions(t *testing.T) {
+	ast := assert.New(t)
+
+	// 有剩余容量时不写入调用方的数组
+	opts := make([]config.AsyncOption, 1, 2)
+	opts[0] = config.WithTimeouts(config.Timeouts{Get: time.Second})
+	provider := Async("app.json", 0, opts...)
+
+	lc := fxLifecycle{}
+	cfg := provider(&lc, config.NewMockAsyncer(false))
+	defer cfg.Close()
+	ast.Nil(opts[:2][1])
+}
+
+type fxLifecycle struct{}
+
+func (fxLifecycle) Append(fx.Hook) {}
@@ -0,0 +1,42 @@
+package fxconfig
+
+import (
+	"time"
+
+	"github.com/google/wire"
+	"github.com/kot-w/config"
+)
+
+// StartTimeout wire provider 首次加载配置的超时时间，同时用于cleanup的超时
+var StartTimeout = 30 * time.Second
+
+// FilePath 文件配置的路径
+type FilePath string
+
+// AsyncSettings 异步配置的参数，见 config.NewAsyncConfig
+type AsyncSettings struct {
+	Key       string
+	CacheTime time.Duration
+	Options   []config.AsyncOption
+}
+
+// DefaultSet 提供全局的分层配置
+var DefaultSet = wire.NewSet(Default)
+
+// FileSet 提供文件配置，需要提供 FilePath
+var FileSet = wire.NewSet(ProvideFile)
+
+// AsyncSet 提供异步配置，需要提供 config.Asyncer 及 AsyncSettings
+var AsyncSet = wire.NewSet(ProvideAsync)
+
+// ProvideFile 加载文件配置，返回的cleanup停止配置
+func ProvideFile(path FilePath) (*config.AsyncConfig, func(), error) {
+	return ProvideAsync(config.NewFileAsyncer(), AsyncSettings{Key: string(path)})
+}
+
+// ProvideAsync 加载异步配置，返回的cleanup停止配置
+func ProvideAsync(asyncer config.Asyncer, settings AsyncSettings) (*config.AsyncConfig, func(), error) {
+	opts := append(append([]config.AsyncOption{}, settings.Options...), config.WithDeferredStart())
+	cfg := config.NewAsyncConfig(asyncer, settings.Key, settings.CacheTime, false, opts...)
+	return startAsync(cfg, StartTimeout)
+}
@@ -0,0 +1,43 @@
+// Package ginconfig 为gin提供配置快照中间件及配置查看接口
+package ginconfig
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kot-w/config"
+)
+
+// ContextKey 快照在gin.Context中的key
+const ContextKey = "config.snapshot"
+
+// Snapshot 为每个请求创建配置快照，同时写入gin.Context及Request.Context
+func Snapshot(cfg config.Configer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		snap := config.Snapshot(cfg)
+		c.Set(ContextKey, snap)
+		c.Request = c.Request.WithContext(config.ContextWithSnapshot(c.Request.Context(), snap))
+		c.Next()
+	}
+}
+
+// FromContext 获取请求的配置快照，未使用 Snapshot 中间件时返回nil
+func FromContext(c *gin.Context) *config.SnapshotConfig {
+	if v, ok := c.Get(ContextKey); ok {
+		return v.(*config.SnapshotConfig)
+	}
+	return config.SnapshotFromContext(c.Request.Context())
+}
+
+// Mount 在path下挂载配置查看接口，见 config.NewAdminHandler
+//
+//	GET path        全部配置
+//	GET path?key=   指定配置
+//	GET path/keys   配置key列表
+//	GET path/dump   树形文本格式
+//	GET path/openapi.json 以上接口的OpenAPI文档
+func Mount(r gin.IRouter, path string, cfg config.Configer, maskKeyPaths ...string) {
+	h := gin.WrapH(config.NewAdminHandler(cfg, maskKeyPaths...))
+	r.GET(path, h)
+	r.GET(path+"/keys", h)
+	r.GET(path+"/dump", h)
+	r.GET(path+"/openapi.json", h)
+}
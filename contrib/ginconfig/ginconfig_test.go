@@ -0,0 +1,46 @@
+package ginconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGin(t *testing.T) {
+	ast := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.NewMapConfig(map[string]interface{}{"name": "app", "password": "secret"})
+
+	r := gin.New()
+	r.Use(Snapshot(cfg))
+	r.GET("/hello", func(c *gin.Context) {
+		ast.Equal(FromContext(c), config.SnapshotFromContext(c.Request.Context()))
+		c.String(http.StatusOK, FromContext(c).String("name"))
+	})
+	Mount(r.Group("/debug"), "/config", cfg, "password")
+
+	do := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	ast.Equal("app", do("/hello").Body.String())
+
+	var m map[string]interface{}
+	ast.Nil(json.Unmarshal(do("/debug/config").Body.Bytes(), &m))
+	ast.Equal(map[string]interface{}{"name": "app", "password": config.AdminMaskValue}, m)
+
+	ast.Equal("\"app\"\n", do("/debug/config?key=name").Body.String())
+
+	var keys []string
+	ast.Nil(json.Unmarshal(do("/debug/config/keys").Body.Bytes(), &keys))
+	ast.Equal([]string{"name", "password"}, keys)
+	ast.Contains(do("/debug/config/openapi.json").Body.String(), `"url": "/debug/config"`)
+}
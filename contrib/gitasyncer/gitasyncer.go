@@ -0,0 +1,313 @@
+// Package gitasyncer 以git仓库为异步配置的数据源，配置即代码，合并到分支后各实例自动生效
+//
+//	asyncer, err := gitasyncer.New(&gitasyncer.Options{URL: "https://github.com/acme/config.git", Branch: "main"})
+//	cfg := config.NewAsyncConfig(asyncer, "billing/app.yaml", 0, false) // 仓库中的文件路径
+//
+// 定期fetch，分支有新的提交且文件内容变化时通知；只读取单个文件时也可以使用 NewGitHubRaw
+package gitasyncer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/kot-w/config"
+	_logger "github.com/kot-w/logger"
+	"github.com/pkg/errors"
+)
+
+var (
+	// PollInterval fetch的间隔，在创建 Asyncer 时确定
+	PollInterval = time.Minute
+	// FetchTimeout clone及fetch的超时时间
+	FetchTimeout = 30 * time.Second
+)
+
+var logger config.Logger = _logger.Named("config.git")
+
+// Options 仓库的连接选项
+type Options struct {
+	URL    string
+	Branch string               // 默认为远端的默认分支
+	Auth   transport.AuthMethod // 如 &http.BasicAuth{Username: "x-access-token", Password: token}
+}
+
+// Asyncer 以git仓库为数据源的 config.Asyncer，key为仓库中文件的路径
+//
+// 仓库克隆在内存中，不占用磁盘
+type Asyncer struct {
+	options  Options
+	repo     *git.Repository
+	remote   *git.Remote
+	interval time.Duration
+
+	// 内存中的仓库不支持并发读写，fetch写入时持有写锁
+	mu     sync.RWMutex
+	commit *object.Commit // 当前分支最新的提交
+
+	notifyChans sync.Map // key => chan struct{}
+	watching    sync.Once
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+func New(options *Options) (*Asyncer, error) {
+	if options == nil || options.URL == "" {
+		return nil, errors.New("git url is required")
+	}
+
+	a := &Asyncer{options: *options, interval: PollInterval}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	ctx, cancel := context.WithTimeout(a.ctx, FetchTimeout)
+	defer cancel()
+
+	cloneOptions := &git.CloneOptions{
+		URL:          options.URL,
+		Auth:         options.Auth,
+		SingleBranch: true,
+		NoCheckout:   true,
+	}
+	if options.Branch != "" {
+		cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(options.Branch)
+	}
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, cloneOptions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "clone git[%s]", options.URL)
+	}
+	a.repo = repo
+
+	if a.options.Branch == "" {
+		if err := a.trackDefaultBranch(); err != nil {
+			return nil, errors.Wrapf(err, "resolve git[%s] default branch", options.URL)
+		}
+	}
+	if a.remote, err = repo.Remote(git.DefaultRemoteName); err != nil {
+		return nil, errors.Wrapf(err, "git[%s] remote", options.URL)
+	}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("NewGitAsyncer:url=%s branch=%s commit=%s", options.URL, a.options.Branch, a.Commit())
+
+	return a, nil
+}
+
+// trackDefaultBranch 未指定分支时克隆的是远端的HEAD，改为跟踪其指向的分支
+func (a *Asyncer) trackDefaultBranch() error {
+	head, err := a.repo.Head()
+	if err != nil {
+		return err
+	}
+	a.options.Branch = head.Name().Short()
+
+	cfg, err := a.repo.Config()
+	if err != nil {
+		return err
+	}
+	remote := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, a.options.Branch)
+	cfg.Remotes[git.DefaultRemoteName].Fetch = []gitconfig.RefSpec{
+		gitconfig.RefSpec(fmt.Sprintf("+%s:%s", head.Name(), remote)),
+	}
+	if err := a.repo.SetConfig(cfg); err != nil {
+		return err
+	}
+	return a.repo.Storer.SetReference(plumbing.NewHashReference(remote, head.Hash()))
+}
+
+// load 读取远端分支最新的提交，需持有写锁
+func (a *Asyncer) load() error {
+	ref, err := a.repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, a.options.Branch), true)
+	if err != nil {
+		return errors.Wrapf(err, "resolve git[%s] branch", a.options.URL)
+	}
+
+	commit, err := a.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return errors.Wrapf(err, "read git[%s] commit %s", a.options.URL, ref.Hash())
+	}
+
+	a.commit = commit
+	return nil
+}
+
+// Commit 当前的提交
+func (a *Asyncer) Commit() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.commit.Hash.String()
+}
+
+func (a *Asyncer) ContentType(key string) config.ContentType {
+	return config.ContentTypeByExt(key)
+}
+
+// file 当前提交中的文件，不存在时返回nil，需持有读锁
+func (a *Asyncer) file(key string) (*object.File, error) {
+	f, err := a.commit.File(strings.TrimPrefix(path.Clean(key), "/"))
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+	return f, err
+}
+
+// Get 文件不存在时返回nil
+func (a *Asyncer) Get(key string) []byte {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	content, err := a.read(key)
+	if err != nil {
+		logger.Errorf("read conf[%s] from git err:%v", key, err)
+		return nil
+	}
+	return content
+}
+
+func (a *Asyncer) read(key string) ([]byte, error) {
+	f, err := a.file(key)
+	if err != nil || f == nil {
+		return nil, err
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// Set 配置通过提交修改，不支持写入
+func (a *Asyncer) Set(key string, value []byte) error {
+	return fmt.Errorf("git[%s] is read-only", a.options.URL)
+}
+
+// blob 文件在当前提交中的hash，不存在时为空，需持有锁
+func (a *Asyncer) blob(key string) string {
+	f, err := a.file(key)
+	if err != nil || f == nil {
+		return ""
+	}
+	return f.Hash.String()
+}
+
+// Watch 每隔 PollInterval fetch一次，有新的提交且文件变化时通知
+func (a *Asyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	a.watching.Do(func() {
+		go a.poll()
+	})
+
+	return ch
+}
+
+func (a *Asyncer) poll() {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := a.fetch(); err != nil {
+			if a.ctx.Err() == nil {
+				logger.Warnf("fetch git[%s] err:%v", a.options.URL, err)
+			}
+		}
+	}
+}
+
+// fetch 远端分支有新的提交时拉取，文件内容变化的key通知
+//
+// 先不持有锁查询远端的分支，没有新的提交时不阻塞读取
+func (a *Asyncer) fetch() error {
+	ctx, cancel := context.WithTimeout(a.ctx, FetchTimeout)
+	defer cancel()
+
+	refs, err := a.remote.ListContext(ctx, &git.ListOptions{Auth: a.options.Auth})
+	if err != nil {
+		return err
+	}
+	branch := plumbing.NewBranchReferenceName(a.options.Branch)
+	var head plumbing.Hash
+	for _, ref := range refs {
+		if ref.Name() == branch {
+			head = ref.Hash()
+		}
+	}
+	if head.IsZero() {
+		return errors.Errorf("branch %s not found", a.options.Branch)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	old := a.commit.Hash
+	if head == old {
+		return nil
+	}
+
+	err = a.repo.FetchContext(ctx, &git.FetchOptions{Auth: a.options.Auth, Force: true})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	blobs := make(map[string]string)
+	a.notifyChans.Range(func(key, _ interface{}) bool {
+		blobs[key.(string)] = a.blob(key.(string))
+		return true
+	})
+
+	if err := a.load(); err != nil {
+		return err
+	}
+	logger.Debugf("fetch git[%s] %s..%s", a.options.URL, old, a.commit.Hash)
+
+	a.notifyChans.Range(func(key, ch interface{}) bool {
+		if a.blob(key.(string)) != blobs[key.(string)] {
+			select {
+			case ch.(chan struct{}) <- struct{}{}:
+			default:
+			}
+		}
+		return true
+	})
+	return nil
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *Asyncer) Capabilities() config.Capabilities {
+	return config.Capabilities{Watch: true}
+}
+
+// Close 停止fetch
+func (a *Asyncer) Close() error {
+	a.cancel()
+	return nil
+}
+
+// NewGitHubRaw 通过 raw.githubusercontent.com 读取GitHub仓库ref（分支、tag或提交）下的文件，
+// 以ETag判断变化，不需要克隆仓库；私有仓库通过 config.WithTokenSource 等选项认证
+func NewGitHubRaw(owner, repo, ref string, opts ...config.BackendOption) *config.HTTPAsyncer {
+	return config.NewHTTPAsyncer(fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s", owner, repo, ref), opts...)
+}
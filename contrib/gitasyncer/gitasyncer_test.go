@@ -0,0 +1,137 @@
+package gitasyncer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// upstream 本地的上游仓库
+type upstream struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+}
+
+func newUpstream(t *testing.T) *upstream {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &upstream{t: t, dir: dir, repo: repo}
+}
+
+// commit 写入文件并提交
+func (u *upstream) commit(files map[string]string) {
+	wt, err := u.repo.Worktree()
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(u.dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			u.t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			u.t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			u.t.Fatal(err)
+		}
+	}
+	_, err = wt.Commit("update", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		u.t.Fatal(err)
+	}
+}
+
+func waitNotify(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(2 * time.Second):
+		return false
+	}
+}
+
+func TestAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	u := newUpstream(t)
+	u.commit(map[string]string{
+		"billing/app.json": `{"a": 1}`,
+		"other.yaml":       "b: 1",
+	})
+
+	_, err := New(&Options{})
+	ast.NotNil(err)
+	_, err = New(&Options{URL: u.dir, Branch: "not_exist"})
+	ast.NotNil(err)
+
+	a, err := New(&Options{URL: u.dir, Branch: "master"})
+	ast.Nil(err)
+	defer a.Close()
+	a.interval = 10 * time.Millisecond
+
+	ast.Equal(`{"a": 1}`, string(a.Get("billing/app.json")))
+	ast.Equal(`{"a": 1}`, string(a.Get("/billing/app.json")))
+	ast.Nil(a.Get("missing.json"))
+	ast.NotNil(a.Set("billing/app.json", nil))
+	ast.Equal(config.T_YAML, a.ContentType("other.yaml"))
+
+	cfg := config.NewAsyncConfig(a, "billing/app.json", time.Hour, false)
+	ast.EqualValues(1, cfg.Int("a"))
+	app := make(chan struct{}, 1)
+	cfg.Watch(app)
+	other := a.Watch("other.yaml")
+
+	// 其他文件的提交只通知其他文件
+	u.commit(map[string]string{"other.yaml": "b: 2"})
+	ast.True(waitNotify(other))
+	select {
+	case <-app:
+		ast.Fail("notified without change")
+	default:
+	}
+
+	head, err := u.repo.Head()
+	ast.Nil(err)
+	u.commit(map[string]string{"billing/app.json": `{"a": 2}`})
+	ast.True(waitNotify(app))
+	ast.EqualValues(2, cfg.Int("a"))
+	ast.NotEqual(head.Hash().String(), a.Commit())
+}
+
+func TestAsyncerDefaultBranch(t *testing.T) {
+	ast := assert.New(t)
+
+	u := newUpstream(t)
+	u.commit(map[string]string{"app.json": `{}`})
+
+	a, err := New(&Options{URL: u.dir})
+	ast.Nil(err)
+	defer a.Close()
+	ast.Equal("master", a.options.Branch)
+	ast.Equal(`{}`, string(a.Get("app.json")))
+
+	u.commit(map[string]string{"app.json": `{"a": 1}`})
+	ast.Nil(a.fetch())
+	ast.Equal(`{"a": 1}`, string(a.Get("app.json")))
+}
+
+func TestNewGitHubRaw(t *testing.T) {
+	ast := assert.New(t)
+
+	a := NewGitHubRaw("acme", "config", "main")
+	ast.Equal(config.T_JSON, a.ContentType("billing/app.json"))
+}
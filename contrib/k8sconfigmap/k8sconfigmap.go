@@ -0,0 +1,272 @@
+// Package k8sconfigmap 使用Kubernetes的ConfigMap或Secret作为异步配置的数据源
+//
+// 通过informer监听资源变化，集群内的服务不需要sidecar即可实时更新配置：
+//
+//	asyncer, err := k8sconfigmap.NewForConfig(restConfig, "default", k8sconfigmap.ConfigMap)
+//	cfg := config.NewAsyncConfig(asyncer, "billing/app.yaml", 0, false) // 名为billing的ConfigMap中的app.yaml
+//
+// 以volume挂载时见 NewMounted
+package k8sconfigmap
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	// SyncTimeout 等待informer首次同步的超时时间
+	SyncTimeout = 30 * time.Second
+	// ResyncPeriod informer全量重新同步的周期，0 不重新同步
+	ResyncPeriod time.Duration = 0
+)
+
+// Kind 资源类型
+type Kind int
+
+const (
+	ConfigMap Kind = iota
+	Secret
+)
+
+func (k Kind) String() string {
+	if k == Secret {
+		return "secret"
+	}
+	return "configmap"
+}
+
+// Asyncer 以ConfigMap或Secret为数据源的 config.Asyncer
+//
+// key为 资源名称/data中的key，内容为该项的值；只有资源名称时内容为所有项组成的JSON对象
+type Asyncer struct {
+	client    kubernetes.Interface
+	namespace string
+	kind      Kind
+	informer  cache.SharedIndexInformer
+
+	notifyChans sync.Map // key => chan struct{}
+	stop        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewForConfig 使用rest.Config创建client，见 New
+func NewForConfig(c *rest.Config, namespace string, kind Kind) (*Asyncer, error) {
+	client, err := kubernetes.NewForConfig(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "create kubernetes client")
+	}
+	return New(client, namespace, kind)
+}
+
+// New 启动namespace下ConfigMap或Secret的informer并等待首次同步完成
+func New(client kubernetes.Interface, namespace string, kind Kind) (*Asyncer, error) {
+	a := &Asyncer{
+		client:    client,
+		namespace: namespace,
+		kind:      kind,
+		stop:      make(chan struct{}),
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, ResyncPeriod, informers.WithNamespace(namespace))
+	if kind == Secret {
+		a.informer = factory.Core().V1().Secrets().Informer()
+	} else {
+		a.informer = factory.Core().V1().ConfigMaps().Informer()
+	}
+	if _, err := a.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    a.onEvent,
+		UpdateFunc: func(_, obj interface{}) { a.onEvent(obj) },
+		DeleteFunc: a.onEvent,
+	}); err != nil {
+		return nil, errors.Wrap(err, "add event handler")
+	}
+
+	go a.informer.Run(a.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), SyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), a.informer.HasSynced) {
+		a.Close()
+		return nil, errors.Errorf("sync %s timeout", kind)
+	}
+
+	return a, nil
+}
+
+func (a *Asyncer) onEvent(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	m, ok := obj.(metav1.Object)
+	if !ok {
+		return
+	}
+
+	a.notifyChans.Range(func(key, ch interface{}) bool {
+		if name, _ := splitKey(key.(string)); name == m.GetName() {
+			notify(ch.(chan struct{}))
+		}
+		return true
+	})
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// splitKey 拆分为资源名称及data中的key
+func splitKey(key string) (name, item string) {
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return key, ""
+}
+
+// ContentType 根据data中key的后缀判断，只有资源名称时为JSON
+func (a *Asyncer) ContentType(key string) config.ContentType {
+	if _, item := splitKey(key); item != "" {
+		return config.ContentTypeByExt(item)
+	}
+	return config.T_JSON
+}
+
+// data 返回资源的所有项，资源不存在时返回nil
+func (a *Asyncer) data(name string) map[string][]byte {
+	obj, ok, err := a.informer.GetStore().GetByKey(a.namespace + "/" + name)
+	if err != nil || !ok {
+		return nil
+	}
+
+	data := make(map[string][]byte)
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		for k, v := range o.BinaryData {
+			data[k] = v
+		}
+		for k, v := range o.Data {
+			data[k] = []byte(v)
+		}
+	case *corev1.Secret:
+		for k, v := range o.Data {
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// Get 资源或项不存在时返回nil
+func (a *Asyncer) Get(key string) []byte {
+	name, item := splitKey(key)
+	data := a.data(name)
+	if data == nil {
+		return nil
+	}
+	if item != "" {
+		return data[item]
+	}
+
+	values := make(map[string]string, len(data))
+	for k, v := range data {
+		values[k] = string(v)
+	}
+	ret, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+	return ret
+}
+
+// Set 更新资源中的一项，资源不存在时创建，key需包含data中的key
+func (a *Asyncer) Set(key string, value []byte) error {
+	name, item := splitKey(key)
+	if item == "" {
+		return errors.Errorf("%s key[%s] requires an item name", a.kind, key)
+	}
+
+	ctx := context.Background()
+	var err error
+	if a.kind == Secret {
+		err = a.setSecret(ctx, name, item, value)
+	} else {
+		err = a.setConfigMap(ctx, name, item, value)
+	}
+	return errors.Wrapf(err, "update %s[%s]", a.kind, key)
+}
+
+func (a *Asyncer) setConfigMap(ctx context.Context, name, item string, value []byte) error {
+	client := a.client.CoreV1().ConfigMaps(a.namespace)
+	cm, err := client.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: a.namespace, Name: name}}
+		cm.Data = map[string]string{item: string(value)}
+		_, err = client.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[item] = string(value)
+	delete(cm.BinaryData, item)
+	_, err = client.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+func (a *Asyncer) setSecret(ctx context.Context, name, item string, value []byte) error {
+	client := a.client.CoreV1().Secrets(a.namespace)
+	secret, err := client.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: a.namespace, Name: name}}
+		secret.Data = map[string][]byte{item: value}
+		_, err = client.Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[item] = value
+	_, err = client.Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+// Watch 资源新增、修改、删除时通知
+func (a *Asyncer) Watch(key string) chan struct{} {
+	ch, _ := a.notifyChans.LoadOrStore(key, make(chan struct{}, 1))
+	return ch.(chan struct{})
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *Asyncer) Capabilities() config.Capabilities {
+	return config.Capabilities{Watch: true}
+}
+
+// Close 停止informer
+func (a *Asyncer) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.stop)
+	})
+	return nil
+}
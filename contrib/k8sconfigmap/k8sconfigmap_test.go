@@ -0,0 +1,100 @@
+package k8sconfigmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func waitNotify(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(2 * time.Second):
+		return false
+	}
+}
+
+func TestConfigMap(t *testing.T) {
+	ast := assert.New(t)
+
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "billing"},
+		Data:       map[string]string{"app.json": `{"a": 1}`},
+		BinaryData: map[string][]byte{"raw": []byte("bin")},
+	})
+	a, err := New(client, "default", ConfigMap)
+	ast.Nil(err)
+	defer a.Close()
+
+	ast.Equal(`{"a": 1}`, string(a.Get("billing/app.json")))
+	ast.Equal("bin", string(a.Get("billing/raw")))
+	ast.JSONEq(`{"app.json": "{\"a\": 1}", "raw": "bin"}`, string(a.Get("billing")))
+	ast.Nil(a.Get("billing/missing"))
+	ast.Nil(a.Get("missing/app.json"))
+	ast.Nil(a.Get("missing"))
+	ast.Equal(config.T_JSON, a.ContentType("billing"))
+	ast.Equal(config.T_YAML, a.ContentType("billing/app.yaml"))
+	ast.Equal(config.Capabilities{Watch: true}, config.ProbeCapabilities(a))
+
+	cfg := config.NewAsyncConfig(a, "billing/app.json", 0, false)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	// 同一资源的其他key也会收到通知
+	other := a.Watch("billing/raw")
+	ast.Nil(a.Set("billing/app.json", []byte(`{"a": 2}`)))
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 2
+	}, 2*time.Second, 5*time.Millisecond)
+	ast.True(waitNotify(other))
+
+	// 不存在时创建
+	ast.Nil(a.Set("orders/app.yaml", []byte("a: 1")))
+	cm, err := client.CoreV1().ConfigMaps("default").Get(context.Background(), "orders", metav1.GetOptions{})
+	ast.Nil(err)
+	ast.Equal("a: 1", cm.Data["app.yaml"])
+	ast.Eventually(func() bool {
+		return string(a.Get("orders/app.yaml")) == "a: 1"
+	}, 2*time.Second, 5*time.Millisecond)
+
+	ast.NotNil(a.Set("orders", []byte("{}")))
+}
+
+func TestSecret(t *testing.T) {
+	ast := assert.New(t)
+
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "db"},
+		Data:       map[string][]byte{"password": []byte("s3cret")},
+	}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db"},
+		Data:       map[string][]byte{"password": []byte("other")},
+	})
+	a, err := New(client, "prod", Secret)
+	ast.Nil(err)
+	defer a.Close()
+
+	ast.Equal("s3cret", string(a.Get("db/password")))
+	ast.Equal(`{"password":"s3cret"}`, string(a.Get("db")))
+
+	ch := a.Watch("db")
+	ast.Nil(a.Set("db/user", []byte("admin")))
+	ast.True(waitNotify(ch))
+	ast.Eventually(func() bool {
+		return string(a.Get("db/user")) == "admin"
+	}, 2*time.Second, 5*time.Millisecond)
+	ast.Equal("s3cret", string(a.Get("db/password")))
+
+	// 删除时通知
+	ast.Nil(client.CoreV1().Secrets("prod").Delete(context.Background(), "db", metav1.DeleteOptions{}))
+	ast.True(waitNotify(ch))
+	ast.Eventually(func() bool {
+		return a.Get("db") == nil
+	}, 2*time.Second, 5*time.Millisecond)
+}
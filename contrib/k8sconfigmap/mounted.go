@@ -0,0 +1,126 @@
+package k8sconfigmap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kot-w/config"
+)
+
+// MountPollInterval 检查挂载目录变化的间隔
+var MountPollInterval = 5 * time.Second
+
+// MountedAsyncer 读取以volume挂载的ConfigMap或Secret，key为目录下的文件名
+//
+// kubelet更新挂载的内容时原子地替换 ..data 符号链接，通过定期检查其指向发现变化；
+// 不是kubelet挂载的目录（没有 ..data）时检查文件的修改时间
+type MountedAsyncer struct {
+	dir string
+
+	mu          sync.Mutex
+	notifyChans map[string]chan struct{}
+	versions    map[string]string // key => 最近一次检查时的版本
+	quit        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewMounted dir为volume的挂载目录
+func NewMounted(dir string) *MountedAsyncer {
+	return &MountedAsyncer{
+		dir:         dir,
+		notifyChans: make(map[string]chan struct{}),
+		versions:    make(map[string]string),
+		quit:        make(chan struct{}),
+	}
+}
+
+func (a *MountedAsyncer) ContentType(key string) config.ContentType {
+	return config.ContentTypeByExt(key)
+}
+
+// Get 文件不存在时返回nil
+func (a *MountedAsyncer) Get(key string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join(a.dir, key))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Set 挂载的内容只读
+func (a *MountedAsyncer) Set(key string, value []byte) error {
+	return fmt.Errorf("mounted %s is read-only", a.dir)
+}
+
+// version 返回 ..data 的指向，没有时返回文件的修改时间
+func (a *MountedAsyncer) version(key string) string {
+	if target, err := os.Readlink(filepath.Join(a.dir, "..data")); err == nil {
+		return target
+	}
+	info, err := os.Stat(filepath.Join(a.dir, key))
+	if err != nil {
+		return ""
+	}
+	return info.ModTime().String()
+}
+
+// Watch 每隔 MountPollInterval 检查变化
+func (a *MountedAsyncer) Watch(key string) chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if ch, ok := a.notifyChans[key]; ok {
+		return ch
+	}
+
+	ch := make(chan struct{}, 1)
+	a.notifyChans[key] = ch
+	a.versions[key] = a.version(key)
+	if len(a.notifyChans) == 1 {
+		go a.poll()
+	}
+	return ch
+}
+
+func (a *MountedAsyncer) poll() {
+	ticker := time.NewTicker(MountPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.quit:
+			return
+		case <-ticker.C:
+			a.check()
+		}
+	}
+}
+
+func (a *MountedAsyncer) check() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for key, ch := range a.notifyChans {
+		if v := a.version(key); v != a.versions[key] {
+			a.versions[key] = v
+			notify(ch)
+		}
+	}
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *MountedAsyncer) Capabilities() config.Capabilities {
+	return config.Capabilities{Watch: true}
+}
+
+// Close 停止检查
+func (a *MountedAsyncer) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.quit)
+	})
+	return nil
+}
@@ -0,0 +1,87 @@
+package k8sconfigmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// mount 模拟kubelet的挂载方式：数据目录 + ..data 符号链接 + 指向 ..data/<name> 的符号链接
+func mount(t *testing.T, dir, version string, files map[string]string) {
+	data := filepath.Join(dir, version)
+	if err := os.Mkdir(data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(data, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			if err := os.Symlink(filepath.Join("..data", name), link); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(version, tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMounted(t *testing.T) {
+	ast := assert.New(t)
+
+	MountPollInterval = 10 * time.Millisecond
+	defer func() { MountPollInterval = 5 * time.Second }()
+
+	dir := t.TempDir()
+	mount(t, dir, "..v1", map[string]string{"app.json": `{"a": 1}`})
+
+	a := NewMounted(dir)
+	defer a.Close()
+
+	ast.Equal(`{"a": 1}`, string(a.Get("app.json")))
+	ast.Nil(a.Get("missing.json"))
+	ast.NotNil(a.Set("app.json", nil))
+	ast.Equal(config.T_YAML, a.ContentType("app.yaml"))
+
+	cfg := config.NewAsyncConfig(a, "app.json", 0, false)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	mount(t, dir, "..v2", map[string]string{"app.json": `{"a": 2}`})
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 2
+	}, 2*time.Second, 5*time.Millisecond)
+
+	ast.Nil(a.Close())
+}
+
+func TestMountedPlainDir(t *testing.T) {
+	ast := assert.New(t)
+
+	MountPollInterval = 10 * time.Millisecond
+	defer func() { MountPollInterval = 5 * time.Second }()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.json")
+	ast.Nil(ioutil.WriteFile(file, []byte(`{"a": 1}`), 0644))
+
+	a := NewMounted(dir)
+	defer a.Close()
+
+	ch := a.Watch("app.json")
+	ast.Nil(ioutil.WriteFile(file, []byte(`{"a": 2}`), 0644))
+	later := time.Now().Add(time.Second)
+	ast.Nil(os.Chtimes(file, later, later))
+	ast.True(waitNotify(ch))
+}
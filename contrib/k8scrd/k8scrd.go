@@ -0,0 +1,190 @@
+// Package k8scrd 使用Kubernetes自定义资源（CRD）作为异步配置的数据源
+//
+// 通过dynamic client的informer监听资源变化，资源的spec作为配置树：
+//
+//	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "appconfigs"}
+//	asyncer, err := k8scrd.NewForConfig(restConfig, gvr, "default")
+//	cfg := config.NewAsyncConfig(asyncer, "billing", 0, false) // 名为billing的AppConfig
+package k8scrd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	// SpecField 作为配置树的资源字段
+	SpecField = "spec"
+	// SyncTimeout 等待informer首次同步的超时时间
+	SyncTimeout = 30 * time.Second
+	// ResyncPeriod informer全量重新同步的周期，0 不重新同步
+	ResyncPeriod time.Duration = 0
+)
+
+// Asyncer 以CRD为数据源的 config.Asyncer
+//
+// key为资源名称，监听所有namespace时为 namespace/name
+type Asyncer struct {
+	client    dynamic.Interface
+	gvr       schema.GroupVersionResource
+	namespace string
+	informer  cache.SharedIndexInformer
+
+	notifyChans sync.Map // key => chan struct{}
+	stop        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewForConfig 使用rest.Config创建dynamic client，见 New
+func NewForConfig(c *rest.Config, gvr schema.GroupVersionResource, namespace string) (*Asyncer, error) {
+	client, err := dynamic.NewForConfig(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "create dynamic client")
+	}
+	return New(client, gvr, namespace)
+}
+
+// New 启动informer并等待首次同步完成
+//
+// namespace: 资源所在的namespace，空值监听所有namespace（集群级资源也传空值）
+func New(client dynamic.Interface, gvr schema.GroupVersionResource, namespace string) (*Asyncer, error) {
+	a := &Asyncer{
+		client:    client,
+		gvr:       gvr,
+		namespace: namespace,
+		stop:      make(chan struct{}),
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, ResyncPeriod, namespace, nil)
+	a.informer = factory.ForResource(gvr).Informer()
+	if _, err := a.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    a.onEvent,
+		UpdateFunc: func(_, obj interface{}) { a.onEvent(obj) },
+		DeleteFunc: a.onEvent,
+	}); err != nil {
+		return nil, errors.Wrap(err, "add event handler")
+	}
+
+	go a.informer.Run(a.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), SyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), a.informer.HasSynced) {
+		a.Close()
+		return nil, errors.Errorf("sync %s timeout", gvr)
+	}
+
+	return a, nil
+}
+
+func (a *Asyncer) onEvent(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	keys := []string{u.GetNamespace() + "/" + u.GetName()}
+	if u.GetNamespace() == "" || u.GetNamespace() == a.namespace {
+		keys = append(keys, u.GetName())
+	}
+	for _, key := range keys {
+		if ch, ok := a.notifyChans.Load(key); ok {
+			select {
+			case ch.(chan struct{}) <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// storeKey 转换为informer缓存的key
+func (a *Asyncer) storeKey(key string) string {
+	if a.namespace != "" && !strings.Contains(key, "/") {
+		return a.namespace + "/" + key
+	}
+	return key
+}
+
+func (a *Asyncer) ContentType(key string) config.ContentType {
+	return config.T_JSON
+}
+
+// Get 返回资源spec的JSON，资源不存在时返回nil
+func (a *Asyncer) Get(key string) []byte {
+	obj, ok, err := a.informer.GetStore().GetByKey(a.storeKey(key))
+	if err != nil || !ok {
+		return nil
+	}
+
+	spec, ok, err := unstructured.NestedFieldNoCopy(obj.(*unstructured.Unstructured).Object, SpecField)
+	if err != nil || !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Set 更新资源的spec，资源需已存在
+func (a *Asyncer) Set(key string, value []byte) error {
+	var spec interface{}
+	if err := json.Unmarshal(value, &spec); err != nil {
+		return errors.Wrap(err, "unmarshal")
+	}
+
+	namespace, name := a.namespace, key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		namespace, name = key[:i], key[i+1:]
+	}
+
+	client := a.client.Resource(a.gvr).Namespace(namespace)
+	ctx := context.Background()
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "get %s[%s]", a.gvr.Resource, key)
+	}
+
+	obj.Object[SpecField] = spec
+	if _, err = client.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "update %s[%s]", a.gvr.Resource, key)
+	}
+	return nil
+}
+
+// Watch 资源新增、修改、删除时通知
+func (a *Asyncer) Watch(key string) chan struct{} {
+	ch, _ := a.notifyChans.LoadOrStore(key, make(chan struct{}, 1))
+	return ch.(chan struct{})
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *Asyncer) Capabilities() config.Capabilities {
+	return config.Capabilities{Watch: true}
+}
+
+// Close 停止informer
+func (a *Asyncer) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.stop)
+	})
+	return nil
+}
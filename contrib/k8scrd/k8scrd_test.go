@@ -0,0 +1,71 @@
+package k8scrd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var gvr = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "appconfigs"}
+
+func newAppConfig(namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "AppConfig",
+		"metadata": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+		},
+		"spec": spec,
+	}}
+}
+
+func TestAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "AppConfigList"},
+		newAppConfig("default", "billing", map[string]interface{}{
+			"db": map[string]interface{}{"host": "example.com"},
+		}),
+	)
+
+	asyncer, err := New(client, gvr, "default")
+	ast.Nil(err)
+	defer asyncer.Close()
+
+	cfg := config.NewAsyncConfig(asyncer, "billing", 0, false)
+	defer cfg.Close()
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Equal(asyncer.Get("billing"), asyncer.Get("default/billing"))
+	ast.Nil(asyncer.Get("not_exist"))
+
+	// 资源变化时刷新
+	ctx := context.Background()
+	obj := newAppConfig("default", "billing", map[string]interface{}{
+		"db": map[string]interface{}{"host": "example2.com"},
+	})
+	_, err = client.Resource(gvr).Namespace("default").Update(ctx, obj, metav1.UpdateOptions{})
+	ast.Nil(err)
+	for i := 0; i < 100 && cfg.String("db.host") != "example2.com"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	ast.Equal("example2.com", cfg.String("db.host"))
+
+	// 写回spec
+	ast.Nil(cfg.Set("db.port", 3306))
+	u, err := client.Resource(gvr).Namespace("default").Get(ctx, "billing", metav1.GetOptions{})
+	ast.Nil(err)
+	port, _, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "db", "port")
+	ast.EqualValues(3306, port)
+
+	ast.NotNil(asyncer.Set("not_exist", []byte(`{}`)))
+}
@@ -0,0 +1,143 @@
+// Package kafkaasyncer 以Kafka topic作为 config.BusAsyncer 的消息总线
+//
+//	asyncer, err := kafkaasyncer.New(&kafkaasyncer.Options{
+//		Brokers:       []string{"kafka:9092"},
+//		Topic:         "config",
+//		FromBeginning: true, // compact的topic，启动时读取每个key最新的内容
+//	}, nil)
+//	cfg := config.NewAsyncConfig(asyncer, "billing/app.json", 0, false)
+//
+// 消息的key为配置的key，value为变化后的配置；value为空（包括tombstone）时只通知变化，从store获取
+package kafkaasyncer
+
+import (
+	"context"
+	"time"
+
+	"github.com/kot-w/config"
+	_logger "github.com/kot-w/logger"
+	"github.com/pkg/errors"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// ProduceTimeout Publish的超时时间
+var ProduceTimeout = 10 * time.Second
+
+var logger config.Logger = _logger.Named("config.kafka")
+
+// Options 连接选项
+type Options struct {
+	Brokers []string
+	Topic   string
+	// FromBeginning 从最早的消息开始读取，适用于消息携带内容的compact topic；否则只接收新的消息
+	FromBeginning bool
+	// ClientOpts 其他 kgo 选项，如TLS、SASL
+	ClientOpts []kgo.Opt
+}
+
+// client kgo.Client 中用到的方法
+type client interface {
+	PollFetches(ctx context.Context) kgo.Fetches
+	ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults
+	Close()
+}
+
+// Bus 见 config.Bus，每个实例独立消费所有分区（不使用消费组），都能收到所有变化
+type Bus struct {
+	client client
+	topic  string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var _ config.Bus = (*Bus)(nil)
+
+func NewBus(options *Options) (*Bus, error) {
+	offset := kgo.NewOffset().AtEnd()
+	if options.FromBeginning {
+		offset = kgo.NewOffset().AtStart()
+	}
+
+	opts := append([]kgo.Opt{
+		kgo.SeedBrokers(options.Brokers...),
+		kgo.ConsumeTopics(options.Topic),
+		kgo.ConsumeResetOffset(offset),
+	}, options.ClientOpts...)
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "new kafka client for topic[%s]", options.Topic)
+	}
+
+	logger.Infof("NewKafkaBus:brokers=%v topic=%s", options.Brokers, options.Topic)
+
+	return newBus(cl, options.Topic), nil
+}
+
+func newBus(cl client, topic string) *Bus {
+	b := &Bus{client: cl, topic: topic}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	return b
+}
+
+// New 见 config.NewBusAsyncer，store为nil时内容只来自消息
+func New(options *Options, store config.Asyncer) (*config.BusAsyncer, error) {
+	bus, err := NewBus(options)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := config.NewBusAsyncer(bus, store)
+	if err != nil {
+		bus.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Subscribe 在后台消费topic，直到Close
+func (b *Bus) Subscribe(handler func(config.BusEvent)) error {
+	go b.consume(handler)
+	return nil
+}
+
+func (b *Bus) consume(handler func(config.BusEvent)) {
+	for {
+		fetches := b.client.PollFetches(b.ctx)
+		if b.ctx.Err() != nil || fetches.IsClientClosed() {
+			return
+		}
+		for _, e := range fetches.Errors() {
+			logger.Warnf("consume kafka[%s] partition %d err:%v", e.Topic, e.Partition, e.Err)
+		}
+
+		fetches.EachRecord(func(r *kgo.Record) {
+			if len(r.Key) == 0 {
+				logger.Warnf("kafka[%s] message without key at offset %d", r.Topic, r.Offset)
+				return
+			}
+
+			e := config.BusEvent{Key: string(r.Key)}
+			if len(r.Value) > 0 {
+				e.Payload = r.Value
+			}
+			handler(e)
+		})
+	}
+}
+
+// Publish 同步写入，等待broker确认
+func (b *Bus) Publish(e config.BusEvent) error {
+	ctx, cancel := context.WithTimeout(b.ctx, ProduceTimeout)
+	defer cancel()
+
+	r := &kgo.Record{Topic: b.topic, Key: []byte(e.Key), Value: e.Payload}
+	return errors.Wrapf(b.client.ProduceSync(ctx, r).FirstErr(), "produce kafka[%s]", b.topic)
+}
+
+// Close 停止消费并关闭连接
+func (b *Bus) Close() error {
+	b.cancel()
+	b.client.Close()
+	return nil
+}
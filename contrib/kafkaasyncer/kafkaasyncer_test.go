@@ -0,0 +1,172 @@
+package kafkaasyncer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// fakeTopic 单分区的topic，所有client都能读取全部消息
+type fakeTopic struct {
+	sync.Mutex
+	records []*kgo.Record
+	cond    chan struct{} // 有新消息时关闭并替换
+}
+
+func newFakeTopic() *fakeTopic {
+	return &fakeTopic{cond: make(chan struct{})}
+}
+
+func (t *fakeTopic) append(r *kgo.Record) {
+	t.Lock()
+	defer t.Unlock()
+	r.Offset = int64(len(t.records))
+	t.records = append(t.records, r)
+	close(t.cond)
+	t.cond = make(chan struct{})
+}
+
+type fakeClient struct {
+	topic  *fakeTopic
+	offset int
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newFakeClient(topic *fakeTopic, fromBeginning bool) *fakeClient {
+	c := &fakeClient{topic: topic, closed: make(chan struct{})}
+	if !fromBeginning {
+		topic.Lock()
+		c.offset = len(topic.records)
+		topic.Unlock()
+	}
+	return c
+}
+
+func (c *fakeClient) PollFetches(ctx context.Context) kgo.Fetches {
+	for {
+		c.topic.Lock()
+		records, cond := c.topic.records[c.offset:], c.topic.cond
+		c.topic.Unlock()
+
+		if len(records) > 0 {
+			c.offset += len(records)
+			return kgo.Fetches{{Topics: []kgo.FetchTopic{{
+				Topic:      "config",
+				Partitions: []kgo.FetchPartition{{Records: records}},
+			}}}}
+		}
+
+		select {
+		case <-cond:
+		case <-c.closed:
+			return kgo.NewErrFetch(kgo.ErrClientClosed)
+		case <-ctx.Done():
+			return kgo.NewErrFetch(ctx.Err())
+		}
+	}
+}
+
+func (c *fakeClient) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	results := make(kgo.ProduceResults, 0, len(rs))
+	for _, r := range rs {
+		if ctx.Err() != nil {
+			results = append(results, kgo.ProduceResult{Record: r, Err: ctx.Err()})
+			continue
+		}
+		c.topic.append(&kgo.Record{Topic: r.Topic, Key: r.Key, Value: r.Value})
+		results = append(results, kgo.ProduceResult{Record: r})
+	}
+	return results
+}
+
+func (c *fakeClient) Close() {
+	c.once.Do(func() { close(c.closed) })
+}
+
+func TestBus(t *testing.T) {
+	ast := assert.New(t)
+
+	topic := newFakeTopic()
+	topic.append(&kgo.Record{Key: []byte("app.json"), Value: []byte(`{"a": 1}`)})
+
+	// 从头读取时能获取到已有的内容
+	bus := newBus(newFakeClient(topic, true), "config")
+	a, err := config.NewBusAsyncer(bus, nil)
+	ast.Nil(err)
+	defer a.Close()
+	ast.Eventually(func() bool {
+		return string(a.Get("app.json")) == `{"a": 1}`
+	}, time.Second, 5*time.Millisecond)
+
+	latest := newBus(newFakeClient(topic, false), "config")
+	b, err := config.NewBusAsyncer(latest, nil)
+	ast.Nil(err)
+	defer b.Close()
+	ast.Nil(b.Get("app.json"))
+
+	cfg := config.NewAsyncConfig(a, "app.json", time.Hour, false)
+	ast.EqualValues(1, cfg.Int("a"))
+	ch := make(chan struct{}, 1)
+	cfg.Watch(ch)
+
+	ast.Nil(b.Set("app.json", []byte(`{"a": 2}`)))
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		ast.Fail("not notified")
+	}
+	ast.EqualValues(2, cfg.Int("a"))
+	ast.Eventually(func() bool {
+		return string(b.Get("app.json")) == `{"a": 2}`
+	}, time.Second, 5*time.Millisecond)
+
+	// 没有key的消息被忽略，tombstone视为删除
+	topic.append(&kgo.Record{Value: []byte(`{"a": 3}`)})
+	topic.append(&kgo.Record{Key: []byte("app.json")})
+	ast.Eventually(func() bool {
+		return b.Get("app.json") == nil
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestBusWithStore(t *testing.T) {
+	ast := assert.New(t)
+
+	topic := newFakeTopic()
+	store := config.NewMockAsyncer(false)
+	a, err := config.NewBusAsyncer(newBus(newFakeClient(topic, false), "config"), store)
+	ast.Nil(err)
+	defer a.Close()
+	b, err := config.NewBusAsyncer(newBus(newFakeClient(topic, false), "config"), store)
+	ast.Nil(err)
+
+	ch := b.Watch("app.json")
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		ast.Fail("not notified")
+	}
+	ast.Contains(string(b.Get("app.json")), `"a":1`)
+
+	// 只通知变化，消息中不包含内容
+	topic.Lock()
+	ast.Nil(topic.records[0].Value)
+	topic.Unlock()
+
+	ast.Nil(b.Close())
+	ast.NotNil(b.Set("app.json", []byte(`{}`)))
+}
+
+func TestNewBus(t *testing.T) {
+	ast := assert.New(t)
+
+	bus, err := NewBus(&Options{Brokers: []string{"127.0.0.1:1"}, Topic: "config"})
+	ast.Nil(err)
+	ast.Nil(bus.Close())
+}
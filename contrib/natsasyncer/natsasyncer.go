@@ -0,0 +1,85 @@
+// Package natsasyncer 以NATS subject作为 config.BusAsyncer 的消息总线
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	asyncer, err := natsasyncer.New(nc, "config.changed", nil)
+//	cfg := config.NewAsyncConfig(asyncer, "billing/app.json", 0, false)
+//
+// 消息头 Config-Key 为配置的key，消息内容为变化后的配置；内容为空时只通知变化，从store获取
+package natsasyncer
+
+import (
+	"time"
+
+	"github.com/kot-w/config"
+	_logger "github.com/kot-w/logger"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// KeyHeader 消息头中配置的key
+const KeyHeader = "Config-Key"
+
+// FlushTimeout Publish等待服务端确认的超时时间
+var FlushTimeout = 5 * time.Second
+
+var logger config.Logger = _logger.Named("config.nats")
+
+// Bus 见 config.Bus，连接由调用方管理，Close不关闭连接
+type Bus struct {
+	conn    *nats.Conn
+	subject string
+	sub     *nats.Subscription
+}
+
+var _ config.Bus = (*Bus)(nil)
+
+func NewBus(conn *nats.Conn, subject string) *Bus {
+	return &Bus{conn: conn, subject: subject}
+}
+
+// New 见 config.NewBusAsyncer，store为nil时内容只来自消息
+func New(conn *nats.Conn, subject string, store config.Asyncer) (*config.BusAsyncer, error) {
+	return config.NewBusAsyncer(NewBus(conn, subject), store)
+}
+
+// Subscribe 同一订阅的消息按顺序串行处理
+func (b *Bus) Subscribe(handler func(config.BusEvent)) error {
+	sub, err := b.conn.Subscribe(b.subject, func(msg *nats.Msg) {
+		key := msg.Header.Get(KeyHeader)
+		if key == "" {
+			logger.Warnf("nats[%s] message without %s header", b.subject, KeyHeader)
+			return
+		}
+
+		e := config.BusEvent{Key: key}
+		if len(msg.Data) > 0 {
+			e.Payload = msg.Data
+		}
+		handler(e)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "subscribe nats[%s]", b.subject)
+	}
+
+	b.sub = sub
+	return nil
+}
+
+// Publish 发布后等待服务端确认
+func (b *Bus) Publish(e config.BusEvent) error {
+	msg := nats.NewMsg(b.subject)
+	msg.Header.Set(KeyHeader, e.Key)
+	msg.Data = e.Payload
+	if err := b.conn.PublishMsg(msg); err != nil {
+		return errors.Wrapf(err, "publish nats[%s]", b.subject)
+	}
+	return errors.Wrapf(b.conn.FlushTimeout(FlushTimeout), "flush nats[%s]", b.subject)
+}
+
+// Close 取消订阅
+func (b *Bus) Close() error {
+	if b.sub == nil {
+		return nil
+	}
+	return b.sub.Unsubscribe()
+}
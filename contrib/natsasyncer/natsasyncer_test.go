@@ -0,0 +1,93 @@
+package natsasyncer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+func runServer(t *testing.T) *server.Server {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	s := natsserver.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func connect(t *testing.T, s *server.Server) *nats.Conn {
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func TestAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	s := runServer(t)
+	publisher, err := New(connect(t, s), "config.changed", nil)
+	ast.Nil(err)
+	defer publisher.Close()
+	subscriber, err := New(connect(t, s), "config.changed", nil)
+	ast.Nil(err)
+	defer subscriber.Close()
+
+	cfg := config.NewAsyncConfig(subscriber, "billing/app.json", time.Hour, false)
+	ch := make(chan struct{}, 1)
+	cfg.Watch(ch)
+
+	ast.Nil(publisher.Set("billing/app.json", []byte(`{"a": 1}`)))
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		ast.Fail("not notified")
+	}
+	ast.EqualValues(1, cfg.Int("a"))
+
+	// 没有key的消息被忽略
+	nc := connect(t, s)
+	ast.Nil(nc.Publish("config.changed", []byte(`{"a": 2}`)))
+	ast.Nil(nc.Flush())
+	select {
+	case <-ch:
+		ast.Fail("notified without key")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAsyncerWithStore(t *testing.T) {
+	ast := assert.New(t)
+
+	s := runServer(t)
+	backend := config.NewMockAsyncer(false)
+	publisher, err := New(connect(t, s), "config.changed", backend)
+	ast.Nil(err)
+	subscriber, err := New(connect(t, s), "config.changed", backend)
+	ast.Nil(err)
+	defer subscriber.Close()
+
+	ch := subscriber.Watch("app.json")
+	ast.Nil(publisher.Set("app.json", []byte(`{"a": 1}`)))
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		ast.Fail("not notified")
+	}
+	ast.Contains(string(subscriber.Get("app.json")), `"a":1`)
+
+	// 取消订阅后不再收到消息
+	ast.Nil(subscriber.Close())
+	ast.Nil(publisher.Set("app.json", []byte(`{"a": 2}`)))
+	select {
+	case <-ch:
+		ast.Fail("notified after close")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
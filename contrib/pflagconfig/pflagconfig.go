@@ -0,0 +1,94 @@
+// Package pflagconfig 将 github.com/spf13/pflag（及cobra）的flag作为配置层，见 config.FlagSet
+package pflagconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kot-w/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Flags pflag的FlagSet，nil 为 pflag.CommandLine
+//
+//	pflag.Parse()
+//	config.AddFlagLayer(pflagconfig.Flags(nil), config.WithFlagSeparator("-"))
+func Flags(fs *pflag.FlagSet) config.FlagSet {
+	if fs == nil {
+		fs = pflag.CommandLine
+	}
+	return flags{fs}
+}
+
+type flags struct {
+	fs *pflag.FlagSet
+}
+
+func (f flags) VisitAll(fn func(name string, value interface{}, changed bool)) {
+	f.fs.VisitAll(func(flag *pflag.Flag) {
+		fn(flag.Name, flagValue(flag.Value), flag.Changed)
+	})
+}
+
+// flagValue 按flag的类型转换，数组为 []interface{}，其他类型为字符串
+func flagValue(v pflag.Value) interface{} {
+	if s, ok := v.(pflag.SliceValue); ok {
+		items := s.GetSlice()
+		ret := make([]interface{}, len(items))
+		for i, item := range items {
+			ret[i] = item
+		}
+		return ret
+	}
+
+	s := v.String()
+	var (
+		ret interface{}
+		err error
+	)
+	switch v.Type() {
+	case "bool":
+		ret, err = strconv.ParseBool(s)
+	case "int", "int8", "int16", "int32", "int64":
+		ret, err = strconv.ParseInt(s, 10, 64)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		ret, err = strconv.ParseUint(s, 10, 64)
+	case "float32", "float64":
+		ret, err = strconv.ParseFloat(s, 64)
+	default:
+		return s
+	}
+	if err != nil {
+		return s
+	}
+	return ret
+}
+
+// SetDefault 数组flag的值为JSON数组时替换为其元素，命令行中设置时不会追加到默认值之后
+func (f flags) SetDefault(name, value string) error {
+	flag := f.fs.Lookup(name)
+	if flag == nil {
+		return errors.Errorf("flag[%s] not defined", name)
+	}
+
+	if s, ok := flag.Value.(pflag.SliceValue); ok && strings.HasPrefix(value, "[") {
+		var items []interface{}
+		if err := json.Unmarshal([]byte(value), &items); err != nil {
+			return err
+		}
+		strs := make([]string, len(items))
+		for i, item := range items {
+			strs[i] = fmt.Sprint(item)
+		}
+		if err := s.Replace(strs); err != nil {
+			return err
+		}
+	} else if err := flag.Value.Set(value); err != nil {
+		return err
+	}
+	flag.DefValue = flag.Value.String()
+	return nil
+}
@@ -0,0 +1,61 @@
+package pflagconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("db-host", "localhost", "")
+	fs.Int("db-port", 3306, "")
+	fs.Bool("debug", false, "")
+	fs.Duration("timeout", time.Second, "")
+	fs.StringSlice("peers", nil, "")
+	return fs
+}
+
+func TestFlags(t *testing.T) {
+	ast := assert.New(t)
+
+	fs := newFlagSet()
+	ast.Nil(fs.Parse([]string{"--db-port=3307", "--debug", "--timeout=5s", "--peers=a,b"}))
+
+	cfg := config.NewFlagConfig(Flags(fs), config.WithFlagSeparator("-"))
+	ast.Equal(map[string]interface{}{
+		"db":      map[string]interface{}{"port": int64(3307)},
+		"debug":   true,
+		"timeout": "5s",
+		"peers":   []interface{}{"a", "b"},
+	}, cfg.Get(config.RootKey))
+}
+
+func TestSetDefault(t *testing.T) {
+	ast := assert.New(t)
+
+	fs := newFlagSet()
+	remote := config.NewMapConfig(map[string]interface{}{
+		"db":    map[string]interface{}{"host": "remote", "port": float64(5432)},
+		"peers": []interface{}{"x", "y"},
+	})
+	ast.Nil(config.SetFlagDefaults(Flags(fs), remote, config.WithFlagSeparator("-")))
+
+	host, _ := fs.GetString("db-host")
+	ast.Equal("remote", host)
+	ast.Equal("[x,y]", fs.Lookup("peers").DefValue)
+	ast.False(fs.Changed("db-host"))
+
+	// 命令行中的数组替换默认值
+	ast.Nil(fs.Parse([]string{"--peers=z"}))
+	peers, _ := fs.GetStringSlice("peers")
+	ast.Equal([]string{"z"}, peers)
+	port, _ := fs.GetInt("db-port")
+	ast.Equal(5432, port)
+
+	ast.NotNil(Flags(fs).SetDefault("missing", "1"))
+	ast.NotNil(Flags(fs).SetDefault("db-port", "x"))
+}
@@ -0,0 +1,28 @@
+// 测试用的WASM模块：将 timeout_ms 换算为 timeout（秒），key为 fail 时返回错误
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var tree map[string]interface{}
+	if err := json.NewDecoder(os.Stdin).Decode(&tree); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if _, ok := tree["fail"]; ok {
+		fmt.Fprintln(os.Stderr, "fail requested")
+		os.Exit(2)
+	}
+
+	if ms, ok := tree["timeout_ms"].(float64); ok {
+		tree["timeout"] = ms / 1000
+		delete(tree, "timeout_ms")
+	}
+
+	json.NewEncoder(os.Stdout).Encode(tree)
+}
@@ -0,0 +1,105 @@
+// Package wasmtransform 基于WASM模块的 config.Transformer 实现
+//
+// 模块为WASI命令程序：从stdin读取json格式的配置，将改写后的json写入stdout，
+// 以非0状态退出表示失败，stderr的内容作为错误信息。任何能编译到WASI的语言均可实现，
+// 如Go：
+//
+//	GOOS=wasip1 GOARCH=wasm go build -o transform.wasm ./transform
+//
+//	t, err := wasmtransform.Load(ctx, "transform.wasm")
+//	cfg := config.NewAsyncConfig(asyncer, key, cacheTime, false, config.WithTransformer(t))
+package wasmtransform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Timeout 单次改写的超时时间
+var Timeout = 5 * time.Second
+
+type Transformer struct {
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+}
+
+// Load 编译WASM模块文件
+func Load(ctx context.Context, path string) (*Transformer, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(ctx, code)
+}
+
+// New 编译WASM模块
+func New(ctx context.Context, code []byte) (*Transformer, error) {
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	module, err := r.CompileModule(ctx, code)
+	if err != nil {
+		r.Close(ctx)
+		return nil, errors.Wrap(err, "compile wasm module")
+	}
+
+	return &Transformer{
+		runtime: r,
+		module:  module,
+	}, nil
+}
+
+// Transform 每次改写都会实例化新的模块，模块之间不共享状态
+func (t *Transformer) Transform(tree map[string]interface{}) (map[string]interface{}, error) {
+	input, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	conf := wazero.NewModuleConfig().
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithName("")
+
+	mod, err := t.runtime.InstantiateModule(ctx, t.module, conf)
+	if mod != nil {
+		mod.Close(ctx)
+	}
+	if err != nil {
+		if exitErr, ok := err.(*sys.ExitError); ok && exitErr.ExitCode() == 0 {
+			err = nil
+		} else if stderr.Len() > 0 {
+			return nil, errors.Errorf("wasm transform: %s", bytes.TrimSpace(stderr.Bytes()))
+		} else {
+			return nil, errors.Wrap(err, "wasm transform")
+		}
+	}
+
+	var ret map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &ret); err != nil {
+		return nil, errors.Wrap(err, "wasm transform output")
+	}
+
+	return ret, nil
+}
+
+func (t *Transformer) Close() error {
+	return t.runtime.Close(context.Background())
+}
@@ -0,0 +1,49 @@
+package wasmtransform
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildModule(t *testing.T) string {
+	out := filepath.Join(t.TempDir(), "rename.wasm")
+	cmd := exec.Command("go", "build", "-o", out, "./testdata/rename")
+	cmd.Env = append(cmd.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("build wasm module: %v %s", err, output)
+	}
+	return out
+}
+
+func TestTransformer(t *testing.T) {
+	ast := assert.New(t)
+
+	tr, err := Load(context.Background(), buildModule(t))
+	ast.Nil(err)
+	defer tr.Close()
+
+	ret, err := tr.Transform(map[string]interface{}{
+		"timeout_ms": 1500,
+		"name":       "foo",
+	})
+	ast.Nil(err)
+	ast.Equal(map[string]interface{}{"timeout": 1.5, "name": "foo"}, ret)
+
+	_, err = tr.Transform(map[string]interface{}{"fail": true})
+	ast.EqualError(err, "wasm transform: fail requested")
+
+	asyncer := config.NewMockAsyncer(false)
+	asyncer.Set("app", []byte(`{"timeout_ms": 3000}`))
+	cfg := config.NewAsyncConfig(asyncer, "app", time.Hour, false, config.WithTransformer(tr))
+	ast.Equal(3.0, cfg.Float("timeout"))
+	ast.Nil(cfg.Get("timeout_ms"))
+
+	_, err = New(context.Background(), []byte("not wasm"))
+	ast.NotNil(err)
+}
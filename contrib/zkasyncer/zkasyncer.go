@@ -0,0 +1,266 @@
+// Package zkasyncer 使用ZooKeeper作为异步配置的数据源
+//
+// key为znode路径（相对 New 的prefix），通过znode watch监听变化。
+// 连接断开后由zk client重连，session过期后watch失效，重新注册watch并通知重新读取：
+//
+//	asyncer, err := zkasyncer.Connect([]string{"127.0.0.1:2181"}, 10*time.Second, "/config")
+//	cfg := config.NewAsyncConfig(asyncer, "/billing.json", 0, false) // znode为 /config/billing.json
+package zkasyncer
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/kot-w/config"
+	_logger "github.com/kot-w/logger"
+	"github.com/pkg/errors"
+)
+
+var (
+	// RetryMin 注册watch失败后首次重试的等待时间，之后每次翻倍
+	RetryMin = 100 * time.Millisecond
+	// RetryMax 重试等待时间的上限
+	RetryMax = 30 * time.Second
+)
+
+var logger config.Logger = _logger.Named("config.zk")
+
+// Conn *zk.Conn 中用到的方法
+type Conn interface {
+	Get(path string) ([]byte, *zk.Stat, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Close()
+}
+
+var _ Conn = (*zk.Conn)(nil)
+
+// Asyncer 以ZooKeeper为数据源的 config.Asyncer
+type Asyncer struct {
+	conn   Conn
+	prefix string
+	acl    []zk.ACL
+	owned  bool // Connect 创建的连接，Close时关闭
+
+	zxids       sync.Map // key => int64，最近一次Get时znode的Mzxid
+	notifyChans sync.Map // key => chan struct{}
+	quit        chan struct{}
+	closeOnce   sync.Once
+	wg          sync.WaitGroup
+}
+
+var (
+	_ config.Asyncer         = (*Asyncer)(nil)
+	_ config.CASSetter       = (*Asyncer)(nil)
+	_ config.VersionedSetter = (*Asyncer)(nil)
+)
+
+// Connect 连接ZooKeeper集群，见 New
+func Connect(servers []string, sessionTimeout time.Duration, prefix string) (*Asyncer, error) {
+	conn, events, err := zk.Connect(servers, sessionTimeout, zk.WithLogInfo(false))
+	if err != nil {
+		return nil, errors.Wrap(err, "connect zookeeper")
+	}
+
+	a := New(conn, prefix)
+	a.owned = true
+	go a.logSession(events)
+	return a, nil
+}
+
+// New 使用已有的连接，prefix为所有znode路径的前缀，Close不关闭连接
+//
+// Set创建znode时使用 zk.WorldACL(zk.PermAll)，见 SetACL
+func New(conn Conn, prefix string) *Asyncer {
+	return &Asyncer{
+		conn:   conn,
+		prefix: prefix,
+		acl:    zk.WorldACL(zk.PermAll),
+		quit:   make(chan struct{}),
+	}
+}
+
+// SetACL Set及CompareAndSet创建znode时使用的ACL
+func (a *Asyncer) SetACL(acl []zk.ACL) {
+	a.acl = acl
+}
+
+func (a *Asyncer) logSession(events <-chan zk.Event) {
+	for ev := range events {
+		switch ev.State {
+		case zk.StateExpired:
+			logger.Warnf("zookeeper session expired, watches will be re-registered")
+		case zk.StateHasSession:
+			logger.Infof("zookeeper session established")
+		case zk.StateDisconnected:
+			logger.Warnf("zookeeper disconnected from %s", ev.Server)
+		}
+	}
+}
+
+func (a *Asyncer) path(key string) string {
+	return a.prefix + key
+}
+
+// ContentType 根据key的后缀判断，见 config.ContentTypeByExt
+func (a *Asyncer) ContentType(key string) config.ContentType {
+	return config.ContentTypeByExt(key)
+}
+
+// Get znode不存在或读取失败时返回nil
+func (a *Asyncer) Get(key string) []byte {
+	data, stat, err := a.conn.Get(a.path(key))
+	if err == zk.ErrNoNode {
+		a.zxids.Store(key, int64(0))
+		return nil
+	}
+	if err != nil {
+		logger.Errorf("read conf[%s] from zookeeper err:%v", key, err)
+		return nil
+	}
+	a.zxids.Store(key, stat.Mzxid)
+
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+func (a *Asyncer) Set(key string, value []byte) error {
+	_, err := a.SetVersioned(key, value)
+	return err
+}
+
+// SetVersioned 返回写入后znode的版本号，znode不存在时创建（父节点需已存在）
+func (a *Asyncer) SetVersioned(key string, value []byte) (string, error) {
+	path := a.path(key)
+	stat, err := a.conn.Set(path, value, -1)
+	if err == zk.ErrNoNode {
+		if _, err = a.conn.Create(path, value, 0, a.acl); err == nil {
+			return "0", nil
+		}
+		if err == zk.ErrNodeExists {
+			// 并发创建
+			stat, err = a.conn.Set(path, value, -1)
+		}
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "set zookeeper[%s]", key)
+	}
+	return strconv.FormatInt(int64(stat.Version), 10), nil
+}
+
+// CompareAndSet 使用znode版本号实现，old为nil时要求znode不存在
+func (a *Asyncer) CompareAndSet(key string, old, value []byte) (bool, error) {
+	path := a.path(key)
+	if old == nil {
+		_, err := a.conn.Create(path, value, 0, a.acl)
+		if err == zk.ErrNodeExists {
+			return false, nil
+		}
+		return err == nil, errors.Wrapf(err, "create zookeeper[%s]", key)
+	}
+
+	data, stat, err := a.conn.Get(path)
+	if err == zk.ErrNoNode {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "get zookeeper[%s]", key)
+	}
+	if !bytes.Equal(data, old) {
+		return false, nil
+	}
+
+	_, err = a.conn.Set(path, value, stat.Version)
+	if err == zk.ErrBadVersion || err == zk.ErrNoNode {
+		return false, nil
+	}
+	return err == nil, errors.Wrapf(err, "set zookeeper[%s]", key)
+}
+
+// Watch znode创建、修改、删除时通知，同一key只注册一个watch
+//
+// znode watch只触发一次，触发后重新注册；watch失效（session过期）时通知并重新注册。
+// 注册时znode已不同于最近一次Get的内容时也会通知
+func (a *Asyncer) Watch(key string) chan struct{} {
+	ch := make(chan struct{}, 1)
+	if actual, loaded := a.notifyChans.LoadOrStore(key, ch); loaded {
+		return actual.(chan struct{})
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.watch(key, ch)
+	}()
+	return ch
+}
+
+func (a *Asyncer) watch(key string, ch chan struct{}) {
+	path := a.path(key)
+	backoff := RetryMin
+	zxid, seen := a.zxids.Load(key)
+	for {
+		_, stat, events, err := a.conn.ExistsW(path)
+		if err != nil {
+			logger.Warnf("zookeeper watch[%s] err:%v", key, err)
+			select {
+			case <-a.quit:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > RetryMax {
+				backoff = RetryMax
+			}
+			// 注册失败期间的变化无法得知
+			notify(ch)
+			continue
+		}
+		backoff = RetryMin
+
+		// Get与注册watch之间的变化
+		if seen && stat.Mzxid != zxid.(int64) {
+			notify(ch)
+		}
+		zxid, seen = stat.Mzxid, true
+
+		select {
+		case <-a.quit:
+			return
+		case ev := <-events:
+			if ev.Type == zk.EventNotWatching {
+				logger.Warnf("zookeeper watch[%s] lost:%v, re-register", key, ev.Err)
+			}
+			notify(ch)
+		}
+	}
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Capabilities 见 config.CapabilityReporter
+func (a *Asyncer) Capabilities() config.Capabilities {
+	return config.Capabilities{Watch: true, CAS: true, Version: true}
+}
+
+// Close 停止所有watch，Connect 创建时关闭连接
+func (a *Asyncer) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.quit)
+		a.wg.Wait()
+		if a.owned {
+			a.conn.Close()
+		}
+	})
+	return nil
+}
@@ -0,0 +1,222 @@
+package zkasyncer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn 内存中的ZooKeeper
+type fakeConn struct {
+	sync.Mutex
+	nodes    map[string]*fakeNode
+	watches  map[string][]chan zk.Event
+	zxid     int64
+	failures int // ExistsW 接下来失败的次数
+	closed   bool
+}
+
+type fakeNode struct {
+	data    []byte
+	version int32
+	mzxid   int64
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		nodes:   make(map[string]*fakeNode),
+		watches: make(map[string][]chan zk.Event),
+	}
+}
+
+func (c *fakeConn) Get(path string) ([]byte, *zk.Stat, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	n, ok := c.nodes[path]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	return n.data, &zk.Stat{Version: n.version, Mzxid: n.mzxid}, nil
+}
+
+func (c *fakeConn) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.failures > 0 {
+		c.failures--
+		return false, nil, nil, zk.ErrNoServer
+	}
+
+	ch := make(chan zk.Event, 1)
+	c.watches[path] = append(c.watches[path], ch)
+	n, ok := c.nodes[path]
+	if !ok {
+		return false, &zk.Stat{}, ch, nil
+	}
+	return true, &zk.Stat{Version: n.version, Mzxid: n.mzxid}, ch, nil
+}
+
+// fire 触发并移除path上的watch
+func (c *fakeConn) fire(path string, ev zk.Event) {
+	for _, ch := range c.watches[path] {
+		ch <- ev
+	}
+	delete(c.watches, path)
+}
+
+func (c *fakeConn) Set(path string, data []byte, version int32) (*zk.Stat, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	n, ok := c.nodes[path]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	if version >= 0 && version != n.version {
+		return nil, zk.ErrBadVersion
+	}
+	c.zxid++
+	n.data = data
+	n.version++
+	n.mzxid = c.zxid
+	c.fire(path, zk.Event{Type: zk.EventNodeDataChanged, Path: path})
+	return &zk.Stat{Version: n.version}, nil
+}
+
+func (c *fakeConn) Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.nodes[path]; ok {
+		return "", zk.ErrNodeExists
+	}
+	c.zxid++
+	c.nodes[path] = &fakeNode{data: data, mzxid: c.zxid}
+	c.fire(path, zk.Event{Type: zk.EventNodeCreated, Path: path})
+	return path, nil
+}
+
+func (c *fakeConn) Close() {
+	c.Lock()
+	defer c.Unlock()
+	c.closed = true
+}
+
+// expire 模拟session过期，所有watch失效
+func (c *fakeConn) expire() {
+	c.Lock()
+	defer c.Unlock()
+	for path := range c.watches {
+		c.fire(path, zk.Event{Type: zk.EventNotWatching, State: zk.StateDisconnected, Path: path, Err: zk.ErrSessionExpired})
+	}
+}
+
+func (c *fakeConn) watchCount(path string) int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.watches[path])
+}
+
+func waitNotify(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestAsyncer(t *testing.T) {
+	ast := assert.New(t)
+
+	conn := newFakeConn()
+	a := New(conn, "/config")
+	defer a.Close()
+
+	ast.Nil(a.Get("/app.json"))
+	version, err := a.SetVersioned("/app.json", []byte(`{"a": 1}`))
+	ast.Nil(err)
+	ast.Equal("0", version)
+	ast.Equal(`{"a": 1}`, string(a.Get("/app.json")))
+	ast.Equal(`{"a": 1}`, string(conn.nodes["/config/app.json"].data))
+	version, err = a.SetVersioned("/app.json", []byte(`{"a": 2}`))
+	ast.Nil(err)
+	ast.Equal("1", version)
+
+	ok, err := a.CompareAndSet("/app.json", []byte(`{"a": 1}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("/app.json", []byte(`{"a": 2}`), []byte(`{"a": 3}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("/app.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.False(ok)
+	ok, err = a.CompareAndSet("/new.json", nil, []byte(`{}`))
+	ast.Nil(err)
+	ast.True(ok)
+	ok, err = a.CompareAndSet("/missing.json", []byte(`{}`), []byte(`{}`))
+	ast.Nil(err)
+	ast.False(ok)
+
+	ast.Equal(config.T_YAML, a.ContentType("/app.yaml"))
+	ast.Equal(config.Capabilities{Watch: true, CAS: true, Version: true}, config.ProbeCapabilities(a))
+
+	cfg := config.NewAsyncConfig(a, "/app.json", 0, false)
+	ast.EqualValues(3, cfg.Int("a"))
+	ast.Nil(a.Set("/app.json", []byte(`{"a": 4}`)))
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 4
+	}, time.Second, 5*time.Millisecond)
+
+	// Get与注册watch之间的变化
+	raced := New(conn, "/config")
+	defer raced.Close()
+	ast.Equal(`{"a": 4}`, string(raced.Get("/app.json")))
+	ast.Nil(raced.Set("/app.json", []byte(`{"a": 5}`)))
+	ast.True(waitNotify(raced.Watch("/app.json")))
+
+	ast.Nil(a.Close())
+	ast.False(conn.closed)
+}
+
+func TestWatchReregister(t *testing.T) {
+	ast := assert.New(t)
+
+	RetryMin = 10 * time.Millisecond
+	defer func() { RetryMin = 100 * time.Millisecond }()
+
+	conn := newFakeConn()
+	a := New(conn, "")
+
+	// 监听不存在的znode
+	ch := a.Watch("/k")
+	ast.Equal(ch, a.Watch("/k"))
+	ast.Eventually(func() bool { return conn.watchCount("/k") == 1 }, time.Second, time.Millisecond)
+	ast.Nil(a.Set("/k", []byte("1")))
+	ast.True(waitNotify(ch))
+
+	// 触发后重新注册
+	ast.Eventually(func() bool { return conn.watchCount("/k") == 1 }, time.Second, time.Millisecond)
+	ast.Nil(a.Set("/k", []byte("2")))
+	ast.True(waitNotify(ch))
+
+	// session过期后重新注册，注册失败时重试
+	ast.Eventually(func() bool { return conn.watchCount("/k") == 1 }, time.Second, time.Millisecond)
+	conn.Lock()
+	conn.failures = 2
+	conn.Unlock()
+	conn.expire()
+	ast.True(waitNotify(ch))
+	ast.Eventually(func() bool { return conn.watchCount("/k") == 1 }, time.Second, time.Millisecond)
+	ast.Nil(a.Set("/k", []byte("3")))
+	ast.True(waitNotify(ch))
+
+	ast.Nil(a.Close())
+}
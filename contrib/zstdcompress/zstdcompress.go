@@ -0,0 +1,57 @@
+// Package zstdcompress 为socket协议注册zstd压缩，客户端与服务端均需调用 Register
+//
+//	zstdcompress.Register()
+//	server, err := config.NewAgentServer(asyncer, "/run/config-agent.sock", time.Minute)
+package zstdcompress
+
+import (
+	"github.com/klauspost/compress/zstd"
+	"github.com/kot-w/config"
+	"github.com/pkg/errors"
+)
+
+// Name 注册的算法名，见 config.SocketEncodings
+const Name = "zstd"
+
+// Compressor zstd的 config.Compressor，可并发使用
+type Compressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func New() (*Compressor, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(config.SocketMaxFrameSize)))
+	if err != nil {
+		return nil, err
+	}
+	return &Compressor{encoder: encoder, decoder: decoder}, nil
+}
+
+// Register 注册为 Name
+func Register() error {
+	c, err := New()
+	if err != nil {
+		return err
+	}
+	config.RegisterCompressor(Name, c)
+	return nil
+}
+
+func (c *Compressor) Compress(data []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+func (c *Compressor) Decompress(data []byte) ([]byte, error) {
+	value, err := c.decoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "zstd")
+	}
+	if len(value) > int(config.SocketMaxFrameSize) {
+		return nil, errors.Errorf("decompressed value too large: > %d", config.SocketMaxFrameSize)
+	}
+	return value, nil
+}
@@ -0,0 +1,36 @@
+package zstdcompress
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kot-w/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressor(t *testing.T) {
+	ast := assert.New(t)
+
+	c, err := New()
+	ast.Nil(err)
+
+	value := bytes.Repeat([]byte(`{"host":"example.com"}`), 100)
+	data, err := c.Compress(value)
+	ast.Nil(err)
+	ast.Less(len(data), len(value))
+
+	decompressed, err := c.Decompress(data)
+	ast.Nil(err)
+	ast.Equal(value, decompressed)
+
+	_, err = c.Decompress([]byte("not zstd"))
+	ast.NotNil(err)
+}
+
+func TestRegister(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Nil(Register())
+	// 优先于gzip
+	ast.Equal(Name, config.SocketEncodings[0])
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// CryptoProvider 包内所有摘要及加解密操作的实现
+//
+// 默认实现使用 SHA-256 及 AES-GCM，均为FIPS认可的算法；
+// 面向FIPS/boringcrypto的构建可以替换为经过认证的实现：
+//
+//	config.SetCryptoProvider(fipsProvider)
+type CryptoProvider interface {
+	// NewHash 摘要算法，用于检测配置内容的变化
+	NewHash() hash.Hash
+	// NewAEAD 使用key创建对称加密算法
+	NewAEAD(key []byte) (cipher.AEAD, error)
+	// Rand 随机数来源
+	Rand() io.Reader
+}
+
+type defaultCryptoProvider struct{}
+
+func (p defaultCryptoProvider) NewHash() hash.Hash {
+	return sha256.New()
+}
+
+func (p defaultCryptoProvider) NewAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func (p defaultCryptoProvider) Rand() io.Reader {
+	return rand.Reader
+}
+
+var _cryptoProvider atomic.Value
+
+func init() {
+	SetCryptoProvider(defaultCryptoProvider{})
+}
+
+type cryptoProviderHolder struct {
+	CryptoProvider
+	gen uint64 // 每次替换时递增，见 digestScratch
+}
+
+var _cryptoProviderGen uint64
+
+// SetCryptoProvider 替换摘要及加解密的实现
+func SetCryptoProvider(p CryptoProvider) {
+	_cryptoProvider.Store(cryptoProviderHolder{p, atomic.AddUint64(&_cryptoProviderGen, 1)})
+}
+
+func getCryptoProvider() CryptoProvider {
+	return _cryptoProvider.Load().(cryptoProviderHolder).CryptoProvider
+}
+
+// digestScratch 计算摘要的临时空间，复用hash及输出的buffer
+//
+// 同一配置的刷新在singleflight中串行执行，每个配置一份，不需要加锁
+type digestScratch struct {
+	gen  uint64
+	h    hash.Hash
+	size [8]byte
+	sum  []byte
+	hex  []byte
+}
+
+// reset 开始计算新的摘要，CryptoProvider被替换时重新创建hash
+func (s *digestScratch) reset() hash.Hash {
+	holder := _cryptoProvider.Load().(cryptoProviderHolder)
+	if s.h == nil || s.gen != holder.gen {
+		s.gen, s.h = holder.gen, holder.NewHash()
+	} else {
+		s.h.Reset()
+	}
+	return s.h
+}
+
+// write 写入一段内容，与 digest 的格式一致
+func (s *digestScratch) write(content []byte) {
+	binary.BigEndian.PutUint64(s.size[:], uint64(len(content)))
+	s.h.Write(s.size[:])
+	s.h.Write(content)
+}
+
+// hexSum 返回hex编码的摘要，在下次调用前有效
+func (s *digestScratch) hexSum() []byte {
+	s.sum = s.h.Sum(s.sum[:0])
+	if n := hex.EncodedLen(len(s.sum)); cap(s.hex) < n {
+		s.hex = make([]byte, n)
+	} else {
+		s.hex = s.hex[:n]
+	}
+	hex.Encode(s.hex, s.sum)
+	return s.hex
+}
+
+// contentDigest 单个内容的摘要（hex编码），与流式读取时计算的一致，见 digestReader
+func contentDigest(content []byte) string {
+	h := getCryptoProvider().NewHash()
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// digest 计算内容的摘要（hex编码）
+func digest(contents ...[]byte) string {
+	h := getCryptoProvider().NewHash()
+	size := make([]byte, 8)
+	for _, content := range contents {
+		binary.BigEndian.PutUint64(size, uint64(len(content)))
+		h.Write(size)
+		h.Write(content)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Encrypt 使用key加密plaintext，返回 nonce + ciphertext
+func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	p := getCryptoProvider()
+	aead, err := p.NewAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(p.Rand(), nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt 解密 Encrypt 的结果
+func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	aead, err := getCryptoProvider().NewAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, data := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, data, nil)
+}
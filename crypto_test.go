@@ -0,0 +1,98 @@
+package config
+
+import (
+	"crypto/cipher"
+	"crypto/sha512"
+	"hash"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingCryptoProvider struct {
+	defaultCryptoProvider
+	hashes int32
+}
+
+func (p *countingCryptoProvider) NewHash() hash.Hash {
+	atomic.AddInt32(&p.hashes, 1)
+	return sha512.New()
+}
+
+func (p *countingCryptoProvider) NewAEAD(key []byte) (cipher.AEAD, error) {
+	return p.defaultCryptoProvider.NewAEAD(key)
+}
+
+func TestCryptoProvider(t *testing.T) {
+	ast := assert.New(t)
+
+	p := &countingCryptoProvider{}
+	SetCryptoProvider(p)
+	defer SetCryptoProvider(defaultCryptoProvider{})
+
+	mock := NewMockAsyncer(false)
+	key := "crypto_key"
+	mock.Set(key, []byte(`{"a": 1}`))
+	cfg := NewAsyncConfig(mock, key, time.Hour, false)
+	ast.EqualValues(1, cfg.Get("a"))
+	ast.EqualValues(1, atomic.LoadInt32(&p.hashes))
+
+	ast.NotEqual(digest([]byte("ab"), []byte("c")), digest([]byte("a"), []byte("bc")))
+	ast.Len(digest([]byte("a")), sha512.Size*2)
+}
+
+func TestEncrypt(t *testing.T) {
+	ast := assert.New(t)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	ciphertext, err := Encrypt(key, []byte("plaintext"))
+	ast.Nil(err)
+
+	ciphertext2, err := Encrypt(key, []byte("plaintext"))
+	ast.Nil(err)
+	ast.NotEqual(ciphertext, ciphertext2)
+
+	plaintext, err := Decrypt(key, ciphertext)
+	ast.Nil(err)
+	ast.Equal([]byte("plaintext"), plaintext)
+
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	_, err = Decrypt(key, ciphertext)
+	ast.NotNil(err)
+
+	_, err = Decrypt(key, []byte("short"))
+	ast.NotNil(err)
+	_, err = Encrypt([]byte("invalid key"), []byte("plaintext"))
+	ast.NotNil(err)
+}
+
+func TestDigestScratch(t *testing.T) {
+	ast := assert.New(t)
+
+	var s digestScratch
+	s.reset()
+	s.write([]byte("a"))
+	s.write([]byte("bc"))
+	ast.Equal(digest([]byte("a"), []byte("bc")), string(s.hexSum()))
+
+	// 复用buffer
+	s.reset()
+	s.write([]byte("a"))
+	ast.Equal(digest([]byte("a")), string(s.hexSum()))
+	content := []byte("a")
+	ast.Zero(testing.AllocsPerRun(10, func() {
+		s.reset()
+		s.write(content)
+		s.hexSum()
+	}))
+
+	// 替换CryptoProvider后重新创建hash
+	SetCryptoProvider(&countingCryptoProvider{})
+	defer SetCryptoProvider(defaultCryptoProvider{})
+	s.reset()
+	s.write([]byte("a"))
+	ast.Len(s.hexSum(), sha512.Size*2)
+	ast.Equal(digest([]byte("a")), string(s.hexSum()))
+}
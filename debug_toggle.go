@@ -0,0 +1,281 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kot-w/goutils/itype"
+)
+
+// DefaultDebugTTL 调试开关开启后自动关闭的默认时间
+var DefaultDebugTTL = 30 * time.Minute
+
+// DebugState 调试开关的状态
+type DebugState struct {
+	Pprof           bool    // 是否开启pprof
+	Verbose         bool    // 是否开启详细日志
+	TraceSampleRate float64 // trace采样率，0 表示不调整
+}
+
+func (s DebugState) enabled() bool {
+	return s.Pprof || s.Verbose || s.TraceSampleRate > 0
+}
+
+// DebugToggle 根据配置开关调试功能（pprof、详细日志、trace采样率）
+//
+// 配置项（prefix = "debug"）：
+//
+//	{
+//		"debug": {
+//			"pprof": true,
+//			"verbose": true,
+//			"trace_sample_rate": 0.5,
+//			"ttl": "10m"
+//		}
+//	}
+//
+// 调试开关开启ttl（默认 DefaultDebugTTL）时间后自动关闭，避免忘记关闭，
+// 关闭后需再次修改配置才会重新开启
+//
+//	toggle := config.NewDebugToggle(cfg, "debug")
+//	toggle.OnChange(func(s config.DebugState) {
+//		logLevel.SetLevel(...)
+//	})
+//	mux.Handle("/debug/pprof/", toggle.PprofHandler())
+type DebugToggle struct {
+	cfg    Configer
+	prefix string
+
+	sync.RWMutex
+	configState DebugState // 配置中的状态
+	state       DebugState // 生效的状态
+	timer       *time.Timer
+	gen         uint64 // 每次配置变化递增，过期的定时器和apply据此忽略
+	callbacks   []func(DebugState)
+
+	notifier  chan struct{}
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewDebugToggle(cfg Configer, prefix string) *DebugToggle {
+	t := &DebugToggle{
+		cfg:      cfg,
+		prefix:   prefix,
+		notifier: make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+
+	t.refresh()
+	cfg.Watch(t.notifier)
+	go t.watch()
+
+	return t
+}
+
+func (t *DebugToggle) watch() {
+	for {
+		select {
+		case <-t.notifier:
+			t.refresh()
+		case <-t.quit:
+			return
+		}
+	}
+}
+
+func (t *DebugToggle) key(name string) string {
+	if t.prefix == "" {
+		return name
+	}
+	return t.prefix + "." + name
+}
+
+func (t *DebugToggle) refresh() {
+	s := DebugState{
+		Pprof:           itype.Bool(t.cfg.Get(t.key("pprof"))),
+		Verbose:         itype.Bool(t.cfg.Get(t.key("verbose"))),
+		TraceSampleRate: itype.Float(t.cfg.Get(t.key("trace_sample_rate"))),
+	}
+	ttl, ok := toDuration(t.cfg.Get(t.key("ttl")))
+	if !ok || ttl <= 0 {
+		ttl = DefaultDebugTTL
+	}
+
+	t.Lock()
+	if s == t.configState {
+		t.Unlock()
+		return
+	}
+	t.configState = s
+	t.gen++
+	gen := t.gen
+
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if s.enabled() {
+		t.timer = time.AfterFunc(ttl, func() { t.expire(gen) })
+		logger.Infof("debug toggle[%s] enabled: %+v, auto disable after %s", t.prefix, s, ttl)
+	}
+	t.Unlock()
+
+	t.apply(gen, s)
+}
+
+// expire 超过ttl后自动关闭，Stop无法取消已触发的定时器，开启后又重新开启时忽略旧的定时器
+func (t *DebugToggle) expire(gen uint64) {
+	if t.apply(gen, DebugState{}) {
+		logger.Infof("debug toggle[%s] expired, disabled", t.prefix)
+	}
+}
+
+// apply gen不是最新时忽略，返回是否生效
+func (t *DebugToggle) apply(gen uint64, s DebugState) bool {
+	t.Lock()
+	if gen != t.gen {
+		t.Unlock()
+		return false
+	}
+	if s == t.state {
+		t.Unlock()
+		return false
+	}
+	t.state = s
+	callbacks := t.callbacks
+	t.Unlock()
+
+	for _, fn := range callbacks {
+		fn(s)
+	}
+	return true
+}
+
+// OnChange 注册状态变化的回调，注册时会以当前状态调用一次
+func (t *DebugToggle) OnChange(fn func(DebugState)) {
+	t.Lock()
+	t.callbacks = append(t.callbacks, fn)
+	s := t.state
+	t.Unlock()
+
+	fn(s)
+}
+
+// State 返回当前生效的状态
+func (t *DebugToggle) State() DebugState {
+	t.RLock()
+	defer t.RUnlock()
+	return t.state
+}
+
+func (t *DebugToggle) PprofEnabled() bool {
+	return t.State().Pprof
+}
+
+func (t *DebugToggle) Verbose() bool {
+	return t.State().Verbose
+}
+
+func (t *DebugToggle) TraceSampleRate() float64 {
+	return t.State().TraceSampleRate
+}
+
+// PprofHandler pprof的http接口，挂载在 "/debug/pprof/" 路径下，未开启时返回404
+//
+// 路径：
+//   - /debug/pprof/                 profile列表
+//   - /debug/pprof/profile?seconds= CPU profile
+//   - /debug/pprof/trace?seconds=   执行trace
+//   - /debug/pprof/<name>?debug=    heap、goroutine、allocs等profile
+func (t *DebugToggle) PprofHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.PprofEnabled() {
+			http.NotFound(w, r)
+			return
+		}
+		servePprof(w, r)
+	})
+}
+
+// Close 停止监控配置变化
+func (t *DebugToggle) Close() {
+	t.closeOnce.Do(func() {
+		close(t.quit)
+		t.Lock()
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+		t.Unlock()
+	})
+}
+
+// servePprof 基于runtime/pprof实现，避免引入net/http/pprof时在DefaultServeMux上注册路由
+func servePprof(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Path
+	if i := strings.Index(name, "/debug/pprof/"); i >= 0 {
+		name = name[i+len("/debug/pprof/"):]
+	}
+	name = strings.Trim(name, "/")
+
+	seconds, _ := strconv.Atoi(r.FormValue("seconds"))
+	if seconds <= 0 {
+		seconds = 30
+	}
+
+	switch name {
+	case "":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, p := range pprof.Profiles() {
+			fmt.Fprintf(w, "%d\t%s\n", p.Count(), p.Name())
+		}
+		fmt.Fprintln(w, "-\tprofile")
+		fmt.Fprintln(w, "-\ttrace")
+	case "profile":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if err := pprof.StartCPUProfile(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sleepOrDone(r, time.Duration(seconds)*time.Second)
+		pprof.StopCPUProfile()
+	case "trace":
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if err := trace.Start(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sleepOrDone(r, time.Duration(seconds)*time.Second)
+		trace.Stop()
+	default:
+		p := pprof.Lookup(name)
+		if p == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if name == "heap" && r.FormValue("gc") != "" {
+			runtime.GC()
+		}
+		debug, _ := strconv.Atoi(r.FormValue("debug"))
+		if debug > 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		}
+		p.WriteTo(w, debug)
+	}
+}
+
+func sleepOrDone(r *http.Request, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.Context().Done():
+	}
+}
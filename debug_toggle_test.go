@@ -0,0 +1,117 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugToggle(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(nil)
+	toggle := NewDebugToggle(cfg, "debug")
+	defer toggle.Close()
+
+	states := make(chan DebugState, 10)
+	toggle.OnChange(func(s DebugState) {
+		states <- s
+	})
+	ast.Equal(DebugState{}, <-states)
+
+	handler := toggle.PprofHandler()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	ast.Equal(http.StatusNotFound, rec.Code)
+
+	ast.Nil(cfg.Set("debug", map[string]interface{}{
+		"pprof":             true,
+		"verbose":           "on",
+		"trace_sample_rate": 0.5,
+		"ttl":               "50ms",
+	}))
+	ast.Equal(DebugState{Pprof: true, Verbose: true, TraceSampleRate: 0.5}, <-states)
+	ast.True(toggle.PprofEnabled())
+	ast.True(toggle.Verbose())
+	ast.Equal(0.5, toggle.TraceSampleRate())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	ast.Equal(http.StatusOK, rec.Code)
+	ast.True(strings.Contains(rec.Body.String(), "goroutine"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	ast.Equal(http.StatusOK, rec.Code)
+	ast.True(strings.Contains(rec.Body.String(), "TestDebugToggle"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/not_exist", nil))
+	ast.Equal(http.StatusNotFound, rec.Code)
+
+	// auto disabled after ttl
+	select {
+	case s := <-states:
+		ast.Equal(DebugState{}, s)
+	case <-time.After(time.Second):
+		t.Fatal("debug toggle not expired")
+	}
+	ast.False(toggle.PprofEnabled())
+
+	// disable
+	ast.Nil(cfg.Set("debug.pprof", false))
+	ast.Nil(cfg.Set("debug.verbose", false))
+	ast.Nil(cfg.Set("debug.trace_sample_rate", 0))
+	time.Sleep(5 * time.Millisecond)
+	ast.Equal(DebugState{}, toggle.State())
+}
+
+func TestDebugToggleStaleExpire(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"debug": map[string]interface{}{"pprof": true}})
+	toggle := NewDebugToggle(cfg, "debug")
+	defer toggle.Close()
+	ast.True(toggle.PprofEnabled())
+
+	toggle.RLock()
+	stale := toggle.gen
+	toggle.RUnlock()
+
+	// 重新开启后，旧的定时器触发不应关闭
+	ast.Nil(cfg.Set("debug.verbose", true))
+	for i := 0; i < 100 && !toggle.Verbose(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	ast.True(toggle.Verbose())
+	toggle.expire(stale)
+	ast.True(toggle.PprofEnabled())
+}
+
+func TestToDuration(t *testing.T) {
+	ast := assert.New(t)
+
+	for _, c := range []struct {
+		v  interface{}
+		d  time.Duration
+		ok bool
+	}{
+		{"30s", 30 * time.Second, true},
+		{"1.5", 1500 * time.Millisecond, true},
+		{float64(2), 2 * time.Second, true},
+		{int64(3), 3 * time.Second, true},
+		{time.Minute, time.Minute, true},
+		{"abc", 0, false},
+		{nil, 0, false},
+		{true, 0, false},
+		{map[string]interface{}{}, 0, false},
+	} {
+		d, ok := toDuration(c.v)
+		ast.Equal(c.d, d, "%v", c.v)
+		ast.Equal(c.ok, ok, "%v", c.v)
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mohae/deepcopy"
+	"github.com/pkg/errors"
+)
+
+// MaxDecodeDepth 解析后配置树的最大嵌套深度，超过时解析失败，避免很深的数组或对象导致后续遍历栈溢出
+var MaxDecodeDepth = 1000
+
+// errTooDeep 嵌套超过 MaxDecodeDepth
+var errTooDeep = errors.New("nesting too deep")
+
+// DecodeAny 按contentType解析原始内容，与刷新时的解析相同（不执行 RegisterRawMessageProcessor 注册的处理）
+//
+// Marshaler panic或嵌套超过 MaxDecodeDepth 时返回错误，可用于校验后端内容及fuzz测试
+func DecodeAny(data []byte, contentType ContentType) (interface{}, error) {
+	marshaler, ok := typeMarshalers[contentType]
+	if !ok {
+		return nil, errors.Errorf("unsupported content type: %v", contentType)
+	}
+
+	var val interface{}
+	if err := safeUnmarshal(marshaler, data, &val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// safeUnmarshal 解析前检查括号的嵌套深度，解析后检查配置树的深度，并将Marshaler的panic转为错误
+func safeUnmarshal(marshaler Marshaler, data []byte, v interface{}) (err error) {
+	if bracketDepth(data) > MaxDecodeDepth {
+		return errors.Wrapf(errTooDeep, "max depth %d", MaxDecodeDepth)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r, "%T panic: %v", marshaler, r)
+		}
+	}()
+
+	if err := marshaler.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if treeDepth(v, 0) > MaxDecodeDepth {
+		return errors.Wrapf(errTooDeep, "max depth %d", MaxDecodeDepth)
+	}
+	return nil
+}
+
+// bracketDepth 引号之外 [ { 的最大嵌套深度，超过 MaxDecodeDepth 时提前返回
+func bracketDepth(data []byte) int {
+	if bytes.IndexAny(data, "[{") < 0 {
+		return 0
+	}
+
+	depth, max := 0, 0
+	var quote byte
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if quote != 0 {
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '[', '{':
+			depth++
+			if depth > max {
+				max = depth
+				if max > MaxDecodeDepth {
+					return max
+				}
+			}
+		case ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return max
+}
+
+// treeDepth 配置树的深度，超过 MaxDecodeDepth 时提前返回
+func treeDepth(v interface{}, depth int) int {
+	if depth > MaxDecodeDepth {
+		return depth
+	}
+
+	max := depth
+	switch vv := v.(type) {
+	case *interface{}:
+		return treeDepth(*vv, depth)
+	case *map[string]interface{}:
+		return treeDepth(*vv, depth)
+	case map[string]interface{}:
+		for _, child := range vv {
+			if d := treeDepth(child, depth+1); d > max {
+				max = d
+			}
+		}
+	case []interface{}:
+		for _, child := range vv {
+			if d := treeDepth(child, depth+1); d > max {
+				max = d
+			}
+		}
+	}
+	return max
+}
+
+// ParseKeyPath 将keyPath拆分为各级的key，RootKey 返回nil；包含空的key（如 "a..b"、".a"、"a."）时返回错误
+func ParseKeyPath(keyPath string) ([]string, error) {
+	if keyPath == RootKey {
+		return nil, nil
+	}
+
+	keys := strings.Split(keyPath, ".")
+	for i, key := range keys {
+		if key == "" {
+			return nil, errors.WithStack(&KeyError{
+				KeyPath: keyPath,
+				Kind:    ErrKeyNotFound,
+				Err:     fmt.Errorf("empty key at %d", i),
+			})
+		}
+	}
+	return keys, nil
+}
+
+// MergeTrees 返回base与overlay深度合并的结果，规则与 Merge 相同：都为map时合并，否则使用overlay的值
+//
+// 不修改base及overlay，结果不与二者共享节点
+func MergeTrees(base, overlay map[string]interface{}) map[string]interface{} {
+	merged, _ := deepcopy.Copy(base).(map[string]interface{})
+	if merged == nil {
+		merged = make(map[string]interface{})
+	}
+	if extra, ok := deepcopy.Copy(overlay).(map[string]interface{}); ok {
+		mergeMap(merged, extra)
+	}
+	return merged
+}
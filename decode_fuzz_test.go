@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// go test -run=^$ -fuzz=FuzzDecodeAny
+func FuzzDecodeAny(f *testing.F) {
+	f.Add([]byte(`{"a": {"b": [1, 2]}}`), uint8(0))
+	f.Add([]byte("a:\n  b: [1, 2]\n"), uint8(1))
+	f.Add([]byte("[a]\nb = 1\n"), uint8(6))
+	f.Add([]byte("<a><b>1</b></a>"), uint8(5))
+	f.Add([]byte("a.b=1\n"), uint8(2))
+	f.Add([]byte(strings.Repeat("[", 2000)), uint8(0))
+
+	types := []ContentType{T_JSON, T_YAML, T_PROPERTIES, T_DOTENV, T_HCL, T_XML, T_TOML, T_INI}
+	f.Fuzz(func(t *testing.T, data []byte, typ uint8) {
+		val, err := DecodeAny(data, types[int(typ)%len(types)])
+		if err != nil {
+			return
+		}
+		if treeDepth(val, 0) > MaxDecodeDepth {
+			t.Fatalf("depth exceeds %d", MaxDecodeDepth)
+		}
+	})
+}
+
+func FuzzParseKeyPath(f *testing.F) {
+	f.Add("")
+	f.Add("a.b.c")
+	f.Add("a..b")
+	f.Add(".")
+
+	f.Fuzz(func(t *testing.T, keyPath string) {
+		keys, err := ParseKeyPath(keyPath)
+		if err != nil {
+			return
+		}
+		if strings.Join(keys, ".") != keyPath {
+			t.Fatalf("%q parsed as %q", keyPath, keys)
+		}
+	})
+}
+
+func FuzzMergeTrees(f *testing.F) {
+	f.Add([]byte(`{"a": {"b": 1}}`), []byte(`{"a": {"c": 2}}`))
+	f.Add([]byte(`{"a": [1]}`), []byte(`{"a": {"b": 1}}`))
+
+	f.Fuzz(func(t *testing.T, a, b []byte) {
+		var base, overlay map[string]interface{}
+		if json.Unmarshal(a, &base) != nil || json.Unmarshal(b, &overlay) != nil {
+			return
+		}
+		before, _ := json.Marshal(base)
+
+		merged := MergeTrees(base, overlay)
+		for k := range overlay {
+			if _, ok := merged[k]; !ok {
+				t.Fatalf("key %q missing", k)
+			}
+		}
+		if after, _ := json.Marshal(base); string(after) != string(before) {
+			t.Fatalf("base modified")
+		}
+	})
+}
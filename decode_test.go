@@ -0,0 +1,118 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeAny(t *testing.T) {
+	ast := assert.New(t)
+
+	val, err := DecodeAny([]byte(`{"a": {"b": [1, 2]}}`), T_JSON)
+	ast.Nil(err)
+	ast.Equal(map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{1.0, 2.0}}}, val)
+
+	val, err = DecodeAny([]byte("a:\n  b: 1\n"), T_YAML)
+	ast.Nil(err)
+	ast.Equal(map[string]interface{}{"a": map[string]interface{}{"b": 1}}, val)
+
+	_, err = DecodeAny([]byte(`{"a":`), T_JSON)
+	ast.NotNil(err)
+	_, err = DecodeAny([]byte(`{}`), ContentType(-1))
+	ast.NotNil(err)
+
+	// 很深的数组返回错误
+	deep := strings.Repeat("[", MaxDecodeDepth+1) + strings.Repeat("]", MaxDecodeDepth+1)
+	_, err = DecodeAny([]byte(deep), T_JSON)
+	ast.True(errors.Is(err, errTooDeep))
+	_, err = DecodeAny([]byte("a: "+deep), T_YAML)
+	ast.True(errors.Is(err, errTooDeep))
+
+	// 引号中的括号不计入深度
+	_, err = DecodeAny([]byte(`{"a": "`+deep+`"}`), T_JSON)
+	ast.Nil(err)
+
+	// 解析后的深度，如YAML的缩进
+	old := MaxDecodeDepth
+	MaxDecodeDepth = 2
+	defer func() { MaxDecodeDepth = old }()
+	_, err = DecodeAny([]byte("a:\n  b:\n    c: 1\n"), T_YAML)
+	ast.True(errors.Is(err, errTooDeep))
+	_, err = DecodeAny([]byte("a:\n  b: 1\n"), T_YAML)
+	ast.Nil(err)
+}
+
+type panicMarshaler struct {
+	JSONMarshaler
+}
+
+func (panicMarshaler) Unmarshal(data []byte, v interface{}) error {
+	panic("malformed")
+}
+
+func TestSafeUnmarshal(t *testing.T) {
+	ast := assert.New(t)
+
+	var val interface{}
+	err := safeUnmarshal(panicMarshaler{}, []byte(`{}`), &val)
+	ast.NotNil(err)
+	ast.Contains(err.Error(), "malformed")
+
+	// 刷新时不会panic，保留原有配置
+	asyncer := NewMockAsyncer(false)
+	asyncer.Set("app.json", []byte(`{"a": 1}`))
+	cfg := NewAsyncConfig(asyncer, "app.json", 0, false)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	deep := strings.Repeat("[", MaxDecodeDepth+1) + strings.Repeat("]", MaxDecodeDepth+1)
+	asyncer.Set("app.json", []byte(`{"a": `+deep+`}`))
+	ast.NotNil(cfg.Configer.(*asyncConfig).refresh())
+	ast.EqualValues(1, cfg.Int("a"))
+}
+
+func TestParseKeyPath(t *testing.T) {
+	ast := assert.New(t)
+
+	keys, err := ParseKeyPath(RootKey)
+	ast.Nil(err)
+	ast.Nil(keys)
+
+	keys, err = ParseKeyPath("a.b.c")
+	ast.Nil(err)
+	ast.Equal([]string{"a", "b", "c"}, keys)
+
+	for _, keyPath := range []string{".", "a..b", ".a", "a."} {
+		_, err = ParseKeyPath(keyPath)
+		ast.True(errors.Is(err, ErrKeyNotFound), keyPath)
+	}
+}
+
+func TestMergeTrees(t *testing.T) {
+	ast := assert.New(t)
+
+	base := map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": 2},
+		"d": []interface{}{1},
+	}
+	overlay := map[string]interface{}{
+		"a": map[string]interface{}{"b": 3},
+		"d": "x",
+	}
+
+	merged := MergeTrees(base, overlay)
+	ast.Equal(map[string]interface{}{
+		"a": map[string]interface{}{"b": 3, "c": 2},
+		"d": "x",
+	}, merged)
+
+	// 不修改输入，也不共享节点
+	ast.Equal(1, base["a"].(map[string]interface{})["b"])
+	merged["a"].(map[string]interface{})["e"] = 4
+	ast.NotContains(base["a"], "e")
+	ast.NotContains(overlay["a"], "e")
+
+	ast.Equal(map[string]interface{}{}, MergeTrees(nil, nil))
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"io"
 	"sync"
 	"sync/atomic"
 
@@ -24,6 +25,7 @@ func init() {
 
 type defaultConfig struct {
 	layers            sync.Map //[string]Configer layerName => Configer
+	requiredLayers    sync.Map //[string]bool 见 AddRequiredLayer
 	proxyPool         sync.Pool
 	defaultLayerNames atomic.Value //[]string
 	ConfigHelper
@@ -99,6 +101,7 @@ func RemoveDefaultLayerName(layerName string) {
 
 func (cfg *defaultConfig) AddLayer(layerName string, layer Configer) {
 	cfg.layers.Store(layerName, layer)
+	reportConflicts(cfg.layerConflicts(layerName))
 }
 
 func AddLayer(layerName string, layer Configer) {
@@ -129,6 +132,7 @@ func Load(path string, remoteSources ...string) (Config, error) {
 
 func (cfg *defaultConfig) RemoveLayer(layerName string) {
 	cfg.layers.Delete(layerName)
+	cfg.requiredLayers.Delete(layerName)
 }
 
 func RemoveLayer(layerName string) {
@@ -267,6 +271,35 @@ func Remarshal(keyPath string, v interface{}, layerNames ...string) error {
 	return p.Remarshal(keyPath, v)
 }
 
+func AllKeys(layerNames ...string) []string {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.AllKeys()
+}
+
+func Range(fn func(keyPath string, value interface{}) bool, layerNames ...string) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	p.Range(fn)
+}
+
+// Origin 返回提供keyPath生效值的Layer及来源
+//
+//  origin, ok := config.Origin("db.host")
+//  fmt.Println(origin) // remote (async *config.RedisAsyncer[app])
+//
+func Origin(keyPath string, layerNames ...string) (KeyOrigin, bool) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.Origin(keyPath)
+}
+
+func DumpTo(w io.Writer, opts *DumpOptions, layerNames ...string) error {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.DumpTo(w, opts)
+}
+
 func String(keyPath string, layerNames ...string) string {
 	p := _cfg.Layer(layerNames...)
 	defer _cfg.PutLayer(p)
@@ -291,6 +324,12 @@ func BytesDefault(keyPath string, dft []byte, layerNames ...string) (value []byt
 	return p.BytesDefault(keyPath, dft)
 }
 
+func GetBytes(keyPath string, layerNames ...string) ([]byte, error) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.GetBytes(keyPath)
+}
+
 func Float(keyPath string, layerNames ...string) float64 {
 	p := _cfg.Layer(layerNames...)
 	defer _cfg.PutLayer(p)
@@ -338,3 +377,45 @@ func BoolDefault(keyPath string, dft bool, layerNames ...string) bool {
 	defer _cfg.PutLayer(p)
 	return p.BoolDefault(keyPath, dft)
 }
+
+func Sample(keyPath string, unitID string, layerNames ...string) bool {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.Sample(keyPath, unitID)
+}
+
+func Lookup(keyPath string, layerNames ...string) (interface{}, error) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.Lookup(keyPath)
+}
+
+func LookupString(keyPath string, layerNames ...string) (string, error) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.LookupString(keyPath)
+}
+
+func LookupFloat(keyPath string, layerNames ...string) (float64, error) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.LookupFloat(keyPath)
+}
+
+func LookupInt(keyPath string, layerNames ...string) (int64, error) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.LookupInt(keyPath)
+}
+
+func LookupUint(keyPath string, layerNames ...string) (uint64, error) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.LookupUint(keyPath)
+}
+
+func LookupBool(keyPath string, layerNames ...string) (bool, error) {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.LookupBool(keyPath)
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mohae/deepcopy"
+)
+
+// ChangeType 配置项变化的类型
+type ChangeType int
+
+const (
+	ChangeAdded ChangeType = iota + 1
+	ChangeModified
+	ChangeRemoved
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeAdded:
+		return "added"
+	case ChangeModified:
+		return "modified"
+	case ChangeRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Change 叶子节点（map之外的值）的变化
+type Change struct {
+	KeyPath string
+	Type    ChangeType
+	Old     interface{} // ChangeAdded 时为nil
+	New     interface{} // ChangeRemoved 时为nil
+}
+
+// Diff 比较两个配置树的叶子节点，按keyPath排序返回变化
+func Diff(old, new interface{}) []Change {
+	oldLeaves := make(map[string]interface{})
+	flattenLeaves(RootKey, old, oldLeaves)
+	newLeaves := make(map[string]interface{})
+	flattenLeaves(RootKey, new, newLeaves)
+
+	changes := make([]Change, 0)
+	for k, ov := range oldLeaves {
+		nv, ok := newLeaves[k]
+		switch {
+		case !ok:
+			changes = append(changes, Change{KeyPath: k, Type: ChangeRemoved, Old: ov})
+		case !reflect.DeepEqual(ov, nv):
+			changes = append(changes, Change{KeyPath: k, Type: ChangeModified, Old: ov, New: nv})
+		}
+	}
+	for k, nv := range newLeaves {
+		if _, ok := oldLeaves[k]; !ok {
+			changes = append(changes, Change{KeyPath: k, Type: ChangeAdded, New: nv})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].KeyPath < changes[j].KeyPath
+	})
+	return changes
+}
+
+// Patch 返回在tree上应用changes后的配置树，不修改tree；Patch(a, Diff(a, b)) 与b相同
+//
+// 先应用删除再应用新增及修改，删除后为空的map一并删除
+func Patch(tree map[string]interface{}, changes []Change) map[string]interface{} {
+	patched, _ := deepcopy.Copy(tree).(map[string]interface{})
+	if patched == nil {
+		patched = make(map[string]interface{})
+	}
+
+	for _, c := range changes {
+		if c.Type == ChangeRemoved {
+			removeLeaf(patched, strings.Split(c.KeyPath, "."))
+		}
+	}
+	for _, c := range changes {
+		if c.Type != ChangeRemoved {
+			setLeaf(patched, strings.Split(c.KeyPath, "."), deepcopy.Copy(c.New))
+		}
+	}
+	return patched
+}
+
+// removeLeaf 删除keys对应的值，返回m是否变为空
+func removeLeaf(m map[string]interface{}, keys []string) bool {
+	if len(keys) == 1 {
+		delete(m, keys[0])
+		return len(m) == 0
+	}
+	if sub, ok := m[keys[0]].(map[string]interface{}); ok && removeLeaf(sub, keys[1:]) {
+		delete(m, keys[0])
+	}
+	return len(m) == 0
+}
+
+// setLeaf 设置keys对应的值，中间节点不是map时替换为map
+func setLeaf(m map[string]interface{}, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		sub, ok := m[key].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			m[key] = sub
+		}
+		m = sub
+	}
+	m[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatch(t *testing.T) {
+	ast := assert.New(t)
+
+	old := map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": 2},
+		"d": 1,
+		"e": []interface{}{1},
+	}
+	changes := []Change{
+		{KeyPath: "a.b", Type: ChangeRemoved, Old: 1},
+		{KeyPath: "a.c", Type: ChangeRemoved, Old: 2},
+		{KeyPath: "d", Type: ChangeRemoved, Old: 1},
+		{KeyPath: "d.f", Type: ChangeAdded, New: "x"},
+		{KeyPath: "e", Type: ChangeModified, Old: []interface{}{1}, New: []interface{}{2}},
+	}
+
+	patched := Patch(old, changes)
+	ast.Equal(map[string]interface{}{
+		"d": map[string]interface{}{"f": "x"},
+		"e": []interface{}{2},
+	}, patched)
+	// 不修改输入
+	ast.Equal(1, old["d"])
+	changes[4].New.([]interface{})[0] = 3
+	ast.Equal([]interface{}{2}, patched["e"])
+
+	ast.Equal(map[string]interface{}{}, Patch(nil, nil))
+}
+
+// genKeys 较少的key，使生成的配置树之间有重叠
+var genKeys = []string{"a", "b", "c", "d"}
+
+// genTree 随机的配置树，用于 testing/quick
+//
+// key不包含 "."；叶子节点为JSON中的值（字符串、float64、bool、nil、数组）或空map
+type genTree map[string]interface{}
+
+func (genTree) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(genTree(genMap(r, 3, genValue)))
+}
+
+func genMap(r *rand.Rand, depth int, leaf func(*rand.Rand) interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, k := range genKeys {
+		switch n := r.Intn(4); {
+		case n == 0:
+		case n == 1 && depth > 0:
+			if sub := genMap(r, depth-1, leaf); len(sub) > 0 {
+				m[k] = sub
+			}
+		default:
+			m[k] = leaf(r)
+		}
+	}
+	return m
+}
+
+func genValue(r *rand.Rand) interface{} {
+	switch r.Intn(6) {
+	case 0:
+		return genString(r)
+	case 1:
+		return float64(r.Intn(100))
+	case 2:
+		return r.Intn(2) == 0
+	case 3:
+		return nil
+	case 4:
+		return []interface{}{genString(r), float64(r.Intn(10))}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func genString(r *rand.Rand) string {
+	return []string{"", "x", "y z", "1", "true", "a=b", "中文"}[r.Intn(7)]
+}
+
+var quickConfig = &quick.Config{MaxCount: 500}
+
+func TestDiffPatchProperty(t *testing.T) {
+	// Patch(a, Diff(a, b)) == b
+	roundTrip := func(a, b genTree) bool {
+		return reflect.DeepEqual(map[string]interface{}(b), Patch(a, Diff(map[string]interface{}(a), map[string]interface{}(b))))
+	}
+	if err := quick.Check(roundTrip, quickConfig); err != nil {
+		t.Error(err)
+	}
+
+	// Diff(a, a) 为空，Diff(b, a) 为 Diff(a, b) 的逆
+	inverse := func(a, b genTree) bool {
+		if len(Diff(map[string]interface{}(a), map[string]interface{}(a))) != 0 {
+			return false
+		}
+		forward := Diff(map[string]interface{}(a), map[string]interface{}(b))
+		backward := Diff(map[string]interface{}(b), map[string]interface{}(a))
+		if len(forward) != len(backward) {
+			return false
+		}
+		for i, c := range forward {
+			r := backward[i]
+			want := map[ChangeType]ChangeType{ChangeAdded: ChangeRemoved, ChangeRemoved: ChangeAdded, ChangeModified: ChangeModified}[c.Type]
+			if r.KeyPath != c.KeyPath || r.Type != want || !reflect.DeepEqual(r.Old, c.New) || !reflect.DeepEqual(r.New, c.Old) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(inverse, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergeTreesProperty(t *testing.T) {
+	// 合并自身及空树不变，重复合并结果不变
+	identity := func(a genTree) bool {
+		m := map[string]interface{}(a)
+		return reflect.DeepEqual(m, MergeTrees(m, m)) &&
+			reflect.DeepEqual(m, MergeTrees(m, nil)) &&
+			reflect.DeepEqual(m, MergeTrees(nil, m))
+	}
+	if err := quick.Check(identity, quickConfig); err != nil {
+		t.Error(err)
+	}
+
+	idempotent := func(a, b genTree) bool {
+		merged := MergeTrees(a, b)
+		return reflect.DeepEqual(merged, MergeTrees(merged, b))
+	}
+	if err := quick.Check(idempotent, quickConfig); err != nil {
+		t.Error(err)
+	}
+
+	// overlay中非空map之外的叶子节点都出现在结果中，base中的其他叶子节点保留
+	overlay := func(a, b genTree) bool {
+		merged := make(map[string]interface{})
+		flattenLeaves(RootKey, MergeTrees(a, b), merged)
+		base := make(map[string]interface{})
+		flattenLeaves(RootKey, map[string]interface{}(a), base)
+		leaves := make(map[string]interface{})
+		flattenLeaves(RootKey, map[string]interface{}(b), leaves)
+
+		for k, v := range leaves {
+			if m, ok := v.(map[string]interface{}); ok && len(m) == 0 {
+				continue
+			}
+			if !reflect.DeepEqual(v, merged[k]) {
+				return false
+			}
+		}
+		for k, v := range merged {
+			if _, ok := leaves[k]; !ok && !reflect.DeepEqual(v, base[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(overlay, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
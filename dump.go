@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DumpOptions 见 ConfigHelper.DumpTo
+type DumpOptions struct {
+	KeyPath      string   // 输出的节点，为空时输出全部
+	Color        bool     // 使用ANSI颜色（终端）
+	Types        bool     // 标注值的类型
+	Sources      bool     // 标注提供值的Layer，见 ConfigHelper.Origin
+	MaskKeyPaths []string // 隐藏这些节点（及子节点）的值
+}
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiKey     = "\x1b[36m"
+	ansiString  = "\x1b[32m"
+	ansiNumber  = "\x1b[33m"
+	ansiBool    = "\x1b[35m"
+	ansiComment = "\x1b[90m"
+)
+
+// DumpTo 以缩进的树形格式输出生效的配置，opts为nil时只输出值
+//
+//	db:
+//	  host: "example.com"  # string, default
+//	  port: 3306  # int, remote
+func (h *ConfigHelper) DumpTo(w io.Writer, opts *DumpOptions) error {
+	if opts == nil {
+		opts = &DumpOptions{}
+	}
+
+	snap := &snapshotConfig{layers: snapshotLayers(h.Configer)}
+	base := splitKeyPath(opts.KeyPath)
+	bw := bufio.NewWriter(w)
+
+	var last []string
+	for _, keyPath := range snap.leafKeys() {
+		if opts.KeyPath != "" && keyPath != opts.KeyPath && !strings.HasPrefix(keyPath, opts.KeyPath+".") {
+			continue
+		}
+
+		val, _, idx := snap.lookupLayer(keyPath)
+		keys := splitKeyPath(keyPath)[len(base):]
+
+		// 与上一个keyPath共同的父节点不重复输出
+		common := 0
+		for common < len(keys)-1 && common < len(last)-1 && keys[common] == last[common] {
+			common++
+		}
+		for i := common; i < len(keys)-1; i++ {
+			fmt.Fprintf(bw, "%s%s:\n", strings.Repeat("  ", i), opts.paint(ansiKey, keys[i]))
+		}
+		last = keys
+
+		if len(keys) == 0 {
+			// KeyPath本身是叶子节点
+			keys = base[len(base)-1:]
+		}
+		fmt.Fprintf(bw, "%s%s: %s", strings.Repeat("  ", len(keys)-1), opts.paint(ansiKey, keys[len(keys)-1]), opts.value(keyPath, val))
+
+		notes := make([]string, 0, 2)
+		if opts.Types {
+			notes = append(notes, valueType(val))
+		}
+		if opts.Sources && idx >= 0 {
+			// 分层配置标注Layer名称，否则标注来源
+			if layer := snap.layers[idx]; layer.name != "" {
+				notes = append(notes, layer.name)
+			} else {
+				notes = append(notes, layer.source)
+			}
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(bw, "  %s", opts.paint(ansiComment, "# "+strings.Join(notes, ", ")))
+		}
+		bw.WriteString("\n")
+	}
+
+	return bw.Flush()
+}
+
+func (o *DumpOptions) paint(color, s string) string {
+	if !o.Color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+func (o *DumpOptions) value(keyPath string, val interface{}) string {
+	val = maskValue(o.MaskKeyPaths, keyPath, val)
+	bs, err := json.Marshal(val)
+	if err != nil {
+		bs = []byte(fmt.Sprintf("%v", val))
+	}
+
+	switch valueType(val) {
+	case "string":
+		return o.paint(ansiString, string(bs))
+	case "int", "float":
+		return o.paint(ansiNumber, string(bs))
+	case "bool":
+		return o.paint(ansiBool, string(bs))
+	case "null":
+		return o.paint(ansiComment, string(bs))
+	default:
+		return string(bs)
+	}
+}
+
+// valueType 配置值的类型名称
+func valueType(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "int"
+	case float32:
+		return valueType(float64(vv))
+	case float64:
+		if vv == math.Trunc(vv) && !math.IsInf(vv, 0) {
+			return "int"
+		}
+		return "float"
+	case json.Number:
+		if _, err := vv.Int64(); err == nil {
+			return "int"
+		}
+		return "float"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func splitKeyPath(keyPath string) []string {
+	if keyPath == "" {
+		return nil
+	}
+	return strings.Split(keyPath, ".")
+}
+
+// maskValue 隐藏maskKeyPaths及其子节点（包括数组元素）的值，不修改原值
+func maskValue(maskKeyPaths []string, keyPath string, v interface{}) interface{} {
+	if len(maskKeyPaths) == 0 {
+		return v
+	}
+
+	for _, p := range maskKeyPaths {
+		if p == keyPath || strings.HasPrefix(keyPath, p+".") {
+			return AdminMaskValue
+		}
+	}
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(vv))
+		for k, sub := range vv {
+			ret[k] = maskValue(maskKeyPaths, joinKeyPath(keyPath, k), sub)
+		}
+		return ret
+	case []interface{}:
+		// 数组元素的路径为下标，如 servers.0.password
+		ret := make([]interface{}, len(vv))
+		for i, sub := range vv {
+			ret[i] = maskValue(maskKeyPaths, joinKeyPath(keyPath, strconv.Itoa(i)), sub)
+		}
+		return ret
+	}
+	return v
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpTo(t *testing.T) {
+	ast := assert.New(t)
+
+	layered := newConfig()
+	layered.AddLayer("default", NewMapConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host":     "localhost",
+			"port":     3306,
+			"password": "secret",
+		},
+		"rate": 0.5,
+	}))
+	layered.AddLayer("remote", NewMapConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host": "example.com",
+			"tags": []interface{}{"a"},
+		},
+		"debug": true,
+		"name":  nil,
+	}))
+	p := layered.Layer("remote", "default")
+
+	var buf bytes.Buffer
+	ast.Nil(p.DumpTo(&buf, nil))
+	ast.Equal(`db:
+  host: "example.com"
+  password: "secret"
+  port: 3306
+  tags: ["a"]
+debug: true
+name: null
+rate: 0.5
+`, buf.String())
+
+	buf.Reset()
+	ast.Nil(p.DumpTo(&buf, &DumpOptions{Types: true, Sources: true, MaskKeyPaths: []string{"db.password"}}))
+	ast.Equal(`db:
+  host: "example.com"  # string, remote
+  password: "******"  # string, default
+  port: 3306  # int, default
+  tags: ["a"]  # list, remote
+debug: true  # bool, remote
+name: null  # null
+rate: 0.5  # float, default
+`, buf.String())
+
+	buf.Reset()
+	ast.Nil(p.DumpTo(&buf, &DumpOptions{KeyPath: "db", Color: true}))
+	ast.True(strings.HasPrefix(buf.String(), ansiKey+"host"+ansiReset+": "+ansiString+`"example.com"`+ansiReset+"\n"), buf.String())
+
+	buf.Reset()
+	ast.Nil(p.DumpTo(&buf, &DumpOptions{KeyPath: "db.port"}))
+	ast.Equal("port: 3306\n", buf.String())
+
+	// 管理接口
+	rec := httptest.NewRecorder()
+	NewAdminHandler(p, "db.password").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dump?key=db", nil))
+	ast.Equal(`host: "example.com"  # string, remote
+password: "******"  # string, default
+port: 3306  # int, default
+tags: ["a"]  # list, remote
+`, rec.Body.String())
+}
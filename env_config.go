@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvLayerName 见 AddEnvLayer
+const EnvLayerName = "env"
+
+type envOptions struct {
+	prefix    string
+	separator string
+	environ   func() []string
+}
+
+type EnvOption func(*envOptions)
+
+// WithEnvPrefix 只使用以prefix开头的环境变量，keyPath中去掉prefix，如 "APP_"
+func WithEnvPrefix(prefix string) EnvOption {
+	return func(o *envOptions) {
+		o.prefix = prefix
+	}
+}
+
+// WithEnvSeparator 环境变量名中分隔keyPath各段的字符串，默认 "_"；
+// key中含有 "_" 时可使用 "__"，如 APP_DB__MAX_CONNS => db.max_conns
+func WithEnvSeparator(separator string) EnvOption {
+	return func(o *envOptions) {
+		o.separator = separator
+	}
+}
+
+// WithEnviron 返回 "KEY=value" 形式环境变量的方法，默认 os.Environ
+func WithEnviron(environ func() []string) EnvOption {
+	return func(o *envOptions) {
+		o.environ = environ
+	}
+}
+
+// EnvConfig 创建时的环境变量组成的配置，只读，值均为字符串
+type EnvConfig struct {
+	ConfigHelper
+}
+
+// NewEnvConfig 环境变量名去掉前缀后转为小写，按分隔符拆分为keyPath，如 APP_DB_HOST => db.host
+//
+// 叠加在其他配置之上时，容器的环境变量可以覆盖远程配置而不修改代码：
+//
+//	cfg := config.NewLayeredConfig(remote, config.NewEnvConfig(config.WithEnvPrefix("APP_")))
+//
+// 同时存在 APP_DB 与 APP_DB_HOST 时前者被忽略并记录warn日志
+func NewEnvConfig(opts ...EnvOption) *EnvConfig {
+	o := &envOptions{
+		separator: "_",
+		environ:   os.Environ,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// 重复的变量以后出现的为准
+	values := make(map[string]string)
+	parents := make(map[string]string) // 父节点 => 子节点
+	for _, env := range o.environ() {
+		i := strings.Index(env, "=")
+		if i <= 0 || !strings.HasPrefix(env[:i], o.prefix) {
+			continue
+		}
+		parts := strings.Split(strings.ToLower(env[len(o.prefix):i]), strings.ToLower(o.separator))
+		if !validEnvKey(parts) {
+			continue
+		}
+		keyPath := strings.Join(parts, ".")
+		values[keyPath] = env[i+1:]
+		for j := 1; j < len(parts); j++ {
+			parents[strings.Join(parts[:j], ".")] = keyPath
+		}
+	}
+	keyPaths := make([]string, 0, len(values))
+	for keyPath := range values {
+		keyPaths = append(keyPaths, keyPath)
+	}
+	sort.Strings(keyPaths)
+
+	root := make(map[string]interface{})
+	for _, keyPath := range keyPaths {
+		if child, ok := parents[keyPath]; ok {
+			logger.Warnf("env config[%s] ignored: conflicts with %s", keyPath, child)
+			continue
+		}
+		setMapValue(root, keyPath, values[keyPath])
+	}
+
+	return &EnvConfig{
+		ConfigHelper: ConfigHelper{
+			Configer: &snapshotConfig{layers: []snapshotLayer{{source: "env", root: root}}},
+		},
+	}
+}
+
+// validEnvKey 各段均不为空
+func validEnvKey(parts []string) bool {
+	if len(parts) == 0 {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// AddEnvLayer 将环境变量添加为 EnvLayerName 层，并作为优先级最高的默认Layer
+func AddEnvLayer(opts ...EnvOption) {
+	AddLayer(EnvLayerName, NewEnvConfig(opts...))
+	AddDefaultLayerName(EnvLayerName)
+}
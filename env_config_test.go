@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvConfig(t *testing.T) {
+	ast := assert.New(t)
+
+	environ := func() []string {
+		return []string{
+			"APP_DB_HOST=env-db",
+			"APP_DB_PORT=3307",
+			"APP_DEBUG=true",
+			"APP_HTTP=ignored",
+			"APP_HTTP_ADDR=:8080",
+			"APP_=empty",
+			"APP_A__B=bad",
+			"APP_DEBUG=false",
+			"HOME=/root",
+			"BROKEN",
+		}
+	}
+	cfg := NewEnvConfig(WithEnvPrefix("APP_"), WithEnviron(environ))
+	ast.Equal(map[string]interface{}{
+		"db":    map[string]interface{}{"host": "env-db", "port": "3307"},
+		"debug": "false",
+		"http":  map[string]interface{}{"addr": ":8080"},
+	}, cfg.Get(RootKey))
+	ast.Equal(int64(3307), cfg.Int("db.port"))
+	ast.False(cfg.Bool("debug"))
+	ast.Equal(ErrReadOnly, cfg.Set("db.host", "x"))
+
+	origin, ok := cfg.Origin("db.host")
+	ast.True(ok)
+	ast.Equal("env", origin.Source)
+
+	// 分隔符
+	cfg = NewEnvConfig(WithEnvPrefix("APP_"), WithEnvSeparator("__"), WithEnviron(func() []string {
+		return []string{"APP_DB__MAX_CONNS=10", "APP_LOG_LEVEL=debug"}
+	}))
+	ast.Equal(int64(10), cfg.Int("db.max_conns"))
+	ast.Equal("debug", cfg.String("log_level"))
+
+	// 叠加在远程配置之上
+	remote := NewMapConfig(map[string]interface{}{"db": map[string]interface{}{"host": "remote-db", "user": "app"}})
+	layered := NewLayeredConfig(remote, NewEnvConfig(WithEnvPrefix("APP_"), WithEnviron(environ)))
+	defer layered.Close()
+	ast.Equal("env-db", layered.String("db.host"))
+	ast.Equal("app", layered.String("db.user"))
+}
+
+func TestAddEnvLayer(t *testing.T) {
+	ast := assert.New(t)
+
+	AddLayer(DefaultLayerName, NewMapConfig(map[string]interface{}{"region": "us", "zone": "a"}))
+	AddEnvLayer(WithEnvPrefix("ENVLAYER_"), WithEnviron(func() []string {
+		return []string{"ENVLAYER_REGION=eu"}
+	}))
+	defer func() {
+		RemoveDefaultLayerName(EnvLayerName)
+		RemoveLayer(EnvLayerName)
+	}()
+
+	ast.Equal("eu", String("region"))
+	ast.Equal("a", String("zone"))
+}
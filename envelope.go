@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Envelope 发布到后端的配置内容及元数据
+//
+//	{"published_at": "2024-01-02T15:04:05.999Z", "content": {"db": {"host": "example.com"}}}
+//
+// content 为JSON时原样嵌入，否则为JSON字符串
+type Envelope struct {
+	PublishedAt time.Time
+	Content     []byte
+}
+
+type envelopeJSON struct {
+	PublishedAt time.Time       `json:"published_at"`
+	Content     json.RawMessage `json:"content"`
+}
+
+// WrapEnvelope 生成发布到后端的内容
+func WrapEnvelope(content []byte, publishedAt time.Time) ([]byte, error) {
+	raw := json.RawMessage(content)
+	if trimmed := bytes.TrimSpace(content); len(trimmed) == 0 || trimmed[0] == '"' || !json.Valid(trimmed) {
+		// 非JSON（或JSON字符串）以字符串形式嵌入
+		bs, err := json.Marshal(string(content))
+		if err != nil {
+			return nil, err
+		}
+		raw = bs
+	}
+
+	return json.Marshal(envelopeJSON{PublishedAt: publishedAt, Content: raw})
+}
+
+// OpenEnvelope 解析 WrapEnvelope 生成的内容
+func OpenEnvelope(data []byte) (*Envelope, error) {
+	var e envelopeJSON
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, errors.Wrap(err, "invalid envelope")
+	}
+	if e.PublishedAt.IsZero() || len(e.Content) == 0 {
+		return nil, errors.New("invalid envelope: missing published_at or content")
+	}
+
+	content := []byte(e.Content)
+	if content[0] == '"' {
+		var s string
+		if err := json.Unmarshal(content, &s); err != nil {
+			return nil, errors.Wrap(err, "invalid envelope content")
+		}
+		content = []byte(s)
+	}
+
+	return &Envelope{PublishedAt: e.PublishedAt, Content: content}, nil
+}
+
+// WithEnvelope 后端内容为 Envelope，Set时同样以Envelope写入
+//
+// 配置变化生效时上报发布到生效的延迟 MetricPropagationLatency，
+// 并记录在 ChangeEvent 中；不是Envelope的内容按原始内容处理
+func WithEnvelope() AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.envelope = true
+	}
+}
+
+// openEnvelope 返回envelope中的内容及发布时间，不是envelope时原样返回
+func (cfg *asyncConfig) openEnvelope(rawMessage []byte) ([]byte, time.Time) {
+	if !cfg.envelope || len(rawMessage) == 0 {
+		return rawMessage, time.Time{}
+	}
+
+	e, err := OpenEnvelope(rawMessage)
+	if err != nil {
+		logger.Warnf("async config[%s] is not an envelope: %v", cfg.asyncKey, err)
+		return rawMessage, time.Time{}
+	}
+	return e.Content, e.PublishedAt
+}
+
+// applied 配置变化生效，记录传播延迟
+func (cfg *asyncConfig) applied(publishedAt, appliedAt time.Time) {
+	if publishedAt.IsZero() {
+		atomic.StoreInt64(&cfg.publishedAt, 0)
+		return
+	}
+
+	atomic.StoreInt64(&cfg.publishedAt, publishedAt.UnixNano())
+	atomic.StoreInt64(&cfg.appliedAt, appliedAt.UnixNano())
+	loadMetrics().Observe(MetricPropagationLatency, appliedAt.Sub(publishedAt).Seconds(), "key", cfg.asyncKey)
+}
+
+// propagation 最近一次生效的变化的发布及生效时间，未使用envelope时为零值
+func (cfg *asyncConfig) propagation() (publishedAt, appliedAt time.Time) {
+	p := atomic.LoadInt64(&cfg.publishedAt)
+	if p == 0 {
+		return time.Time{}, time.Time{}
+	}
+	return time.Unix(0, p), time.Unix(0, atomic.LoadInt64(&cfg.appliedAt))
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	ast := assert.New(t)
+
+	publishedAt := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, content := range []string{"a: 1\n", `"a"`, "not json {"} {
+		data, err := WrapEnvelope([]byte(content), publishedAt)
+		ast.Nil(err)
+		e, err := OpenEnvelope(data)
+		ast.Nil(err, content)
+		ast.Equal(content, string(e.Content))
+		ast.True(publishedAt.Equal(e.PublishedAt))
+	}
+
+	// JSON原样嵌入
+	data, _ := WrapEnvelope([]byte(`{"a": 1}`), publishedAt)
+	ast.Contains(string(data), `"content":{"a":1}`)
+	e, err := OpenEnvelope(data)
+	ast.Nil(err)
+	ast.JSONEq(`{"a": 1}`, string(e.Content))
+
+	_, err = OpenEnvelope([]byte(`{"a": 1}`))
+	ast.NotNil(err)
+	_, err = OpenEnvelope([]byte(`a: 1`))
+	ast.NotNil(err)
+}
+
+func TestPropagationLatency(t *testing.T) {
+	ast := assert.New(t)
+
+	m := &testMetrics{counters: make(map[string]float64)}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	now := time.Now()
+	asyncer := &rawAsyncer{NewMockAsyncer(false)}
+	data, _ := WrapEnvelope([]byte(`{"a": 1}`), now.Add(-2*time.Second))
+	asyncer.data.Store("envelope.json", data)
+
+	cfg := NewAsyncConfig(asyncer, "envelope.json", 0, false, WithEnvelope())
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a"))
+	m.Lock()
+	latencies := m.observed[MetricPropagationLatency+"{key,envelope.json}"]
+	m.Unlock()
+	if ast.Len(latencies, 1) {
+		ast.True(latencies[0] >= 2 && latencies[0] < 3, "latency=%v", latencies[0])
+	}
+
+	v2 := AdaptV1(cfg)
+	defer v2.Close()
+	events, err := v2.WatchEvents(context.Background())
+	ast.Nil(err)
+
+	data, _ = WrapEnvelope([]byte(`{"a": 2}`), now.Add(-500*time.Millisecond))
+	asyncer.data.Store("envelope.json", data)
+	ast.Nil(cfg.Configer.(*asyncConfig).refresh())
+	ast.EqualValues(2, cfg.Int("a"))
+
+	select {
+	case e := <-events:
+		ast.True(now.Add(-500 * time.Millisecond).Equal(e.PublishedAt))
+		ast.True(e.Latency >= 500*time.Millisecond && e.Latency < time.Second, "latency=%v", e.Latency)
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	// 内容未变化时不重复上报
+	data, _ = WrapEnvelope([]byte(`{"a": 2}`), time.Now())
+	asyncer.data.Store("envelope.json", data)
+	ast.Nil(cfg.Configer.(*asyncConfig).refresh())
+	m.Lock()
+	ast.Len(m.observed[MetricPropagationLatency+"{key,envelope.json}"], 2)
+	m.Unlock()
+
+	// Set 以envelope写入
+	ast.Nil(cfg.Set("a", 3))
+	v, _ := asyncer.data.Load("envelope.json")
+	e, err := OpenEnvelope(v.([]byte))
+	ast.Nil(err)
+	ast.JSONEq(`{"a": 3}`, string(e.Content))
+	ast.False(e.PublishedAt.Before(now))
+
+	// 非envelope按原始内容处理
+	asyncer.data.Store("envelope.json", []byte(`{"a": 4}`))
+	ast.Nil(cfg.Configer.(*asyncConfig).refresh())
+	ast.EqualValues(4, cfg.Int("a"))
+}
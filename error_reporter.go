@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// 内部错误发生的位置，见 InternalError.Op
+const (
+	OpCallback = "callback" // OnChange 回调panic
+	OpDecode   = "decode"   // 刷新时Marshaler panic
+)
+
+// InternalError 内部的意外错误及recover的panic，可上报至Sentry等错误追踪系统
+type InternalError struct {
+	Op    string      // 发生的位置，如 OpCallback
+	Key   string      // 配置的asyncKey，非 AsyncConfig 时为空
+	Err   error       // panic时为包含recover值的错误
+	Panic interface{} // recover的值，非panic时为nil
+	Stack []byte      // panic时的goroutine栈
+}
+
+func (e *InternalError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("config %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("config %s[%s]: %v", e.Op, e.Key, e.Err)
+}
+
+func (e *InternalError) Unwrap() error {
+	return e.Err
+}
+
+var errorReporter atomic.Value // func(*InternalError)
+
+// SetErrorReporter 设置内部错误的处理，nil 时只记录error日志（默认）；
+// 设置后仍记录日志，WithErrorReporter 设置的配置使用其自己的处理
+//
+//	config.SetErrorReporter(func(e *config.InternalError) {
+//		sentry.CaptureException(e)
+//	})
+func SetErrorReporter(fn func(*InternalError)) {
+	errorReporter.Store(fn)
+}
+
+// WithErrorReporter 该配置的内部错误使用fn处理，替代 SetErrorReporter 设置的处理
+func WithErrorReporter(fn func(*InternalError)) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.errorReporter = fn
+	}
+}
+
+// reportError 记录日志并交给reporter处理，reporter为nil时使用 SetErrorReporter 设置的处理
+func reportError(reporter func(*InternalError), e *InternalError) {
+	logger.Errorf("%v", e)
+
+	if reporter == nil {
+		reporter, _ = errorReporter.Load().(func(*InternalError))
+	}
+	if reporter == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("config error reporter panic: %v", r)
+		}
+	}()
+	reporter(e)
+}
+
+// panicError recover的panic
+type panicError struct {
+	value interface{}
+	stack []byte
+	msg   string
+}
+
+func newPanicError(value interface{}, format string, args ...interface{}) *panicError {
+	return &panicError{value: value, stack: debug.Stack(), msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *panicError) Error() string {
+	return e.msg
+}
+
+// internalErrorReporter 支持 WithErrorReporter 的Configer
+type internalErrorReporter interface {
+	reportError(e *InternalError)
+}
+
+func (cfg *asyncConfig) reportError(e *InternalError) {
+	e.Key = cfg.asyncKey
+	reportError(cfg.errorReporter, e)
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveError(t *testing.T, ch chan *InternalError) *InternalError {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("error not reported")
+		return nil
+	}
+}
+
+func TestErrorReporter(t *testing.T) {
+	ast := assert.New(t)
+
+	global := make(chan *InternalError, 1)
+	SetErrorReporter(func(e *InternalError) { global <- e })
+	defer SetErrorReporter(nil)
+
+	// 回调panic
+	m := NewMapConfig(nil)
+	cancel := m.OnChange(func() { panic("callback") })
+	defer cancel()
+	ast.Nil(m.Set("a", 1))
+	e := receiveError(t, global)
+	ast.Equal(OpCallback, e.Op)
+	ast.Equal("", e.Key)
+	ast.Equal("callback", e.Panic)
+	ast.Contains(string(e.Stack), "TestErrorReporter")
+	ast.Equal("config callback: change callback panic: callback", e.Error())
+
+	// 配置自己的处理
+	local := make(chan *InternalError, 2)
+	mock := NewMockAsyncer(false)
+	mock.Set("panic.json", []byte(`{"a": 1}`))
+	cfg := NewAsyncConfig(mock, "panic.json", 0, false,
+		WithMarshaler(panicMarshaler{}), WithErrorReporter(func(e *InternalError) { local <- e }))
+	defer cfg.Close()
+
+	e = receiveError(t, local)
+	ast.Equal(OpDecode, e.Op)
+	ast.Equal("panic.json", e.Key)
+	ast.Equal("malformed", e.Panic)
+	ast.NotEmpty(e.Stack)
+	ast.Contains(e.Error(), "config decode[panic.json]: config.panicMarshaler panic: malformed")
+	var pe *panicError
+	ast.True(errors.As(e, &pe))
+
+	cancel = cfg.OnChange(func() { panic("async callback") })
+	defer cancel()
+	cfg.Configer.(*asyncConfig).notify()
+	e = receiveError(t, local)
+	ast.Equal(OpCallback, e.Op)
+	ast.Equal("panic.json", e.Key)
+	select {
+	case <-global:
+		ast.Fail("reported to the global reporter")
+	default:
+	}
+
+	// reporter panic不影响后续的回调
+	SetErrorReporter(func(e *InternalError) { panic("reporter") })
+	ast.Nil(m.Set("a", 2))
+	time.Sleep(50 * time.Millisecond)
+	SetErrorReporter(func(e *InternalError) { global <- e })
+	ast.Nil(m.Set("a", 3))
+	ast.Equal(OpCallback, receiveError(t, global).Op)
+}
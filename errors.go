@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// 可通过 errors.Is 判断的错误类型
+var (
+	// ErrKeyNotFound 配置项不存在
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrNullValue 配置项存在但值为null
+	ErrNullValue = errors.New("null value")
+	// ErrTypeMismatch 配置项的类型与期望的不符
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrBackendUnavailable 后端读写失败
+	ErrBackendUnavailable = errors.New("backend unavailable")
+	// ErrClosed 配置已关闭
+	ErrClosed = errors.New("config closed")
+	// ErrReadOnly 配置只读，不支持Set
+	ErrReadOnly = errors.New("read-only config")
+	// ErrNotReady 必需的Layer未加载或为空，见 AddRequiredLayer
+	ErrNotReady = errors.New("config not ready")
+	// ErrForbidden Authorizer拒绝访问，详情见 AuthzError
+	ErrForbidden = errors.New("forbidden")
+	// ErrSignature 内容未签名或签名无效，见 WithSignatureVerification
+	ErrSignature = errors.New("invalid signature")
+	// ErrValidation 配置校验失败，字段详情见 ValidationError
+	ErrValidation = errors.New("validation failed")
+)
+
+// KeyError 与配置项相关的错误，Kind 为 ErrKeyNotFound、ErrNullValue 或 ErrTypeMismatch
+type KeyError struct {
+	KeyPath string
+	Kind    error
+	Err     error // 具体原因，可为nil
+}
+
+func (e *KeyError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("path[%s]: %v", e.KeyPath, e.Kind)
+	}
+	return fmt.Sprintf("path[%s]: %v: %v", e.KeyPath, e.Kind, e.Err)
+}
+
+func (e *KeyError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+func keyNotFound(keyPath string) error {
+	return errors.WithStack(&KeyError{KeyPath: keyPath, Kind: ErrKeyNotFound})
+}
+
+func typeMismatch(keyPath string, cause error) error {
+	return errors.WithStack(&KeyError{KeyPath: keyPath, Kind: ErrTypeMismatch, Err: cause})
+}
+
+// BackendError 后端读写失败
+type BackendError struct {
+	Key string // 后端的key
+	Err error
+}
+
+func (e *BackendError) Error() string {
+	return fmt.Sprintf("backend[%s]: %v", e.Key, e.Err)
+}
+
+func (e *BackendError) Is(target error) bool {
+	return target == ErrBackendUnavailable
+}
+
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+func backendError(key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.WithStack(&BackendError{Key: key, Err: err})
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string // 字段的keyPath
+	Message string
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError 校验失败，Schema 实现可返回该类型以提供字段详情
+type ValidationError struct {
+	Fields []FieldError
+	Err    error // 原始错误，可为nil
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		if e.Err == nil {
+			return ErrValidation.Error()
+		}
+		return fmt.Sprintf("%v: %v", ErrValidation, e.Err)
+	}
+
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, f.String())
+	}
+	return fmt.Sprintf("%v: %s", ErrValidation, strings.Join(fields, "; "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validationError 将schema返回的错误转换为 ValidationError
+func validationError(err error) error {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return err
+	}
+	return errors.WithStack(&ValidationError{Err: err})
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type failAsyncer struct {
+	*MockAsyncer
+}
+
+func (a *failAsyncer) Set(key string, content []byte) error {
+	return errors.New("connection refused")
+}
+
+func TestErrors(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"name": "foo",
+		"list": []interface{}{1, 2},
+	})
+
+	_, err := cfg.JSON("not_exist")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+	var kerr *KeyError
+	ast.True(errors.As(err, &kerr))
+	ast.Equal("not_exist", kerr.KeyPath)
+	ast.EqualError(err, "path[not_exist]: key not found")
+
+	var n int
+	err = cfg.Remarshal("name", &n)
+	ast.True(errors.Is(err, ErrTypeMismatch))
+	ast.False(errors.Is(err, ErrKeyNotFound))
+
+	_, err = cfg.GetBytes("list")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+
+	err = cfg.Set("name.first", "bar")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+	err = cfg.Set("list.5", 1)
+	ast.True(errors.Is(err, ErrKeyNotFound))
+	ast.False(errors.Is(err, ErrTypeMismatch))
+
+	// backend
+	asyncer := &failAsyncer{NewMockAsyncer(false)}
+	asyncer.data.Store("errors.json", []byte(`{"name": "foo"}`))
+	acfg := NewAsyncConfig(asyncer, "errors.json", time.Hour, false)
+	err = acfg.Set("name", "bar")
+	ast.True(errors.Is(err, ErrBackendUnavailable))
+	var berr *BackendError
+	ast.True(errors.As(err, &berr))
+	ast.Equal("errors.json", berr.Key)
+	ast.EqualError(berr.Err, "connection refused")
+
+	// validation
+	vcfg := NewAsyncConfig(NewMockAsyncer(false), "validate.json", time.Hour, false,
+		WithSchema(SchemaFunc(func(value interface{}) (interface{}, error) {
+			if m, ok := value.(map[string]interface{}); ok && m["port"] == "x" {
+				return nil, &ValidationError{Fields: []FieldError{{Field: "port", Message: "must be a number"}}}
+			}
+			if m, ok := value.(map[string]interface{}); ok && m["port"] == "y" {
+				return nil, errors.New("invalid port")
+			}
+			return value, nil
+		})))
+	err = vcfg.Set("port", "x")
+	ast.True(errors.Is(err, ErrValidation))
+	var verr *ValidationError
+	ast.True(errors.As(err, &verr))
+	ast.Equal([]FieldError{{Field: "port", Message: "must be a number"}}, verr.Fields)
+	ast.Contains(err.Error(), "validation failed: port: must be a number")
+
+	err = vcfg.Set("port", "y")
+	ast.True(errors.Is(err, ErrValidation))
+	ast.Contains(err.Error(), "validation failed: invalid port")
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventBuffer 每个事件订阅的channel缓冲大小，消费不及时时丢弃新的事件
+var EventBuffer = 256
+
+// EventType 事件类型
+type EventType int
+
+const (
+	EventRefreshSucceeded   EventType = iota + 1 // 刷新成功，Changed 表示内容是否变化
+	EventRefreshFailed                           // 刷新失败，保留旧值
+	EventValidationRejected                      // 刷新的内容校验失败（ErrValidation），保留旧值
+	EventWatchReconnected                        // 后端的监听在失败后恢复
+	EventNotifyDropped                           // Watch的notifier已满，本次通知被合并
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventRefreshSucceeded:
+		return "refresh_succeeded"
+	case EventRefreshFailed:
+		return "refresh_failed"
+	case EventValidationRejected:
+		return "validation_rejected"
+	case EventWatchReconnected:
+		return "watch_reconnected"
+	case EventNotifyDropped:
+		return "notify_dropped"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event 配置及后端的内部事件，可统一用于指标、日志及告警
+type Event struct {
+	Type    EventType
+	Key     string // asyncKey
+	Source  string // 来源描述，同 KeyOrigin.Source，后端事件为后端的类型
+	Time    time.Time
+	Err     error // 失败及校验失败的原因
+	Changed bool  // EventRefreshSucceeded 时内容是否变化
+
+	config *asyncConfig // 配置产生的事件
+}
+
+type eventSubscriber struct {
+	ch     chan Event
+	filter func(Event) bool
+}
+
+var (
+	eventMu          sync.RWMutex
+	eventSubscribers = make(map[*eventSubscriber]struct{})
+	eventCount       int32 // 订阅数，没有订阅时不创建事件
+)
+
+// SubscribeEvents 订阅进程内所有配置及后端的事件，调用cancel取消并关闭channel
+//
+//	events, cancel := config.SubscribeEvents()
+//	defer cancel()
+//	for e := range events {
+//		metrics.Counter("config_events_total", 1, "type", e.Type.String())
+//	}
+func SubscribeEvents() (events <-chan Event, cancel func()) {
+	return subscribeEvents(nil)
+}
+
+func subscribeEvents(filter func(Event) bool) (<-chan Event, func()) {
+	s := &eventSubscriber{ch: make(chan Event, EventBuffer), filter: filter}
+
+	eventMu.Lock()
+	eventSubscribers[s] = struct{}{}
+	atomic.AddInt32(&eventCount, 1)
+	eventMu.Unlock()
+
+	var once sync.Once
+	return s.ch, func() {
+		once.Do(func() {
+			eventMu.Lock()
+			delete(eventSubscribers, s)
+			atomic.AddInt32(&eventCount, -1)
+			close(s.ch)
+			eventMu.Unlock()
+		})
+	}
+}
+
+// eventsEnabled 有订阅时才需要创建事件
+func eventsEnabled() bool {
+	return atomic.LoadInt32(&eventCount) > 0
+}
+
+// emitEvent 不阻塞，订阅的channel已满时丢弃
+func emitEvent(e Event) {
+	if !eventsEnabled() {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = _now()
+	}
+
+	eventMu.RLock()
+	defer eventMu.RUnlock()
+	for s := range eventSubscribers {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+		}
+	}
+}
+
+// emitBackendEvent 后端产生的事件，由使用该key的配置接收
+func emitBackendEvent(t EventType, asyncer Asyncer, key string) {
+	if !eventsEnabled() {
+		return
+	}
+	emitEvent(Event{Type: t, Key: key, Source: fmt.Sprintf("%T", asyncer)})
+}
+
+// emit 配置产生的事件
+func (cfg *asyncConfig) emit(t EventType, err error, changed bool) {
+	if !eventsEnabled() {
+		return
+	}
+	emitEvent(Event{Type: t, Key: cfg.asyncKey, Source: configSource(cfg), Err: err, Changed: changed, config: cfg})
+}
+
+// Events 返回该配置及其后端（相同asyncKey）的事件，配置关闭时关闭channel；
+// 多次调用返回同一channel，消费不及时时丢弃新的事件，见 EventBuffer
+func (c *AsyncConfig) Events() <-chan Event {
+	return c.Configer.(*asyncConfig).Events()
+}
+
+func (cfg *asyncConfig) Events() <-chan Event {
+	cfg.bgMu.Lock()
+	defer cfg.bgMu.Unlock()
+
+	if cfg.events != nil {
+		return cfg.events
+	}
+	if cfg.closed() {
+		ch := make(chan Event)
+		close(ch)
+		return ch
+	}
+	cfg.events, cfg.eventsCancel = subscribeEvents(func(e Event) bool {
+		return e.config == cfg || (e.config == nil && e.Key == cfg.asyncKey)
+	})
+	return cfg.events
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveEvent(t *testing.T, events <-chan Event, typ EventType) Event {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == typ {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", typ)
+			return Event{}
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ast := assert.New(t)
+
+	all, cancel := SubscribeEvents()
+	defer cancel()
+
+	mock := NewMockAsyncer(false)
+	mock.Set("events.json", []byte(`{"a": 1}`))
+	reject := int32(0)
+	cfg := NewAsyncConfig(mock, "events.json", 0, false, WithSchema(SchemaFunc(func(v interface{}) (interface{}, error) {
+		if atomic.LoadInt32(&reject) == 1 {
+			return nil, errors.New("a must be positive")
+		}
+		return v, nil
+	})))
+	events := cfg.Events()
+	ast.Equal(events, cfg.Events())
+
+	e := receiveEvent(t, all, EventRefreshSucceeded)
+	ast.Equal("events.json", e.Key)
+	ast.True(e.Changed)
+	ast.Contains(e.Source, "MockAsyncer")
+	ast.False(e.Time.IsZero())
+
+	c := cfg.Configer.(*asyncConfig)
+	// MockAsyncer每次Get的内容不同
+	ast.Nil(c.refresh())
+	ast.True(receiveEvent(t, events, EventRefreshSucceeded).Changed)
+
+	atomic.StoreInt32(&reject, 1)
+	ast.NotNil(c.refresh())
+	e = receiveEvent(t, events, EventValidationRejected)
+	ast.True(errors.Is(e.Err, ErrValidation))
+
+	mock.data.Delete("events.json")
+	ast.NotNil(c.refresh())
+	e = receiveEvent(t, events, EventRefreshFailed)
+	ast.True(errors.Is(e.Err, ErrBackendUnavailable))
+
+	// notifier已满
+	cfg.Watch(make(chan struct{}))
+	c.notify()
+	receiveEvent(t, events, EventNotifyDropped)
+
+	// 后端的事件按key接收
+	emitBackendEvent(EventWatchReconnected, mock, "other.json")
+	emitBackendEvent(EventWatchReconnected, mock, "events.json")
+	e = receiveEvent(t, events, EventWatchReconnected)
+	ast.Equal("events.json", e.Key)
+	ast.Equal("*config.MockAsyncer", e.Source)
+	ast.Equal("watch_reconnected", e.Type.String())
+
+	// 关闭时关闭channel
+	cfg.Close()
+	for range events {
+	}
+	_, ok := <-cfg.Events()
+	ast.False(ok)
+	closed := NewAsyncConfig(mock, "closed.json", 0, false)
+	closed.Close()
+	_, ok = <-closed.Events()
+	ast.False(ok)
+
+	cancel()
+	cancel()
+	for range all {
+	}
+	ast.False(eventsEnabled())
+}
+
+func TestConsulWatchReconnected(t *testing.T) {
+	ast := assert.New(t)
+
+	old := ConsulRetryInterval
+	ConsulRetryInterval = 10 * time.Millisecond
+	defer func() { ConsulRetryInterval = old }()
+
+	fake := newFakeConsul()
+	failures := int32(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("index") != "" && atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	a := NewConsulAsyncer(server.URL, nil)
+	defer a.Close()
+	ast.Nil(a.Set("app.json", []byte(`{"a": 1}`)))
+
+	events, cancel := SubscribeEvents()
+	defer cancel()
+	a.Watch("app.json")
+	e := receiveEvent(t, events, EventWatchReconnected)
+	ast.Equal("app.json", e.Key)
+	ast.Equal("*config.ConsulAsyncer", e.Source)
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EncryptedFieldPrefix 加密字段在后端存储的值的前缀，其后为 Encrypt 结果的base64编码
+const EncryptedFieldPrefix = "enc:"
+
+// fieldCrypto 见 WithEncryptedFields
+type fieldCrypto struct {
+	key      []byte
+	patterns [][]string
+}
+
+// encryptedField 刷新时解密的字段
+type encryptedField struct {
+	ciphertext string
+	plain      interface{}
+}
+
+// WithEncryptedFields 只加密keyPaths中的字段：后端存储为 "enc:..." 的密文，刷新时解密，Set时加密，
+// 其他字段保持明文，在KV的界面中仍然可读
+//
+//	cfg := config.NewAsyncConfig(asyncer, "app.yaml", time.Minute, true,
+//		config.WithEncryptedFields(key, "db.password", "clients.*.secret"),
+//	)
+//
+// keyPath中的 "*" 匹配任意一级（包括数组下标）；字段的值可以是任意类型，加密前序列化为JSON；
+// 后端中为明文的字段原样使用，下次Set时加密；未修改的字段Set时保留原密文，避免无意义的变更。
+// key为 CryptoProvider 的AEAD密钥，默认实现为AES，长度为16、24或32
+func WithEncryptedFields(key []byte, keyPaths ...string) AsyncOption {
+	return func(cfg *asyncConfig) {
+		f := &fieldCrypto{key: key}
+		for _, keyPath := range keyPaths {
+			f.patterns = append(f.patterns, strings.Split(keyPath, "."))
+		}
+		cfg.fieldCrypto = f
+	}
+}
+
+// match keys匹配某个pattern
+func (f *fieldCrypto) match(keys []string) bool {
+	for _, p := range f.patterns {
+		if len(p) == len(keys) && matchSegments(p, keys) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayMatch keys的子节点可能匹配某个pattern
+func (f *fieldCrypto) mayMatch(keys []string) bool {
+	for _, p := range f.patterns {
+		if len(p) > len(keys) && matchSegments(p[:len(keys)], keys) {
+			return true
+		}
+	}
+	return false
+}
+
+// walk 用fn的返回值替换匹配的字段，直接修改val
+func (f *fieldCrypto) walk(keys []string, val interface{}, fn func(keyPath string, v interface{}) (interface{}, error)) (interface{}, error) {
+	if len(keys) > 0 && f.match(keys) {
+		return fn(strings.Join(keys, "."), val)
+	}
+	if !f.mayMatch(keys) {
+		return val, nil
+	}
+
+	keys = keys[:len(keys):len(keys)]
+	switch vv := val.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			ret, err := f.walk(append(keys, k), item, fn)
+			if err != nil {
+				return nil, err
+			}
+			vv[k] = ret
+		}
+	case []interface{}:
+		for i, item := range vv {
+			ret, err := f.walk(append(keys, strconv.Itoa(i)), item, fn)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = ret
+		}
+	}
+	return val, nil
+}
+
+// decrypt 解密val中的字段，返回解密后的配置及各字段的密文
+func (f *fieldCrypto) decrypt(val interface{}) (interface{}, map[string]encryptedField, error) {
+	fields := make(map[string]encryptedField)
+	ret, err := f.walk(nil, val, func(keyPath string, v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok || !strings.HasPrefix(s, EncryptedFieldPrefix) {
+			return v, nil
+		}
+
+		ciphertext, err := base64.StdEncoding.DecodeString(s[len(EncryptedFieldPrefix):])
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypt field[%s]", keyPath)
+		}
+		plaintext, err := Decrypt(f.key, ciphertext)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypt field[%s]", keyPath)
+		}
+		var plain interface{}
+		if err := json.Unmarshal(plaintext, &plain); err != nil {
+			return nil, errors.Wrapf(err, "decrypt field[%s]", keyPath)
+		}
+		fields[keyPath] = encryptedField{ciphertext: s, plain: plain}
+		return plain, nil
+	})
+	return ret, fields, err
+}
+
+// encrypt 加密val（需为副本）中的字段，值未变化的字段使用fields中的原密文
+//
+// 只有与原密文相同的值视为已加密，其他以 EncryptedFieldPrefix 开头的值也作为明文加密
+func (f *fieldCrypto) encrypt(val interface{}, fields map[string]encryptedField) (interface{}, error) {
+	return f.walk(nil, val, func(keyPath string, v interface{}) (interface{}, error) {
+		if v == nil {
+			return v, nil
+		}
+		if field, ok := fields[keyPath]; ok {
+			if s, isStr := v.(string); isStr && s == field.ciphertext || reflect.DeepEqual(field.plain, v) {
+				return field.ciphertext, nil
+			}
+		}
+
+		plaintext, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encrypt field[%s]", keyPath)
+		}
+		ciphertext, err := Encrypt(f.key, plaintext)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encrypt field[%s]", keyPath)
+		}
+		return EncryptedFieldPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+	})
+}
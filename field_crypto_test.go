@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func storedJSON(t *testing.T, mock *MockAsyncer, key string) map[string]interface{} {
+	v, ok := mock.data.Load(key)
+	assert.True(t, ok)
+	var m map[string]interface{}
+	assert.Nil(t, json.Unmarshal(v.([]byte), &m))
+	return m
+}
+
+func TestEncryptedFields(t *testing.T) {
+	ast := assert.New(t)
+
+	key := []byte("0123456789abcdef")
+	mock := NewMockAsyncer(false)
+	ast.Nil(mock.Set("app.json", []byte(`{
+		"db": {"host": "example.com", "password": "plain"},
+		"clients": [{"name": "a", "secret": {"token": "t1"}}, {"name": "b"}]
+	}`)))
+
+	opt := WithEncryptedFields(key, "db.password", "clients.*.secret")
+	cfg := NewAsyncConfig(mock, "app.json", time.Hour, false, opt)
+	defer cfg.Close()
+	// 后端中的明文原样使用
+	ast.Equal("plain", cfg.String("db.password"))
+
+	ast.Nil(cfg.Set("db.password", "s3cret"))
+	stored := storedJSON(t, mock, "app.json")
+	db := stored["db"].(map[string]interface{})
+	ast.Equal("example.com", db["host"], "other fields stay readable")
+	password := db["password"].(string)
+	ast.True(strings.HasPrefix(password, EncryptedFieldPrefix))
+	ast.NotContains(password, "s3cret")
+	clients := stored["clients"].([]interface{})
+	ast.True(strings.HasPrefix(clients[0].(map[string]interface{})["secret"].(string), EncryptedFieldPrefix))
+	ast.Equal("a", clients[0].(map[string]interface{})["name"])
+	ast.NotContains(clients[1].(map[string]interface{}), "secret")
+
+	// 刷新时解密，字段可以是任意类型
+	reader := NewAsyncConfig(mock, "app.json", time.Hour, false, opt)
+	defer reader.Close()
+	ast.Equal("s3cret", reader.String("db.password"))
+	ast.Equal("t1", reader.String("clients.0.secret.token"))
+
+	// 未修改的字段保留原密文
+	ast.Nil(reader.Set("db.host", "db.example.com"))
+	stored = storedJSON(t, mock, "app.json")
+	ast.Equal(password, stored["db"].(map[string]interface{})["password"])
+	ast.Equal("db.example.com", stored["db"].(map[string]interface{})["host"])
+
+	// 以前缀开头的明文同样加密
+	ast.Nil(reader.Set("db.password", "enc:not-a-ciphertext"))
+	stored = storedJSON(t, mock, "app.json")
+	ast.NotEqual("enc:not-a-ciphertext", stored["db"].(map[string]interface{})["password"])
+	again := NewAsyncConfig(mock, "app.json", time.Hour, false, opt)
+	defer again.Close()
+	ast.Equal("enc:not-a-ciphertext", again.String("db.password"))
+
+	// 密钥错误时刷新失败
+	wrong := NewAsyncConfig(mock, "app.json", time.Hour, false, WithEncryptedFields([]byte("fedcba9876543210"), "db.password"))
+	defer wrong.Close()
+	ast.Nil(wrong.Get("db.password"))
+}
+
+func TestFieldCryptoWalk(t *testing.T) {
+	ast := assert.New(t)
+
+	f := &fieldCrypto{key: []byte("0123456789abcdef"), patterns: [][]string{{"a", "*", "b"}, {"c"}}}
+	ast.True(f.match([]string{"a", "x", "b"}))
+	ast.False(f.match([]string{"a", "x"}))
+	ast.True(f.mayMatch([]string{"a", "x"}))
+	ast.False(f.mayMatch([]string{"d"}))
+
+	val := map[string]interface{}{
+		"a": map[string]interface{}{"x": map[string]interface{}{"b": 1.0, "e": 2.0}},
+		"c": nil,
+		"d": "public",
+	}
+	encrypted, err := f.encrypt(val, nil)
+	ast.Nil(err)
+	ast.Nil(encrypted.(map[string]interface{})["c"], "null stays null")
+	ast.Equal(2.0, val["a"].(map[string]interface{})["x"].(map[string]interface{})["e"])
+
+	decrypted, fields, err := f.decrypt(encrypted)
+	ast.Nil(err)
+	ast.Equal(1.0, decrypted.(map[string]interface{})["a"].(map[string]interface{})["x"].(map[string]interface{})["b"])
+	ast.Contains(fields, "a.x.b")
+
+	_, _, err = f.decrypt(map[string]interface{}{"c": EncryptedFieldPrefix + "!!"})
+	ast.NotNil(err)
+}
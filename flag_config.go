@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FlagLayerName 见 AddFlagLayer
+const FlagLayerName = "flag"
+
+// FlagSet 命令行flag的集合，标准库的 flag 见 StdFlags，pflag 见 contrib/pflagconfig
+type FlagSet interface {
+	// VisitAll 按名称顺序遍历所有flag，changed 表示在命令行中设置
+	VisitAll(fn func(name string, value interface{}, changed bool))
+	// SetDefault 设置flag的当前值及默认值，需在Parse之前调用
+	SetDefault(name, value string) error
+}
+
+// StdFlags 标准库的FlagSet，nil 为 flag.CommandLine
+func StdFlags(fs *flag.FlagSet) FlagSet {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	return stdFlags{fs}
+}
+
+type stdFlags struct {
+	fs *flag.FlagSet
+}
+
+func (s stdFlags) VisitAll(fn func(name string, value interface{}, changed bool)) {
+	changed := make(map[string]bool)
+	s.fs.Visit(func(f *flag.Flag) {
+		changed[f.Name] = true
+	})
+	s.fs.VisitAll(func(f *flag.Flag) {
+		var value interface{} = f.Value.String()
+		if g, ok := f.Value.(flag.Getter); ok {
+			value = g.Get()
+		}
+		fn(f.Name, value, changed[f.Name])
+	})
+}
+
+func (s stdFlags) SetDefault(name, value string) error {
+	f := s.fs.Lookup(name)
+	if f == nil {
+		return errors.Errorf("flag[%s] not defined", name)
+	}
+	if err := f.Value.Set(value); err != nil {
+		return err
+	}
+	f.DefValue = value
+	return nil
+}
+
+type flagOptions struct {
+	separator string
+	prefix    string
+}
+
+type FlagOption func(*flagOptions)
+
+// WithFlagSeparator flag名中分隔keyPath各段的字符串，默认 "."，如 -db.host => db.host
+func WithFlagSeparator(separator string) FlagOption {
+	return func(o *flagOptions) {
+		o.separator = separator
+	}
+}
+
+// WithFlagPrefix 只使用以prefix开头的flag，keyPath中去掉prefix
+func WithFlagPrefix(prefix string) FlagOption {
+	return func(o *flagOptions) {
+		o.prefix = prefix
+	}
+}
+
+func newFlagOptions(opts []FlagOption) *flagOptions {
+	o := &flagOptions{separator: "."}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// keyPath flag对应的keyPath，不以prefix开头时返回false
+func (o *flagOptions) keyPath(name string) (string, bool) {
+	if !strings.HasPrefix(name, o.prefix) || len(name) == len(o.prefix) {
+		return "", false
+	}
+	name = name[len(o.prefix):]
+	if o.separator == "." {
+		return name, true
+	}
+	return strings.Replace(name, o.separator, ".", -1), true
+}
+
+// FlagConfig 命令行中设置的flag组成的配置，只读
+type FlagConfig struct {
+	ConfigHelper
+}
+
+// NewFlagConfig fs需已Parse，只包含命令行中设置的flag，未设置的flag使用其他层的值；
+// 值为flag的类型（flag.Getter），time.Duration 转为字符串（如 "5s"）
+//
+//	flag.Parse()
+//	cfg := config.NewLayeredConfig(remote, config.NewFlagConfig(config.StdFlags(nil)))
+func NewFlagConfig(fs FlagSet, opts ...FlagOption) *FlagConfig {
+	o := newFlagOptions(opts)
+
+	root := make(map[string]interface{})
+	fs.VisitAll(func(name string, value interface{}, changed bool) {
+		keyPath, ok := o.keyPath(name)
+		if !changed || !ok {
+			return
+		}
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		if err := setMapValue(root, keyPath, value); err != nil {
+			logger.Warnf("flag config[%s] ignored: %v", name, err)
+		}
+	})
+
+	return &FlagConfig{
+		ConfigHelper: ConfigHelper{
+			Configer: &snapshotConfig{layers: []snapshotLayer{{source: "flag", root: root}}},
+		},
+	}
+}
+
+// SetFlagDefaults 使用cfg中的值作为flag的默认值，需在Parse之前调用，命令行中设置的值仍然优先，
+// -help 中显示的默认值为配置中的值；cfg中不存在的flag不变
+//
+//	config.SetFlagDefaults(config.StdFlags(nil), remote)
+//	flag.Parse()
+func SetFlagDefaults(fs FlagSet, cfg Configer, opts ...FlagOption) error {
+	o := newFlagOptions(opts)
+
+	var errs []string
+	fs.VisitAll(func(name string, _ interface{}, _ bool) {
+		keyPath, ok := o.keyPath(name)
+		if !ok {
+			return
+		}
+		v := cfg.Get(keyPath)
+		if v == nil {
+			return
+		}
+		value, err := flagValueString(v)
+		if err == nil {
+			err = fs.SetDefault(name, value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("flag[%s]: %v", name, err))
+		}
+	})
+	if len(errs) > 0 {
+		return errors.Errorf("set flag defaults: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// flagValueString 标量转为字符串，map及数组转为JSON
+func flagValueString(v interface{}) (string, error) {
+	switch vv := v.(type) {
+	case string:
+		return vv, nil
+	case float64:
+		// JSON的数字，避免 1e+06 等无法被整数flag解析的格式
+		return strconv.FormatFloat(vv, 'f', -1, 64), nil
+	case map[string]interface{}, []interface{}:
+		bs, err := json.Marshal(vv)
+		return string(bs), err
+	}
+	return fmt.Sprint(v), nil
+}
+
+// AddFlagLayer 将命令行中设置的flag添加为 FlagLayerName 层，并作为优先级最高的默认Layer，
+// 同时使用环境变量时在 AddEnvLayer 之后调用
+func AddFlagLayer(fs FlagSet, opts ...FlagOption) {
+	AddLayer(FlagLayerName, NewFlagConfig(fs, opts...))
+	AddDefaultLayerName(FlagLayerName)
+}
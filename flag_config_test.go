@@ -0,0 +1,98 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.String("db.host", "localhost", "")
+	fs.Int("db.port", 3306, "")
+	fs.Bool("debug", false, "")
+	fs.Duration("timeout", time.Second, "")
+	fs.String("name", "", "")
+	return fs
+}
+
+func TestFlagConfig(t *testing.T) {
+	ast := assert.New(t)
+
+	fs := newTestFlagSet()
+	ast.Nil(fs.Parse([]string{"-db.port", "3307", "-debug", "-timeout", "5s"}))
+
+	cfg := NewFlagConfig(StdFlags(fs))
+	ast.Equal(map[string]interface{}{
+		"db":      map[string]interface{}{"port": 3307},
+		"debug":   true,
+		"timeout": "5s",
+	}, cfg.Get(RootKey))
+	ast.Equal(ErrReadOnly, cfg.Set("debug", false))
+	origin, _ := cfg.Origin("debug")
+	ast.Equal("flag", origin.Source)
+
+	// 命令行中的flag优先级最高
+	remote := NewMapConfig(map[string]interface{}{"db": map[string]interface{}{"host": "remote", "port": 5432}, "timeout": "1m"})
+	layered := NewLayeredConfig(remote, cfg)
+	defer layered.Close()
+	ast.Equal("remote", layered.String("db.host"))
+	ast.Equal(int64(3307), layered.Int("db.port"))
+	ast.Equal("5s", layered.String("timeout"))
+
+	// 分隔符及前缀
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("app-db-host", "", "")
+	fs.String("other", "", "")
+	ast.Nil(fs.Parse([]string{"-app-db-host", "h", "-other", "o"}))
+	cfg = NewFlagConfig(StdFlags(fs), WithFlagPrefix("app-"), WithFlagSeparator("-"))
+	ast.Equal(map[string]interface{}{"db": map[string]interface{}{"host": "h"}}, cfg.Get(RootKey))
+}
+
+func TestSetFlagDefaults(t *testing.T) {
+	ast := assert.New(t)
+
+	fs := newTestFlagSet()
+	remote := NewMapConfig(map[string]interface{}{
+		"db":      map[string]interface{}{"host": "remote", "port": float64(1000000)},
+		"timeout": "1m",
+	})
+	ast.Nil(SetFlagDefaults(StdFlags(fs), remote))
+	ast.Nil(fs.Parse([]string{"-timeout", "5s"}))
+
+	ast.Equal("remote", fs.Lookup("db.host").Value.String())
+	ast.Equal("remote", fs.Lookup("db.host").DefValue)
+	ast.Equal("1000000", fs.Lookup("db.port").Value.String())
+	ast.Equal("5s", fs.Lookup("timeout").Value.String())
+	ast.Equal("", fs.Lookup("name").Value.String())
+
+	// 默认值不是在命令行中设置的
+	ast.Equal(map[string]interface{}{"timeout": "5s"}, NewFlagConfig(StdFlags(fs)).Get(RootKey))
+
+	fs = newTestFlagSet()
+	err := SetFlagDefaults(StdFlags(fs), NewMapConfig(map[string]interface{}{"debug": "maybe"}))
+	ast.NotNil(err)
+	ast.Contains(err.Error(), "flag[debug]")
+}
+
+func TestAddFlagLayer(t *testing.T) {
+	ast := assert.New(t)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("flaglayer.region", "", "")
+	ast.Nil(fs.Parse([]string{"-flaglayer.region", "eu"}))
+
+	AddLayer(DefaultLayerName, NewMapConfig(map[string]interface{}{"flaglayer": map[string]interface{}{"region": "us", "zone": "a"}}))
+	AddFlagLayer(StdFlags(fs))
+	defer func() {
+		RemoveDefaultLayerName(FlagLayerName)
+		RemoveLayer(FlagLayerName)
+	}()
+
+	ast.Equal("eu", String("flaglayer.region"))
+	ast.Equal("a", String("flaglayer.zone"))
+}
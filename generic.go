@@ -0,0 +1,37 @@
+package config
+
+import "reflect"
+
+// LookupAs 返回转换为T的配置值，转换规则同 ConfigHelper.Unmarshal；
+// 配置项不存在时返回 ErrKeyNotFound，值为null时返回 ErrNullValue，无法转换时返回 ErrTypeMismatch
+func LookupAs[T any](cfg Configer, keyPath string) (T, error) {
+	var v T
+	val, err := (&ConfigHelper{Configer: cfg}).lookupNotNull(keyPath)
+	if err != nil {
+		return v, err
+	}
+	if err := decodeInto(keyPath, val, reflect.ValueOf(&v).Elem()); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+// GetAs 返回转换为T的配置值，不存在或无法转换时返回T的零值；包级的 Get 用于默认配置，因此命名为GetAs
+//
+//	port := config.GetAs[int](cfg, "db.port") // "3306" => 3306
+//	timeout := config.GetAs[time.Duration](cfg, "db.timeout")
+//	hosts := config.GetAs[[]string](cfg, "db.hosts")
+func GetAs[T any](cfg Configer, keyPath string) T {
+	v, _ := LookupAs[T](cfg, keyPath)
+	return v
+}
+
+// GetOr 返回转换为T的配置值，不存在、为null或无法转换时返回def
+func GetOr[T any](cfg Configer, keyPath string, def T) T {
+	v, err := LookupAs[T](cfg, keyPath)
+	if err != nil {
+		return def
+	}
+	return v
+}
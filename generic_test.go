@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAs(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host":    "example.com",
+			"port":    "3306",
+			"timeout": "5s",
+			"hosts":   []interface{}{"a", "b"},
+			"null":    nil,
+		},
+	})
+
+	ast.Equal("example.com", GetAs[string](cfg, "db.host"))
+	ast.Equal(3306, GetAs[int](cfg, "db.port"))
+	ast.Equal(uint16(3306), GetAs[uint16](cfg, "db.port"))
+	ast.Equal(5*time.Second, GetAs[time.Duration](cfg, "db.timeout"))
+	ast.Equal([]string{"a", "b"}, GetAs[[]string](cfg, "db.hosts"))
+	ast.Equal(struct{ Host string }{"example.com"}, GetAs[struct{ Host string }](cfg, "db"))
+	ast.Equal(0, GetAs[int](cfg, "db.host"), "type mismatch")
+	ast.Equal("", GetAs[string](cfg, "not_exist"))
+
+	ast.Equal(3306, GetOr(cfg, "db.port", 1))
+	ast.Equal(1, GetOr(cfg, "not_exist", 1))
+	ast.Equal(1, GetOr(cfg, "db.null", 1))
+	ast.Equal(1, GetOr(cfg, "db.host", 1))
+	ast.Equal([]int{1}, GetOr(cfg, "db.hosts", []int{1}), "invalid items")
+
+	_, err := LookupAs[int](cfg, "not_exist")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+	_, err = LookupAs[int](cfg, "db.null")
+	ast.True(errors.Is(err, ErrNullValue))
+	_, err = LookupAs[int](cfg, "db.host")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"regexp"
+
+	"github.com/pkg/errors"
 )
 
 var (
@@ -38,8 +41,29 @@ func processRawMessage(msg []byte, t ContentType) []byte {
 	return ret
 }
 
+// TransformStage 对解析后的配置进行处理，如统一的key规范化，直接修改tree
+type TransformStage func(tree map[string]interface{}) error
+
+var transformStages []TransformStage
+
+// RegisterTransform 注册刷新时的处理阶段，按注册顺序在解析之后、校验之前执行，
+// 任一阶段返回error时本次刷新失败并保留旧值，需在创建配置前注册
+func RegisterTransform(stage TransformStage) {
+	transformStages = append(transformStages, stage)
+}
+
+func runTransformStages(tree map[string]interface{}) error {
+	for i, stage := range transformStages {
+		if err := stage(tree); err != nil {
+			return errors.Wrapf(err, "transform stage %d", i)
+		}
+	}
+	return nil
+}
+
 func trimJsonComment(content []byte, tp ContentType) []byte {
-	if tp != T_JSON {
+	// 没有注释时不复制内容
+	if tp != T_JSON || bytes.IndexByte(content, '/') < 0 {
 		return content
 	}
 
@@ -60,3 +84,109 @@ func trimJsonComment(content []byte, tp ContentType) []byte {
 
 	return content
 }
+
+// LenientJSON 宽松模式的JSON预处理：去除注释（// 与 /* */）以及对象、数组末尾多余的逗号
+//
+// 适用于在KV后台手工编辑的JSONC文档，默认不启用，需要时注册：
+//
+//	config.RegisterRawMessageProcessor(config.LenientJSON)
+func LenientJSON(content []byte, tp ContentType) []byte {
+	if tp != T_JSON {
+		return content
+	}
+
+	return stripTrailingCommas(stripJSONComments(content))
+}
+
+// stripJSONComments 去除字符串之外的注释，保留换行以便错误信息中的行号不变
+func stripJSONComments(content []byte) []byte {
+	ret := make([]byte, 0, len(content))
+	inString := false
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		if inString {
+			ret = append(ret, c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				ret = append(ret, content[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		if c == '/' && i+1 < len(content) {
+			if content[i+1] == '/' {
+				for i < len(content) && content[i] != '\n' {
+					i++
+				}
+				if i < len(content) {
+					ret = append(ret, '\n')
+				}
+				continue
+			}
+			if content[i+1] == '*' {
+				i += 2
+				for i < len(content) && !(content[i] == '*' && i+1 < len(content) && content[i+1] == '/') {
+					if content[i] == '\n' {
+						ret = append(ret, '\n')
+					}
+					i++
+				}
+				i++ // skip '/'
+				continue
+			}
+		}
+
+		if c == '"' {
+			inString = true
+		}
+		ret = append(ret, c)
+	}
+
+	return ret
+}
+
+// stripTrailingCommas 去除 '}' 或 ']' 之前多余的逗号（需在去除注释之后调用）
+func stripTrailingCommas(content []byte) []byte {
+	ret := make([]byte, 0, len(content))
+	inString := false
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		if inString {
+			ret = append(ret, c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				ret = append(ret, content[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		if c == ',' {
+			j := i + 1
+			for j < len(content) && isJSONSpace(content[j]) {
+				j++
+			}
+			if j < len(content) && (content[j] == '}' || content[j] == ']') {
+				continue
+			}
+		}
+
+		if c == '"' {
+			inString = true
+		}
+		ret = append(ret, c)
+	}
+
+	return ret
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -3,8 +3,11 @@ package config
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/kot-w/goutils/itype"
 	"github.com/kot-w/goutils/object"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,3 +44,74 @@ func testTrimJsonComment(t *testing.T) {
 	v, _ := object.GetValue(v1, "__debug")
 	ast.Equal(true, v)
 }
+
+func TestLenientJSON(t *testing.T) {
+	ast := assert.New(t)
+
+	jsonStr := []byte(`{
+	/* block
+	   comment */
+	"url" : "http://example.com/path", // trailing comment
+	"pattern" : "/* not a comment */",
+	"list" : [1, 2, 3,],
+	"nested" : {
+		"a" : "a,]", /* inline */
+	},
+}`)
+
+	var v interface{}
+	ast.NotNil(json.Unmarshal(jsonStr, &v))
+
+	ret := LenientJSON(jsonStr, T_JSON)
+	ast.Nil(json.Unmarshal(ret, &v))
+
+	val, _ := object.GetValue(v, "url")
+	ast.Equal("http://example.com/path", val)
+	val, _ = object.GetValue(v, "pattern")
+	ast.Equal("/* not a comment */", val)
+	val, _ = object.GetValue(v, "list")
+	ast.Equal([]interface{}{float64(1), float64(2), float64(3)}, val)
+	val, _ = object.GetValue(v, "nested.a")
+	ast.Equal("a,]", val)
+
+	// not json content
+	yamlStr := []byte("a: 1, # comment")
+	ast.Equal(yamlStr, LenientJSON(yamlStr, T_YAML))
+}
+
+func TestRegisterTransform(t *testing.T) {
+	ast := assert.New(t)
+
+	// 只处理测试的配置，避免影响其他测试
+	RegisterTransform(func(tree map[string]interface{}) error {
+		if _, ok := tree["transform_stage"]; !ok {
+			return nil
+		}
+		if port, ok := tree["port"].(string); ok {
+			tree["port"] = itype.Int(port)
+		}
+		return nil
+	})
+	RegisterTransform(func(tree map[string]interface{}) error {
+		if tree["transform_stage"] == "fail" {
+			return errors.New("invalid")
+		}
+		return nil
+	})
+
+	asyncer := NewMockAsyncer(false)
+	asyncer.data.Store("stage.json", []byte(`{"transform_stage": true, "port": "3306"}`))
+	cfg := NewAsyncConfig(asyncer, "stage.json", time.Millisecond, false,
+		WithSchema(SchemaFunc(func(value interface{}) (interface{}, error) {
+			port, _ := object.GetValue(value, "port")
+			if _, ok := port.(int64); !ok {
+				return nil, errors.Errorf("port not normalized: %T", port)
+			}
+			return value, nil
+		})))
+	ast.EqualValues(3306, cfg.Get("port"))
+
+	asyncer.data.Store("stage.json", []byte(`{"transform_stage": "fail", "port": "3307"}`))
+	time.Sleep(2 * time.Millisecond)
+	ast.EqualValues(3306, cfg.Get("port"), "keep old value")
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"hash/fnv"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// KeyWatchShards WatchKey订阅索引的分片数，<=0 时为 GOMAXPROCS，在首次 WatchKey 时确定
+var KeyWatchShards = 0
+
+// keyWatchParallel 变化的keyPath（含父节点）不少于该数量时各分片并发匹配
+var keyWatchParallel = 64
+
+// WatchKey 只在keyPath或其子节点变化时通知notifier，调用stop停止
+//
+// 同一Configer的所有 WatchKey 及 WatchFilter 共用一次 OnChange 及一次比较，订阅按keyPath的hash分片，
+// 大量订阅（如每个租户一个）时订阅、取消与变化匹配不会争用同一把锁
+func (h *ConfigHelper) WatchKey(keyPath string, notifier chan struct{}) (stop func()) {
+	sub := &keySub{keyPath: keyPath, notifier: notifier}
+	idx := acquireKeyIndex(h.Configer)
+	idx.shard(keyPath).add(sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			idx.shard(keyPath).remove(sub)
+			releaseKeyIndex(idx)
+		})
+	}
+}
+
+var (
+	_keyIndexesMu sync.Mutex
+	_keyIndexes   = make(map[Configer]*keyIndex)
+)
+
+// acquireKeyIndex 返回c的订阅索引，不存在时创建并开始监听
+//
+// c不能比较（如包含slice、map的struct）时不共享，每次返回新的索引
+func acquireKeyIndex(c Configer) *keyIndex {
+	_keyIndexesMu.Lock()
+	defer _keyIndexesMu.Unlock()
+
+	shared := isComparable(c)
+	var idx *keyIndex
+	if shared {
+		idx = _keyIndexes[c]
+	}
+	if idx == nil {
+		idx = newKeyIndex(c)
+		idx.shared = shared
+		if shared {
+			_keyIndexes[c] = idx
+		}
+		idx.start()
+	}
+	idx.refs++
+	return idx
+}
+
+// releaseKeyIndex 最后一个订阅取消时取消监听
+func releaseKeyIndex(idx *keyIndex) {
+	_keyIndexesMu.Lock()
+	defer _keyIndexesMu.Unlock()
+
+	if idx.refs--; idx.refs == 0 {
+		if idx.shared {
+			delete(_keyIndexes, idx.c)
+		}
+		idx.cancel()
+	}
+}
+
+type keySub struct {
+	keyPath  string
+	notifier chan struct{}
+}
+
+// keyShard 一个分片，keyPath => 订阅
+type keyShard struct {
+	sync.RWMutex
+	subs map[string][]*keySub
+}
+
+func (s *keyShard) add(sub *keySub) {
+	s.Lock()
+	defer s.Unlock()
+	s.subs[sub.keyPath] = append(s.subs[sub.keyPath], sub)
+}
+
+func (s *keyShard) remove(sub *keySub) {
+	s.Lock()
+	defer s.Unlock()
+
+	subs := s.subs[sub.keyPath]
+	for i, v := range subs {
+		if v == sub {
+			subs = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(s.subs, sub.keyPath)
+	} else {
+		s.subs[sub.keyPath] = subs
+	}
+}
+
+// notify 通知订阅了keyPaths中任一节点的监听者
+func (s *keyShard) notify(keyPaths []string) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, keyPath := range keyPaths {
+		for _, sub := range s.subs[keyPath] {
+			select {
+			case sub.notifier <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// keyIndex 一个Configer的 WatchKey 及 WatchFilter 订阅，共用一次 OnChange 及一次比较
+type keyIndex struct {
+	c      Configer
+	shards []*keyShard
+	refs   int  // 订阅数，由 _keyIndexesMu 保护
+	shared bool // 是否在 _keyIndexes 中
+
+	filtersMu sync.RWMutex
+	filters   []*filterSub
+
+	last   map[string]interface{} // 上一次比较时的叶子节点，只在OnChange回调中访问
+	cancel func()
+}
+
+type filterSub struct {
+	filter   Filter
+	notifier chan struct{}
+}
+
+func newKeyIndex(c Configer) *keyIndex {
+	n := KeyWatchShards
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	idx := &keyIndex{
+		c:      c,
+		shards: make([]*keyShard, n),
+	}
+	for i := range idx.shards {
+		idx.shards[i] = &keyShard{subs: make(map[string][]*keySub)}
+	}
+	return idx
+}
+
+func (idx *keyIndex) shardIndex(keyPath string) int {
+	h := fnv.New32a()
+	h.Write([]byte(keyPath))
+	return int(h.Sum32() % uint32(len(idx.shards)))
+}
+
+func (idx *keyIndex) shard(keyPath string) *keyShard {
+	return idx.shards[idx.shardIndex(keyPath)]
+}
+
+// start 通过 OnChange 监听，最后一个订阅取消时取消
+func (idx *keyIndex) start() {
+	idx.last = leafValues(idx.c)
+	idx.cancel = (&ConfigHelper{idx.c}).OnChange(func() {
+		// 同一回调不会并发执行
+		cur := leafValues(idx.c)
+		leaves := changedLeaves(idx.last, cur)
+		idx.last = cur
+		idx.matchFilters(leaves)
+		idx.match(withParents(leaves))
+	})
+}
+
+func (idx *keyIndex) addFilter(sub *filterSub) {
+	idx.filtersMu.Lock()
+	defer idx.filtersMu.Unlock()
+	idx.filters = append(idx.filters, sub)
+}
+
+func (idx *keyIndex) removeFilter(sub *filterSub) {
+	idx.filtersMu.Lock()
+	defer idx.filtersMu.Unlock()
+	for i, v := range idx.filters {
+		if v == sub {
+			idx.filters = append(idx.filters[:i:i], idx.filters[i+1:]...)
+			return
+		}
+	}
+}
+
+// matchFilters 通知filter匹配任一变化的叶子节点的监听者
+func (idx *keyIndex) matchFilters(leaves []string) {
+	if len(leaves) == 0 {
+		return
+	}
+
+	idx.filtersMu.RLock()
+	defer idx.filtersMu.RUnlock()
+	for _, sub := range idx.filters {
+		for _, keyPath := range leaves {
+			if sub.filter.Match(keyPath) {
+				select {
+				case sub.notifier <- struct{}{}:
+				default:
+				}
+				break
+			}
+		}
+	}
+}
+
+// match 按分片分组，数量较多时各分片并发通知
+func (idx *keyIndex) match(keyPaths []string) {
+	if len(keyPaths) == 0 {
+		return
+	}
+	if len(idx.shards) == 1 {
+		idx.shards[0].notify(keyPaths)
+		return
+	}
+
+	groups := make([][]string, len(idx.shards))
+	for _, keyPath := range keyPaths {
+		i := idx.shardIndex(keyPath)
+		groups[i] = append(groups[i], keyPath)
+	}
+
+	if len(keyPaths) < keyWatchParallel {
+		for i, group := range groups {
+			if len(group) > 0 {
+				idx.shards[i].notify(group)
+			}
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(shard *keyShard, group []string) {
+			defer wg.Done()
+			shard.notify(group)
+		}(idx.shards[i], group)
+	}
+	wg.Wait()
+}
+
+func leafValues(c Configer) map[string]interface{} {
+	ret := make(map[string]interface{})
+	(&ConfigHelper{c}).Range(func(keyPath string, value interface{}) bool {
+		ret[keyPath] = value
+		return true
+	})
+	return ret
+}
+
+// changedLeaves 新增、修改、删除的叶子节点
+func changedLeaves(old, new map[string]interface{}) []string {
+	var leaves []string
+	for k, ov := range old {
+		if nv, ok := new[k]; !ok || !reflect.DeepEqual(ov, nv) {
+			leaves = append(leaves, k)
+		}
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			leaves = append(leaves, k)
+		}
+	}
+	return leaves
+}
+
+// withParents 叶子节点及其所有父节点（包括 RootKey）
+func withParents(leaves []string) []string {
+	set := make(map[string]struct{})
+	for _, keyPath := range leaves {
+		for {
+			if _, ok := set[keyPath]; ok {
+				break
+			}
+			set[keyPath] = struct{}{}
+			if keyPath == RootKey {
+				break
+			}
+			if i := strings.LastIndexByte(keyPath, '.'); i >= 0 {
+				keyPath = keyPath[:i]
+			} else {
+				keyPath = RootKey
+			}
+		}
+	}
+
+	keyPaths := make([]string, 0, len(set))
+	for k := range set {
+		keyPaths = append(keyPaths, k)
+	}
+	return keyPaths
+}
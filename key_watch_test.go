@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchKey(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"tenants": map[string]interface{}{
+			"a": map[string]interface{}{"limit": 1},
+			"b": map[string]interface{}{"limit": 1},
+		},
+	})
+
+	wait := func(ch chan struct{}) bool {
+		select {
+		case <-ch:
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}
+
+	a := make(chan struct{}, 1)
+	stopA := cfg.WatchKey("tenants.a", a)
+	b := make(chan struct{}, 1)
+	stopB := cfg.WatchKey("tenants.b.limit", b)
+	defer stopB()
+	root := make(chan struct{}, 1)
+	stopRoot := cfg.WatchKey(RootKey, root)
+	defer stopRoot()
+
+	ast.Nil(cfg.Set("tenants.a.limit", 2))
+	ast.True(wait(a))
+	ast.True(wait(root))
+	ast.False(wait(b), "other tenant")
+
+	ast.Nil(cfg.Set("tenants.a.burst", 10))
+	ast.True(wait(a), "added child")
+
+	ast.Nil(cfg.Set("tenants.b.limit", 1))
+	ast.False(wait(b), "same value")
+	ast.Nil(cfg.Set("tenants.b.limit", 3))
+	ast.True(wait(b))
+
+	stopA()
+	stopA()
+	ast.Nil(cfg.Set("tenants.a.limit", 3))
+	ast.False(wait(a), "stopped")
+	ast.True(wait(root))
+
+	// 最后一个订阅取消后移除索引
+	stopB()
+	stopRoot()
+	_keyIndexesMu.Lock()
+	_, ok := _keyIndexes[cfg.Configer]
+	_keyIndexesMu.Unlock()
+	ast.False(ok)
+
+	// 同时取消OnChange订阅，重新订阅不会累积
+	for i := 0; i < 3; i++ {
+		cfg.WatchKey("tenants.a", a)()
+	}
+	m := cfg.Configer.(*mapConfig)
+	m.subs.mu.Lock()
+	ast.Empty(m.subs.subs)
+	m.subs.mu.Unlock()
+}
+
+type uncomparableConfiger struct {
+	Configer
+	tags []string
+}
+
+func TestWatchKeyUncomparable(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"a": 1})
+	c := uncomparableConfiger{Configer: cfg}
+	h := &ConfigHelper{c}
+
+	notifier := make(chan struct{}, 1)
+	stop := h.WatchKey("a", notifier)
+	defer stop()
+	ast.Nil(cfg.Set("a", 2))
+	select {
+	case <-notifier:
+	case <-time.After(time.Second):
+		t.Fatal("not notified")
+	}
+
+	_keyIndexesMu.Lock()
+	ast.Empty(_keyIndexes)
+	_keyIndexesMu.Unlock()
+}
+
+func TestWatchKeyParallelMatch(t *testing.T) {
+	ast := assert.New(t)
+
+	KeyWatchShards = 8
+	defer func() { KeyWatchShards = 0 }()
+
+	m := make(map[string]interface{})
+	for i := 0; i < 200; i++ {
+		m[fmt.Sprintf("t%d", i)] = 0
+	}
+	cfg := NewMapConfig(map[string]interface{}{"tenants": m})
+
+	chans := make([]chan struct{}, 200)
+	for i := range chans {
+		chans[i] = make(chan struct{}, 1)
+		defer cfg.WatchKey(fmt.Sprintf("tenants.t%d", i), chans[i])()
+	}
+
+	// 全部修改，变化的keyPath超过 keyWatchParallel
+	next := make(map[string]interface{})
+	for k := range m {
+		next[k] = 1
+	}
+	ast.Nil(cfg.Set("tenants", next))
+	for i, ch := range chans {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			ast.Fail("not notified", "tenants.t%d", i)
+		}
+	}
+}
+
+func TestChangedKeyPaths(t *testing.T) {
+	ast := assert.New(t)
+
+	leaves := changedLeaves(
+		map[string]interface{}{"a.b": 1, "a.c": 2, "d": 3},
+		map[string]interface{}{"a.b": 1, "a.c": 5, "e.f": 4},
+	)
+	sort.Strings(leaves)
+	ast.Equal([]string{"a.c", "d", "e.f"}, leaves)
+	keyPaths := withParents(leaves)
+	sort.Strings(keyPaths)
+	ast.Equal([]string{"", "a", "a.c", "d", "e", "e.f"}, keyPaths)
+	ast.Empty(changedLeaves(map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1}))
+}
+
+func newBenchKeyIndex(shards, subs int) (*keyIndex, []string) {
+	KeyWatchShards = shards
+	defer func() { KeyWatchShards = 0 }()
+
+	idx := newKeyIndex(NewMapConfig(nil))
+	keyPaths := make([]string, subs)
+	for i := range keyPaths {
+		keyPaths[i] = fmt.Sprintf("tenants.t%d.limit", i)
+		idx.shard(keyPaths[i]).add(&keySub{keyPath: keyPaths[i], notifier: make(chan struct{}, 1)})
+	}
+	return idx, keyPaths
+}
+
+// BenchmarkKeyIndexSubscribe 并发订阅及取消
+func BenchmarkKeyIndexSubscribe(b *testing.B) {
+	for _, shards := range []int{1, 0} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			idx, keyPaths := newBenchKeyIndex(shards, 10000)
+			var n uint64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					keyPath := keyPaths[atomic.AddUint64(&n, 1)%uint64(len(keyPaths))]
+					sub := &keySub{keyPath: keyPath, notifier: make(chan struct{}, 1)}
+					idx.shard(keyPath).add(sub)
+					idx.shard(keyPath).remove(sub)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkKeyIndexMatch 一次变化涉及1000个租户，共10000个订阅
+func BenchmarkKeyIndexMatch(b *testing.B) {
+	for _, shards := range []int{1, 0} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			idx, keyPaths := newBenchKeyIndex(shards, 10000)
+			changed := keyPaths[:1000]
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				idx.match(changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"sort"
+)
+
+// AllKeys 返回所有叶子节点（map之外的值）的keyPath，按字典序排序
+//
+// 分层配置返回各层keyPath的并集，被上层的map覆盖的keyPath除外
+func (h *ConfigHelper) AllKeys() []string {
+	return (&snapshotConfig{layers: snapshotLayers(h.Configer)}).leafKeys()
+}
+
+// Range 按 AllKeys 的顺序遍历叶子节点及生效的值，fn返回false时停止
+//
+// 遍历基于调用时的快照，遍历期间配置的变化不影响遍历结果
+func (h *ConfigHelper) Range(fn func(keyPath string, value interface{}) bool) {
+	snap := &snapshotConfig{layers: snapshotLayers(h.Configer)}
+	for _, keyPath := range snap.leafKeys() {
+		if !fn(keyPath, snap.Get(keyPath)) {
+			return
+		}
+	}
+}
+
+// leafKeys 各层叶子节点keyPath的并集，按字典序排序
+func (s *snapshotConfig) leafKeys() []string {
+	leaves := make(map[string]interface{})
+	for _, layer := range s.layers {
+		flattenLeaves(RootKey, layer.root, leaves)
+	}
+
+	keys := make([]string, 0, len(leaves))
+	for keyPath := range leaves {
+		if m, ok := s.Get(keyPath).(map[string]interface{}); ok && len(m) > 0 {
+			continue
+		}
+		keys = append(keys, keyPath)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllKeys(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"b": map[string]interface{}{"y": 1, "x": []interface{}{1, 2}},
+		"a": "1",
+		"c": map[string]interface{}{},
+		"d": nil,
+	})
+	ast.Equal([]string{"a", "b.x", "b.y", "c", "d"}, cfg.AllKeys())
+
+	for i := 0; i < 10; i++ {
+		keys := make([]string, 0)
+		cfg.Range(func(keyPath string, value interface{}) bool {
+			keys = append(keys, keyPath)
+			return true
+		})
+		ast.Equal(cfg.AllKeys(), keys, "stable order")
+	}
+
+	var visited []string
+	cfg.Range(func(keyPath string, value interface{}) bool {
+		visited = append(visited, keyPath)
+		return keyPath != "b.x"
+	})
+	ast.Equal([]string{"a", "b.x"}, visited)
+
+	// layered
+	layered := newConfig()
+	layered.AddLayer("l1", NewMapConfig(map[string]interface{}{"a": 1, "m": map[string]interface{}{"x": 1}}))
+	layered.AddLayer("l2", NewMapConfig(map[string]interface{}{"a": 2, "b": 3, "m": map[string]interface{}{"y": 2}}))
+	p := layered.Layer("l1", "l2")
+	ast.Equal([]string{"a", "b", "m.x", "m.y"}, p.AllKeys())
+	values := make(map[string]interface{})
+	p.Range(func(keyPath string, value interface{}) bool {
+		values[keyPath] = value
+		return true
+	})
+	ast.Equal(map[string]interface{}{"a": 1, "b": 3, "m.x": 1, "m.y": 2}, values)
+
+	ast.Empty(NewMapConfig(nil).AllKeys())
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// LayeredConfig 合并多个来源的配置，后面的来源优先级更高，map按key深度合并，其他值整体覆盖，
+// 数组等的合并方式见 SetMergePolicies
+//
+//	cfg := config.NewLayeredConfig(defaults, remote, env, flags)
+//	defer cfg.Close()
+//
+// 任一来源变化时重新合并，合并结果变化时通知 Watch 及 OnChange
+type LayeredConfig struct {
+	ConfigHelper
+}
+
+// NewLayeredConfig sources按优先级从低到高排列，根节点不是map的来源被忽略
+func NewLayeredConfig(sources ...Configer) *LayeredConfig {
+	l := &layeredConfig{
+		sources: sources,
+		notify:  make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+	}
+	for _, source := range sources {
+		source.Watch(l.notify)
+	}
+	l.merge()
+	go l.watch()
+
+	return &LayeredConfig{
+		ConfigHelper: ConfigHelper{
+			Configer: l,
+		},
+	}
+}
+
+// SetMergePolicies 设置各路径的合并策略并立即重新合并，见 MergeTreesWith
+func (c *LayeredConfig) SetMergePolicies(policies MergePolicies) {
+	l := c.Configer.(*layeredConfig)
+	l.policies.Store(policies)
+	l.merge()
+}
+
+// Close 停止监听各来源的变化，不关闭来源
+func (c *LayeredConfig) Close() error {
+	return c.Configer.(*layeredConfig).Close()
+}
+
+type layeredConfig struct {
+	sync.Mutex
+	sources   []Configer
+	merged    atomic.Value // map[string]interface{}
+	policies  atomic.Value // MergePolicies
+	mergeMu   sync.Mutex
+	notifiers []chan struct{}
+	subs      subscriptions // 见 OnChange
+
+	notify    chan struct{} // 各来源的变化
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *layeredConfig) watch() {
+	for {
+		select {
+		case <-l.notify:
+			l.merge()
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// merge 重新合并各来源，结果变化时通知
+func (l *layeredConfig) merge() {
+	l.mergeMu.Lock()
+	defer l.mergeMu.Unlock()
+
+	policies, _ := l.policies.Load().(MergePolicies)
+	merged := make(map[string]interface{})
+	for _, source := range l.sources {
+		if m, ok := source.Get(RootKey).(map[string]interface{}); ok {
+			merged = MergeTreesWith(merged, m, policies)
+		}
+	}
+
+	if prev, ok := l.merged.Load().(map[string]interface{}); ok && reflect.DeepEqual(prev, merged) {
+		return
+	}
+	l.merged.Store(merged)
+
+	l.Lock()
+	notifiers := l.notifiers
+	l.Unlock()
+	for _, notifier := range notifiers {
+		select {
+		case notifier <- struct{}{}:
+		default:
+		}
+	}
+	l.subs.dispatch()
+}
+
+func (l *layeredConfig) Get(keyPath string) interface{} {
+	val, _ := l.Lookup(keyPath)
+	return val
+}
+
+func (l *layeredConfig) Lookup(keyPath string) (interface{}, bool) {
+	return lookupValue(l.merged.Load(), keyPath)
+}
+
+// Set 写入优先级最高的来源，并立即重新合并
+func (l *layeredConfig) Set(keyPath string, value interface{}) error {
+	if len(l.sources) == 0 {
+		return ErrReadOnly
+	}
+	if err := l.sources[len(l.sources)-1].Set(keyPath, value); err != nil {
+		return err
+	}
+	l.merge()
+	return nil
+}
+
+func (l *layeredConfig) Watch(notifier chan struct{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.notifiers = append(l.notifiers, notifier)
+}
+
+func (l *layeredConfig) subscribe(s *subscription)   { l.subs.subscribe(s) }
+func (l *layeredConfig) unsubscribe(s *subscription) { l.subs.unsubscribe(s) }
+
+// snapshotLayers 按优先级从高到低，Layer名称为来源的下标
+func (l *layeredConfig) snapshotLayers() []snapshotLayer {
+	layers := make([]snapshotLayer, 0, len(l.sources))
+	for i := len(l.sources) - 1; i >= 0; i-- {
+		for _, sub := range snapshotLayers(l.sources[i]) {
+			if sub.name != "" {
+				sub.name = strconv.Itoa(i) + "/" + sub.name
+			} else {
+				sub.name = strconv.Itoa(i)
+			}
+			layers = append(layers, sub)
+		}
+	}
+	return layers
+}
+
+func (l *layeredConfig) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.quit)
+	})
+	return nil
+}
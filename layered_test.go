@@ -0,0 +1,90 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayeredConfig(t *testing.T) {
+	ast := assert.New(t)
+
+	defaults := NewMapConfig(map[string]interface{}{
+		"db":      map[string]interface{}{"host": "localhost", "port": 3306, "pool": map[string]interface{}{"size": 10}},
+		"timeout": "1s",
+	})
+	remote := NewAsyncConfig(NewMockAsyncer(false), "remote.json", 0, false)
+	defer remote.Close()
+	ast.Nil(remote.Set(RootKey, map[string]interface{}{
+		"db": map[string]interface{}{"host": "db.example.com", "pool": map[string]interface{}{"idle": 2}},
+	}))
+	flags := NewMapConfig(map[string]interface{}{"timeout": "5s"})
+
+	cfg := NewLayeredConfig(defaults, remote, flags)
+	defer cfg.Close()
+
+	ast.Equal("db.example.com", cfg.String("db.host"))
+	ast.Equal(int64(3306), cfg.Int("db.port"))
+	ast.Equal(int64(10), cfg.Int("db.pool.size"))
+	ast.Equal(int64(2), cfg.Int("db.pool.idle"))
+	ast.Equal("5s", cfg.String("timeout"))
+	ast.Nil(cfg.Get("missing"))
+
+	origin, ok := cfg.Origin("db.host")
+	ast.True(ok)
+	ast.Equal("1", origin.Layer)
+	origin, _ = cfg.Origin("db.port")
+	ast.Equal("0", origin.Layer)
+	origin, _ = cfg.Origin("timeout")
+	ast.Equal(KeyOrigin{Layer: "2", Source: "map"}, origin)
+
+	// 任一来源变化时重新合并
+	notifier := make(chan struct{}, 1)
+	cfg.Watch(notifier)
+	changed := make(chan struct{}, 1)
+	cancel := cfg.OnChange(func() { changed <- struct{}{} })
+	defer cancel()
+
+	ast.Nil(defaults.Set("db.port", 3307))
+	select {
+	case <-notifier:
+	case <-time.After(time.Second):
+		ast.Fail("not notified")
+	}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		ast.Fail("OnChange not called")
+	}
+	ast.Equal(int64(3307), cfg.Int("db.port"))
+
+	// 被覆盖的值变化时合并结果不变，不通知
+	ast.Nil(defaults.Set("timeout", "2s"))
+	select {
+	case <-notifier:
+		ast.Fail("notified without change")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ast.Equal("5s", cfg.String("timeout"))
+
+	// Set写入优先级最高的来源
+	ast.Nil(cfg.Set("db.host", "override"))
+	ast.Equal("override", cfg.String("db.host"))
+	ast.Equal("override", flags.String("db.host"))
+
+	// 来源之间不共享修改
+	ast.Equal("localhost", defaults.String("db.host"))
+
+	ast.Nil(cfg.Close())
+	ast.Nil(cfg.Close())
+}
+
+func TestLayeredConfigEmpty(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewLayeredConfig()
+	defer cfg.Close()
+	ast.Equal(map[string]interface{}{}, cfg.Get(RootKey))
+	ast.Equal(ErrReadOnly, cfg.Set("a", 1))
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StartRetryInterval Start首次加载失败后重试的间隔
+var StartRetryInterval = time.Second
+
+var errEmptyContent = errors.New("empty content")
+
+// Lifecycle 可由 fx、oklog/run 等生命周期管理的组件
+//
+//	lc.Append(fx.Hook{OnStart: cfg.Start, OnStop: cfg.Stop})
+type Lifecycle interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+var _ Lifecycle = (*AsyncConfig)(nil)
+
+// LoadStatus 配置的加载状态
+type LoadStatus struct {
+	Loaded   bool      // 是否成功加载过
+	LoadedAt time.Time // 最近一次成功加载开始的时间（内容可能未变化）
+	Err      error     // 最近一次加载的错误，成功时为nil
+}
+
+// WithDeferredStart 创建时不加载也不监听变化，直到调用 AsyncConfig.Start
+//
+// 适用于需要确认首次加载成功后再启动，或后端延迟连接（见 LazyAsyncer）的场景
+func WithDeferredStart() AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.deferStart = true
+	}
+}
+
+// Status 返回加载状态
+func (c *AsyncConfig) Status() LoadStatus {
+	return c.Configer.(*asyncConfig).Status()
+}
+
+// Start 加载配置，失败时每隔 StartRetryInterval 重试，直到成功或ctx结束，
+// 首次加载成功后才开始监听变化。已加载成功时直接开始监听
+func (c *AsyncConfig) Start(ctx context.Context) error {
+	return c.Configer.(*asyncConfig).Start(ctx)
+}
+
+func (cfg *asyncConfig) Status() LoadStatus {
+	if s, ok := cfg.status.Load().(LoadStatus); ok {
+		return s
+	}
+	return LoadStatus{}
+}
+
+func (cfg *asyncConfig) setStatus(loadedAt time.Time, err error) {
+	cfg.Lock()
+	defer cfg.Unlock()
+
+	s := cfg.Status()
+	s.Err = err
+	if err == nil {
+		s.Loaded = true
+		s.LoadedAt = loadedAt
+	}
+	cfg.status.Store(s)
+}
+
+// Stop 停止监听及刷新，等待后台goroutine退出，直到ctx结束
+func (c *AsyncConfig) Stop(ctx context.Context) error {
+	return c.Configer.(*asyncConfig).Stop(ctx)
+}
+
+// Run 返回 oklog/run 的execute及interrupt函数：
+// execute 启动配置（见 Start）并阻塞直到interrupt被调用，interrupt 停止配置
+//
+//	var g run.Group
+//	g.Add(cfg.Run())
+func (c *AsyncConfig) Run() (execute func() error, interrupt func(error)) {
+	cfg := c.Configer.(*asyncConfig)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	execute = func() error {
+		if err := cfg.Start(ctx); err != nil {
+			if errors.Is(err, ErrClosed) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		<-cfg.quit
+		return nil
+	}
+	interrupt = func(error) {
+		cancel()
+		cfg.Close()
+	}
+
+	return execute, interrupt
+}
+
+func (cfg *asyncConfig) Stop(ctx context.Context) error {
+	cfg.Close()
+
+	done := make(chan struct{})
+	go func() {
+		cfg.bgWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// goBackground 启动后台goroutine，关闭后不再启动
+func (cfg *asyncConfig) goBackground(fn func()) {
+	cfg.bgMu.Lock()
+	defer cfg.bgMu.Unlock()
+
+	if cfg.closed() {
+		return
+	}
+
+	cfg.bgWg.Add(1)
+	atomic.AddInt64(&_resources.Goroutines, 1)
+	go func() {
+		defer cfg.bgWg.Done()
+		defer atomic.AddInt64(&_resources.Goroutines, -1)
+		fn()
+	}()
+}
+
+func (cfg *asyncConfig) Start(ctx context.Context) error {
+	if cfg.closed() {
+		return ErrClosed
+	}
+
+	for !cfg.Status().Loaded {
+		err := cfg.refresh()
+		if err == nil {
+			break
+		}
+		logger.Warnf("start async config[%s] err:%v", cfg.asyncKey, err)
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(err, "start async config[%s]: %v", cfg.asyncKey, ctx.Err())
+		case <-cfg.quit:
+			return ErrClosed
+		case <-time.After(StartRetryInterval):
+		}
+	}
+
+	cfg.startWatch()
+	return nil
+}
+
+// watching 是否已开始监听变化
+func (cfg *asyncConfig) watching() bool {
+	return atomic.LoadInt32(&cfg.watchStarted) == 1
+}
+
+// startWatch 监听后端的变化通知，只执行一次
+func (cfg *asyncConfig) startWatch() {
+	cfg.startOnce.Do(func() {
+		if cfg.frozen || cfg.noWatch {
+			return
+		}
+
+		notify := cfg.asyncer.Watch(cfg.asyncKey)
+		if notify == nil {
+			return
+		}
+
+		// 推送更新机制下可以不使用过期策略
+		// 但为了防止更新消息丢失导致的旧值一直得不到更新
+		// 设置一个兜底的过期时间
+		fallback := cfg.watchFallback
+		if fallback <= 0 {
+			fallback = 5 * time.Minute
+		}
+		atomic.StoreInt64(&cfg.cacheTime, int64(fallback))
+		atomic.StoreInt32(&cfg.watchStarted, 1)
+		cfg.goBackground(func() { cfg.watch(notify) })
+
+		for _, blob := range cfg.blobs {
+			if blob.BackendKey == "" {
+				continue
+			}
+			if notify := cfg.asyncer.Watch(blob.BackendKey); notify != nil {
+				cfg.goBackground(func() { cfg.watch(notify) })
+			}
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeferredStart(t *testing.T) {
+	ast := assert.New(t)
+
+	interval := StartRetryInterval
+	StartRetryInterval = time.Millisecond
+	defer func() { StartRetryInterval = interval }()
+	retry := LazyConnectRetryInterval
+	LazyConnectRetryInterval = 0
+	defer func() { LazyConnectRetryInterval = retry }()
+
+	mock := NewMockAsyncer(true)
+	connects := 0
+	asyncer := NewLazyAsyncer(func() (Asyncer, error) {
+		connects++
+		if connects < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return mock, nil
+	})
+
+	cfg := NewAsyncConfig(asyncer, "lazy.json", 0, false, WithDeferredStart())
+	ast.Equal(0, connects, "not connected before Start")
+	ast.False(cfg.Status().Loaded)
+	ast.Nil(asyncer.Connected())
+
+	// 连接失败
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	err := cfg.Start(ctx)
+	cancel()
+	ast.NotNil(err)
+	status := cfg.Status()
+	ast.False(status.Loaded)
+	ast.True(errors.Is(status.Err, ErrBackendUnavailable))
+
+	// 连接成功但内容为空时继续重试
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		mock.Set("lazy.json", []byte(`{"a": 1}`))
+	}()
+	ast.Nil(cfg.Start(context.Background()))
+	status = cfg.Status()
+	ast.True(status.Loaded)
+	ast.Nil(status.Err)
+	ast.False(status.LoadedAt.IsZero())
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.Equal(mock, asyncer.Connected())
+
+	// 启动后监听变化
+	mock.Set("lazy.json", []byte(`{"a": 2}`))
+	ast.Eventually(func() bool {
+		return cfg.Int("a") == 2
+	}, time.Second, time.Millisecond)
+
+	ast.Nil(cfg.Start(context.Background()), "idempotent")
+
+	ast.Nil(cfg.Close())
+	ast.Equal(ErrClosed, newClosedAsyncConfig().Start(context.Background()))
+}
+
+func newClosedAsyncConfig() *AsyncConfig {
+	cfg := NewAsyncConfig(NewMockAsyncer(false), "closed.json", 0, false, WithDeferredStart())
+	cfg.Close()
+	return cfg
+}
+
+func TestLoadStatus(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(false)
+	cfg := NewAsyncConfig(mock, "status.json", time.Millisecond, false)
+	ast.False(cfg.Status().Loaded)
+	ast.NotNil(cfg.Status().Err)
+
+	mock.Set("status.json", []byte(`{"a": 1}`))
+	time.Sleep(2 * time.Millisecond)
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.True(cfg.Status().Loaded)
+
+	mock.Set("status.json", []byte(`{"a": `))
+	time.Sleep(2 * time.Millisecond)
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.True(cfg.Status().Loaded)
+	ast.NotNil(cfg.Status().Err)
+}
+
+func TestStartStop(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(true)
+	mock.Set("stop.json", []byte(`{"a": 1}`))
+
+	var lc Lifecycle = NewAsyncConfig(mock, "stop.json", 0, false, WithDeferredStart())
+	ast.Nil(lc.Start(context.Background()))
+	cfg := lc.(*AsyncConfig)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ast.Nil(lc.Stop(ctx))
+
+	// 停止后不再更新
+	mock.Set("stop.json", []byte(`{"a": 2}`))
+	time.Sleep(2 * time.Millisecond)
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.Equal(ErrClosed, lc.Start(context.Background()))
+}
+
+func TestRun(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(true)
+	mock.Set("run.json", []byte(`{"a": 1}`))
+	cfg := NewAsyncConfig(mock, "run.json", 0, false, WithDeferredStart())
+
+	execute, interrupt := cfg.Run()
+	done := make(chan error, 1)
+	go func() {
+		done <- execute()
+	}()
+
+	ast.Eventually(func() bool {
+		return cfg.Status().Loaded
+	}, time.Second, time.Millisecond)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	interrupt(nil)
+	select {
+	case err := <-done:
+		ast.Nil(err)
+	case <-time.After(time.Second):
+		t.Fatal("execute not returned")
+	}
+
+	// 首次加载未成功时interrupt
+	cfg = NewAsyncConfig(NewMockAsyncer(false), "run_empty.json", 0, false, WithDeferredStart())
+	execute, interrupt = cfg.Run()
+	go func() {
+		done <- execute()
+	}()
+	interrupt(nil)
+	select {
+	case err := <-done:
+		ast.Nil(err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("execute not returned")
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LintKind 配置内容问题的类型
+type LintKind string
+
+const (
+	LintDuplicateKey LintKind = "duplicate_key" // 重复的key，或仅大小写不同的key
+	LintEmptyObject  LintKind = "empty_object"  // 空对象
+	LintNullLeaf     LintKind = "null_leaf"     // 值为null的叶子节点
+)
+
+// LintIssue 配置内容中可疑的写法，通常是上游编写配置时的错误
+type LintIssue struct {
+	Kind    LintKind
+	KeyPath string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	if i.Message == "" {
+		return fmt.Sprintf("%s: %s", i.Kind, i.KeyPath)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Kind, i.KeyPath, i.Message)
+}
+
+// WithLint 刷新时检查配置内容的问题并记录警告日志，见 Lint、AsyncConfig.LintIssues
+func WithLint() AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.lint = true
+	}
+}
+
+// LintIssues 最近一次变化的配置内容的问题，未开启 WithLint 时为nil
+func (c *AsyncConfig) LintIssues() []LintIssue {
+	issues, _ := c.Configer.(*asyncConfig).lintIssues.Load().([]LintIssue)
+	return issues
+}
+
+func (cfg *asyncConfig) lintContent(rawMessage []byte, val interface{}) {
+	if !cfg.lint {
+		return
+	}
+
+	issues := Lint(val)
+	// 解析后无法发现完全相同的重复key
+	if json.Valid(rawMessage) {
+		dups, _ := LintJSON(rawMessage)
+		issues = append(dups, issues...)
+	}
+
+	for _, issue := range issues {
+		logger.Warnf("lint async config[%s]: %s", cfg.asyncKey, issue)
+	}
+	cfg.lintIssues.Store(issues)
+}
+
+// Lint 检查已解析的配置树：大小写不同的同名key、空对象及null叶子节点，按keyPath排序
+func Lint(root interface{}) []LintIssue {
+	issues := make([]LintIssue, 0)
+	lintNode(RootKey, root, &issues)
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].KeyPath < issues[j].KeyPath
+	})
+	return issues
+}
+
+func lintNode(keyPath string, v interface{}, issues *[]LintIssue) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if len(vv) == 0 && keyPath != RootKey {
+			*issues = append(*issues, LintIssue{Kind: LintEmptyObject, KeyPath: keyPath})
+			return
+		}
+
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		folded := make(map[string]string, len(keys))
+		for _, k := range keys {
+			lower := strings.ToLower(k)
+			if other, ok := folded[lower]; ok {
+				*issues = append(*issues, LintIssue{
+					Kind:    LintDuplicateKey,
+					KeyPath: joinKeyPath(keyPath, k),
+					Message: fmt.Sprintf("collides with %q", other),
+				})
+			} else {
+				folded[lower] = k
+			}
+			lintNode(joinKeyPath(keyPath, k), vv[k], issues)
+		}
+	case []interface{}:
+		for i, item := range vv {
+			lintNode(joinKeyPath(keyPath, strconv.Itoa(i)), item, issues)
+		}
+	case nil:
+		if keyPath != RootKey {
+			*issues = append(*issues, LintIssue{Kind: LintNullLeaf, KeyPath: keyPath})
+		}
+	}
+}
+
+// LintJSON 检查JSON中完全相同的重复key（json.Unmarshal 只保留最后一个）
+func LintJSON(data []byte) ([]LintIssue, error) {
+	issues := make([]LintIssue, 0)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := lintJSONValue(dec, RootKey, &issues); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return issues, err
+	}
+	return issues, nil
+}
+
+func lintJSONValue(dec *json.Decoder, keyPath string, issues *[]LintIssue) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			sub := joinKeyPath(keyPath, key)
+			if seen[key] {
+				*issues = append(*issues, LintIssue{Kind: LintDuplicateKey, KeyPath: sub, Message: "duplicate key"})
+			}
+			seen[key] = true
+			if err := lintJSONValue(dec, sub, issues); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := lintJSONValue(dec, joinKeyPath(keyPath, strconv.Itoa(i)), issues); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	ast := assert.New(t)
+
+	issues := Lint(map[string]interface{}{
+		"db": map[string]interface{}{
+			"Host": "a",
+			"host": "b",
+			"opts": map[string]interface{}{},
+			"pass": nil,
+		},
+		"list": []interface{}{nil, map[string]interface{}{}},
+		"ok":   1,
+	})
+	ast.Equal([]LintIssue{
+		{Kind: LintDuplicateKey, KeyPath: "db.host", Message: `collides with "Host"`},
+		{Kind: LintEmptyObject, KeyPath: "db.opts"},
+		{Kind: LintNullLeaf, KeyPath: "db.pass"},
+		{Kind: LintNullLeaf, KeyPath: "list.0"},
+		{Kind: LintEmptyObject, KeyPath: "list.1"},
+	}, issues)
+	ast.Equal(`duplicate_key: db.host: collides with "Host"`, issues[0].String())
+	ast.Equal("empty_object: db.opts", issues[1].String())
+	ast.Empty(Lint(map[string]interface{}{}))
+
+	issues, err := LintJSON([]byte(`{"a": 1, "b": {"c": [1, {"d": 1, "d": 2}]}, "a": 2}`))
+	ast.Nil(err)
+	ast.Equal([]LintIssue{
+		{Kind: LintDuplicateKey, KeyPath: "b.c.1.d", Message: "duplicate key"},
+		{Kind: LintDuplicateKey, KeyPath: "a", Message: "duplicate key"},
+	}, issues)
+	_, err = LintJSON([]byte(`{"a": `))
+	ast.NotNil(err)
+
+	asyncer := rawAsyncer{NewMockAsyncer(false)}
+	asyncer.data.Store("lint.json", []byte(`{"a": 1, "a": 2, "b": null}`))
+	cfg := NewAsyncConfig(asyncer, "lint.json", time.Hour, false, WithLint())
+	defer cfg.Close()
+	ast.EqualValues(2, cfg.Int("a"))
+	ast.Equal([]LintIssue{
+		{Kind: LintDuplicateKey, KeyPath: "a", Message: "duplicate key"},
+		{Kind: LintNullLeaf, KeyPath: "b"},
+	}, cfg.LintIssues())
+
+	ast.Nil(NewAsyncConfig(asyncer, "lint.json", time.Hour, false).LintIssues())
+}
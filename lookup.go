@@ -0,0 +1,208 @@
+package config
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/kot-w/goutils/itype"
+	"github.com/kot-w/goutils/object"
+	"github.com/pkg/errors"
+)
+
+// Lookuper 可区分配置项不存在与值为null的Configer
+//
+// 未实现该接口的Configer，Get返回nil时均视为不存在
+type Lookuper interface {
+	Lookup(keyPath string) (value interface{}, found bool)
+}
+
+func lookupValue(root interface{}, keyPath string) (interface{}, bool) {
+	if keyPath == RootKey {
+		return root, root != nil
+	}
+	return object.GetValue(root, keyPath)
+}
+
+// lookuper 嵌入了ConfigHelper的配置，如 *MapConfig、*AsyncConfig
+type lookuper interface {
+	lookup(keyPath string) (interface{}, bool)
+}
+
+func lookupConfiger(c Configer, keyPath string) (interface{}, bool) {
+	switch l := c.(type) {
+	case Lookuper:
+		return l.Lookup(keyPath)
+	case lookuper:
+		return l.lookup(keyPath)
+	}
+
+	val := c.Get(keyPath)
+	return val, val != nil
+}
+
+func (h *ConfigHelper) lookup(keyPath string) (interface{}, bool) {
+	return lookupConfiger(h.Configer, keyPath)
+}
+
+// Lookup 配置项不存在时返回 ErrKeyNotFound，值为null时返回 (nil, nil)
+func (h *ConfigHelper) Lookup(keyPath string) (interface{}, error) {
+	val, found := h.lookup(keyPath)
+	if !found {
+		return nil, keyNotFound(keyPath)
+	}
+	return val, nil
+}
+
+// lookupNotNull 值为null时返回 ErrNullValue
+func (h *ConfigHelper) lookupNotNull(keyPath string) (interface{}, error) {
+	val, err := h.Lookup(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		return nil, errors.WithStack(&KeyError{KeyPath: keyPath, Kind: ErrNullValue})
+	}
+	return val, nil
+}
+
+// LookupString 值必须为字符串，不做类型转换
+func (h *ConfigHelper) LookupString(keyPath string) (string, error) {
+	val, err := h.lookupNotNull(keyPath)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return "", typeMismatch(keyPath, errors.Errorf("%T is not a string", val))
+	}
+	return s, nil
+}
+
+// LookupFloat 值必须为数字
+func (h *ConfigHelper) LookupFloat(keyPath string) (float64, error) {
+	val, err := h.lookupNotNull(keyPath)
+	if err != nil {
+		return 0, err
+	}
+	return lookupFloat(keyPath, val)
+}
+
+// LookupInt 值必须为整数，小数返回 ErrTypeMismatch
+func (h *ConfigHelper) LookupInt(keyPath string) (int64, error) {
+	val, err := h.lookupNotNull(keyPath)
+	if err != nil {
+		return 0, err
+	}
+	return lookupInt(keyPath, val)
+}
+
+// LookupUint 值必须为非负整数
+func (h *ConfigHelper) LookupUint(keyPath string) (uint64, error) {
+	val, err := h.lookupNotNull(keyPath)
+	if err != nil {
+		return 0, err
+	}
+	if v, ok := val.(uint64); ok {
+		return v, nil
+	}
+
+	i, err := lookupInt(keyPath, val)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, typeMismatch(keyPath, errors.Errorf("%d is negative", i))
+	}
+	return uint64(i), nil
+}
+
+// LookupBool 值必须为bool
+func (h *ConfigHelper) LookupBool(keyPath string) (bool, error) {
+	val, err := h.lookupNotNull(keyPath)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := val.(bool)
+	if !ok {
+		return false, typeMismatch(keyPath, errors.Errorf("%T is not a bool", val))
+	}
+	return b, nil
+}
+
+func lookupFloat(keyPath string, val interface{}) (float64, error) {
+	if n, ok := val.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return 0, typeMismatch(keyPath, err)
+		}
+		return f, nil
+	}
+	if itype.GetType(val) != itype.NUMBER {
+		return 0, typeMismatch(keyPath, errors.Errorf("%T is not a number", val))
+	}
+	return itype.Float(val), nil
+}
+
+func lookupInt(keyPath string, val interface{}) (int64, error) {
+	switch v := val.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, typeMismatch(keyPath, errors.Errorf("%d overflows int64", v))
+		}
+		return int64(v), nil
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return 0, typeMismatch(keyPath, err)
+		}
+		return i, nil
+	}
+
+	f, err := lookupFloat(keyPath, val)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, typeMismatch(keyPath, errors.Errorf("%v is not an integer", val))
+	}
+	return itype.Int(val), nil
+}
+
+func (m *mapConfig) Lookup(keyPath string) (interface{}, bool) {
+	return lookupValue(m.m.Load(), keyPath)
+}
+
+// Lookup2 依次从指定的Layer中查询，见 Get2
+func (cfg *defaultConfig) Lookup2(keyPath string, layerNames ...string) (val interface{}, found bool) {
+	if len(layerNames) == 0 {
+		layerNames = cfg.defaultLayerNames.Load().([]string)
+	}
+
+	for _, layerName := range layerNames {
+		layer, ok := cfg.layers.Load(layerName)
+		if !ok {
+			continue
+		}
+
+		v, f := lookupConfiger(layer.(Configer), keyPath)
+		// 与Get2一致，值为null时继续查询后面的Layer
+		if v != nil {
+			return v, true
+		}
+		found = found || f
+	}
+
+	return nil, found
+}
+
+func (c *defaultConfiger) Lookup(keyPath string) (interface{}, bool) {
+	return c.cfg.Lookup2(keyPath)
+}
+
+func (p *layerConfigProxy) Lookup(keyPath string) (interface{}, bool) {
+	return p.cfg.Lookup2(keyPath, p.layerNames...)
+}
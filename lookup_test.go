@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"name":   "foo",
+		"null":   nil,
+		"port":   float64(3306),
+		"ratio":  0.5,
+		"neg":    -1,
+		"big":    uint64(1 << 63),
+		"num":    json.Number("42"),
+		"on":     true,
+		"onStr":  "true",
+		"nested": map[string]interface{}{"a": nil},
+	})
+
+	v, err := cfg.Lookup("name")
+	ast.Nil(err)
+	ast.Equal("foo", v)
+
+	// null与不存在
+	v, err = cfg.Lookup("null")
+	ast.Nil(err)
+	ast.Nil(v)
+	_, err = cfg.Lookup("not_exist")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+	_, err = cfg.Lookup("nested.a.b")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+
+	_, err = cfg.LookupString("null")
+	ast.True(errors.Is(err, ErrNullValue))
+	ast.False(errors.Is(err, ErrKeyNotFound))
+	_, err = cfg.LookupString("not_exist")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+
+	s, err := cfg.LookupString("name")
+	ast.Nil(err)
+	ast.Equal("foo", s)
+	_, err = cfg.LookupString("port")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+
+	i, err := cfg.LookupInt("port")
+	ast.Nil(err)
+	ast.EqualValues(3306, i)
+	i, err = cfg.LookupInt("num")
+	ast.Nil(err)
+	ast.EqualValues(42, i)
+	_, err = cfg.LookupInt("ratio")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+	_, err = cfg.LookupInt("big")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+	_, err = cfg.LookupInt("name")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+
+	u, err := cfg.LookupUint("big")
+	ast.Nil(err)
+	ast.EqualValues(uint64(1<<63), u)
+	_, err = cfg.LookupUint("neg")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+
+	f, err := cfg.LookupFloat("ratio")
+	ast.Nil(err)
+	ast.Equal(0.5, f)
+
+	b, err := cfg.LookupBool("on")
+	ast.Nil(err)
+	ast.True(b)
+	_, err = cfg.LookupBool("onStr")
+	ast.True(errors.Is(err, ErrTypeMismatch))
+
+	// async config
+	asyncer := NewMockAsyncer(false)
+	asyncer.data.Store("lookup.json", []byte(`{"a": null}`))
+	acfg := NewAsyncConfig(asyncer, "lookup.json", time.Hour, false)
+	v, err = acfg.Lookup("a")
+	ast.Nil(err)
+	ast.Nil(v)
+	_, err = acfg.Lookup("b")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+}
+
+func TestLookupLayers(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := newConfig()
+	cfg.AddLayer("l1", NewMapConfig(map[string]interface{}{"a": nil, "c": nil}))
+	cfg.AddLayer("l2", NewMapConfig(map[string]interface{}{"a": 1}))
+
+	v, err := cfg.Layer("l1", "l2").Lookup("a")
+	ast.Nil(err)
+	ast.EqualValues(1, v)
+
+	v, err = cfg.Layer("l1", "l2").Lookup("c")
+	ast.Nil(err)
+	ast.Nil(v)
+
+	_, err = cfg.Layer("l1", "l2").Lookup("d")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+}
@@ -43,7 +43,8 @@ type mapConfig struct {
 	sync.Mutex
 	syncMode  bool
 	notifiers []chan struct{}
-	m         atomic.Value //map[string]interface{}
+	subs      subscriptions // 见 OnChange
+	m         atomic.Value  //map[string]interface{}
 }
 
 func (m *mapConfig) Get(keyPath string) interface{} {
@@ -77,9 +78,14 @@ func (m *mapConfig) Set(keyPath string, value interface{}) error {
 
 	if keyPath == RootKey {
 		if vm, ok := value.(map[string]interface{}); ok {
+			conflicts := mergeConflicts(newMap, vm)
+			reportConflicts(conflicts)
+			if err := conflictsError(conflicts); err != nil {
+				return err
+			}
 			mergeMap(newMap, vm)
 		} else {
-			return errors.Errorf("merge map error: value is not map[string]interface{}:%v", value)
+			return errors.Wrap(typeMismatch(RootKey, errors.Errorf("%T is not a map", value)), "merge map error")
 		}
 	} else {
 		if err := setMapValue(newMap, keyPath, value); err != nil {
@@ -103,6 +109,15 @@ func (m *mapConfig) notify() {
 		default:
 		}
 	}
+	m.subs.dispatch()
+}
+
+func (m *mapConfig) subscribe(s *subscription) {
+	m.subs.subscribe(s)
+}
+
+func (m *mapConfig) unsubscribe(s *subscription) {
+	m.subs.unsubscribe(s)
 }
 
 func (m *mapConfig) Watch(notifier chan struct{}) {
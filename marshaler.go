@@ -2,6 +2,8 @@ package config
 
 import (
 	"encoding/json"
+	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,15 +14,57 @@ type ContentType int
 const (
 	T_JSON ContentType = iota
 	T_YAML
+	T_PROPERTIES
+	T_DOTENV
+	T_HCL
+	T_PROTOBUF // 需要注册消息类型，见 RegisterProtoMessage
+	T_XML
+	T_TOML
+	T_INI
 )
 
 var (
 	typeMarshalers = map[ContentType]Marshaler{
-		T_JSON: JSONMarshaler{},
-		T_YAML: YAMLMarshaler{},
+		T_JSON:       JSONMarshaler{},
+		T_YAML:       YAMLMarshaler{},
+		T_PROPERTIES: PropertiesMarshaler{},
+		T_DOTENV:     DotenvMarshaler{},
+		T_HCL:        HCLMarshaler{},
+		T_XML:        XMLMarshaler{},
+		T_TOML:       TOMLMarshaler{},
+		T_INI:        INIMarshaler{},
+	}
+
+	extContentTypes = map[string]ContentType{
+		".json":       T_JSON,
+		".yml":        T_YAML,
+		".yaml":       T_YAML,
+		".properties": T_PROPERTIES,
+		".env":        T_DOTENV,
+		".hcl":        T_HCL,
+		".tf":         T_HCL,
+		".tfvars":     T_HCL,
+		".nomad":      T_HCL,
+		".xml":        T_XML,
+		".toml":       T_TOML,
+		".ini":        T_INI,
 	}
 )
 
+// ContentTypeByExt 根据文件名（或key）后缀判断内容类型，未知后缀默认为JSON
+func ContentTypeByExt(name string) ContentType {
+	if t, ok := extContentTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return t
+	}
+
+	if strings.HasPrefix(filepath.Base(name), ".env") {
+		// .env.local, .env.production ...
+		return T_DOTENV
+	}
+
+	return T_JSON
+}
+
 type Marshaler interface {
 	Marshal(interface{}) ([]byte, error)
 	Unmarshal([]byte, interface{}) error
@@ -47,3 +91,23 @@ func (m YAMLMarshaler) Marshal(v interface{}) ([]byte, error) {
 func (m YAMLMarshaler) Unmarshal(data []byte, v interface{}) error {
 	return yaml.Unmarshal(data, v)
 }
+
+// assignValue 将解析出的通用结构赋值给v
+// v 为 *interface{} 或 *map[string]interface{} 时直接赋值，否则经由JSON转换
+func assignValue(val map[string]interface{}, v interface{}) error {
+	switch p := v.(type) {
+	case *interface{}:
+		*p = val
+		return nil
+	case *map[string]interface{}:
+		*p = val
+		return nil
+	}
+
+	bs, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bs, v)
+}
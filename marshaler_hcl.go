@@ -0,0 +1,62 @@
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/hcl"
+)
+
+// HCLMarshaler HashiCorp HCL 格式
+//
+// HCL的block会被解析为map，同名block合并为同一个map：
+//
+//	service "web" {
+//	  port = 80
+//	}
+//
+// 等价于 {"service": {"web": {"port": 80}}}
+//
+// HCL兼容JSON格式，Marshal 输出JSON
+type HCLMarshaler struct{}
+
+func (m HCLMarshaler) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (m HCLMarshaler) Unmarshal(data []byte, v interface{}) error {
+	var raw interface{}
+	if err := hcl.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	ret, _ := normalizeHCL(raw).(map[string]interface{})
+	if ret == nil {
+		ret = make(map[string]interface{})
+	}
+
+	return assignValue(ret, v)
+}
+
+// normalizeHCL 将hcl解析出的 []map[string]interface{} (block) 合并为 map[string]interface{}
+func normalizeHCL(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case []map[string]interface{}:
+		ret := make(map[string]interface{})
+		for _, item := range vv {
+			mergeMap(ret, normalizeHCL(item).(map[string]interface{}))
+		}
+		return ret
+	case map[string]interface{}:
+		for k, item := range vv {
+			vv[k] = normalizeHCL(item)
+		}
+		return vv
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = normalizeHCL(item)
+		}
+		return vv
+	default:
+		return v
+	}
+}
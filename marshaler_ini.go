@@ -0,0 +1,120 @@
+package config
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// INIMarshaler INI 格式
+//
+// section名称为顶层的key，section及key中的"."展开为嵌套的map，所有值均为字符串：
+//
+//	; comment
+//	name = app
+//
+//	[db]
+//	host = example.com
+//	replica.host = "replica.example.com"
+//
+// 等价于 {"name": "app", "db": {"host": "example.com", "replica": {"host": "replica.example.com"}}}
+type INIMarshaler struct{}
+
+func (m INIMarshaler) Marshal(v interface{}) ([]byte, error) {
+	root, ok := v.(map[string]interface{})
+	if !ok && v != nil {
+		return nil, errors.Errorf("ini requires a map, got %T", v)
+	}
+
+	var buf bytes.Buffer
+	sections := make([]string, 0)
+	globals := make(map[string]interface{})
+	for k, sub := range root {
+		if _, ok := sub.(map[string]interface{}); ok {
+			sections = append(sections, k)
+		} else {
+			globals[k] = sub
+		}
+	}
+	sort.Strings(sections)
+
+	if err := writeINIPairs(&buf, globals); err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("[" + section + "]\n")
+		if err := writeINIPairs(&buf, root[section]); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeINIPairs(buf *bytes.Buffer, v interface{}) error {
+	pairs, err := flattenValue(v)
+	if err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		value := p[1]
+		if value != strings.TrimSpace(value) || strings.ContainsAny(value, ";#\"\n") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(p[0] + " = " + value + "\n")
+	}
+	return nil
+}
+
+func (m INIMarshaler) Unmarshal(data []byte, v interface{}) error {
+	ret := make(map[string]interface{})
+
+	section := ""
+	for lineNo, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return errors.Errorf("ini line %d: missing ']'", lineNo+1)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == "" {
+				return errors.Errorf("ini line %d: empty section", lineNo+1)
+			}
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			return errors.Errorf("ini line %d: missing '='", lineNo+1)
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			if value[0] == '"' {
+				if s, err := strconv.Unquote(value); err == nil {
+					value = s
+				} else {
+					value = value[1 : len(value)-1]
+				}
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		}
+
+		if err := expandKeyPath(ret, joinKeyPath(section, key), value); err != nil {
+			return errors.Wrapf(err, "ini line %d", lineNo+1)
+		}
+	}
+
+	return assignValue(ret, v)
+}
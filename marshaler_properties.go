@@ -0,0 +1,410 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PropertiesMarshaler java .properties 格式
+//
+// 扁平的 key=value 结构，key按"."展开为嵌套的map，所有值均为字符串：
+//
+//	db.host=example.com
+//	db.port=3306
+//
+// 等价于 {"db": {"host": "example.com", "port": "3306"}}
+type PropertiesMarshaler struct{}
+
+func (m PropertiesMarshaler) Marshal(v interface{}) ([]byte, error) {
+	pairs, err := flattenValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, p := range pairs {
+		buf.WriteString(escapeProperty(p[0], true))
+		buf.WriteByte('=')
+		buf.WriteString(escapeProperty(p[1], false))
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m PropertiesMarshaler) Unmarshal(data []byte, v interface{}) error {
+	ret := make(map[string]interface{})
+
+	for _, line := range propertiesLines(data) {
+		key, value := splitProperty(line)
+		if err := expandKeyPath(ret, key, value); err != nil {
+			return err
+		}
+	}
+
+	return assignValue(ret, v)
+}
+
+// DotenvMarshaler .env 格式
+//
+//	# comment
+//	export DB_HOST=example.com
+//	DB_PASSWORD="secret # not comment"
+//
+// 与 PropertiesMarshaler 相同，key中的"."会被展开为嵌套的map
+type DotenvMarshaler struct{}
+
+func (m DotenvMarshaler) Marshal(v interface{}) ([]byte, error) {
+	pairs, err := flattenValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, p := range pairs {
+		buf.WriteString(p[0])
+		buf.WriteByte('=')
+		buf.WriteString(quoteDotenv(p[1]))
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m DotenvMarshaler) Unmarshal(data []byte, v interface{}) error {
+	ret := make(map[string]interface{})
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			return errors.Errorf("dotenv line %d: missing '='", lineNo)
+		}
+
+		key := strings.TrimSpace(line[:i])
+		value, err := parseDotenvValue(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return errors.Wrapf(err, "dotenv line %d", lineNo)
+		}
+
+		if err := expandKeyPath(ret, key, value); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return assignValue(ret, v)
+}
+
+// expandKeyPath 将 a.b.c=value 展开设置到嵌套的map中
+func expandKeyPath(m map[string]interface{}, keyPath string, value string) error {
+	if keyPath == "" {
+		return errEmptyKeyPath
+	}
+
+	keys := strings.Split(keyPath, ".")
+	node := m
+	for i, key := range keys[:len(keys)-1] {
+		child, ok := node[key]
+		if !ok {
+			sub := make(map[string]interface{})
+			node[key] = sub
+			node = sub
+			continue
+		}
+		sub, ok := child.(map[string]interface{})
+		if !ok {
+			return errors.Errorf("key[%s] conflicts with value of key[%s]", keyPath, strings.Join(keys[:i+1], "."))
+		}
+		node = sub
+	}
+
+	lastKey := keys[len(keys)-1]
+	if _, ok := node[lastKey].(map[string]interface{}); ok {
+		return errors.Errorf("key[%s] conflicts with its sub keys", keyPath)
+	}
+	node[lastKey] = value
+
+	return nil
+}
+
+// flattenValue 将嵌套结构展开为按key排序的 [key, value] 列表
+func flattenValue(v interface{}) ([][2]string, error) {
+	pairs := make([][2]string, 0)
+
+	var walk func(prefix string, v interface{}) error
+	walk = func(prefix string, v interface{}) error {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			for k, sub := range vv {
+				if err := walk(joinKeyPath(prefix, k), sub); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for i, sub := range vv {
+				if err := walk(joinKeyPath(prefix, strconv.Itoa(i)), sub); err != nil {
+					return err
+				}
+			}
+		default:
+			if prefix == "" {
+				return errors.Errorf("flat format requires a map, got %T", v)
+			}
+			pairs = append(pairs, [2]string{prefix, flatString(vv)})
+		}
+		return nil
+	}
+
+	if v == nil {
+		return pairs, nil
+	}
+
+	if err := walk("", v); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0] < pairs[j][0]
+	})
+
+	return pairs, nil
+}
+
+func joinKeyPath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func flatString(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case []byte:
+		return string(vv)
+	case bool:
+		return strconv.FormatBool(vv)
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case json.Number:
+		return vv.String()
+	default:
+		return fmt.Sprint(vv)
+	}
+}
+
+// propertiesLines 返回去除注释、合并续行后的逻辑行
+func propertiesLines(data []byte) []string {
+	lines := make([]string, 0)
+	var cur strings.Builder
+	continued := false
+
+	for _, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimRight(raw, "\r")
+		line = strings.TrimLeft(line, " \t\f")
+
+		if !continued && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		// 奇数个结尾的反斜杠表示续行
+		n := 0
+		for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+			n++
+		}
+		continued = n%2 == 1
+		if continued {
+			line = line[:len(line)-1]
+		}
+
+		cur.WriteString(line)
+		if !continued {
+			lines = append(lines, cur.String())
+			cur.Reset()
+		}
+	}
+
+	if cur.Len() > 0 {
+		lines = append(lines, cur.String())
+	}
+
+	return lines
+}
+
+func splitProperty(line string) (key string, value string) {
+	i := 0
+	for ; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+	}
+
+	if i > len(line) {
+		i = len(line)
+	}
+
+	key = line[:i]
+	rest := strings.TrimLeft(line[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	return unescapeProperty(key), unescapeProperty(rest)
+}
+
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+func escapeProperty(s string, isKey bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			if isKey || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		case ' ':
+			if isKey || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func parseDotenvValue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	switch s[0] {
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated single quote")
+		}
+		return s[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			c := s[i]
+			if c == '"' {
+				return b.String(), nil
+			}
+			if c == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'r':
+					b.WriteByte('\r')
+				default:
+					b.WriteByte(s[i])
+				}
+				continue
+			}
+			b.WriteByte(c)
+		}
+		return "", errors.New("unterminated double quote")
+	}
+
+	// 未加引号的值，" #" 之后为行内注释
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+
+	return strings.TrimSpace(s), nil
+}
+
+func quoteDotenv(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\r#\"'\\=") {
+		return s
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	// asyncKey => *ProtoMarshaler
+	_protoMarshalers sync.Map
+)
+
+// RegisterProtoMessage 注册asyncKey对应内容的protobuf消息类型
+//
+// 通过 Load 等方式创建的AsyncConfig会使用注册的消息类型解析内容
+//
+//	config.RegisterProtoMessage("app/config", &pb.AppConfig{})
+func RegisterProtoMessage(asyncKey string, msg proto.Message) {
+	_protoMarshalers.Store(asyncKey, NewProtoMarshaler(msg))
+}
+
+func getProtoMarshaler(asyncKey string) *ProtoMarshaler {
+	m, ok := _protoMarshalers.Load(asyncKey)
+	if !ok {
+		return nil
+	}
+
+	return m.(*ProtoMarshaler)
+}
+
+// ProtoMarshaler protobuf 二进制格式
+//
+// 内容按注册的消息类型解析，并转换为以proto字段名为key的map，
+// 因此可以使用proto字段路径访问配置：
+//
+//	cfg.String("db.host") // AppConfig.db.host
+//
+// 注意：与protojson一致，int64/uint64类型的字段值为字符串
+type ProtoMarshaler struct {
+	desc protoreflect.MessageDescriptor
+}
+
+func NewProtoMarshaler(msg proto.Message) *ProtoMarshaler {
+	return &ProtoMarshaler{
+		desc: msg.ProtoReflect().Descriptor(),
+	}
+}
+
+func (m *ProtoMarshaler) Marshal(v interface{}) ([]byte, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(m.desc)
+	if err := protojson.Unmarshal(bs, msg); err != nil {
+		return nil, errors.Wrapf(err, "convert to %s", m.desc.FullName())
+	}
+
+	return proto.Marshal(msg)
+}
+
+func (m *ProtoMarshaler) Unmarshal(data []byte, v interface{}) error {
+	msg := dynamicpb.NewMessage(m.desc)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return errors.Wrapf(err, "unmarshal %s", m.desc.FullName())
+	}
+
+	bs, err := protojson.MarshalOptions{
+		UseProtoNames:   true,
+		EmitUnpopulated: true,
+	}.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bs, v)
+}
+
+// BindProto 将指定节点的配置解析到生成的protobuf消息中
+//
+//	var dbConf pb.DBConfig
+//	err := config.BindProto(cfg, "db", &dbConf)
+func BindProto(cfg Configer, keyPath string, msg proto.Message) error {
+	val := cfg.Get(keyPath)
+	if val == nil {
+		return keyNotFound(keyPath)
+	}
+
+	bs, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	return protojson.UnmarshalOptions{
+		DiscardUnknown: true,
+	}.Unmarshal(bs, msg)
+}
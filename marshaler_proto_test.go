@@ -0,0 +1,72 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestProtoMarshaler(t *testing.T) {
+	ast := assert.New(t)
+
+	origin := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("user_id"),
+		Number:   proto.Int32(3),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		JsonName: proto.String("userId"),
+		Options: &descriptorpb.FieldOptions{
+			Deprecated: proto.Bool(true),
+		},
+	}
+	data, err := proto.Marshal(origin)
+	ast.Nil(err)
+
+	m := NewProtoMarshaler(&descriptorpb.FieldDescriptorProto{})
+	var v interface{}
+	ast.Nil(m.Unmarshal(data, &v))
+	ast.Equal("user_id", NewMapConfig(v.(map[string]interface{})).String("name"))
+
+	bs, err := m.Marshal(v)
+	ast.Nil(err)
+	decoded := &descriptorpb.FieldDescriptorProto{}
+	ast.Nil(proto.Unmarshal(bs, decoded))
+	ast.True(proto.Equal(origin, decoded))
+
+	// async config
+	mock := NewMockAsyncer(false)
+	key := "proto_key"
+	mock.data.Store(key, data)
+	cfg := newProtoTestConfig(mock, key)
+	ast.Equal("user_id", cfg.String("name"))
+	ast.Equal(int64(3), cfg.Int("number"))
+	ast.Equal("LABEL_OPTIONAL", cfg.String("label"))
+	ast.True(cfg.Bool("options.deprecated"))
+
+	opts := &descriptorpb.FieldOptions{}
+	ast.Nil(BindProto(cfg, "options", opts))
+	ast.True(opts.GetDeprecated())
+	ast.NotNil(BindProto(cfg, "not_exist", opts))
+
+	cfg = NewAsyncConfig(rawAsyncer{mock}, key, time.Minute, false, WithProtoMessage(&descriptorpb.FieldDescriptorProto{}))
+	ast.Equal("userId", cfg.String("json_name"))
+}
+
+// rawAsyncer 返回原始内容的MockAsyncer
+type rawAsyncer struct {
+	*MockAsyncer
+}
+
+func (a rawAsyncer) Get(key string) []byte {
+	v, _ := a.data.Load(key)
+	bs, _ := v.([]byte)
+	return bs
+}
+
+func newProtoTestConfig(mock *MockAsyncer, key string) *AsyncConfig {
+	RegisterProtoMessage(key, &descriptorpb.FieldDescriptorProto{})
+	defer _protoMarshalers.Delete(key)
+	return NewAsyncConfig(rawAsyncer{mock}, key, time.Minute, false)
+}
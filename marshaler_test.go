@@ -0,0 +1,334 @@
+package config
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentTypeByExt(t *testing.T) {
+	ast := assert.New(t)
+	ast.Equal(T_JSON, ContentTypeByExt("conf.json"))
+	ast.Equal(T_JSON, ContentTypeByExt("default_config"))
+	ast.Equal(T_YAML, ContentTypeByExt("conf.yml"))
+	ast.Equal(T_YAML, ContentTypeByExt("/path/to/conf.YAML"))
+	ast.Equal(T_PROPERTIES, ContentTypeByExt("app.properties"))
+	ast.Equal(T_DOTENV, ContentTypeByExt(".env"))
+	ast.Equal(T_DOTENV, ContentTypeByExt("/app/.env.local"))
+	ast.Equal(T_DOTENV, ContentTypeByExt("prod.env"))
+	ast.Equal(T_JSON, ContentTypeByExt(".env.json"))
+	ast.Equal(T_YAML, ContentTypeByExt("/app/.env.yaml"))
+}
+
+func TestPropertiesMarshaler(t *testing.T) {
+	ast := assert.New(t)
+	m := PropertiesMarshaler{}
+
+	var v interface{}
+	err := m.Unmarshal([]byte(`
+# comment
+! another comment
+db.host = example.com
+db.port:3306
+db.name   test
+message=hello \
+    world
+path=c\:\\data\\conf
+key\ with\ space=v
+unicode=\u4e2d\u6587
+empty=
+`), &v)
+	ast.Nil(err)
+
+	cfg := NewMapConfig(v.(map[string]interface{}))
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Equal(int64(3306), cfg.Int("db.port"))
+	ast.Equal("test", cfg.String("db.name"))
+	ast.Equal("hello world", cfg.String("message"))
+	ast.Equal(`c:\data\conf`, cfg.String("path"))
+	ast.Equal("v", cfg.String("key with space"))
+	ast.Equal("中文", cfg.String("unicode"))
+	ast.Equal("", cfg.Get("empty"))
+
+	bs, err := m.Marshal(v)
+	ast.Nil(err)
+	var v2 interface{}
+	ast.Nil(m.Unmarshal(bs, &v2))
+	ast.Equal(v, v2)
+
+	// conflicts
+	ast.NotNil(m.Unmarshal([]byte("a=1\na.b=2"), &v))
+	ast.NotNil(m.Unmarshal([]byte("a.b=2\na=1"), &v))
+}
+
+func TestDotenvMarshaler(t *testing.T) {
+	ast := assert.New(t)
+	m := DotenvMarshaler{}
+
+	var v map[string]interface{}
+	err := m.Unmarshal([]byte(`
+# comment
+export DB_HOST=example.com
+DB_PORT=3306 # port
+DB_PASSWORD="p@ss # word\n"
+RAW='single $quoted'
+redis.addr=127.0.0.1:6379
+`), &v)
+	ast.Nil(err)
+
+	cfg := NewMapConfig(v)
+	ast.Equal("example.com", cfg.String("DB_HOST"))
+	ast.Equal(int64(3306), cfg.Int("DB_PORT"))
+	ast.Equal("p@ss # word\n", cfg.String("DB_PASSWORD"))
+	ast.Equal("single $quoted", cfg.String("RAW"))
+	ast.Equal("127.0.0.1:6379", cfg.String("redis.addr"))
+
+	bs, err := m.Marshal(v)
+	ast.Nil(err)
+	var v2 map[string]interface{}
+	ast.Nil(m.Unmarshal(bs, &v2))
+	ast.Equal(v, v2)
+
+	ast.NotNil(m.Unmarshal([]byte("NO_EQUAL_SIGN"), &v))
+	ast.NotNil(m.Unmarshal([]byte(`A="unterminated`), &v))
+}
+
+func TestHCLMarshaler(t *testing.T) {
+	ast := assert.New(t)
+	m := HCLMarshaler{}
+
+	var v interface{}
+	err := m.Unmarshal([]byte(`
+# comment
+name = "svc"
+port = 8080
+ratio = 0.5
+tags = ["a", "b"]
+
+db {
+  host = "example.com"
+}
+
+service "web" {
+  port = 80
+}
+
+service "api" {
+  port = 81
+}
+`), &v)
+	ast.Nil(err)
+
+	cfg := NewMapConfig(v.(map[string]interface{}))
+	ast.Equal("svc", cfg.String("name"))
+	ast.Equal(int64(8080), cfg.Int("port"))
+	ast.InDelta(0.5, cfg.Float("ratio"), 0.000001)
+	ast.Equal("b", cfg.String("tags.1"))
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Equal(int64(80), cfg.Int("service.web.port"))
+	ast.Equal(int64(81), cfg.Int("service.api.port"))
+
+	bs, err := m.Marshal(v)
+	ast.Nil(err)
+	var v2 interface{}
+	ast.Nil(m.Unmarshal(bs, &v2))
+	ast.Equal("example.com", NewMapConfig(v2.(map[string]interface{})).String("db.host"))
+	ast.Equal(int64(81), NewMapConfig(v2.(map[string]interface{})).Int("service.api.port"))
+
+	ast.Equal(T_HCL, ContentTypeByExt("main.tf"))
+	ast.Equal(T_HCL, ContentTypeByExt("prod.tfvars"))
+	ast.NotNil(m.Unmarshal([]byte(`db {`), &v))
+
+	// key没有后缀时指定codec
+	asyncer := &rawAsyncer{NewMockAsyncer(false)}
+	asyncer.data.Store("service/web", []byte("service \"web\" {\n  port = 80\n}\n"))
+	ast.EqualValues(80, NewAsyncConfigWithCodec(asyncer, "service/web", HCLCodec, 0, false).Int("service.web.port"))
+}
+
+func TestXMLMarshaler(t *testing.T) {
+	ast := assert.New(t)
+	m := XMLMarshaler{}
+
+	var v interface{}
+	err := m.Unmarshal([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- comment -->
+<config env="prod">
+	<db host="example.com" port="3306"/>
+	<name>svc</name>
+	<tag>a</tag>
+	<tag>b</tag>
+	<timeout unit="s">3</timeout>
+	<empty></empty>
+</config>`), &v)
+	ast.Nil(err)
+
+	cfg := NewMapConfig(v.(map[string]interface{}))
+	ast.Equal("prod", cfg.String("config.@env"))
+	ast.Equal("example.com", cfg.String("config.db.@host"))
+	ast.Equal(int64(3306), cfg.Int("config.db.@port"))
+	ast.Equal("svc", cfg.String("config.name"))
+	ast.Equal("b", cfg.String("config.tag.1"))
+	ast.Equal(int64(3), cfg.Int("config.timeout.#text"))
+	ast.Equal("s", cfg.String("config.timeout.@unit"))
+	ast.Equal("", cfg.Get("config.empty"))
+
+	bs, err := m.Marshal(v)
+	ast.Nil(err)
+	var v2 interface{}
+	ast.Nil(m.Unmarshal(bs, &v2))
+	ast.Equal(v, v2)
+
+	ast.NotNil(m.Unmarshal([]byte(`<config><a></config>`), &v))
+	ast.NotNil(m.Unmarshal([]byte(``), &v))
+	_, err = m.Marshal(map[string]interface{}{"a": 1, "b": 2})
+	ast.NotNil(err)
+}
+
+func TestTOMLMarshaler(t *testing.T) {
+	ast := assert.New(t)
+	m := TOMLMarshaler{}
+
+	var v map[string]interface{}
+	err := m.Unmarshal([]byte(`
+name = "app"
+started = 2024-01-02T15:04:05Z
+day = 2024-01-02
+
+[db]
+host = "example.com"
+port = 3306
+
+[[servers]]
+ip = "10.0.0.1"
+
+[[servers]]
+ip = "10.0.0.2"
+`), &v)
+	ast.Nil(err)
+
+	cfg := NewMapConfig(v)
+	ast.Equal("app", cfg.String("name"))
+	ast.Equal("2024-01-02T15:04:05Z", cfg.String("started"))
+	ast.Equal("2024-01-02", cfg.String("day"))
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.EqualValues(3306, cfg.Int("db.port"))
+	ast.Equal("10.0.0.2", cfg.String("servers.1.ip"))
+
+	bs, err := m.Marshal(v)
+	ast.Nil(err)
+	var v2 map[string]interface{}
+	ast.Nil(m.Unmarshal(bs, &v2))
+	ast.Equal(v, v2)
+
+	ast.NotNil(m.Unmarshal([]byte("a = "), &v))
+	ast.Equal(T_TOML, ContentTypeByExt("conf.toml"))
+
+	asyncer := NewMockAsyncer(false)
+	asyncer.data.Store("app.toml", []byte("[db]\nport = 1\n"))
+	ast.EqualValues(1, NewAsyncConfig(asyncer, "app.toml", 0, false).Int("db.port"))
+}
+
+func TestINIMarshaler(t *testing.T) {
+	ast := assert.New(t)
+	m := INIMarshaler{}
+
+	var v map[string]interface{}
+	err := m.Unmarshal([]byte(`
+; comment
+name = app
+# comment
+[db]
+host = example.com
+port: 3306
+replica.host = "replica.example.com ; not comment"
+
+[feature.search]
+enabled = 'true'
+`), &v)
+	ast.Nil(err)
+
+	cfg := NewMapConfig(v)
+	ast.Equal("app", cfg.String("name"))
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Equal("3306", cfg.String("db.port"))
+	ast.Equal("replica.example.com ; not comment", cfg.String("db.replica.host"))
+	ast.Equal("true", cfg.String("feature.search.enabled"))
+
+	bs, err := m.Marshal(v)
+	ast.Nil(err)
+	ast.True(strings.HasPrefix(string(bs), "name = app\n\n[db]\n"), string(bs))
+	var v2 map[string]interface{}
+	ast.Nil(m.Unmarshal(bs, &v2))
+	ast.Equal(v, v2)
+
+	ast.NotNil(m.Unmarshal([]byte("[db"), &v))
+	ast.NotNil(m.Unmarshal([]byte("host"), &v))
+	ast.NotNil(m.Unmarshal([]byte("a = 1\na.b = 2"), &v), "conflicts")
+	_, err = m.Marshal([]interface{}{1})
+	ast.NotNil(err)
+	ast.Equal(T_INI, ContentTypeByExt("php.ini"))
+
+	// 数据库中key没有后缀
+	asyncer := &rawAsyncer{NewMockAsyncer(false)}
+	asyncer.data.Store("legacy", []byte("db.host=example.com\n"))
+	ast.Equal("example.com", NewAsyncConfigWithCodec(asyncer, "legacy", PropertiesCodec, 0, false).String("db.host"))
+}
+
+// genStringTree 叶子节点都为字符串的非空配置树，各格式都能无损表示
+type genStringTree map[string]interface{}
+
+func (genStringTree) Generate(r *rand.Rand, size int) reflect.Value {
+	m := genMap(r, 2, func(r *rand.Rand) interface{} { return genString(r) })
+	if len(m) == 0 {
+		m["a"] = genString(r)
+	}
+	return reflect.ValueOf(genStringTree(m))
+}
+
+func TestMarshalerRoundTripProperty(t *testing.T) {
+	for _, contentType := range []ContentType{T_JSON, T_YAML, T_TOML, T_PROPERTIES, T_HCL, T_XML} {
+		marshaler := typeMarshalers[contentType]
+		roundTrip := func(tree genStringTree) bool {
+			want := map[string]interface{}(tree)
+			if contentType == T_XML {
+				// xml只有一个根元素
+				want = map[string]interface{}{"config": want}
+			}
+			data, err := marshaler.Marshal(want)
+			if err != nil {
+				return false
+			}
+			var val map[string]interface{}
+			if err := marshaler.Unmarshal(data, &val); err != nil {
+				return false
+			}
+			return reflect.DeepEqual(want, val)
+		}
+		if err := quick.Check(roundTrip, quickConfig); err != nil {
+			t.Errorf("%T: %v", marshaler, err)
+		}
+	}
+}
+
+func TestSetGetProperty(t *testing.T) {
+	// 逐个Set叶子节点后Get得到相同的值，最终与原树相同
+	setGet := func(tree genStringTree) bool {
+		leaves := make(map[string]interface{})
+		flattenLeaves(RootKey, map[string]interface{}(tree), leaves)
+
+		cfg := NewMapConfig(nil)
+		for k, v := range leaves {
+			if cfg.Set(k, v) != nil || !reflect.DeepEqual(v, cfg.Get(k)) {
+				return false
+			}
+		}
+		return reflect.DeepEqual(map[string]interface{}(tree), cfg.Get(RootKey))
+	}
+	if err := quick.Check(setGet, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
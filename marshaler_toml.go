@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TOMLMarshaler TOML 格式
+//
+// 表数组（[[servers]]）解析为 []interface{}，日期时间解析为RFC3339格式的字符串（不带时区的保持原格式）
+type TOMLMarshaler struct{}
+
+func (m TOMLMarshaler) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m TOMLMarshaler) Unmarshal(data []byte, v interface{}) error {
+	ret := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &ret); err != nil {
+		return err
+	}
+
+	return assignValue(normalizeTOML(ret).(map[string]interface{}), v)
+}
+
+// normalizeTOML 将toml解析出的 []map[string]interface{} 及时间类型转换为通用结构
+func normalizeTOML(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case []map[string]interface{}:
+		ret := make([]interface{}, len(vv))
+		for i, item := range vv {
+			ret[i] = normalizeTOML(item)
+		}
+		return ret
+	case map[string]interface{}:
+		for k, item := range vv {
+			vv[k] = normalizeTOML(item)
+		}
+		return vv
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = normalizeTOML(item)
+		}
+		return vv
+	case time.Time:
+		// 不带时区的日期时间，见 toml/internal.LocalDatetime
+		switch vv.Location().String() {
+		case "date-local":
+			return vv.Format("2006-01-02")
+		case "time-local":
+			return vv.Format("15:04:05.999999999")
+		case "datetime-local":
+			return vv.Format("2006-01-02T15:04:05.999999999")
+		}
+		return vv.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
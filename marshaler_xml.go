@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// XMLAttrPrefix xml属性名的前缀
+	XMLAttrPrefix = "@"
+	// XMLTextKey 同时包含属性（或子元素）和文本的元素，其文本对应的key
+	XMLTextKey = "#text"
+)
+
+// XMLMarshaler xml 格式
+//
+// 元素映射规则：
+//   - 根元素名为顶层key
+//   - 只包含文本的元素值为字符串
+//   - 属性的key为 "@" + 属性名
+//   - 同时包含文本和属性/子元素时，文本的key为 "#text"
+//   - 同名的兄弟元素合并为数组
+//
+// 例如：
+//
+//	<config><db host="example.com" port="3306"/><tag>a</tag><tag>b</tag></config>
+//
+// 等价于 {"config": {"db": {"@host": "example.com", "@port": "3306"}, "tag": ["a", "b"]}}
+type XMLMarshaler struct{}
+
+func (m XMLMarshaler) Marshal(v interface{}) ([]byte, error) {
+	root, ok := v.(map[string]interface{})
+	if !ok || len(root) != 1 {
+		return nil, errors.New("xml requires a map with exactly one root element")
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	for name, val := range root {
+		if err := encodeXMLElement(enc, name, val); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m XMLMarshaler) Unmarshal(data []byte, v interface{}) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return errors.New("xml root element not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			val, err := decodeXMLElement(dec, start)
+			if err != nil {
+				return err
+			}
+			return assignValue(map[string]interface{}{
+				start.Name.Local: val,
+			}, v)
+		}
+	}
+}
+
+func decodeXMLElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	node := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		node[XMLAttrPrefix+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			if exist, ok := node[name]; ok {
+				if list, ok := exist.([]interface{}); ok {
+					node[name] = append(list, child)
+				} else {
+					node[name] = []interface{}{exist, child}
+				}
+			} else {
+				node[name] = child
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(node) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				node[XMLTextKey] = s
+			}
+			return node, nil
+		}
+	}
+}
+
+func encodeXMLElement(enc *xml.Encoder, name string, v interface{}) error {
+	switch vv := v.(type) {
+	case []interface{}:
+		for _, item := range vv {
+			if err := encodeXMLElement(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		children := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if strings.HasPrefix(k, XMLAttrPrefix) {
+				start.Attr = append(start.Attr, xml.Attr{
+					Name:  xml.Name{Local: strings.TrimPrefix(k, XMLAttrPrefix)},
+					Value: flatString(vv[k]),
+				})
+			} else if k != XMLTextKey {
+				children = append(children, k)
+			}
+		}
+
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if text, ok := vv[XMLTextKey]; ok {
+			if err := enc.EncodeToken(xml.CharData(flatString(text))); err != nil {
+				return err
+			}
+		}
+		for _, k := range children {
+			if err := encodeXMLElement(enc, k, vv[k]); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	default:
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(xml.CharData(flatString(vv))); err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	}
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// ErrMergeConflict 严格模式下合并的配置存在类型冲突，见 SetStrictMerge
+var ErrMergeConflict = errors.New("merge conflict")
+
+// MergeConflict 同一keyPath在不同来源中的结构不同（如一处是map一处是string），
+// 生效的值来自Winner，Loser中该节点及其子节点被忽略
+type MergeConflict struct {
+	KeyPath    string
+	Winner     KeyOrigin
+	WinnerType string
+	Loser      KeyOrigin
+	LoserType  string
+}
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("path[%s] %s from %s overrides %s from %s", c.KeyPath, c.WinnerType, c.Winner, c.LoserType, c.Loser)
+}
+
+var (
+	conflictReporter atomic.Value // func(MergeConflict)
+	strictMerge      int32
+)
+
+// SetConflictReporter 设置合并冲突的处理，nil 时记录warn日志（默认）
+//
+// 添加Layer（AddLayer）及合并配置（Merge）时检查冲突
+func SetConflictReporter(fn func(MergeConflict)) {
+	conflictReporter.Store(fn)
+}
+
+// SetStrictMerge 严格模式下Merge存在冲突时返回 ErrMergeConflict 且不修改配置，
+// WaitReady 及 AddRequiredLayer 在Layer间存在冲突时返回 ErrMergeConflict
+func SetStrictMerge(strict bool) {
+	var v int32
+	if strict {
+		v = 1
+	}
+	atomic.StoreInt32(&strictMerge, v)
+}
+
+func isStrictMerge() bool {
+	return atomic.LoadInt32(&strictMerge) == 1
+}
+
+func reportConflicts(conflicts []MergeConflict) {
+	fn, _ := conflictReporter.Load().(func(MergeConflict))
+	for _, c := range conflicts {
+		if fn != nil {
+			fn(c)
+		} else {
+			logger.Warnf("config merge conflict: %s", c)
+		}
+	}
+}
+
+// conflictsError 严格模式下存在冲突时返回error
+func conflictsError(conflicts []MergeConflict) error {
+	if len(conflicts) == 0 || !isStrictMerge() {
+		return nil
+	}
+	return errors.Wrapf(ErrMergeConflict, "%s", conflicts[0])
+}
+
+// Conflicts 返回分层配置中各Layer之间的结构冲突，按keyPath排序
+func (h *ConfigHelper) Conflicts() []MergeConflict {
+	return layerConflicts(snapshotLayers(h.Configer))
+}
+
+func Conflicts(layerNames ...string) []MergeConflict {
+	p := _cfg.Layer(layerNames...)
+	defer _cfg.PutLayer(p)
+	return p.Conflicts()
+}
+
+// layerConflicts 查询顺序在前的Layer优先
+func layerConflicts(layers []snapshotLayer) []MergeConflict {
+	type node struct {
+		layer int
+		value interface{}
+	}
+
+	var conflicts []MergeConflict
+	first := make(map[string]node)
+	for i, layer := range layers {
+		walkNodes(layer.root, "", func(keyPath string, v interface{}) bool {
+			n, ok := first[keyPath]
+			if !ok {
+				first[keyPath] = node{layer: i, value: v}
+				return true
+			}
+			if structureOf(n.value) == structureOf(v) {
+				return true
+			}
+
+			winner := layers[n.layer]
+			conflicts = append(conflicts, MergeConflict{
+				KeyPath:    keyPath,
+				Winner:     KeyOrigin{Layer: winner.name, Source: winner.source},
+				WinnerType: valueType(n.value),
+				Loser:      KeyOrigin{Layer: layer.name, Source: layer.source},
+				LoserType:  valueType(v),
+			})
+			return false
+		})
+	}
+
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].KeyPath < conflicts[j].KeyPath
+	})
+	return conflicts
+}
+
+// mergeConflicts 将extra合并到origin时的冲突，extra优先
+func mergeConflicts(origin, extra map[string]interface{}) []MergeConflict {
+	return layerConflicts([]snapshotLayer{
+		{source: "merge", root: extra},
+		{source: "map", root: origin},
+	})
+}
+
+// walkNodes 按key的顺序遍历root下值不为null的节点，fn返回false时不遍历其子节点
+func walkNodes(root interface{}, prefix string, fn func(keyPath string, v interface{}) bool) {
+	m, ok := root.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := m[k]
+		if v == nil {
+			continue
+		}
+		keyPath := k
+		if prefix != "" {
+			keyPath = prefix + "." + k
+		}
+		if fn(keyPath, v) {
+			walkNodes(v, keyPath, fn)
+		}
+	}
+}
+
+// structureOf 节点的结构：map、list或标量
+func structureOf(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "map"
+	case []interface{}:
+		return "list"
+	default:
+		return "scalar"
+	}
+}
+
+// layerConflicts 默认查询的Layer中与layerName相关的冲突，layerName不在默认查询的Layer中时不检查
+func (cfg *defaultConfig) layerConflicts(layerName string) []MergeConflict {
+	layerNames := cfg.defaultLayerNames.Load().([]string)
+	found := false
+	for _, name := range layerNames {
+		if name == layerName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	var ret []MergeConflict
+	for _, c := range layerConflicts(cfg.snapshotLayers(layerNames...)) {
+		if inLayer(c.Winner.Layer, layerName) || inLayer(c.Loser.Layer, layerName) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// inLayer name 为layerName或其中嵌套的Layer（"outer/inner"）
+func inLayer(name, layerName string) bool {
+	return name == layerName || strings.HasPrefix(name, layerName+"/")
+}
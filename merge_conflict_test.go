@@ -0,0 +1,68 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeConflicts(t *testing.T) {
+	ast := assert.New(t)
+
+	var reported []MergeConflict
+	SetConflictReporter(func(c MergeConflict) { reported = append(reported, c) })
+	defer SetConflictReporter(nil)
+
+	cfg := newConfig()
+	cfg.AddDefaultLayerName("override")
+	cfg.AddLayer(DefaultLayerName, NewMapConfig(map[string]interface{}{
+		"db":    map[string]interface{}{"host": "a", "port": 1},
+		"hosts": []interface{}{"a"},
+		"name":  "x",
+	}))
+	ast.Empty(reported)
+
+	cfg.AddLayer("override", NewMapConfig(map[string]interface{}{
+		"db":    "mysql://b",
+		"hosts": []interface{}{"b"},
+		"name":  1,
+	}))
+	expected := []MergeConflict{{
+		KeyPath:    "db",
+		Winner:     KeyOrigin{Layer: "override", Source: "map"},
+		WinnerType: "string",
+		Loser:      KeyOrigin{Layer: DefaultLayerName, Source: "map"},
+		LoserType:  "map",
+	}}
+	ast.Equal(expected, reported, "scalar types and lists are compatible")
+	ast.Equal(expected, cfg.Conflicts())
+	ast.Equal("path[db] string from override (map) overrides map from default (map)", reported[0].String())
+
+	// 不在默认查询的Layer中
+	reported = nil
+	cfg.AddLayer("other", NewMapConfig(map[string]interface{}{"db": []interface{}{1}}))
+	ast.Empty(reported)
+	ast.Len(cfg.Layer("other", DefaultLayerName).Conflicts(), 1)
+
+	// Merge
+	m := NewMapConfig(map[string]interface{}{"db": map[string]interface{}{"host": "a"}})
+	ast.Nil(m.Merge(map[string]interface{}{"db": map[string]interface{}{"host": map[string]interface{}{"name": "b"}}}))
+	if ast.Len(reported, 1) {
+		ast.Equal("db.host", reported[0].KeyPath)
+		ast.Equal(KeyOrigin{Source: "merge"}, reported[0].Winner)
+	}
+	ast.Equal("b", m.String("db.host.name"))
+
+	// 严格模式
+	SetStrictMerge(true)
+	defer SetStrictMerge(false)
+	err := m.Merge(map[string]interface{}{"db": "c"})
+	ast.True(errors.Is(err, ErrMergeConflict))
+	ast.Equal("b", m.String("db.host.name"), "not merged")
+	ast.Nil(m.Merge(map[string]interface{}{"db": map[string]interface{}{"port": 1}}))
+
+	err = cfg.AddRequiredLayer(DefaultLayerName, cfg.Layer(DefaultLayerName).Map(RootKey))
+	ast.True(errors.Is(err, ErrMergeConflict))
+	ast.True(errors.Is(cfg.ready(), ErrMergeConflict))
+}
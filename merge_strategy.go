@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mohae/deepcopy"
+)
+
+// MergeStrategy 合并时overlay的值与base的值的处理方式
+type MergeStrategy int
+
+const (
+	// MergeDeep 默认：都为map时按key深度合并，否则使用overlay的值
+	MergeDeep MergeStrategy = iota
+	// MergeReplace 使用overlay的值，map也不合并
+	MergeReplace
+	// MergeAppend 都为数组时将overlay的元素追加到base之后，否则同 MergeDeep
+	MergeAppend
+	// MergeByKey 都为数组时按元素（map）的 MergePolicy.Key 字段合并：key相同的元素深度合并，其他的追加；
+	// 没有该字段的元素直接追加。否则同 MergeDeep
+	MergeByKey
+)
+
+// MergePolicy 一个路径的合并策略
+type MergePolicy struct {
+	Strategy MergeStrategy
+	Key      string // MergeByKey 使用的字段，如 "name"
+}
+
+// MergePolicies keyPath => 合并策略，keyPath中的 "*" 匹配任意一段，如 "services.*.ports"；
+// 未指定的路径使用 MergeDeep
+//
+// 按key合并的数组中，元素的字段路径为数组的路径加字段名，如 "plugins" 中元素的 "args" 为 "plugins.args"
+type MergePolicies map[string]MergePolicy
+
+// match 精确的路径优先，其次为 "*" 最少的路径，相同时按字典序
+func (p MergePolicies) match(keyPath string) MergePolicy {
+	if policy, ok := p[keyPath]; ok {
+		return policy
+	}
+
+	var (
+		best     string
+		wildcard int
+		matched  bool
+	)
+	segments := strings.Split(keyPath, ".")
+	for pattern := range p {
+		n := strings.Count(pattern, "*")
+		if n == 0 {
+			continue
+		}
+		if ps := strings.Split(pattern, "."); len(ps) != len(segments) || !matchSegments(ps, segments) {
+			continue
+		}
+		if !matched || n < wildcard || (n == wildcard && pattern < best) {
+			best, wildcard, matched = pattern, n, true
+		}
+	}
+	if !matched {
+		return MergePolicy{}
+	}
+	return p[best]
+}
+
+// MergeTreesWith 同 MergeTrees，按policies指定的路径合并数组或整体替换
+//
+//	config.MergeTreesWith(base, override, config.MergePolicies{
+//		"allowlist": {Strategy: config.MergeAppend},
+//		"upstreams": {Strategy: config.MergeByKey, Key: "name"},
+//		"limits":    {Strategy: config.MergeReplace},
+//	})
+func MergeTreesWith(base, overlay map[string]interface{}, policies MergePolicies) map[string]interface{} {
+	if len(policies) == 0 {
+		return MergeTrees(base, overlay)
+	}
+
+	merged, _ := deepcopy.Copy(base).(map[string]interface{})
+	if merged == nil {
+		merged = make(map[string]interface{})
+	}
+	if extra, ok := deepcopy.Copy(overlay).(map[string]interface{}); ok {
+		mergeMapWith(merged, extra, "", policies)
+	}
+	return merged
+}
+
+func mergeMapWith(origin, extra map[string]interface{}, prefix string, policies MergePolicies) {
+	for k, v := range extra {
+		keyPath := k
+		if prefix != "" {
+			keyPath = prefix + "." + k
+		}
+		origin[k] = mergeValueWith(origin[k], v, keyPath, policies)
+	}
+}
+
+func mergeValueWith(origin, extra interface{}, keyPath string, policies MergePolicies) interface{} {
+	policy := policies.match(keyPath)
+	if policy.Strategy == MergeReplace {
+		return extra
+	}
+
+	originArr, originArrOk := origin.([]interface{})
+	extraArr, extraArrOk := extra.([]interface{})
+	if originArrOk && extraArrOk {
+		switch policy.Strategy {
+		case MergeAppend:
+			return append(originArr, extraArr...)
+		case MergeByKey:
+			return mergeByKey(originArr, extraArr, policy.Key, keyPath, policies)
+		}
+		return extra
+	}
+
+	originMap, originMapOk := origin.(map[string]interface{})
+	extraMap, extraMapOk := extra.(map[string]interface{})
+	if originMapOk && extraMapOk {
+		mergeMapWith(originMap, extraMap, keyPath, policies)
+		return originMap
+	}
+	return extra
+}
+
+// mergeByKey 修改origin中的元素，调用方已复制
+func mergeByKey(origin, extra []interface{}, key, keyPath string, policies MergePolicies) []interface{} {
+	merged := origin
+	index := make(map[string]int, len(origin))
+	for i, elem := range merged {
+		if id, ok := elementKey(elem, key); ok {
+			if _, exist := index[id]; !exist {
+				index[id] = i
+			}
+		}
+	}
+
+	for _, elem := range extra {
+		id, ok := elementKey(elem, key)
+		if !ok {
+			merged = append(merged, elem)
+			continue
+		}
+		if i, exist := index[id]; exist {
+			mergeMapWith(merged[i].(map[string]interface{}), elem.(map[string]interface{}), keyPath, policies)
+			continue
+		}
+		index[id] = len(merged)
+		merged = append(merged, elem)
+	}
+	return merged
+}
+
+// elementKey 元素为map且有key字段时返回字段值，不同格式解析出的数字类型可能不同，按字符串比较
+func elementKey(elem interface{}, key string) (string, bool) {
+	m, ok := elem.(map[string]interface{})
+	if !ok || key == "" {
+		return "", false
+	}
+	v, ok := m[key]
+	if !ok || v == nil {
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
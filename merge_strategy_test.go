@@ -0,0 +1,101 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeTreesWith(t *testing.T) {
+	ast := assert.New(t)
+
+	base := map[string]interface{}{
+		"allowlist": []interface{}{"10.0.0.1"},
+		"denylist":  []interface{}{"1.1.1.1"},
+		"limits":    map[string]interface{}{"qps": 100, "burst": 10},
+		"upstreams": []interface{}{
+			map[string]interface{}{"name": "a", "weight": 1, "tags": []interface{}{"x"}},
+			map[string]interface{}{"name": "b", "weight": 1},
+			"raw",
+		},
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{"ports": []interface{}{80}},
+			"web": map[string]interface{}{"ports": []interface{}{8080}},
+		},
+	}
+	overlay := map[string]interface{}{
+		"allowlist": []interface{}{"10.0.0.2"},
+		"denylist":  []interface{}{"2.2.2.2"},
+		"limits":    map[string]interface{}{"qps": 200},
+		"upstreams": []interface{}{
+			map[string]interface{}{"name": "b", "weight": 5},
+			map[string]interface{}{"name": "c", "weight": 1},
+			map[string]interface{}{"name": "a", "tags": []interface{}{"y"}},
+			map[string]interface{}{"weight": 9},
+		},
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{"ports": []interface{}{443}},
+		},
+	}
+	policies := MergePolicies{
+		"allowlist":        {Strategy: MergeAppend},
+		"limits":           {Strategy: MergeReplace},
+		"upstreams":        {Strategy: MergeByKey, Key: "name"},
+		"upstreams.tags":   {Strategy: MergeAppend},
+		"services.*.ports": {Strategy: MergeAppend},
+	}
+
+	merged := MergeTreesWith(base, overlay, policies)
+	ast.Equal([]interface{}{"10.0.0.1", "10.0.0.2"}, merged["allowlist"])
+	// 未指定的数组替换
+	ast.Equal([]interface{}{"2.2.2.2"}, merged["denylist"])
+	ast.Equal(map[string]interface{}{"qps": 200}, merged["limits"])
+	ast.Equal([]interface{}{
+		map[string]interface{}{"name": "a", "weight": 1, "tags": []interface{}{"x", "y"}},
+		map[string]interface{}{"name": "b", "weight": 5},
+		"raw",
+		map[string]interface{}{"name": "c", "weight": 1},
+		map[string]interface{}{"weight": 9},
+	}, merged["upstreams"])
+	ast.Equal([]interface{}{80, 443}, merged["services"].(map[string]interface{})["api"].(map[string]interface{})["ports"])
+	ast.Equal([]interface{}{8080}, merged["services"].(map[string]interface{})["web"].(map[string]interface{})["ports"])
+
+	// 不修改输入
+	ast.Equal([]interface{}{"10.0.0.1"}, base["allowlist"])
+	ast.Equal(map[string]interface{}{"qps": 100, "burst": 10}, base["limits"])
+	ast.Len(base["upstreams"], 3)
+	ast.Equal(1, base["upstreams"].([]interface{})[1].(map[string]interface{})["weight"])
+
+	// 没有策略时与 MergeTrees 一致
+	ast.Equal(MergeTrees(base, overlay), MergeTreesWith(base, overlay, nil))
+}
+
+func TestMergePoliciesMatch(t *testing.T) {
+	ast := assert.New(t)
+
+	p := MergePolicies{
+		"a.b":   {Strategy: MergeReplace},
+		"a.*":   {Strategy: MergeAppend},
+		"*.*":   {Strategy: MergeByKey, Key: "id"},
+		"*.b.c": {Strategy: MergeAppend},
+	}
+	ast.Equal(MergeReplace, p.match("a.b").Strategy)
+	ast.Equal(MergeAppend, p.match("a.x").Strategy)
+	ast.Equal(MergePolicy{Strategy: MergeByKey, Key: "id"}, p.match("x.y"))
+	ast.Equal(MergeAppend, p.match("x.b.c").Strategy)
+	ast.Equal(MergeDeep, p.match("x").Strategy)
+	ast.Equal(MergeDeep, p.match("x.y.z").Strategy)
+}
+
+func TestLayeredConfigMergePolicies(t *testing.T) {
+	ast := assert.New(t)
+
+	base := NewMapConfig(map[string]interface{}{"hosts": []interface{}{"a"}})
+	override := NewMapConfig(map[string]interface{}{"hosts": []interface{}{"b"}})
+	cfg := NewLayeredConfig(base, override)
+	defer cfg.Close()
+
+	ast.Equal([]interface{}{"b"}, cfg.Get("hosts"))
+	cfg.SetMergePolicies(MergePolicies{"hosts": {Strategy: MergeAppend}})
+	ast.Equal([]interface{}{"a", "b"}, cfg.Get("hosts"))
+}
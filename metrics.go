@@ -0,0 +1,59 @@
+package config
+
+import "sync/atomic"
+
+// 内置的指标名称
+const (
+	MetricSlowGets      = "config_slow_gets_total"      // labels: key
+	MetricSlowRefreshes = "config_slow_refreshes_total" // labels: key
+	// MetricPropagationLatency 配置从发布到生效的延迟（秒），labels: key，见 WithEnvelope
+	MetricPropagationLatency = "config_propagation_latency_seconds"
+	// MetricShadowReads、MetricShadowMismatches 影子读取的次数及不一致的次数，labels: name，见 NewShadowConfig
+	MetricShadowReads      = "config_shadow_reads_total"
+	MetricShadowMismatches = "config_shadow_mismatches_total"
+	// MetricBackendRequests、MetricBackendBytes 后端的调用次数及传输的字节数，labels: backend, op，见 Account
+	MetricBackendRequests = "config_backend_requests_total"
+	MetricBackendBytes    = "config_backend_bytes_total"
+	// MetricPayloadBytes 每次刷新读取的内容大小（字节），labels: key，分桶见 PayloadSizeBuckets
+	MetricPayloadBytes = "config_payload_bytes"
+	// MetricTreeKeys、MetricTreeDepth 解析后配置树的叶子节点数及最大嵌套深度，labels: key
+	MetricTreeKeys  = "config_tree_keys"
+	MetricTreeDepth = "config_tree_depth"
+)
+
+// Metrics 指标上报接口，可对接prometheus、statsd等，默认不上报
+//
+// labels 为 key, value 交替的标签列表
+type Metrics interface {
+	Counter(name string, value float64, labels ...string)
+	Gauge(name string, value float64, labels ...string)
+	Observe(name string, value float64, labels ...string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Counter(string, float64, ...string) {}
+func (nopMetrics) Gauge(string, float64, ...string)   {}
+func (nopMetrics) Observe(string, float64, ...string) {}
+
+// metricsHolder atomic.Value 要求存储的类型一致
+type metricsHolder struct{ Metrics }
+
+var _metrics atomic.Value // metricsHolder
+
+func init() {
+	SetMetrics(nil)
+}
+
+// SetMetrics 设置指标上报，nil 不上报；可在运行中调用
+func SetMetrics(m Metrics) {
+	if m == nil {
+		m = nopMetrics{}
+	}
+	_metrics.Store(metricsHolder{m})
+}
+
+// loadMetrics 当前的指标上报，一次上报多个指标时只需读取一次
+func loadMetrics() Metrics {
+	return _metrics.Load().(metricsHolder).Metrics
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// NotifyWorkers 执行 OnChange 回调的goroutine数，需在首次调用 OnChange 前设置
+var NotifyWorkers = 4
+
+// OnChange 配置变化时在后台的通知池中执行fn，调用cancel取消
+//
+// 回调不在刷新及Set的调用路径中执行；同一回调按顺序执行，不会并发，
+// 执行期间的多次变化合并为一次
+func (h *ConfigHelper) OnChange(fn func()) (cancel func()) {
+	s := &subscription{id: atomic.AddUint64(&_subscriptionID, 1), fn: fn}
+
+	c := unwrapConfiger(h.Configer)
+	if r, ok := c.(internalErrorReporter); ok {
+		s.report = r.reportError
+	}
+	if cs, ok := c.(changeSubscriber); ok {
+		cs.subscribe(s)
+		return func() {
+			atomic.StoreInt32(&s.cancelled, 1)
+			cs.unsubscribe(s)
+		}
+	}
+
+	watchForward(c, s)
+	return func() {
+		atomic.StoreInt32(&s.cancelled, 1)
+	}
+}
+
+// watchForward 不支持订阅的Configer通过Watch转发，取消后在下一次变化时退出
+func watchForward(c Configer, s *subscription) {
+	notifier := make(chan struct{}, 1)
+	c.Watch(notifier)
+	go func() {
+		for range notifier {
+			if atomic.LoadInt32(&s.cancelled) == 1 {
+				return
+			}
+			_notifyPool.dispatch(s)
+		}
+	}()
+}
+
+// changeSubscriber 支持 OnChange 回调的Configer
+type changeSubscriber interface {
+	subscribe(s *subscription)
+	unsubscribe(s *subscription)
+}
+
+var _subscriptionID uint64
+
+type subscription struct {
+	id        uint64
+	fn        func()
+	pending   int32 // 已在队列中等待执行
+	cancelled int32
+	report    func(*InternalError) // 回调panic的处理，见 WithErrorReporter
+}
+
+// subscriptions 一个配置的回调列表
+type subscriptions struct {
+	mu   sync.Mutex
+	subs []*subscription
+}
+
+func (ss *subscriptions) subscribe(s *subscription) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.subs = append(ss.subs, s)
+}
+
+// unsubscribe 返回是否存在
+func (ss *subscriptions) unsubscribe(s *subscription) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for i, sub := range ss.subs {
+		if sub == s {
+			ss.subs = append(ss.subs[:i:i], ss.subs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// clear 移除所有回调，返回移除的数量
+func (ss *subscriptions) clear() int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	n := len(ss.subs)
+	ss.subs = nil
+	return n
+}
+
+// dispatch 将回调交给通知池执行，不等待
+func (ss *subscriptions) dispatch() {
+	ss.mu.Lock()
+	subs := ss.subs
+	ss.mu.Unlock()
+
+	for _, s := range subs {
+		_notifyPool.dispatch(s)
+	}
+}
+
+var _notifyPool notifyPool
+
+// notifyPool 执行回调的固定数量的goroutine，同一回调总是由同一goroutine执行以保证顺序
+type notifyPool struct {
+	once    sync.Once
+	workers []*notifyWorker
+}
+
+type notifyWorker struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	queue []*subscription
+}
+
+func (p *notifyPool) dispatch(s *subscription) {
+	p.once.Do(func() {
+		n := NotifyWorkers
+		if n <= 0 {
+			n = 1
+		}
+		p.workers = make([]*notifyWorker, n)
+		for i := range p.workers {
+			w := &notifyWorker{}
+			w.cond = sync.NewCond(&w.mu)
+			p.workers[i] = w
+			go w.run()
+		}
+	})
+
+	// 已在队列中的回调不重复加入，队列长度不超过回调的数量
+	if atomic.LoadInt32(&s.cancelled) == 1 || !atomic.CompareAndSwapInt32(&s.pending, 0, 1) {
+		return
+	}
+
+	w := p.workers[s.id%uint64(len(p.workers))]
+	w.mu.Lock()
+	w.queue = append(w.queue, s)
+	w.mu.Unlock()
+	w.cond.Signal()
+}
+
+func (w *notifyWorker) run() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 {
+			w.cond.Wait()
+		}
+		s := w.queue[0]
+		w.queue[0] = nil
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+
+		// 执行期间的变化需要再次执行
+		atomic.StoreInt32(&s.pending, 0)
+		if atomic.LoadInt32(&s.cancelled) == 0 {
+			w.call(s)
+		}
+	}
+}
+
+func (w *notifyWorker) call(s *subscription) {
+	defer func() {
+		if r := recover(); r != nil {
+			e := &InternalError{Op: OpCallback, Err: errors.Errorf("change callback panic: %v", r), Panic: r, Stack: debug.Stack()}
+			if s.report != nil {
+				s.report(e)
+			} else {
+				reportError(nil, e)
+			}
+		}
+	}()
+	s.fn()
+}
+
+// subscribe 与Watch2一致，只订阅已存在的Layer
+func (cfg *defaultConfig) subscribe(s *subscription, layerNames ...string) {
+	for _, layer := range cfg.loadLayers(layerNames...) {
+		if cs, ok := unwrapConfiger(layer).(changeSubscriber); ok {
+			cs.subscribe(s)
+		} else {
+			watchForward(layer, s)
+		}
+	}
+}
+
+func (cfg *defaultConfig) unsubscribe(s *subscription, layerNames ...string) {
+	for _, layer := range cfg.loadLayers(layerNames...) {
+		if cs, ok := unwrapConfiger(layer).(changeSubscriber); ok {
+			cs.unsubscribe(s)
+		}
+	}
+}
+
+func (cfg *defaultConfig) loadLayers(layerNames ...string) []Configer {
+	if len(layerNames) == 0 {
+		layerNames = cfg.defaultLayerNames.Load().([]string)
+	}
+	layers := make([]Configer, 0, len(layerNames))
+	for _, layerName := range layerNames {
+		if layer, ok := cfg.layers.Load(layerName); ok {
+			layers = append(layers, layer.(Configer))
+		}
+	}
+	return layers
+}
+
+func (c *defaultConfiger) subscribe(s *subscription)   { c.cfg.subscribe(s) }
+func (c *defaultConfiger) unsubscribe(s *subscription) { c.cfg.unsubscribe(s) }
+
+func (p *layerConfigProxy) subscribe(s *subscription)   { p.cfg.subscribe(s, p.layerNames...) }
+func (p *layerConfigProxy) unsubscribe(s *subscription) { p.cfg.unsubscribe(s, p.layerNames...) }
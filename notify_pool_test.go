@@ -0,0 +1,84 @@
+package config
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnChange(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"a": 1})
+
+	var calls, running, overlapped int32
+	release := make(chan struct{})
+	cancel := cfg.OnChange(func() {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		<-release
+		atomic.AddInt32(&calls, 1)
+		atomic.AddInt32(&running, -1)
+	})
+
+	// 回调阻塞时Set不等待
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		ast.Nil(cfg.Set("a", i))
+	}
+	ast.True(time.Since(start) < 100*time.Millisecond)
+	close(release)
+
+	ast.Eventually(func() bool { return atomic.LoadInt32(&calls) >= 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	ast.True(atomic.LoadInt32(&calls) <= 2, "coalesced, calls=%d", calls)
+	ast.EqualValues(0, atomic.LoadInt32(&overlapped))
+
+	cancel()
+	n := atomic.LoadInt32(&calls)
+	ast.Nil(cfg.Set("a", 100))
+	time.Sleep(50 * time.Millisecond)
+	ast.Equal(n, atomic.LoadInt32(&calls), "cancelled")
+
+	// panic不影响后续回调
+	var ok int32
+	cfg.OnChange(func() { panic("boom") })
+	cfg.OnChange(func() { atomic.StoreInt32(&ok, 1) })
+	ast.Nil(cfg.Set("a", 101))
+	ast.Eventually(func() bool { return atomic.LoadInt32(&ok) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestOnChangeLayered(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := newConfig()
+	layer := NewMapConfig(map[string]interface{}{"a": 1})
+	other := NewMapConfig(map[string]interface{}{"b": 1})
+	cfg.AddLayer(DefaultLayerName, layer)
+	cfg.AddLayer("other", other)
+
+	var calls int32
+	cancel := cfg.OnChange(func() { atomic.AddInt32(&calls, 1) })
+	ast.Nil(layer.Set("a", 2))
+	ast.Eventually(func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 10*time.Millisecond)
+
+	ast.Nil(other.Set("b", 2))
+	time.Sleep(50 * time.Millisecond)
+	ast.EqualValues(1, atomic.LoadInt32(&calls), "not subscribed")
+
+	cancel()
+	ast.Nil(layer.Set("a", 3))
+	time.Sleep(50 * time.Millisecond)
+	ast.EqualValues(1, atomic.LoadInt32(&calls))
+
+	// 通过Watch转发
+	var forwarded int32
+	shadow := NewShadowConfig("s", layer, other)
+	cancel = shadow.OnChange(func() { atomic.AddInt32(&forwarded, 1) })
+	defer cancel()
+	ast.Nil(layer.Set("a", 4))
+	ast.Eventually(func() bool { return atomic.LoadInt32(&forwarded) == 1 }, time.Second, 10*time.Millisecond)
+}
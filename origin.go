@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+)
+
+// KeyOrigin 提供配置值的来源
+type KeyOrigin struct {
+	Layer  string // Layer名称，非分层配置为空
+	Source string // 来源描述，如 "map"、"async *config.FileAsyncer[conf/app.yaml]"、"bootstrap"
+}
+
+func (o KeyOrigin) String() string {
+	if o.Layer == "" {
+		return o.Source
+	}
+	return fmt.Sprintf("%s (%s)", o.Layer, o.Source)
+}
+
+// Origin 返回提供keyPath生效值的来源，keyPath不存在或值为null时返回false
+//
+// 分层配置按查询顺序返回第一个值不为null的Layer
+func (h *ConfigHelper) Origin(keyPath string) (KeyOrigin, bool) {
+	snap := &snapshotConfig{layers: snapshotLayers(h.Configer)}
+	_, _, idx := snap.lookupLayer(keyPath)
+	if idx < 0 {
+		return KeyOrigin{}, false
+	}
+
+	layer := snap.layers[idx]
+	return KeyOrigin{Layer: layer.name, Source: layer.source}, true
+}
+
+// configSource 描述配置的来源，c已去除 ConfigHelper 的包装
+func configSource(c Configer) string {
+	switch cc := c.(type) {
+	case *mapConfig:
+		return "map"
+	case *asyncConfig:
+		return fmt.Sprintf("async %T[%s]", cc.asyncer, cc.asyncKey)
+	default:
+		return fmt.Sprintf("%T", c)
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrigin(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(false)
+	mock.Set("app.json", []byte(`{"db": {"host": "example.com"}, "name": null}`))
+	remote := NewAsyncConfig(rawAsyncer{mock}, "app.json", time.Hour, false)
+	defer remote.Close()
+
+	layered := newConfig()
+	layered.AddLayer("remote", remote)
+	layered.AddLayer("default", NewMapConfig(map[string]interface{}{
+		"db":   map[string]interface{}{"host": "localhost", "port": 3306},
+		"name": "app",
+	}))
+
+	p := layered.Layer("remote", "default")
+	origin, ok := p.Origin("db.host")
+	ast.True(ok)
+	ast.Equal(KeyOrigin{Layer: "remote", Source: "async config.rawAsyncer[app.json]"}, origin)
+	ast.Equal("remote (async config.rawAsyncer[app.json])", origin.String())
+
+	origin, ok = p.Origin("db.port")
+	ast.True(ok)
+	ast.Equal(KeyOrigin{Layer: "default", Source: "map"}, origin)
+
+	origin, ok = p.Origin("name")
+	ast.True(ok)
+	ast.Equal("default", origin.Layer, "null value skipped")
+
+	_, ok = p.Origin("not_exist")
+	ast.False(ok)
+
+	// 非分层配置
+	origin, ok = remote.Origin("db")
+	ast.True(ok)
+	ast.Equal("async config.rawAsyncer[app.json]", origin.String())
+
+	origin, _ = Snapshot(p).Origin("db.port")
+	ast.Equal(KeyOrigin{Layer: "default", Source: "map"}, origin)
+}
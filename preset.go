@@ -0,0 +1,59 @@
+package config
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Preset 缓存行为的预设，见 RealTime、Balanced、Cheap
+type Preset struct {
+	CacheTime     time.Duration // 缓存时间，<= 0 不过期
+	RefreshAsync  bool          // 缓存过期时是否异步刷新
+	RetryInterval time.Duration // 刷新失败后重试的间隔，<= 0 同CacheTime
+	Watch         bool          // 是否监听后端的变化通知
+	WatchFallback time.Duration // 监听时兜底的刷新间隔，防止通知丢失，<= 0 为5分钟
+}
+
+// 内置的预设，可在创建配置前统一调整
+var (
+	// PresetRealTime 变化需要尽快生效的配置（开关、限流阈值）
+	PresetRealTime = Preset{CacheTime: time.Second, RetryInterval: time.Second, Watch: true, WatchFallback: 30 * time.Second}
+	// PresetBalanced 大多数配置
+	PresetBalanced = Preset{CacheTime: 30 * time.Second, RefreshAsync: true, RetryInterval: 5 * time.Second, Watch: true, WatchFallback: 5 * time.Minute}
+	// PresetCheap 很少变化的配置，降低后端压力
+	PresetCheap = Preset{CacheTime: 10 * time.Minute, RefreshAsync: true, RetryInterval: time.Minute}
+)
+
+func RealTime() Preset { return PresetRealTime }
+func Balanced() Preset { return PresetBalanced }
+func Cheap() Preset    { return PresetCheap }
+
+// WithPreset 使用预设的缓存行为，替代 NewAsyncConfig 的cacheTime及refreshAsync参数
+func WithPreset(p Preset) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.cacheTime = int64(p.CacheTime)
+		cfg.refreshAsync = p.RefreshAsync
+		cfg.retryInterval = p.RetryInterval
+		cfg.noWatch = !p.Watch
+		cfg.watchFallback = p.WatchFallback
+	}
+}
+
+// NewAsyncConfigWithPreset 使用预设创建异步配置
+//
+//	cfg := config.NewAsyncConfigWithPreset(asyncer, "feature.json", config.RealTime())
+func NewAsyncConfigWithPreset(asyncer Asyncer, asyncKey string, p Preset, opts ...AsyncOption) *AsyncConfig {
+	return NewAsyncConfig(asyncer, asyncKey, p.CacheTime, p.RefreshAsync, append([]AsyncOption{WithPreset(p)}, opts...)...)
+}
+
+// expired 缓存是否过期，刷新失败后按retryInterval重试
+func (cfg *asyncConfig) expired(now int64) bool {
+	cacheTime := cfg.effectiveTTL()
+	if cacheTime <= 0 {
+		return false
+	}
+	if cfg.retryInterval > 0 && cfg.Status().Err != nil {
+		cacheTime = cfg.retryInterval
+	}
+	return time.Duration(now-atomic.LoadInt64(&cfg.refreshTime)) > cacheTime
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreset(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal(PresetRealTime, RealTime())
+	ast.True(Cheap().CacheTime > Balanced().CacheTime)
+	ast.True(Balanced().CacheTime > RealTime().CacheTime)
+
+	asyncer := NewMockAsyncer(true)
+	asyncer.data.Store("preset.json", []byte(`{"a": 1}`))
+	cfg := NewAsyncConfigWithPreset(asyncer, "preset.json", RealTime())
+	defer cfg.Close()
+	c := cfg.Configer.(*asyncConfig)
+	ast.Equal(int64(30*time.Second), c.cacheTime, "watch fallback")
+	ast.False(c.refreshAsync)
+
+	cheap := NewAsyncConfigWithPreset(asyncer, "preset.json", Cheap())
+	defer cheap.Close()
+	c = cheap.Configer.(*asyncConfig)
+	ast.Equal(int64(10*time.Minute), c.cacheTime, "not watching")
+	ast.True(c.refreshAsync)
+}
+
+func TestPresetRetryInterval(t *testing.T) {
+	ast := assert.New(t)
+
+	now := time.Now()
+	oldNow := _now
+	_now = func() time.Time { return now }
+	defer func() { _now = oldNow }()
+
+	asyncer := &rawAsyncer{NewMockAsyncer(false)}
+	cfg := NewAsyncConfigWithPreset(asyncer, "retry.json", Preset{CacheTime: time.Hour, RetryInterval: time.Second})
+	ast.NotNil(cfg.Status().Err)
+
+	asyncer.data.Store("retry.json", []byte(`{"a": 1}`))
+	now = now.Add(500 * time.Millisecond)
+	ast.Nil(cfg.Get("a"))
+	now = now.Add(time.Second)
+	ast.EqualValues(1, cfg.Int("a"), "retry after RetryInterval")
+
+	asyncer.data.Store("retry.json", []byte(`{"a": 2}`))
+	now = now.Add(time.Minute)
+	ast.EqualValues(1, cfg.Int("a"), "cache time after success")
+}
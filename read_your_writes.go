@@ -0,0 +1,82 @@
+package config
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithReadYourWrites Set后的window内，刷新获取的内容不是最近一次写入的内容时，
+// 视为后端（最终一致）尚未同步或读到了旧副本，保留Set的值
+//
+// 其他实例在window内的修改会延迟到window结束后生效
+func WithReadYourWrites(window time.Duration) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.ryw = &readYourWrites{window: window}
+	}
+}
+
+// readYourWrites 最近一次写入的版本，除version外由 asyncConfig.Mutex 保护
+type readYourWrites struct {
+	window  time.Duration
+	version uint64 // 每次Set加1
+	digest  string // 写入内容的摘要，见 contentDigest
+	until   time.Time
+}
+
+func (r *readYourWrites) currentVersion() uint64 {
+	if r == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&r.version)
+}
+
+// wrote 记录写入的内容
+func (r *readYourWrites) wrote(rawMessage []byte, now time.Time) {
+	if r == nil {
+		return
+	}
+	atomic.AddUint64(&r.version, 1)
+	r.digest = contentDigest(rawMessage)
+	r.until = now.Add(r.window)
+}
+
+// digestOf 刷新获取的内容的摘要，未开启时不计算
+func (r *readYourWrites) digestOf(rawMessage []byte) string {
+	if r == nil {
+		return ""
+	}
+	return contentDigest(rawMessage)
+}
+
+// stale 内容是否早于最近一次写入，version 为获取内容前的写入版本
+func (r *readYourWrites) stale(version uint64, digest string, now time.Time) bool {
+	if r == nil || r.until.IsZero() {
+		return false
+	}
+	if !now.Before(r.until) {
+		r.until = time.Time{}
+		return false
+	}
+	// 获取内容时还未写入
+	if version != atomic.LoadUint64(&r.version) {
+		return true
+	}
+	return digest != r.digest
+}
+
+// storeRefreshed 保存刷新的配置，内容早于最近一次写入时返回false
+func (cfg *asyncConfig) storeRefreshed(val interface{}, rawMessageDigest string, version uint64, contentDigest string, now time.Time) bool {
+	if cfg.ryw != nil {
+		cfg.Lock()
+		defer cfg.Unlock()
+
+		if cfg.ryw.stale(version, contentDigest, now) {
+			logger.Debugf("async config[%s] ignore stale content before write version %d", cfg.asyncKey, cfg.ryw.currentVersion())
+			return false
+		}
+	}
+
+	cfg.rawMessageDigest = rawMessageDigest
+	cfg.value.Store(val)
+	return true
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lagAsyncer 写入不立即生效的后端
+type lagAsyncer struct {
+	rawAsyncer
+	mu      sync.Mutex
+	written []byte
+}
+
+func (a *lagAsyncer) Set(key string, value []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.written = value
+	return nil
+}
+
+func TestReadYourWrites(t *testing.T) {
+	ast := assert.New(t)
+
+	now := time.Now()
+	oldNow := _now
+	_now = func() time.Time { return now }
+	defer func() { _now = oldNow }()
+
+	newConfig := func(opts ...AsyncOption) (*AsyncConfig, *lagAsyncer) {
+		asyncer := &lagAsyncer{rawAsyncer: rawAsyncer{NewMockAsyncer(false)}}
+		asyncer.data.Store("ryw.json", []byte(`{"a": 1}`))
+		return NewAsyncConfig(asyncer, "ryw.json", 0, false, opts...), asyncer
+	}
+
+	// 未开启时旧内容会覆盖Set的值
+	cfg, asyncer := newConfig()
+	ast.Nil(cfg.Set("a", 2))
+	ast.Nil(cfg.Set("a", 3))
+	asyncer.data.Store("ryw.json", []byte(`{"a":2}`))
+	ast.Nil(cfg.Configer.(*asyncConfig).refresh())
+	ast.EqualValues(2, cfg.Int("a"))
+
+	cfg, asyncer = newConfig(WithReadYourWrites(time.Minute))
+	refresh := cfg.Configer.(*asyncConfig).refresh
+	ast.Nil(cfg.Set("a", 2))
+	ast.Nil(refresh())
+	ast.EqualValues(2, cfg.Int("a"), "backend not synced")
+
+	ast.Nil(cfg.Set("a", 3))
+	asyncer.data.Store("ryw.json", []byte(`{"a":2}`))
+	ast.Nil(refresh())
+	ast.EqualValues(3, cfg.Int("a"), "earlier write")
+
+	asyncer.mu.Lock()
+	asyncer.data.Store("ryw.json", asyncer.written)
+	asyncer.mu.Unlock()
+	ast.Nil(refresh())
+	ast.EqualValues(3, cfg.Int("a"))
+
+	// 同步后读到旧副本
+	asyncer.data.Store("ryw.json", []byte(`{"a":2}`))
+	ast.Nil(refresh())
+	ast.EqualValues(3, cfg.Int("a"), "stale replica")
+
+	// 超过window后恢复正常刷新
+	now = now.Add(time.Minute)
+	asyncer.data.Store("ryw.json", []byte(`{"a":4}`))
+	ast.Nil(refresh())
+	ast.EqualValues(4, cfg.Int("a"))
+
+	// 获取内容后发生的写入
+	ast.Nil(cfg.Set("a", 5))
+	ryw := cfg.Configer.(*asyncConfig).ryw
+	version := ryw.currentVersion()
+	ast.Nil(cfg.Set("a", 6))
+	asyncer.mu.Lock()
+	written := asyncer.written
+	asyncer.mu.Unlock()
+	ast.True(ryw.stale(version, ryw.digestOf(written), now), "fetched before write")
+	ast.False(ryw.stale(ryw.currentVersion(), ryw.digestOf(written), now))
+}
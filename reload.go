@@ -0,0 +1,52 @@
+package config
+
+import (
+	"context"
+)
+
+// WithFrozen 只在创建（或 AsyncConfig.Start）时加载一次，不过期也不监听变化，
+// 之后只能通过 AsyncConfig.Reload 更新
+//
+// 注意：密钥引用（见 secret_ref.go）也不会自动重新解析
+func WithFrozen() AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.frozen = true
+	}
+}
+
+// Reload 立即从后端重新加载，返回生效的变化（内容未变化时为空）
+//
+// ctx结束时返回ctx.Err()，加载仍会在后台完成
+func (c *AsyncConfig) Reload(ctx context.Context) ([]Change, error) {
+	return c.Configer.(*asyncConfig).Reload(ctx)
+}
+
+func (cfg *asyncConfig) Reload(ctx context.Context) ([]Change, error) {
+	if cfg.closed() {
+		return nil, ErrClosed
+	}
+
+	type result struct {
+		changes []Change
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		cfg.Lock()
+		old := cfg.value.Load()
+		cfg.Unlock()
+
+		if err := cfg.refresh(); err != nil {
+			done <- result{err: err}
+			return
+		}
+		done <- result{changes: Diff(old, cfg.value.Load())}
+	}()
+
+	select {
+	case r := <-done:
+		return r.changes, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// SignalReloadTimeout 信号触发的单次加载的超时时间
+var SignalReloadTimeout = 30 * time.Second
+
+// Reloader 可手动重新加载的配置，如 AsyncConfig
+type Reloader interface {
+	Reload(ctx context.Context) ([]Change, error)
+}
+
+var _ Reloader = (*AsyncConfig)(nil)
+
+// ReloadOnSignal 收到信号时调用Reload，未指定signals时为SIGHUP，返回停止监听的函数
+//
+// 加载期间收到的多个信号合并为一次，在当前加载结束后执行
+//
+//	stop := config.ReloadOnSignal(cfg)
+//	defer stop()
+func ReloadOnSignal(r Reloader, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	// 缓冲为1：加载期间最多保留一个待处理的信号
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reloadLoop(r, ch, quit)
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(quit)
+			<-done
+		})
+	}
+}
+
+func reloadLoop(r Reloader, ch <-chan os.Signal, quit <-chan struct{}) {
+	for {
+		select {
+		case sig := <-ch:
+			ctx, cancel := context.WithTimeout(context.Background(), SignalReloadTimeout)
+			changes, err := r.Reload(ctx)
+			cancel()
+			if err != nil {
+				logger.Errorf("reload on signal %v err:%v", sig, err)
+				continue
+			}
+			logger.Infof("reload on signal %v, %d changes", sig, len(changes))
+
+		case <-quit:
+			return
+		}
+	}
+}
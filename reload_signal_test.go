@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingReloader struct {
+	calls   int32
+	release chan struct{}
+}
+
+func (r *blockingReloader) Reload(ctx context.Context) ([]Change, error) {
+	n := atomic.AddInt32(&r.calls, 1)
+	<-r.release
+	if n == 1 {
+		return nil, errors.New("first reload failed")
+	}
+	return nil, nil
+}
+
+func TestReloadOnSignal(t *testing.T) {
+	ast := assert.New(t)
+
+	r := &blockingReloader{release: make(chan struct{})}
+	ch := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reloadLoop(r, ch, quit)
+	}()
+
+	ch <- syscall.SIGHUP
+	for atomic.LoadInt32(&r.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 加载期间的多个信号合并为一次
+	for i := 0; i < 5; i++ {
+		select {
+		case ch <- syscall.SIGHUP:
+		default:
+		}
+	}
+	r.release <- struct{}{}
+	r.release <- struct{}{}
+	time.Sleep(10 * time.Millisecond)
+	ast.EqualValues(2, atomic.LoadInt32(&r.calls))
+
+	close(quit)
+	<-done
+
+	// 使用真实的signal注册
+	stop := ReloadOnSignal(r)
+	stop()
+	stop()
+}
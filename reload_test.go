@@ -0,0 +1,70 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	ast := assert.New(t)
+
+	mock := NewMockAsyncer(true)
+	mock.Set("frozen.json", []byte(`{"a": 1, "b": {"c": "x"}, "d": true}`))
+
+	cfg := NewAsyncConfig(rawAsyncer{mock}, "frozen.json", time.Nanosecond, false, WithFrozen())
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a"))
+
+	// 后端变化及通知不生效
+	mock.Set("frozen.json", []byte(`{"a": 2, "b": {"e": "y"}, "d": true}`))
+	time.Sleep(10 * time.Millisecond)
+	ast.EqualValues(1, cfg.Int("a"))
+
+	changes, err := cfg.Reload(context.Background())
+	ast.Nil(err)
+	ast.Equal([]Change{
+		{KeyPath: "a", Type: ChangeModified, Old: float64(1), New: float64(2)},
+		{KeyPath: "b.c", Type: ChangeRemoved, Old: "x"},
+		{KeyPath: "b.e", Type: ChangeAdded, New: "y"},
+	}, changes)
+	ast.EqualValues(2, cfg.Int("a"))
+	ast.Equal("modified", changes[0].Type.String())
+
+	changes, err = cfg.Reload(context.Background())
+	ast.Nil(err)
+	ast.Empty(changes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewAsyncConfig(&slowAsyncer{MockAsyncer: mock}, "frozen.json", 0, false, WithDeferredStart()).Reload(ctx)
+	ast.Equal(context.Canceled, err)
+
+	// 非冻结模式也可以手动加载
+	cfg2 := NewAsyncConfig(rawAsyncer{mock}, "frozen.json", time.Hour, false)
+	mock.data.Store("frozen.json", []byte(`{"a": 3}`))
+	changes, err = cfg2.Reload(context.Background())
+	ast.Nil(err)
+	ast.Len(changes, 3)
+	cfg2.Close()
+	_, err = cfg2.Reload(context.Background())
+	ast.Equal(ErrClosed, err)
+
+	mock.data.Store("frozen.json", []byte(``))
+	_, err = cfg.Reload(context.Background())
+	ast.NotNil(err)
+	ast.EqualValues(2, cfg.Int("a"), "keep last value")
+}
+
+func TestDiff(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Empty(Diff(nil, nil))
+	ast.Equal([]Change{{KeyPath: "a", Type: ChangeAdded, New: 1}}, Diff(nil, map[string]interface{}{"a": 1}))
+	ast.Equal([]Change{
+		{KeyPath: "a", Type: ChangeModified, Old: []interface{}{1}, New: []interface{}{1, 2}},
+	}, Diff(map[string]interface{}{"a": []interface{}{1}}, map[string]interface{}{"a": []interface{}{1, 2}}))
+	ast.Empty(Diff(map[string]interface{}{"a": map[string]interface{}{"b": 1}}, map[string]interface{}{"a": map[string]interface{}{"b": 1}}))
+}
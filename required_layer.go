@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AddRequiredLayer 添加必需的Layer，该Layer未加载成功或内容为空时返回 ErrNotReady，
+// 此时Layer仍会被添加，可通过 WaitReady 等待加载完成
+//
+// 分层配置中某一层加载失败时查询会静默地回落到其他层，
+// 必需的Layer可以在启动阶段尽早发现
+func (cfg *defaultConfig) AddRequiredLayer(layerName string, layer Configer) error {
+	cfg.AddLayer(layerName, layer)
+	cfg.requiredLayers.Store(layerName, true)
+	if err := layerReady(layerName, layer); err != nil {
+		return err
+	}
+	return conflictsError(cfg.layerConflicts(layerName))
+}
+
+func AddRequiredLayer(layerName string, layer Configer) error {
+	return _cfg.AddRequiredLayer(layerName, layer)
+}
+
+// WaitReady 等待所有必需的Layer加载完成，未加载成功的异步配置每隔 StartRetryInterval 重新加载，
+// ctx结束时返回最后一次检查的错误
+func (cfg *defaultConfig) WaitReady(ctx context.Context) error {
+	for {
+		err := cfg.ready()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(err, "wait ready: %v", ctx.Err())
+		case <-time.After(StartRetryInterval):
+		}
+	}
+}
+
+func WaitReady(ctx context.Context) error {
+	return _cfg.WaitReady(ctx)
+}
+
+// ready 检查所有必需的Layer，未加载成功的异步配置重新加载
+func (cfg *defaultConfig) ready() error {
+	var err error
+	cfg.requiredLayers.Range(func(k, _ interface{}) bool {
+		layerName := k.(string)
+		layer, ok := cfg.layers.Load(layerName)
+		if !ok {
+			err = errors.Wrapf(ErrNotReady, "required layer[%s] not found", layerName)
+			return false
+		}
+
+		if a, ok := unwrapConfiger(layer.(Configer)).(*asyncConfig); ok && !a.Status().Loaded && !a.closed() {
+			a.refresh()
+		}
+		err = layerReady(layerName, layer.(Configer))
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return conflictsError(cfg.Conflicts())
+}
+
+// layerReady Layer是否加载成功且内容不为空
+func layerReady(layerName string, layer Configer) error {
+	if a, ok := unwrapConfiger(layer).(*asyncConfig); ok {
+		if s := a.Status(); !s.Loaded {
+			if s.Err != nil {
+				return errors.Wrapf(ErrNotReady, "required layer[%s] not loaded: %v", layerName, s.Err)
+			}
+			return errors.Wrapf(ErrNotReady, "required layer[%s] not loaded", layerName)
+		}
+	}
+
+	switch root := layer.Get(RootKey).(type) {
+	case nil:
+	case map[string]interface{}:
+		if len(root) > 0 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return errors.Wrapf(ErrNotReady, "required layer[%s] is empty", layerName)
+}
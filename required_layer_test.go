@@ -0,0 +1,46 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredLayer(t *testing.T) {
+	ast := assert.New(t)
+
+	oldInterval := StartRetryInterval
+	StartRetryInterval = 10 * time.Millisecond
+	defer func() { StartRetryInterval = oldInterval }()
+
+	cfg := newConfig()
+	ast.Nil(cfg.AddRequiredLayer("base", NewMapConfig(map[string]interface{}{"a": 1})))
+	ast.Nil(cfg.WaitReady(context.Background()))
+
+	err := cfg.AddRequiredLayer("empty", NewMapConfig(map[string]interface{}{}))
+	ast.True(errors.Is(err, ErrNotReady))
+	ast.Contains(err.Error(), "layer[empty] is empty")
+	cfg.RemoveLayer("empty")
+	ast.Nil(cfg.WaitReady(context.Background()), "removed layer is not required")
+
+	asyncer := &rawAsyncer{NewMockAsyncer(false)}
+	err = cfg.AddRequiredLayer("remote", NewAsyncConfig(asyncer, "remote.json", time.Hour, false))
+	ast.True(errors.Is(err, ErrNotReady))
+	ast.Contains(err.Error(), "layer[remote] not loaded")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	err = cfg.WaitReady(ctx)
+	cancel()
+	ast.True(errors.Is(err, ErrNotReady))
+	ast.Contains(err.Error(), context.DeadlineExceeded.Error())
+
+	// 后端恢复后重新加载
+	asyncer.data.Store("remote.json", []byte(`{"b": 2}`))
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ast.Nil(cfg.WaitReady(ctx))
+	ast.EqualValues(2, cfg.Layer("remote").Int("b"))
+}
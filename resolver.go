@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ResolverRefreshInterval 解析结果的缓存时间，超过后下次连接时重新解析
+var ResolverRefreshInterval = 30 * time.Second
+
+// Resolver 解析后端的地址列表（host:port）
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// ResolverFunc 函数形式的Resolver
+type ResolverFunc func(ctx context.Context) ([]string, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// StaticResolver 固定的地址列表
+func StaticResolver(addrs ...string) Resolver {
+	return ResolverFunc(func(ctx context.Context) ([]string, error) {
+		return addrs, nil
+	})
+}
+
+// NewDNSSRVResolver 通过DNS SRV记录解析地址，参数同 net.LookupSRV，
+// 如 NewDNSSRVResolver("redis", "tcp", "example.com") 查询 _redis._tcp.example.com
+func NewDNSSRVResolver(service, proto, name string) Resolver {
+	return ResolverFunc(func(ctx context.Context) ([]string, error) {
+		_, srvs, err := net.DefaultResolver.LookupSRV(ctx, service, proto, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "lookup srv %s.%s.%s", service, proto, name)
+		}
+
+		addrs := make([]string, 0, len(srvs))
+		for _, srv := range srvs {
+			host := srv.Target
+			if n := len(host); n > 0 && host[n-1] == '.' {
+				host = host[:n-1]
+			}
+			addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+		}
+		return addrs, nil
+	})
+}
+
+// NewConsulResolver 通过consul catalog解析服务地址，consulAddr 如 "http://127.0.0.1:8500"
+//
+// opts: 访问consul的TLS及代理等选项，见 BackendOptions
+func NewConsulResolver(consulAddr, service string, opts ...BackendOption) Resolver {
+	client := backendHTTPClient(opts)
+	endpoint := fmt.Sprintf("%s/v1/catalog/service/%s", consulAddr, url.PathEscape(service))
+
+	return ResolverFunc(func(ctx context.Context) ([]string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "consul catalog %s", service)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("consul catalog %s: status %d", service, resp.StatusCode)
+		}
+
+		var nodes []struct {
+			Address        string
+			ServiceAddress string
+			ServicePort    int
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+			return nil, errors.Wrapf(err, "consul catalog %s", service)
+		}
+
+		addrs := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			host := node.ServiceAddress
+			if host == "" {
+				host = node.Address
+			}
+			addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(node.ServicePort)))
+		}
+		return addrs, nil
+	})
+}
+
+// endpointPool 缓存Resolver的解析结果，轮询连接，连接失败时重新解析
+type endpointPool struct {
+	resolver Resolver
+	dial     func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	sync.Mutex
+	addrs      []string
+	next       int
+	resolvedAt time.Time
+}
+
+func newEndpointPool(resolver Resolver) *endpointPool {
+	return &endpointPool{
+		resolver: resolver,
+		dial:     (&net.Dialer{}).DialContext,
+	}
+}
+
+func (p *endpointPool) endpoints(ctx context.Context, force bool) ([]string, error) {
+	p.Lock()
+	if !force && len(p.addrs) > 0 && _now().Sub(p.resolvedAt) < ResolverRefreshInterval {
+		defer p.Unlock()
+		return p.rotate(), nil
+	}
+	p.Unlock()
+
+	// 解析时不持有锁，避免慢的Resolver阻塞其他连接，或Resolver读取配置时死锁
+	addrs, err := p.resolver.Resolve(ctx)
+	if err == nil && len(addrs) == 0 {
+		err = errors.New("no endpoint resolved")
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if err != nil {
+		if len(p.addrs) > 0 {
+			// 解析失败时继续使用旧的地址
+			logger.Warnf("resolve endpoints err:%v, use last resolved %v", err, p.addrs)
+			return p.rotate(), nil
+		}
+		return nil, err
+	}
+
+	p.addrs = addrs
+	p.resolvedAt = _now()
+	return p.rotate(), nil
+}
+
+// rotate 从下一个地址开始的地址列表
+func (p *endpointPool) rotate() []string {
+	n := len(p.addrs)
+	ret := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ret = append(ret, p.addrs[(p.next+i)%n])
+	}
+	p.next = (p.next + 1) % n
+	return ret
+}
+
+// DialContext 依次尝试解析出的地址，全部失败时重新解析后再试一次，忽略传入的addr
+func (p *endpointPool) DialContext(ctx context.Context, network, _ string) (net.Conn, error) {
+	var lastErr error
+	for _, force := range []bool{false, true} {
+		addrs, err := p.endpoints(ctx, force)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			conn, err := p.dial(ctx, network, addr)
+			if err == nil {
+				return conn, nil
+			}
+			logger.Warnf("dial %s err:%v", addr, err)
+			lastErr = err
+		}
+	}
+
+	return nil, lastErr
+}
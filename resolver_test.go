@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsulResolver(t *testing.T) {
+	ast := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/catalog/service/redis" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"Address": "10.0.0.1", "ServiceAddress": "", "ServicePort": 6379},
+			{"Address": "10.0.0.2", "ServiceAddress": "10.0.1.2", "ServicePort": 6380}
+		]`))
+	}))
+	defer srv.Close()
+
+	addrs, err := NewConsulResolver(srv.URL, "redis").Resolve(context.Background())
+	ast.Nil(err)
+	ast.Equal([]string{"10.0.0.1:6379", "10.0.1.2:6380"}, addrs)
+
+	_, err = NewConsulResolver(srv.URL, "mysql").Resolve(context.Background())
+	ast.NotNil(err)
+}
+
+func TestEndpointPool(t *testing.T) {
+	ast := assert.New(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	ast.Nil(err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	var addrs []string
+	var resolveErr error
+	resolved := 0
+	pool := newEndpointPool(ResolverFunc(func(ctx context.Context) ([]string, error) {
+		resolved++
+		return addrs, resolveErr
+	}))
+
+	_, err = pool.DialContext(context.Background(), "tcp", "")
+	ast.NotNil(err, "no endpoint")
+
+	addrs = []string{"127.0.0.1:1", ln.Addr().String()}
+	conn, err := pool.DialContext(context.Background(), "tcp", "")
+	ast.Nil(err)
+	conn.Close()
+	resolved = 0
+
+	// 使用缓存的地址
+	conn, err = pool.DialContext(context.Background(), "tcp", "")
+	ast.Nil(err)
+	conn.Close()
+	ast.Equal(0, resolved)
+
+	// 全部连接失败时重新解析
+	pool.addrs = []string{"127.0.0.1:1"}
+	addrs = []string{ln.Addr().String()}
+	conn, err = pool.DialContext(context.Background(), "tcp", "")
+	ast.Nil(err)
+	conn.Close()
+	ast.Equal(1, resolved)
+
+	// 解析失败时使用旧的地址
+	resolveErr = errors.New("consul down")
+	pool.resolvedAt = time.Time{}
+	conn, err = pool.DialContext(context.Background(), "tcp", "")
+	ast.Nil(err)
+	conn.Close()
+
+	addrs, err = StaticResolver("a:1", "b:2").Resolve(context.Background())
+	ast.Nil(err)
+	ast.Equal([]string{"a:1", "b:2"}, addrs)
+}
+
+func TestEndpointPoolResolveUnlocked(t *testing.T) {
+	ast := assert.New(t)
+
+	var pool *endpointPool
+	pool = newEndpointPool(ResolverFunc(func(ctx context.Context) ([]string, error) {
+		// 解析时不持有锁，Resolver中可以访问pool
+		pool.Lock()
+		defer pool.Unlock()
+		return []string{"a:1"}, nil
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		addrs, err := pool.endpoints(context.Background(), true)
+		ast.Nil(err)
+		ast.Equal([]string{"a:1"}, addrs)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("resolve deadlocked")
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import "sync/atomic"
+
+// Resources 配置占用的后台资源，用于测试中检查泄漏，见 configtest.VerifyNoLeaks
+type Resources struct {
+	Configs       int64 // 未关闭的 AsyncConfig
+	Goroutines    int64 // AsyncConfig 的后台goroutine（监听、异步刷新）
+	Subscriptions int64 // 未关闭的 AsyncConfig 上未取消的 OnChange 回调
+}
+
+var _resources Resources
+
+// LiveResources 返回当前进程中所有配置占用的资源
+func LiveResources() Resources {
+	return Resources{
+		Configs:       atomic.LoadInt64(&_resources.Configs),
+		Goroutines:    atomic.LoadInt64(&_resources.Goroutines),
+		Subscriptions: atomic.LoadInt64(&_resources.Subscriptions),
+	}
+}
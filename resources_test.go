@@ -0,0 +1,31 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveResources(t *testing.T) {
+	ast := assert.New(t)
+
+	before := LiveResources()
+	cfg := NewAsyncConfig(NewMockAsyncer(true), "resources.json", 0, true)
+	cancel := cfg.OnChange(func() {})
+	cfg.OnChange(func() {})
+
+	now := LiveResources()
+	ast.Equal(before.Configs+1, now.Configs)
+	ast.Equal(before.Subscriptions+2, now.Subscriptions)
+	ast.Equal(before.Goroutines+1, now.Goroutines)
+
+	cancel()
+	cancel()
+	ast.Equal(before.Subscriptions+1, LiveResources().Subscriptions)
+
+	// 关闭时清空回调，之后不再订阅
+	ast.Nil(cfg.Stop(context.Background()))
+	cfg.OnChange(func() {})
+	ast.Equal(before, LiveResources())
+}
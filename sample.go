@@ -0,0 +1,34 @@
+package config
+
+import (
+	"hash/fnv"
+
+	"github.com/kot-w/goutils/itype"
+)
+
+// sampleBuckets 采样的精度
+const sampleBuckets = 1000000
+
+// Sample 读取keyPath的采样比例（0~1），返回unitID是否命中采样
+//
+// 同一keyPath下相同unitID的结果稳定，比例调大时已命中的unitID仍然命中，
+// 不同keyPath的采样相互独立，可用于日志/trace采样及功能的逐步放量
+func (h *ConfigHelper) Sample(keyPath string, unitID string) bool {
+	return sampled(itype.Float(h.Get(keyPath)), keyPath, unitID)
+}
+
+func sampled(ratio float64, keyPath string, unitID string) bool {
+	if ratio <= 0 {
+		return false
+	}
+	if ratio >= 1 {
+		return true
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(keyPath))
+	h.Write([]byte{0})
+	h.Write([]byte(unitID))
+
+	return h.Sum64()%sampleBuckets < uint64(ratio*sampleBuckets)
+}
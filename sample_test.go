@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"sample": map[string]interface{}{
+			"none": 0,
+			"all":  "1",
+			"half": 0.5,
+			"ten":  0.1,
+		},
+	})
+
+	ast.False(cfg.Sample("sample.none", "user1"))
+	ast.False(cfg.Sample("sample.not_exist", "user1"))
+	ast.True(cfg.Sample("sample.all", "user1"))
+
+	half, ten, both := 0, 0, 0
+	for i := 0; i < 10000; i++ {
+		id := strconv.Itoa(i)
+		h := cfg.Sample("sample.half", id)
+		ast.Equal(h, cfg.Sample("sample.half", id), "stable")
+		if h {
+			half++
+		}
+		if cfg.Sample("sample.ten", id) {
+			ten++
+			if h {
+				both++
+			}
+		}
+	}
+	ast.InDelta(5000, half, 300)
+	ast.InDelta(1000, ten, 150)
+	// 不同keyPath相互独立
+	ast.InDelta(500, both, 100)
+
+	// 比例调大时已命中的仍然命中
+	for i := 0; i < 1000; i++ {
+		id := strconv.Itoa(i)
+		if sampled(0.2, "k", id) {
+			ast.True(sampled(0.3, "k", id))
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package config
+
+// Schema 配置内容的约束
+//
+// 一个Schema同时负责校验配置内容以及填充默认值，实现可以基于CUE、JSON Schema等
+type Schema interface {
+	// Apply 校验value，返回填充默认值后的配置，value不满足约束时返回error
+	Apply(value interface{}) (interface{}, error)
+}
+
+// SchemaFunc 函数形式的Schema
+type SchemaFunc func(value interface{}) (interface{}, error)
+
+func (f SchemaFunc) Apply(value interface{}) (interface{}, error) {
+	return f(value)
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kot-w/goutils/object"
+	"github.com/pkg/errors"
+)
+
+// SecretProvider 外部密钥的解析
+//
+// 配置中形如 "vault:secret/data/db#password" 或 "sm://prod/db-pass" 的值，
+// 若scheme（"vault"、"sm"）已注册，刷新配置时会替换为解析出的密钥，密钥本身不需要存储在配置中
+type SecretProvider interface {
+	// Resolve 解析密钥引用，ref 为去掉 "scheme:" 或 "scheme://" 前缀的部分，
+	// ttl为解析结果的缓存时间，<= 0 表示不过期
+	Resolve(ref string) (value string, ttl time.Duration, err error)
+}
+
+// SecretProviderFunc 函数形式的SecretProvider
+type SecretProviderFunc func(ref string) (string, time.Duration, error)
+
+func (f SecretProviderFunc) Resolve(ref string) (string, time.Duration, error) {
+	return f(ref)
+}
+
+var (
+	_secretProviders sync.Map // scheme => SecretProvider
+
+	_secretCache = struct {
+		sync.Mutex
+		items map[string]secretCacheItem
+	}{
+		items: make(map[string]secretCacheItem),
+	}
+
+	secretRefRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):(.+)$`)
+)
+
+// secretRef 配置中的密钥引用及解析出的密钥
+type secretRef struct {
+	ref   string
+	value string
+}
+
+type secretCacheItem struct {
+	value    string
+	expireAt time.Time // zero: never expire
+}
+
+// RegisterSecretProvider 注册scheme对应的密钥解析器
+func RegisterSecretProvider(scheme string, p SecretProvider) {
+	_secretProviders.Store(scheme, p)
+}
+
+func hasSecretProviders() bool {
+	has := false
+	_secretProviders.Range(func(_, _ interface{}) bool {
+		has = true
+		return false
+	})
+	return has
+}
+
+// parseSecretRef 返回引用对应的解析器，值不是已注册scheme的引用时返回nil
+func parseSecretRef(s string) (SecretProvider, string) {
+	matches := secretRefRegexp.FindStringSubmatch(s)
+	if matches == nil {
+		return nil, ""
+	}
+
+	p, ok := _secretProviders.Load(matches[1])
+	if !ok {
+		return nil, ""
+	}
+
+	return p.(SecretProvider), strings.TrimPrefix(matches[2], "//")
+}
+
+// resolveSecret 解析密钥引用，优先使用未过期的缓存
+func resolveSecret(s string, p SecretProvider, ref string) (string, time.Time, error) {
+	now := _now()
+
+	_secretCache.Lock()
+	item, ok := _secretCache.items[s]
+	_secretCache.Unlock()
+	if ok && (item.expireAt.IsZero() || now.Before(item.expireAt)) {
+		return item.value, item.expireAt, nil
+	}
+
+	value, ttl, err := p.Resolve(ref)
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "resolve secret[%s]", s)
+	}
+
+	item = secretCacheItem{value: value}
+	if ttl > 0 {
+		item.expireAt = now.Add(ttl)
+	}
+
+	_secretCache.Lock()
+	_secretCache.items[s] = item
+	_secretCache.Unlock()
+
+	return item.value, item.expireAt, nil
+}
+
+// resolveSecrets 替换配置中所有的密钥引用
+//
+// 返回替换后的配置、密钥引用（keyPath => 引用）以及最早的过期时间（零值表示不过期）
+func resolveSecrets(val interface{}) (interface{}, map[string]secretRef, time.Time, error) {
+	var minExpire time.Time
+	refs := make(map[string]secretRef)
+	if !hasSecretProviders() {
+		return val, refs, minExpire, nil
+	}
+
+	var walk func(keyPath string, v interface{}) (interface{}, error)
+	walk = func(keyPath string, v interface{}) (interface{}, error) {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			for k, item := range vv {
+				resolved, err := walk(joinKeyPath(keyPath, k), item)
+				if err != nil {
+					return nil, err
+				}
+				vv[k] = resolved
+			}
+		case []interface{}:
+			for i, item := range vv {
+				resolved, err := walk(joinKeyPath(keyPath, strconv.Itoa(i)), item)
+				if err != nil {
+					return nil, err
+				}
+				vv[i] = resolved
+			}
+		case string:
+			p, ref := parseSecretRef(vv)
+			if p == nil {
+				return vv, nil
+			}
+			value, expireAt, err := resolveSecret(vv, p, ref)
+			if err != nil {
+				return nil, err
+			}
+			if !expireAt.IsZero() && (minExpire.IsZero() || expireAt.Before(minExpire)) {
+				minExpire = expireAt
+			}
+			refs[keyPath] = secretRef{ref: vv, value: value}
+			return value, nil
+		}
+		return v, nil
+	}
+
+	ret, err := walk("", val)
+	return ret, refs, minExpire, err
+}
+
+// restoreSecretRefs 将未修改的密钥恢复为引用，避免密钥被写入配置存储
+func restoreSecretRefs(val map[string]interface{}, refs map[string]secretRef) {
+	for keyPath, ref := range refs {
+		if current, ok := object.GetValue(val, keyPath); ok && current == ref.value {
+			setMapValue(val, keyPath, ref.ref)
+		}
+	}
+}
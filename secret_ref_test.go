@@ -0,0 +1,73 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretRef(t *testing.T) {
+	tb := time.Now()
+	tm := tb
+	originFun := _now
+	defer func() {
+		_now = originFun
+	}()
+	_now = func() time.Time {
+		return tm
+	}
+
+	ast := assert.New(t)
+
+	resolves := 0
+	RegisterSecretProvider("vault", SecretProviderFunc(func(ref string) (string, time.Duration, error) {
+		resolves++
+		if ref == "secret/data/db#password" {
+			return "p" + string(rune('0'+resolves)), time.Minute, nil
+		}
+		return "", 0, errors.Errorf("secret %s not found", ref)
+	}))
+	RegisterSecretProvider("sm", SecretProviderFunc(func(ref string) (string, time.Duration, error) {
+		return "sm:" + ref, 0, nil
+	}))
+	defer _secretProviders.Delete("vault")
+	defer _secretProviders.Delete("sm")
+
+	mock := NewMockAsyncer(false)
+	key := "secret_ref_key"
+	mock.data.Store(key, []byte(`{
+		"db": {"host": "example.com", "password": "vault:secret/data/db#password"},
+		"keys": ["sm://prod/api-key"],
+		"url": "http://example.com"
+	}`))
+	asyncer := rawAsyncer{mock}
+
+	cfg := NewAsyncConfig(asyncer, key, time.Hour, false)
+	ast.Equal("p1", cfg.String("db.password"))
+	ast.Equal("sm:prod/api-key", cfg.String("keys.0"))
+	ast.Equal("http://example.com", cfg.String("url"))
+
+	// cached
+	cfg2 := NewAsyncConfig(asyncer, key, time.Hour, false)
+	ast.Equal("p1", cfg2.String("db.password"))
+	ast.Equal(1, resolves)
+
+	// ttl expired
+	tm = tb.Add(2 * time.Hour)
+	ast.Equal("p2", cfg.String("db.password"))
+	ast.Equal(2, resolves)
+
+	// secrets never written to the config store
+	ast.Nil(cfg.Set("db.port", 3306))
+	v, _ := mock.data.Load(key)
+	ast.Contains(string(v.([]byte)), `"password":"vault:secret/data/db#password"`)
+	ast.Contains(string(v.([]byte)), `"sm://prod/api-key"`)
+	ast.Equal("p2", cfg.String("db.password"))
+
+	// resolve failed, keep old config
+	mock.data.Store(key, []byte(`{"db": {"password": "vault:not_exist"}}`))
+	tm = tb.Add(4 * time.Hour)
+	ast.Equal("p2", cfg.String("db.password"))
+}
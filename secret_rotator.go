@@ -0,0 +1,141 @@
+package config
+
+import (
+	"crypto/subtle"
+	"sync"
+	"time"
+
+	"github.com/kot-w/goutils/itype"
+)
+
+// SecretRotator 密钥（数据库密码、API Key等）轮换
+//
+// 配置中的密钥变化后，旧密钥在overlap时间内仍然有效，
+// 保证使用旧密钥建立的连接、签发的请求在轮换期间可以正常工作
+//
+//	r := config.NewSecretRotator(cfg, "db.password", 10*time.Minute)
+//	for _, password := range r.Secrets() { // 当前密钥在前
+//		if conn, err = connect(password); err == nil {
+//			break
+//		}
+//	}
+type SecretRotator struct {
+	cfg     Configer
+	keyPath string
+	overlap time.Duration
+
+	sync.RWMutex
+	current   string
+	previous  string
+	rotatedAt time.Time
+	onRotate  []func(current string, previous string)
+
+	notifier  chan struct{}
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewSecretRotator(cfg Configer, keyPath string, overlap time.Duration) *SecretRotator {
+	r := &SecretRotator{
+		cfg:      cfg,
+		keyPath:  keyPath,
+		overlap:  overlap,
+		current:  itype.String(cfg.Get(keyPath)),
+		notifier: make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+
+	cfg.Watch(r.notifier)
+	go r.watch()
+
+	return r
+}
+
+func (r *SecretRotator) watch() {
+	for {
+		select {
+		case <-r.notifier:
+			r.refresh()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *SecretRotator) refresh() {
+	secret := itype.String(r.cfg.Get(r.keyPath))
+
+	r.Lock()
+	if secret == r.current {
+		r.Unlock()
+		return
+	}
+	r.previous = r.current
+	r.current = secret
+	r.rotatedAt = _now()
+	previous, callbacks := r.previous, r.onRotate
+	r.Unlock()
+
+	logger.Infof("secret[%s] rotated", r.keyPath)
+	for _, fn := range callbacks {
+		fn(secret, previous)
+	}
+}
+
+// OnRotate 注册密钥轮换的回调
+func (r *SecretRotator) OnRotate(fn func(current string, previous string)) {
+	r.Lock()
+	defer r.Unlock()
+	r.onRotate = append(r.onRotate, fn)
+}
+
+// Current 返回当前密钥
+func (r *SecretRotator) Current() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.current
+}
+
+// Previous 返回上一个密钥，超过overlap时间后不再有效
+func (r *SecretRotator) Previous() (string, bool) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if r.previous == "" || _now().Sub(r.rotatedAt) > r.overlap {
+		return "", false
+	}
+
+	return r.previous, true
+}
+
+// Secrets 返回当前有效的所有密钥，当前密钥在前
+func (r *SecretRotator) Secrets() []string {
+	secrets := make([]string, 0, 2)
+	if current := r.Current(); current != "" {
+		secrets = append(secrets, current)
+	}
+	if previous, ok := r.Previous(); ok {
+		secrets = append(secrets, previous)
+	}
+
+	return secrets
+}
+
+// Match 判断secret是否为有效的密钥（常量时间比较）
+func (r *SecretRotator) Match(secret string) bool {
+	matched := false
+	for _, s := range r.Secrets() {
+		if subtle.ConstantTimeCompare([]byte(s), []byte(secret)) == 1 {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// Close 停止监控配置变化
+func (r *SecretRotator) Close() {
+	r.closeOnce.Do(func() {
+		close(r.quit)
+	})
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretRotator(t *testing.T) {
+	tb := time.Now()
+	tm := tb
+	originFun := _now
+	defer func() {
+		_now = originFun
+	}()
+	_now = func() time.Time {
+		return tm
+	}
+
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": "p1",
+		},
+	})
+
+	r := NewSecretRotator(cfg, "db.password", time.Minute)
+	defer r.Close()
+
+	rotated := make(chan string, 1)
+	r.OnRotate(func(current string, previous string) {
+		rotated <- previous + "->" + current
+	})
+
+	ast.Equal("p1", r.Current())
+	_, ok := r.Previous()
+	ast.False(ok)
+	ast.Equal([]string{"p1"}, r.Secrets())
+
+	ast.Nil(cfg.Set("db.password", "p2"))
+	ast.Equal("p1->p2", <-rotated)
+	ast.Equal("p2", r.Current())
+	previous, ok := r.Previous()
+	ast.True(ok)
+	ast.Equal("p1", previous)
+	ast.Equal([]string{"p2", "p1"}, r.Secrets())
+	ast.True(r.Match("p1"))
+	ast.True(r.Match("p2"))
+	ast.False(r.Match("p3"))
+
+	// overlap window passed
+	tm = tb.Add(time.Minute + time.Second)
+	_, ok = r.Previous()
+	ast.False(ok)
+	ast.False(r.Match("p1"))
+	ast.Equal([]string{"p2"}, r.Secrets())
+
+	// unrelated changes do not rotate
+	ast.Nil(cfg.Set("db.host", "example.com"))
+	time.Sleep(time.Millisecond)
+	ast.Equal("p2", r.Current())
+}
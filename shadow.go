@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+)
+
+// ShadowLogEvery 影子读取每N次不一致记录一次日志（首次不一致总会记录），<= 0 不记录
+var ShadowLogEvery int64 = 100
+
+// ShadowStats 影子读取的统计
+type ShadowStats struct {
+	Reads      int64
+	Mismatches int64
+}
+
+// ShadowConfig 迁移后端时对比新旧配置，每次读取同时查询primary及shadow，返回primary的值，
+// 不一致时上报 MetricShadowMismatches 并按 ShadowLogEvery 采样记录日志
+//
+// Set及Watch只作用于primary
+type ShadowConfig struct {
+	ConfigHelper
+}
+
+// NewShadowConfig name 用于指标及日志，区分不同的迁移
+//
+//	cfg := config.NewShadowConfig("redis-to-etcd", redisCfg, etcdCfg)
+func NewShadowConfig(name string, primary, shadow Configer) *ShadowConfig {
+	return &ShadowConfig{
+		ConfigHelper: ConfigHelper{
+			Configer: &shadowConfig{
+				name:    name,
+				primary: primary,
+				shadow:  shadow,
+			},
+		},
+	}
+}
+
+// Stats 返回影子读取的统计
+func (c *ShadowConfig) Stats() ShadowStats {
+	s := c.Configer.(*shadowConfig)
+	return ShadowStats{
+		Reads:      atomic.LoadInt64(&s.reads),
+		Mismatches: atomic.LoadInt64(&s.mismatches),
+	}
+}
+
+type shadowConfig struct {
+	name       string
+	primary    Configer
+	shadow     Configer
+	reads      int64
+	mismatches int64
+}
+
+func (s *shadowConfig) Get(keyPath string) interface{} {
+	val, _ := s.Lookup(keyPath)
+	return val
+}
+
+func (s *shadowConfig) Lookup(keyPath string) (interface{}, bool) {
+	val, found := lookupConfiger(s.primary, keyPath)
+	shadowVal, shadowFound := lookupConfiger(s.shadow, keyPath)
+
+	atomic.AddInt64(&s.reads, 1)
+	m := loadMetrics()
+	m.Counter(MetricShadowReads, 1, "name", s.name)
+	if found != shadowFound || !equalValues(val, shadowVal) {
+		n := atomic.AddInt64(&s.mismatches, 1)
+		m.Counter(MetricShadowMismatches, 1, "name", s.name)
+		if ShadowLogEvery > 0 && (n-1)%ShadowLogEvery == 0 {
+			logger.Warnf("shadow config[%s] path[%s] mismatch(%d): primary=%s shadow=%s",
+				s.name, keyPath, n, shadowValueString(val, found), shadowValueString(shadowVal, shadowFound))
+		}
+	}
+
+	return val, found
+}
+
+func (s *shadowConfig) Set(keyPath string, value interface{}) error {
+	return s.primary.Set(keyPath, value)
+}
+
+func (s *shadowConfig) Watch(notifier chan struct{}) {
+	s.primary.Watch(notifier)
+}
+
+// equalValues 按JSON比较，不同格式解析出的数字类型可能不同（如int与float64）
+func equalValues(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
+
+func shadowValueString(v interface{}, found bool) string {
+	if !found {
+		return "<not found>"
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return "<unmarshalable>"
+	}
+	return string(bs)
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowConfig(t *testing.T) {
+	ast := assert.New(t)
+
+	m := &testMetrics{counters: make(map[string]float64)}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	primary := NewMapConfig(map[string]interface{}{"a": 1, "b": "x", "c": map[string]interface{}{"d": 1}, "null": nil})
+	shadow := NewMapConfig(map[string]interface{}{"a": 1.0, "b": "y", "c": map[string]interface{}{"d": 1}})
+	cfg := NewShadowConfig("migrate", primary, shadow)
+
+	ast.EqualValues(1, cfg.Int("a"), "int and float64 are equal")
+	ast.Equal("x", cfg.String("b"), "serve primary value")
+	ast.EqualValues(1, cfg.Get("c.d"))
+	v, err := cfg.Lookup("null")
+	ast.Nil(err)
+	ast.Nil(v)
+
+	ast.Equal(ShadowStats{Reads: 4, Mismatches: 2}, cfg.Stats())
+	ast.EqualValues(4, m.counters[MetricShadowReads+"{name,migrate}"])
+	ast.EqualValues(2, m.counters[MetricShadowMismatches+"{name,migrate}"])
+
+	// 只写入primary
+	ast.Nil(cfg.Set("b", "y"))
+	ast.Equal("y", primary.String("b"))
+	cfg.Get("b")
+	ast.EqualValues(2, cfg.Stats().Mismatches)
+	ast.Nil(cfg.Set("e", 1))
+	ast.Nil(shadow.Get("e"))
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// signedBundleVersion 签名格式的版本，签名的内容包含该版本；版本2起签名包含目标key
+const signedBundleVersion = 2
+
+// SignedBundle 带签名的配置内容，由 configctl sign 在发布时生成
+//
+//	{"version": 2, "key": "app.json", "content": "<base64>", "provenance": {"commit": "9f2c1e0"}, "signed_at": "...", "key_id": "ci-2024", "signature": "<base64>"}
+//
+// 签名覆盖除signature之外的所有字段，Provenance 记录来源，如提交、PR及流水线；
+// Target 为发布到的后端key，避免签名的内容被重放到其他key
+type SignedBundle struct {
+	Target     string
+	Content    []byte
+	Provenance map[string]string
+	SignedAt   time.Time
+	KeyID      string
+	Signature  []byte
+}
+
+type signedBundleJSON struct {
+	Version    int               `json:"version"`
+	Target     string            `json:"key"`
+	Content    []byte            `json:"content"`
+	Provenance map[string]string `json:"provenance,omitempty"`
+	SignedAt   time.Time         `json:"signed_at"`
+	KeyID      string            `json:"key_id"`
+	Signature  []byte            `json:"signature,omitempty"`
+}
+
+// signingInput 签名的内容，json序列化map时按key排序，结果是确定的
+func (b *signedBundleJSON) signingInput() []byte {
+	unsigned := *b
+	unsigned.Signature = nil
+	data, _ := json.Marshal(unsigned)
+	return data
+}
+
+// SignBundle 使用ed25519私钥签名，返回发布到后端target的内容
+func SignBundle(target string, content []byte, key ed25519.PrivateKey, keyID string, provenance map[string]string, signedAt time.Time) ([]byte, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid ed25519 private key")
+	}
+	if target == "" {
+		return nil, errors.New("target key is required")
+	}
+
+	b := signedBundleJSON{
+		Version:    signedBundleVersion,
+		Target:     target,
+		Content:    content,
+		Provenance: provenance,
+		SignedAt:   signedAt.UTC(),
+		KeyID:      keyID,
+	}
+	b.Signature = ed25519.Sign(key, b.signingInput())
+
+	return json.Marshal(b)
+}
+
+// VerifyBundle 校验签名，keys 为 key_id => 公钥；未签名、key_id未知或签名不一致时返回 ErrSignature
+//
+// 不校验 Target 及 SignedAt，由调用方与目标key及已生效的签名时间比较，见 WithSignatureVerification
+func VerifyBundle(data []byte, keys map[string]ed25519.PublicKey) (*SignedBundle, error) {
+	var b signedBundleJSON
+	if err := json.Unmarshal(data, &b); err != nil || b.Version == 0 {
+		return nil, errors.Wrap(ErrSignature, "not a signed bundle")
+	}
+	if b.Version != signedBundleVersion {
+		return nil, errors.Wrapf(ErrSignature, "unsupported bundle version %d", b.Version)
+	}
+	if len(b.Signature) == 0 {
+		return nil, errors.Wrap(ErrSignature, "unsigned bundle")
+	}
+
+	key, ok := keys[b.KeyID]
+	if !ok {
+		return nil, errors.Wrapf(ErrSignature, "unknown key_id[%s]", b.KeyID)
+	}
+	if !ed25519.Verify(key, b.signingInput(), b.Signature) {
+		return nil, errors.Wrapf(ErrSignature, "key_id[%s] signature mismatch", b.KeyID)
+	}
+
+	return &SignedBundle{
+		Target:     b.Target,
+		Content:    b.Content,
+		Provenance: b.Provenance,
+		SignedAt:   b.SignedAt,
+		KeyID:      b.KeyID,
+		Signature:  b.Signature,
+	}, nil
+}
+
+// ParsePublicKeyPEM 解析PEM格式（PKIX）的ed25519公钥，见 configctl keygen
+func ParsePublicKeyPEM(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid public key: no PEM block")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid public key")
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("invalid public key: %T is not ed25519", key)
+	}
+	return pub, nil
+}
+
+// ParsePrivateKeyPEM 解析PEM格式（PKCS #8）的ed25519私钥，见 configctl keygen
+func ParsePrivateKeyPEM(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid private key: no PEM block")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid private key")
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("invalid private key: %T is not ed25519", key)
+	}
+	return priv, nil
+}
+
+// WithSignatureVerification 后端内容必须是 SignedBundle 且签名有效，否则本次刷新失败并保留旧值
+//
+// bundle的 Target 须为该配置的key，SignedAt 不能早于已生效的bundle，避免重放其他key或旧版本的内容
+//
+// keys 为 key_id => 公钥，轮换密钥时同时配置新旧公钥；配置只读，Set返回 ErrReadOnly
+//
+//	pub, _ := config.ParsePublicKeyPEM(pemBytes)
+//	cfg := config.NewAsyncConfig(asyncer, key, 0, false,
+//		config.WithSignatureVerification(map[string]ed25519.PublicKey{"ci-2024": pub}))
+func WithSignatureVerification(keys map[string]ed25519.PublicKey) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.signingKeys = keys
+	}
+}
+
+// verifySignature 返回签名的内容及签名时间，未启用签名校验时原样返回
+func (cfg *asyncConfig) verifySignature(rawMessage []byte) ([]byte, time.Time, error) {
+	if cfg.signingKeys == nil || len(rawMessage) == 0 {
+		return rawMessage, time.Time{}, nil
+	}
+
+	b, err := VerifyBundle(rawMessage, cfg.signingKeys)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "async config[%s]", cfg.asyncKey)
+	}
+	if b.Target != cfg.asyncKey {
+		return nil, time.Time{}, errors.Wrapf(ErrSignature, "async config[%s]: bundle signed for key[%s]", cfg.asyncKey, b.Target)
+	}
+	if b.SignedAt.Before(cfg.lastSignedAt) {
+		return nil, time.Time{}, errors.Wrapf(ErrSignature, "async config[%s]: bundle signed at %s is older than applied %s",
+			cfg.asyncKey, b.SignedAt.Format(time.RFC3339), cfg.lastSignedAt.Format(time.RFC3339))
+	}
+	logger.Debugf("async config[%s] signed by %s at %s, provenance:%v", cfg.asyncKey, b.KeyID, b.SignedAt, b.Provenance)
+	return b.Content, b.SignedAt, nil
+}
+
+// signedApplied 记录已生效的bundle的签名时间
+func (cfg *asyncConfig) signedApplied(signedAt time.Time) {
+	if signedAt.After(cfg.lastSignedAt) {
+		cfg.lastSignedAt = signedAt
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignBundle(t *testing.T) {
+	ast := assert.New(t)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	ast.Nil(err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	ast.Nil(err)
+
+	signedAt := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	data, err := SignBundle("app.json", []byte(`{"a": 1}`), priv, "ci", map[string]string{"commit": "9f2c1e0"}, signedAt)
+	ast.Nil(err)
+
+	b, err := VerifyBundle(data, map[string]ed25519.PublicKey{"ci": pub})
+	ast.Nil(err)
+	ast.Equal(`{"a": 1}`, string(b.Content))
+	ast.Equal("app.json", b.Target)
+	ast.Equal("ci", b.KeyID)
+	ast.Equal("9f2c1e0", b.Provenance["commit"])
+	ast.True(signedAt.Equal(b.SignedAt))
+
+	_, err = VerifyBundle(data, map[string]ed25519.PublicKey{"ci": otherPub})
+	ast.True(errors.Is(err, ErrSignature))
+	_, err = VerifyBundle(data, map[string]ed25519.PublicKey{"other": pub})
+	ast.True(errors.Is(err, ErrSignature))
+	_, err = VerifyBundle([]byte(`{"a": 1}`), map[string]ed25519.PublicKey{"ci": pub})
+	ast.True(errors.Is(err, ErrSignature))
+
+	// 修改签名覆盖的任一字段
+	var m map[string]interface{}
+	ast.Nil(json.Unmarshal(data, &m))
+	m["provenance"] = map[string]string{"commit": "0000000"}
+	tampered, _ := json.Marshal(m)
+	_, err = VerifyBundle(tampered, map[string]ed25519.PublicKey{"ci": pub})
+	ast.True(errors.Is(err, ErrSignature))
+	ast.Nil(json.Unmarshal(data, &m))
+	m["key"] = "other.json"
+	tampered, _ = json.Marshal(m)
+	_, err = VerifyBundle(tampered, map[string]ed25519.PublicKey{"ci": pub})
+	ast.True(errors.Is(err, ErrSignature))
+	delete(m, "signature")
+	unsigned, _ := json.Marshal(m)
+	_, err = VerifyBundle(unsigned, map[string]ed25519.PublicKey{"ci": pub})
+	ast.True(errors.Is(err, ErrSignature))
+
+	_, err = SignBundle("app.json", nil, nil, "ci", nil, signedAt)
+	ast.NotNil(err)
+	_, err = SignBundle("", nil, priv, "ci", nil, signedAt)
+	ast.NotNil(err)
+}
+
+func TestParseKeyPEM(t *testing.T) {
+	ast := assert.New(t)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	ast.Nil(err)
+	privDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	pubDER, _ := x509.MarshalPKIXPublicKey(pub)
+
+	parsedPriv, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}))
+	ast.Nil(err)
+	ast.Equal(priv, parsedPriv)
+	parsedPub, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	ast.Nil(err)
+	ast.Equal(pub, parsedPub)
+
+	_, err = ParsePublicKeyPEM([]byte("not pem"))
+	ast.NotNil(err)
+	_, err = ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	ast.NotNil(err)
+}
+
+func TestWithSignatureVerification(t *testing.T) {
+	ast := assert.New(t)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	ast.Nil(err)
+	signedAt := time.Now()
+	signAt := func(key, content string, at time.Time) []byte {
+		data, err := SignBundle(key, []byte(content), priv, "ci", nil, at)
+		ast.Nil(err)
+		return data
+	}
+	sign := func(content string) []byte {
+		signedAt = signedAt.Add(time.Second)
+		return signAt("app.json", content, signedAt)
+	}
+
+	asyncer := NewMockAsyncer(false)
+	asyncer.Set("app.json", sign(`{"a": 1}`))
+
+	cfg := NewAsyncConfig(asyncer, "app.json", 0, false,
+		WithSignatureVerification(map[string]ed25519.PublicKey{"ci": pub}))
+	ast.EqualValues(1, cfg.Int("a"))
+
+	// 未签名的内容不生效
+	asyncer.Set("app.json", []byte(`{"a": 2}`))
+	ast.True(errors.Is(cfg.Configer.(*asyncConfig).refresh(), ErrSignature))
+	ast.EqualValues(1, cfg.Int("a"))
+
+	old := sign(`{"a": 3}`)
+	asyncer.Set("app.json", old)
+	ast.Nil(cfg.Configer.(*asyncConfig).refresh())
+	ast.EqualValues(3, cfg.Int("a"))
+
+	// 其他key的bundle不能重放
+	asyncer.Set("app.json", signAt("other.json", `{"a": 5}`, signedAt.Add(time.Second)))
+	ast.True(errors.Is(cfg.Configer.(*asyncConfig).refresh(), ErrSignature))
+	ast.EqualValues(3, cfg.Int("a"))
+
+	// 不能回滚到更早签名的bundle
+	asyncer.Set("app.json", sign(`{"a": 6}`))
+	ast.Nil(cfg.Configer.(*asyncConfig).refresh())
+	asyncer.Set("app.json", old)
+	ast.True(errors.Is(cfg.Configer.(*asyncConfig).refresh(), ErrSignature))
+	ast.EqualValues(6, cfg.Int("a"))
+
+	ast.True(errors.Is(cfg.Set("a", 4), ErrReadOnly))
+	ast.EqualValues(6, cfg.Int("a"))
+}
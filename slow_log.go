@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// SlowStats 慢调用计数
+type SlowStats struct {
+	Gets      uint64 // 超过阈值的Get次数
+	Refreshes uint64 // 超过阈值的刷新次数
+}
+
+type slowLog struct {
+	getThreshold     time.Duration
+	refreshThreshold time.Duration
+	gets             uint64
+	refreshes        uint64
+}
+
+// WithSlowLog 记录耗时超过阈值的Get（通常由同步刷新导致）及刷新，阈值 <= 0 不记录
+//
+// 慢调用输出Warn日志（包含keyPath及调用位置）并上报 MetricSlowGets、MetricSlowRefreshes
+func WithSlowLog(getThreshold, refreshThreshold time.Duration) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.slow = &slowLog{
+			getThreshold:     getThreshold,
+			refreshThreshold: refreshThreshold,
+		}
+	}
+}
+
+// SlowStats 返回慢调用计数，未使用 WithSlowLog 时为零值
+func (c *AsyncConfig) SlowStats() SlowStats {
+	s := c.Configer.(*asyncConfig).slow
+	if s == nil {
+		return SlowStats{}
+	}
+	return SlowStats{
+		Gets:      atomic.LoadUint64(&s.gets),
+		Refreshes: atomic.LoadUint64(&s.refreshes),
+	}
+}
+
+// startGet 开始计时，未启用时返回零值
+func (s *slowLog) startGet() time.Time {
+	if s == nil || s.getThreshold <= 0 {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// startRefresh 开始计时，未启用时返回零值
+func (s *slowLog) startRefresh() time.Time {
+	if s == nil || s.refreshThreshold <= 0 {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+func (s *slowLog) get(start time.Time, asyncKey, keyPath string) {
+	if start.IsZero() {
+		return
+	}
+	if elapsed := time.Since(start); elapsed > s.getThreshold {
+		atomic.AddUint64(&s.gets, 1)
+		loadMetrics().Counter(MetricSlowGets, 1, "key", asyncKey)
+		logger.Warnf("slow get config[%s] path[%s] took %v, caller: %s", asyncKey, keyPath, elapsed, callerHint())
+	}
+}
+
+func (s *slowLog) refresh(start time.Time, asyncKey string) {
+	if start.IsZero() {
+		return
+	}
+	if elapsed := time.Since(start); elapsed > s.refreshThreshold {
+		atomic.AddUint64(&s.refreshes, 1)
+		loadMetrics().Counter(MetricSlowRefreshes, 1, "key", asyncKey)
+		logger.Warnf("slow refresh config[%s] took %v", asyncKey, elapsed)
+	}
+}
+
+// callerHint 本包外的前几层调用位置
+func callerHint() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	hints := make([]string, 0, 3)
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "github.com/kot-w/config.") && !strings.HasSuffix(frame.File, "_test.go")
+		if !internal {
+			hints = append(hints, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+			if len(hints) == cap(hints) {
+				break
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.Join(hints, " <- ")
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMetrics struct {
+	sync.Mutex
+	counters map[string]float64
+	observed map[string][]float64
+	gauges   map[string]float64
+}
+
+func (m *testMetrics) Counter(name string, value float64, labels ...string) {
+	m.Lock()
+	defer m.Unlock()
+	m.counters[name+"{"+strings.Join(labels, ",")+"}"] += value
+}
+
+func (m *testMetrics) Gauge(name string, value float64, labels ...string) {
+	m.Lock()
+	defer m.Unlock()
+	if m.gauges == nil {
+		m.gauges = make(map[string]float64)
+	}
+	m.gauges[name+"{"+strings.Join(labels, ",")+"}"] = value
+}
+
+func (m *testMetrics) Observe(name string, value float64, labels ...string) {
+	m.Lock()
+	defer m.Unlock()
+	if m.observed == nil {
+		m.observed = make(map[string][]float64)
+	}
+	key := name + "{" + strings.Join(labels, ",") + "}"
+	m.observed[key] = append(m.observed[key], value)
+}
+
+func TestSlowLog(t *testing.T) {
+	ast := assert.New(t)
+
+	m := &testMetrics{counters: make(map[string]float64)}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	mock := NewMockAsyncer(false)
+	mock.Set("slow.json", []byte(`{"a": 1}`))
+	asyncer := &slowAsyncer{MockAsyncer: mock}
+
+	// 缓存过期时同步刷新
+	cfg := NewAsyncConfig(asyncer, "slow.json", time.Nanosecond, false, WithSlowLog(10*time.Millisecond, 10*time.Millisecond))
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a"))
+
+	stats := cfg.SlowStats()
+	ast.EqualValues(1, stats.Gets)
+	ast.EqualValues(2, stats.Refreshes)
+	m.Lock()
+	ast.EqualValues(1, m.counters[MetricSlowGets+"{key,slow.json}"])
+	ast.EqualValues(2, m.counters[MetricSlowRefreshes+"{key,slow.json}"])
+	m.Unlock()
+
+	// 未超过阈值
+	cfg = NewAsyncConfig(asyncer, "slow.json", time.Hour, false, WithSlowLog(10*time.Millisecond, time.Second))
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.Equal(SlowStats{}, cfg.SlowStats())
+
+	cfg = NewAsyncConfig(asyncer, "slow.json", time.Nanosecond, false)
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.Equal(SlowStats{}, cfg.SlowStats())
+
+	ast.Contains(callerHint(), "TestSlowLog")
+}
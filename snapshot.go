@@ -0,0 +1,153 @@
+package config
+
+import (
+	"context"
+	"net/http"
+)
+
+// SnapshotConfig 配置在某一时刻的只读快照，同一请求内多次读取的结果一致
+//
+// 配置在刷新及Set时整体替换配置树，快照不复制配置树，
+// 非同步模式的MapConfig（见 NewMapConfig）除外
+type SnapshotConfig struct {
+	ConfigHelper
+}
+
+// Snapshot 创建快照，分层配置按查询的Layer顺序保存各层配置
+func Snapshot(cfg Configer) *SnapshotConfig {
+	return &SnapshotConfig{
+		ConfigHelper: ConfigHelper{
+			Configer: &snapshotConfig{layers: snapshotLayers(cfg)},
+		},
+	}
+}
+
+// snapshotLayer 快照中的一层配置
+type snapshotLayer struct {
+	name   string // Layer名称，非分层配置为空
+	source string // 配置来源，见 KeyOrigin
+	root   interface{}
+}
+
+// layerSnapshotter 分层配置，返回各层的配置树
+type layerSnapshotter interface {
+	snapshotLayers() []snapshotLayer
+}
+
+// unwrapConfiger 去除 ConfigHelper 的包装
+func unwrapConfiger(c Configer) Configer {
+	for {
+		h, ok := c.(interface{ configer() Configer })
+		if !ok {
+			return c
+		}
+		c = h.configer()
+	}
+}
+
+func snapshotLayers(c Configer) []snapshotLayer {
+	c = unwrapConfiger(c)
+
+	if l, ok := c.(layerSnapshotter); ok {
+		return l.snapshotLayers()
+	}
+	return []snapshotLayer{{source: configSource(c), root: c.Get(RootKey)}}
+}
+
+func (h *ConfigHelper) configer() Configer {
+	return h.Configer
+}
+
+func (cfg *defaultConfig) snapshotLayers(layerNames ...string) []snapshotLayer {
+	if len(layerNames) == 0 {
+		layerNames = cfg.defaultLayerNames.Load().([]string)
+	}
+
+	layers := make([]snapshotLayer, 0, len(layerNames))
+	for _, layerName := range layerNames {
+		layer, ok := cfg.layers.Load(layerName)
+		if !ok {
+			continue
+		}
+		for _, sub := range snapshotLayers(layer.(Configer)) {
+			// 嵌套的分层配置
+			if sub.name != "" {
+				sub.name = layerName + "/" + sub.name
+			} else {
+				sub.name = layerName
+			}
+			layers = append(layers, sub)
+		}
+	}
+	return layers
+}
+
+func (c *defaultConfiger) snapshotLayers() []snapshotLayer {
+	return c.cfg.snapshotLayers()
+}
+
+func (p *layerConfigProxy) snapshotLayers() []snapshotLayer {
+	return p.cfg.snapshotLayers(p.layerNames...)
+}
+
+type snapshotConfig struct {
+	layers []snapshotLayer
+}
+
+func (s *snapshotConfig) snapshotLayers() []snapshotLayer {
+	return s.layers
+}
+
+func (s *snapshotConfig) Get(keyPath string) interface{} {
+	val, _ := s.Lookup(keyPath)
+	return val
+}
+
+// Lookup 与分层配置一致，依次查询各层，值为null时继续查询
+func (s *snapshotConfig) Lookup(keyPath string) (interface{}, bool) {
+	val, found, _ := s.lookupLayer(keyPath)
+	return val, found
+}
+
+// lookupLayer 同 Lookup，并返回提供值的层的下标，未找到或值为null时为-1
+func (s *snapshotConfig) lookupLayer(keyPath string) (interface{}, bool, int) {
+	found := false
+	for i, layer := range s.layers {
+		v, f := lookupValue(layer.root, keyPath)
+		if v != nil {
+			return v, true, i
+		}
+		found = found || f
+	}
+	return nil, found, -1
+}
+
+func (s *snapshotConfig) Set(keyPath string, value interface{}) error {
+	return ErrReadOnly
+}
+
+func (s *snapshotConfig) Watch(notifier chan struct{}) {
+}
+
+type snapshotKey struct{}
+
+// ContextWithSnapshot 将快照保存到ctx中
+func ContextWithSnapshot(ctx context.Context, snap *SnapshotConfig) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, snap)
+}
+
+// SnapshotFromContext 返回ctx中的快照，不存在时返回nil
+func SnapshotFromContext(ctx context.Context) *SnapshotConfig {
+	snap, _ := ctx.Value(snapshotKey{}).(*SnapshotConfig)
+	return snap
+}
+
+// SnapshotMiddleware 为每个请求创建配置快照，通过 SnapshotFromContext 读取
+func SnapshotMiddleware(cfg Configer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := ContextWithSnapshot(r.Context(), Snapshot(cfg))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"a": 1})
+	snap := Snapshot(cfg)
+	ast.Nil(cfg.Set("a", 2))
+	ast.EqualValues(1, snap.Get("a"), "isolated from later changes")
+	ast.EqualValues(2, Snapshot(cfg).Get("a"))
+	ast.NotNil(snap.Set("a", 3))
+
+	// layered
+	layered := newConfig()
+	layered.AddLayer("l1", NewMapConfig(map[string]interface{}{"a": nil, "b": 1}))
+	layered.AddLayer("l2", NewMapConfig(map[string]interface{}{"a": 2, "c": 3}))
+	snap = Snapshot(layered.Layer("l1", "l2"))
+	layered.Set2("c", 4, "l2")
+	ast.EqualValues(2, snap.Get("a"))
+	ast.EqualValues(1, snap.Get("b"))
+	ast.EqualValues(3, snap.Get("c"))
+	_, err := snap.Lookup("d")
+	ast.NotNil(err)
+
+	layered.AddDefaultLayerName("l2")
+	ast.EqualValues(4, Snapshot(layered).Get("c"))
+
+	// middleware
+	var got *SnapshotConfig
+	handler := SnapshotMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = SnapshotFromContext(r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if ast.NotNil(got) {
+		ast.EqualValues(2, got.Get("a"))
+	}
+	ast.Nil(SnapshotFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// EncodingGzip 内置的gzip压缩，zstd 见 contrib/zstdcompress
+const EncodingGzip = "gzip"
+
+var (
+	// SocketEncodings 客户端可接受的压缩算法，按优先级排列，未注册的被忽略
+	SocketEncodings = []string{"zstd", EncodingGzip}
+	// SocketCompressMinSize 服务端只压缩不小于该长度的值
+	SocketCompressMinSize = 1024
+)
+
+// Compressor socket协议中值的压缩算法，见 RegisterCompressor
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	// Decompress 解压后超过 SocketMaxFrameSize 时返回错误
+	Decompress(data []byte) ([]byte, error)
+}
+
+var _compressors sync.Map // name => Compressor
+
+func init() {
+	RegisterCompressor(EncodingGzip, gzipCompressor{})
+}
+
+// RegisterCompressor 注册压缩算法，客户端与服务端均需注册，同名的覆盖
+func RegisterCompressor(name string, c Compressor) {
+	_compressors.Store(name, c)
+}
+
+func compressorByName(name string) (Compressor, bool) {
+	c, ok := _compressors.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return c.(Compressor), true
+}
+
+// acceptedEncodings SocketEncodings 中已注册的
+func acceptedEncodings() []string {
+	var encodings []string
+	for _, name := range SocketEncodings {
+		if _, ok := compressorByName(name); ok {
+			encodings = append(encodings, name)
+		}
+	}
+	return encodings
+}
+
+// registeredEncodings 服务端支持的压缩算法，见 SocketOpHello
+func registeredEncodings() []string {
+	var encodings []string
+	_compressors.Range(func(name, _ interface{}) bool {
+		encodings = append(encodings, name.(string))
+		return true
+	})
+	return encodings
+}
+
+// valueHash 值的版本，客户端在请求中携带以避免重复传输未变化的值
+func valueHash(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:16])
+}
+
+// encodeValue 使用客户端可接受的第一个已注册的算法压缩值，值较小或压缩无收益时不压缩
+func encodeValue(resp *SocketResponse, value []byte, encodings []string) {
+	resp.Value, resp.Data, resp.Encoding = value, nil, ""
+	if len(value) < SocketCompressMinSize {
+		return
+	}
+
+	for _, name := range encodings {
+		c, ok := compressorByName(name)
+		if !ok {
+			continue
+		}
+		data, err := c.Compress(value)
+		if err != nil {
+			logger.Warnf("config socket compress by %s err:%v", name, err)
+			return
+		}
+		if len(data) < len(value) {
+			resp.Value, resp.Data, resp.Encoding = nil, data, name
+		}
+		return
+	}
+}
+
+// decodeValue encodeValue 的逆操作
+func decodeValue(resp *SocketResponse) ([]byte, error) {
+	if resp.Encoding == "" {
+		return resp.Value, nil
+	}
+
+	c, ok := compressorByName(resp.Encoding)
+	if !ok {
+		return nil, errors.Errorf("unsupported encoding: %s", resp.Encoding)
+	}
+	value, err := c.Decompress(resp.Data)
+	return value, errors.Wrapf(err, "decompress by %s", resp.Encoding)
+}
+
+// encodeCache 一轮推送中相同的值对相同的可接受算法只压缩一次
+type encodeCache map[string]SocketResponse
+
+func (e encodeCache) encode(resp *SocketResponse, value []byte, encodings []string) {
+	key := resp.Hash + "/" + strings.Join(encodings, ",")
+	if cached, ok := e[key]; ok {
+		resp.Value, resp.Data, resp.Encoding = cached.Value, cached.Data, cached.Encoding
+		return
+	}
+	encodeValue(resp, value, encodings)
+	e[key] = *resp
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	return gunzip(data, int(SocketMaxFrameSize))
+}
+
+// gunzip 解压后超过limit时返回错误
+func gunzip(data []byte, limit int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	value, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(value) > limit {
+		return nil, errors.Errorf("decompressed value too large: > %d", limit)
+	}
+	return value, nil
+}
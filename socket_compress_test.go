@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzipCompressor(t *testing.T) {
+	ast := assert.New(t)
+
+	value := bytes.Repeat([]byte(`{"host":"example.com"}`), 100)
+	data, err := gzipCompressor{}.Compress(value)
+	ast.Nil(err)
+	ast.Less(len(data), len(value))
+
+	decompressed, err := gzipCompressor{}.Decompress(data)
+	ast.Nil(err)
+	ast.Equal(value, decompressed)
+
+	_, err = gzipCompressor{}.Decompress([]byte("not gzip"))
+	ast.NotNil(err)
+
+	_, err = gunzip(data, 100)
+	ast.NotNil(err, "too large")
+}
+
+func TestEncodeValue(t *testing.T) {
+	ast := assert.New(t)
+
+	small := []byte(`"example.com"`)
+	large := bytes.Repeat([]byte(`{"host":"example.com"}`), 100)
+
+	var resp SocketResponse
+	encodeValue(&resp, small, []string{EncodingGzip})
+	ast.Equal(small, []byte(resp.Value))
+	ast.Empty(resp.Encoding)
+
+	// 未注册的算法被忽略
+	encodeValue(&resp, large, []string{"br", EncodingGzip})
+	ast.Nil(resp.Value)
+	ast.Equal(EncodingGzip, resp.Encoding)
+	value, err := decodeValue(&resp)
+	ast.Nil(err)
+	ast.Equal(large, value)
+
+	encodeValue(&resp, large, nil)
+	ast.Equal(large, []byte(resp.Value))
+	ast.Empty(resp.Encoding)
+
+	_, err = decodeValue(&SocketResponse{Encoding: "br", Data: []byte("x")})
+	ast.NotNil(err)
+
+	// 相同的值只压缩一次
+	cache := make(encodeCache)
+	first := SocketResponse{ID: 1, Hash: valueHash(large)}
+	cache.encode(&first, large, []string{EncodingGzip})
+	second := SocketResponse{ID: 2, Hash: valueHash(large)}
+	cache.encode(&second, large, []string{EncodingGzip})
+	ast.EqualValues(2, second.ID)
+	ast.Equal(first.Data, second.Data)
+	ast.Len(cache, 1)
+
+	ast.Equal(valueHash(large), valueHash(append([]byte(nil), large...)))
+	ast.NotEqual(valueHash(large), valueHash(small))
+	ast.Contains(acceptedEncodings(), EncodingGzip)
+	ast.Contains(registeredEncodings(), EncodingGzip)
+}
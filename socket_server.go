@@ -0,0 +1,401 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SocketMaxFrameSize 单个消息的最大长度
+var SocketMaxFrameSize uint32 = 16 << 20
+
+// SocketWriteTimeout 服务端写入一帧的超时时间，超时的连接会被关闭，避免阻塞对该连接的其他推送
+var SocketWriteTimeout = 5 * time.Second
+
+// SocketProtocolVersion 当前的协议版本：1 只支持get及watch，2 支持hello，
+// 3 支持值的压缩及按版本hash跳过未变化的值
+const SocketProtocolVersion = 3
+
+const (
+	SocketOpGet   = "get"
+	SocketOpWatch = "watch"
+	SocketOpHello = "hello" // 协商协议版本及支持的操作，版本1的服务端返回 unsupported op
+)
+
+// socketOps 服务端支持的操作，见 SocketOpHello
+var socketOps = []string{SocketOpGet, SocketOpWatch, SocketOpHello}
+
+// SocketRequest unix socket协议的请求
+type SocketRequest struct {
+	ID  uint64 `json:"id"`
+	Op  string `json:"op"`
+	Key string `json:"key"`
+
+	Source string `json:"source,omitempty"` // 后端key，仅代理模式，见 NewAgentServer
+
+	Version int `json:"version,omitempty"` // hello请求时为客户端支持的最高版本
+
+	Encodings []string `json:"encodings,omitempty"` // 可接受的压缩算法，见 SocketEncodings
+	Hash      string   `json:"hash,omitempty"`      // 客户端已有值的版本，与服务端相同时不返回值
+}
+
+// SocketResponse unix socket协议的响应，watch请求在每次值变化时以相同的id推送
+type SocketResponse struct {
+	ID    uint64          `json:"id"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+
+	Version   int      `json:"version,omitempty"`   // hello响应的协商版本
+	Ops       []string `json:"ops,omitempty"`       // hello响应时服务端支持的操作
+	Encodings []string `json:"encodings,omitempty"` // hello响应时服务端支持的压缩算法
+
+	Data        []byte `json:"data,omitempty"`         // 压缩后的值，此时Value为空
+	Encoding    string `json:"encoding,omitempty"`     // Data的压缩算法
+	Hash        string `json:"hash,omitempty"`         // 值的版本
+	NotModified bool   `json:"not_modified,omitempty"` // 值与请求的Hash相同，未返回值
+}
+
+// writeFrame 写入一帧：4字节大端长度 + json
+func writeFrame(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(len(body)))
+	_, err = w.Write(append(buf, body...))
+	return err
+}
+
+func readFrame(r io.Reader, v interface{}) error {
+	var head [4]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return err
+	}
+
+	n := binary.BigEndian.Uint32(head[:])
+	if n > SocketMaxFrameSize {
+		return errors.Errorf("frame too large: %d", n)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// SocketServer 通过unix socket以只读方式提供配置，供sidecar或脚本读取同一份生效的配置
+//
+// 协议：每帧为 4字节大端长度 + json，请求为 SocketRequest，响应为 SocketResponse
+//
+//	{"id": 1, "op": "get", "key": "db.host"}
+//	{"id": 2, "op": "watch", "key": "db"}
+//
+// 客户端可先发送 {"op": "hello", "version": 2} 协商版本，不发送时按版本1处理
+type SocketServer struct {
+	cfg   Configer
+	agent *socketAgent // 代理模式，见 NewAgentServer
+	ln    net.Listener
+
+	authz   Authorizer
+	subject func(conn net.Conn) string
+
+	sync.Mutex
+	conns map[*socketConn]struct{}
+
+	notifier  chan struct{}
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSocketServer 监听unix socket，path已存在时会先删除
+func NewSocketServer(cfg Configer, path string) (*SocketServer, error) {
+	s, err := listenSocket(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cfg = cfg
+	cfg.Watch(s.notifier)
+	return s, nil
+}
+
+func listenSocket(path string) (*SocketServer, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SocketServer{
+		ln:       ln,
+		conns:    make(map[*socketConn]struct{}),
+		notifier: make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+	go s.watch()
+
+	logger.Infof("config socket server listen on %s", path)
+
+	return s, nil
+}
+
+// SetAuthorizer 以 subject(conn) 为调用方检查每个get及watch请求读取的权限，需在Serve之前调用
+//
+// 代理模式（见 NewAgentServer）下检查的keyPath为 "后端key:keyPath"，如 "app.yaml:"（整个配置）、
+// "app.yaml:db.host"，可按后端key限制读取；拒绝的后端key不会被加载
+//
+// 拒绝时响应的error为 AuthzError 的信息
+func (s *SocketServer) SetAuthorizer(authz Authorizer, subject func(conn net.Conn) string) {
+	s.authz = authz
+	s.subject = subject
+}
+
+// Serve 处理连接，直到Close
+func (s *SocketServer) Serve() error {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		c := &socketConn{
+			server:  s,
+			conn:    conn,
+			watches: make(map[socketWatchKey]*socketWatch),
+		}
+		if s.subject != nil {
+			c.subject = s.subject(conn)
+		}
+		s.Lock()
+		s.conns[c] = struct{}{}
+		s.Unlock()
+
+		go c.serve()
+	}
+}
+
+func (s *SocketServer) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.quit)
+		err = s.ln.Close()
+
+		s.Lock()
+		for c := range s.conns {
+			c.conn.Close()
+		}
+		s.Unlock()
+
+		if s.agent != nil {
+			s.agent.close()
+		}
+	})
+
+	return err
+}
+
+func (s *SocketServer) watch() {
+	for {
+		select {
+		case <-s.notifier:
+			s.Lock()
+			conns := make([]*socketConn, 0, len(s.conns))
+			for c := range s.conns {
+				conns = append(conns, c)
+			}
+			s.Unlock()
+
+			cache := make(encodeCache)
+			for _, c := range conns {
+				c.push(cache)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *SocketServer) value(source, key string) (json.RawMessage, error) {
+	cfg := s.cfg
+	if s.agent != nil {
+		if source == "" {
+			return nil, errors.New("source is required by agent")
+		}
+		var err error
+		if cfg, err = s.agent.config(source); err != nil {
+			return nil, err
+		}
+	} else if source != "" {
+		return nil, errors.New("source is only supported by agent")
+	}
+
+	return json.Marshal(cfg.Get(key))
+}
+
+// resource 授权检查的keyPath，代理模式下包含后端key
+func (s *SocketServer) resource(req SocketRequest) string {
+	if s.agent != nil {
+		return req.Source + ":" + req.Key
+	}
+	return req.Key
+}
+
+type socketWatchKey struct {
+	source string
+	key    string
+}
+
+type socketWatch struct {
+	id        uint64
+	last      []byte
+	encodings []string
+}
+
+type socketConn struct {
+	server  *SocketServer
+	conn    net.Conn
+	subject string
+
+	sync.Mutex
+	watches map[socketWatchKey]*socketWatch
+}
+
+func (c *socketConn) serve() {
+	defer func() {
+		c.conn.Close()
+		c.server.Lock()
+		delete(c.server.conns, c)
+		c.server.Unlock()
+
+		if agent := c.server.agent; agent != nil {
+			c.Lock()
+			for key := range c.watches {
+				agent.release(key.source)
+			}
+			c.Unlock()
+		}
+	}()
+
+	r := bufio.NewReader(c.conn)
+	for {
+		var req SocketRequest
+		if err := readFrame(r, &req); err != nil {
+			if err != io.EOF {
+				logger.Debugf("config socket read err:%v", err)
+			}
+			return
+		}
+
+		resp := SocketResponse{ID: req.ID}
+		if req.Op == SocketOpHello {
+			resp.Version, resp.Ops, resp.Encodings = negotiateVersion(req.Version), socketOps, registeredEncodings()
+			if err := c.write(resp); err != nil {
+				logger.Debugf("config socket write err:%v", err)
+				return
+			}
+			continue
+		}
+
+		err := authorize(c.server.authz, c.subject, ActionRead, c.server.resource(req))
+		var value json.RawMessage
+		if err == nil {
+			value, err = c.server.value(req.Source, req.Key)
+		}
+		switch {
+		case err != nil:
+			resp.Error = err.Error()
+		case req.Op == SocketOpGet || req.Op == SocketOpWatch:
+			resp.Hash = valueHash(value)
+			if req.Hash == resp.Hash {
+				resp.NotModified = true
+			} else {
+				encodeValue(&resp, value, req.Encodings)
+			}
+			if req.Op == SocketOpWatch {
+				c.addWatch(req, value)
+			}
+		default:
+			resp.Error = "unsupported op: " + req.Op
+		}
+
+		if err := c.write(resp); err != nil {
+			logger.Debugf("config socket write err:%v", err)
+			return
+		}
+	}
+}
+
+// addWatch 代理模式下watch中的后端key不会因空闲被关闭
+func (c *socketConn) addWatch(req SocketRequest, value json.RawMessage) {
+	c.Lock()
+	defer c.Unlock()
+
+	key := socketWatchKey{source: req.Source, key: req.Key}
+	if _, ok := c.watches[key]; !ok && c.server.agent != nil {
+		c.server.agent.retain(req.Source)
+	}
+	c.watches[key] = &socketWatch{id: req.ID, last: value, encodings: req.Encodings}
+}
+
+// negotiateVersion 客户端与服务端均支持的最高版本，客户端未指定时为服务端的版本
+func negotiateVersion(client int) int {
+	if client <= 0 || client > SocketProtocolVersion {
+		return SocketProtocolVersion
+	}
+	return client
+}
+
+func (c *socketConn) write(resp SocketResponse) error {
+	c.Lock()
+	defer c.Unlock()
+	return c.writeLocked(resp)
+}
+
+func (c *socketConn) writeLocked(resp SocketResponse) error {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(SocketWriteTimeout)); err != nil {
+		return err
+	}
+	return writeFrame(c.conn, resp)
+}
+
+// push 推送发生变化的watch
+func (c *socketConn) push(cache encodeCache) {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, w := range c.watches {
+		value, err := c.server.value(key.source, key.key)
+		if err != nil || bytes.Equal(value, w.last) {
+			continue
+		}
+		w.last = value
+
+		resp := SocketResponse{ID: w.id, Hash: valueHash(value)}
+		cache.encode(&resp, value, w.encodings)
+		if err := c.writeLocked(resp); err != nil {
+			logger.Debugf("config socket push err:%v", err)
+			c.conn.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSocketServer(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host": "example.com",
+			"port": 3306,
+		},
+	})
+
+	path := filepath.Join(t.TempDir(), "config.sock")
+	server, err := NewSocketServer(cfg, path)
+	ast.Nil(err)
+	defer server.Close()
+	go server.Serve()
+
+	asyncer := NewSocketAsyncer(path)
+	ast.JSONEq(`"example.com"`, string(asyncer.Get("db.host")))
+	ast.Nil(asyncer.Get("not_exist"))
+	ast.NotNil(asyncer.Set("db", []byte(`{}`)), "read-only")
+
+	dbCfg := NewAsyncConfig(asyncer, "db", time.Hour, false)
+	ast.Equal("example.com", dbCfg.String("host"))
+	ast.EqualValues(3306, dbCfg.Int("port"))
+
+	// 推送变化
+	ast.Nil(cfg.Set("db.host", "db.example.com"))
+	ast.Eventually(func() bool {
+		return dbCfg.String("host") == "db.example.com"
+	}, time.Second, 5*time.Millisecond)
+
+	// 不支持的操作
+	conn, err := net.Dial("unix", path)
+	ast.Nil(err)
+	defer conn.Close()
+	ast.Nil(writeFrame(conn, SocketRequest{ID: 7, Op: "set", Key: "db"}))
+	var resp SocketResponse
+	ast.Nil(readFrame(bufio.NewReader(conn), &resp))
+	ast.EqualValues(7, resp.ID)
+	ast.Equal("unsupported op: set", resp.Error)
+}
+
+func TestSocketNegotiation(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"db": map[string]interface{}{"host": "example.com"}})
+	path := filepath.Join(t.TempDir(), "config.sock")
+	server, err := NewSocketServer(cfg, path)
+	ast.Nil(err)
+	defer server.Close()
+	go server.Serve()
+
+	conn, err := net.Dial("unix", path)
+	ast.Nil(err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for client, want := range map[int]int{0: SocketProtocolVersion, 1: 1, SocketProtocolVersion + 1: SocketProtocolVersion} {
+		ast.Nil(writeFrame(conn, SocketRequest{ID: 1, Op: SocketOpHello, Version: client}))
+		var resp SocketResponse
+		ast.Nil(readFrame(r, &resp))
+		ast.Equal(want, resp.Version)
+		ast.Equal(socketOps, resp.Ops)
+	}
+
+	// 新的客户端连接新的服务端使用推送
+	asyncer := NewSocketAsyncer(path)
+	ch := asyncer.Watch("db")
+	<-ch
+	ast.Equal(SocketProtocolVersion, asyncer.ServerVersion())
+}
+
+// oldSocketServer 只支持get的旧服务端
+func oldSocketServer(t *testing.T, path string, value func() string) {
+	ln, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					var req SocketRequest
+					if err := readFrame(r, &req); err != nil {
+						return
+					}
+					resp := SocketResponse{ID: req.ID}
+					if req.Op == SocketOpGet {
+						resp.Value = []byte(value())
+					} else {
+						resp.Error = "unsupported op: " + req.Op
+					}
+					if err := writeFrame(conn, resp); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+}
+
+func TestSocketPollFallback(t *testing.T) {
+	ast := assert.New(t)
+
+	interval := SocketPollInterval
+	SocketPollInterval = 10 * time.Millisecond
+	defer func() { SocketPollInterval = interval }()
+
+	var mu sync.Mutex
+	host := "example.com"
+	path := filepath.Join(t.TempDir(), "config.sock")
+	oldSocketServer(t, path, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return `{"host":"` + host + `"}`
+	})
+
+	asyncer := NewSocketAsyncer(path)
+	dbCfg := NewAsyncConfig(asyncer, "db", time.Hour, false)
+	defer dbCfg.Close()
+	ast.Equal("example.com", dbCfg.String("host"))
+
+	ast.Eventually(func() bool {
+		return asyncer.ServerVersion() == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// 轮询发现变化
+	mu.Lock()
+	host = "db.example.com"
+	mu.Unlock()
+	ast.Eventually(func() bool {
+		return dbCfg.String("host") == "db.example.com"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSocketCompression(t *testing.T) {
+	ast := assert.New(t)
+
+	hosts := make([]interface{}, 0, 100)
+	for i := 0; i < 100; i++ {
+		hosts = append(hosts, "db.example.com")
+	}
+	cfg := NewMapConfig(map[string]interface{}{"db": map[string]interface{}{"hosts": hosts}})
+	path := filepath.Join(t.TempDir(), "config.sock")
+	server, err := NewSocketServer(cfg, path)
+	ast.Nil(err)
+	defer server.Close()
+	go server.Serve()
+
+	conn, err := net.Dial("unix", path)
+	ast.Nil(err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	ast.Nil(writeFrame(conn, SocketRequest{ID: 1, Op: SocketOpGet, Key: "db", Encodings: []string{EncodingGzip}}))
+	var resp SocketResponse
+	ast.Nil(readFrame(r, &resp))
+	ast.Equal(EncodingGzip, resp.Encoding)
+	ast.Nil(resp.Value)
+	ast.NotEmpty(resp.Hash)
+
+	// 版本相同时不返回值
+	ast.Nil(writeFrame(conn, SocketRequest{ID: 2, Op: SocketOpGet, Key: "db", Hash: resp.Hash}))
+	var notModified SocketResponse
+	ast.Nil(readFrame(r, &notModified))
+	ast.True(notModified.NotModified)
+	ast.Equal(resp.Hash, notModified.Hash)
+	ast.Nil(notModified.Value)
+	ast.Nil(notModified.Data)
+
+	// 未声明可接受的算法时不压缩
+	ast.Nil(writeFrame(conn, SocketRequest{ID: 3, Op: SocketOpGet, Key: "db"}))
+	var plain SocketResponse
+	ast.Nil(readFrame(r, &plain))
+	ast.Empty(plain.Encoding)
+	ast.NotEmpty(plain.Value)
+
+	asyncer := NewSocketAsyncer(path)
+	value := asyncer.Get("db")
+	ast.JSONEq(string(plain.Value), string(value))
+	ast.Equal(asyncer.values["db"].hash, resp.Hash)
+	ast.Equal(value, asyncer.Get("db"), "not modified")
+
+	dbCfg := NewAsyncConfig(asyncer, "db", time.Hour, false)
+	defer dbCfg.Close()
+	ast.Len(dbCfg.Get("hosts"), 100)
+
+	// 推送压缩的值
+	ast.Nil(cfg.Set("db.hosts", append(hosts, "db2.example.com")))
+	ast.Eventually(func() bool {
+		return len(dbCfg.Get("hosts").([]interface{})) == 101
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSocketAsyncerClose(t *testing.T) {
+	ast := assert.New(t)
+
+	interval := SocketReconnectInterval
+	SocketReconnectInterval = 10 * time.Millisecond
+	defer func() { SocketReconnectInterval = interval }()
+
+	cfg := NewMapConfig(map[string]interface{}{"db": "example.com"})
+	path := filepath.Join(t.TempDir(), "config.sock")
+	server, err := NewSocketServer(cfg, path)
+	ast.Nil(err)
+	defer server.Close()
+	go server.Serve()
+
+	conns := func() int {
+		server.Lock()
+		defer server.Unlock()
+		return len(server.conns)
+	}
+
+	asyncer := NewSocketAsyncer(path)
+	<-asyncer.Watch("db")
+	ast.Equal(1, conns())
+
+	// Close后断开watch连接且不再重连
+	ast.Nil(asyncer.Close())
+	ast.Eventually(func() bool { return conns() == 0 }, time.Second, 5*time.Millisecond)
+	time.Sleep(5 * asyncer.reconnectInterval)
+	ast.Equal(0, conns())
+}
+
+func TestSocketPushWriteTimeout(t *testing.T) {
+	ast := assert.New(t)
+
+	timeout := SocketWriteTimeout
+	SocketWriteTimeout = 50 * time.Millisecond
+	defer func() { SocketWriteTimeout = timeout }()
+
+	cfg := NewMapConfig(map[string]interface{}{"db": ""})
+	path := filepath.Join(t.TempDir(), "config.sock")
+	server, err := NewSocketServer(cfg, path)
+	ast.Nil(err)
+	defer server.Close()
+	go server.Serve()
+
+	// watch后不再读取的客户端
+	conn, err := net.Dial("unix", path)
+	ast.Nil(err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	ast.Nil(writeFrame(conn, SocketRequest{ID: 1, Op: SocketOpWatch, Key: "db"}))
+	var resp SocketResponse
+	ast.Nil(readFrame(r, &resp))
+
+	large := make([]byte, 4<<20)
+	for i := range large {
+		large[i] = 'a'
+	}
+	ast.Nil(cfg.Set("db", string(large)))
+
+	// 写入超时后关闭该连接
+	ast.Eventually(func() bool {
+		server.Lock()
+		defer server.Unlock()
+		return len(server.conns) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
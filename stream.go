@@ -0,0 +1,143 @@
+package config
+
+import (
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReaderGetter 以流的方式读取内容的后端，适用于很大的配置，key不存在时返回 (nil, nil)
+type ReaderGetter interface {
+	GetReader(key string) (io.ReadCloser, error)
+}
+
+// StreamUnmarshaler 从流中解析内容的Marshaler，不需要读取完整的内容
+type StreamUnmarshaler interface {
+	UnmarshalFrom(r io.Reader, v interface{}) error
+}
+
+var _ StreamUnmarshaler = JSONMarshaler{}
+
+// UnmarshalFrom 使用json.Decoder解析，见 StreamUnmarshaler
+func (m JSONMarshaler) UnmarshalFrom(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// NewMapConfigFromReader 从r中解析配置，Marshaler实现了 StreamUnmarshaler 时不读取完整的内容
+func NewMapConfigFromReader(r io.Reader, contentType ContentType) (*MapConfig, error) {
+	marshaler, ok := typeMarshalers[contentType]
+	if !ok {
+		return nil, errors.Errorf("unsupported content type: %v", contentType)
+	}
+
+	var m map[string]interface{}
+	if err := unmarshalFrom(marshaler, r, &m); err != nil {
+		return nil, errors.Wrap(err, "unmarshal")
+	}
+	return NewMapConfig(m), nil
+}
+
+func unmarshalFrom(marshaler Marshaler, r io.Reader, v interface{}) error {
+	if s, ok := marshaler.(StreamUnmarshaler); ok {
+		return s.UnmarshalFrom(r, v)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return safeUnmarshal(marshaler, data, v)
+}
+
+// WithStreaming 后端实现了 ReaderGetter 且Marshaler实现了 StreamUnmarshaler 时，
+// 刷新时从流中解析配置，不读取完整的内容，降低很大的配置刷新时的内存峰值
+//
+// 需要解析完成后才能判断内容是否变化；不执行 RegisterRawMessageProcessor 注册的处理（包括去除JSON注释），
+// 与需要完整内容的功能（WithEnvelope、WithSignatureVerification、blob、WithLint、WithAutoDetect）同时使用时仍使用Get；
+// 后端经过 WithTimeouts、RateLimit、Account 等包装时以被包装的后端判断
+func WithStreaming() AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.streaming = true
+	}
+}
+
+// streamReader 是否可以从流中解析配置
+func (cfg *asyncConfig) streamReader() (ReaderGetter, bool) {
+	if !cfg.streaming || cfg.envelope || cfg.signingKeys != nil || len(cfg.blobs) > 0 ||
+		cfg.lint || cfg.autoDetect {
+		return nil, false
+	}
+	if _, ok := cfg.marshaler.(StreamUnmarshaler); !ok {
+		return nil, false
+	}
+	if _, ok := backendOf(cfg.asyncer).(ReaderGetter); !ok {
+		return nil, false
+	}
+
+	r, ok := cfg.asyncer.(ReaderGetter)
+	return r, ok
+}
+
+// digestReader 读取时计算内容的摘要及长度
+type digestReader struct {
+	r    io.Reader
+	h    hash.Hash
+	size int64
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.h.Write(p[:n])
+	d.size += int64(n)
+	return n, err
+}
+
+// refreshStream version 为读取前的写入版本，见 WithReadYourWrites
+func (cfg *asyncConfig) refreshStream(getter ReaderGetter, version uint64, now time.Time) error {
+	rc, err := getter.GetReader(cfg.asyncKey)
+	if err != nil {
+		logger.Errorf("asyncer[%s] get reader err:%v", cfg.asyncKey, err)
+		return backendError(cfg.asyncKey, err)
+	}
+	if rc == nil {
+		logger.Warnf("asyncer[%s] get empty content", cfg.asyncKey)
+		return backendError(cfg.asyncKey, errEmptyContent)
+	}
+	defer rc.Close()
+
+	r := &digestReader{r: rc, h: cfg.scratch.reset()}
+	var val interface{}
+	if err := cfg.marshaler.(StreamUnmarshaler).UnmarshalFrom(r, &val); err != nil {
+		if r.size == 0 {
+			logger.Warnf("asyncer[%s] get empty content", cfg.asyncKey)
+			return backendError(cfg.asyncKey, errEmptyContent)
+		}
+		logger.Errorf("decode async config[%s] error:%v", cfg.asyncKey, err)
+		return errors.Wrap(err, "unmarshal")
+	}
+	// 读取剩余的内容（如末尾的空白），摘要包含完整的内容
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return backendError(cfg.asyncKey, err)
+	}
+	loadMetrics().Observe(MetricPayloadBytes, float64(r.size), "key", cfg.asyncKey)
+
+	// 与 contentDigest 一致
+	rawMessageDigest := string(cfg.scratch.hexSum())
+	if rawMessageDigest == cfg.rawMessageDigest && !cfg.secretsExpired() {
+		cfg.adaptive.unchanged()
+		return nil
+	}
+
+	val, err = cfg.process(val, nil)
+	if err != nil {
+		logger.Errorf("decode async config[%s] error:%v", cfg.asyncKey, err)
+		return err
+	}
+
+	cfg.refreshed(val, rawMessageDigest, version, rawMessageDigest, nil, time.Time{}, now)
+	return nil
+}
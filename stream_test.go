@@ -0,0 +1,137 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type readerAsyncer struct {
+	rawAsyncer
+	readers int32
+}
+
+func (a *readerAsyncer) GetReader(key string) (io.ReadCloser, error) {
+	atomic.AddInt32(&a.readers, 1)
+	v, ok := a.data.Load(key)
+	if !ok {
+		return nil, nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(v.([]byte))), nil
+}
+
+func TestStreaming(t *testing.T) {
+	ast := assert.New(t)
+
+	asyncer := &readerAsyncer{rawAsyncer: rawAsyncer{NewMockAsyncer(false)}}
+	asyncer.data.Store("big.json", []byte(`{"a": {"b": 1}}`+"\n"))
+
+	cfg := NewAsyncConfig(asyncer, "big.json", time.Nanosecond, false, WithStreaming())
+	defer cfg.Close()
+	notifier := make(chan struct{}, 1)
+	cfg.Watch(notifier)
+	ast.EqualValues(1, cfg.Int("a.b"))
+	ast.True(atomic.LoadInt32(&asyncer.readers) >= 1)
+
+	// 内容未变化时不通知
+	ast.Nil(cfg.Configer.(*asyncConfig).refresh())
+	select {
+	case <-notifier:
+		ast.Fail("unexpected notify")
+	default:
+	}
+
+	asyncer.data.Store("big.json", []byte(`{"a": {"b": 2}}`))
+	ast.EqualValues(2, cfg.Int("a.b"))
+	select {
+	case <-notifier:
+	case <-time.After(time.Second):
+		ast.Fail("notify timeout")
+	}
+
+	// 不存在及格式错误
+	missing := NewAsyncConfig(asyncer, "missing.json", time.Hour, false, WithStreaming())
+	ast.True(errors.Is(missing.Status().Err, ErrBackendUnavailable))
+	asyncer.data.Store("bad.json", []byte(`{"a": `))
+	bad := NewAsyncConfig(asyncer, "bad.json", time.Hour, false, WithStreaming())
+	ast.NotNil(bad.Status().Err)
+
+	// 需要完整内容的功能使用Get
+	readers := atomic.LoadInt32(&asyncer.readers)
+	linted := NewAsyncConfig(asyncer, "big.json", time.Hour, false, WithStreaming(), WithLint())
+	ast.EqualValues(2, linted.Int("a.b"))
+	ast.Equal(readers, atomic.LoadInt32(&asyncer.readers))
+
+	// 文件
+	path := filepath.Join(t.TempDir(), "app.json")
+	ast.Nil(ioutil.WriteFile(path, []byte(`{"c": 3}`), 0644))
+	fileCfg := NewAsyncConfig(NewFileAsyncer(), path, time.Hour, false, WithStreaming())
+	ast.EqualValues(3, fileCfg.Int("c"))
+	rc, err := NewFileAsyncer().GetReader(filepath.Join(t.TempDir(), "not_exist.json"))
+	ast.Nil(err)
+	ast.Nil(rc)
+	ast.Nil(os.Remove(path))
+}
+
+func TestStreamingWrapped(t *testing.T) {
+	ast := assert.New(t)
+
+	asyncer := &readerAsyncer{rawAsyncer: rawAsyncer{NewMockAsyncer(false)}}
+	asyncer.data.Store("wrapped.json", []byte(`{"a": 1}`))
+
+	// 经过超时、限流及统计的包装仍从流中读取
+	accounted := Account(RateLimit(asyncer, 1000, 1000), "stream")
+	cfg := NewAsyncConfig(accounted, "wrapped.json", time.Hour, false, WithStreaming(),
+		WithTimeouts(Timeouts{Get: time.Second}), WithReadYourWrites(time.Minute))
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a"))
+	ast.EqualValues(1, atomic.LoadInt32(&asyncer.readers))
+	ast.EqualValues(len(`{"a": 1}`), accounted.Stats().GetBytes)
+
+	// 流式刷新同样保留Set的值
+	ast.Nil(cfg.Set("a", 2))
+	asyncer.data.Store("wrapped.json", []byte(`{"a": 1}`))
+	ast.Nil(cfg.Configer.(*asyncConfig).refresh())
+	ast.EqualValues(2, cfg.Int("a"), "stale replica")
+	ast.EqualValues(2, atomic.LoadInt32(&asyncer.readers))
+
+	// 被包装的后端不支持时使用Get
+	mock := NewMockAsyncer(false)
+	mock.Set("plain.json", []byte(`{"b": 1}`))
+	plainAsyncer := Account(rawAsyncer{mock}, "plain")
+	plain := NewAsyncConfig(plainAsyncer, "plain.json", time.Hour, false, WithStreaming(),
+		WithTimeouts(Timeouts{Get: time.Second}))
+	defer plain.Close()
+	ast.EqualValues(1, plain.Int("b"))
+	_, err := plainAsyncer.GetReader("plain.json")
+	ast.NotNil(err)
+	rc, err := accounted.GetReader("not_exist.json")
+	ast.Nil(err)
+	ast.Nil(rc)
+}
+
+func TestNewMapConfigFromReader(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg, err := NewMapConfigFromReader(strings.NewReader(`{"a": {"b": 1}}`), T_JSON)
+	ast.Nil(err)
+	ast.EqualValues(1, cfg.Int("a.b"))
+
+	cfg, err = NewMapConfigFromReader(strings.NewReader("a:\n  b: 2\n"), T_YAML)
+	ast.Nil(err)
+	ast.EqualValues(2, cfg.Int("a.b"))
+
+	_, err = NewMapConfigFromReader(strings.NewReader(`{`), T_JSON)
+	ast.NotNil(err)
+	_, err = NewMapConfigFromReader(strings.NewReader(`{}`), ContentType(-1))
+	ast.NotNil(err)
+}
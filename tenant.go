@@ -0,0 +1,136 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TenantPlaceholder keyPath模板中替换为租户ID的占位符
+const TenantPlaceholder = "{tenant}"
+
+// TenantConfig 多租户配置的访问，keyPath为包含 {tenant} 的模板，调用时传入租户ID
+//
+//	tenants := config.NewTenantConfig(cfg)
+//	qps := tenants.Int("acme", "tenants.{tenant}.limits.qps") // tenants.acme.limits.qps
+//
+//	var limits Limits
+//	err := tenants.Bind("acme", "tenants.{tenant}.limits", &limits)
+//
+// 租户ID为空或包含 "." 时视为配置项不存在，避免读取到其他节点
+type TenantConfig struct {
+	h *ConfigHelper
+}
+
+func NewTenantConfig(c Configer) *TenantConfig {
+	if h, ok := c.(interface{ configer() Configer }); ok {
+		c = h.configer()
+	}
+	return &TenantConfig{h: &ConfigHelper{c}}
+}
+
+// KeyPath 返回租户的keyPath，租户ID无效时返回错误
+func (t *TenantConfig) KeyPath(tenant, template string) (string, error) {
+	if tenant == "" || strings.Contains(tenant, ".") {
+		return "", errors.WithStack(&KeyError{
+			KeyPath: template,
+			Kind:    ErrKeyNotFound,
+			Err:     errors.Errorf("invalid tenant[%s]", tenant),
+		})
+	}
+	return strings.ReplaceAll(template, TenantPlaceholder, tenant), nil
+}
+
+func (t *TenantConfig) Get(tenant, template string) interface{} {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return nil
+	}
+	return t.h.Get(keyPath)
+}
+
+// Lookup 见 ConfigHelper.Lookup
+func (t *TenantConfig) Lookup(tenant, template string) (interface{}, error) {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return nil, err
+	}
+	return t.h.Lookup(keyPath)
+}
+
+func (t *TenantConfig) String(tenant, template string) string {
+	return t.StringDefault(tenant, template, "")
+}
+
+func (t *TenantConfig) StringDefault(tenant, template string, dft string) string {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return dft
+	}
+	return t.h.StringDefault(keyPath, dft)
+}
+
+func (t *TenantConfig) Int(tenant, template string) int64 {
+	return t.IntDefault(tenant, template, 0)
+}
+
+func (t *TenantConfig) IntDefault(tenant, template string, dft int64) int64 {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return dft
+	}
+	return t.h.IntDefault(keyPath, dft)
+}
+
+func (t *TenantConfig) Float(tenant, template string) float64 {
+	return t.FloatDefault(tenant, template, 0)
+}
+
+func (t *TenantConfig) FloatDefault(tenant, template string, dft float64) float64 {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return dft
+	}
+	return t.h.FloatDefault(keyPath, dft)
+}
+
+func (t *TenantConfig) Bool(tenant, template string) bool {
+	return t.BoolDefault(tenant, template, false)
+}
+
+func (t *TenantConfig) BoolDefault(tenant, template string, dft bool) bool {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return dft
+	}
+	return t.h.BoolDefault(keyPath, dft)
+}
+
+// Bind 将租户的配置节点解析到v，见 ConfigHelper.Remarshal
+func (t *TenantConfig) Bind(tenant, template string, v interface{}) error {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return err
+	}
+	return t.h.Remarshal(keyPath, v)
+}
+
+// Set 设置租户的配置项
+func (t *TenantConfig) Set(tenant, template string, value interface{}) error {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return err
+	}
+	return t.h.Set(keyPath, value)
+}
+
+// WatchKey 租户的配置节点变化时通知notifier，见 ConfigHelper.WatchKey
+//
+// 租户ID无效时不会通知，stop可照常调用
+func (t *TenantConfig) WatchKey(tenant, template string, notifier chan struct{}) (stop func()) {
+	keyPath, err := t.KeyPath(tenant, template)
+	if err != nil {
+		return func() {}
+	}
+	return t.h.WatchKey(keyPath, notifier)
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantConfig(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"tenants": map[string]interface{}{
+			"acme":   map[string]interface{}{"limits": map[string]interface{}{"qps": 100, "burst": 1.5}, "name": "Acme", "beta": true},
+			"globex": map[string]interface{}{"limits": map[string]interface{}{"qps": 10}},
+		},
+	})
+	tenants := NewTenantConfig(cfg)
+
+	keyPath, err := tenants.KeyPath("acme", "tenants.{tenant}.limits")
+	ast.Nil(err)
+	ast.Equal("tenants.acme.limits", keyPath)
+
+	ast.EqualValues(100, tenants.Int("acme", "tenants.{tenant}.limits.qps"))
+	ast.EqualValues(10, tenants.Int("globex", "tenants.{tenant}.limits.qps"))
+	ast.EqualValues(5, tenants.IntDefault("initech", "tenants.{tenant}.limits.qps", 5))
+	ast.Equal(1.5, tenants.Float("acme", "tenants.{tenant}.limits.burst"))
+	ast.Equal("Acme", tenants.String("acme", "tenants.{tenant}.name"))
+	ast.Equal("none", tenants.StringDefault("globex", "tenants.{tenant}.name", "none"))
+	ast.True(tenants.Bool("acme", "tenants.{tenant}.beta"))
+	ast.NotNil(tenants.Get("acme", "tenants.{tenant}"))
+
+	var limits struct {
+		QPS   int     `json:"qps"`
+		Burst float64 `json:"burst"`
+	}
+	ast.Nil(tenants.Bind("acme", "tenants.{tenant}.limits", &limits))
+	ast.Equal(100, limits.QPS)
+	ast.Equal(1.5, limits.Burst)
+	ast.True(errors.Is(tenants.Bind("initech", "tenants.{tenant}.limits", &limits), ErrKeyNotFound))
+
+	// 无效的租户ID不会读取到其他节点
+	ast.EqualValues(-1, tenants.IntDefault("acme.limits", "tenants.{tenant}.qps", -1))
+	ast.Nil(tenants.Get("", "tenants{tenant}"))
+	_, err = tenants.Lookup("a.b", "tenants.{tenant}")
+	ast.True(errors.Is(err, ErrKeyNotFound))
+	ast.NotNil(tenants.Set("", "tenants.{tenant}.name", "x"))
+
+	// 每个租户单独监听
+	acme := make(chan struct{}, 1)
+	stopAcme := tenants.WatchKey("acme", "tenants.{tenant}.limits", acme)
+	defer stopAcme()
+	globex := make(chan struct{}, 1)
+	stopGlobex := tenants.WatchKey("globex", "tenants.{tenant}.limits", globex)
+	defer stopGlobex()
+	tenants.WatchKey("a.b", "tenants.{tenant}", make(chan struct{}))()
+
+	ast.Nil(tenants.Set("globex", "tenants.{tenant}.limits.qps", 20))
+	select {
+	case <-globex:
+	case <-time.After(time.Second):
+		ast.Fail("globex not notified")
+	}
+	select {
+	case <-acme:
+		ast.Fail("acme notified")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ast.EqualValues(20, tenants.Int("globex", "tenants.{tenant}.limits.qps"))
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kot-w/goutils/itype"
+	"github.com/pkg/errors"
+)
+
+// CertFileCheckInterval 证书配置为文件路径时，检查文件变化的间隔
+var CertFileCheckInterval = 30 * time.Second
+
+// CertReloader 根据配置热更新TLS证书
+//
+// 证书及私钥配置的值可以是PEM内容，也可以是PEM文件路径。
+// 配置变化（或证书文件变化）时重新加载，新的证书校验通过后才会替换旧证书
+//
+//	reloader, err := config.NewCertReloader(cfg, "tls.cert", "tls.key")
+//	server := &http.Server{TLSConfig: reloader.TLSConfig(nil)}
+type CertReloader struct {
+	cfg         Configer
+	certKeyPath string
+	keyKeyPath  string
+
+	cert atomic.Value // *tls.Certificate
+
+	sync.Mutex
+	// 证书文件路径 => 修改时间
+	fileModTimes map[string]time.Time
+
+	notifier  chan struct{}
+	quit      chan struct{}
+	done      chan struct{} // watch退出时关闭
+	closeOnce sync.Once
+}
+
+func NewCertReloader(cfg Configer, certKeyPath string, keyKeyPath string) (*CertReloader, error) {
+	r := &CertReloader{
+		cfg:          cfg,
+		certKeyPath:  certKeyPath,
+		keyKeyPath:   keyKeyPath,
+		fileModTimes: make(map[string]time.Time),
+		notifier:     make(chan struct{}, 1),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	cfg.Watch(r.notifier)
+	go r.watch()
+
+	return r, nil
+}
+
+func (r *CertReloader) watch() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(CertFileCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.notifier:
+		case <-ticker.C:
+			if !r.filesChanged() {
+				continue
+			}
+		case <-r.quit:
+			return
+		}
+
+		if err := r.Reload(); err != nil {
+			logger.Errorf("reload certificate[%s] error:%v", r.certKeyPath, err)
+		}
+	}
+}
+
+// Reload 重新加载证书，新证书不合法时保留旧证书并返回error
+func (r *CertReloader) Reload() error {
+	r.Lock()
+	defer r.Unlock()
+
+	modTimes := make(map[string]time.Time)
+	certPEM, err := r.loadPEM(r.certKeyPath, modTimes)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := r.loadPEM(r.keyKeyPath, modTimes)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return errors.Wrapf(err, "invalid certificate[%s] key pair", r.certKeyPath)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return errors.Wrapf(err, "parse certificate[%s]", r.certKeyPath)
+	}
+	if now := _now(); now.After(leaf.NotAfter) {
+		return errors.Errorf("certificate[%s] expired at %s", r.certKeyPath, leaf.NotAfter)
+	}
+	cert.Leaf = leaf
+
+	if old, ok := r.cert.Load().(*tls.Certificate); ok && bytes.Equal(old.Certificate[0], cert.Certificate[0]) {
+		// no change
+		r.fileModTimes = modTimes
+		return nil
+	}
+
+	r.cert.Store(&cert)
+	r.fileModTimes = modTimes
+	logger.Infof("certificate[%s] loaded, subject=%s, expire=%s", r.certKeyPath, leaf.Subject, leaf.NotAfter)
+
+	return nil
+}
+
+// loadPEM 读取PEM内容，配置的值不是PEM内容时作为文件路径读取
+func (r *CertReloader) loadPEM(keyPath string, modTimes map[string]time.Time) ([]byte, error) {
+	content := itype.Bytes(r.cfg.Get(keyPath))
+	if len(content) == 0 {
+		return nil, errors.Errorf("certificate config[%s] is empty", keyPath)
+	}
+
+	if bytes.Contains(content, []byte("-----BEGIN")) {
+		return content, nil
+	}
+
+	file := string(bytes.TrimSpace(content))
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "certificate config[%s]", keyPath)
+	}
+	modTimes[file] = info.ModTime()
+
+	return ioutil.ReadFile(file)
+}
+
+func (r *CertReloader) filesChanged() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	for file, modTime := range r.fileModTimes {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Certificate 返回当前的证书
+func (r *CertReloader) Certificate() *tls.Certificate {
+	cert, _ := r.cert.Load().(*tls.Certificate)
+	return cert
+}
+
+// GetCertificate 用于 tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate 用于 tls.Config.GetClientCertificate
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// TLSConfig 返回使用当前证书的tls.Config，base不为nil时基于base的副本
+func (r *CertReloader) TLSConfig(base *tls.Config) *tls.Config {
+	var c *tls.Config
+	if base != nil {
+		c = base.Clone()
+	} else {
+		c = &tls.Config{}
+	}
+	c.GetCertificate = r.GetCertificate
+	c.GetClientCertificate = r.GetClientCertificate
+
+	return c
+}
+
+// Close 停止监控配置变化，返回时不再重新加载
+func (r *CertReloader) Close() {
+	r.closeOnce.Do(func() {
+		close(r.quit)
+	})
+	<-r.done
+}
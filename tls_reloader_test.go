@@ -0,0 +1,119 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func genTestCert(t *testing.T, cn string, notAfter time.Time) (certPEM []byte, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestCertReloader(t *testing.T) {
+	ast := assert.New(t)
+
+	cert1, key1 := genTestCert(t, "v1", time.Now().Add(time.Hour))
+	cfg := NewMapConfig(map[string]interface{}{
+		"tls": map[string]interface{}{
+			"cert": string(cert1),
+			"key":  string(key1),
+		},
+	})
+
+	r, err := NewCertReloader(cfg, "tls.cert", "tls.key")
+	ast.Nil(err)
+	defer r.Close()
+
+	c, err := r.GetCertificate(&tls.ClientHelloInfo{})
+	ast.Nil(err)
+	ast.Equal("v1", c.Leaf.Subject.CommonName)
+	ast.NotNil(r.TLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}).GetCertificate)
+
+	// rotate
+	cert2, key2 := genTestCert(t, "v2", time.Now().Add(time.Hour))
+	ast.Nil(cfg.Merge(map[string]interface{}{
+		"tls": map[string]interface{}{
+			"cert": string(cert2),
+			"key":  string(key2),
+		},
+	}))
+	for i := 0; i < 100 && r.Certificate().Leaf.Subject.CommonName != "v2"; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	ast.Equal("v2", r.Certificate().Leaf.Subject.CommonName)
+
+	// mismatched key pair, keep the old one
+	ast.Nil(cfg.Set("tls.key", string(key1)))
+	ast.NotNil(r.Reload())
+	ast.Equal("v2", r.Certificate().Leaf.Subject.CommonName)
+
+	// expired
+	cert3, key3 := genTestCert(t, "v3", time.Now().Add(-time.Minute))
+	ast.Nil(cfg.Set("tls", map[string]interface{}{"cert": string(cert3), "key": string(key3)}))
+	ast.NotNil(r.Reload())
+	ast.Equal("v2", r.Certificate().Leaf.Subject.CommonName)
+
+	_, err = NewCertReloader(cfg, "not_exist", "tls.key")
+	ast.NotNil(err)
+}
+
+func TestCertReloaderFile(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	ast.Nil(err)
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	cert1, key1 := genTestCert(t, "file", time.Now().Add(time.Hour))
+	ast.Nil(ioutil.WriteFile(certFile, cert1, 0600))
+	ast.Nil(ioutil.WriteFile(keyFile, key1, 0600))
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"cert": certFile,
+		"key":  keyFile,
+	})
+
+	r, err := NewCertReloader(cfg, "cert", "key")
+	ast.Nil(err)
+	defer r.Close()
+	ast.Equal("file", r.Certificate().Leaf.Subject.CommonName)
+	ast.False(r.filesChanged())
+
+	ast.Nil(os.Chtimes(certFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	ast.True(r.filesChanged())
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// TokenRefreshAhead token过期前多久开始后台刷新
+	TokenRefreshAhead = time.Minute
+	// TokenRefreshTimeout 后台刷新token的超时时间
+	TokenRefreshTimeout = 10 * time.Second
+	// TokenRetryMin 刷新失败后的最短重试间隔，连续失败时翻倍
+	TokenRetryMin = time.Second
+	// TokenRetryMax 刷新失败后的最长重试间隔
+	TokenRetryMax = time.Minute
+)
+
+// Token 访问后端的凭证
+type Token struct {
+	Value     string
+	Type      string    // Authorization头的类型，默认Bearer
+	ExpiresAt time.Time // 为零值时不过期
+}
+
+func (t *Token) valid(now time.Time) bool {
+	return t != nil && t.Value != "" && (t.ExpiresAt.IsZero() || now.Before(t.ExpiresAt))
+}
+
+func (t *Token) expiresWithin(now time.Time, d time.Duration) bool {
+	return !t.ExpiresAt.IsZero() && t.ExpiresAt.Sub(now) < d
+}
+
+// SetAuthHeader 设置请求的Authorization头
+func (t *Token) SetAuthHeader(r *http.Request) {
+	typ := t.Type
+	if typ == "" {
+		typ = "Bearer"
+	}
+	r.Header.Set("Authorization", typ+" "+t.Value)
+}
+
+// TokenSource 提供访问后端的token（类似 oauth2.TokenSource），内置后端每次请求（或建立连接）时调用
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// StaticToken 不过期的token
+func StaticToken(value string) TokenSource {
+	tok := &Token{Value: value}
+	return TokenSourceFunc(func(context.Context) (*Token, error) {
+		return tok, nil
+	})
+}
+
+// ReuseTokenSource 缓存src返回的token直到过期
+//
+// 过期前 TokenRefreshAhead 内在后台刷新，刷新期间及刷新失败时继续使用未过期的token；
+// 刷新失败后按 TokenRetryMin ~ TokenRetryMax 退避，退避期间token已过期时直接返回最近一次的错误
+func ReuseTokenSource(src TokenSource) TokenSource {
+	if s, ok := src.(*reuseTokenSource); ok {
+		return s
+	}
+	return &reuseTokenSource{src: src}
+}
+
+type reuseTokenSource struct {
+	src TokenSource
+	sf  singleflight.Group
+
+	sync.Mutex
+	token      *Token
+	refreshing bool
+	retryAt    time.Time
+	backoff    time.Duration
+	lastErr    error
+}
+
+func (s *reuseTokenSource) Token(ctx context.Context) (*Token, error) {
+	now := _now()
+
+	s.Lock()
+	tok := s.token
+	if tok.valid(now) {
+		if tok.expiresWithin(now, TokenRefreshAhead) && !s.refreshing && !now.Before(s.retryAt) {
+			s.refreshing = true
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), TokenRefreshTimeout)
+				defer cancel()
+				s.refresh(ctx, now)
+			}()
+		}
+		s.Unlock()
+		return tok, nil
+	}
+	if now.Before(s.retryAt) {
+		err := s.lastErr
+		s.Unlock()
+		return nil, errors.Wrap(err, "token refresh backoff")
+	}
+	s.Unlock()
+
+	return s.refresh(ctx, now)
+}
+
+// refresh 并发的刷新合并为一次，now为触发刷新的时间
+func (s *reuseTokenSource) refresh(ctx context.Context, now time.Time) (*Token, error) {
+	v, err, _ := s.sf.Do("", func() (interface{}, error) {
+		tok, err := s.src.Token(ctx)
+
+		s.Lock()
+		defer s.Unlock()
+		s.refreshing = false
+
+		if err == nil && !tok.valid(now) {
+			err = errors.New("invalid or expired token")
+		}
+		if err != nil {
+			logger.Warnf("refresh token err:%v", err)
+			s.backoff *= 2
+			if s.backoff < TokenRetryMin {
+				s.backoff = TokenRetryMin
+			}
+			if s.backoff > TokenRetryMax {
+				s.backoff = TokenRetryMax
+			}
+			s.retryAt = now.Add(s.backoff)
+			s.lastErr = err
+			return nil, errors.Wrap(err, "refresh token")
+		}
+
+		s.token = tok
+		s.backoff = 0
+		s.retryAt = time.Time{}
+		s.lastErr = nil
+		return tok, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Token), nil
+}
+
+// tokenTransport 为每个请求设置Authorization头
+type tokenTransport struct {
+	base   http.RoundTripper
+	source TokenSource
+}
+
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	tok, err := t.source.Token(r.Context())
+	if err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, err
+	}
+
+	r = r.Clone(r.Context())
+	tok.SetAuthHeader(r)
+	return t.base.RoundTrip(r)
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReuseTokenSource(t *testing.T) {
+	ast := assert.New(t)
+
+	tm := time.Now()
+	originFun := _now
+	defer func() {
+		_now = originFun
+	}()
+	_now = func() time.Time {
+		return tm
+	}
+
+	var calls int32
+	var fail atomic.Value
+	fail.Store(false)
+	refreshed := make(chan struct{}, 1)
+	src := ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		n := atomic.AddInt32(&calls, 1)
+		defer func() {
+			select {
+			case refreshed <- struct{}{}:
+			default:
+			}
+		}()
+		if fail.Load().(bool) {
+			return nil, errors.New("unavailable")
+		}
+		return &Token{Value: string(rune('a' + n - 1)), ExpiresAt: tm.Add(10 * time.Minute)}, nil
+	}))
+	ast.Equal(src, ReuseTokenSource(src))
+
+	ctx := context.Background()
+	tok, err := src.Token(ctx)
+	ast.Nil(err)
+	ast.Equal("a", tok.Value)
+	<-refreshed
+	tok, _ = src.Token(ctx)
+	ast.Equal("a", tok.Value, "cached")
+	ast.EqualValues(1, atomic.LoadInt32(&calls))
+
+	// 即将过期时后台刷新，先返回旧token
+	tm = tm.Add(9*time.Minute + 30*time.Second)
+	tok, _ = src.Token(ctx)
+	ast.Equal("a", tok.Value)
+	<-refreshed
+	for i := 0; i < 100; i++ {
+		if tok, _ = src.Token(ctx); tok.Value == "b" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ast.Equal("b", tok.Value)
+
+	// 过期后同步刷新，失败后退避
+	fail.Store(true)
+	tm = tm.Add(time.Hour)
+	_, err = src.Token(ctx)
+	ast.NotNil(err)
+	<-refreshed
+	_, err = src.Token(ctx)
+	ast.NotNil(err)
+	ast.EqualValues(3, atomic.LoadInt32(&calls), "backoff")
+
+	tm = tm.Add(TokenRetryMin)
+	_, err = src.Token(ctx)
+	ast.NotNil(err)
+	<-refreshed
+	ast.EqualValues(4, atomic.LoadInt32(&calls))
+	tm = tm.Add(TokenRetryMin)
+	_, err = src.Token(ctx)
+	ast.EqualValues(4, atomic.LoadInt32(&calls), "backoff doubled")
+
+	fail.Store(false)
+	tm = tm.Add(TokenRetryMin)
+	tok, err = src.Token(ctx)
+	ast.Nil(err)
+	ast.Equal("e", tok.Value)
+}
+
+func TestBackendOptionsToken(t *testing.T) {
+	ast := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := NewBackendOptions(WithTokenSource(StaticToken("t1"))).HTTPClient().Do(req)
+	if ast.Nil(err) {
+		defer resp.Body.Close()
+		body := make([]byte, 64)
+		n, _ := resp.Body.Read(body)
+		ast.Equal("Bearer t1", string(body[:n]))
+	}
+	ast.Empty(req.Header.Get("Authorization"), "request not modified")
+
+	_, err = NewBackendOptions(WithTokenSource(TokenSourceFunc(func(context.Context) (*Token, error) {
+		return nil, errors.New("unavailable")
+	}))).HTTPClient().Get(srv.URL)
+	ast.NotNil(err)
+}
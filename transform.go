@@ -0,0 +1,50 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Transformer 刷新时改写解析后的配置，如旧key的映射、单位换算
+//
+// 在解析之后、blob及密钥引用处理之前执行，Set时写回的是改写后的内容
+type Transformer interface {
+	Transform(tree map[string]interface{}) (map[string]interface{}, error)
+}
+
+// TransformerFunc 函数形式的Transformer
+type TransformerFunc func(tree map[string]interface{}) (map[string]interface{}, error)
+
+func (f TransformerFunc) Transform(tree map[string]interface{}) (map[string]interface{}, error) {
+	return f(tree)
+}
+
+// WithTransformer 刷新时依次使用transformers改写配置，见 LoadPluginTransformer
+func WithTransformer(transformers ...Transformer) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.transformers = append(cfg.transformers, transformers...)
+	}
+}
+
+// transform 依次执行transformers及 RegisterTransform 注册的处理阶段，非map类型的配置不处理
+func (cfg *asyncConfig) transform(val interface{}) (interface{}, error) {
+	tree, ok := val.(map[string]interface{})
+	if !ok {
+		return val, nil
+	}
+
+	for _, t := range cfg.transformers {
+		ret, err := t.Transform(tree)
+		if err != nil {
+			return nil, errors.Wrap(err, "transform")
+		}
+		if ret != nil {
+			tree = ret
+		}
+	}
+
+	if err := runTransformStages(tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,43 @@
+//go:build (linux || darwin || freebsd) && cgo
+// +build linux darwin freebsd
+// +build cgo
+
+package config
+
+import (
+	"plugin"
+
+	"github.com/pkg/errors"
+)
+
+// PluginTransformSymbol 插件中导出的Transformer符号名
+const PluginTransformSymbol = "Transform"
+
+// LoadPluginTransformer 从Go插件（go build -buildmode=plugin）加载Transformer，
+// 插件需导出名为 Transform 的函数或实现 Transformer 的变量：
+//
+//	func Transform(tree map[string]interface{}) (map[string]interface{}, error)
+//
+// 插件需使用与服务相同的Go版本及依赖版本构建
+func LoadPluginTransformer(path string) (Transformer, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open plugin %s", path)
+	}
+
+	sym, err := p.Lookup(PluginTransformSymbol)
+	if err != nil {
+		return nil, errors.Wrapf(err, "plugin %s", path)
+	}
+
+	switch t := sym.(type) {
+	case func(map[string]interface{}) (map[string]interface{}, error):
+		return TransformerFunc(t), nil
+	case *Transformer:
+		return *t, nil
+	case Transformer:
+		return t, nil
+	}
+
+	return nil, errors.Errorf("plugin %s: unsupported %s type %T", path, PluginTransformSymbol, sym)
+}
@@ -0,0 +1,16 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+// +build !linux,!darwin,!freebsd !cgo
+
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// PluginTransformSymbol 插件中导出的Transformer符号名
+const PluginTransformSymbol = "Transform"
+
+// LoadPluginTransformer 当前平台不支持Go插件
+func LoadPluginTransformer(path string) (Transformer, error) {
+	return nil, errors.Errorf("load plugin %s: go plugin is not supported on this platform", path)
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformer(t *testing.T) {
+	ast := assert.New(t)
+
+	// 旧key映射
+	rename := TransformerFunc(func(tree map[string]interface{}) (map[string]interface{}, error) {
+		if v, ok := tree["db_host"]; ok {
+			setMapValue(tree, "db.host", v)
+			delete(tree, "db_host")
+		}
+		return tree, nil
+	})
+	fail := false
+	check := TransformerFunc(func(tree map[string]interface{}) (map[string]interface{}, error) {
+		if fail {
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+
+	asyncer := NewMockAsyncer(false)
+	asyncer.data.Store("transform.json", []byte(`{"db_host": "example.com"}`))
+
+	cfg := NewAsyncConfig(asyncer, "transform.json", time.Millisecond, false, WithTransformer(rename, check))
+	ast.Equal("example.com", cfg.String("db.host"))
+	ast.Nil(cfg.Get("db_host"))
+
+	// 失败时保留旧值
+	fail = true
+	asyncer.data.Store("transform.json", []byte(`{"db_host": "new.example.com"}`))
+	time.Sleep(2 * time.Millisecond)
+	ast.Equal("example.com", cfg.String("db.host"))
+
+	fail = false
+	time.Sleep(2 * time.Millisecond)
+	ast.Equal("new.example.com", cfg.String("db.host"))
+
+	_, err := LoadPluginTransformer("not_exist.so")
+	ast.NotNil(err)
+}
@@ -0,0 +1,53 @@
+package config
+
+// PayloadSizeBuckets MetricPayloadBytes 建议的直方图分桶：256B ~ 16MB，按4倍递增
+var PayloadSizeBuckets = ExponentialBuckets(256, 4, 9)
+
+// ExponentialBuckets 从start开始每次乘以factor的count个分桶上界，与 prometheus.ExponentialBuckets 一致
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// treeStats 返回叶子节点数（同 AllKeys，空map及list视为叶子）及最大嵌套深度（map、list各算一层）
+func treeStats(v interface{}) (keys, depth int) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if len(vv) == 0 {
+			return 1, 1
+		}
+		for _, child := range vv {
+			k, d := treeStats(child)
+			keys += k
+			if d > depth {
+				depth = d
+			}
+		}
+		return keys, depth + 1
+	case []interface{}:
+		for _, child := range vv {
+			if _, d := treeStats(child); d > depth {
+				depth = d
+			}
+		}
+		return 1, depth + 1
+	default:
+		return 1, 0
+	}
+}
+
+// observeTree 上报配置树的大小，便于在配置异常膨胀前告警
+func observeTree(key string, val interface{}) {
+	m := loadMetrics()
+	if _, ok := m.(nopMetrics); ok {
+		return
+	}
+
+	keys, depth := treeStats(val)
+	m.Gauge(MetricTreeKeys, float64(keys), "key", key)
+	m.Gauge(MetricTreeDepth, float64(depth), "key", key)
+}
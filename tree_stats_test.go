@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreeStats(t *testing.T) {
+	ast := assert.New(t)
+
+	ast.Equal([]float64{256, 1024, 4096}, ExponentialBuckets(256, 4, 3))
+	ast.Len(PayloadSizeBuckets, 9)
+	ast.EqualValues(16<<20, PayloadSizeBuckets[8])
+
+	keys, depth := treeStats(map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{
+			"c": []interface{}{map[string]interface{}{"d": 1}},
+			"e": map[string]interface{}{},
+		},
+	})
+	ast.Equal(3, keys)
+	ast.Equal(4, depth)
+
+	keys, depth = treeStats("scalar")
+	ast.Equal(1, keys)
+	ast.Equal(0, depth)
+
+	m := &testMetrics{counters: make(map[string]float64)}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	asyncer := rawAsyncer{NewMockAsyncer(false)}
+	asyncer.data.Store("tree.json", []byte(`{"a": {"b": 1, "c": 2}}`))
+	cfg := NewAsyncConfig(asyncer, "tree.json", time.Nanosecond, false)
+	defer cfg.Close()
+	ast.EqualValues(1, cfg.Int("a.b"))
+
+	m.Lock()
+	defer m.Unlock()
+	sizes := m.observed[MetricPayloadBytes+"{key,tree.json}"]
+	ast.True(len(sizes) >= 2, "observed every refresh: %v", sizes)
+	ast.EqualValues(len(`{"a": {"b": 1, "c": 2}}`), sizes[0])
+	ast.EqualValues(2, m.gauges[MetricTreeKeys+"{key,tree.json}"])
+	ast.EqualValues(2, m.gauges[MetricTreeDepth+"{key,tree.json}"])
+}
@@ -0,0 +1,381 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mohae/deepcopy"
+	"github.com/pkg/errors"
+)
+
+// UnmarshalTag 字段对应的key的tag，未设置时依次使用 mapstructure tag及字段名（不区分大小写）
+const UnmarshalTag = "config"
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Unmarshal 将指定节点直接解析到out（非nil指针），不经过JSON，类型不符时按需转换：
+// 字符串与数字、布尔值（含 yes/no、on/off）互转，time.Duration 同 toDuration，字符串可解析到 encoding.TextUnmarshaler
+//
+//	type DBConfig struct {
+//		Host    string        `config:"host"`
+//		Port    int           `config:"port"`
+//		Timeout time.Duration `config:"timeout"`
+//		Common  `config:",squash"`           // 内嵌结构体的字段与其他字段同级，匿名字段默认如此
+//		Extra   map[string]interface{} `config:",remain"` // 未使用的key
+//	}
+//	var db DBConfig
+//	err := cfg.Unmarshal("db", &db)
+//
+// 配置中不存在或为null的字段保留out中的原值，可预先设置默认值；类型不符时返回 ErrTypeMismatch 及字段的keyPath
+func (h *ConfigHelper) Unmarshal(keyPath string, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.Errorf("unmarshal config[%s]: out must be a non-nil pointer, got %T", keyPath, out)
+	}
+
+	val := h.Get(keyPath)
+	if val == nil {
+		return keyNotFound(keyPath)
+	}
+	return decodeInto(keyPath, val, rv.Elem())
+}
+
+// decodeInto 将val解析到out，keyPath用于错误信息
+func decodeInto(keyPath string, val interface{}, out reflect.Value) error {
+	if val == nil {
+		return nil
+	}
+
+	if out.Kind() != reflect.Ptr && out.CanAddr() && out.Addr().Type().Implements(textUnmarshalerType) {
+		if s, ok := val.(string); ok {
+			if err := out.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+				return typeMismatch(keyPath, err)
+			}
+			return nil
+		}
+	}
+	if out.Type() == durationType {
+		d, ok := toDuration(val)
+		if !ok {
+			return mismatch(keyPath, val, out)
+		}
+		out.SetInt(int64(d))
+		return nil
+	}
+
+	switch out.Kind() {
+	case reflect.Ptr:
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		return decodeInto(keyPath, val, out.Elem())
+	case reflect.Interface:
+		v := reflect.ValueOf(deepcopy.Copy(val))
+		if !v.Type().AssignableTo(out.Type()) {
+			return mismatch(keyPath, val, out)
+		}
+		out.Set(v)
+		return nil
+	case reflect.Struct:
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return mismatch(keyPath, val, out)
+		}
+		return decodeStruct(keyPath, m, out)
+	case reflect.Map:
+		return decodeMap(keyPath, val, out)
+	case reflect.Slice, reflect.Array:
+		return decodeSlice(keyPath, val, out)
+	}
+	return decodeScalar(keyPath, val, out)
+}
+
+func mismatch(keyPath string, val interface{}, out reflect.Value) error {
+	return typeMismatch(keyPath, fmt.Errorf("cannot unmarshal %T into %s", val, out.Type()))
+}
+
+// structField 结构体字段对应的key
+type structField struct {
+	index  []int
+	key    string
+	remain bool
+}
+
+// structFields 按 UnmarshalTag、mapstructure tag的顺序确定key，squash及匿名结构体的字段展开
+func structFields(t reflect.Type, index []int) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag, ok := f.Tag.Lookup(UnmarshalTag)
+		if !ok {
+			tag = f.Tag.Get("mapstructure")
+		}
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name, opts := parts[0], parts[1:]
+
+		idx := append(append([]int(nil), index...), i)
+		if f.Type.Kind() == reflect.Struct && (containsString(opts, "squash") || (f.Anonymous && name == "")) {
+			fields = append(fields, structFields(f.Type, idx)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, structField{index: idx, key: name, remain: containsString(opts, "remain")})
+	}
+	return fields
+}
+
+func decodeStruct(keyPath string, m map[string]interface{}, out reflect.Value) error {
+	used := make(map[string]bool, len(m))
+	var remain *structField
+	fields := structFields(out.Type(), nil)
+	for i := range fields {
+		f := &fields[i]
+		if f.remain {
+			remain = f
+			continue
+		}
+
+		key, ok := f.key, false
+		if _, ok = m[key]; !ok {
+			// 不区分大小写
+			for k := range m {
+				if strings.EqualFold(k, f.key) {
+					key, ok = k, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		used[key] = true
+		if err := decodeInto(joinKeyPath(keyPath, key), m[key], out.FieldByIndex(f.index)); err != nil {
+			return err
+		}
+	}
+
+	if remain == nil {
+		return nil
+	}
+	rest := make(map[string]interface{})
+	for k, v := range m {
+		if !used[k] {
+			rest[k] = v
+		}
+	}
+	return decodeInto(keyPath, rest, out.FieldByIndex(remain.index))
+}
+
+func decodeMap(keyPath string, val interface{}, out reflect.Value) error {
+	m, ok := val.(map[string]interface{})
+	if !ok || out.Type().Key().Kind() != reflect.String {
+		return mismatch(keyPath, val, out)
+	}
+
+	if out.IsNil() {
+		out.Set(reflect.MakeMapWithSize(out.Type(), len(m)))
+	}
+	for k, v := range m {
+		elem := reflect.New(out.Type().Elem()).Elem()
+		if err := decodeInto(joinKeyPath(keyPath, k), v, elem); err != nil {
+			return err
+		}
+		out.SetMapIndex(reflect.ValueOf(k).Convert(out.Type().Key()), elem)
+	}
+	return nil
+}
+
+func decodeSlice(keyPath string, val interface{}, out reflect.Value) error {
+	// 字符串可解析为[]byte
+	if s, ok := val.(string); ok && out.Kind() == reflect.Slice && out.Type().Elem().Kind() == reflect.Uint8 {
+		out.SetBytes([]byte(s))
+		return nil
+	}
+
+	items, ok := val.([]interface{})
+	if !ok {
+		return mismatch(keyPath, val, out)
+	}
+
+	if out.Kind() == reflect.Array {
+		if len(items) > out.Len() {
+			return typeMismatch(keyPath, fmt.Errorf("%d items overflow %s", len(items), out.Type()))
+		}
+	} else {
+		out.Set(reflect.MakeSlice(out.Type(), len(items), len(items)))
+	}
+	for i, item := range items {
+		if err := decodeInto(joinKeyPath(keyPath, strconv.Itoa(i)), item, out.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeScalar 字符串、数字及布尔值之间的转换
+func decodeScalar(keyPath string, val interface{}, out reflect.Value) error {
+	v := reflect.ValueOf(val)
+
+	switch out.Kind() {
+	case reflect.String:
+		switch vv := val.(type) {
+		case string:
+			out.SetString(vv)
+		case float64:
+			out.SetString(strconv.FormatFloat(vv, 'f', -1, 64))
+		case bool:
+			out.SetString(strconv.FormatBool(vv))
+		default:
+			if !isNumberKind(v.Kind()) {
+				return mismatch(keyPath, val, out)
+			}
+			out.SetString(fmt.Sprint(val))
+		}
+		return nil
+	case reflect.Bool:
+		switch vv := val.(type) {
+		case bool:
+			out.SetBool(vv)
+		case string:
+			b, err := parseBool(vv)
+			if err != nil {
+				return typeMismatch(keyPath, err)
+			}
+			out.SetBool(b)
+		default:
+			f, ok := toFloat(v)
+			if !ok {
+				return mismatch(keyPath, val, out)
+			}
+			out.SetBool(f != 0)
+		}
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := scalarFloat(val, v)
+		if err != nil {
+			return typeMismatch(keyPath, err)
+		}
+		if out.OverflowFloat(f) {
+			return typeMismatch(keyPath, fmt.Errorf("%v overflows %s", val, out.Type()))
+		}
+		out.SetFloat(f)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := scalarInt(val, v)
+		if err != nil {
+			return typeMismatch(keyPath, err)
+		}
+		if out.OverflowInt(i) {
+			return typeMismatch(keyPath, fmt.Errorf("%v overflows %s", val, out.Type()))
+		}
+		out.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s, ok := val.(string); ok {
+			u, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return typeMismatch(keyPath, err)
+			}
+			if out.OverflowUint(u) {
+				return typeMismatch(keyPath, fmt.Errorf("%v overflows %s", val, out.Type()))
+			}
+			out.SetUint(u)
+			return nil
+		}
+		f, err := scalarFloat(val, v)
+		if err != nil {
+			return typeMismatch(keyPath, err)
+		}
+		if f < 0 || f != float64(uint64(f)) || out.OverflowUint(uint64(f)) {
+			return typeMismatch(keyPath, fmt.Errorf("%v is not a valid %s", val, out.Type()))
+		}
+		out.SetUint(uint64(f))
+		return nil
+	}
+	return mismatch(keyPath, val, out)
+}
+
+// parseBool 在 strconv.ParseBool 之外支持 yes/no、on/off
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(strings.TrimSpace(s))
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Uint64) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func toFloat(v reflect.Value) (float64, bool) {
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return float64(v.Int()), true
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		return float64(v.Uint()), true
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// scalarInt 整数、整数值的浮点数及字符串，避免大整数经过float64丢失精度
+func scalarInt(val interface{}, v reflect.Value) (int64, error) {
+	if v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64 {
+		return v.Int(), nil
+	}
+	if s, ok := val.(string); ok {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+	}
+
+	f, err := scalarFloat(val, v)
+	if err != nil {
+		return 0, err
+	}
+	if f != float64(int64(f)) {
+		return 0, fmt.Errorf("%v is not an integer", val)
+	}
+	return int64(f), nil
+}
+
+// scalarFloat 数字、数字字符串及布尔值（1/0）
+func scalarFloat(val interface{}, v reflect.Value) (float64, error) {
+	switch vv := val.(type) {
+	case string:
+		return strconv.ParseFloat(vv, 64)
+	case bool:
+		if vv {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	if f, ok := toFloat(v); ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("cannot unmarshal %T into number", val)
+}
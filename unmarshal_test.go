@@ -0,0 +1,151 @@
+package config
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type unmarshalCommon struct {
+	Name string
+}
+
+type unmarshalDB struct {
+	unmarshalCommon
+	Host     string                 `config:"host"`
+	Port     int                    `config:"port"`
+	Timeout  time.Duration          `config:"timeout"`
+	Enabled  bool                   `mapstructure:"enabled"`
+	Weight   float32                `config:"weight"`
+	MaxConns uint16                 `config:"max_conns"`
+	IP       net.IP                 `config:"ip"`
+	Replicas []*unmarshalDB         `config:"replicas"`
+	Labels   map[string]string      `config:"labels"`
+	Raw      interface{}            `config:"raw"`
+	Ignored  string                 `config:"-"`
+	Extra    map[string]interface{} `config:",remain"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{
+		"db": map[string]interface{}{
+			"name":      "main",
+			"host":      "example.com",
+			"port":      "3306",
+			"timeout":   "5s",
+			"enabled":   "true",
+			"weight":    0.5,
+			"max_conns": 100.0,
+			"ip":        "10.0.0.1",
+			"replicas": []interface{}{
+				map[string]interface{}{"HOST": "replica.example.com", "port": 3307.0},
+			},
+			"labels":  map[string]interface{}{"zone": "a", "rack": 3.0},
+			"raw":     map[string]interface{}{"k": "v"},
+			"Ignored": "x",
+			"unknown": 1.0,
+		},
+		"bad": map[string]interface{}{"port": "abc", "replicas": []interface{}{map[string]interface{}{"port": 1.5}}},
+	})
+
+	db := unmarshalDB{Weight: 1, Labels: map[string]string{"env": "prod"}}
+	ast.Nil(cfg.Unmarshal("db", &db))
+	ast.Equal("main", db.Name, "anonymous struct")
+	ast.Equal("example.com", db.Host)
+	ast.Equal(3306, db.Port)
+	ast.Equal(5*time.Second, db.Timeout)
+	ast.True(db.Enabled)
+	ast.EqualValues(0.5, db.Weight)
+	ast.EqualValues(100, db.MaxConns)
+	ast.Equal("10.0.0.1", db.IP.String())
+	ast.Len(db.Replicas, 1)
+	ast.Equal("replica.example.com", db.Replicas[0].Host, "case-insensitive")
+	ast.Equal(3307, db.Replicas[0].Port)
+	ast.Equal(map[string]string{"env": "prod", "zone": "a", "rack": "3"}, db.Labels)
+	ast.Equal(map[string]interface{}{"k": "v"}, db.Raw)
+	ast.Empty(db.Ignored)
+	ast.Equal(map[string]interface{}{"Ignored": "x", "unknown": 1.0}, db.Extra)
+
+	// 不共享配置的节点
+	db.Raw.(map[string]interface{})["k"] = "changed"
+	ast.Equal("v", cfg.String("db.raw.k"))
+
+	// 不存在的字段保留原值
+	partial := unmarshalDB{Host: "default", Port: 1}
+	ast.Nil(cfg.Unmarshal("db.replicas.0", &partial))
+	ast.Equal("replica.example.com", partial.Host)
+	ast.Equal(3307, partial.Port)
+	partial = unmarshalDB{Host: "default"}
+	ast.Nil(cfg.Unmarshal("db", &struct{ Host *string }{&partial.Host}))
+	ast.Equal("example.com", partial.Host)
+
+	var port int
+	ast.Nil(cfg.Unmarshal("db.port", &port))
+	ast.Equal(3306, port)
+
+	err := cfg.Unmarshal("not_exist", &db)
+	ast.True(errors.Is(err, ErrKeyNotFound))
+	ast.NotNil(cfg.Unmarshal("db", db), "not a pointer")
+
+	var bad unmarshalDB
+	err = cfg.Unmarshal("bad", &bad)
+	ast.True(errors.Is(err, ErrTypeMismatch))
+	var keyErr *KeyError
+	ast.True(errors.As(err, &keyErr))
+	ast.Contains([]string{"bad.port", "bad.replicas.0.port"}, keyErr.KeyPath)
+
+	var small struct {
+		Port int8 `config:"port"`
+	}
+	ast.True(errors.Is(cfg.Unmarshal("db", &small), ErrTypeMismatch), "overflow")
+	var list [0]string
+	ast.True(errors.Is(cfg.Unmarshal("db.replicas", &list), ErrTypeMismatch))
+	var host struct {
+		Host map[string]string `config:"host"`
+	}
+	ast.True(errors.Is(cfg.Unmarshal("db", &host), ErrTypeMismatch))
+}
+
+func TestDecodeScalar(t *testing.T) {
+	ast := assert.New(t)
+
+	var s string
+	ast.Nil(decodeInto("s", 1e6, reflectValue(&s)))
+	ast.Equal("1000000", s)
+	ast.Nil(decodeInto("s", true, reflectValue(&s)))
+	ast.Equal("true", s)
+
+	var b bool
+	ast.Nil(decodeInto("b", 1.0, reflectValue(&b)))
+	ast.True(b)
+	ast.NotNil(decodeInto("b", "yes?", reflectValue(&b)))
+
+	var i int64
+	ast.Nil(decodeInto("i", "9007199254740993", reflectValue(&i)))
+	ast.EqualValues(9007199254740993, i, "no float64 precision loss")
+	ast.NotNil(decodeInto("i", 1.5, reflectValue(&i)))
+	ast.NotNil(decodeInto("i", []interface{}{}, reflectValue(&i)))
+
+	var u uint
+	ast.NotNil(decodeInto("u", -1.0, reflectValue(&u)))
+	ast.Nil(decodeInto("u", "42", reflectValue(&u)))
+	ast.EqualValues(42, u)
+
+	var d time.Duration
+	ast.Nil(decodeInto("d", 1.5, reflectValue(&d)))
+	ast.Equal(1500*time.Millisecond, d)
+
+	var bs []byte
+	ast.Nil(decodeInto("bs", "raw", reflectValue(&bs)))
+	ast.Equal([]byte("raw"), bs)
+}
+
+func reflectValue(p interface{}) reflect.Value {
+	return reflect.ValueOf(p).Elem()
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/kot-w/goutils/itype"
 	"github.com/kot-w/goutils/object"
+	"github.com/pkg/errors"
 )
 
 func JSONToMap(rawMessage json.RawMessage) (map[string]interface{}, error) {
@@ -45,7 +48,7 @@ func setMapValue(m map[string]interface{}, keyPath string, value interface{}) er
 	}
 
 	if obj == nil {
-		return fmt.Errorf("config[%s] type error", keyPath)
+		return typeMismatch(keyPath, errors.New("parent is not a map or array"))
 	}
 
 	switch v := obj.(type) {
@@ -58,15 +61,16 @@ func setMapValue(m map[string]interface{}, keyPath string, value interface{}) er
 		}
 	case []interface{}:
 		if index, err := strconv.ParseInt(lastKey, 10, 32); err == nil {
-			if int(index) >= len(v) {
-				return fmt.Errorf("config[%s] array out of range[%d]", keyPath, index)
+			if index < 0 || int(index) >= len(v) {
+				// 下标越界视为配置项不存在，而非类型冲突
+				return errors.WithStack(&KeyError{KeyPath: keyPath, Kind: ErrKeyNotFound, Err: errors.Errorf("array index out of range[%d]", index)})
 			}
 			v[int(index)] = value
 		} else {
-			return err
+			return typeMismatch(keyPath, err)
 		}
 	default:
-		return fmt.Errorf("config[%s] type error", keyPath)
+		return typeMismatch(keyPath, errors.New("parent is not a map or array"))
 	}
 
 	return nil
@@ -97,3 +101,38 @@ func mergeMap(originMap map[string]interface{}, extraMap map[string]interface{})
 func dump(vals ...interface{}) {
 	fmt.Println(vals...)
 }
+
+// toDuration 字符串按 time.ParseDuration 解析（如 "30s"），数字按秒解析
+func toDuration(v interface{}) (time.Duration, bool) {
+	switch vv := v.(type) {
+	case nil:
+		return 0, false
+	case time.Duration:
+		return vv, true
+	case string:
+		if d, err := time.ParseDuration(vv); err == nil {
+			return d, true
+		}
+		if f, err := strconv.ParseFloat(vv, 64); err == nil {
+			return time.Duration(f * float64(time.Second)), true
+		}
+		return 0, false
+	case bool:
+		return 0, false
+	default:
+		if itype.GetType(v) != itype.NUMBER {
+			return 0, false
+		}
+		return time.Duration(itype.Float(v) * float64(time.Second)), true
+	}
+}
+
+// isComparable v能否作为map的key，如包含slice、map、func的struct不能比较
+func isComparable(v interface{}) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return v == v
+}
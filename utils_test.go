@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/kot-w/goutils/object"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -65,10 +66,14 @@ func TestSetMapValue(t *testing.T) {
 	ast.Equal([]interface{}{"d", "b", "c"}, v)
 
 	err = setMapValue(m, "l1.l11.n", "a")
-	ast.NotNil(err)
+	ast.True(errors.Is(err, ErrTypeMismatch))
 
 	err = setMapValue(m, "l1.l11.3", "a")
-	ast.NotNil(err)
+	ast.True(errors.Is(err, ErrKeyNotFound))
+	ast.False(errors.Is(err, ErrTypeMismatch))
+
+	err = setMapValue(m, "l1.l11.-1", "a")
+	ast.True(errors.Is(err, ErrKeyNotFound))
 }
 
 func TestMergeMap(t *testing.T) {
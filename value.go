@@ -0,0 +1,91 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Value 绑定到keyPath的类型化配置，配置变化时解析为新的*T并原子替换，Load无锁
+//
+// validate 不为nil时，解析后的值通过校验才会替换，否则保留旧值并记录错误（见 Err）
+//
+//	dbConf, err := config.NewValue[DBConfig](cfg, "db", func(c *DBConfig) error {
+//		if c.Host == "" {
+//			return errors.New("empty host")
+//		}
+//		return nil
+//	})
+//	conn := dial(dbConf.Load().Host)
+type Value[T any] struct {
+	cfg      *ConfigHelper
+	keyPath  string
+	validate func(*T) error
+
+	p      atomic.Pointer[T]
+	errMu  sync.Mutex
+	err    error
+	cancel func()
+}
+
+// NewValue 首次解析或校验失败时返回error
+func NewValue[T any](cfg Configer, keyPath string, validate func(*T) error) (*Value[T], error) {
+	v := &Value[T]{
+		cfg:      &ConfigHelper{Configer: cfg},
+		keyPath:  keyPath,
+		validate: validate,
+	}
+	if err := v.reload(); err != nil {
+		return nil, err
+	}
+
+	v.cancel = v.cfg.OnChange(func() {
+		if err := v.reload(); err != nil {
+			logger.Warnf("reload config value[%s] err:%v", keyPath, err)
+		}
+	})
+	return v, nil
+}
+
+// Load 返回最近一次通过校验的值，返回值不应被修改
+func (v *Value[T]) Load() *T {
+	return v.p.Load()
+}
+
+// Err 最近一次更新的错误，成功时为nil
+func (v *Value[T]) Err() error {
+	v.errMu.Lock()
+	defer v.errMu.Unlock()
+	return v.err
+}
+
+// Close 停止跟随配置更新，Load返回最后的值
+func (v *Value[T]) Close() {
+	if v.cancel != nil {
+		v.cancel()
+	}
+}
+
+func (v *Value[T]) reload() error {
+	err := v.decode()
+
+	v.errMu.Lock()
+	v.err = err
+	v.errMu.Unlock()
+
+	return err
+}
+
+func (v *Value[T]) decode() error {
+	val := new(T)
+	if err := v.cfg.Remarshal(v.keyPath, val); err != nil {
+		return err
+	}
+	if v.validate != nil {
+		if err := v.validate(val); err != nil {
+			return validationError(err)
+		}
+	}
+
+	v.p.Store(val)
+	return nil
+}
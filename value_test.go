@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDBConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+func TestValue(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"db": map[string]interface{}{"host": "a", "port": 1}})
+	v, err := NewValue[testDBConfig](cfg, "db", func(c *testDBConfig) error {
+		if c.Host == "" {
+			return errors.New("empty host")
+		}
+		return nil
+	})
+	ast.Nil(err)
+	defer v.Close()
+	ast.Equal(testDBConfig{Host: "a", Port: 1}, *v.Load())
+
+	ast.Nil(cfg.Set("db.port", 2))
+	ast.Eventually(func() bool { return v.Load().Port == 2 }, time.Second, 10*time.Millisecond)
+
+	// 校验失败保留旧值
+	ast.Nil(cfg.Set("db.host", ""))
+	ast.Eventually(func() bool { return v.Err() != nil }, time.Second, 10*time.Millisecond)
+	ast.True(errors.Is(v.Err(), ErrValidation))
+	ast.Equal("a", v.Load().Host)
+
+	ast.Nil(cfg.Set("db.host", "b"))
+	ast.Eventually(func() bool { return v.Err() == nil && v.Load().Host == "b" }, time.Second, 10*time.Millisecond)
+
+	_, err = NewValue[testDBConfig](cfg, "missing", nil)
+	ast.True(errors.Is(err, ErrKeyNotFound))
+	_, err = NewValue[int](cfg, "db", nil)
+	ast.True(errors.Is(err, ErrTypeMismatch))
+
+	v.Close()
+	ast.Nil(cfg.Set("db.port", 3))
+	time.Sleep(50 * time.Millisecond)
+	ast.Equal(2, v.Load().Port, "closed")
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Filter 选择关心的keyPath，见 ConfigHelper.WatchFilter
+type Filter interface {
+	Match(keyPath string) bool
+}
+
+// FilterFunc 函数形式的 Filter
+type FilterFunc func(keyPath string) bool
+
+func (f FilterFunc) Match(keyPath string) bool {
+	return f(keyPath)
+}
+
+// KeyPrefixes 匹配prefixes及其子节点
+func KeyPrefixes(prefixes ...string) Filter {
+	return FilterFunc(func(keyPath string) bool {
+		for _, prefix := range prefixes {
+			if keyPath == prefix || strings.HasPrefix(keyPath, prefix+".") {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ParseFilter 解析过滤表达式，以逗号或空白分隔的匹配规则：
+//
+//   - db          匹配db及其子节点
+//   - db.*.host   * 匹配任意一级key
+//   - !db.password 排除匹配的节点
+//
+// 至少匹配一条非排除的规则（只有排除规则时视为匹配）且不匹配任何排除规则
+//
+//	filter, err := config.ParseFilter("db, feature.*.enabled, !db.password")
+func ParseFilter(expr string) (Filter, error) {
+	f := &exprFilter{}
+	for _, term := range strings.FieldsFunc(expr, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	}) {
+		exclude := strings.HasPrefix(term, "!")
+		term = strings.TrimPrefix(term, "!")
+
+		segments := strings.Split(term, ".")
+		for _, seg := range segments {
+			if seg == "" {
+				return nil, errors.Errorf("invalid filter term[%s]: empty key", term)
+			}
+		}
+
+		if exclude {
+			f.excludes = append(f.excludes, segments)
+		} else {
+			f.includes = append(f.includes, segments)
+		}
+	}
+	if len(f.includes) == 0 && len(f.excludes) == 0 {
+		return nil, errors.New("empty filter")
+	}
+
+	return f, nil
+}
+
+type exprFilter struct {
+	includes [][]string
+	excludes [][]string
+}
+
+func (f *exprFilter) Match(keyPath string) bool {
+	keys := strings.Split(keyPath, ".")
+	for _, pattern := range f.excludes {
+		if matchSegments(pattern, keys) {
+			return false
+		}
+	}
+	if len(f.includes) == 0 {
+		return true
+	}
+	for _, pattern := range f.includes {
+		if matchSegments(pattern, keys) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments keys 为pattern匹配的节点或其子节点
+func matchSegments(pattern, keys []string) bool {
+	if len(keys) < len(pattern) {
+		return false
+	}
+	for i, seg := range pattern {
+		if seg != "*" && seg != keys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchFilter 只在filter匹配的叶子节点变化时通知notifier，调用stop停止
+//
+// 与 WatchKey 共用同一Configer的一次 OnChange 及一次比较，高频变化的配置中不相关的修改不会唤醒监听者
+func (h *ConfigHelper) WatchFilter(notifier chan struct{}, filter Filter) (stop func()) {
+	sub := &filterSub{filter: filter, notifier: notifier}
+	idx := acquireKeyIndex(h.Configer)
+	idx.addFilter(sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			idx.removeFilter(sub)
+			releaseKeyIndex(idx)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	ast := assert.New(t)
+
+	f, err := ParseFilter("db, feature.*.enabled, !db.password")
+	ast.Nil(err)
+	ast.True(f.Match("db"))
+	ast.True(f.Match("db.host"))
+	ast.False(f.Match("dbx"))
+	ast.False(f.Match("db.password"))
+	ast.False(f.Match("db.password.old"))
+	ast.True(f.Match("feature.a.enabled"))
+	ast.False(f.Match("feature.a.ratio"))
+	ast.False(f.Match("feature"))
+
+	f, err = ParseFilter("!log")
+	ast.Nil(err)
+	ast.True(f.Match("db"))
+	ast.False(f.Match("log.level"))
+
+	_, err = ParseFilter(" , ")
+	ast.NotNil(err)
+	_, err = ParseFilter("db..host")
+	ast.NotNil(err)
+
+	ast.True(KeyPrefixes("a", "b.c").Match("b.c.d"))
+	ast.False(KeyPrefixes("a", "b.c").Match("b"))
+}
+
+func TestWatchFilter(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := NewMapConfig(map[string]interface{}{"db": map[string]interface{}{"host": "a"}, "counter": 1})
+	notifier := make(chan struct{}, 1)
+	stop := cfg.WatchFilter(notifier, KeyPrefixes("db"))
+	defer stop()
+
+	waitNotify := func() bool {
+		select {
+		case <-notifier:
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}
+
+	ast.Nil(cfg.Set("counter", 2))
+	ast.False(waitNotify(), "irrelevant change")
+
+	ast.Nil(cfg.Set("db.host", "b"))
+	ast.True(waitNotify())
+
+	ast.Nil(cfg.Set("db.port", 3306))
+	ast.True(waitNotify(), "added key")
+
+	ast.Nil(cfg.Set("db.port", 3306))
+	ast.False(waitNotify(), "same value")
+
+	stop()
+	stop()
+	ast.Nil(cfg.Set("db.host", "c"))
+	ast.False(waitNotify(), "stopped")
+
+	// 取消后不再保留订阅
+	m := cfg.Configer.(*mapConfig)
+	m.subs.mu.Lock()
+	ast.Empty(m.subs.subs)
+	m.subs.mu.Unlock()
+}
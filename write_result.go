@@ -0,0 +1,13 @@
+package config
+
+// WriteResult Set的结果
+type WriteResult struct {
+	Version  string // 后端返回的版本号，后端未实现 VersionedSetter 时为空
+	Bytes    int    // 写入后端的字节数
+	Notified bool   // 是否向监听者发出了变化通知
+}
+
+// SetWithResult 同 Set，并返回写入的结果
+func (c *AsyncConfig) SetWithResult(keyPath string, value interface{}) (WriteResult, error) {
+	return c.Configer.(*asyncConfig).SetWithResult(keyPath, value)
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type versionedAsyncer struct {
+	rawAsyncer
+	version int64
+	err     error
+}
+
+func (a *versionedAsyncer) SetVersioned(key string, value []byte) (string, error) {
+	if a.err != nil {
+		return "", a.err
+	}
+	a.data.Store(key, value)
+	return strconv.FormatInt(atomic.AddInt64(&a.version, 1), 10), nil
+}
+
+func TestSetWithResult(t *testing.T) {
+	ast := assert.New(t)
+
+	asyncer := &versionedAsyncer{rawAsyncer: rawAsyncer{NewMockAsyncer(false)}}
+	asyncer.data.Store("write.json", []byte(`{"a": 1}`))
+	ast.True(ProbeCapabilities(struct {
+		Asyncer
+		VersionedSetter
+	}{asyncer, asyncer}).Version)
+
+	cfg := NewAsyncConfig(asyncer, "write.json", 0, false)
+	ret, err := cfg.SetWithResult("a", 2)
+	ast.Nil(err)
+	ast.Equal(WriteResult{Version: "1", Bytes: len(`{"a":2}`)}, ret)
+
+	notifier := make(chan struct{}, 1)
+	cfg.Watch(notifier)
+	ret, err = cfg.SetWithResult("a", 3)
+	ast.Nil(err)
+	ast.Equal("2", ret.Version)
+	ast.True(ret.Notified)
+	ret, _ = cfg.SetWithResult("a", 4)
+	ast.False(ret.Notified, "previous notify not consumed")
+
+	// 经过超时包装
+	cfg = NewAsyncConfig(asyncer, "write.json", 0, false, WithTimeouts(Timeouts{Set: time.Second}))
+	ret, err = cfg.SetWithResult("a", 5)
+	ast.Nil(err)
+	ast.Equal("4", ret.Version)
+
+	// 经过限流包装
+	cfg = NewAsyncConfig(NewRateLimitedAsyncer(asyncer, 1000, 10), "write.json", 0, false)
+	ret, err = cfg.SetWithResult("a", 5)
+	ast.Nil(err)
+	ast.Equal("5", ret.Version)
+
+	asyncer.err = errors.New("conflict")
+	_, err = cfg.SetWithResult("a", 6)
+	ast.True(errors.Is(err, ErrBackendUnavailable))
+
+	// 未实现 VersionedSetter
+	cfg = NewAsyncConfig(NewMockAsyncer(false), "write.json", 0, false)
+	ret, err = cfg.SetWithResult("a", 1)
+	ast.Nil(err)
+	ast.Empty(ret.Version)
+	ast.True(ret.Bytes > 0)
+}